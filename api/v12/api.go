@@ -0,0 +1,60 @@
+// This package defines the types for Flux API version 12.
+package v12
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/event"
+)
+
+type ListDeploymentsOptions struct {
+	Namespace string
+}
+
+// DeployedContainer is a container in a workload, and the image it is
+// running.
+type DeployedContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// WorkloadDeployment is the deployment state of a workload: what it's
+// running, where that came from, and when it last changed. It is meant
+// to be consumed by service catalogs (e.g., Backstage), so it has
+// stable, lower-case JSON field names.
+type WorkloadDeployment struct {
+	ID         flux.ResourceID     `json:"id"`
+	Containers []DeployedContainer `json:"containers"`
+	Status     string              `json:"status"`
+	Rollout    Rollout             `json:"rollout"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Owner      string              `json:"owner,omitempty"`
+	// The git revision the cluster was last synced to
+	Revision string `json:"revision,omitempty"`
+	// The most recent release, automated release or rollback of the
+	// workload in the event store, if any
+	LastRelease *event.Event `json:"lastRelease,omitempty"`
+}
+
+// Rollout is how far along the rollout of a workload is; it's
+// cluster.RolloutStatus, with lower-case JSON field names.
+type Rollout struct {
+	Desired   int32    `json:"desired"`
+	Updated   int32    `json:"updated"`
+	Ready     int32    `json:"ready"`
+	Available int32    `json:"available"`
+	Outdated  int32    `json:"outdated"`
+	Messages  []string `json:"messages,omitempty"`
+}
+
+type Server interface {
+	v11.Server
+
+	ListDeployments(ctx context.Context, opts ListDeploymentsOptions) ([]WorkloadDeployment, error)
+}
+
+type Upstream interface {
+	v11.Upstream
+}
@@ -0,0 +1,27 @@
+// This package defines the types for Flux API version 13.
+package v13
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/event"
+)
+
+type ListEventsOptions struct {
+	// Only events with an ID greater than this are returned
+	After event.EventID
+	// If more than zero, return at most this many (of the most
+	// recent) events
+	Limit int
+}
+
+type Server interface {
+	v12.Server
+
+	ListEvents(ctx context.Context, opts ListEventsOptions) ([]event.Event, error)
+}
+
+type Upstream interface {
+	v12.Upstream
+}
@@ -0,0 +1,39 @@
+// This package defines the types for Flux API version 14.
+package v14
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v13"
+)
+
+type CheckWorkloadOptions struct {
+	Workload flux.ResourceID
+}
+
+// WorkloadCheck explains whether a workload can be automated, i.e.,
+// whether Flux is able to update the images it uses.
+type WorkloadCheck struct {
+	ID flux.ResourceID `json:"id"`
+	// Eligible is true if nothing prevents the workload being
+	// automated
+	Eligible bool `json:"eligible"`
+	// Problems are the reasons a workload isn't eligible
+	Problems []string `json:"problems,omitempty"`
+	// Notes are things worth knowing that don't prevent automation,
+	// e.g., that the workload is locked
+	Notes     []string `json:"notes,omitempty"`
+	Automated bool     `json:"automated"`
+	Locked    bool     `json:"locked"`
+}
+
+type Server interface {
+	v13.Server
+
+	CheckWorkload(ctx context.Context, opts CheckWorkloadOptions) (WorkloadCheck, error)
+}
+
+type Upstream interface {
+	v13.Upstream
+}
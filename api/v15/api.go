@@ -0,0 +1,52 @@
+// This package defines the types for Flux API version 15.
+package v15
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v14"
+)
+
+type ImageReportOptions struct {
+	// If not empty, only report images used in this namespace
+	Namespace string
+}
+
+// ImageUser is a container of a workload that runs a particular
+// image.
+type ImageUser struct {
+	Workload  flux.ResourceID `json:"workload"`
+	Container string          `json:"container"`
+	Owner     string          `json:"owner,omitempty"`
+}
+
+// ImageUsage reports on an image (i.e., a repository and tag) running
+// in the cluster: which workloads use it, and how it compares to the
+// newest image available that matches their tag filter.
+type ImageUsage struct {
+	Image      string `json:"image"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	// The tag filter used in working out Latest and Behind
+	Pattern string `json:"pattern"`
+	// The newest tag matching Pattern, if any are known
+	Latest string `json:"latest,omitempty"`
+	// How many tags matching Pattern are newer than Tag; or -1 if
+	// it's not known, e.g., because the tag is not in the registry
+	Behind    int         `json:"behind"`
+	CreatedAt *time.Time  `json:"createdAt,omitempty"`
+	LatestAt  *time.Time  `json:"latestAt,omitempty"`
+	UsedBy    []ImageUser `json:"usedBy"`
+}
+
+type Server interface {
+	v14.Server
+
+	ImageReport(ctx context.Context, opts ImageReportOptions) ([]ImageUsage, error)
+}
+
+type Upstream interface {
+	v14.Upstream
+}
@@ -0,0 +1,46 @@
+// This package defines the types for Flux API version 16.
+package v16
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v15"
+)
+
+type DeliveryReportOptions struct {
+	// If not empty, only report on workloads in this namespace
+	Namespace string
+	// How far back to report; if zero, as far back as is known
+	Period time.Duration
+}
+
+// WorkloadDelivery gives (DORA-style) measures of how often changes
+// to a workload are deployed, how long they take to be deployed, and
+// how often they are rolled back, over the period reported on.
+type WorkloadDelivery struct {
+	Workload flux.ResourceID `json:"workload"`
+	// How many syncs changed the workload
+	Deploys int `json:"deploys"`
+	// Deploys per day
+	DeployFrequency float64 `json:"deployFrequency"`
+	// The median time from a change being committed to it being
+	// synced, in seconds; zero if not known
+	MedianLeadTimeSeconds float64 `json:"medianLeadTimeSeconds"`
+	// How many releases were back to an image the workload ran before
+	Rollbacks int `json:"rollbacks"`
+	// Rollbacks per deploy
+	ChangeFailureRate float64    `json:"changeFailureRate"`
+	LastDeployed      *time.Time `json:"lastDeployed,omitempty"`
+}
+
+type Server interface {
+	v15.Server
+
+	DeliveryReport(ctx context.Context, opts DeliveryReportOptions) ([]WorkloadDelivery, error)
+}
+
+type Upstream interface {
+	v15.Upstream
+}
@@ -0,0 +1,57 @@
+// This package defines the types for Flux API version 17.
+package v17
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v16"
+)
+
+type CompareImagesOptions struct {
+	// If not empty, only compare workloads in this namespace
+	Namespace string
+}
+
+// The ways the image a container is running can compare with the
+// image it's given in git.
+const (
+	// The container is running the image in git
+	ImageInSync = "in-sync"
+	// The image in git has not been synced yet
+	ImagePendingSync = "pending-sync"
+	// The container is not running the image last synced, e.g.,
+	// because it's been changed with kubectl, or is still rolling out
+	ImageChangedInCluster = "changed-in-cluster"
+	// The workload or container is in git, but not in the cluster
+	ImageNotRunning = "not-running"
+)
+
+// ImageComparison compares the image given for a container at the
+// head of the git repo with the image it is running in the cluster.
+type ImageComparison struct {
+	Workload  flux.ResourceID `json:"workload"`
+	Container string          `json:"container"`
+	// The image at the head of the git repo
+	Desired string `json:"desired"`
+	// The image running in the cluster, if it's running
+	Running string `json:"running,omitempty"`
+	// The image as of the last sync, if it's known
+	Synced string `json:"synced,omitempty"`
+	Status string `json:"status"`
+}
+
+// InSync says whether the container is running the image in git.
+func (c ImageComparison) InSync() bool {
+	return c.Status == ImageInSync
+}
+
+type Server interface {
+	v16.Server
+
+	CompareImages(ctx context.Context, opts CompareImagesOptions) ([]ImageComparison, error)
+}
+
+type Upstream interface {
+	v16.Upstream
+}
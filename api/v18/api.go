@@ -0,0 +1,29 @@
+// This package defines the types for Flux API version 18.
+package v18
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v17"
+)
+
+type ExportClusterOptions struct {
+	// If not empty, only export from these namespaces
+	Namespaces []string
+	// If not empty, only export resources of these kinds (e.g.,
+	// namespace, deployment)
+	Kinds []string
+}
+
+type Server interface {
+	v17.Server
+
+	// ExportCluster gives the resources selected from the cluster as
+	// YAML, without the fields filled in by the cluster (status, uid,
+	// and so on), ready to commit to git
+	ExportCluster(ctx context.Context, opts ExportClusterOptions) ([]byte, error)
+}
+
+type Upstream interface {
+	v17.Upstream
+}
@@ -0,0 +1,56 @@
+// This package defines the types for Flux API version 19.
+package v19
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v18"
+)
+
+type DeployedAtOptions struct {
+	// The time to say what was deployed at
+	At time.Time
+	// If not empty, only give workloads in this namespace
+	Namespace string
+}
+
+// DeployedState is what was deployed at a point in time: the revision
+// synced to the cluster, and the images that revision gave the
+// workloads.
+type DeployedState struct {
+	At       time.Time `json:"at"`
+	Revision string    `json:"revision"`
+	// When the revision was synced; or, if it's estimated, when it
+	// was committed
+	SyncedAt time.Time `json:"syncedAt"`
+	// True if there's no record of what was synced at the time
+	// (e.g., because it's from before the daemon last started), so
+	// the revision given is the last one committed before then
+	Estimated bool               `json:"estimated,omitempty"`
+	Workloads []DeployedWorkload `json:"workloads"`
+}
+
+type DeployedWorkload struct {
+	ID         flux.ResourceID     `json:"id"`
+	Containers []DeployedContainer `json:"containers"`
+}
+
+type DeployedContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type Server interface {
+	v18.Server
+
+	// DeployedAt reconstructs, from the history of syncs, which
+	// revision was synced and which images the workloads were given
+	// at the time asked about
+	DeployedAt(ctx context.Context, opts DeployedAtOptions) (DeployedState, error)
+}
+
+type Upstream interface {
+	v18.Upstream
+}
@@ -0,0 +1,32 @@
+// This package defines the types for Flux API version 20.
+package v20
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/event"
+)
+
+type AnnotateEventOptions struct {
+	// The event to annotate, as listed by ListEvents
+	ID      event.EventID
+	Comment string
+	// If true, the annotation acknowledges the event
+	Acknowledge bool
+	// Who is annotating the event; if the request is authenticated,
+	// the identity it's authenticated as is used instead
+	User string
+}
+
+type Server interface {
+	v19.Server
+
+	// AnnotateEvent attaches a comment, or an acknowledgement, to an
+	// event, and returns the event as annotated
+	AnnotateEvent(ctx context.Context, opts AnnotateEventOptions) (event.Event, error)
+}
+
+type Upstream interface {
+	v19.Upstream
+}
@@ -0,0 +1,69 @@
+// This package defines the types for Flux API version 21.
+package v21
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v20"
+)
+
+type DiffRevisionsOptions struct {
+	// The revisions to compare; either may be abbreviated, or be a
+	// branch or tag. If To is empty, the head of the branch flux
+	// syncs is used
+	From string
+	To   string
+	// If not empty, only give resources in this namespace
+	Namespace string
+	// If true, include a diff of each manifest changed
+	Manifests bool
+}
+
+// ResourceChangeStatus says how a resource differs between two
+// revisions
+type ResourceChangeStatus string
+
+const (
+	ResourceAdded   ResourceChangeStatus = "added"
+	ResourceRemoved ResourceChangeStatus = "removed"
+	ResourceChanged ResourceChangeStatus = "changed"
+)
+
+// RevisionDiff is how the resources defined in the repo differ
+// between two revisions.
+type RevisionDiff struct {
+	// The revisions compared, in full
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Changes []ResourceChange `json:"changes"`
+}
+
+type ResourceChange struct {
+	ID     flux.ResourceID      `json:"id"`
+	Status ResourceChangeStatus `json:"status"`
+	// For workloads, the containers whose images differ
+	Images []ImageChange `json:"images,omitempty"`
+	// If asked for, a unified diff of the manifest
+	Diff string `json:"diff,omitempty"`
+}
+
+// ImageChange is a container's image at each revision; either is
+// empty if the container isn't there at that revision.
+type ImageChange struct {
+	Container string `json:"container"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+}
+
+type Server interface {
+	v20.Server
+
+	// DiffRevisions compares the resources defined in the repo at
+	// two revisions, as flux parses them
+	DiffRevisions(ctx context.Context, opts DiffRevisionsOptions) (RevisionDiff, error)
+}
+
+type Upstream interface {
+	v20.Upstream
+}
@@ -0,0 +1,45 @@
+// This package defines the types for Flux API version 22.
+package v22
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/event"
+)
+
+type EventHistoryOptions struct {
+	// If given, only events concerning this workload are returned
+	Workload *flux.ResourceID `json:"workload,omitempty"`
+	// If more than zero, only events with an ID greater than this
+	After event.EventID `json:"after,omitempty"`
+	// If more than zero, only events with an ID less than this
+	Before event.EventID `json:"before,omitempty"`
+	// The most events to return (of the most recent); if zero, or
+	// more than the daemon allows, the daemon's limit is used
+	Limit int `json:"limit,omitempty"`
+	// Selects events by type, time or log level; events that don't
+	// match don't count towards the limit
+	Filter event.EventFilter `json:"filter"`
+}
+
+// EventHistory is a page of events, oldest first.
+type EventHistory struct {
+	Events []event.Event `json:"events"`
+	// If some events (between After, if given, and those returned)
+	// were left out because of the limit, the ID to give as Before to
+	// get the page of events before these
+	Older event.EventID `json:"older,omitempty"`
+}
+
+type Server interface {
+	v21.Server
+
+	// EventHistory returns a page of the events kept by the daemon
+	EventHistory(ctx context.Context, opts EventHistoryOptions) (EventHistory, error)
+}
+
+type Upstream interface {
+	v21.Upstream
+}
@@ -0,0 +1,50 @@
+// This package defines the types for Flux API version 23.
+package v23
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux/api/v22"
+)
+
+type MetricType string
+
+const (
+	MetricCounter   MetricType = "counter"
+	MetricGauge     MetricType = "gauge"
+	MetricHistogram MetricType = "histogram"
+	MetricSummary   MetricType = "summary"
+	MetricUntyped   MetricType = "untyped"
+)
+
+// MetricsSnapshot has the value, at a point in time, of each of the
+// daemon's metrics.
+type MetricsSnapshot struct {
+	Time    time.Time `json:"time"`
+	Metrics []Metric  `json:"metrics"`
+}
+
+// Metric is the value of a metric for one set of labels.
+type Metric struct {
+	Name   string            `json:"name"`
+	Type   MetricType        `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// For counters and gauges, the value; for histograms and
+	// summaries, the sum of the observations
+	Value float64 `json:"value"`
+	// For histograms and summaries, the number of observations
+	Count uint64 `json:"count,omitempty"`
+}
+
+type Server interface {
+	v22.Server
+
+	// MetricsSnapshot gives the current value of the daemon's
+	// metrics, for when they can't be scraped
+	MetricsSnapshot(ctx context.Context) (MetricsSnapshot, error)
+}
+
+type Upstream interface {
+	v22.Upstream
+}
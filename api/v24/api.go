@@ -0,0 +1,34 @@
+// This package defines the types for Flux API version 24.
+package v24
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/event"
+)
+
+type PruneEventsOptions struct {
+	// The policy by which to prune events; if zero, the daemon's own
+	// retention policy is used
+	Policy event.RetentionPolicy `json:"policy"`
+}
+
+type PruneEventsResult struct {
+	// The policy events were pruned by
+	Policy event.RetentionPolicy `json:"policy"`
+	// How many events were removed
+	Pruned int `json:"pruned"`
+}
+
+type Server interface {
+	v23.Server
+
+	// PruneEvents removes the events kept by the daemon that the
+	// retention policy given (or else its own) doesn't keep
+	PruneEvents(ctx context.Context, opts PruneEventsOptions) (PruneEventsResult, error)
+}
+
+type Upstream interface {
+	v23.Upstream
+}
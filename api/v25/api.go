@@ -0,0 +1,42 @@
+// This package defines the types for Flux API version 25.
+package v25
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/event"
+)
+
+type ReplayEventsOptions struct {
+	// The cursor last received by the webhook subscriber, from the
+	// X-Flux-Cursor header
+	Cursor string `json:"cursor"`
+	// The most events to return; if zero, or too many, the daemon
+	// returns as many as it allows
+	Limit int `json:"limit"`
+}
+
+type ReplayEventsResult struct {
+	// The events after the cursor that the subscriber's filter
+	// selects, oldest first
+	Events []event.Event `json:"events"`
+	// The cursor to ask with next time, i.e., the cursor for the
+	// last event returned (or the cursor given, if there were none)
+	Cursor string `json:"cursor"`
+	// Whether there are more events after those returned
+	More bool `json:"more"`
+}
+
+type Server interface {
+	v24.Server
+
+	// ReplayEvents gives the events a webhook subscriber would have
+	// been sent since the cursor it last received, e.g., because it
+	// was down
+	ReplayEvents(ctx context.Context, opts ReplayEventsOptions) (ReplayEventsResult, error)
+}
+
+type Upstream interface {
+	v24.Upstream
+}
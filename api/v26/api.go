@@ -0,0 +1,158 @@
+// This package defines the types for Flux API version 26.
+package v26
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/event"
+)
+
+type ComplianceReportOptions struct {
+	// The period to report on; if Until is zero, up to now
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until,omitempty"`
+	// If given, only changes concerning workloads in this namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ComplianceReport lists the changes made, and the failures seen, in
+// a period, from the events the daemon keeps.
+type ComplianceReport struct {
+	Since       time.Time `json:"since"`
+	Until       time.Time `json:"until"`
+	Namespace   string    `json:"namespace,omitempty"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Releases, automated releases, rollbacks and restarts
+	Releases []ReportedEvent `json:"releases"`
+	// Automation, locks, and other policy changes
+	PolicyChanges []ReportedEvent `json:"policyChanges"`
+	// Events logged as errors
+	Failures []ReportedEvent `json:"failures"`
+	// Acknowledgements of any of the events above
+	Approvals []ReportedApproval `json:"approvals"`
+	// PublicKey is the (base64) ed25519 public key the report was
+	// signed with, and Signature the (base64) ed25519 signature of
+	// the report's JSON, without the signature; both are empty if
+	// the daemon has no signing key
+	PublicKey string `json:"publicKey,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// ReportedEvent is an event in a compliance report.
+type ReportedEvent struct {
+	EventID   event.EventID     `json:"eventID"`
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	Workloads []flux.ResourceID `json:"workloads,omitempty"`
+	// Who asked for the change, if known
+	User string `json:"user,omitempty"`
+	// The event's message
+	Message string `json:"message"`
+}
+
+// ReportedApproval is an acknowledgement of an event in a compliance
+// report.
+type ReportedApproval struct {
+	EventID event.EventID `json:"eventID"`
+	Time    time.Time     `json:"time"`
+	User    string        `json:"user,omitempty"`
+	Comment string        `json:"comment,omitempty"`
+}
+
+// SignReport signs the report with the key given, filling in its
+// PublicKey and Signature.
+func SignReport(report *ComplianceReport, key ed25519.PrivateKey) error {
+	report.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	payload, err := reportPayload(*report)
+	if err != nil {
+		return err
+	}
+	report.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return nil
+}
+
+// VerifyReport says whether the report was signed with the private
+// key belonging to the public key given. The public key should come
+// from whoever runs the daemon, rather than from the report itself,
+// since anyone changing the report could change its key too.
+func VerifyReport(report ComplianceReport, key ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(report.Signature)
+	if err != nil || len(signature) == 0 || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	payload, err := reportPayload(report)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, payload, signature)
+}
+
+func reportPayload(report ComplianceReport) ([]byte, error) {
+	report.Signature = ""
+	return json.Marshal(report)
+}
+
+// ParseReportSigningKey parses an ed25519 private key for signing
+// reports, PEM-encoded in PKCS #8 form, e.g., as made by `openssl
+// genpkey -algorithm ed25519`.
+func ParseReportSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM-encoded key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 key, got %T", key)
+	}
+	return private, nil
+}
+
+// ParseReportPublicKey parses an ed25519 public key for verifying
+// reports, either PEM-encoded in PKIX form (e.g., as made by `openssl
+// pkey -pubout`), or in base64 as given in a report.
+func ParseReportPublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, errors.New("expected a PEM-encoded or base64 ed25519 public key")
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 key, got %T", key)
+	}
+	return public, nil
+}
+
+type Server interface {
+	v25.Server
+
+	// ComplianceReport reports the changes made, and failures seen,
+	// in a period, signed if the daemon has a signing key
+	ComplianceReport(ctx context.Context, opts ComplianceReportOptions) (ComplianceReport, error)
+}
+
+type Upstream interface {
+	v25.Upstream
+}
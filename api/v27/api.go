@@ -0,0 +1,28 @@
+// This package defines the types for Flux API version 27.
+package v27
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/event"
+)
+
+// LogEventsOptions carries a batch of events logged elsewhere, e.g.,
+// by an agent that kept them while it couldn't reach the daemon.
+type LogEventsOptions struct {
+	// The events, oldest first; the daemon gives each its own ID
+	Events []event.Event `json:"events"`
+}
+
+type Server interface {
+	v26.Server
+
+	// LogEvents records a batch of events in the daemon's history in
+	// one go, rather than one request per event
+	LogEvents(ctx context.Context, opts LogEventsOptions) error
+}
+
+type Upstream interface {
+	v26.Upstream
+}
@@ -0,0 +1,71 @@
+// This package defines the types for Flux API version 28.
+package v28
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/event"
+)
+
+type WorkloadHistoryOptions struct {
+	Workload flux.ResourceID `json:"workload"`
+	// If not zero, only what happened since this time
+	Since time.Time `json:"since,omitempty"`
+}
+
+// The kinds of entry in a workload's history
+const (
+	// A release (or automated release, or rollback) of the workload
+	HistoryRelease = "release"
+	// A container of the workload being given a new image
+	HistoryImage = "image"
+	// The workload being locked, until it was unlocked
+	HistoryLock = "lock"
+	// The workload being automated, until automation was turned off
+	HistoryAutomation = "automation"
+	// Any other change to the workload's policies
+	HistoryPolicy = "policy"
+)
+
+// WorkloadHistory is a timeline of the changes made to one workload,
+// oldest first, from the events the daemon keeps.
+type WorkloadHistory struct {
+	Workload flux.ResourceID        `json:"workload"`
+	Entries  []WorkloadHistoryEntry `json:"entries"`
+}
+
+// WorkloadHistoryEntry is one change in a workload's history. For
+// images, locks and automation, it lasts from Start until End, which
+// is when the container was next given an image, or the workload was
+// unlocked or deautomated; if End is nil, it's still the case. For
+// releases and other policy changes, End is when the change was done.
+type WorkloadHistoryEntry struct {
+	Kind string `json:"kind"`
+	// The event the entry is from
+	EventID event.EventID `json:"eventID"`
+	Start   time.Time     `json:"start"`
+	End     *time.Time    `json:"end,omitempty"`
+	// For images, the container, and the images it went from and to
+	Container string `json:"container,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	// Who asked for the change, if known
+	User string `json:"user,omitempty"`
+	// The event's message
+	Message string `json:"message,omitempty"`
+}
+
+type Server interface {
+	v27.Server
+
+	// WorkloadHistory gives the releases, image changes, locks and
+	// other policy changes of one workload, with how long each lasted
+	WorkloadHistory(ctx context.Context, opts WorkloadHistoryOptions) (WorkloadHistory, error)
+}
+
+type Upstream interface {
+	v27.Upstream
+}
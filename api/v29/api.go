@@ -0,0 +1,33 @@
+// This package defines the types for Flux API version 29.
+package v29
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/job"
+)
+
+// JobQueue describes the jobs the daemon has queued, and how it runs
+// them.
+type JobQueue struct {
+	// The most jobs run at once
+	MaxRunning int `json:"maxRunning"`
+	// The priority of each kind of job (e.g., image, for releases, or
+	// auto, for automated releases); jobs with a higher priority run
+	// first, and kinds not given have priority zero
+	Priorities map[string]int `json:"priorities,omitempty"`
+	// The jobs running, then those waiting, in the order they'll run
+	Jobs []job.QueuedJob `json:"jobs"`
+}
+
+type Server interface {
+	v28.Server
+
+	// JobQueue gives the jobs that are running or waiting to run
+	JobQueue(ctx context.Context) (JobQueue, error)
+}
+
+type Upstream interface {
+	v28.Upstream
+}
@@ -0,0 +1,17 @@
+// This package defines the types for Flux API version 30.
+package v30
+
+import (
+	"github.com/weaveworks/flux/api/v29"
+)
+
+// Server adds no methods to version 29; this version introduces the
+// export-events and graphql endpoints, which are served over HTTP
+// from EventHistory.
+type Server interface {
+	v29.Server
+}
+
+type Upstream interface {
+	v29.Upstream
+}
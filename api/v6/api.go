@@ -28,6 +28,9 @@ const (
 	ReadOnlySystem   ReadOnlyReason = "System"
 	ReadOnlyNoRepo   ReadOnlyReason = "NoRepo"
 	ReadOnlyNotReady ReadOnlyReason = "NotReady"
+	// The controller's images can't be changed once it exists, e.g.,
+	// it's a Job or a bare Pod, so it's not released or automated
+	ReadOnlyNotAutomatable ReadOnlyReason = "NotAutomatable"
 )
 
 type ControllerStatus struct {
@@ -42,6 +45,7 @@ type ControllerStatus struct {
 	Locked     bool
 	Ignore     bool
 	Policies   map[string]string
+	Owner      string
 }
 
 // --- config types
@@ -56,6 +60,7 @@ type GitConfig struct {
 	Remote       GitRemoteConfig   `json:"remote"`
 	PublicSSHKey ssh.PublicKey     `json:"publicSSHKey"`
 	Status       git.GitRepoStatus `json:"status"`
+	Progress     *git.Progress     `json:"progress,omitempty"` // of the last clone or fetch
 }
 
 type Deprecated interface {
@@ -24,10 +24,19 @@ type Container struct {
 	// Filtered available images (matching tag filters)
 	FilteredImagesCount    int `json:",omitempty"`
 	NewFilteredImagesCount int `json:",omitempty"`
+
+	// The tag the container is pinned to, if it is
+	Pinned string `json:",omitempty"`
 }
 
 // NewContainer creates a Container given a list of images and the current image
 func NewContainer(name string, images update.ImageInfos, currentImage image.Info, tagPattern policy.Pattern, fields []string) (Container, error) {
+	return NewPinnedContainer(name, images, currentImage, tagPattern, "", fields)
+}
+
+// NewPinnedContainer creates a Container as NewContainer does, noting
+// the tag it's pinned to, if any.
+func NewPinnedContainer(name string, images update.ImageInfos, currentImage image.Info, tagPattern policy.Pattern, pinned string, fields []string) (Container, error) {
 	sorted := images.Sort(tagPattern)
 
 	// All images
@@ -67,6 +76,7 @@ func NewContainer(name string, images update.ImageInfos, currentImage image.Info
 		NewAvailableImagesCount: newImagesCount,
 		FilteredImagesCount:     filteredImagesCount,
 		NewFilteredImagesCount:  newFilteredImagesCount,
+		Pinned:                  pinned,
 	}
 	return filterContainerFields(container, fields)
 }
@@ -86,6 +96,7 @@ func filterContainerFields(container Container, fields []string) (Container, err
 			"NewAvailableImagesCount",
 			"FilteredImagesCount",
 			"NewFilteredImagesCount",
+			"Pinned",
 		}
 	}
 
@@ -110,6 +121,8 @@ func filterContainerFields(container Container, fields []string) (Container, err
 			c.FilteredImagesCount = container.FilteredImagesCount
 		case "NewFilteredImagesCount":
 			c.NewFilteredImagesCount = container.NewFilteredImagesCount
+		case "Pinned":
+			c.Pinned = container.Pinned
 		default:
 			return c, errors.Errorf("%s is an invalid field", field)
 		}
@@ -82,6 +82,17 @@ func TestNewContainer(t *testing.T) {
 	}
 }
 
+func TestNewPinnedContainer(t *testing.T) {
+	current := image.Info{ID: image.Ref{Tag: "v1.4.2"}}
+	got, err := NewPinnedContainer("app", update.ImageInfos{current}, current, policy.PatternAll, "v1.4.2", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.4.2", got.Pinned)
+
+	got, err = NewPinnedContainer("app", update.ImageInfos{current}, current, policy.PatternAll, "v1.4.2", []string{"Name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", got.Pinned)
+}
+
 func TestFilterContainerFields(t *testing.T) {
 	testContainer := Container{
 		Name:                    "test",
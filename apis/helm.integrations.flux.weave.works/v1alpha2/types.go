@@ -22,11 +22,21 @@ type FluxHelmRelease struct {
 // FluxHelmReleaseSpec is the spec for a FluxHelmRelease resource
 // FluxHelmReleaseSpec
 type FluxHelmReleaseSpec struct {
-	ChartGitPath   string `json:"chartGitPath"`
-	ReleaseName    string `json:"releaseName,omitempty"`
+	ChartGitPath string `json:"chartGitPath,omitempty"`
+	// ChartSource, if given, is used instead of ChartGitPath
+	ChartSource    *RepoChartSource `json:"chart,omitempty"`
+	ReleaseName    string           `json:"releaseName,omitempty"`
 	FluxHelmValues `json:",inline"`
 }
 
+// RepoChartSource refers to a version of a chart in a Helm chart
+// repository.
+type RepoChartSource struct {
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+}
+
 type FluxHelmReleaseStatus struct {
 	ReleaseStatus string `json:"releaseStatus"`
 }
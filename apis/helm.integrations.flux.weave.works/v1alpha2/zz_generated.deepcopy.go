@@ -90,6 +90,11 @@ func (in *FluxHelmReleaseList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FluxHelmReleaseSpec) DeepCopyInto(out *FluxHelmReleaseSpec) {
 	*out = *in
+	if in.ChartSource != nil {
+		in, out := &in.ChartSource, &out.ChartSource
+		*out = new(RepoChartSource)
+		**out = **in
+	}
 	in.FluxHelmValues.DeepCopyInto(&out.FluxHelmValues)
 	return
 }
@@ -104,6 +109,22 @@ func (in *FluxHelmReleaseSpec) DeepCopy() *FluxHelmReleaseSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepoChartSource) DeepCopyInto(out *RepoChartSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoChartSource.
+func (in *RepoChartSource) DeepCopy() *RepoChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RepoChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FluxHelmReleaseStatus) DeepCopyInto(out *FluxHelmReleaseStatus) {
 	*out = *in
@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v10"
+	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
+	"github.com/weaveworks/flux/api/v6"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+var _ api.Server = &AuditingServer{}
+
+const (
+	AuditResultOK     = "ok"
+	AuditResultDenied = "denied"
+	AuditResultError  = "error"
+)
+
+// AuditRecord is a record of a single API call.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Method  string    `json:"method"`
+	Verbs   []Verb    `json:"verbs"`
+	Targets []string  `json:"targets,omitempty"`
+	Result  string    `json:"result"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Mutating says whether the call was to change something -- release,
+// change policies, sync, and so on -- rather than only to read, going
+// by the verbs it needed.
+func (r AuditRecord) Mutating() bool {
+	for _, v := range r.Verbs {
+		if v != VerbRead {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditSink is somewhere audit records can be sent.
+type AuditSink interface {
+	Audit(AuditRecord) error
+}
+
+// JSONAuditSink writes audit records as lines of JSON, which is a
+// format most log shippers and SIEMs will accept.
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONAuditSink) Audit(r AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+// EventAuditSink records audit records as events, so they are kept
+// alongside the rest of the daemon's history. Only calls that change
+// something are recorded: reads are made all the time, e.g., by
+// `fluxctl events --follow` and UIs polling, and would fill the
+// history (and push the daemon's own events out of a store that only
+// keeps so many). A JSONAuditSink records every call.
+type EventAuditSink struct {
+	Events event.EventWriter
+}
+
+func (s EventAuditSink) Audit(r AuditRecord) error {
+	if !r.Mutating() {
+		return nil
+	}
+	verbs := make([]string, len(r.Verbs))
+	for i := range r.Verbs {
+		verbs[i] = string(r.Verbs[i])
+	}
+	level := event.LogLevelInfo
+	if r.Result != AuditResultOK {
+		level = event.LogLevelWarn
+	}
+	return s.Events.LogEvent(event.Event{
+		Type:      event.EventAudit,
+		StartedAt: r.Time,
+		EndedAt:   r.Time,
+		LogLevel:  level,
+		Metadata: &event.AuditEventMetadata{
+			User:    r.User,
+			Method:  r.Method,
+			Verbs:   verbs,
+			Targets: r.Targets,
+			Result:  r.Result,
+			Error:   r.Error,
+		},
+	})
+}
+
+// AuditingServer wraps an api.Server, sending a record of each call
+// made to it to the sinks given. It should wrap anything that can
+// refuse requests, e.g., an AuthorizingServer, so those refusals are
+// recorded.
+type AuditingServer struct {
+	server api.Server
+	sinks  []AuditSink
+	logger log.Logger
+}
+
+func NewAuditingServer(s api.Server, logger log.Logger, sinks ...AuditSink) *AuditingServer {
+	return &AuditingServer{
+		server: s,
+		sinks:  sinks,
+		logger: logger,
+	}
+}
+
+func (s *AuditingServer) audit(ctx context.Context, method string, verbs []Verb, targets []string, err error) {
+	record := AuditRecord{
+		Time:    time.Now().UTC(),
+		User:    "<unauthenticated>",
+		Method:  method,
+		Verbs:   verbs,
+		Targets: targets,
+		Result:  AuditResultOK,
+	}
+	if id, ok := IdentityFromContext(ctx); ok {
+		record.User = id.Name()
+	}
+	switch {
+	case err == ErrForbidden:
+		record.Result = AuditResultDenied
+	case err != nil:
+		record.Result = AuditResultError
+		record.Error = err.Error()
+	}
+	for _, sink := range s.sinks {
+		if sinkErr := sink.Audit(record); sinkErr != nil {
+			s.logger.Log("method", method, "audit", "failed", "err", sinkErr)
+		}
+	}
+}
+
+// targetsForSpec lists the workloads an update is aimed at.
+func targetsForSpec(spec update.Spec) []string {
+	var targets []string
+	switch s := spec.Spec.(type) {
+	case update.ReleaseSpec:
+		for _, ss := range s.ServiceSpecs {
+			targets = append(targets, ss.String())
+		}
+	case policy.Updates:
+		for id := range s {
+			targets = append(targets, id.String())
+		}
+	case update.ContainerSpecs:
+		for id := range s.ContainerSpecs {
+			targets = append(targets, id.String())
+		}
+	case update.ChartUpdates:
+		for _, ch := range s.Changes {
+			targets = append(targets, ch.ResourceID.String())
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func (s *AuditingServer) Export(ctx context.Context) (config []byte, err error) {
+	defer func() { s.audit(ctx, "Export", []Verb{VerbRead}, nil, err) }()
+	return s.server.Export(ctx)
+}
+
+func (s *AuditingServer) ListServices(ctx context.Context, namespace string) (_ []v6.ControllerStatus, err error) {
+	defer func() { s.audit(ctx, "ListServices", []Verb{VerbRead}, nil, err) }()
+	return s.server.ListServices(ctx, namespace)
+}
+
+func (s *AuditingServer) ListServicesWithOptions(ctx context.Context, opts v11.ListServicesOptions) (_ []v6.ControllerStatus, err error) {
+	var targets []string
+	for _, id := range opts.Services {
+		targets = append(targets, id.String())
+	}
+	defer func() { s.audit(ctx, "ListServicesWithOptions", []Verb{VerbRead}, targets, err) }()
+	return s.server.ListServicesWithOptions(ctx, opts)
+}
+
+func (s *AuditingServer) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) (_ []v12.WorkloadDeployment, err error) {
+	defer func() { s.audit(ctx, "ListDeployments", []Verb{VerbRead}, nil, err) }()
+	return s.server.ListDeployments(ctx, opts)
+}
+
+func (s *AuditingServer) ListEvents(ctx context.Context, opts v13.ListEventsOptions) (_ []event.Event, err error) {
+	defer func() { s.audit(ctx, "ListEvents", []Verb{VerbRead}, nil, err) }()
+	return s.server.ListEvents(ctx, opts)
+}
+
+func (s *AuditingServer) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (_ v14.WorkloadCheck, err error) {
+	defer func() { s.audit(ctx, "CheckWorkload", []Verb{VerbRead}, []string{opts.Workload.String()}, err) }()
+	return s.server.CheckWorkload(ctx, opts)
+}
+
+func (s *AuditingServer) ImageReport(ctx context.Context, opts v15.ImageReportOptions) (_ []v15.ImageUsage, err error) {
+	defer func() { s.audit(ctx, "ImageReport", []Verb{VerbRead}, nil, err) }()
+	return s.server.ImageReport(ctx, opts)
+}
+
+func (s *AuditingServer) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) (_ []v16.WorkloadDelivery, err error) {
+	defer func() { s.audit(ctx, "DeliveryReport", []Verb{VerbRead}, nil, err) }()
+	return s.server.DeliveryReport(ctx, opts)
+}
+
+func (s *AuditingServer) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) (_ []v17.ImageComparison, err error) {
+	defer func() { s.audit(ctx, "CompareImages", []Verb{VerbRead}, nil, err) }()
+	return s.server.CompareImages(ctx, opts)
+}
+
+func (s *AuditingServer) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) (_ []byte, err error) {
+	defer func() { s.audit(ctx, "ExportCluster", []Verb{VerbRead}, opts.Namespaces, err) }()
+	return s.server.ExportCluster(ctx, opts)
+}
+
+func (s *AuditingServer) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (_ v19.DeployedState, err error) {
+	defer func() { s.audit(ctx, "DeployedAt", []Verb{VerbRead}, nil, err) }()
+	return s.server.DeployedAt(ctx, opts)
+}
+
+func (s *AuditingServer) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (_ event.Event, err error) {
+	defer func() {
+		s.audit(ctx, "AnnotateEvent", []Verb{VerbAnnotate}, []string{strconv.FormatInt(int64(opts.ID), 10)}, err)
+	}()
+	return s.server.AnnotateEvent(ctx, opts)
+}
+
+func (s *AuditingServer) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (_ v21.RevisionDiff, err error) {
+	defer func() { s.audit(ctx, "DiffRevisions", []Verb{VerbRead}, nil, err) }()
+	return s.server.DiffRevisions(ctx, opts)
+}
+
+func (s *AuditingServer) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (_ v22.EventHistory, err error) {
+	defer func() { s.audit(ctx, "EventHistory", []Verb{VerbRead}, nil, err) }()
+	return s.server.EventHistory(ctx, opts)
+}
+
+func (s *AuditingServer) MetricsSnapshot(ctx context.Context) (_ v23.MetricsSnapshot, err error) {
+	defer func() { s.audit(ctx, "MetricsSnapshot", []Verb{VerbRead}, nil, err) }()
+	return s.server.MetricsSnapshot(ctx)
+}
+
+func (s *AuditingServer) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (_ v24.PruneEventsResult, err error) {
+	defer func() { s.audit(ctx, "PruneEvents", []Verb{VerbAdmin}, nil, err) }()
+	return s.server.PruneEvents(ctx, opts)
+}
+
+func (s *AuditingServer) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (_ v25.ReplayEventsResult, err error) {
+	defer func() { s.audit(ctx, "ReplayEvents", []Verb{VerbRead}, nil, err) }()
+	return s.server.ReplayEvents(ctx, opts)
+}
+
+func (s *AuditingServer) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (_ v26.ComplianceReport, err error) {
+	defer func() { s.audit(ctx, "ComplianceReport", []Verb{VerbRead}, nil, err) }()
+	return s.server.ComplianceReport(ctx, opts)
+}
+
+func (s *AuditingServer) LogEvents(ctx context.Context, opts v27.LogEventsOptions) (err error) {
+	defer func() { s.audit(ctx, "LogEvents", []Verb{VerbAdmin}, nil, err) }()
+	return s.server.LogEvents(ctx, opts)
+}
+
+func (s *AuditingServer) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (_ v28.WorkloadHistory, err error) {
+	defer func() { s.audit(ctx, "WorkloadHistory", []Verb{VerbRead}, nil, err) }()
+	return s.server.WorkloadHistory(ctx, opts)
+}
+
+func (s *AuditingServer) JobQueue(ctx context.Context) (_ v29.JobQueue, err error) {
+	defer func() { s.audit(ctx, "JobQueue", []Verb{VerbRead}, nil, err) }()
+	return s.server.JobQueue(ctx)
+}
+
+func (s *AuditingServer) ListImages(ctx context.Context, spec update.ResourceSpec) (_ []v6.ImageStatus, err error) {
+	defer func() { s.audit(ctx, "ListImages", []Verb{VerbRead}, []string{spec.String()}, err) }()
+	return s.server.ListImages(ctx, spec)
+}
+
+func (s *AuditingServer) ListImagesWithOptions(ctx context.Context, opts v10.ListImagesOptions) (_ []v6.ImageStatus, err error) {
+	defer func() { s.audit(ctx, "ListImagesWithOptions", []Verb{VerbRead}, []string{opts.Spec.String()}, err) }()
+	return s.server.ListImagesWithOptions(ctx, opts)
+}
+
+func (s *AuditingServer) UpdateManifests(ctx context.Context, spec update.Spec) (_ job.ID, err error) {
+	defer func() { s.audit(ctx, "UpdateManifests", verbsForSpec(spec), targetsForSpec(spec), err) }()
+	return s.server.UpdateManifests(ctx, spec)
+}
+
+func (s *AuditingServer) SyncStatus(ctx context.Context, ref string) (_ []string, err error) {
+	defer func() { s.audit(ctx, "SyncStatus", []Verb{VerbRead}, nil, err) }()
+	return s.server.SyncStatus(ctx, ref)
+}
+
+func (s *AuditingServer) JobStatus(ctx context.Context, id job.ID) (_ job.Status, err error) {
+	defer func() { s.audit(ctx, "JobStatus", []Verb{VerbRead}, nil, err) }()
+	return s.server.JobStatus(ctx, id)
+}
+
+func (s *AuditingServer) GitRepoConfig(ctx context.Context, regenerate bool) (_ v6.GitConfig, err error) {
+	verb := VerbRead
+	if regenerate {
+		verb = VerbAdmin
+	}
+	defer func() { s.audit(ctx, "GitRepoConfig", []Verb{verb}, nil, err) }()
+	return s.server.GitRepoConfig(ctx, regenerate)
+}
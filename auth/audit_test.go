@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/remote"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestAuditingServerRecordsCalls(t *testing.T) {
+	config, err := ParseRBACConfig([]byte(testRBACConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &remote.MockServer{ExportError: errors.New("boom")}
+	var buf bytes.Buffer
+	s := NewAuditingServer(NewAuthorizingServer(mock, config, &recordingEventWriter{}, log.NewNopLogger()),
+		log.NewNopLogger(), NewJSONAuditSink(&buf))
+
+	bob := WithIdentity(context.Background(), Identity{Email: "bob@example.com"})
+	jane := WithIdentity(context.Background(), Identity{Email: "jane@example.com"})
+	release := update.Spec{Type: update.Images, Spec: update.ReleaseSpec{
+		ServiceSpecs: []update.ResourceSpec{update.MakeResourceSpec(flux.MustParseResourceID("default:deployment/foo"))},
+	}}
+
+	s.ListServices(bob, "")
+	s.UpdateManifests(bob, release)
+	s.Export(jane)
+
+	dec := json.NewDecoder(&buf)
+	var records []AuditRecord
+	for dec.More() {
+		var r AuditRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected three audit records, got %d", len(records))
+	}
+	if r := records[0]; r.User != "bob@example.com" || r.Method != "ListServices" || r.Result != AuditResultOK {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r := records[1]; r.Result != AuditResultDenied || len(r.Targets) != 1 || r.Targets[0] != "default:deployment/foo" || r.Verbs[0] != VerbRelease {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r := records[2]; r.Result != AuditResultError || r.Error != "boom" {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestEventAuditSinkRecordsChanges(t *testing.T) {
+	events := &recordingEventWriter{}
+	sink := EventAuditSink{Events: events}
+	for _, r := range []AuditRecord{
+		{Method: "ListServices", Verbs: []Verb{VerbRead}, Result: AuditResultOK},
+		{Method: "UpdateManifests", Verbs: []Verb{VerbRelease}, Result: AuditResultOK},
+		{Method: "ListEvents", Verbs: []Verb{VerbRead}, Result: AuditResultDenied},
+		{Method: "PruneEvents", Verbs: []Verb{VerbAdmin}, Result: AuditResultError},
+	} {
+		if err := sink.Audit(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*events) != 2 {
+		t.Fatalf("expected only the calls that change something to be recorded, got %+v", *events)
+	}
+	for i, method := range []string{"UpdateManifests", "PruneEvents"} {
+		metadata, ok := (*events)[i].Metadata.(*event.AuditEventMetadata)
+		if !ok || metadata.Method != method {
+			t.Errorf("expected an audit event for %s, got %+v", method, (*events)[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceFlow obtains an ID token using the OAuth 2.0 device
+// authorization grant (RFC 8628), which suits a command-line tool
+// with no way to receive a redirect.
+type DeviceFlow struct {
+	Client   *http.Client
+	Provider ProviderConfig
+	ClientID string
+	Scopes   []string
+}
+
+// DeviceAuthorization is what the provider gives back when starting
+// the flow; the user must visit the verification URI and enter the
+// user code.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Start asks the provider for a device code.
+func (f *DeviceFlow) Start(ctx context.Context) (DeviceAuthorization, error) {
+	var da DeviceAuthorization
+	if f.Provider.DeviceAuthorizationEndpoint == "" {
+		return da, errors.New("identity provider does not support the device authorization grant")
+	}
+	scopes := append([]string{"openid"}, f.Scopes...)
+	resp, err := f.post(ctx, f.Provider.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {f.ClientID},
+		"scope":     {strings.Join(scopes, " ")},
+	})
+	if err != nil {
+		return da, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return da, fmt.Errorf("unexpected response %s requesting device code", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&da); err != nil {
+		return da, errors.Wrap(err, "decoding device authorization response")
+	}
+	if da.Interval <= 0 {
+		da.Interval = 5
+	}
+	return da, nil
+}
+
+// Wait polls the provider until the user has approved (or denied)
+// the request, or it expires, and returns the ID token issued.
+func (f *DeviceFlow) Wait(ctx context.Context, da DeviceAuthorization) (string, error) {
+	interval := time.Duration(da.Interval) * time.Second
+	if da.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(da.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", errors.New("timed out waiting for authorization")
+		case <-time.After(interval):
+		}
+
+		resp, err := f.post(ctx, f.Provider.TokenEndpoint, url.Values{
+			"client_id":   {f.ClientID},
+			"device_code": {da.DeviceCode},
+			"grant_type":  {deviceCodeGrantType},
+		})
+		if err != nil {
+			return "", err
+		}
+		var tok tokenResponse
+		err = json.NewDecoder(resp.Body).Decode(&tok)
+		resp.Body.Close()
+		if err != nil {
+			return "", errors.Wrap(err, "decoding token response")
+		}
+
+		switch tok.Error {
+		case "":
+			if tok.IDToken == "" {
+				return "", errors.New("identity provider did not issue an ID token")
+			}
+			return tok.IDToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			if tok.Description != "" {
+				return "", fmt.Errorf("%s: %s", tok.Error, tok.Description)
+			}
+			return "", errors.New(tok.Error)
+		}
+	}
+}
+
+func (f *DeviceFlow) post(ctx context.Context, endpoint string, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return f.Client.Do(req.WithContext(ctx))
+}
@@ -0,0 +1,43 @@
+/*
+Package auth deals with establishing who is making a request of the
+daemon's API, by verifying tokens issued by an OpenID Connect
+provider, and with obtaining such tokens for `fluxctl`.
+*/
+package auth
+
+import (
+	"context"
+)
+
+// Identity is the authenticated party behind a request, as asserted
+// by the identity provider.
+type Identity struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+}
+
+// Name gives a human-readable name for the identity, suitable for
+// recording as the user responsible for a change.
+func (id Identity) Name() string {
+	if id.Email != "" {
+		return id.Email
+	}
+	return id.Subject
+}
+
+type contextKey int
+
+const identityKey contextKey = 0
+
+// WithIdentity returns a context carrying the identity given.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// IdentityFromContext returns the identity attached to the context,
+// if there is one.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
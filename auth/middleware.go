@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	transport "github.com/weaveworks/flux/http"
+)
+
+// Authenticate wraps the handler given so that only requests bearing
+// a token accepted by the verifier get through. The identity from the
+// token is attached to the request's context.
+func Authenticate(v Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			transport.WriteError(w, r, http.StatusUnauthorized, transport.ErrorUnauthorized)
+			return
+		}
+		id, err := v.Verify(r.Context(), strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			transport.WriteError(w, r, http.StatusUnauthorized, transport.ErrorUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+	})
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+// Verifier checks a raw bearer token, and returns the identity it
+// asserts if it is valid.
+type Verifier interface {
+	Verify(ctx context.Context, rawToken string) (Identity, error)
+}
+
+// ProviderConfig is the subset of an OpenID Connect provider's
+// discovery document that we use.
+type ProviderConfig struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// Discover fetches the discovery document for the issuer given.
+func Discover(ctx context.Context, client *http.Client, issuerURL string) (ProviderConfig, error) {
+	var config ProviderConfig
+	wellKnown := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, wellKnown, &config); err != nil {
+		return config, errors.Wrap(err, "fetching OpenID provider configuration")
+	}
+	if strings.TrimSuffix(config.Issuer, "/") != strings.TrimSuffix(issuerURL, "/") {
+		return config, fmt.Errorf("issuer in provider configuration %q does not match %q", config.Issuer, issuerURL)
+	}
+	return config, nil
+}
+
+const (
+	// How far the clocks of the provider and fluxd may disagree,
+	// when checking when a token was issued and when it expires
+	clockSkew = time.Minute
+	// The provider's signing keys are refetched, for a token signed
+	// with a key we haven't seen, at most this often; otherwise
+	// anyone could make fluxd fetch them with every request
+	minRefetchInterval = time.Minute
+)
+
+// OIDCVerifier verifies ID tokens issued by an OpenID Connect
+// provider for a particular client. The provider's signing keys are
+// fetched on demand, and refetched when a token is signed with a key
+// we haven't seen (but not more than once a minute).
+type OIDCVerifier struct {
+	IssuerURL string
+	ClientID  string
+	// GroupsClaim names the claim holding the groups the subject
+	// belongs to; if empty, "groups" is used.
+	GroupsClaim string
+
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	lastFetch time.Time
+}
+
+func NewOIDCVerifier(client *http.Client, issuerURL, clientID string) *OIDCVerifier {
+	return &OIDCVerifier{
+		IssuerURL: issuerURL,
+		ClientID:  clientID,
+		client:    client,
+		keys:      map[string]*rsa.PublicKey{},
+	}
+}
+
+func (v *OIDCVerifier) Verify(ctx context.Context, rawToken string) (Identity, error) {
+	claims := jwt.MapClaims{}
+	// The times in the token are checked below, since the parser
+	// doesn't insist on them being there, and allows for no skew
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "verifying token")
+	}
+	if err := checkTimes(claims, time.Now()); err != nil {
+		return Identity{}, errors.Wrap(err, "verifying token")
+	}
+
+	if !claims.VerifyIssuer(v.IssuerURL, true) {
+		return Identity{}, errors.New("token was not issued by the expected issuer")
+	}
+	if !hasAudience(claims["aud"], v.ClientID) {
+		return Identity{}, errors.New("token was not issued for this client")
+	}
+
+	id := Identity{}
+	id.Subject, _ = claims["sub"].(string)
+	if id.Subject == "" {
+		return Identity{}, errors.New("token has no subject")
+	}
+	id.Email, _ = claims["email"].(string)
+	groupsClaim := v.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if groups, ok := claims[groupsClaim].([]interface{}); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+	return id, nil
+}
+
+// hasAudience checks the audience claim, which may be either a single
+// string or a list of strings.
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkTimes checks that the token has an expiry and a time it was
+// issued, as ID tokens must, and that it's valid at the time given,
+// give or take the clock skew allowed.
+func checkTimes(claims jwt.MapClaims, now time.Time) error {
+	exp, ok := numericDate(claims, "exp")
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.Add(-clockSkew).After(exp) {
+		return errors.New("token has expired")
+	}
+	iat, ok := numericDate(claims, "iat")
+	if !ok {
+		return errors.New("token has no time of issue")
+	}
+	if iat.After(now.Add(clockSkew)) {
+		return errors.New("token was issued in the future")
+	}
+	if _, present := claims["nbf"]; present {
+		nbf, ok := numericDate(claims, "nbf")
+		if !ok {
+			return errors.New("token has an invalid not-before time")
+		}
+		if nbf.After(now.Add(clockSkew)) {
+			return errors.New("token is not valid yet")
+		}
+	}
+	return nil
+}
+
+// numericDate gives the time in the claim named, which is in seconds
+// since the epoch, if it's there and is a number.
+func numericDate(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch secs := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(secs), 0), true
+	case json.Number:
+		n, err := secs.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	if k, ok := v.keys[kid]; ok {
+		v.mu.Unlock()
+		return k, nil
+	}
+	if time.Since(v.lastFetch) < minRefetchInterval {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("no signing key with ID %q", kid)
+	}
+	v.lastFetch = time.Now()
+	v.mu.Unlock()
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("no signing key with ID %q", kid)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (v *OIDCVerifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	config, err := Discover(ctx, v.client, v.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, v.client, config.JWKSURI, &set); err != nil {
+		return nil, errors.Wrap(err, "fetching signing keys")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding modulus of key %q", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding exponent of key %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, dest interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
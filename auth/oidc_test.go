@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const testClientID = "fluxctl"
+
+func newTestProvider(t *testing.T) (*httptest.Server, *rsa.PrivateKey) {
+	server, key, _ := newCountingProvider(t)
+	return server, key
+}
+
+// newCountingProvider is as newTestProvider, and gives a count of the
+// times the signing keys have been fetched.
+func newCountingProvider(t *testing.T) (*httptest.Server, *rsa.PrivateKey, *int) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProviderConfig{
+			Issuer:  server.URL,
+			JWKSURI: server.URL + "/keys",
+		})
+	})
+	var fetches int
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{
+			"keys": {{
+				Kid: "test",
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	server = httptest.NewServer(mux)
+	return server, key, &fetches
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	server, key := newTestProvider(t)
+	defer server.Close()
+
+	v := NewOIDCVerifier(server.Client(), server.URL, testClientID)
+	token := signToken(t, key, jwt.MapClaims{
+		"iss":    server.URL,
+		"aud":    []string{testClientID},
+		"sub":    "1234",
+		"email":  "jane@example.com",
+		"groups": []string{"ops"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iat":    time.Now().Unix(),
+	})
+	id, err := v.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "1234" || id.Name() != "jane@example.com" || len(id.Groups) != 1 || id.Groups[0] != "ops" {
+		t.Errorf("unexpected identity %+v", id)
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	server, key := newTestProvider(t)
+	defer server.Close()
+	v := NewOIDCVerifier(server.Client(), server.URL, testClientID)
+
+	now, hour := time.Now(), time.Hour
+	valid := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"iss": server.URL, "aud": testClientID, "sub": "1234", "exp": now.Add(hour).Unix(), "iat": now.Unix()}
+		for k, v := range overrides {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+	for name, claims := range map[string]jwt.MapClaims{
+		"expired":                 valid(jwt.MapClaims{"exp": now.Add(-hour).Unix()}),
+		"no expiry":               valid(jwt.MapClaims{"exp": nil}),
+		"no time of issue":        valid(jwt.MapClaims{"iat": nil}),
+		"issued in future":        valid(jwt.MapClaims{"iat": now.Add(hour).Unix()}),
+		"not yet valid":           valid(jwt.MapClaims{"nbf": now.Add(hour).Unix()}),
+		"invalid nbf":             valid(jwt.MapClaims{"nbf": "soon"}),
+		"wrong issuer":            valid(jwt.MapClaims{"iss": "https://elsewhere.example.com"}),
+		"wrong audience":          valid(jwt.MapClaims{"aud": "someone-else"}),
+		"no subject":              valid(jwt.MapClaims{"sub": nil}),
+		"wrong issuer, no expiry": {"iss": "https://elsewhere.example.com", "aud": testClientID, "sub": "1234"},
+	} {
+		if _, err := v.Verify(context.Background(), signToken(t, key, claims)); err == nil {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+	// A little clock skew is allowed for
+	if _, err := v.Verify(context.Background(), signToken(t, key, valid(jwt.MapClaims{"iat": now.Add(10 * time.Second).Unix()}))); err != nil {
+		t.Errorf("expected token issued a few seconds ahead to be accepted, got %v", err)
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := signToken(t, other, valid(nil))
+	if _, err := v.Verify(context.Background(), forged); err == nil {
+		t.Error("expected token signed with unknown key to be rejected")
+	}
+}
+
+func TestVerifyRefetchesKeysAtMostOnceAMinute(t *testing.T) {
+	server, key, fetches := newCountingProvider(t)
+	defer server.Close()
+	v := NewOIDCVerifier(server.Client(), server.URL, testClientID)
+
+	claims := jwt.MapClaims{"iss": server.URL, "aud": testClientID, "sub": "1234", "exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix()}
+	if _, err := v.Verify(context.Background(), signToken(t, key, claims)); err != nil {
+		t.Fatal(err)
+	}
+	unknown := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	unknown.Header["kid"] = "unknown"
+	signed, err := unknown.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown keys don't make it fetch the keys again, so soon
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), signed); err == nil {
+			t.Error("expected token signed with unknown key to be rejected")
+		}
+	}
+	if *fetches != 1 {
+		t.Errorf("expected the keys to be fetched once, got %d", *fetches)
+	}
+
+	// .. but do after a minute
+	v.mu.Lock()
+	v.lastFetch = v.lastFetch.Add(-2 * minRefetchInterval)
+	v.mu.Unlock()
+	if _, err := v.Verify(context.Background(), signed); err == nil {
+		t.Error("expected token signed with unknown key to be rejected")
+	}
+	if *fetches != 2 {
+		t.Errorf("expected the keys to be fetched again, got %d fetches", *fetches)
+	}
+}
+
+type staticVerifier Identity
+
+func (v staticVerifier) Verify(_ context.Context, token string) (Identity, error) {
+	if token != "good" {
+		return Identity{}, jwt.ErrSignatureInvalid
+	}
+	return Identity(v), nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	var seen Identity
+	handler := Authenticate(staticVerifier{Subject: "1234"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = IdentityFromContext(r.Context())
+	}))
+
+	for header, code := range map[string]int{
+		"":            http.StatusUnauthorized,
+		"Bearer bad":  http.StatusUnauthorized,
+		"Bearer good": http.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/v11/services", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("%q: expected status %d, got %d", header, code, rec.Code)
+		}
+	}
+	if seen.Subject != "1234" {
+		t.Errorf("expected identity to be passed to handler, got %+v", seen)
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	authzv1 "k8s.io/api/authorization/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// Verb is a class of operation on the API, for the purpose of
+// deciding who may do what.
+type Verb string
+
+const (
+	// VerbRead covers listing workloads and images, and looking at
+	// job and sync status.
+	VerbRead Verb = "read"
+	// VerbRelease covers releasing images to workloads.
+	VerbRelease Verb = "release"
+	// VerbPolicy covers changing policies other than locks, e.g.,
+	// automating workloads or setting tag filters.
+	VerbPolicy Verb = "policy"
+	// VerbLock covers locking and unlocking workloads.
+	VerbLock Verb = "lock"
+	// VerbSync covers asking for a sync with the git repo.
+	VerbSync Verb = "sync"
+	// VerbAnnotate covers commenting on and acknowledging events.
+	VerbAnnotate Verb = "annotate"
+	// VerbAdmin covers anything else, like regenerating the deploy
+	// key.
+	VerbAdmin Verb = "admin"
+	// VerbAll may be used in a role to mean every verb.
+	VerbAll Verb = "*"
+)
+
+// Authorizer decides whether an identity may perform an operation.
+type Authorizer interface {
+	Authorize(id Identity, verb Verb) (bool, error)
+}
+
+// RBACConfig maps identities onto roles, each of which is allowed a
+// set of verbs. It is usually loaded from a file like
+//
+//     roles:
+//       viewer: [read]
+//       deployer: [read, release, sync]
+//       admin: ["*"]
+//     bindings:
+//     - role: deployer
+//       groups: [developers]
+//     - role: admin
+//       users: [jane@example.com]
+//
+// Users are matched by the name of the identity (i.e., usually its
+// email address), or its subject.
+type RBACConfig struct {
+	Roles    map[string][]Verb `yaml:"roles"`
+	Bindings []RoleBinding     `yaml:"bindings"`
+}
+
+type RoleBinding struct {
+	Role   string   `yaml:"role"`
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
+// LoadRBACConfig reads and checks an RBAC configuration file.
+func LoadRBACConfig(path string) (*RBACConfig, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading RBAC config")
+	}
+	return ParseRBACConfig(bytes)
+}
+
+func ParseRBACConfig(bytes []byte) (*RBACConfig, error) {
+	var config RBACConfig
+	if err := yaml.Unmarshal(bytes, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing RBAC config")
+	}
+	for _, b := range config.Bindings {
+		if _, ok := config.Roles[b.Role]; !ok {
+			return nil, fmt.Errorf("binding refers to undefined role %q", b.Role)
+		}
+	}
+	return &config, nil
+}
+
+func (c *RBACConfig) Authorize(id Identity, verb Verb) (bool, error) {
+	for _, b := range c.Bindings {
+		if !b.matches(id) {
+			continue
+		}
+		for _, v := range c.Roles[b.Role] {
+			if v == verb || v == VerbAll {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (b RoleBinding) matches(id Identity) bool {
+	for _, u := range b.Users {
+		if u == id.Name() || u == id.Subject {
+			return true
+		}
+	}
+	for _, g := range b.Groups {
+		for _, ig := range id.Groups {
+			if g == ig {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// KubernetesAuthorizer defers decisions to the Kubernetes API server,
+// by asking whether the identity may perform the verb on the
+// (virtual) resource `fluxapi` in the API group `flux.weave.works`.
+// This means access can be granted using ordinary Kubernetes RBAC
+// roles and bindings, e.g.,
+//
+//     rules:
+//     - apiGroups: ["flux.weave.works"]
+//       resources: ["fluxapi"]
+//       verbs: ["read", "release"]
+type KubernetesAuthorizer struct {
+	Client k8sclient.Interface
+	// Namespace, if not empty, is the namespace in which access is
+	// checked, so a Role there rather than a ClusterRole can grant
+	// access.
+	Namespace string
+}
+
+func (a *KubernetesAuthorizer) Authorize(id Identity, verb Verb) (bool, error) {
+	review := &authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   id.Name(),
+			Groups: id.Groups,
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Namespace: a.Namespace,
+				Group:     "flux.weave.works",
+				Resource:  "fluxapi",
+				Verb:      string(verb),
+			},
+		},
+	}
+	result, err := a.Client.AuthorizationV1().SubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, errors.Wrap(err, "checking access with Kubernetes API")
+	}
+	return result.Status.Allowed, nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/remote"
+	"github.com/weaveworks/flux/update"
+)
+
+const testRBACConfig = `
+roles:
+  viewer: [read]
+  deployer: [read, release, sync]
+  admin: ["*"]
+bindings:
+- role: deployer
+  groups: [developers]
+- role: admin
+  users: [jane@example.com]
+- role: viewer
+  users: [bob@example.com]
+`
+
+func TestRBACConfigAuthorize(t *testing.T) {
+	config, err := ParseRBACConfig([]byte(testRBACConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		id      Identity
+		verb    Verb
+		allowed bool
+	}{
+		{Identity{Email: "bob@example.com"}, VerbRead, true},
+		{Identity{Email: "bob@example.com"}, VerbRelease, false},
+		{Identity{Subject: "dev", Groups: []string{"developers"}}, VerbRelease, true},
+		{Identity{Subject: "dev", Groups: []string{"developers"}}, VerbLock, false},
+		{Identity{Email: "jane@example.com"}, VerbAdmin, true},
+		{Identity{Email: "nobody@example.com"}, VerbRead, false},
+	} {
+		allowed, err := config.Authorize(c.id, c.verb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != c.allowed {
+			t.Errorf("%s %s: expected allowed=%v", c.id.Name(), c.verb, c.allowed)
+		}
+	}
+}
+
+func TestParseRBACConfigUndefinedRole(t *testing.T) {
+	if _, err := ParseRBACConfig([]byte("bindings:\n- role: missing\n  users: [bob]\n")); err == nil {
+		t.Error("expected error for binding to undefined role")
+	}
+}
+
+func TestVerbsForPolicySpec(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/foo")
+	lock := update.Spec{Type: update.Policy, Spec: policy.Updates{
+		id: {Add: policy.Set{policy.Locked: "true", policy.LockedUser: "jane"}},
+	}}
+	if verbs := verbsForSpec(lock); len(verbs) != 1 || verbs[0] != VerbLock {
+		t.Errorf("expected lock to need only %q, got %v", VerbLock, verbs)
+	}
+	automate := update.Spec{Type: update.Policy, Spec: policy.Updates{
+		id: {Add: policy.Set{policy.Automated: "true"}},
+	}}
+	if verbs := verbsForSpec(automate); len(verbs) != 1 || verbs[0] != VerbPolicy {
+		t.Errorf("expected automate to need only %q, got %v", VerbPolicy, verbs)
+	}
+}
+
+type recordingEventWriter []event.Event
+
+func (w *recordingEventWriter) LogEvent(ev event.Event) error {
+	*w = append(*w, ev)
+	return nil
+}
+
+func TestAuthorizingServerRecordsDenials(t *testing.T) {
+	config, err := ParseRBACConfig([]byte(testRBACConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := &recordingEventWriter{}
+	s := NewAuthorizingServer(&remote.MockServer{}, config, events, log.NewNopLogger())
+
+	ctx := WithIdentity(context.Background(), Identity{Email: "bob@example.com"})
+	if _, err := s.ListServices(ctx, ""); err != nil {
+		t.Errorf("expected read to be allowed, got %v", err)
+	}
+	if _, err := s.UpdateManifests(ctx, update.Spec{Type: update.Sync}); err != ErrForbidden {
+		t.Errorf("expected sync to be forbidden, got %v", err)
+	}
+	if _, err := s.ListServices(context.Background(), ""); err != ErrForbidden {
+		t.Errorf("expected unauthenticated request to be forbidden, got %v", err)
+	}
+
+	if len(*events) != 2 {
+		t.Fatalf("expected two access denied events, got %d", len(*events))
+	}
+	md := (*events)[0].Metadata.(*event.AccessDeniedEventMetadata)
+	if md.User != "bob@example.com" || md.Verb != string(VerbSync) || md.Method != "UpdateManifests" {
+		t.Errorf("unexpected event metadata %+v", md)
+	}
+}
@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v10"
+	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
+	"github.com/weaveworks/flux/api/v6"
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+var _ api.Server = &AuthorizingServer{}
+
+// ErrForbidden is returned when a request is refused by the
+// authorizer.
+var ErrForbidden = &fluxerr.Error{
+	Type: fluxerr.User,
+	Help: `You are not allowed to do that.
+
+The identity you authenticated as does not have permission to make
+this request. If you think it should, ask whoever administers Flux to
+grant you a role that includes it.
+`,
+	Err: errors.New("access denied"),
+}
+
+// AuthorizingServer wraps an api.Server, checking that the identity
+// attached to each request is allowed to make it. Refused requests
+// are recorded as events.
+type AuthorizingServer struct {
+	server     api.Server
+	authorizer Authorizer
+	events     event.EventWriter
+	logger     log.Logger
+}
+
+func NewAuthorizingServer(s api.Server, a Authorizer, events event.EventWriter, logger log.Logger) *AuthorizingServer {
+	return &AuthorizingServer{
+		server:     s,
+		authorizer: a,
+		events:     events,
+		logger:     logger,
+	}
+}
+
+func (s *AuthorizingServer) authorize(ctx context.Context, method string, verbs ...Verb) error {
+	id, ok := IdentityFromContext(ctx)
+	for _, verb := range verbs {
+		allowed := false
+		if ok {
+			var err error
+			if allowed, err = s.authorizer.Authorize(id, verb); err != nil {
+				return err
+			}
+		}
+		if !allowed {
+			s.denied(id, verb, method)
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+func (s *AuthorizingServer) denied(id Identity, verb Verb, method string) {
+	user := id.Name()
+	if user == "" {
+		user = "<unauthenticated>"
+	}
+	now := time.Now().UTC()
+	ev := event.Event{
+		Type:      event.EventAccessDenied,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelWarn,
+		Metadata: &event.AccessDeniedEventMetadata{
+			User:   user,
+			Verb:   string(verb),
+			Method: method,
+		},
+	}
+	if err := s.events.LogEvent(ev); err != nil {
+		s.logger.Log("method", method, "err", err)
+	}
+}
+
+// verbsForSpec works out what an update would need permission to do.
+func verbsForSpec(spec update.Spec) []Verb {
+	switch spec.Type {
+	case update.Images, update.Containers, update.Auto, update.Charts, update.Rollback, update.Restart:
+		return []Verb{VerbRelease}
+	case update.Sync:
+		// Confirming a sync that was held back may change a lot of
+		// the cluster, so needs the same permission as a release
+		if s, ok := spec.Spec.(update.ManualSync); ok && s.Confirm != "" {
+			return []Verb{VerbSync, VerbRelease}
+		}
+		return []Verb{VerbSync}
+	case update.Policy:
+		updates, _ := spec.Spec.(policy.Updates)
+		var lock, other bool
+		for _, u := range updates {
+			for _, set := range []policy.Set{u.Add, u.Remove} {
+				for p := range set {
+					switch p {
+					case policy.Locked, policy.LockedUser, policy.LockedMsg, policy.LockedUntil:
+						lock = true
+					default:
+						other = true
+					}
+				}
+			}
+		}
+		var verbs []Verb
+		if lock {
+			verbs = append(verbs, VerbLock)
+		}
+		if other || !lock {
+			verbs = append(verbs, VerbPolicy)
+		}
+		return verbs
+	}
+	return []Verb{VerbAdmin}
+}
+
+func (s *AuthorizingServer) Export(ctx context.Context) ([]byte, error) {
+	if err := s.authorize(ctx, "Export", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.Export(ctx)
+}
+
+func (s *AuthorizingServer) ListServices(ctx context.Context, namespace string) ([]v6.ControllerStatus, error) {
+	if err := s.authorize(ctx, "ListServices", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListServices(ctx, namespace)
+}
+
+func (s *AuthorizingServer) ListServicesWithOptions(ctx context.Context, opts v11.ListServicesOptions) ([]v6.ControllerStatus, error) {
+	if err := s.authorize(ctx, "ListServicesWithOptions", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListServicesWithOptions(ctx, opts)
+}
+
+func (s *AuthorizingServer) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) ([]v12.WorkloadDeployment, error) {
+	if err := s.authorize(ctx, "ListDeployments", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListDeployments(ctx, opts)
+}
+
+func (s *AuthorizingServer) ListEvents(ctx context.Context, opts v13.ListEventsOptions) ([]event.Event, error) {
+	if err := s.authorize(ctx, "ListEvents", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListEvents(ctx, opts)
+}
+
+func (s *AuthorizingServer) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (v14.WorkloadCheck, error) {
+	if err := s.authorize(ctx, "CheckWorkload", VerbRead); err != nil {
+		return v14.WorkloadCheck{}, err
+	}
+	return s.server.CheckWorkload(ctx, opts)
+}
+
+func (s *AuthorizingServer) ImageReport(ctx context.Context, opts v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	if err := s.authorize(ctx, "ImageReport", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ImageReport(ctx, opts)
+}
+
+func (s *AuthorizingServer) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	if err := s.authorize(ctx, "DeliveryReport", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.DeliveryReport(ctx, opts)
+}
+
+func (s *AuthorizingServer) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	if err := s.authorize(ctx, "CompareImages", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.CompareImages(ctx, opts)
+}
+
+func (s *AuthorizingServer) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) ([]byte, error) {
+	if err := s.authorize(ctx, "ExportCluster", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ExportCluster(ctx, opts)
+}
+
+func (s *AuthorizingServer) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (v19.DeployedState, error) {
+	if err := s.authorize(ctx, "DeployedAt", VerbRead); err != nil {
+		return v19.DeployedState{}, err
+	}
+	return s.server.DeployedAt(ctx, opts)
+}
+
+func (s *AuthorizingServer) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (event.Event, error) {
+	if err := s.authorize(ctx, "AnnotateEvent", VerbAnnotate); err != nil {
+		return event.Event{}, err
+	}
+	return s.server.AnnotateEvent(ctx, opts)
+}
+
+func (s *AuthorizingServer) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	if err := s.authorize(ctx, "DiffRevisions", VerbRead); err != nil {
+		return v21.RevisionDiff{}, err
+	}
+	return s.server.DiffRevisions(ctx, opts)
+}
+
+func (s *AuthorizingServer) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	if err := s.authorize(ctx, "EventHistory", VerbRead); err != nil {
+		return v22.EventHistory{}, err
+	}
+	return s.server.EventHistory(ctx, opts)
+}
+
+func (s *AuthorizingServer) MetricsSnapshot(ctx context.Context) (v23.MetricsSnapshot, error) {
+	if err := s.authorize(ctx, "MetricsSnapshot", VerbRead); err != nil {
+		return v23.MetricsSnapshot{}, err
+	}
+	return s.server.MetricsSnapshot(ctx)
+}
+
+func (s *AuthorizingServer) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	if err := s.authorize(ctx, "PruneEvents", VerbAdmin); err != nil {
+		return v24.PruneEventsResult{}, err
+	}
+	return s.server.PruneEvents(ctx, opts)
+}
+
+func (s *AuthorizingServer) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	if err := s.authorize(ctx, "ReplayEvents", VerbRead); err != nil {
+		return v25.ReplayEventsResult{}, err
+	}
+	return s.server.ReplayEvents(ctx, opts)
+}
+
+func (s *AuthorizingServer) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	if err := s.authorize(ctx, "ComplianceReport", VerbRead); err != nil {
+		return v26.ComplianceReport{}, err
+	}
+	return s.server.ComplianceReport(ctx, opts)
+}
+
+func (s *AuthorizingServer) LogEvents(ctx context.Context, opts v27.LogEventsOptions) error {
+	if err := s.authorize(ctx, "LogEvents", VerbAdmin); err != nil {
+		return err
+	}
+	return s.server.LogEvents(ctx, opts)
+}
+
+func (s *AuthorizingServer) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	if err := s.authorize(ctx, "WorkloadHistory", VerbRead); err != nil {
+		return v28.WorkloadHistory{}, err
+	}
+	return s.server.WorkloadHistory(ctx, opts)
+}
+
+func (s *AuthorizingServer) JobQueue(ctx context.Context) (v29.JobQueue, error) {
+	if err := s.authorize(ctx, "JobQueue", VerbRead); err != nil {
+		return v29.JobQueue{}, err
+	}
+	return s.server.JobQueue(ctx)
+}
+
+func (s *AuthorizingServer) ListImages(ctx context.Context, spec update.ResourceSpec) ([]v6.ImageStatus, error) {
+	if err := s.authorize(ctx, "ListImages", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListImages(ctx, spec)
+}
+
+func (s *AuthorizingServer) ListImagesWithOptions(ctx context.Context, opts v10.ListImagesOptions) ([]v6.ImageStatus, error) {
+	if err := s.authorize(ctx, "ListImagesWithOptions", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.ListImagesWithOptions(ctx, opts)
+}
+
+func (s *AuthorizingServer) UpdateManifests(ctx context.Context, spec update.Spec) (job.ID, error) {
+	if err := s.authorize(ctx, "UpdateManifests", verbsForSpec(spec)...); err != nil {
+		return "", err
+	}
+	return s.server.UpdateManifests(ctx, spec)
+}
+
+func (s *AuthorizingServer) SyncStatus(ctx context.Context, ref string) ([]string, error) {
+	if err := s.authorize(ctx, "SyncStatus", VerbRead); err != nil {
+		return nil, err
+	}
+	return s.server.SyncStatus(ctx, ref)
+}
+
+func (s *AuthorizingServer) JobStatus(ctx context.Context, id job.ID) (job.Status, error) {
+	if err := s.authorize(ctx, "JobStatus", VerbRead); err != nil {
+		return job.Status{}, err
+	}
+	return s.server.JobStatus(ctx, id)
+}
+
+func (s *AuthorizingServer) GitRepoConfig(ctx context.Context, regenerate bool) (v6.GitConfig, error) {
+	verb := VerbRead
+	if regenerate {
+		verb = VerbAdmin
+	}
+	if err := s.authorize(ctx, "GitRepoConfig", verb); err != nil {
+		return v6.GitConfig{}, err
+	}
+	return s.server.GitRepoConfig(ctx, regenerate)
+}
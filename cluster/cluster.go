@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"errors"
+	"time"
 
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/resource"
@@ -17,8 +18,81 @@ type Cluster interface {
 	SomeControllers([]flux.ResourceID) ([]Controller, error)
 	Ping() error
 	Export() ([]byte, error)
+	// Export the namespaces given (or all of them), and the
+	// resources of the kinds given (or all kinds) in them, cleaned
+	// of fields filled in by the cluster
+	ExportResources(namespaces, kinds []string) ([]byte, error)
 	Sync(SyncDef) error
 	PublicSSHKey(regenerate bool) (ssh.PublicKey, error)
+	// Check whether there's room in the cluster to roll out new pods
+	// for the workloads given
+	CheckCapacity([]flux.ResourceID) (CapacityReport, error)
+	// Find the fields of the resources given that something other
+	// than flux has changed since flux last applied them
+	SyncConflicts([]flux.ResourceID) ([]SyncConflict, error)
+	// Roll out new pods for the workload given, without changing it
+	// otherwise; the time given is recorded as when it was restarted
+	Restart(flux.ResourceID, time.Time) error
+}
+
+// CapacityReport says whether the pods needed to roll out changes to
+// some workloads can be scheduled, given the resources requested by
+// the pods already running and those allocatable on the nodes. CPU is
+// in millicores, and memory in bytes.
+type CapacityReport struct {
+	// Allocatable less requested, summed over the schedulable nodes
+	FreeCPU    int64 `json:"freeCPU"`
+	FreeMemory int64 `json:"freeMemory"`
+	// Requested by the extra pods, summed over all workloads
+	RequestedCPU    int64              `json:"requestedCPU"`
+	RequestedMemory int64              `json:"requestedMemory"`
+	Workloads       []WorkloadCapacity `json:"workloads,omitempty"`
+}
+
+// WorkloadCapacity is the part of a capacity report for one
+// workload.
+type WorkloadCapacity struct {
+	ID flux.ResourceID `json:"id"`
+	// The number of pods that will run alongside the existing pods
+	// during the rollout (e.g., the surge of a rolling update)
+	ExtraPods int   `json:"extraPods"`
+	CPU       int64 `json:"cpu"`    // requested by each pod
+	Memory    int64 `json:"memory"` // requested by each pod
+	// How many of the extra pods wouldn't fit on any node
+	Unschedulable int `json:"unschedulable,omitempty"`
+}
+
+// Unschedulable gives the number of pods, over all workloads, that
+// wouldn't fit on any node.
+func (r CapacityReport) Unschedulable() int {
+	var n int
+	for _, w := range r.Workloads {
+		n += w.Unschedulable
+	}
+	return n
+}
+
+// Sufficient says whether all the pods needed can be scheduled.
+func (r CapacityReport) Sufficient() bool {
+	return r.Unschedulable() == 0
+}
+
+// SyncConflict is a field of a resource that was changed in the
+// cluster, by something other than flux, after flux applied it; e.g.,
+// the replicas of a deployment scaled by a HorizontalPodAutoscaler.
+// Flux will set it back at each sync, and the other actor will likely
+// change it again.
+type SyncConflict struct {
+	ID flux.ResourceID `json:"id"`
+	// The path to the field, e.g., `spec.replicas`, or
+	// `spec.template.spec.containers[name=app].image`
+	Field string `json:"field"`
+	// The value flux applied, and that in the cluster, as JSON
+	Applied string `json:"applied"`
+	Live    string `json:"live"`
+	// The field manager that last changed the field, if the cluster
+	// says (e.g., `kube-controller-manager`)
+	Manager string `json:"manager,omitempty"`
 }
 
 // RolloutStatus describes numbers of pods in different states and
@@ -56,9 +130,14 @@ type Controller struct {
 	// resource through some mechanism (like an operator, or custom
 	// resource controller), we try to record the ID of that resource
 	// in this field.
-	Antecedent flux.ResourceID
-	Labels     map[string]string
-	Rollout    RolloutStatus
+	Antecedent  flux.ResourceID
+	Labels      map[string]string
+	Annotations map[string]string
+	Rollout     RolloutStatus
+	// Whether the images of the controller can't be changed once it
+	// exists, as for Kubernetes Jobs and Pods; these are synced and
+	// reported on, but not updated by releases or automation
+	Immutable bool
 
 	Containers ContainersOrExcuse
 }
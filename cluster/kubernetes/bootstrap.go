@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux/cluster"
+)
+
+// The name given to the role binding generated for a namespace
+const bootstrapRoleBinding = "flux-bootstrap"
+
+// BootstrapNamespace gives a multidoc with the namespace, a role
+// binding for the cluster role given (if any), and the default
+// service account referring to the image pull secrets given (if
+// any). Syncing applies namespaces first, then service accounts, then
+// role bindings, so they're created in the right order even when
+// they're synced along with the workloads that need them.
+func (c *Manifests) BootstrapNamespace(namespace string, bootstrap cluster.NamespaceBootstrap) ([]byte, error) {
+	docs := []yaml.MapSlice{{
+		{Key: "apiVersion", Value: "v1"},
+		{Key: "kind", Value: "Namespace"},
+		{Key: "metadata", Value: yaml.MapSlice{{Key: "name", Value: namespace}}},
+	}}
+
+	if bootstrap.ClusterRole != "" && len(bootstrap.Subjects) > 0 {
+		var subjects []yaml.MapSlice
+		for _, s := range bootstrap.Subjects {
+			parts := strings.Split(s, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("service account %q is not of the form <namespace>/<name>", s)
+			}
+			subjects = append(subjects, yaml.MapSlice{
+				{Key: "kind", Value: "ServiceAccount"},
+				{Key: "name", Value: parts[1]},
+				{Key: "namespace", Value: parts[0]},
+			})
+		}
+		docs = append(docs, yaml.MapSlice{
+			{Key: "apiVersion", Value: "rbac.authorization.k8s.io/v1"},
+			{Key: "kind", Value: "RoleBinding"},
+			{Key: "metadata", Value: yaml.MapSlice{
+				{Key: "name", Value: bootstrapRoleBinding},
+				{Key: "namespace", Value: namespace},
+			}},
+			{Key: "roleRef", Value: yaml.MapSlice{
+				{Key: "apiGroup", Value: "rbac.authorization.k8s.io"},
+				{Key: "kind", Value: "ClusterRole"},
+				{Key: "name", Value: bootstrap.ClusterRole},
+			}},
+			{Key: "subjects", Value: subjects},
+		})
+	}
+
+	if len(bootstrap.PullSecrets) > 0 {
+		var secrets []yaml.MapSlice
+		for _, name := range bootstrap.PullSecrets {
+			secrets = append(secrets, yaml.MapSlice{{Key: "name", Value: name}})
+		}
+		docs = append(docs, yaml.MapSlice{
+			{Key: "apiVersion", Value: "v1"},
+			{Key: "kind", Value: "ServiceAccount"},
+			{Key: "metadata", Value: yaml.MapSlice{
+				{Key: "name", Value: "default"},
+				{Key: "namespace", Value: namespace},
+			}},
+			{Key: "imagePullSecrets", Value: secrets},
+		})
+	}
+
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		bytes, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(bytes)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster"
+)
+
+func TestBootstrapNamespace(t *testing.T) {
+	m := &Manifests{}
+	out, err := m.BootstrapNamespace("payments", cluster.NamespaceBootstrap{
+		ClusterRole: "admin",
+		Subjects:    []string{"flux/flux"},
+		PullSecrets: []string{"registry-creds"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := m.ParseManifests(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{
+		"default:namespace/payments",
+		"payments:rolebinding/flux-bootstrap",
+		"payments:serviceaccount/default",
+	} {
+		if _, ok := resources[id]; !ok {
+			t.Errorf("expected %s in bootstrap manifests:\n%s", id, out)
+		}
+	}
+	for _, expected := range []string{
+		"name: admin\n",
+		"- kind: ServiceAccount\n  name: flux\n  namespace: flux\n",
+		"imagePullSecrets:\n- name: registry-creds\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected %q in bootstrap manifests:\n%s", expected, out)
+		}
+	}
+
+	// Without a role or pull secrets, only the namespace is given
+	out, err = m.BootstrapNamespace("payments", cluster.NamespaceBootstrap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources, err = m.ParseManifests(out); err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 {
+		t.Errorf("expected only the namespace, got:\n%s", out)
+	}
+
+	if _, err = m.BootstrapNamespace("payments", cluster.NamespaceBootstrap{ClusterRole: "admin", Subjects: []string{"flux"}}); err == nil {
+		t.Error("expected error for service account without namespace")
+	}
+}
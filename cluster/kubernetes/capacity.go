@@ -0,0 +1,179 @@
+package kubernetes
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	apiapps "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+)
+
+// The default maxSurge of a Deployment's rolling update
+var defaultMaxSurge = intstr.FromString("25%")
+
+// CheckCapacity works out how many pods will be added while the
+// workloads given roll out, and whether they fit on the schedulable
+// nodes, given the resources requested by the pods already there.
+//
+// This is an estimate: only requests for CPU and memory are taken into
+// account (not taints, affinities, or node selectors), and only
+// Deployments using rolling updates need extra pods, since the other
+// kinds replace their pods one by one.
+func (c *Cluster) CheckCapacity(ids []flux.ResourceID) (cluster.CapacityReport, error) {
+	var report cluster.CapacityReport
+	free, err := c.freeCapacity()
+	if err != nil {
+		return report, err
+	}
+	for _, n := range free {
+		report.FreeCPU += n.cpu
+		report.FreeMemory += n.memory
+	}
+
+	for _, id := range ids {
+		w, err := c.workloadCapacity(id)
+		if err != nil {
+			return report, errors.Wrapf(err, "checking capacity for %s", id)
+		}
+		for i := 0; i < w.ExtraPods; i++ {
+			if !free.place(w.CPU, w.Memory) {
+				w.Unschedulable++
+			}
+		}
+		report.RequestedCPU += int64(w.ExtraPods) * w.CPU
+		report.RequestedMemory += int64(w.ExtraPods) * w.Memory
+		report.Workloads = append(report.Workloads, w)
+	}
+	return report, nil
+}
+
+func (c *Cluster) workloadCapacity(id flux.ResourceID) (cluster.WorkloadCapacity, error) {
+	w := cluster.WorkloadCapacity{ID: id}
+	ns, kind, name := id.Components()
+	if kind != "deployment" {
+		return w, nil
+	}
+	deployment, err := c.client.AppsV1().Deployments(ns).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return w, err
+	}
+	w.CPU, w.Memory = podRequests(deployment.Spec.Template.Spec)
+
+	strategy := deployment.Spec.Strategy
+	if strategy.Type == apiapps.RecreateDeploymentStrategyType {
+		// Recreate stops the old pods before starting new ones
+		return w, nil
+	}
+	replicas := 1
+	if deployment.Spec.Replicas != nil {
+		replicas = int(*deployment.Spec.Replicas)
+	}
+	maxSurge := &defaultMaxSurge
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.MaxSurge != nil {
+		maxSurge = strategy.RollingUpdate.MaxSurge
+	}
+	surge, err := intstr.GetValueFromIntOrPercent(maxSurge, replicas, true)
+	if err != nil {
+		return w, err
+	}
+	if surge > replicas {
+		surge = replicas
+	}
+	w.ExtraPods = surge
+	return w, nil
+}
+
+type nodeCapacity struct {
+	cpu, memory int64
+}
+
+type nodeCapacities []*nodeCapacity
+
+// place finds room for a pod on the node with the most CPU free, and
+// says whether there was room.
+func (ns nodeCapacities) place(cpu, memory int64) bool {
+	for _, n := range ns {
+		if n.cpu >= cpu && n.memory >= memory {
+			n.cpu -= cpu
+			n.memory -= memory
+			sort.SliceStable(ns, func(i, j int) bool { return ns[i].cpu > ns[j].cpu })
+			return true
+		}
+	}
+	return false
+}
+
+// freeCapacity gives the resources allocatable on each node that's
+// ready and schedulable, less those requested by the pods running on
+// it, most CPU free first.
+func (c *Cluster) freeCapacity() (nodeCapacities, error) {
+	nodes, err := c.client.CoreV1().Nodes().List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing nodes")
+	}
+	byName := map[string]*nodeCapacity{}
+	var free nodeCapacities
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		n := &nodeCapacity{
+			cpu:    node.Status.Allocatable.Cpu().MilliValue(),
+			memory: node.Status.Allocatable.Memory().Value(),
+		}
+		byName[node.Name] = n
+		free = append(free, n)
+	}
+
+	pods, err := c.client.CoreV1().Pods("").List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing pods")
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+			continue
+		}
+		n, ok := byName[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		cpu, memory := podRequests(pod.Spec)
+		n.cpu -= cpu
+		n.memory -= memory
+	}
+	sort.SliceStable(free, func(i, j int) bool { return free[i].cpu > free[j].cpu })
+	return free, nil
+}
+
+func nodeReady(node apiv1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == apiv1.NodeReady {
+			return cond.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podRequests gives the CPU (in millicores) and memory (in bytes)
+// requested by a pod: the sum over its containers, or the most any
+// one init container requests, if that's more.
+func podRequests(spec apiv1.PodSpec) (cpu, memory int64) {
+	for _, container := range spec.Containers {
+		cpu += container.Resources.Requests.Cpu().MilliValue()
+		memory += container.Resources.Requests.Memory().Value()
+	}
+	for _, container := range spec.InitContainers {
+		if v := container.Resources.Requests.Cpu().MilliValue(); v > cpu {
+			cpu = v
+		}
+		if v := container.Resources.Requests.Memory().Value(); v > memory {
+			memory = v
+		}
+	}
+	return cpu, memory
+}
@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	apiapps "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/weaveworks/flux"
+)
+
+func requests(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func node(name string, ready bool, cpu, memory string) *apiv1.Node {
+	status := apiv1.ConditionTrue
+	if !ready {
+		status = apiv1.ConditionFalse
+	}
+	return &apiv1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name},
+		Status: apiv1.NodeStatus{
+			Allocatable: requests(cpu, memory),
+			Conditions:  []apiv1.NodeCondition{{Type: apiv1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func podSpec(cpu, memory string) apiv1.PodSpec {
+	return apiv1.PodSpec{
+		Containers: []apiv1.Container{{
+			Name:      "main",
+			Resources: apiv1.ResourceRequirements{Requests: requests(cpu, memory)},
+		}},
+	}
+}
+
+func runningPod(name, nodeName, cpu, memory string) *apiv1.Pod {
+	spec := podSpec(cpu, memory)
+	spec.NodeName = nodeName
+	return &apiv1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       spec,
+		Status:     apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+}
+
+func capacityDeployment(name string, replicas int32, strategy apiapps.DeploymentStrategy, cpu, memory string) *apiapps.Deployment {
+	return &apiapps.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: apiapps.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: strategy,
+			Template: apiv1.PodTemplateSpec{Spec: podSpec(cpu, memory)},
+		},
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	surge := intstr.FromInt(2)
+	clientset := fakekubernetes.NewSimpleClientset(
+		node("node-a", true, "2", "4Gi"),
+		node("node-b", true, "1", "2Gi"),
+		node("node-down", false, "8", "16Gi"),
+		runningPod("a-1", "node-a", "1", "1Gi"),
+		runningPod("b-1", "node-b", "500m", "1Gi"),
+		// 25% of 4 replicas, rounded up, is one extra pod
+		capacityDeployment("default-surge", 4, apiapps.DeploymentStrategy{}, "500m", "512Mi"),
+		capacityDeployment("big-surge", 3, apiapps.DeploymentStrategy{
+			Type:          apiapps.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &apiapps.RollingUpdateDeployment{MaxSurge: &surge},
+		}, "500m", "512Mi"),
+		capacityDeployment("recreate", 10, apiapps.DeploymentStrategy{
+			Type: apiapps.RecreateDeploymentStrategyType,
+		}, "4", "8Gi"),
+	)
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), nil, Shard{})
+
+	report, err := c.CheckCapacity([]flux.ResourceID{
+		flux.MustParseResourceID("default:deployment/default-surge"),
+		flux.MustParseResourceID("default:deployment/big-surge"),
+		flux.MustParseResourceID("default:deployment/recreate"),
+		flux.MustParseResourceID("default:statefulset/db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The node that's not ready doesn't count
+	if report.FreeCPU != 1500 {
+		t.Errorf("expected 1500m CPU free, got %dm", report.FreeCPU)
+	}
+	if report.RequestedCPU != 1500 {
+		t.Errorf("expected 1500m CPU requested, got %dm", report.RequestedCPU)
+	}
+	expected := []struct {
+		extra, unschedulable int
+	}{
+		{1, 0},
+		{2, 0},
+		{0, 0},
+		{0, 0},
+	}
+	if len(report.Workloads) != len(expected) {
+		t.Fatalf("expected %d workloads in report, got %+v", len(expected), report.Workloads)
+	}
+	for i, e := range expected {
+		w := report.Workloads[i]
+		if w.ExtraPods != e.extra || w.Unschedulable != e.unschedulable {
+			t.Errorf("%s: expected %d extra pods and %d unschedulable, got %+v", w.ID, e.extra, e.unschedulable, w)
+		}
+	}
+	if !report.Sufficient() {
+		t.Errorf("expected capacity to be sufficient, got %+v", report)
+	}
+
+	// There's 1000m free overall, but only one of the nodes has room
+	// for a 500m pod
+	clientset.CoreV1().Pods("default").Create(runningPod("a-2", "node-a", "250m", "256Mi"))
+	clientset.CoreV1().Pods("default").Create(runningPod("b-2", "node-b", "250m", "256Mi"))
+	report, err = c.CheckCapacity([]flux.ResourceID{flux.MustParseResourceID("default:deployment/big-surge")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sufficient() || report.Unschedulable() != 1 {
+		t.Errorf("expected one pod to be unschedulable, got %+v", report)
+	}
+}
@@ -0,0 +1,339 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+)
+
+const (
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+	// The field manager recorded for `kubectl apply`, i.e., flux
+	applyFieldManager = "kubectl-client-side-apply"
+)
+
+// liveGetter is implemented by appliers that can also get resources
+// as they are in the cluster, as JSON, given the namespace and
+// `<kind>/<name>` of each.
+type liveGetter interface {
+	getLive(logger log.Logger, namespace string, names []string) ([]byte, error)
+}
+
+// SyncConflicts compares the resources given, as they are in the
+// cluster, with what flux last applied (as recorded by kubectl, in
+// the last-applied-configuration annotation), and reports each field
+// flux applied that has a different value now. Where the cluster
+// records field managers, the one that last changed each field is
+// given. Resources that don't exist, or weren't applied by flux, are
+// skipped.
+func (c *Cluster) SyncConflicts(ids []flux.ResourceID) ([]cluster.SyncConflict, error) {
+	getter, ok := c.applier.(liveGetter)
+	if !ok {
+		return nil, nil
+	}
+	logger := log.With(c.logger, "method", "SyncConflicts")
+
+	byNamespace := map[string][]string{}
+	for _, id := range ids {
+		ns, kind, name := id.Components()
+		if !c.shard.Owns(ns) {
+			continue
+		}
+		byNamespace[ns] = append(byNamespace[ns], kind+"/"+name)
+	}
+	var namespaces []string
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	var conflicts []cluster.SyncConflict
+	for _, ns := range namespaces {
+		objs, err := c.getLiveObjects(logger, getter, ns, byNamespace[ns])
+		if err != nil {
+			return conflicts, errors.Wrapf(err, "getting resources in namespace %q", ns)
+		}
+		for _, obj := range objs {
+			conflicts = append(conflicts, liveConflicts(obj)...)
+		}
+	}
+	return conflicts, nil
+}
+
+// getLiveObjects gets the resources named in one go, or if that
+// fails (e.g., because one is of a kind the cluster doesn't know
+// about), one by one, skipping those that fail.
+func (c *Cluster) getLiveObjects(logger log.Logger, getter liveGetter, namespace string, names []string) ([]map[string]interface{}, error) {
+	out, err := getter.getLive(logger, namespace, names)
+	if err == nil {
+		return parseLiveObjects(out)
+	}
+	if len(names) == 1 {
+		logger.Log("namespace", namespace, "resource", names[0], "err", err)
+		return nil, nil
+	}
+	var objs []map[string]interface{}
+	for _, name := range names {
+		out, err := getter.getLive(logger, namespace, []string{name})
+		if err != nil {
+			logger.Log("namespace", namespace, "resource", name, "err", err)
+			continue
+		}
+		some, err := parseLiveObjects(out)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, some...)
+	}
+	return objs, nil
+}
+
+// parseLiveObjects parses the output of `kubectl get -o json`, which
+// is a list if more than one resource was asked for, and nothing if
+// none were found.
+func parseLiveObjects(out []byte) ([]map[string]interface{}, error) {
+	if len(strings.TrimSpace(string(out))) == 0 {
+		return nil, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(out, &obj); err != nil {
+		return nil, errors.Wrap(err, "parsing resources from the cluster")
+	}
+	items, ok := obj["items"].([]interface{})
+	if !ok {
+		return []map[string]interface{}{obj}, nil
+	}
+	var objs []map[string]interface{}
+	for _, item := range items {
+		if o, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, o)
+		}
+	}
+	return objs, nil
+}
+
+// A field found to differ
+type fieldConflict struct {
+	field   string
+	applied interface{}
+	live    interface{}
+	// The path to the field in a managed fields entry
+	managedPath []string
+}
+
+// liveConflicts compares the live object with what was last applied,
+// as recorded in its annotation.
+func liveConflicts(obj map[string]interface{}) []cluster.SyncConflict {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	lastApplied, _ := annotations[lastAppliedAnnotation].(string)
+	if lastApplied == "" {
+		return nil
+	}
+	var applied map[string]interface{}
+	if err := json.Unmarshal([]byte(lastApplied), &applied); err != nil {
+		return nil
+	}
+
+	var found []fieldConflict
+	for _, key := range sortedKeys(applied) {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			appliedMetadata, _ := applied[key].(map[string]interface{})
+			for _, k := range []string{"labels", "annotations"} {
+				if v, ok := appliedMetadata[k]; ok {
+					compareFields(v, metadata[k], []string{"metadata", k}, []string{"f:metadata", "f:" + k}, &found)
+				}
+			}
+		default:
+			compareFields(applied[key], obj[key], []string{key}, []string{"f:" + key}, &found)
+		}
+	}
+
+	kind, _ := obj["kind"].(string)
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" {
+		// as for resources loaded from git
+		namespace = "default"
+	}
+	id := flux.MakeResourceID(namespace, kind, name)
+	managedFields, _ := metadata["managedFields"].([]interface{})
+	var conflicts []cluster.SyncConflict
+	for _, f := range found {
+		conflicts = append(conflicts, cluster.SyncConflict{
+			ID:      id,
+			Field:   f.field,
+			Applied: jsonString(f.applied),
+			Live:    jsonString(f.live),
+			Manager: fieldManager(managedFields, f.managedPath),
+		})
+	}
+	return conflicts
+}
+
+// compareFields records each field of applied with a different value
+// in live. Lists of objects with names (e.g., containers) are
+// compared element by element; other lists as a whole.
+func compareFields(applied, live interface{}, path, managedPath []string, found *[]fieldConflict) {
+	conflict := func() {
+		*found = append(*found, fieldConflict{
+			field:       strings.Join(path, "."),
+			applied:     applied,
+			live:        live,
+			managedPath: managedPath,
+		})
+	}
+	switch a := applied.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			if len(a) > 0 {
+				conflict()
+			}
+			return
+		}
+		for _, k := range sortedKeys(a) {
+			compareFields(a[k], l[k], appendPath(path, k), appendPath(managedPath, "f:"+k), found)
+		}
+	case []interface{}:
+		names, ok := elementNames(a)
+		if !ok {
+			if !equalValues(applied, live, path) {
+				conflict()
+			}
+			return
+		}
+		liveNames, _ := elementNames(asList(live))
+		liveByName := map[string]interface{}{}
+		for i, name := range liveNames {
+			liveByName[name] = asList(live)[i]
+		}
+		last := len(path) - 1
+		for i, name := range names {
+			elemPath := appendPath(path[:last], fmt.Sprintf("%s[name=%s]", path[last], name))
+			key, _ := json.Marshal(map[string]string{"name": name})
+			compareFields(a[i], liveByName[name], elemPath, appendPath(managedPath, "k:"+string(key)), found)
+		}
+	default:
+		if applied == nil {
+			return
+		}
+		if !equalValues(applied, live, path) {
+			conflict()
+		}
+	}
+}
+
+// equalValues says whether the values are the same; quantities of
+// resources are compared by amount, since the cluster may write them
+// differently (e.g., `0.5` as `500m`).
+func equalValues(applied, live interface{}, path []string) bool {
+	if len(path) >= 2 && (path[len(path)-2] == "limits" || path[len(path)-2] == "requests") {
+		a, aerr := resource.ParseQuantity(fmt.Sprint(applied))
+		l, lerr := resource.ParseQuantity(fmt.Sprint(live))
+		if aerr == nil && lerr == nil {
+			return a.Cmp(l) == 0
+		}
+	}
+	return jsonString(applied) == jsonString(live)
+}
+
+// fieldManager gives the manager, other than flux's, that most
+// recently took ownership of the field at the path given, or the
+// empty string if none did (or the cluster doesn't record field
+// managers).
+func fieldManager(managedFields []interface{}, managedPath []string) string {
+	var manager, latest string
+	for _, entry := range managedFields {
+		e, _ := entry.(map[string]interface{})
+		name, _ := e["manager"].(string)
+		if name == "" || name == applyFieldManager {
+			continue
+		}
+		fields, _ := e["fieldsV1"].(map[string]interface{})
+		if !ownsField(fields, managedPath) {
+			continue
+		}
+		if t, _ := e["time"].(string); manager == "" || t > latest {
+			manager, latest = name, t
+		}
+	}
+	return manager
+}
+
+// ownsField says whether the fields, in the form of a managed fields
+// entry, include the path given (or a field it's within, as a whole).
+func ownsField(fields map[string]interface{}, path []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	for i, key := range path {
+		next, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if len(next) == 0 && i < len(path)-1 {
+			return true
+		}
+		fields = next
+	}
+	return true
+}
+
+// elementNames gives the name of each element of the list, if they
+// are all objects with names.
+func elementNames(list []interface{}) ([]string, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	var names []string
+	for _, elem := range list {
+		m, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func appendPath(path []string, elem string) []string {
+	return append(append([]string(nil), path...), elem)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonString(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bytes)
+}
@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+)
+
+// The last-applied-configuration is what flux applied: three
+// replicas, and the image helloworld:1. Since then, an autoscaler has
+// scaled it to five, someone has edited the image and added a label
+// of their own; and the cluster has written the CPU request
+// differently, and filled in defaults.
+const liveDeployment = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {
+    "name": "helloworld",
+    "namespace": "default",
+    "labels": {"app": "helloworld", "extra": "label"},
+    "annotations": {
+      "kubectl.kubernetes.io/last-applied-configuration": "{\"apiVersion\":\"apps/v1\",\"kind\":\"Deployment\",\"metadata\":{\"name\":\"helloworld\",\"namespace\":\"default\",\"labels\":{\"app\":\"helloworld\"}},\"spec\":{\"replicas\":3,\"template\":{\"spec\":{\"containers\":[{\"name\":\"helloworld\",\"image\":\"helloworld:1\",\"resources\":{\"requests\":{\"cpu\":\"0.5\"}}}]}}}}"
+    },
+    "managedFields": [
+      {"manager": "kubectl-client-side-apply", "operation": "Update", "time": "2019-01-01T00:00:00Z",
+       "fieldsV1": {"f:spec": {"f:replicas": {}, "f:template": {"f:spec": {"f:containers": {"k:{\"name\":\"helloworld\"}": {"f:image": {}}}}}}}},
+      {"manager": "kube-controller-manager", "operation": "Update", "time": "2019-01-02T00:00:00Z",
+       "fieldsV1": {"f:spec": {"f:replicas": {}}}},
+      {"manager": "kubectl-edit", "operation": "Update", "time": "2019-01-03T00:00:00Z",
+       "fieldsV1": {"f:spec": {"f:template": {"f:spec": {"f:containers": {"k:{\"name\":\"helloworld\"}": {"f:image": {}}}}}}}}
+    ]
+  },
+  "spec": {
+    "replicas": 5,
+    "strategy": {"type": "RollingUpdate"},
+    "template": {"spec": {"containers": [{"name": "helloworld", "image": "helloworld:edited", "resources": {"requests": {"cpu": "500m"}}}]}}
+  }
+}`
+
+type mockLiveApplier struct {
+	mockApplier
+	live map[string]string
+}
+
+func (m *mockLiveApplier) getLive(_ log.Logger, namespace string, names []string) ([]byte, error) {
+	if len(names) > 1 {
+		return nil, errors.New("one at a time, please")
+	}
+	if out, ok := m.live[namespace+":"+names[0]]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("the server doesn't have a resource type")
+}
+
+func TestSyncConflicts(t *testing.T) {
+	applier := &mockLiveApplier{live: map[string]string{
+		"default:deployment/helloworld": liveDeployment,
+		"default:service/helloworld":    "",
+	}}
+	kube := &Cluster{applier: applier, logger: log.NewNopLogger()}
+
+	conflicts, err := kube.SyncConflicts([]flux.ResourceID{
+		flux.MustParseResourceID("default:deployment/helloworld"),
+		flux.MustParseResourceID("default:service/helloworld"),
+		flux.MustParseResourceID("default:widget/unknown"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	expected := []cluster.SyncConflict{
+		{ID: id, Field: "spec.replicas", Applied: "3", Live: "5", Manager: "kube-controller-manager"},
+		{ID: id, Field: "spec.template.spec.containers[name=helloworld].image", Applied: `"helloworld:1"`, Live: `"helloworld:edited"`, Manager: "kubectl-edit"},
+	}
+	if len(conflicts) != len(expected) {
+		t.Fatalf("expected %d conflicts, got %+v", len(expected), conflicts)
+	}
+	for i := range expected {
+		if conflicts[i] != expected[i] {
+			t.Errorf("expected conflict %+v, got %+v", expected[i], conflicts[i])
+		}
+	}
+}
+
+func TestSyncConflictsNotSupported(t *testing.T) {
+	kube, _ := setup(t)
+	conflicts, err := kube.SyncConflicts([]flux.ResourceID{flux.MustParseResourceID("default:deployment/helloworld")})
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("expected no conflicts from an applier that can't get resources, got %+v (%v)", conflicts, err)
+	}
+}
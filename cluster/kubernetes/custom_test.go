@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/weaveworks/flux"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+	"github.com/weaveworks/flux/image"
+)
+
+const customWorkloadManifest = `---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: frobnicator
+  namespace: tools
+spec:
+  replicas: 2
+  # the main image
+  image: "quay.io/example/widget:1.0" # pinned by hand
+  sidecar:
+    image: quay.io/example/sidecar:2.0
+`
+
+const customWorkloadManifestOut = `---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: frobnicator
+  namespace: tools
+spec:
+  replicas: 2
+  # the main image
+  image: "quay.io/example/widget:1.1" # pinned by hand
+  sidecar:
+    image: quay.io/example/sidecar:2.0
+`
+
+const customWorkloadJSON = `{
+  "apiVersion": "example.com/v1",
+  "kind": "Widget",
+  "metadata": {
+    "name": "frobnicator",
+    "namespace": "tools"
+  },
+  "spec": {
+    "image": "quay.io/example/widget:1.0",
+    "sidecar": {"image": "quay.io/example/sidecar:2.0"}
+  }
+}
+`
+
+func customWorkloadManifests(t *testing.T) *Manifests {
+	kinds, err := kresource.ParseWorkloadKinds([]string{
+		"example.com/v1/Widget=spec.image",
+		"example.com/v1/Widget=spec.sidecar.image",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Manifests{PreserveFormatting: true, Workloads: kinds}
+}
+
+func TestUpdateCustomWorkloadImage(t *testing.T) {
+	m := customWorkloadManifests(t)
+	id := flux.MustParseResourceID("tools:widget/frobnicator")
+	ref, _ := image.ParseRef("quay.io/example/widget:1.1")
+
+	out, err := m.UpdateImage([]byte(customWorkloadManifest), id, "spec.image", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != customWorkloadManifestOut {
+		t.Errorf("did not get expected result:\n\n%s\n\nInstead got:\n\n%s", customWorkloadManifestOut, out)
+	}
+
+	sidecar, _ := image.ParseRef("quay.io/example/sidecar:2.1")
+	out, err = m.UpdateImage([]byte(customWorkloadJSON), id, "spec.sidecar.image", sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload, err := findWorkload(m.loader(), out, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, err := containerImage(workload, id, "spec.sidecar.image"); err != nil || img != sidecar.String() {
+		t.Errorf("expected sidecar image %s, got %q (%v)", sidecar, img, err)
+	}
+
+	if _, err := m.UpdateImage([]byte(customWorkloadManifest), id, "spec.other.image", ref); err == nil {
+		t.Error("expected updating a path not configured to fail")
+	}
+}
+
+func TestUpdateFieldInPlaceFallsBack(t *testing.T) {
+	loader := customWorkloadManifests(t).loader()
+	id := flux.MustParseResourceID("tools:widget/frobnicator")
+	for name, manifest := range map[string]string{
+		"flow-style": `---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: frobnicator
+  namespace: tools
+spec: {image: "quay.io/example/widget:1.0"}
+`,
+		"no such field": `---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: frobnicator
+  namespace: tools
+spec:
+  sidecar:
+    image: quay.io/example/sidecar:2.0
+`,
+	} {
+		if _, ok := updateFieldInPlace(loader, []byte(manifest), id, "spec.image", "quay.io/example/widget:1.1"); ok {
+			t.Errorf("%s: expected in-place update to give up", name)
+		}
+	}
+}
+
+func TestCustomWorkloadControllers(t *testing.T) {
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "frobnicator",
+			"namespace": "tools",
+		},
+		"spec": map[string]interface{}{
+			"image": "quay.io/example/widget:1.0",
+		},
+	}}
+	clientset := fakekubernetes.NewSimpleClientset(newNamespace("tools"))
+	clientset.Resources = []*meta_v1.APIResourceList{{
+		GroupVersion: "example.com/v1",
+		APIResources: []meta_v1.APIResource{
+			{Name: "widgets", Kind: "Widget", Namespaced: true},
+			{Name: "widgets/status", Kind: "Widget", Namespaced: true},
+		},
+	}}
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), nil, Shard{})
+	c.SetWorkloadKinds(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), widget), customWorkloadManifests(t).Workloads)
+
+	id := flux.MustParseResourceID("tools:widget/frobnicator")
+	controllers, err := c.SomeControllers([]flux.ResourceID{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(controllers) != 1 {
+		t.Fatalf("expected one controller, got %#v", controllers)
+	}
+	containers := controllers[0].ContainersOrNil()
+	if len(containers) != 1 || containers[0].Name != "spec.image" || containers[0].Image.String() != "quay.io/example/widget:1.0" {
+		t.Errorf("unexpected containers %#v", containers)
+	}
+}
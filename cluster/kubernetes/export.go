@@ -0,0 +1,216 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The fields of metadata that the cluster fills in, and which don't
+// belong in git
+var exportOmitMetadata = []string{
+	"uid",
+	"resourceVersion",
+	"selfLink",
+	"creationTimestamp",
+	"generation",
+}
+
+// The annotations that tools and controllers add, and which don't
+// belong in git
+var exportOmitAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"kubernetes.io/change-cause",
+}
+
+// ExportResources gives the namespaces given (or all those flux can
+// see), and the workloads of the kinds given (or of all the kinds
+// flux knows about) in them, as YAML that's ready to commit to git:
+// without status, nor the metadata the cluster fills in.
+func (c *Cluster) ExportResources(namespaces, kinds []string) ([]byte, error) {
+	wantKind := map[string]bool{}
+	for _, kind := range kinds {
+		kind = strings.ToLower(kind)
+		if _, ok := resourceKinds[kind]; !ok && kind != "namespace" {
+			return nil, fmt.Errorf("unknown kind %q; expected namespace, or one of %s", kind, strings.Join(exportableKinds(), ", "))
+		}
+		wantKind[kind] = true
+	}
+	want := func(kind string) bool {
+		return len(wantKind) == 0 || wantKind[kind]
+	}
+
+	allowed, err := c.getAllowedNamespaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting namespaces")
+	}
+	selected := allowed
+	if len(namespaces) > 0 {
+		byName := map[string]apiv1.Namespace{}
+		for _, ns := range allowed {
+			byName[ns.Name] = ns
+		}
+		selected = nil
+		for _, name := range namespaces {
+			ns, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("namespace %q does not exist, or is not one flux can see", name)
+			}
+			selected = append(selected, ns)
+		}
+	}
+
+	var config bytes.Buffer
+	for _, ns := range selected {
+		if want("namespace") {
+			if err := appendCleanYAML(&config, "v1", "Namespace", ns); err != nil {
+				return nil, errors.Wrap(err, "marshalling namespace to YAML")
+			}
+		}
+		for _, kind := range exportableKinds() {
+			if !want(kind) {
+				continue
+			}
+			podControllers, err := resourceKinds[kind].getPodControllers(c, ns.Name)
+			if err != nil {
+				if se, ok := err.(*apierrors.StatusError); ok && se.ErrStatus.Reason == meta_v1.StatusReasonNotFound {
+					// Kind not supported by API server, skip
+					continue
+				}
+				return nil, err
+			}
+			for _, pc := range podControllers {
+				if isAddon(pc) {
+					continue
+				}
+				if err := appendCleanYAML(&config, pc.apiVersion, pc.kind, pc.k8sObject); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return config.Bytes(), nil
+}
+
+// exportableKinds gives the kinds of workload that can be exported,
+// in a stable order. Jobs and Pods are left out: they get fields from
+// the cluster (e.g., the selector of a Job, the node of a Pod) that
+// can't be applied again, and are usually one-offs anyway.
+func exportableKinds() []string {
+	var kinds []string
+	for kind := range resourceKinds {
+		if kind == "job" || kind == "pod" {
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func appendCleanYAML(buffer *bytes.Buffer, apiVersion, kind string, object interface{}) error {
+	yamlBytes, err := cleanExported(apiVersion, kind, object)
+	if err != nil {
+		return err
+	}
+	buffer.WriteString("---\n")
+	buffer.Write(yamlBytes)
+	return nil
+}
+
+// cleanExported marshals the object given to YAML, keeping the order
+// of its fields, but leaving out those the cluster fills in.
+func cleanExported(apiVersion, kind string, object interface{}) ([]byte, error) {
+	jsonBytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, and decoding into a MapSlice keeps the order
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal(jsonBytes, &fields); err != nil {
+		return nil, err
+	}
+
+	doc := yaml.MapSlice{
+		{Key: "apiVersion", Value: apiVersion},
+		{Key: "kind", Value: kind},
+	}
+	for _, field := range fields {
+		switch field.Key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			if meta, ok := field.Value.(yaml.MapSlice); ok {
+				meta = withoutKeys(meta, exportOmitMetadata...)
+				for i, item := range meta {
+					if annotations, ok := item.Value.(yaml.MapSlice); ok && item.Key == "annotations" {
+						meta[i].Value = withoutKeys(annotations, exportOmitAnnotations...)
+					}
+				}
+				field.Value = meta
+			}
+		case "spec":
+			// Namespaces get their finalizers from the cluster
+			if kind == "Namespace" {
+				continue
+			}
+		}
+		doc = append(doc, field)
+	}
+	return yaml.Marshal(withoutEmpty(doc))
+}
+
+func withoutKeys(m yaml.MapSlice, keys ...string) yaml.MapSlice {
+	omit := map[string]bool{}
+	for _, k := range keys {
+		omit[k] = true
+	}
+	var res yaml.MapSlice
+	for _, item := range m {
+		if k, ok := item.Key.(string); ok && omit[k] {
+			continue
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// withoutEmpty removes fields that are null (like an unset
+// creationTimestamp), or empty maps (like unset resources),
+// throughout the value given. An empty emptyDir is kept, since that
+// is how it's used.
+func withoutEmpty(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		var res yaml.MapSlice
+		for _, item := range v {
+			value := withoutEmpty(item.Value)
+			if value == nil {
+				continue
+			}
+			if m, ok := value.(yaml.MapSlice); ok && len(m) == 0 && item.Key != "emptyDir" {
+				continue
+			}
+			res = append(res, yaml.MapItem{Key: item.Key, Value: value})
+		}
+		if res == nil {
+			return yaml.MapSlice{}
+		}
+		return res
+	case []interface{}:
+		for i := range v {
+			v[i] = withoutEmpty(v[i])
+		}
+		return v
+	}
+	return v
+}
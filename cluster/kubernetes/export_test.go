@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	apiapps "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExportResources(t *testing.T) {
+	replicas := int32(1)
+	deployment := &apiapps.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "helloworld",
+			Namespace:       "default",
+			UID:             "0f9c5c3e-0000-0000-0000-000000000000",
+			ResourceVersion: "12345",
+			Generation:      3,
+			Annotations: map[string]string{
+				"deployment.kubernetes.io/revision": "3",
+				"flux.weave.works/automated":        "true",
+			},
+		},
+		Spec: apiapps.DeploymentSpec{
+			Replicas: &replicas,
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{Name: "greeter", Image: "quay.io/weaveworks/helloworld:master-a000001"},
+					},
+					Volumes: []apiv1.Volume{
+						{Name: "scratch", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		},
+		Status: apiapps.DeploymentStatus{Replicas: 1},
+	}
+	clientset := fakekubernetes.NewSimpleClientset(newNamespace("default"), newNamespace("other"), deployment)
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), nil, Shard{})
+
+	out, err := c.ExportResources([]string{"default"}, []string{"namespace", "Deployment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := string(out)
+	for _, expected := range []string{
+		"kind: Namespace\n",
+		"kind: Deployment\n",
+		"flux.weave.works/automated",
+		"image: quay.io/weaveworks/helloworld:master-a000001",
+		"emptyDir: {}",
+	} {
+		if !strings.Contains(exported, expected) {
+			t.Errorf("expected %q in export:\n%s", expected, exported)
+		}
+	}
+	for _, unexpected := range []string{
+		"name: other",
+		"uid:",
+		"resourceVersion:",
+		"generation:",
+		"creationTimestamp:",
+		"deployment.kubernetes.io/revision",
+		"status:",
+		"resources: {}",
+		"strategy: {}",
+	} {
+		if strings.Contains(exported, unexpected) {
+			t.Errorf("did not expect %q in export:\n%s", unexpected, exported)
+		}
+	}
+	if !strings.HasPrefix(exported, "---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: default\n") {
+		t.Errorf("expected the namespace first, with fields in order, got:\n%s", exported)
+	}
+
+	if _, err := c.ExportResources(nil, []string{"gadget"}); err == nil {
+		t.Error("expected an error exporting an unknown kind")
+	}
+	if _, err := c.ExportResources([]string{"nonesuch"}, []string{"deployment"}); err == nil {
+		t.Error("expected an error exporting a namespace that doesn't exist")
+	}
+}
@@ -111,7 +111,7 @@ func (c *Cluster) ImagesToFetch() registry.ImageCreds {
 
 	for _, ns := range namespaces {
 		seenCreds := make(map[string]registry.Credentials)
-		for kind, resourceKind := range resourceKinds {
+		for kind, resourceKind := range c.kinds() {
 			podControllers, err := resourceKind.getPodControllers(c, ns.Name)
 			if err != nil {
 				if se, ok := err.(*apierrors.StatusError); ok && se.ErrStatus.Reason == meta_v1.StatusReasonNotFound {
@@ -0,0 +1,415 @@
+package kubernetes
+
+import (
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+)
+
+// updateImageInPlace replaces the image of a container by rewriting
+// only the value of its `image` field, leaving everything else in
+// the YAML -- comments, key order, indentation and the style in which
+// the value is quoted -- exactly as it was. It only handles the
+// common, block-style layout of a workload's containers; if it can't
+// find a single field to change, or the result doesn't parse as
+// expected, it returns false and the caller should fall back to a
+// more thorough (if less faithful) means of updating the manifest.
+func updateImageInPlace(in []byte, id flux.ResourceID, container, newImage string) ([]byte, bool) {
+	_, kind, _ := id.Components()
+	// The images for FluxHelmReleases live in the values, in a
+	// variety of arrangements, so leave those to kubeyaml
+	if kind := strings.ToLower(kind); kind == "fluxhelmrelease" || resourceKinds[kind] == nil {
+		return nil, false
+	}
+
+	lines := strings.SplitAfter(string(in), "\n")
+	var spans []span
+	for _, doc := range documentSpans(lines) {
+		spans = append(spans, resourceSpans(lines, doc, id)...)
+	}
+	if len(spans) != 1 {
+		return nil, false
+	}
+
+	var found []int
+	for _, item := range listItems(lines, spans[0]) {
+		if i, ok := containerImageLine(lines, item, container); ok {
+			found = append(found, i)
+		}
+	}
+	if len(found) != 1 {
+		return nil, false
+	}
+
+	updated, ok := replaceImageValue(lines[found[0]], newImage)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, len(lines))
+	copy(out, lines)
+	out[found[0]] = updated
+	result := []byte(strings.Join(out, ""))
+
+	if checkImageUpdate(kresource.Loader{}, in, result, id, container, newImage) != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// updateFieldInPlace replaces the value at a path of field names in
+// a resource, rewriting only that value, as updateImageInPlace does.
+// It handles only block-style mappings along the path.
+func updateFieldInPlace(loader kresource.Loader, in []byte, id flux.ResourceID, path, newValue string) ([]byte, bool) {
+	lines := strings.SplitAfter(string(in), "\n")
+	var spans []span
+	for _, doc := range documentSpans(lines) {
+		spans = append(spans, resourceSpans(lines, doc, id)...)
+	}
+	if len(spans) != 1 {
+		return nil, false
+	}
+
+	within := spans[0]
+	line := -1
+	for _, key := range strings.Split(path, ".") {
+		var ok bool
+		if line, within, ok = mappingEntry(lines, within, key); !ok {
+			return nil, false
+		}
+	}
+
+	updated, ok := replaceValue(lines[line], path[strings.LastIndex(path, ".")+1:], newValue)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, len(lines))
+	copy(out, lines)
+	out[line] = updated
+	result := []byte(strings.Join(out, ""))
+
+	if checkImageUpdate(loader, in, result, id, path, newValue) != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// mappingEntry finds the entry for the key given in the block-style
+// mapping that makes up the span, which may be a sequence item. It
+// returns the line with the key, and the span of the lines after it
+// that are nested under it.
+func mappingEntry(lines []string, within span, key string) (int, span, bool) {
+	col := -1
+	found := -1
+	var entry span
+	for i := within.start; i < within.end; i++ {
+		if !isContent(lines[i]) {
+			continue
+		}
+		indent, content := indentOf(lines[i]), strings.TrimSpace(lines[i])
+		if i == within.start {
+			if m := listItemStart.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n")); m != nil {
+				indent, content = len(m[1])+1+len(m[2]), m[3]
+			}
+		}
+		if col < 0 {
+			col = indent
+		}
+		if indent != col {
+			continue
+		}
+		k := mappingKey.FindStringSubmatch(content)
+		if k == nil || k[1] != key {
+			continue
+		}
+		if found >= 0 {
+			return 0, span{}, false
+		}
+		end := i + 1
+		for ; end < within.end; end++ {
+			if isContent(lines[end]) && indentOf(lines[end]) <= col {
+				break
+			}
+		}
+		found, entry = i, span{i + 1, end}
+	}
+	return found, entry, found >= 0
+}
+
+// span is a range of lines, [start, end).
+type span struct {
+	start, end int
+}
+
+var (
+	documentSeparator = regexp.MustCompile(`^---(\s|$)`)
+	listItemStart     = regexp.MustCompile(`^(\s*)-(\s+)(\S.*)$`)
+	mappingKey        = regexp.MustCompile(`^([A-Za-z_][\w.-]*)\s*:(\s+(.*))?$`)
+)
+
+func documentSpans(lines []string) []span {
+	var spans []span
+	start := 0
+	for i, line := range lines {
+		if documentSeparator.MatchString(line) {
+			if i > start {
+				spans = append(spans, span{start, i})
+			}
+			start = i + 1
+		}
+	}
+	if start < len(lines) {
+		spans = append(spans, span{start, len(lines)})
+	}
+	return spans
+}
+
+type resourceHeader struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+func (h resourceHeader) matches(id flux.ResourceID) bool {
+	ns, kind, name := id.Components()
+	namespace := h.Metadata.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return strings.EqualFold(h.Kind, kind) && h.Metadata.Name == name && namespace == ns
+}
+
+// resourceSpans gives the span of the resource identified within the
+// document given; this is the whole document, or, if it's a List,
+// an item in it.
+func resourceSpans(lines []string, doc span, id flux.ResourceID) []span {
+	var header resourceHeader
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[doc.start:doc.end], "")), &header); err != nil {
+		return nil
+	}
+	if header.matches(id) {
+		return []span{doc}
+	}
+	if header.Kind != "List" {
+		return nil
+	}
+	var spans []span
+	for _, item := range listItems(lines, doc) {
+		// Only the items of the top-level `items` will parse as
+		// resources, so there's no need to check where they are
+		var itemHeader resourceHeader
+		if err := yaml.Unmarshal([]byte(dedentItem(lines[item.start:item.end])), &itemHeader); err != nil {
+			continue
+		}
+		if itemHeader.matches(id) {
+			spans = append(spans, item)
+		}
+	}
+	return spans
+}
+
+// listItems finds all the (block-style) items of sequences within a
+// span, at any depth.
+func listItems(lines []string, within span) []span {
+	var items []span
+	for i := within.start; i < within.end; i++ {
+		m := listItemStart.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n"))
+		if m == nil || strings.HasPrefix(m[3], "#") {
+			continue
+		}
+		contentCol := len(m[1]) + 1 + len(m[2])
+		end := i + 1
+		for ; end < within.end; end++ {
+			if !isContent(lines[end]) {
+				continue
+			}
+			if indentOf(lines[end]) < contentCol {
+				break
+			}
+		}
+		items = append(items, span{i, end})
+	}
+	return items
+}
+
+// dedentItem turns the lines of a sequence item into a YAML document
+// of its own.
+func dedentItem(lines []string) string {
+	m := listItemStart.FindStringSubmatch(strings.TrimRight(lines[0], "\r\n"))
+	contentCol := len(m[1]) + 1 + len(m[2])
+	out := []string{m[3] + "\n"}
+	for _, line := range lines[1:] {
+		if len(line) >= contentCol && strings.TrimSpace(line[:contentCol]) == "" {
+			line = line[contentCol:]
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "")
+}
+
+// containerImageLine checks whether the sequence item is the
+// container named, in either `containers` or `initContainers`, and
+// if so returns the index of the line with its image.
+func containerImageLine(lines []string, item span, container string) (int, bool) {
+	m := listItemStart.FindStringSubmatch(strings.TrimRight(lines[item.start], "\r\n"))
+	dashIndent := len(m[1])
+	contentCol := dashIndent + 1 + len(m[2])
+
+	var name string
+	var hasName bool
+	imageLine := -1
+	checkKey := func(i int, content string) {
+		k := mappingKey.FindStringSubmatch(content)
+		if k == nil {
+			return
+		}
+		switch k[1] {
+		case "name":
+			name, hasName = scalarValue(k[3])
+		case "image":
+			imageLine = i
+		}
+	}
+	checkKey(item.start, m[3])
+	for i := item.start + 1; i < item.end; i++ {
+		if isContent(lines[i]) && indentOf(lines[i]) == contentCol {
+			checkKey(i, strings.TrimSpace(lines[i]))
+		}
+	}
+	if !hasName || name != container || imageLine < 0 {
+		return 0, false
+	}
+
+	// Look back for the key of the sequence this item is in; other
+	// items in the sequence may come in between.
+	for i := item.start - 1; i >= 0; i-- {
+		line := lines[i]
+		if !isContent(line) {
+			continue
+		}
+		indent := indentOf(line)
+		trimmed := strings.TrimSpace(line)
+		if indent > dashIndent || (indent == dashIndent && strings.HasPrefix(trimmed, "-")) {
+			continue
+		}
+		if lm := listItemStart.FindStringSubmatch(strings.TrimRight(line, "\r\n")); lm != nil {
+			// e.g., `- containers:` as an item of an outer sequence
+			trimmed = lm[3]
+		}
+		k := mappingKey.FindStringSubmatch(trimmed)
+		if k == nil || (k[1] != "containers" && k[1] != "initContainers") {
+			return 0, false
+		}
+		if v := strings.TrimSpace(k[3]); v != "" && !strings.HasPrefix(v, "#") {
+			return 0, false
+		}
+		return imageLine, true
+	}
+	return 0, false
+}
+
+// scalarValue gives the value of a plain or quoted scalar, as it
+// appears after a key, ignoring any comment.
+func scalarValue(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", false
+	}
+	if q := s[0]; q == '"' || q == '\'' {
+		end := strings.IndexByte(s[1:], q)
+		if end < 0 {
+			return "", false
+		}
+		return s[1 : end+1], true
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), true
+}
+
+// replaceImageValue replaces the value in a line `image: <value>`,
+// keeping the quotes (if any), and anything after the value.
+func replaceImageValue(line, newImage string) (string, bool) {
+	return replaceValue(line, "image", newImage)
+}
+
+// replaceValue replaces the value in a line `<key>: <value>`, as
+// replaceImageValue does.
+func replaceValue(line, key, newValue string) (string, bool) {
+	i := strings.Index(line, key+":")
+	if k := strings.Index(line, key+" "); i < 0 || (k >= 0 && k < i) {
+		i = k
+	}
+	if i < 0 {
+		return "", false
+	}
+	j := i + len(key)
+	for j < len(line) && line[j] == ' ' {
+		j++
+	}
+	if j >= len(line) || line[j] != ':' {
+		return "", false
+	}
+	j++
+	for j < len(line) && line[j] == ' ' {
+		j++
+	}
+	// Keep an anchor (`&name`) or tag (`!!str`) before the value;
+	// if the value is an alias (`*name`), it's replaced with the
+	// image, since only this container is to be updated.
+	for j < len(line) && (line[j] == '&' || line[j] == '!') {
+		for j < len(line) && line[j] != ' ' && line[j] != '\n' && line[j] != '\r' {
+			j++
+		}
+		for j < len(line) && line[j] == ' ' {
+			j++
+		}
+	}
+	prefix, rest := line[:j], line[j:]
+	if rest == "" || rest[0] == '\n' || rest[0] == '\r' || rest[0] == '#' {
+		// the value is on another line, or missing
+		return "", false
+	}
+	var oldValue, replacement string
+	if q := rest[0]; q == '"' || q == '\'' {
+		end := strings.IndexByte(rest[1:], q)
+		if end < 0 {
+			return "", false
+		}
+		oldValue, replacement = rest[:end+2], string(q)+newValue+string(q)
+	} else {
+		end := len(strings.TrimRight(rest, "\r\n"))
+		if c := strings.Index(rest, " #"); c >= 0 && c < end {
+			end = c
+		}
+		oldValue, replacement = strings.TrimRight(rest[:end], " "), newValue
+	}
+	suffix := rest[len(oldValue):]
+	// Keep a comment following the value in the same column, if
+	// there's room
+	if trimmed := strings.TrimLeft(suffix, " "); strings.HasPrefix(trimmed, "#") {
+		gap := len(suffix) - len(trimmed) + len(oldValue) - len(replacement)
+		if gap < 1 {
+			gap = 1
+		}
+		suffix = strings.Repeat(" ", gap) + trimmed
+	}
+	return prefix + replacement + suffix, true
+}
+
+// isContent says whether a line has something other than whitespace
+// or a comment on it.
+func isContent(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
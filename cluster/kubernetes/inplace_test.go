@@ -0,0 +1,202 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+)
+
+func testUpdateInPlace(t *testing.T, u update) {
+	id, err := image.ParseRef(u.updatedImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := u.caseIn
+	for _, container := range u.containers {
+		out, ok := updateImageInPlace([]byte(manifest), flux.MustParseResourceID(u.resourceID), container, id.String())
+		if !ok {
+			t.Errorf("Could not update container %q in place", container)
+			return
+		}
+		manifest = string(out)
+	}
+	if manifest != u.caseOut {
+		t.Errorf("did not get expected result:\n\n%s\n\nInstead got:\n\n%s", u.caseOut, manifest)
+	}
+}
+
+func TestUpdatesInPlace(t *testing.T) {
+	for _, c := range []update{
+		{"common case", case1resource, case1container, case1image, case1, case1out},
+		{"new version like number", case2resource, case2container, case2image, case2, case2out},
+		{"old version like number", case2resource, case2container, case2reverseImage, case2out, case2},
+		{"name label out of order, indentation kept", case3resource, case3container, case3image, case3, case3outInPlace},
+		{"version (tag) with dots", case4resource, case4container, case4image, case4, case4out},
+		{"minimal dockerhub image name", case5resource, case5container, case5image, case5, case5out},
+		{"reordered keys", case6resource, case6containers, case6image, case6, case6out},
+		{"from prod", case7resource, case7containers, case7image, case7, case7out},
+		{"single quotes", case8resource, case8containers, case8image, case8, case8out},
+		{"in multidoc", case9resource, case9containers, case9image, case9, case9out},
+		{"in kubernetes List resource", case10resource, case10containers, case10image, case10, case10out},
+		{"initContainer", case13resource, case13containers, case13image, case13, case13out},
+		{"comments and quoting kept", caseCommentsResource, caseCommentsContainers, caseCommentsImage, caseComments, caseCommentsOut},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			testUpdateInPlace(t, c)
+		})
+	}
+}
+
+func TestUpdateInPlaceFallsBack(t *testing.T) {
+	for name, c := range map[string]struct {
+		resourceID, container, manifest string
+	}{
+		"FluxHelmRelease":   {case11resource, case11containers[0], case11},
+		"no such container": {case1resource, "nope", case1},
+		"no such resource":  {"extra:deployment/nope", case1container[0], case1},
+		"flow-style container": {"default:deployment/flow", "app", `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: flow
+spec:
+  template:
+    spec:
+      containers: [{name: app, image: "quay.io/example/app:1.0"}]
+`},
+	} {
+		if _, ok := updateImageInPlace([]byte(c.manifest), flux.MustParseResourceID(c.resourceID), c.container, "quay.io/example/app:1.1"); ok {
+			t.Errorf("%s: expected in-place update to give up", name)
+		}
+	}
+}
+
+const case3outInPlace = `---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+ namespace: monitoring
+ name: grafana # comment, and only one space indent
+spec:
+  replicas: 1
+  template:
+    metadata:
+      labels:
+        name: grafana
+    spec:
+      imagePullSecrets:
+      - name: quay-secret
+      containers:
+      - name: grafana
+        image: quay.io/weaveworks/grafana:master-37aaf67
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 80
+      - name: gfdatasource
+        image: quay.io/weaveworks/gfdatasource:master-e50ecf2
+        imagePullPolicy: IfNotPresent
+        args:
+        - http://prometheus.monitoring.svc.cluster.local/admin/prometheus
+`
+
+const caseComments = `# The web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name:   web   # extra spaces
+  namespace: "frontend"
+  annotations: {flux.weave.works/automated: "true"}
+spec:
+  template:
+    spec:
+      containers:
+        # the main app
+        - image: "quay.io/example/web:1.0"   # bumped by flux
+          name: 'web'
+          env:
+            - name: web
+              value: "not an image"
+        - name: sidecar
+          image: quay.io/example/web:1.0
+`
+
+const caseCommentsResource = "frontend:deployment/web"
+const caseCommentsImage = "quay.io/example/web:1.1"
+
+var caseCommentsContainers = []string{"web"}
+
+var caseCommentsOut = strings.Replace(caseComments, `"quay.io/example/web:1.0"   # bumped`, `"quay.io/example/web:1.1"   # bumped`, 1)
+
+const caseShared = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shared
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: &app quay.io/example/app:1.0
+      - name: worker
+        image: *app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: templated
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "${REGISTRY}/example/app:1.0"
+`
+
+func TestUpdateImageTolerance(t *testing.T) {
+	m := &Manifests{PreserveFormatting: true}
+	shared := flux.MustParseResourceID("default:deployment/shared")
+	templated := flux.MustParseResourceID("default:deployment/templated")
+	ref, _ := image.ParseRef("quay.io/example/app:1.1")
+
+	// An alias is replaced with the image, so only that container
+	// changes
+	out, err := m.UpdateImage([]byte(caseShared), shared, "worker", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(caseShared, "image: *app", "image: quay.io/example/app:1.1", 1)
+	if string(out) != expected {
+		t.Errorf("expected alias to be replaced:\n%s\ngot:\n%s", expected, out)
+	}
+
+	// Changing the anchored value would change the container using
+	// the alias too, so that has to be done by hand
+	if _, err = m.UpdateImage([]byte(caseShared), shared, "app", ref); err == nil {
+		t.Error("expected error updating image shared by anchor and alias")
+	}
+
+	// A templated prefix is kept
+	out, err = m.UpdateImage([]byte(caseShared), templated, "app", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Replace(caseShared, `"${REGISTRY}/example/app:1.0"`, `"${REGISTRY}/example/app:1.1"`, 1)
+	if string(out) != expected {
+		t.Errorf("expected templated prefix to be kept:\n%s\ngot:\n%s", expected, out)
+	}
+
+	other, _ := image.ParseRef("quay.io/example/other:1.1")
+	if _, err = m.UpdateImage([]byte(caseShared), templated, "app", other); err == nil {
+		t.Error("expected error updating templated image to an image of another name")
+	}
+	if _, err = m.UpdateImage([]byte(caseShared), flux.MustParseResourceID("default:deployment/missing"), "app", ref); err == nil {
+		t.Error("expected error for resource not in manifest")
+	}
+}
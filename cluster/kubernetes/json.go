@@ -0,0 +1,354 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux"
+)
+
+// Manifests written in JSON are updated by editing the JSON text
+// directly, rather than with kubeyaml, which would rewrite them as
+// YAML. Each edit replaces, adds or removes a single value, leaving
+// the rest of the file -- key order and indentation in particular --
+// as it was.
+
+// isJSON says whether a manifest is a JSON object, rather than YAML
+// (of which JSON is, technically, a subset).
+func isJSON(def []byte) bool {
+	trimmed := bytes.TrimSpace(def)
+	return len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed)
+}
+
+// jsonValue is a value in a JSON document, along with its position
+// in the document, so that it can be edited in place.
+type jsonValue struct {
+	kind       byte // one of '{', '[', '"', or 0 for other scalars
+	start, end int
+	keys       []jsonValue // for objects, the keys of the members
+	values     []jsonValue // for objects, the values of the members; for arrays, the items
+	str        string      // for strings, the decoded value
+}
+
+func (v *jsonValue) member(key string) *jsonValue {
+	if v == nil || v.kind != '{' {
+		return nil
+	}
+	for i := range v.keys {
+		if v.keys[i].str == key {
+			return &v.values[i]
+		}
+	}
+	return nil
+}
+
+// path follows the keys given through nested objects, returning nil
+// if any is missing.
+func (v *jsonValue) path(keys ...string) *jsonValue {
+	for _, k := range keys {
+		v = v.member(k)
+	}
+	return v
+}
+
+type jsonParser struct {
+	in  []byte
+	pos int
+}
+
+func parseJSON(in []byte) (*jsonValue, error) {
+	if !json.Valid(in) {
+		return nil, errors.New("invalid JSON")
+	}
+	p := &jsonParser{in: in}
+	p.skipSpace()
+	v := p.value()
+	return &v, nil
+}
+
+// Since the input has already been validated, the parser need not
+// check for errors.
+
+func (p *jsonParser) skipSpace() {
+	for p.pos < len(p.in) && strings.IndexByte(" \t\r\n", p.in[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *jsonParser) value() jsonValue {
+	v := jsonValue{start: p.pos}
+	switch p.in[p.pos] {
+	case '{':
+		v.kind = '{'
+		p.pos++
+		for p.skipSpace(); p.in[p.pos] != '}'; p.skipSpace() {
+			if p.in[p.pos] == ',' {
+				p.pos++
+				p.skipSpace()
+			}
+			v.keys = append(v.keys, p.value())
+			p.skipSpace()
+			p.pos++ // the ':'
+			p.skipSpace()
+			v.values = append(v.values, p.value())
+		}
+		p.pos++
+	case '[':
+		v.kind = '['
+		p.pos++
+		for p.skipSpace(); p.in[p.pos] != ']'; p.skipSpace() {
+			if p.in[p.pos] == ',' {
+				p.pos++
+				p.skipSpace()
+			}
+			v.values = append(v.values, p.value())
+		}
+		p.pos++
+	case '"':
+		v.kind = '"'
+		for p.pos++; p.in[p.pos] != '"'; p.pos++ {
+			if p.in[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		p.pos++
+		json.Unmarshal(p.in[v.start:p.pos], &v.str)
+	default:
+		for p.pos < len(p.in) && strings.IndexByte(",]} \t\r\n", p.in[p.pos]) < 0 {
+			p.pos++
+		}
+	}
+	v.end = p.pos
+	return v
+}
+
+func splice(in []byte, start, end int, with []byte) []byte {
+	out := make([]byte, 0, len(in)-(end-start)+len(with))
+	out = append(out, in[:start]...)
+	out = append(out, with...)
+	return append(out, in[end:]...)
+}
+
+// setJSONMember sets the member of the object given to the (encoded)
+// value given, replacing the value if the member is already present,
+// and otherwise adding it at the end, laid out like the members
+// before it.
+func setJSONMember(in []byte, obj *jsonValue, key string, value []byte) []byte {
+	if v := obj.member(key); v != nil {
+		return splice(in, v.start, v.end, value)
+	}
+	encodedKey, _ := json.Marshal(key)
+	n := len(obj.keys)
+	if n == 0 {
+		// Nothing to copy the layout from; if the document is
+		// indented, indent the member by one more level than the
+		// object's line.
+		member := append(encodedKey, ": "...)
+		member = append(member, value...)
+		if !bytes.Contains(in, []byte("\n")) {
+			return splice(in, obj.start+1, obj.end-1, member)
+		}
+		indent := lineIndent(in, obj.start)
+		inner := "\n" + indent + indentUnit(in) + string(member) + "\n" + indent
+		return splice(in, obj.start+1, obj.end-1, []byte(inner))
+	}
+	// e.g., `,\n    ` before the last key, and `: ` after it
+	last := obj.keys[n-1]
+	before := in[last.start-leadingSpace(in, last.start) : last.start]
+	sep := in[last.end:obj.values[n-1].start]
+	member := append([]byte(","), before...)
+	member = append(member, encodedKey...)
+	member = append(member, sep...)
+	member = append(member, value...)
+	at := obj.values[n-1].end
+	return splice(in, at, at, member)
+}
+
+// deleteJSONMember removes a member of the object given, along with
+// the comma separating it from its neighbour, if it's present.
+func deleteJSONMember(in []byte, obj *jsonValue, key string) []byte {
+	for i := range obj.keys {
+		if obj.keys[i].str != key {
+			continue
+		}
+		switch {
+		case i > 0:
+			return splice(in, obj.values[i-1].end, obj.values[i].end, nil)
+		case len(obj.keys) > 1:
+			return splice(in, obj.keys[0].start, obj.keys[1].start, nil)
+		default:
+			return splice(in, obj.start+1, obj.end-1, nil)
+		}
+	}
+	return in
+}
+
+func leadingSpace(in []byte, pos int) int {
+	n := 0
+	for pos-n > 0 && strings.IndexByte(" \t\r\n", in[pos-n-1]) >= 0 {
+		n++
+	}
+	return n
+}
+
+// lineIndent gives the whitespace at the start of the line pos is in.
+func lineIndent(in []byte, pos int) string {
+	start := bytes.LastIndexByte(in[:pos], '\n') + 1
+	end := start
+	for end < pos && (in[end] == ' ' || in[end] == '\t') {
+		end++
+	}
+	return string(in[start:end])
+}
+
+// indentUnit guesses the indentation used for each level in the
+// document, from the first indented line.
+func indentUnit(in []byte) string {
+	for _, line := range strings.Split(string(in), "\n")[1:] {
+		if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; indent != "" {
+			return indent
+		}
+	}
+	return "  "
+}
+
+// findJSONResource finds the object for the resource identified, in
+// a manifest that is either the resource itself, or a List
+// containing it.
+func findJSONResource(in []byte, id flux.ResourceID) (*jsonValue, error) {
+	root, err := parseJSON(in)
+	if err != nil {
+		return nil, err
+	}
+	matches := func(v *jsonValue) bool {
+		var header resourceHeader
+		if err := yaml.Unmarshal(in[v.start:v.end], &header); err != nil {
+			return false
+		}
+		return header.matches(id)
+	}
+	if matches(root) {
+		return root, nil
+	}
+	if kind := root.member("kind"); kind != nil && kind.str == "List" {
+		if items := root.member("items"); items != nil && items.kind == '[' {
+			for i := range items.values {
+				if item := &items.values[i]; item.kind == '{' && matches(item) {
+					return item, nil
+				}
+			}
+		}
+	}
+	return nil, errors.Errorf("resource %s not found in JSON manifest", id)
+}
+
+// jsonPodSpecPath gives the path to the pod spec, for a kind of
+// workload.
+func jsonPodSpecPath(kind string) []string {
+	switch strings.ToLower(kind) {
+	case "deployment", "daemonset", "statefulset":
+		return []string{"spec", "template", "spec"}
+	case "cronjob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	return nil
+}
+
+func updateJSONImage(in []byte, id flux.ResourceID, container, newImage string) ([]byte, error) {
+	_, kind, _ := id.Components()
+	podSpecPath := jsonPodSpecPath(kind)
+	if podSpecPath == nil {
+		return nil, UpdateNotSupportedError(kind)
+	}
+	res, err := findJSONResource(in, id)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := res.path(podSpecPath...)
+	for _, field := range []string{"containers", "initContainers"} {
+		containers := podSpec.member(field)
+		if containers == nil || containers.kind != '[' {
+			continue
+		}
+		for i := range containers.values {
+			c := &containers.values[i]
+			if name := c.member("name"); name == nil || name.str != container {
+				continue
+			}
+			image := c.member("image")
+			if image == nil {
+				return nil, errors.Errorf("container %q in %s has no image", container, id)
+			}
+			value, _ := json.Marshal(newImage)
+			return splice(in, image.start, image.end, value), nil
+		}
+	}
+	return nil, errors.Errorf("container %q not found in %s", container, id)
+}
+
+// updateJSONField replaces the string at a path of field names in a
+// resource.
+func updateJSONField(in []byte, id flux.ResourceID, path, value string) ([]byte, error) {
+	res, err := findJSONResource(in, id)
+	if err != nil {
+		return nil, err
+	}
+	field := res.path(strings.Split(path, ".")...)
+	if field == nil || field.kind != '"' {
+		return nil, errors.Errorf("no image at %s in %s", path, id)
+	}
+	encoded, _ := json.Marshal(value)
+	return splice(in, field.start, field.end, encoded), nil
+}
+
+// annotateJSON sets and removes annotations, given as `key=value`,
+// or `key=` to remove the annotation, like `kubeyaml annotate`.
+func annotateJSON(in []byte, id flux.ResourceID, annotations ...string) ([]byte, error) {
+	for _, a := range annotations {
+		kv := strings.SplitN(a, "=", 2)
+		key, value := kv[0], kv[1]
+
+		res, err := findJSONResource(in, id)
+		if err != nil {
+			return nil, err
+		}
+		metadata := res.member("metadata")
+		if metadata == nil {
+			return nil, errors.Errorf("resource %s has no metadata", id)
+		}
+		anns := metadata.member("annotations")
+		if value == "" {
+			if anns != nil {
+				in = deleteJSONMember(in, anns, key)
+			}
+			continue
+		}
+		if anns == nil || anns.kind != '{' {
+			in = setJSONMember(in, metadata, "annotations", []byte("{}"))
+			if res, err = findJSONResource(in, id); err != nil {
+				return nil, err
+			}
+			anns = res.path("metadata", "annotations")
+		}
+		encoded, _ := json.Marshal(value)
+		in = setJSONMember(in, anns, key, encoded)
+	}
+	return in, nil
+}
+
+func setJSONChartVersion(in []byte, id flux.ResourceID, version string) ([]byte, error) {
+	res, err := findJSONResource(in, id)
+	if err != nil {
+		return nil, err
+	}
+	chart := res.path("spec", "chart")
+	if chart == nil || chart.kind != '{' {
+		return nil, errors.Errorf("resource %s has no spec.chart", id)
+	}
+	encoded, _ := json.Marshal(version)
+	return setJSONMember(in, chart, "version", encoded), nil
+}
@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+)
+
+const jsonDeployment = `{
+    "apiVersion": "apps/v1",
+    "kind": "Deployment",
+    "metadata": {
+        "name": "helloworld",
+        "namespace": "default"
+    },
+    "spec": {
+        "template": {
+            "spec": {
+                "initContainers": [
+                    {"name": "init", "image": "quay.io/weaveworks/init:1"}
+                ],
+                "containers": [
+                    {
+                        "name": "greeter",
+                        "image": "quay.io/weaveworks/helloworld:master-a000001",
+                        "args": ["--port", "80"]
+                    }
+                ]
+            }
+        }
+    }
+}
+`
+
+func TestUpdateJSONImage(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	ref, _ := image.ParseRef("quay.io/weaveworks/helloworld:master-a000002")
+
+	out, err := (&Manifests{}).UpdateImage([]byte(jsonDeployment), id, "greeter", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `                        "image": "quay.io/weaveworks/helloworld:master-a000002",`
+	if !containsLine(string(out), expected) {
+		t.Errorf("expected updated image line %q in:\n%s", expected, out)
+	}
+	if len(out) != len(jsonDeployment) {
+		t.Errorf("expected only the image to change, got:\n%s", out)
+	}
+
+	ref, _ = image.ParseRef("quay.io/weaveworks/init:2")
+	out, err = (&Manifests{}).UpdateImage(out, id, "init", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `                    {"name": "init", "image": "quay.io/weaveworks/init:2"}`
+	if !containsLine(string(out), expected) {
+		t.Errorf("expected updated init container %q in:\n%s", expected, out)
+	}
+
+	if _, err = (&Manifests{}).UpdateImage(out, id, "notthere", ref); err == nil {
+		t.Error("expected error for missing container")
+	}
+}
+
+func TestUpdateJSONImageInList(t *testing.T) {
+	list := `{"kind": "List", "items": [
+  {"kind": "Service", "metadata": {"name": "helloworld"}},
+  {"kind": "Deployment", "metadata": {"name": "helloworld"},
+   "spec": {"template": {"spec": {"containers": [{"name": "greeter", "image": "helloworld:1"}]}}}}
+]}`
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	ref, _ := image.ParseRef("helloworld:2")
+	out, err := (&Manifests{}).UpdateImage([]byte(list), id, "greeter", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `   "spec": {"template": {"spec": {"containers": [{"name": "greeter", "image": "helloworld:2"}]}}}}`
+	if !containsLine(string(out), expected) {
+		t.Errorf("expected %q in:\n%s", expected, out)
+	}
+}
+
+func TestUpdateJSONPolicies(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	add := policy.Update{
+		Add: policy.Set{policy.Automated: "true"},
+	}
+	out, err := (&Manifests{}).UpdatePolicies([]byte(jsonDeployment), id, add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `    "metadata": {
+        "name": "helloworld",
+        "namespace": "default",
+        "annotations": {
+            "flux.weave.works/automated": "true"
+        }
+    },`
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("expected annotation to be added, as\n%s\ngot:\n%s", expected, out)
+	}
+
+	add = policy.Update{
+		Add: policy.Set{policy.Locked: "true"},
+	}
+	out, err = (&Manifests{}).UpdatePolicies(out, id, add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `        "annotations": {
+            "flux.weave.works/automated": "true",
+            "flux.weave.works/locked": "true"
+        }`
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("expected second annotation to be added, as\n%s\ngot:\n%s", expected, out)
+	}
+
+	remove := policy.Update{
+		Remove: policy.Set{policy.Automated: "true", policy.Locked: "true"},
+	}
+	out, err = (&Manifests{}).UpdatePolicies(out, id, remove)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `        "annotations": {}`
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("expected annotations to be removed, got:\n%s", out)
+	}
+}
+
+func TestUpdateJSONChartVersion(t *testing.T) {
+	fhr := `{
+  "apiVersion": "helm.integrations.flux.weave.works/v1alpha2",
+  "kind": "FluxHelmRelease",
+  "metadata": {"name": "mariadb", "namespace": "maria"},
+  "spec": {
+    "chart": {
+      "repository": "https://kubernetes-charts.storage.googleapis.com/",
+      "name": "mariadb"
+    }
+  }
+}`
+	id := flux.MustParseResourceID("maria:fluxhelmrelease/mariadb")
+	out, err := (&Manifests{}).UpdateChartVersion([]byte(fhr), id, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `      "name": "mariadb",
+      "version": "1.2.3"
+    }`
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("expected version to be added, as\n%s\ngot:\n%s", expected, out)
+	}
+	out, err = (&Manifests{}).UpdateChartVersion(out, id, "1.2.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"version": "1.2.4"`) {
+		t.Errorf("expected version to be replaced, got:\n%s", out)
+	}
+}
+
+func containsLine(s, line string) bool {
+	return strings.Contains("\n"+s, "\n"+line+"\n")
+}
@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"sync"
 
 	k8syaml "github.com/ghodss/yaml"
@@ -13,10 +14,12 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	k8sclient "k8s.io/client-go/kubernetes"
 
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
 	"github.com/weaveworks/flux/resource"
 	"github.com/weaveworks/flux/ssh"
 )
@@ -107,6 +110,11 @@ type Cluster struct {
 	nsWhitelist       []string
 	nsWhitelistLogged map[string]bool // to keep track of whether we've logged a problem with seeing a whitelisted ns
 
+	shard Shard
+
+	dynamicClient dynamic.Interface
+	customKinds   map[string]resourceKind
+
 	mu sync.Mutex
 }
 
@@ -116,7 +124,8 @@ func NewCluster(clientset k8sclient.Interface,
 	applier Applier,
 	sshKeyRing ssh.KeyRing,
 	logger log.Logger,
-	nsWhitelist []string) *Cluster {
+	nsWhitelist []string,
+	shard Shard) *Cluster {
 
 	c := &Cluster{
 		client: extendedClient{
@@ -128,11 +137,38 @@ func NewCluster(clientset k8sclient.Interface,
 		sshKeyRing:        sshKeyRing,
 		nsWhitelist:       nsWhitelist,
 		nsWhitelistLogged: map[string]bool{},
+		shard:             shard,
 	}
 
 	return c
 }
 
+// SetWorkloadKinds makes the cluster treat resources of the kinds
+// given as workloads, fetching them with the dynamic client given.
+func (c *Cluster) SetWorkloadKinds(client dynamic.Interface, kinds kresource.WorkloadKinds) {
+	c.dynamicClient = client
+	c.customKinds = map[string]resourceKind{}
+	for _, k := range kinds {
+		c.customKinds[strings.ToLower(k.Kind)] = &customKind{k}
+	}
+}
+
+// kinds gives all the kinds of workload the cluster knows about:
+// those built in, and those configured.
+func (c *Cluster) kinds() map[string]resourceKind {
+	if len(c.customKinds) == 0 {
+		return resourceKinds
+	}
+	kinds := make(map[string]resourceKind, len(resourceKinds)+len(c.customKinds))
+	for k, kind := range resourceKinds {
+		kinds[k] = kind
+	}
+	for k, kind := range c.customKinds {
+		kinds[k] = kind
+	}
+	return kinds
+}
+
 // --- cluster.Cluster
 
 // SomeControllers returns the controllers named, missing out any that don't
@@ -142,8 +178,11 @@ func (c *Cluster) SomeControllers(ids []flux.ResourceID) (res []cluster.Controll
 	var controllers []cluster.Controller
 	for _, id := range ids {
 		ns, kind, name := id.Components()
+		if !c.shard.Owns(ns) {
+			continue
+		}
 
-		resourceKind, ok := resourceKinds[kind]
+		resourceKind, ok := c.kinds()[kind]
 		if !ok {
 			return nil, fmt.Errorf("Unsupported kind %v", kind)
 		}
@@ -174,7 +213,7 @@ func (c *Cluster) AllControllers(namespace string) (res []cluster.Controller, er
 			continue
 		}
 
-		for kind, resourceKind := range resourceKinds {
+		for kind, resourceKind := range c.kinds() {
 			podControllers, err := resourceKind.getPodControllers(c, ns.Name)
 			if err != nil {
 				if se, ok := err.(*apierrors.StatusError); ok && se.ErrStatus.Reason == meta_v1.StatusReasonNotFound {
@@ -218,6 +257,10 @@ func (c *Cluster) Sync(spec cluster.SyncDef) error {
 			}
 			obj, err := parseObj(stage.res.Bytes())
 			if err == nil {
+				if !c.shard.Owns(obj.Metadata.Namespace) {
+					// another daemon instance is responsible for this one
+					break
+				}
 				obj.Resource = stage.res
 				cs.stage(stage.cmd, obj)
 			} else {
@@ -260,7 +303,7 @@ func (c *Cluster) Export() ([]byte, error) {
 			return nil, errors.Wrap(err, "marshalling namespace to YAML")
 		}
 
-		for _, resourceKind := range resourceKinds {
+		for _, resourceKind := range c.kinds() {
 			podControllers, err := resourceKind.getPodControllers(c, ns.Name)
 			if err != nil {
 				if se, ok := err.(*apierrors.StatusError); ok && se.ErrStatus.Reason == meta_v1.StatusReasonNotFound {
@@ -313,8 +356,23 @@ func (c *Cluster) PublicSSHKey(regenerate bool) (ssh.PublicKey, error) {
 // to have access to and can look for resources inside of.
 // It returns a list of all namespaces unless a namespace whitelist has been set on the Cluster
 // instance, in which case it returns a list containing the namespaces from the whitelist
-// that exist in the cluster.
+// that exist in the cluster. In either case, namespaces that belong
+// to another shard are left out.
 func (c *Cluster) getAllowedNamespaces() ([]apiv1.Namespace, error) {
+	namespaces, err := c.getVisibleNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	nsList := []apiv1.Namespace{}
+	for _, ns := range namespaces {
+		if c.shard.Owns(ns.Name) {
+			nsList = append(nsList, ns)
+		}
+	}
+	return nsList, nil
+}
+
+func (c *Cluster) getVisibleNamespaces() ([]apiv1.Namespace, error) {
 	if len(c.nsWhitelist) > 0 {
 		nsList := []apiv1.Namespace{}
 		for _, name := range c.nsWhitelist {
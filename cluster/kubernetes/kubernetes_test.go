@@ -5,9 +5,12 @@ import (
 	"testing"
 
 	"github.com/go-kit/kit/log"
+	apibatchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/weaveworks/flux"
 )
 
 func newNamespace(name string) *apiv1.Namespace {
@@ -26,7 +29,7 @@ func testGetAllowedNamespaces(t *testing.T, namespace []string, expected []strin
 	clientset := fakekubernetes.NewSimpleClientset(newNamespace("default"),
 		newNamespace("kube-system"))
 
-	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), namespace)
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), namespace, Shard{})
 
 	namespaces, err := c.getAllowedNamespaces()
 	if err != nil {
@@ -62,3 +65,53 @@ func TestGetAllowedNamespacesNamespacesSetDoesNotExist(t *testing.T) {
 func TestGetAllowedNamespacesNamespacesMultiple(t *testing.T) {
 	testGetAllowedNamespaces(t, []string{"default", "hello", "kube-system"}, []string{"default", "kube-system"})
 }
+
+func TestJobsAndPodsAreImmutableControllers(t *testing.T) {
+	isController := true
+	job := &apibatchv1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "migrate", Namespace: "default"},
+		Spec: apibatchv1.JobSpec{Template: apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "migrate", Image: "quay.io/example/migrate:1.0"}},
+		}}},
+	}
+	pod := &apiv1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "debug", Namespace: "default"},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "debug", Image: "busybox:1.29"}},
+		},
+	}
+	// Pods belonging to something else are not listed by themselves
+	owned := &apiv1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "migrate-abcde",
+			Namespace: "default",
+			OwnerReferences: []meta_v1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "Job", Name: "migrate", Controller: &isController},
+			},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "migrate", Image: "quay.io/example/migrate:1.0"}},
+		},
+	}
+	clientset := fakekubernetes.NewSimpleClientset(newNamespace("default"), job, pod, owned)
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), nil, Shard{})
+
+	ids := map[string]bool{}
+	for _, kind := range []string{"job", "pod"} {
+		podControllers, err := resourceKinds[kind].getPodControllers(c, "default")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pc := range podControllers {
+			controller := pc.toClusterController(flux.MakeResourceID("default", kind, pc.name))
+			if !controller.Immutable {
+				t.Errorf("expected %s to be immutable", controller.ID)
+			}
+			ids[controller.ID.String()] = true
+		}
+	}
+	expected := map[string]bool{"default:job/migrate": true, "default:pod/debug": true}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected controllers %v, got %v", expected, ids)
+	}
+}
@@ -1,6 +1,9 @@
 package kubernetes
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/weaveworks/flux"
 	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
 	"github.com/weaveworks/flux/image"
@@ -8,18 +11,119 @@ import (
 )
 
 type Manifests struct {
+	// If true, images are updated by changing only the value of the
+	// image field where possible, so comments, key order and quoting
+	// are left as they were
+	PreserveFormatting bool
+	// If true, Jsonnet files are evaluated and the resources they
+	// result in loaded, along with those in YAML and JSON files
+	Jsonnet bool
+	// Resources of these kinds are treated as workloads, with images
+	// at the paths given for each kind
+	Workloads kresource.WorkloadKinds
+}
+
+func (c *Manifests) loader() kresource.Loader {
+	return kresource.Loader{Jsonnet: c.Jsonnet, Workloads: c.Workloads}
 }
 
 func (c *Manifests) LoadManifests(base string, paths []string) (map[string]resource.Resource, error) {
-	return kresource.Load(base, paths)
+	return c.loader().Load(base, paths)
 }
 
 func (c *Manifests) ParseManifests(allDefs []byte) (map[string]resource.Resource, error) {
-	return kresource.ParseMultidoc(allDefs, "exported")
+	return c.loader().ParseMultidoc(allDefs, "exported")
+}
+
+func (c *Manifests) UpdateImage(def []byte, id flux.ResourceID, container string, ref image.Ref) ([]byte, error) {
+	workload, err := findWorkload(c.loader(), def, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := containerImage(workload, id, container)
+	if err != nil {
+		return nil, err
+	}
+	if custom, ok := workload.(*kresource.CustomWorkload); ok {
+		return c.updateCustomImage(def, id, custom.WorkloadKind(), container, current, ref)
+	}
+	newImage, ok := image.ReplaceKeepingTemplate(current, ref.String())
+	if !ok {
+		return nil, fmt.Errorf("image %q for container %q in %s is templated, and cannot be updated to %s", current, container, id, ref)
+	}
+
+	var out []byte
+	switch {
+	case isJSON(def):
+		if out, err = updateJSONImage(def, id, container, newImage); err != nil {
+			return nil, err
+		}
+	case c.PreserveFormatting:
+		if out, ok = updateImageInPlace(def, id, container, newImage); ok {
+			return out, nil
+		}
+		fallthrough
+	default:
+		if newImage != ref.String() {
+			// kubeyaml would replace the placeholder along with
+			// everything else
+			return nil, fmt.Errorf("image %q for container %q in %s has a templated prefix, which can only be kept if the manifest is laid out in block style", current, container, id)
+		}
+		if out, err = updatePodController(def, id, container, ref); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkImageUpdate(c.loader(), def, out, id, container, newImage); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// updateCustomImage updates the image at a path in a resource of one
+// of the workload kinds configured; the container is the path.
+func (c *Manifests) updateCustomImage(def []byte, id flux.ResourceID, kind kresource.WorkloadKind, path, current string, ref image.Ref) ([]byte, error) {
+	newImage, ok := image.ReplaceKeepingTemplate(current, ref.String())
+	if !ok {
+		return nil, fmt.Errorf("image %q at %s in %s is templated, and cannot be updated to %s", current, path, id, ref)
+	}
+
+	var out []byte
+	var err error
+	switch {
+	case isJSON(def):
+		if out, err = updateJSONField(def, id, path, newImage); err != nil {
+			return nil, err
+		}
+	case c.PreserveFormatting:
+		if out, ok = updateFieldInPlace(c.loader(), def, id, path, newImage); ok {
+			return out, nil
+		}
+		fallthrough
+	default:
+		if newImage != ref.String() {
+			return nil, fmt.Errorf("image %q at %s in %s has a templated prefix, which can only be kept if the manifest is laid out in block style", current, path, id)
+		}
+		namespace, _, name := id.Components()
+		if out, err = (KubeYAML{}).Set(def, namespace, kind.Kind, name, path+"="+newImage); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkImageUpdate(c.loader(), def, out, id, path, newImage); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (c *Manifests) UpdateImage(def []byte, id flux.ResourceID, container string, image image.Ref) ([]byte, error) {
-	return updatePodController(def, id, container, image)
+func (c *Manifests) UpdateChartVersion(def []byte, id flux.ResourceID, version string) ([]byte, error) {
+	namespace, kind, name := id.Components()
+	if strings.ToLower(kind) != "fluxhelmrelease" {
+		return nil, UpdateNotSupportedError(kind)
+	}
+	if isJSON(def) {
+		return setJSONChartVersion(def, id, version)
+	}
+	return (KubeYAML{}).Set(def, namespace, kind, name, "spec.chart.version="+version)
 }
 
 // UpdatePolicies and ServicesWithPolicies in policies.go
+// BootstrapNamespace in bootstrap.go
@@ -21,12 +21,12 @@ func (m *Manifests) UpdatePolicies(def []byte, id flux.ResourceID, update policy
 	// what all the containers are.
 	if tagAll, ok := update.Add.Get(policy.TagAll); ok {
 		add = add.Without(policy.TagAll)
-		containers, err := extractContainers(def, id)
+		workload, err := findWorkload(m.loader(), def, id)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, container := range containers {
+		for _, container := range workload.Containers() {
 			if tagAll == policy.PatternAll.String() {
 				del = del.Add(policy.TagPrefix(container.Name))
 			} else {
@@ -46,6 +46,9 @@ func (m *Manifests) UpdatePolicies(def []byte, id flux.ResourceID, update policy
 		args = append(args, fmt.Sprintf("%s%s=", kresource.PolicyPrefix, pol))
 	}
 
+	if isJSON(def) {
+		return annotateJSON(def, id, args...)
+	}
 	return (KubeYAML{}).Annotate(def, ns, kind, name, args...)
 }
 
@@ -66,8 +69,8 @@ func extractAnnotations(def []byte) (map[string]string, error) {
 	return m.Metadata.Annotations, nil
 }
 
-func extractContainers(def []byte, id flux.ResourceID) ([]resource.Container, error) {
-	resources, err := kresource.ParseMultidoc(def, "stdin")
+func findWorkload(loader kresource.Loader, def []byte, id flux.ResourceID) (resource.Workload, error) {
+	resources, err := loader.ParseMultidoc(def, "stdin")
 	if err != nil {
 		return nil, err
 	}
@@ -79,5 +82,5 @@ func extractContainers(def []byte, id flux.ResourceID) ([]resource.Container, er
 	if !ok {
 		return nil, errors.New("resource " + id.String() + " does not have containers")
 	}
-	return workload.Containers(), nil
+	return workload, nil
 }
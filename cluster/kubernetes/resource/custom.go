@@ -0,0 +1,221 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/resource"
+)
+
+// WorkloadKind describes a kind of resource that flux doesn't
+// otherwise know about -- usually a custom resource, for an operator
+// -- that nonetheless has images in it. The images are found, and
+// updated, at each of the paths given, which are field names
+// separated by dots, e.g., `spec.server.image`.
+//
+// Since there are no container names, each image is treated as a
+// container named by its path.
+type WorkloadKind struct {
+	APIVersion string
+	Kind       string
+	ImagePaths []string
+}
+
+// ParseWorkloadKinds parses workload kinds given as
+// `<apiVersion>/<Kind>=<path>`, e.g.,
+// `example.com/v1/Widget=spec.image`. The same kind can be given more
+// than once, to supply more than one path.
+func ParseWorkloadKinds(specs []string) (WorkloadKinds, error) {
+	var kinds WorkloadKinds
+	index := map[string]int{}
+	for _, spec := range specs {
+		eq := strings.Index(spec, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("image path %q is not of the form <apiVersion>/<Kind>=<path>", spec)
+		}
+		gvk, path := spec[:eq], spec[eq+1:]
+		slash := strings.LastIndex(gvk, "/")
+		if slash <= 0 || slash == len(gvk)-1 {
+			return nil, fmt.Errorf("image path %q does not start with <apiVersion>/<Kind>", spec)
+		}
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+			return nil, fmt.Errorf("image path %q does not have a path to a field, e.g., spec.image", spec)
+		}
+		apiVersion, kind := gvk[:slash], gvk[slash+1:]
+		if builtinWorkloadKind(kind) {
+			return nil, fmt.Errorf("image path %q is for a kind of workload flux already knows about", spec)
+		}
+		// Resources are identified by kind, without the group, so
+		// there can be only one group for each kind
+		key := strings.ToLower(kind)
+		i, ok := index[key]
+		if ok && kinds[i].APIVersion != apiVersion {
+			return nil, fmt.Errorf("image path %q is for kind %s, which is already given with apiVersion %s", spec, kind, kinds[i].APIVersion)
+		}
+		if !ok {
+			i = len(kinds)
+			index[key] = i
+			kinds = append(kinds, WorkloadKind{APIVersion: apiVersion, Kind: kind})
+		}
+		kinds[i].ImagePaths = append(kinds[i].ImagePaths, path)
+	}
+	return kinds, nil
+}
+
+func builtinWorkloadKind(kind string) bool {
+	switch strings.ToLower(kind) {
+	case "cronjob", "daemonset", "deployment", "statefulset", "fluxhelmrelease":
+		return true
+	}
+	return false
+}
+
+// Matches says whether a resource with the apiVersion and kind given
+// is of this kind.
+func (k WorkloadKind) Matches(apiVersion, kind string) bool {
+	return k.APIVersion == apiVersion && strings.EqualFold(k.Kind, kind)
+}
+
+// HasPath says whether the path given is one of those where images
+// are found, i.e., whether it names a container.
+func (k WorkloadKind) HasPath(path string) bool {
+	for _, p := range k.ImagePaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// FindImages calls visit with each path that has an image, and the
+// image, in the object given, in the order the paths were given. The
+// object can be from a YAML file, or from the Kubernetes API (i.e.,
+// unstructured content). Paths that are missing, or don't have an
+// image, are skipped.
+func (k WorkloadKind) FindImages(obj interface{}, visit func(path string, ref image.Ref)) {
+	for _, path := range k.ImagePaths {
+		value, ok := valueAtPath(obj, path)
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		ref, err := image.ParseRef(s)
+		if err != nil {
+			continue
+		}
+		visit(path, ref)
+	}
+}
+
+func asMapper(v interface{}) (mapper, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return stringMap(m), true
+	case map[interface{}]interface{}:
+		return anyMap(m), true
+	}
+	return nil, false
+}
+
+func valueAtPath(obj interface{}, path string) (interface{}, bool) {
+	value := obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := asMapper(value)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m.get(key); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func setValueAtPath(obj interface{}, path string, value interface{}) bool {
+	keys := strings.Split(path, ".")
+	parent, ok := valueAtPath(obj, strings.Join(keys[:len(keys)-1], "."))
+	if len(keys) == 1 {
+		parent, ok = obj, true
+	}
+	if !ok {
+		return false
+	}
+	m, ok := asMapper(parent)
+	if !ok {
+		return false
+	}
+	m.set(keys[len(keys)-1], value)
+	return true
+}
+
+// CustomWorkload is a resource of one of the workload kinds
+// configured, interpreted as having a container for each of the
+// kind's image paths.
+type CustomWorkload struct {
+	baseObject
+	kind WorkloadKind
+	doc  map[interface{}]interface{}
+}
+
+func (w CustomWorkload) Containers() []resource.Container {
+	var containers []resource.Container
+	w.kind.FindImages(w.doc, func(path string, ref image.Ref) {
+		containers = append(containers, resource.Container{Name: path, Image: ref})
+	})
+	return containers
+}
+
+func (w CustomWorkload) SetContainerImage(container string, ref image.Ref) error {
+	if !w.kind.HasPath(container) || !setValueAtPath(w.doc, container, ref.String()) {
+		return fmt.Errorf("container %q not found in workload", container)
+	}
+	return nil
+}
+
+// WorkloadKind gives the kind of workload, as configured, that this
+// resource is.
+func (w CustomWorkload) WorkloadKind() WorkloadKind {
+	return w.kind
+}
+
+var _ resource.Workload = CustomWorkload{}
+
+// WorkloadKinds is a set of workload kinds, which can be used to
+// interpret resources that would otherwise be of no interest.
+type WorkloadKinds []WorkloadKind
+
+// Lookup gives the workload kind for the apiVersion and kind given,
+// if there is one.
+func (ks WorkloadKinds) Lookup(apiVersion, kind string) (WorkloadKind, bool) {
+	for _, k := range ks {
+		if k.Matches(apiVersion, kind) {
+			return k, true
+		}
+	}
+	return WorkloadKind{}, false
+}
+
+// interpret gives the resource as a custom workload if it is of one of
+// the kinds, or else the resource as it is.
+func (ks WorkloadKinds) interpret(res resource.Resource) (resource.Resource, error) {
+	base, ok := res.(*baseObject)
+	if !ok || len(ks) == 0 {
+		return res, nil
+	}
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(base.bytes, &doc); err != nil {
+		return nil, err
+	}
+	apiVersion, _ := doc["apiVersion"].(string)
+	kind, ok := ks.Lookup(apiVersion, base.Kind)
+	if !ok {
+		return res, nil
+	}
+	return &CustomWorkload{baseObject: *base, kind: kind, doc: doc}, nil
+}
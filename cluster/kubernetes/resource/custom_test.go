@@ -0,0 +1,113 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/resource"
+)
+
+func TestParseWorkloadKinds(t *testing.T) {
+	kinds, err := ParseWorkloadKinds([]string{
+		"example.com/v1/Widget=spec.image",
+		"example.com/v1/Widget=spec.sidecar.image",
+		"v1/Pod=spec.image",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("expected two kinds, got %#v", kinds)
+	}
+	widget := kinds[0]
+	if widget.APIVersion != "example.com/v1" || widget.Kind != "Widget" || len(widget.ImagePaths) != 2 {
+		t.Errorf("unexpected kind %#v", widget)
+	}
+	if kinds[1].APIVersion != "v1" || kinds[1].Kind != "Pod" {
+		t.Errorf("unexpected kind %#v", kinds[1])
+	}
+
+	for _, spec := range []string{
+		"Widget=spec.image",
+		"example.com/v1/Widget",
+		"example.com/v1/=spec.image",
+		"example.com/v1/Widget=",
+		"example.com/v1/Widget=spec..image",
+		"apps/v1/Deployment=spec.image",
+	} {
+		if _, err := ParseWorkloadKinds([]string{spec}); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+	if _, err := ParseWorkloadKinds([]string{"example.com/v1/Widget=spec.image", "other.com/v1/Widget=spec.image"}); err == nil {
+		t.Error("expected the same kind in two groups to be rejected")
+	}
+}
+
+const widgetManifest = `---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: frobnicator
+  namespace: tools
+spec:
+  image: quay.io/example/widget:1.0
+  sidecar:
+    image: quay.io/example/sidecar:2.0
+---
+apiVersion: other.com/v1
+kind: Widget
+metadata:
+  name: different
+spec:
+  image: quay.io/example/widget:1.0
+`
+
+func TestParseCustomWorkload(t *testing.T) {
+	kinds, err := ParseWorkloadKinds([]string{
+		"example.com/v1/Widget=spec.image",
+		"example.com/v1/Widget=spec.sidecar.image",
+		"example.com/v1/Widget=spec.missing.image",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := Loader{Workloads: kinds}.ParseMultidoc([]byte(widgetManifest), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := objs["default:widget/different"].(resource.Workload); ok {
+		t.Error("expected resource with another apiVersion not to be a workload")
+	}
+	workload, ok := objs["tools:widget/frobnicator"].(resource.Workload)
+	if !ok {
+		t.Fatalf("expected a workload, got %#v", objs["tools:widget/frobnicator"])
+	}
+	containers := workload.Containers()
+	if len(containers) != 2 ||
+		containers[0].Name != "spec.image" || containers[0].Image.String() != "quay.io/example/widget:1.0" ||
+		containers[1].Name != "spec.sidecar.image" || containers[1].Image.String() != "quay.io/example/sidecar:2.0" {
+		t.Errorf("unexpected containers %#v", containers)
+	}
+
+	ref, _ := image.ParseRef("quay.io/example/sidecar:2.1")
+	if err := workload.SetContainerImage("spec.sidecar.image", ref); err != nil {
+		t.Fatal(err)
+	}
+	if img := workload.Containers()[1].Image.String(); img != ref.String() {
+		t.Errorf("expected sidecar image to be %s, got %s", ref, img)
+	}
+	if err := workload.SetContainerImage("spec.nope", ref); err == nil {
+		t.Error("expected setting an image at a path not configured to fail")
+	}
+
+	// Without the kinds, it's not a workload
+	plain, err := ParseMultidoc([]byte(widgetManifest), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plain["tools:widget/frobnicator"].(resource.Workload); ok {
+		t.Error("expected resource not to be a workload without configuring its kind")
+	}
+}
@@ -28,6 +28,11 @@ type FluxHelmRelease struct {
 	baseObject
 	Spec struct {
 		Values map[string]interface{}
+		Chart  *struct {
+			Repository string
+			Name       string
+			Version    string
+		}
 	}
 }
 
@@ -192,6 +197,20 @@ func (fhr FluxHelmRelease) Containers() []resource.Container {
 	return containers
 }
 
+// Chart returns the chart from a chart repository that the
+// FluxHelmRelease refers to, if it does.
+func (fhr FluxHelmRelease) Chart() (resource.Chart, bool) {
+	c := fhr.Spec.Chart
+	if c == nil || c.Repository == "" || c.Name == "" {
+		return resource.Chart{}, false
+	}
+	return resource.Chart{
+		Repository: c.Repository,
+		Name:       c.Name,
+		Version:    c.Version,
+	}, true
+}
+
 // SetContainerImage mutates this resource by setting the `image`
 // field of `values`, or a subvalue therein, per one of the
 // interpretations in `FindFluxHelmReleaseContainers` above. NB we can
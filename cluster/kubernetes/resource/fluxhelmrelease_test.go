@@ -419,3 +419,45 @@ spec:
 		}
 	}
 }
+
+func TestParseChartSource(t *testing.T) {
+	doc := `---
+apiVersion: helm.integrations.flux.weave.works/v1alpha2
+kind: FluxHelmRelease
+metadata:
+  name: podinfo
+  namespace: test
+spec:
+  chart:
+    repository: https://stefanprodan.github.io/flux-helm-charts/
+    name: podinfo
+    version: 1.2.0
+  values:
+    image: stefanprodan/podinfo:1.2.0
+`
+
+	resources, err := ParseMultidoc([]byte(doc), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := resources["test:fluxhelmrelease/podinfo"]
+	if !ok {
+		t.Fatalf("expected resource not found; instead got %#v", resources)
+	}
+	cr, ok := res.(resource.ChartRelease)
+	if !ok {
+		t.Fatalf("expected resource to be a ChartRelease, instead got %#v", res)
+	}
+	chart, ok := cr.Chart()
+	if !ok {
+		t.Fatal("expected a chart from a chart repository")
+	}
+	expected := resource.Chart{
+		Repository: "https://stefanprodan.github.io/flux-helm-charts/",
+		Name:       "podinfo",
+		Version:    "1.2.0",
+	}
+	if chart != expected {
+		t.Errorf("expected chart %#v, got %#v", expected, chart)
+	}
+}
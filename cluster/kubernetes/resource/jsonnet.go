@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/resource"
+)
+
+// evalJsonnet evaluates the Jsonnet file at path, using the
+// executable `jsonnet`, and parses the resources in the result. The
+// result can be a single resource (which may be a List), an array of
+// resources, or an object with resources as its values -- the last
+// being the form `jsonnet -m` would write to separate files.
+func evalJsonnet(path, source string) (map[string]resource.Resource, error) {
+	cmd := exec.Command("jsonnet", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		if errOut.Len() > 0 {
+			err = errors.New(strings.TrimSpace(errOut.String()))
+		}
+		return nil, errors.Wrapf(err, "evaluating Jsonnet in %q", source)
+	}
+	return parseJsonnetOutput(out.Bytes(), source)
+}
+
+func parseJsonnetOutput(out []byte, source string) (map[string]resource.Resource, error) {
+	var docs []json.RawMessage
+	switch {
+	case looksLikeJSONObject(out):
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(out, &fields); err != nil {
+			return nil, errors.Wrapf(err, "parsing result of Jsonnet in %q", source)
+		}
+		if _, ok := fields["kind"]; ok {
+			docs = []json.RawMessage{out}
+			break
+		}
+		var keys []string
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			docs = append(docs, fields[k])
+		}
+	default:
+		if err := json.Unmarshal(out, &docs); err != nil {
+			return nil, errors.Wrapf(err, "result of Jsonnet in %q is neither an object nor an array", source)
+		}
+	}
+
+	objs := map[string]resource.Resource{}
+	for _, doc := range docs {
+		if !looksLikeJSONObject(doc) {
+			continue
+		}
+		docObjs, err := ParseMultidoc(doc, source)
+		if err != nil {
+			return nil, err
+		}
+		for id, obj := range docObjs {
+			if _, ok := objs[id]; ok {
+				return nil, errors.Errorf(`duplicate definition of '%s' in result of Jsonnet in %s`, id, source)
+			}
+			objs[id] = obj
+		}
+	}
+	return objs, nil
+}
+
+// looksLikeJSONObject says whether the bytes given are a JSON object
+// (as opposed to an array or scalar, or not JSON at all).
+func looksLikeJSONObject(in []byte) bool {
+	trimmed := bytes.TrimSpace(in)
+	return len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed)
+}
@@ -16,6 +16,21 @@ import (
 // based on the file(s) therein. Resources are named according to the
 // file content, rather than the file name of directory structure.
 func Load(base string, paths []string) (map[string]resource.Resource, error) {
+	return Loader{}.Load(base, paths)
+}
+
+// Loader loads resources from YAML (`.yaml` or `.yml`) and JSON
+// (`.json`) files and, if Jsonnet is true, from the result of
+// evaluating Jsonnet (`.jsonnet`) files. Resources of any of the
+// Workloads kinds are interpreted as workloads.
+type Loader struct {
+	Jsonnet   bool
+	Workloads WorkloadKinds
+}
+
+// Load is like the package function `Load`, but uses the loader's
+// settings.
+func (l Loader) Load(base string, paths []string) (map[string]resource.Resource, error) {
 	if _, err := os.Stat(base); os.IsNotExist(err) {
 		return nil, fmt.Errorf("git path %q not found", base)
 	}
@@ -34,29 +49,49 @@ func Load(base string, paths []string) (map[string]resource.Resource, error) {
 				return filepath.SkipDir
 			}
 
-			if charts.isPathInChart(path) {
+			if charts.isPathInChart(path) || info.IsDir() {
 				return nil
 			}
 
-			if !info.IsDir() && filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml" {
-				bytes, err := ioutil.ReadFile(path)
-				if err != nil {
-					return errors.Wrapf(err, "unable to read file at %q", path)
-				}
-				source, err := filepath.Rel(base, path)
-				if err != nil {
-					return errors.Wrapf(err, "path to scan %q is not under base %q", path, base)
-				}
-				docsInFile, err := ParseMultidoc(bytes, source)
-				if err != nil {
-					return err
+			ext := filepath.Ext(path)
+			if !(ext == ".yaml" || ext == ".yml" || ext == ".json" || (l.Jsonnet && ext == ".jsonnet")) {
+				return nil
+			}
+
+			bytes, err := ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "unable to read file at %q", path)
+			}
+			source, err := filepath.Rel(base, path)
+			if err != nil {
+				return errors.Wrapf(err, "path to scan %q is not under base %q", path, base)
+			}
+
+			var docsInFile map[string]resource.Resource
+			switch ext {
+			case ".json":
+				// Plenty of JSON files aren't manifests (e.g.,
+				// package.json); only objects can be resources.
+				if !looksLikeJSONObject(bytes) {
+					return nil
 				}
-				for id, obj := range docsInFile {
-					if alreadyDefined, ok := objs[id]; ok {
-						return fmt.Errorf(`duplicate definition of '%s' (in %s and %s)`, id, alreadyDefined.Source(), source)
-					}
-					objs[id] = obj
+				docsInFile, err = ParseMultidoc(bytes, source)
+			case ".jsonnet":
+				docsInFile, err = evalJsonnet(path, source)
+			default:
+				docsInFile, err = ParseMultidoc(bytes, source)
+			}
+			if err != nil {
+				return err
+			}
+			if docsInFile, err = l.interpret(docsInFile); err != nil {
+				return errors.Wrapf(err, "interpreting resources in %q", source)
+			}
+			for id, obj := range docsInFile {
+				if alreadyDefined, ok := objs[id]; ok {
+					return fmt.Errorf(`duplicate definition of '%s' (in %s and %s)`, id, alreadyDefined.Source(), source)
 				}
+				objs[id] = obj
 			}
 			return nil
 		})
@@ -68,6 +103,27 @@ func Load(base string, paths []string) (map[string]resource.Resource, error) {
 	return objs, nil
 }
 
+// ParseMultidoc is like the package function `ParseMultidoc`, but
+// uses the loader's settings.
+func (l Loader) ParseMultidoc(multidoc []byte, source string) (map[string]resource.Resource, error) {
+	objs, err := ParseMultidoc(multidoc, source)
+	if err != nil {
+		return nil, err
+	}
+	return l.interpret(objs)
+}
+
+func (l Loader) interpret(objs map[string]resource.Resource) (map[string]resource.Resource, error) {
+	for id, obj := range objs {
+		res, err := l.Workloads.interpret(obj)
+		if err != nil {
+			return nil, err
+		}
+		objs[id] = res
+	}
+	return objs, nil
+}
+
 type chartTracker map[string]bool
 
 func newChartTracker(root string) (chartTracker, error) {
@@ -2,6 +2,7 @@ package resource
 
 import (
 	"bytes"
+	"io/ioutil"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -209,6 +210,82 @@ func TestLoadSome(t *testing.T) {
 	}
 }
 
+func TestLoadJSON(t *testing.T) {
+	dir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	files := map[string]string{
+		"deploy.json": `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {"name": "helloworld", "namespace": "default"},
+  "spec": {"template": {"spec": {"containers": [{"name": "greeter", "image": "helloworld:1"}]}}}
+}`,
+		// Neither of these are manifests, so should be ignored
+		"package.json": `{"name": "helloworld", "version": "1.0.0"}`,
+		"list.json":    `[1, 2, 3]`,
+		// Not loaded, since Jsonnet isn't enabled
+		"app.jsonnet": `{kind: "Deployment", metadata: {name: "other"}}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objs, err := Load(dir, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("expected one resource, got %#v", objs)
+	}
+	obj, ok := objs["default:deployment/helloworld"]
+	if !ok {
+		t.Fatalf("expected deployment to be loaded, got %#v", objs)
+	}
+	if obj.Source() != "deploy.json" {
+		t.Errorf("expected source deploy.json, got %q", obj.Source())
+	}
+	containers := obj.(resource.Workload).Containers()
+	if len(containers) != 1 || containers[0].Image.String() != "helloworld:1" {
+		t.Errorf("unexpected containers %#v", containers)
+	}
+}
+
+func TestParseJsonnetOutput(t *testing.T) {
+	for name, out := range map[string]string{
+		"object": `{"apiVersion": "v1", "kind": "List", "items": [
+  {"kind": "Deployment", "metadata": {"name": "a", "namespace": "ns"}},
+  {"kind": "Service", "metadata": {"name": "a", "namespace": "ns"}}
+]}`,
+		"array": `[
+  {"kind": "Deployment", "metadata": {"name": "a", "namespace": "ns"}},
+  {"kind": "Service", "metadata": {"name": "a", "namespace": "ns"}}
+]`,
+		"files": `{
+  "deployment.json": {"kind": "Deployment", "metadata": {"name": "a", "namespace": "ns"}},
+  "service.json": {"kind": "Service", "metadata": {"name": "a", "namespace": "ns"}}
+}`,
+	} {
+		objs, err := parseJsonnetOutput([]byte(out), "app.jsonnet")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		for _, id := range []string{"ns:deployment/a", "ns:service/a"} {
+			if obj, ok := objs[id]; !ok {
+				t.Errorf("%s: expected %s in %#v", name, id, objs)
+			} else if obj.Source() != "app.jsonnet" {
+				t.Errorf("%s: expected source app.jsonnet, got %q", name, obj.Source())
+			}
+		}
+	}
+
+	if _, err := parseJsonnetOutput([]byte(`"not resources"`), "app.jsonnet"); err == nil {
+		t.Error("expected error for output that's neither an object nor an array")
+	}
+}
+
 func TestChartTracker(t *testing.T) {
 	dir, cleanup := testfiles.TempDir(t)
 	defer cleanup()
@@ -1,10 +1,18 @@
 package kubernetes
 
 import (
+	"fmt"
+	"strings"
+
 	apiapps "k8s.io/api/apps/v1"
+	apibatchv1 "k8s.io/api/batch/v1"
 	apibatch "k8s.io/api/batch/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 
 	"github.com/weaveworks/flux"
 	fhr_v1alpha2 "github.com/weaveworks/flux/apis/helm.integrations.flux.weave.works/v1alpha2"
@@ -40,6 +48,8 @@ func init() {
 	resourceKinds["deployment"] = &deploymentKind{}
 	resourceKinds["statefulset"] = &statefulSetKind{}
 	resourceKinds["fluxhelmrelease"] = &fluxHelmReleaseKind{}
+	resourceKinds["job"] = &jobKind{}
+	resourceKinds["pod"] = &podKind{}
 }
 
 type podController struct {
@@ -50,6 +60,9 @@ type podController struct {
 	status      string
 	rollout     cluster.RolloutStatus
 	podTemplate apiv1.PodTemplateSpec
+	// Whether the pod template can't be changed once the resource is
+	// created, as for Jobs and Pods
+	immutable bool
 }
 
 func (pc podController) toClusterController(resourceID flux.ResourceID) cluster.Controller {
@@ -83,12 +96,14 @@ func (pc podController) toClusterController(resourceID flux.ResourceID) cluster.
 	}
 
 	return cluster.Controller{
-		ID:         resourceID,
-		Status:     pc.status,
-		Rollout:    pc.rollout,
-		Antecedent: antecedent,
-		Labels:     pc.GetLabels(),
-		Containers: cluster.ContainersOrExcuse{Containers: clusterContainers, Excuse: excuse},
+		ID:          resourceID,
+		Status:      pc.status,
+		Rollout:     pc.rollout,
+		Antecedent:  antecedent,
+		Labels:      pc.GetLabels(),
+		Annotations: pc.GetAnnotations(),
+		Containers:  cluster.ContainersOrExcuse{Containers: clusterContainers, Excuse: excuse},
+		Immutable:   pc.immutable,
 	}
 }
 
@@ -330,6 +345,124 @@ func makeCronJobPodController(cronJob *apibatch.CronJob) podController {
 		k8sObject:   cronJob}
 }
 
+/////////////////////////////////////////////////////////////////////////////
+// batch/v1 Job
+
+// Only Jobs and Pods that aren't owned by another resource (e.g., those
+// created by CronJobs or Deployments) are listed, since the others are
+// accounted for by their owners.
+
+type jobKind struct{}
+
+func (jk *jobKind) getPodController(c *Cluster, namespace, name string) (podController, error) {
+	job, err := c.client.BatchV1().Jobs(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return podController{}, err
+	}
+
+	return makeJobPodController(job), nil
+}
+
+func (jk *jobKind) getPodControllers(c *Cluster, namespace string) ([]podController, error) {
+	jobs, err := c.client.BatchV1().Jobs(namespace).List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var podControllers []podController
+	for i := range jobs.Items {
+		if meta_v1.GetControllerOf(&jobs.Items[i]) != nil {
+			continue
+		}
+		podControllers = append(podControllers, makeJobPodController(&jobs.Items[i]))
+	}
+
+	return podControllers, nil
+}
+
+func makeJobPodController(job *apibatchv1.Job) podController {
+	status := StatusStarted
+	for _, c := range job.Status.Conditions {
+		if c.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case apibatchv1.JobComplete:
+			status = StatusReady
+		case apibatchv1.JobFailed:
+			status = StatusError
+		}
+	}
+
+	return podController{
+		apiVersion:  "batch/v1",
+		kind:        "Job",
+		name:        job.ObjectMeta.Name,
+		status:      status,
+		podTemplate: job.Spec.Template,
+		k8sObject:   job,
+		immutable:   true,
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////
+// v1 Pod
+
+type podKind struct{}
+
+func (pk *podKind) getPodController(c *Cluster, namespace, name string) (podController, error) {
+	pod, err := c.client.CoreV1().Pods(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return podController{}, err
+	}
+
+	return makePodPodController(pod), nil
+}
+
+func (pk *podKind) getPodControllers(c *Cluster, namespace string) ([]podController, error) {
+	pods, err := c.client.CoreV1().Pods(namespace).List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var podControllers []podController
+	for i := range pods.Items {
+		if meta_v1.GetControllerOf(&pods.Items[i]) != nil {
+			continue
+		}
+		podControllers = append(podControllers, makePodPodController(&pods.Items[i]))
+	}
+
+	return podControllers, nil
+}
+
+func makePodPodController(pod *apiv1.Pod) podController {
+	var status string
+	switch pod.Status.Phase {
+	case apiv1.PodRunning, apiv1.PodSucceeded:
+		status = StatusReady
+	case apiv1.PodPending:
+		status = StatusStarted
+	case apiv1.PodFailed:
+		status = StatusError
+	default:
+		status = StatusUnknown
+	}
+
+	return podController{
+		apiVersion: "v1",
+		kind:       "Pod",
+		name:       pod.ObjectMeta.Name,
+		status:     status,
+		podTemplate: apiv1.PodTemplateSpec{
+			ObjectMeta: pod.ObjectMeta,
+			Spec:       pod.Spec,
+		},
+		k8sObject: pod,
+		immutable: true,
+	}
+}
+
 /////////////////////////////////////////////////////////////////////////////
 // helm.integrations.flux.weave.works/v1alpha2 FluxHelmRelease
 
@@ -393,3 +526,104 @@ func createK8sFHRContainers(spec fhr_v1alpha2.FluxHelmReleaseSpec) []apiv1.Conta
 	})
 	return containers
 }
+
+/////////////////////////////////////////////////////////////////////////////
+// Custom workloads, of the kinds configured
+
+// customKind is a kind of resource with images at the paths given,
+// fetched with the dynamic client. The resource (i.e., the plural
+// name used in the API) isn't known until the API server is asked
+// about the kind, which can only be done once it's been defined.
+type customKind struct {
+	kresource.WorkloadKind
+}
+
+func (ck *customKind) resource(c *Cluster) (schema.GroupVersionResource, bool, error) {
+	gv, err := schema.ParseGroupVersion(ck.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	resources, err := c.client.coreClient.Discovery().ServerResourcesForGroupVersion(ck.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	for _, r := range resources.APIResources {
+		// Subresources, e.g., `widgets/status`, have the same kind
+		if r.Kind == ck.Kind && !strings.Contains(r.Name, "/") {
+			return gv.WithResource(r.Name), r.Namespaced, nil
+		}
+	}
+	return schema.GroupVersionResource{}, false, apierrors.NewNotFound(gv.WithResource(strings.ToLower(ck.Kind)).GroupResource(), "")
+}
+
+func (ck *customKind) client(c *Cluster, namespace string) (dynamic.ResourceInterface, error) {
+	if c.dynamicClient == nil {
+		return nil, fmt.Errorf("no client for resources of kind %s", ck.Kind)
+	}
+	gvr, namespaced, err := ck.resource(c)
+	if err != nil {
+		return nil, err
+	}
+	if !namespaced {
+		return nil, fmt.Errorf("resources of kind %s are not namespaced", ck.Kind)
+	}
+	return c.dynamicClient.Resource(gvr).Namespace(namespace), nil
+}
+
+func (ck *customKind) getPodController(c *Cluster, namespace, name string) (podController, error) {
+	client, err := ck.client(c, namespace)
+	if err != nil {
+		return podController{}, err
+	}
+	obj, err := client.Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return podController{}, err
+	}
+	return ck.makePodController(obj), nil
+}
+
+func (ck *customKind) getPodControllers(c *Cluster, namespace string) ([]podController, error) {
+	client, err := ck.client(c, namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var podControllers []podController
+	for i := range list.Items {
+		podControllers = append(podControllers, ck.makePodController(&list.Items[i]))
+	}
+	return podControllers, nil
+}
+
+func (ck *customKind) makePodController(obj *unstructured.Unstructured) podController {
+	var containers []apiv1.Container
+	ck.FindImages(obj.UnstructuredContent(), func(path string, ref image.Ref) {
+		containers = append(containers, apiv1.Container{
+			Name:  path,
+			Image: ref.String(),
+		})
+	})
+
+	return podController{
+		apiVersion: ck.APIVersion,
+		kind:       ck.Kind,
+		name:       obj.GetName(),
+		status:     StatusReady,
+		podTemplate: apiv1.PodTemplateSpec{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace:   obj.GetNamespace(),
+				Labels:      obj.GetLabels(),
+				Annotations: obj.GetAnnotations(),
+			},
+			Spec: apiv1.PodSpec{
+				Containers:       containers,
+				ImagePullSecrets: []apiv1.LocalObjectReference{},
+			},
+		},
+		k8sObject: obj,
+	}
+}
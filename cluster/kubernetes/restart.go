@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/weaveworks/flux"
+)
+
+// The annotation set on a workload's pod template, to restart it
+const restartedAtAnnotation = "flux.weave.works/restarted-at"
+
+// Restart rolls out new pods for the workload given, without changing
+// anything else about it, by setting an annotation on its pod template
+// to the time given (as `kubectl rollout restart` does). Since the
+// annotation isn't in the manifests flux applies, syncing leaves it
+// be.
+func (c *Cluster) Restart(id flux.ResourceID, at time.Time) error {
+	ns, kind, name := id.Components()
+	if !c.shard.Owns(ns) {
+		return fmt.Errorf("%s is in a namespace looked after by another shard", id)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: at.UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	apps := c.client.AppsV1()
+	switch strings.ToLower(kind) {
+	case "deployment":
+		_, err = apps.Deployments(ns).Patch(name, types.StrategicMergePatchType, patch)
+	case "daemonset":
+		_, err = apps.DaemonSets(ns).Patch(name, types.StrategicMergePatchType, patch)
+	case "statefulset":
+		_, err = apps.StatefulSets(ns).Patch(name, types.StrategicMergePatchType, patch)
+	default:
+		return fmt.Errorf("restarting %s not supported; only deployments, daemonsets and statefulsets can be restarted", kind)
+	}
+	return errors.Wrapf(err, "restarting %s", id)
+}
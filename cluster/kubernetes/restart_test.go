@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	apiapps "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestRestart(t *testing.T) {
+	clientset := fakekubernetes.NewSimpleClientset(&apiapps.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "helloworld", Namespace: "default"},
+	})
+	c := NewCluster(clientset, nil, nil, nil, log.NewNopLogger(), nil, Shard{})
+
+	at := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	if err := c.Restart(flux.MustParseResourceID("default:deployment/helloworld"), at); err != nil {
+		t.Fatal(err)
+	}
+	deployment, err := clientset.AppsV1().Deployments("default").Get("helloworld", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deployment.Spec.Template.Annotations[restartedAtAnnotation]; got != "2026-10-14T09:30:00Z" {
+		t.Errorf("expected the pod template to be annotated with the time of the restart, got %q", got)
+	}
+
+	if err := c.Restart(flux.MustParseResourceID("default:cronjob/nightly"), at); err == nil {
+		t.Error("expected an error restarting a cronjob")
+	}
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard describes the portion of the cluster a daemon instance is
+// responsible for, when the work of managing a large cluster is
+// split across several daemons. Namespaces are the unit of
+// assignment: each namespace is owned by exactly one shard, either
+// because it has been explicitly assigned to it, or by consistent
+// hashing of the namespace name over the number of shards.
+//
+// The zero value (and any Shard with a Count of one or less) owns
+// every namespace, i.e., there is no sharding.
+type Shard struct {
+	Index       int
+	Count       int
+	Assignments map[string]int
+}
+
+// ParseShardAssignments parses explicit namespace assignments of the
+// form `namespace=index`.
+func ParseShardAssignments(specs []string) (map[string]int, error) {
+	assignments := map[string]int{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid shard assignment %q, expected <namespace>=<index>", spec)
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid shard index in assignment %q", spec)
+		}
+		assignments[parts[0]] = index
+	}
+	return assignments, nil
+}
+
+// Validate checks that the shard's index and assignments are within
+// the number of shards.
+func (s Shard) Validate() error {
+	if s.Count <= 1 {
+		return nil
+	}
+	if s.Index < 0 || s.Index >= s.Count {
+		return fmt.Errorf("shard index %d out of range for %d shards", s.Index, s.Count)
+	}
+	for ns, index := range s.Assignments {
+		if index >= s.Count {
+			return fmt.Errorf("namespace %q assigned to shard %d, but there are only %d shards", ns, index, s.Count)
+		}
+	}
+	return nil
+}
+
+// Owns reports whether the namespace given belongs to this shard.
+func (s Shard) Owns(namespace string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	if index, ok := s.Assignments[namespace]; ok {
+		return index == s.Index
+	}
+	return jumpHash(namespace, s.Count) == s.Index
+}
+
+// jumpHash maps the key given onto one of n buckets using the "jump"
+// consistent hash of Lamping and Veach, so that changing the number
+// of shards moves as few namespaces as possible.
+func jumpHash(key string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	k := h.Sum64()
+
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
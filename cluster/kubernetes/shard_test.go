@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShardOwnsEverythingWhenUnsharded(t *testing.T) {
+	for _, s := range []Shard{{}, {Index: 0, Count: 1}} {
+		if !s.Owns("default") || !s.Owns("kube-system") {
+			t.Errorf("shard %+v should own every namespace", s)
+		}
+	}
+}
+
+func TestShardsPartitionNamespaces(t *testing.T) {
+	const count = 4
+	owners := map[int]int{}
+	for i := 0; i < 100; i++ {
+		ns := fmt.Sprintf("ns-%d", i)
+		var owned int
+		for index := 0; index < count; index++ {
+			if (Shard{Index: index, Count: count}).Owns(ns) {
+				owned++
+				owners[index]++
+			}
+		}
+		if owned != 1 {
+			t.Errorf("namespace %s owned by %d shards, expected exactly one", ns, owned)
+		}
+	}
+	if len(owners) != count {
+		t.Errorf("expected every shard to own some namespaces, got %v", owners)
+	}
+}
+
+func TestShardExplicitAssignment(t *testing.T) {
+	assignments, err := ParseShardAssignments([]string{"default=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := 0; index < 3; index++ {
+		s := Shard{Index: index, Count: 3, Assignments: assignments}
+		if s.Owns("default") != (index == 2) {
+			t.Errorf("shard %d: unexpected ownership of explicitly assigned namespace", index)
+		}
+	}
+	if err := (Shard{Index: 0, Count: 2, Assignments: assignments}).Validate(); err == nil {
+		t.Error("expected assignment to out-of-range shard to be invalid")
+	}
+}
+
+func TestParseShardAssignmentsInvalid(t *testing.T) {
+	for _, spec := range []string{"default", "=1", "default=x", "default=-1"} {
+		if _, err := ParseShardAssignments([]string{spec}); err == nil {
+			t.Errorf("expected error parsing %q", spec)
+		}
+	}
+}
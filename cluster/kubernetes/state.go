@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// The key, in the config map, under which state is kept
+const stateDataKey = "state"
+
+// ConfigMapStateStore keeps a little state (e.g., what the daemon
+// remembers between runs) in a config map, which is created when
+// first saved to.
+type ConfigMapStateStore struct {
+	API  v1.ConfigMapInterface
+	Name string
+}
+
+// Load gives the state last saved, or nil if there's none.
+func (s ConfigMapStateStore) Load() ([]byte, error) {
+	cm, err := s.API.Get(s.Name, meta_v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(cm.Data[stateDataKey]), nil
+}
+
+// Save replaces the state saved with that given.
+func (s ConfigMapStateStore) Save(state []byte) error {
+	cm, err := s.API.Get(s.Name, meta_v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = s.API.Create(&apiv1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{Name: s.Name},
+			Data:       map[string]string{stateDataKey: string(state)},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[stateDataKey] = string(state)
+	_, err = s.API.Update(cm)
+	return err
+}
@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapStateStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := ConfigMapStateStore{API: client.CoreV1().ConfigMaps("flux"), Name: "flux-state"}
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil {
+		t.Errorf("expected no state before any is saved, got %q", state)
+	}
+
+	for _, saved := range []string{`{"version":"1.4.0"}`, `{"version":"1.5.0"}`} {
+		if err := store.Save([]byte(saved)); err != nil {
+			t.Fatal(err)
+		}
+		state, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(state) != saved {
+			t.Errorf("expected %q, got %q", saved, state)
+		}
+	}
+}
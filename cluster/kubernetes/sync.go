@@ -165,6 +165,25 @@ func (c *Kubectl) doCommand(logger log.Logger, r io.Reader, args ...string) erro
 	return err
 }
 
+// getLive gets the resources named (as `<kind>/<name>`) in the
+// namespace given, as JSON, as a liveGetter.
+func (c *Kubectl) getLive(logger log.Logger, namespace string, names []string) ([]byte, error) {
+	args := append([]string{"get", "--namespace", namespace, "--ignore-not-found", "-o", "json"}, names...)
+	cmd := c.kubectlCommand(args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+
+	begin := time.Now()
+	err := cmd.Run()
+	if err != nil {
+		err = errors.Wrap(errors.New(strings.TrimSpace(stderr.String())), "running kubectl")
+	}
+	logger.Log("cmd", "kubectl get", "namespace", namespace, "count", len(names), "took", time.Since(begin), "err", err)
+	return stdout.Bytes(), err
+}
+
 func makeMultidoc(objs []*apiObject) *bytes.Buffer {
 	buf := &bytes.Buffer{}
 	for _, obj := range objs {
@@ -1,10 +1,15 @@
 package kubernetes
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/weaveworks/flux"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/resource"
 )
 
 // updatePodController takes a YAML document stream (one or more YAML
@@ -19,3 +24,68 @@ func updatePodController(in []byte, resource flux.ResourceID, container string,
 	}
 	return (KubeYAML{}).Image(in, namespace, kind, name, container, newImageID.String())
 }
+
+// containerImage gives the image of the container named, as it's
+// written in the manifest.
+func containerImage(workload resource.Workload, id flux.ResourceID, container string) (string, error) {
+	for _, c := range workload.Containers() {
+		if c.Name == container {
+			return c.Image.String(), nil
+		}
+	}
+	return "", fmt.Errorf("container %q not found in %s", container, id)
+}
+
+// checkImageUpdate makes sure that updating the image of a container
+// changed that, and only that, image -- manifests with several
+// documents, or which share values using YAML anchors and aliases,
+// can otherwise be changed in ways that aren't obvious.
+func checkImageUpdate(loader kresource.Loader, before, after []byte, id flux.ResourceID, container, image string) error {
+	beforeResources, err := loader.ParseMultidoc(before, "before")
+	if err != nil {
+		return err
+	}
+	afterResources, err := loader.ParseMultidoc(after, "after")
+	if err != nil {
+		return errors.Wrap(err, "manifest does not parse after updating image")
+	}
+	if len(afterResources) != len(beforeResources) {
+		return fmt.Errorf("updating image for %s changed the number of resources in the manifest from %d to %d", id, len(beforeResources), len(afterResources))
+	}
+
+	updated := false
+	for resID, beforeRes := range beforeResources {
+		afterRes, ok := afterResources[resID]
+		if !ok {
+			return fmt.Errorf("resource %s missing from manifest after updating image for %s", resID, id)
+		}
+		beforeWorkload, ok := beforeRes.(resource.Workload)
+		if !ok {
+			continue
+		}
+		afterWorkload, ok := afterRes.(resource.Workload)
+		if !ok {
+			return fmt.Errorf("resource %s is no longer a workload after updating image for %s", resID, id)
+		}
+		beforeContainers, afterContainers := beforeWorkload.Containers(), afterWorkload.Containers()
+		if len(beforeContainers) != len(afterContainers) {
+			return fmt.Errorf("resource %s has a different set of containers after updating image for %s", resID, id)
+		}
+		for i, c := range afterContainers {
+			if resID == id.String() && c.Name == container {
+				if c.Image.String() != image {
+					return fmt.Errorf("image for container %q in %s should be %q after updating, but is %q", container, id, image, c.Image.String())
+				}
+				updated = true
+				continue
+			}
+			if c.Image != beforeContainers[i].Image {
+				return fmt.Errorf("updating container %q in %s also changed the image of container %q in %s; the image may be shared using a YAML anchor and alias, in which case it must be updated by hand", container, id, c.Name, resID)
+			}
+		}
+	}
+	if !updated {
+		return fmt.Errorf("container %q not found in %s after updating image", container, id)
+	}
+	return nil
+}
@@ -20,6 +20,17 @@ func ErrResourceNotFound(name string) error {
 	return ManifestError{fmt.Errorf("manifest for resource %s not found under manifests path", name)}
 }
 
+// IsGenerated says whether a manifest file is a program that
+// generates resources (e.g., Jsonnet), rather than a definition of
+// resources that can be updated.
+func IsGenerated(source string) bool {
+	return filepath.Ext(source) == ".jsonnet"
+}
+
+func ErrManifestGenerated(name, source string) error {
+	return ManifestError{fmt.Errorf("manifest for resource %s is generated by %s, so cannot be updated", name, source)}
+}
+
 // Manifests represents how a set of files are used as definitions of
 // resources, e.g., in Kubernetes, YAML files describing Kubernetes
 // resources.
@@ -35,6 +46,27 @@ type Manifests interface {
 	ParseManifests([]byte) (map[string]resource.Resource, error)
 	// UpdatePolicies modifies a manifest to apply the policy update specified
 	UpdatePolicies([]byte, flux.ResourceID, policy.Update) ([]byte, error)
+	// UpdateChartVersion changes the version of the chart (from a
+	// chart repository) a manifest refers to
+	UpdateChartVersion(def []byte, resourceID flux.ResourceID, version string) ([]byte, error)
+	// BootstrapNamespace gives the manifests to create the namespace
+	// given, along with what's needed to run workloads in it
+	BootstrapNamespace(namespace string, bootstrap NamespaceBootstrap) ([]byte, error)
+}
+
+// NamespaceBootstrap says what is generated, besides the namespace
+// itself, for namespaces that resources in git are in but which are
+// not themselves defined in git.
+type NamespaceBootstrap struct {
+	// If not empty, the cluster role to bind to the subjects in each
+	// namespace
+	ClusterRole string
+	// The service accounts the cluster role is bound to, each as
+	// <namespace>/<name>
+	Subjects []string
+	// The names of the image pull secrets the default service
+	// account in each namespace refers to
+	PullSecrets []string
 }
 
 // UpdateManifest looks for the manifest for the identified resource,
@@ -50,6 +82,9 @@ func UpdateManifest(m Manifests, root string, paths []string, id flux.ResourceID
 	if !ok {
 		return ErrResourceNotFound(id.String())
 	}
+	if IsGenerated(resource.Source()) {
+		return ErrManifestGenerated(id.String(), resource.Source())
+	}
 
 	path := filepath.Join(root, resource.Source())
 	def, err := ioutil.ReadFile(path)
@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"time"
+
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/policy"
@@ -10,17 +12,23 @@ import (
 
 // Doubles as a cluster.Cluster and cluster.Manifests implementation
 type Mock struct {
-	AllServicesFunc    func(maybeNamespace string) ([]Controller, error)
-	SomeServicesFunc   func([]flux.ResourceID) ([]Controller, error)
-	PingFunc           func() error
-	ExportFunc         func() ([]byte, error)
-	SyncFunc           func(SyncDef) error
-	PublicSSHKeyFunc   func(regenerate bool) (ssh.PublicKey, error)
-	UpdateImageFunc    func(def []byte, id flux.ResourceID, container string, newImageID image.Ref) ([]byte, error)
-	LoadManifestsFunc  func(base string, paths []string) (map[string]resource.Resource, error)
-	ParseManifestsFunc func([]byte) (map[string]resource.Resource, error)
-	UpdateManifestFunc func(path, resourceID string, f func(def []byte) ([]byte, error)) error
-	UpdatePoliciesFunc func([]byte, flux.ResourceID, policy.Update) ([]byte, error)
+	AllServicesFunc     func(maybeNamespace string) ([]Controller, error)
+	SomeServicesFunc    func([]flux.ResourceID) ([]Controller, error)
+	PingFunc            func() error
+	ExportFunc          func() ([]byte, error)
+	ExportResourcesFunc func(namespaces, kinds []string) ([]byte, error)
+	SyncFunc            func(SyncDef) error
+	PublicSSHKeyFunc    func(regenerate bool) (ssh.PublicKey, error)
+	UpdateImageFunc     func(def []byte, id flux.ResourceID, container string, newImageID image.Ref) ([]byte, error)
+	LoadManifestsFunc   func(base string, paths []string) (map[string]resource.Resource, error)
+	ParseManifestsFunc  func([]byte) (map[string]resource.Resource, error)
+	UpdateManifestFunc  func(path, resourceID string, f func(def []byte) ([]byte, error)) error
+	UpdatePoliciesFunc  func([]byte, flux.ResourceID, policy.Update) ([]byte, error)
+	UpdateChartFunc     func(def []byte, id flux.ResourceID, version string) ([]byte, error)
+	BootstrapFunc       func(namespace string, bootstrap NamespaceBootstrap) ([]byte, error)
+	CheckCapacityFunc   func([]flux.ResourceID) (CapacityReport, error)
+	SyncConflictsFunc   func([]flux.ResourceID) ([]SyncConflict, error)
+	RestartFunc         func(flux.ResourceID, time.Time) error
 }
 
 func (m *Mock) AllControllers(maybeNamespace string) ([]Controller, error) {
@@ -39,6 +47,10 @@ func (m *Mock) Export() ([]byte, error) {
 	return m.ExportFunc()
 }
 
+func (m *Mock) ExportResources(namespaces, kinds []string) ([]byte, error) {
+	return m.ExportResourcesFunc(namespaces, kinds)
+}
+
 func (m *Mock) Sync(c SyncDef) error {
 	return m.SyncFunc(c)
 }
@@ -47,6 +59,18 @@ func (m *Mock) PublicSSHKey(regenerate bool) (ssh.PublicKey, error) {
 	return m.PublicSSHKeyFunc(regenerate)
 }
 
+func (m *Mock) CheckCapacity(ids []flux.ResourceID) (CapacityReport, error) {
+	return m.CheckCapacityFunc(ids)
+}
+
+func (m *Mock) SyncConflicts(ids []flux.ResourceID) ([]SyncConflict, error) {
+	return m.SyncConflictsFunc(ids)
+}
+
+func (m *Mock) Restart(id flux.ResourceID, at time.Time) error {
+	return m.RestartFunc(id, at)
+}
+
 func (m *Mock) UpdateImage(def []byte, id flux.ResourceID, container string, newImageID image.Ref) ([]byte, error) {
 	return m.UpdateImageFunc(def, id, container, newImageID)
 }
@@ -66,3 +90,11 @@ func (m *Mock) UpdateManifest(path string, resourceID string, f func(def []byte)
 func (m *Mock) UpdatePolicies(def []byte, id flux.ResourceID, p policy.Update) ([]byte, error) {
 	return m.UpdatePoliciesFunc(def, id, p)
 }
+
+func (m *Mock) UpdateChartVersion(def []byte, id flux.ResourceID, version string) ([]byte, error) {
+	return m.UpdateChartFunc(def, id, version)
+}
+
+func (m *Mock) BootstrapNamespace(namespace string, bootstrap NamespaceBootstrap) ([]byte, error) {
+	return m.BootstrapFunc(namespace, bootstrap)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v14"
+)
+
+type checkWorkloadOpts struct {
+	*rootOpts
+	namespace  string
+	controller string
+}
+
+func newCheckWorkload(parent *rootOpts) *checkWorkloadOpts {
+	return &checkWorkloadOpts{rootOpts: parent}
+}
+
+func (opts *checkWorkloadOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-workload",
+		Short: "Explain whether a controller can be automated, and if not, why not.",
+		Example: makeExample(
+			"fluxctl check-workload --controller=default:deployment/helloworld",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
+	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Controller to check")
+	return cmd
+}
+
+func (opts *checkWorkloadOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	if len(opts.controller) == 0 {
+		return newUsageError("-c, --controller is required")
+	}
+	id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
+	if err != nil {
+		return err
+	}
+
+	check, err := opts.API.CheckWorkload(context.Background(), v14.CheckWorkloadOptions{Workload: id})
+	if err != nil {
+		return err
+	}
+	printWorkloadCheck(os.Stdout, check)
+	return nil
+}
+
+func printWorkloadCheck(out io.Writer, check v14.WorkloadCheck) {
+	if check.Eligible {
+		fmt.Fprintf(out, "%s can be automated\n", check.ID)
+	} else {
+		fmt.Fprintf(out, "%s cannot be automated:\n", check.ID)
+		for _, p := range check.Problems {
+			fmt.Fprintf(out, "  - %s\n", p)
+		}
+	}
+	if len(check.Notes) > 0 {
+		fmt.Fprintln(out, "Notes:")
+		for _, n := range check.Notes {
+			fmt.Fprintf(out, "  - %s\n", n)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v16"
+)
+
+type deliveryReportOpts struct {
+	*rootOpts
+	namespace string
+	period    time.Duration
+	format    string
+}
+
+func newDeliveryReport(parent *rootOpts) *deliveryReportOpts {
+	return &deliveryReportOpts{rootOpts: parent}
+}
+
+func (opts *deliveryReportOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delivery-report",
+		Short: "Report how often each controller is deployed, how long changes take to be deployed, and how often it's rolled back.",
+		Example: makeExample(
+			"fluxctl delivery-report",
+			"fluxctl delivery-report --namespace=default --period=168h --format=csv > delivery.csv",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only report on controllers in this namespace")
+	cmd.Flags().DurationVar(&opts.period, "period", 30*24*time.Hour, "Report on this long, back from now")
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Output format; one of table, csv or json")
+	return cmd
+}
+
+func (opts *deliveryReportOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	switch opts.format {
+	case "table", "csv", "json":
+	default:
+		return newUsageError("--format must be one of table, csv or json")
+	}
+
+	report, err := opts.API.DeliveryReport(context.Background(), v16.DeliveryReportOptions{
+		Namespace: opts.namespace,
+		Period:    opts.period,
+	})
+	if err != nil {
+		return err
+	}
+	return writeDeliveryReport(os.Stdout, opts.format, report)
+}
+
+func writeDeliveryReport(out io.Writer, format string, report []v16.WorkloadDelivery) error {
+	switch format {
+	case "json":
+		if report == nil {
+			report = []v16.WorkloadDelivery{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write([]string{"controller", "deploys", "deploys_per_day", "median_lead_time_seconds", "rollbacks", "change_failure_rate", "last_deployed"})
+		for _, d := range report {
+			w.Write([]string{
+				d.Workload.String(),
+				strconv.Itoa(d.Deploys),
+				strconv.FormatFloat(d.DeployFrequency, 'f', 2, 64),
+				strconv.FormatFloat(d.MedianLeadTimeSeconds, 'f', 0, 64),
+				strconv.Itoa(d.Rollbacks),
+				strconv.FormatFloat(d.ChangeFailureRate, 'f', 3, 64),
+				formatReportTime(d.LastDeployed),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+		fmt.Fprintln(w, "CONTROLLER\tDEPLOYS\tPER DAY\tLEAD TIME\tROLLBACKS\tFAILURE RATE")
+		for _, d := range report {
+			leadTime := "-"
+			if d.MedianLeadTimeSeconds > 0 {
+				leadTime = (time.Duration(d.MedianLeadTimeSeconds) * time.Second).String()
+			}
+			fmt.Fprintf(w, "%s\t%d\t%.2f\t%s\t%d\t%.0f%%\n", d.Workload, d.Deploys, d.DeployFrequency, leadTime, d.Rollbacks, 100*d.ChangeFailureRate)
+		}
+		return w.Flush()
+	}
+}
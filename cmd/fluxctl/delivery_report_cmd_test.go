@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v16"
+)
+
+func deliveryReportFixture() []v16.WorkloadDelivery {
+	return []v16.WorkloadDelivery{
+		{
+			Workload:              flux.MustParseResourceID("default:deployment/hello"),
+			Deploys:               4,
+			DeployFrequency:       0.5,
+			MedianLeadTimeSeconds: 5400,
+			Rollbacks:             1,
+			ChangeFailureRate:     0.25,
+		},
+	}
+}
+
+func TestDeliveryReportCSV(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeDeliveryReport(out, "csv", deliveryReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"default:deployment/hello", "4", "0.50", "5400", "1", "0.250", ""}
+	if len(rows) != 2 || !reflect.DeepEqual(rows[1], expected) {
+		t.Errorf("expected header and %q, got %q", expected, rows)
+	}
+}
+
+func TestDeliveryReportTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeDeliveryReport(out, "table", deliveryReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two lines, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "1h30m0s") || !strings.Contains(lines[1], "25%") {
+		t.Errorf("expected lead time and failure rate to be formatted, got %q", lines[1])
+	}
+}
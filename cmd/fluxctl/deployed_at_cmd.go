@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v19"
+)
+
+type deployedAtOpts struct {
+	*rootOpts
+	at        string
+	namespace string
+}
+
+func newDeployedAt(parent *rootOpts) *deployedAtOpts {
+	return &deployedAtOpts{rootOpts: parent}
+}
+
+func (opts *deployedAtOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deployed-at",
+		Short: "Show which revision was synced, and which images controllers were given, at a point in time.",
+		Long: `
+Show which revision of the git repo was synced to the cluster at the
+time given, and the image each controller's containers were given in
+that revision. The daemon remembers the revisions it has synced since
+it started; for times before then, the last commit before the time is
+shown instead, marked as estimated.
+`,
+		Example: makeExample(
+			"fluxctl deployed-at --at=2026-10-14T03:12:00Z",
+			"fluxctl deployed-at --at=2h --namespace=default",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVar(&opts.at, "at", "", "The time to show, in RFC3339 format (e.g., 2026-10-14T03:12:00Z), or as a duration ago (e.g., 90m); defaults to now")
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only show controllers in this namespace")
+	return cmd
+}
+
+func (opts *deployedAtOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	at, err := parseAt(opts.at, time.Now())
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	deployed, err := opts.API.DeployedAt(context.Background(), v19.DeployedAtOptions{
+		At:        at,
+		Namespace: opts.namespace,
+	})
+	if err != nil {
+		return err
+	}
+	writeDeployedAt(os.Stdout, deployed)
+	return nil
+}
+
+// parseAt parses a time given either in RFC3339 format, or as a
+// duration before now. An empty string is the zero time, which the
+// daemon takes to mean now.
+func parseAt(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse %q as an RFC3339 time (e.g., 2026-10-14T03:12:00Z) or a duration (e.g., 90m)", s)
+}
+
+func writeDeployedAt(out io.Writer, deployed v19.DeployedState) {
+	rev := deployed.Revision
+	if len(rev) > 7 {
+		rev = rev[:7]
+	}
+	if deployed.Estimated {
+		fmt.Fprintf(out, "At %s: no record of a sync; the last commit was %s, at %s\n", deployed.At.Format(time.RFC3339), rev, deployed.SyncedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(out, "At %s: revision %s, synced at %s\n", deployed.At.Format(time.RFC3339), rev, deployed.SyncedAt.Format(time.RFC3339))
+	}
+	if len(deployed.Workloads) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTROLLER\tCONTAINER\tIMAGE")
+	for _, workload := range deployed.Workloads {
+		for _, c := range workload.Containers {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", workload.ID, c.Name, c.Image)
+		}
+	}
+	w.Flush()
+}
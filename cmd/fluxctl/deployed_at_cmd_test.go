@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v19"
+)
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2026, 10, 14, 5, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		in       string
+		expected time.Time
+	}{
+		{"", time.Time{}},
+		{"2026-10-14T03:12:00Z", time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC)},
+		{"90m", time.Date(2026, 10, 14, 3, 30, 0, 0, time.UTC)},
+	} {
+		at, err := parseAt(c.in, now)
+		if err != nil {
+			t.Errorf("%q: %s", c.in, err)
+			continue
+		}
+		if !at.Equal(c.expected) {
+			t.Errorf("%q: expected %s, got %s", c.in, c.expected, at)
+		}
+	}
+	if _, err := parseAt("03:12", now); err == nil {
+		t.Error("expected error for time without a date")
+	}
+}
+
+func TestWriteDeployedAt(t *testing.T) {
+	deployed := v19.DeployedState{
+		At:       time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC),
+		Revision: "3a5b4c1d9e0f2a7b68c4e1d3f5a7b9c0d2e4f6a8",
+		SyncedAt: time.Date(2026, 10, 14, 3, 5, 10, 0, time.UTC),
+		Workloads: []v19.DeployedWorkload{
+			{
+				ID:         flux.MustParseResourceID("default:deployment/hello"),
+				Containers: []v19.DeployedContainer{{Name: "greeter", Image: "hello:2"}},
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	writeDeployedAt(out, deployed)
+	for _, expected := range []string{"revision 3a5b4c1, synced at 2026-10-14T03:05:10Z", "default:deployment/hello", "hello:2"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out)
+		}
+	}
+
+	deployed.Estimated = true
+	out.Reset()
+	writeDeployedAt(out, deployed)
+	if !strings.Contains(out.String(), "no record of a sync") {
+		t.Errorf("expected to be told the revision is estimated, got:\n%s", out)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v21"
+)
+
+type diffOpts struct {
+	*rootOpts
+	from      string
+	to        string
+	namespace string
+	manifests bool
+	format    string
+}
+
+func newDiff(parent *rootOpts) *diffOpts {
+	return &diffOpts{rootOpts: parent}
+}
+
+func (opts *diffOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show how the resources in the git repo differ between two revisions.",
+		Long: `
+Show which resources defined in the git repo were added, removed or
+changed between two revisions, and which images the controllers'
+containers were changed from and to, as flux reads the manifests.
+With --format=json, the result is suitable for e.g., writing release
+notes.
+`,
+		Example: makeExample(
+			"fluxctl diff --from=3a5b4c1 --to=9c0d2e4",
+			"fluxctl diff --from=flux-sync --namespace=default --manifests",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVar(&opts.from, "from", "", "The revision to compare from; a commit, branch or tag")
+	cmd.Flags().StringVar(&opts.to, "to", "", "The revision to compare to; defaults to the head of the branch flux syncs")
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only show resources in this namespace")
+	cmd.Flags().BoolVar(&opts.manifests, "manifests", false, "Also show a diff of each manifest changed")
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Output format; one of table or json")
+	return cmd
+}
+
+func (opts *diffOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	if opts.from == "" {
+		return newUsageError("please supply --from=<revision>")
+	}
+	switch opts.format {
+	case "table", "json":
+	default:
+		return newUsageError("--format must be one of table or json")
+	}
+
+	diff, err := opts.API.DiffRevisions(context.Background(), v21.DiffRevisionsOptions{
+		From:      opts.from,
+		To:        opts.to,
+		Namespace: opts.namespace,
+		Manifests: opts.manifests,
+	})
+	if err != nil {
+		return err
+	}
+	return writeDiff(os.Stdout, opts.format, diff)
+}
+
+func writeDiff(out io.Writer, format string, diff v21.RevisionDiff) error {
+	if format == "json" {
+		if diff.Changes == nil {
+			diff.Changes = []v21.ResourceChange{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+
+	fmt.Fprintf(out, "Changes from %s to %s\n", shortRev(diff.From), shortRev(diff.To))
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(out, "No resources changed")
+		return nil
+	}
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tSTATUS\tCONTAINER\tIMAGE")
+	for _, change := range diff.Changes {
+		if len(change.Images) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t\t\n", change.ID, change.Status)
+			continue
+		}
+		for i, image := range change.Images {
+			id, status := change.ID.String(), string(change.Status)
+			if i > 0 {
+				id, status = "", ""
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, status, image.Container, imageChangeString(image))
+		}
+	}
+	w.Flush()
+
+	for _, change := range diff.Changes {
+		if change.Diff != "" {
+			fmt.Fprintln(out)
+			fmt.Fprint(out, change.Diff)
+		}
+	}
+	return nil
+}
+
+func imageChangeString(image v21.ImageChange) string {
+	switch {
+	case image.From == "":
+		return "(none) -> " + image.To
+	case image.To == "":
+		return image.From + " -> (none)"
+	}
+	return image.From + " -> " + image.To
+}
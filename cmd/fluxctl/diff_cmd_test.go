@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v21"
+)
+
+func TestWriteDiff(t *testing.T) {
+	diff := v21.RevisionDiff{
+		From: "3a5b4c1d9e0f2a7b68c4e1d3f5a7b9c0d2e4f6a8",
+		To:   "9c0d2e4f6a83a5b4c1d9e0f2a7b68c4e1d3f5a7b",
+		Changes: []v21.ResourceChange{
+			{
+				ID:     flux.MustParseResourceID("default:deployment/hello"),
+				Status: v21.ResourceChanged,
+				Images: []v21.ImageChange{{Container: "greeter", From: "hello:1", To: "hello:2"}},
+				Diff:   "--- 3a5b4c1:hello.yaml\n+++ 9c0d2e4:hello.yaml\n",
+			},
+			{
+				ID:     flux.MustParseResourceID("default:service/hello"),
+				Status: v21.ResourceAdded,
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := writeDiff(out, "table", diff); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Changes from 3a5b4c1 to 9c0d2e4",
+		"default:deployment/hello",
+		"hello:1 -> hello:2",
+		"default:service/hello",
+		"added",
+		"+++ 9c0d2e4:hello.yaml",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out)
+		}
+	}
+
+	out.Reset()
+	if err := writeDiff(out, "json", diff); err != nil {
+		t.Fatal(err)
+	}
+	var decoded v21.RevisionDiff
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Changes) != 2 || decoded.Changes[0].Images[0].To != "hello:2" {
+		t.Errorf("unexpected JSON output:\n%s", out)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+const (
+	eventsFormatPlain  = "plain"
+	eventsFormatPretty = "pretty"
+)
+
+type eventsOpts struct {
+	*rootOpts
+	follow   bool
+	format   string
+	limit    int
+	interval time.Duration
+	noColor  bool
+}
+
+func newEvents(parent *rootOpts) *eventsOpts {
+	return &eventsOpts{rootOpts: parent}
+}
+
+func (opts *eventsOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show recent events (syncs, releases, and so on) from the daemon.",
+		Example: makeExample(
+			"fluxctl events",
+			"fluxctl events --follow --format=pretty",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "Keep showing events as they happen")
+	cmd.Flags().StringVar(&opts.format, "format", eventsFormatPlain, "How to show events; one of 'plain' or 'pretty' (one colourised line per event)")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "l", 20, "Number of past events to show first (0 for all those the daemon has)")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 2*time.Second, "How often to check for new events, when following")
+	cmd.Flags().BoolVar(&opts.noColor, "no-color", false, "Don't colourise 'pretty' output")
+	cmd.AddCommand(newEventAnnotate(opts.rootOpts).Command())
+	return cmd
+}
+
+func (opts *eventsOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	var format func(event.Event) string
+	switch opts.format {
+	case eventsFormatPlain:
+		format = plainEvent
+	case eventsFormatPretty:
+		color := !opts.noColor && os.Getenv("NO_COLOR") == ""
+		format = func(e event.Event) string { return prettyEvent(e, color) }
+	default:
+		return newUsageError(fmt.Sprintf("unknown format %q; expected one of %q or %q", opts.format, eventsFormatPlain, eventsFormatPretty))
+	}
+
+	ctx := context.Background()
+	last, err := printEvents(ctx, opts.API.ListEvents, v13.ListEventsOptions{Limit: opts.limit}, format, os.Stdout)
+	if err != nil || !opts.follow {
+		return err
+	}
+	for {
+		time.Sleep(opts.interval)
+		last, err = printEvents(ctx, opts.API.ListEvents, v13.ListEventsOptions{After: last}, format, os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// printEvents fetches and prints events, returning the ID of the
+// last event printed (or the ID asked to start after, if there were
+// none).
+func printEvents(ctx context.Context, list func(context.Context, v13.ListEventsOptions) ([]event.Event, error), opts v13.ListEventsOptions, format func(event.Event) string, out io.Writer) (event.EventID, error) {
+	events, err := list(ctx, opts)
+	if err != nil {
+		return opts.After, err
+	}
+	last := opts.After
+	for _, e := range events {
+		fmt.Fprintln(out, format(e))
+		if e.ID > last {
+			last = e.ID
+		}
+	}
+	return last, nil
+}
+
+func plainEvent(e event.Event) string {
+	line := fmt.Sprintf("%d\t%s\t%s\t%s", e.ID, e.StartedAt.Local().Format(time.RFC3339), e.Type, e.String())
+	for _, a := range e.Annotations {
+		line += "\n\t" + annotationString(a)
+	}
+	return line
+}
+
+// annotationString describes an annotation, e.g.,
+//
+//     acknowledged by jane at 2026-10-14T03:20:00Z: expected, maintenance
+func annotationString(a event.Annotation) string {
+	verb := "comment"
+	if a.Acknowledged {
+		verb = "acknowledged"
+	}
+	if a.User != "" {
+		verb += " by " + a.User
+	}
+	s := fmt.Sprintf("%s at %s", verb, a.Time.Local().Format(time.RFC3339))
+	if a.Comment != "" {
+		s += ": " + a.Comment
+	}
+	return s
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+func eventColor(e event.Event) string {
+	switch e.LogLevel {
+	case event.LogLevelError:
+		return ansiRed
+	case event.LogLevelWarn:
+		return ansiYellow
+	}
+	switch e.Type {
+	case event.EventRelease, event.EventAutoRelease:
+		return ansiGreen
+	case event.EventRollback, event.EventRestart:
+		return ansiYellow
+	case event.EventObservedRelease:
+		return ansiDim
+	case event.EventSync:
+		return ansiBlue
+	case event.EventCommit:
+		return ansiCyan
+	}
+	return ""
+}
+
+// prettyEvent formats an event as a single line, e.g.,
+//
+//     15:04:05 #12 autorelease  a1b2c3d default:deployment/foo app: quay.io/foo/app:1.0 -> 1.1
+func prettyEvent(e event.Event, color bool) string {
+	paint := func(code, s string) string {
+		if !color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	rev := eventRevision(e)
+	if rev == "" {
+		rev = "-"
+	}
+	summary := e.String()
+	if diffs := imageDiffs(e); len(diffs) > 0 {
+		summary = strings.Join(diffs, ", ")
+	}
+	if errMsg := eventError(e); errMsg != "" {
+		summary = summary + " " + paint(ansiRed, "error: "+errMsg)
+	}
+	for _, a := range e.Annotations {
+		summary = summary + " " + paint(ansiDim, "["+annotationString(a)+"]")
+	}
+	return fmt.Sprintf("%s %s %s %s %s",
+		paint(ansiDim, e.StartedAt.Local().Format("15:04:05")),
+		paint(ansiDim, fmt.Sprintf("#%d", e.ID)),
+		paint(eventColor(e), fmt.Sprintf("%-12s", e.Type)),
+		paint(ansiDim, fmt.Sprintf("%-7s", rev)),
+		summary)
+}
+
+func shortRev(rev string) string {
+	if len(rev) > 7 {
+		return rev[:7]
+	}
+	return rev
+}
+
+func eventRevision(e event.Event) string {
+	switch m := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		return shortRev(m.Revision)
+	case *event.AutoReleaseEventMetadata:
+		return shortRev(m.Revision)
+	case *event.RollbackEventMetadata:
+		return shortRev(m.Revision)
+	case *event.CommitEventMetadata:
+		return m.ShortRevision()
+	case *event.SyncEventMetadata:
+		if len(m.Commits) > 0 {
+			return shortRev(m.Commits[0].Revision)
+		}
+	}
+	return ""
+}
+
+func eventError(e event.Event) string {
+	switch m := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		return m.Error
+	case *event.AutoReleaseEventMetadata:
+		return m.Error
+	case *event.RollbackEventMetadata:
+		return m.Error
+	case *event.RestartEventMetadata:
+		return m.Result.Error()
+	}
+	return ""
+}
+
+// imageDiffs describes the image changes made by a release, one per
+// container changed.
+func imageDiffs(e event.Event) []string {
+	var result update.Result
+	switch m := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		result = m.Result
+	case *event.AutoReleaseEventMetadata:
+		result = m.Result
+	case *event.RollbackEventMetadata:
+		result = m.Result
+	case *event.ObservedReleaseEventMetadata:
+		result = m.Result
+	default:
+		return nil
+	}
+	var diffs []string
+	for id, res := range result {
+		for _, c := range res.PerContainer {
+			target := c.Target.String()
+			if c.Current.Name == c.Target.Name {
+				target = c.Target.Tag
+			}
+			diffs = append(diffs, fmt.Sprintf("%s %s: %s -> %s", id, c.Container, c.Current, target))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+type eventAnnotateOpts struct {
+	*rootOpts
+	comment     string
+	acknowledge bool
+	user        string
+}
+
+func newEventAnnotate(parent *rootOpts) *eventAnnotateOpts {
+	return &eventAnnotateOpts{rootOpts: parent}
+}
+
+func (opts *eventAnnotateOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "annotate <event ID>",
+		Short: "Attach a comment, or an acknowledgement, to an event.",
+		Example: makeExample(
+			`fluxctl events annotate 42 --ack --comment="expected, maintenance"`,
+			`fluxctl events annotate 42 --comment="rolled back by hand"`,
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.comment, "comment", "c", "", "Comment to attach to the event")
+	cmd.Flags().BoolVar(&opts.acknowledge, "ack", false, "Acknowledge the event, e.g., to say a failure has been seen to")
+	cmd.Flags().StringVar(&opts.user, "user", getCommitAuthor(), "Override the user recorded as annotating the event")
+	return cmd
+}
+
+func (opts *eventAnnotateOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return newUsageError("expected the ID of the event to annotate")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return newUsageError(fmt.Sprintf("expected an event ID, as shown by fluxctl events, got %q", args[0]))
+	}
+	if opts.comment == "" && !opts.acknowledge {
+		return newUsageError("supply --comment, --ack, or both")
+	}
+
+	annotated, err := opts.API.AnnotateEvent(context.Background(), v20.AnnotateEventOptions{
+		ID:          event.EventID(id),
+		Comment:     opts.comment,
+		Acknowledge: opts.acknowledge,
+		User:        opts.user,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), plainEvent(annotated))
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestPrettyEvent(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/foo")
+	e := event.Event{
+		ID:         3,
+		ServiceIDs: []flux.ResourceID{id},
+		Type:       event.EventAutoRelease,
+		StartedAt:  time.Now(),
+		LogLevel:   event.LogLevelInfo,
+		Metadata: &event.AutoReleaseEventMetadata{
+			ReleaseEventCommon: event.ReleaseEventCommon{
+				Revision: "a1b2c3d4e5f6",
+				Result: update.Result{
+					id: update.ControllerResult{
+						Status: update.ReleaseStatusSuccess,
+						PerContainer: []update.ContainerUpdate{{
+							Container: "app",
+							Current:   mustParseRef(t, "quay.io/foo/app:1.0"),
+							Target:    mustParseRef(t, "quay.io/foo/app:1.1"),
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	plain := prettyEvent(e, false)
+	for _, expected := range []string{"autorelease", "a1b2c3d ", "default:deployment/foo app: quay.io/foo/app:1.0 -> 1.1"} {
+		if !strings.Contains(plain, expected) {
+			t.Errorf("expected %q in %q", expected, plain)
+		}
+	}
+	if strings.Contains(plain, "\x1b[") {
+		t.Errorf("expected no colour codes, got %q", plain)
+	}
+	if colored := prettyEvent(e, true); !strings.Contains(colored, ansiGreen+"autorelease") {
+		t.Errorf("expected release to be coloured green, got %q", colored)
+	}
+}
+
+func TestPrintEventsFollowsOn(t *testing.T) {
+	var asked []v13.ListEventsOptions
+	list := func(_ context.Context, opts v13.ListEventsOptions) ([]event.Event, error) {
+		asked = append(asked, opts)
+		if opts.After > 0 {
+			return nil, nil
+		}
+		return []event.Event{{ID: 4, Type: event.EventLock}, {ID: 5, Type: event.EventUnlock}}, nil
+	}
+	out := &bytes.Buffer{}
+	last, err := printEvents(context.Background(), list, v13.ListEventsOptions{Limit: 10}, plainEvent, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 5 {
+		t.Errorf("expected last event ID 5, got %d", last)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Errorf("expected two lines of output, got %d", lines)
+	}
+	last, err = printEvents(context.Background(), list, v13.ListEventsOptions{After: last}, plainEvent, out)
+	if err != nil || last != 5 {
+		t.Errorf("expected no new events to leave last ID at 5, got %d (err %v)", last, err)
+	}
+}
+
+func mustParseRef(t *testing.T, s string) image.Ref {
+	ref, err := image.ParseRef(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestPlainEventAnnotations(t *testing.T) {
+	e := event.Event{
+		ID:        7,
+		Type:      event.EventSync,
+		StartedAt: time.Now(),
+		Message:   "Sync failed",
+		Annotations: []event.Annotation{
+			{Time: time.Now(), User: "jane", Comment: "expected, maintenance", Acknowledged: true},
+			{Time: time.Now(), Comment: "back to normal"},
+		},
+	}
+	plain := plainEvent(e)
+	if !strings.HasPrefix(plain, "7\t") {
+		t.Errorf("expected the event ID first, got %q", plain)
+	}
+	for _, expected := range []string{"\n\tacknowledged by jane at ", ": expected, maintenance", "\n\tcomment at "} {
+		if !strings.Contains(plain, expected) {
+			t.Errorf("expected %q in %q", expected, plain)
+		}
+	}
+	if pretty := prettyEvent(e, false); !strings.Contains(pretty, "#7 ") || !strings.Contains(pretty, "[acknowledged by jane") {
+		t.Errorf("expected ID and annotations on one line, got %q", pretty)
+	}
+}
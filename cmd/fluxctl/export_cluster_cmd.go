@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux/api/v18"
+)
+
+type exportClusterOpts struct {
+	*rootOpts
+	namespaces []string
+	kinds      []string
+	path       string
+}
+
+func newExportCluster(parent *rootOpts) *exportClusterOpts {
+	return &exportClusterOpts{rootOpts: parent}
+}
+
+func (opts *exportClusterOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-cluster",
+		Short: "Export resources running in the cluster as YAML ready to commit to git.",
+		Long: `
+Export the namespaces, and the controllers in them, that are running
+in the cluster, without the fields filled in by the cluster (status,
+uid, resourceVersion and so on). This is a way to start managing an
+existing cluster with flux: export it into the git repo, check the
+result, and commit it.
+`,
+		Example: makeExample(
+			"fluxctl export-cluster --namespace=default",
+			"fluxctl export-cluster --namespace=default,payments --kind=namespace,deployment --out=config/",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringSliceVarP(&opts.namespaces, "namespace", "n", nil, "Namespaces to export from; all of them, if not given")
+	cmd.Flags().StringSliceVarP(&opts.kinds, "kind", "k", nil, "Kinds of resource to export, e.g., namespace or deployment; all of them, if not given")
+	cmd.Flags().StringVarP(&opts.path, "out", "o", "-", "Output path for exported resources; '-' indicates stdout; if a directory is given, each resource will be saved in a file under the directory")
+	return cmd
+}
+
+func (opts *exportClusterOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+	if opts.path != "-" {
+		if info, err := os.Stat(opts.path); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("path %s is not a directory", opts.path)
+		}
+	}
+
+	exported, err := opts.API.ExportCluster(context.Background(), v18.ExportClusterOptions{
+		Namespaces: opts.namespaces,
+		Kinds:      opts.kinds,
+	})
+	if err != nil {
+		return errors.Wrap(err, "exporting cluster")
+	}
+	return writeExported(cmd.OutOrStdout(), exported, opts.path)
+}
+
+// writeExported writes the resources exported to stdout, or to a file
+// for each under the directory given. They are already clean, so are
+// written as they are.
+func writeExported(stdout io.Writer, exported []byte, out string) error {
+	if out == "-" {
+		_, err := stdout.Write(exported)
+		return err
+	}
+
+	docs := bufio.NewScanner(bytes.NewReader(exported))
+	docs.Buffer(nil, len(exported)+1)
+	docs.Split(splitYAMLDocument)
+	for docs.Scan() {
+		doc := bytes.TrimPrefix(docs.Bytes(), []byte("---\n"))
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			doc = append(doc, '\n')
+		}
+		// Only the kind and metadata are needed to name the file
+		var object saveObject
+		if err := yaml.Unmarshal(doc, &object); err != nil {
+			return errors.Wrap(err, "unmarshalling exported yaml")
+		}
+		path, err := outputFile(stdout, object, out)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, append([]byte("---\n"), doc...), 0666); err != nil {
+			return errors.Wrap(err, "writing yaml file")
+		}
+	}
+	return errors.Wrap(docs.Err(), "splitting exported yaml")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const exportedFixture = `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: helloworld
+  namespace: default
+spec:
+  replicas: 1
+`
+
+func TestWriteExportedToDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fluxctl-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeExported(&bytes.Buffer{}, []byte(exportedFixture), dir); err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{
+		"default-ns.yaml":             "---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: default\n",
+		"default/helloworld-dep.yaml": "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: helloworld\n  namespace: default\nspec:\n  replicas: 1\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(got) != expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", path, expected, got)
+		}
+	}
+}
+
+func TestWriteExportedToStdout(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeExported(out, []byte(exportedFixture), "-"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != exportedFixture {
+		t.Errorf("expected the export as it is, got:\n%s", out)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
+)
+
+type exportEventsOpts struct {
+	*rootOpts
+	namespace  string
+	controller string
+	types      []string
+	namespaces []string
+	since      string
+	until      string
+	level      string
+	format     string
+	output     string
+}
+
+func newExportEvents(parent *rootOpts) *exportEventsOpts {
+	return &exportEventsOpts{rootOpts: parent}
+}
+
+func (opts *exportEventsOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-events",
+		Short: "Export the whole history of events kept by the daemon, as NDJSON or CSV.",
+		Long: `
+Export every event kept by the daemon (or those concerning one
+controller, or of some types, or from a period), most recent first,
+for archiving or for a compliance review. The daemon reads the events
+a page at a time and sends each page as it goes, so long histories
+can be exported.
+`,
+		Example: makeExample(
+			"fluxctl export-events > events.ndjson",
+			"fluxctl export-events --format=csv --since=720h --output=events.csv",
+			"fluxctl export-events --controller=default:deployment/helloworld --type=release,autorelease",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
+	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Export only the events concerning this controller")
+	cmd.Flags().StringSliceVar(&opts.types, "type", nil, "Export only events of these types, e.g., release,autorelease")
+	cmd.Flags().StringSliceVar(&opts.namespaces, "in-namespace", nil, "Export only the events in these namespaces, or concerning controllers in them")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Export only events from this time onwards; an RFC3339 time, or a duration ago, e.g., 720h")
+	cmd.Flags().StringVar(&opts.until, "until", "", "Export only events from before this time; an RFC3339 time, or a duration ago")
+	cmd.Flags().StringVar(&opts.level, "level", "", "Export only events logged at this level or above; one of debug, info, warn or error")
+	cmd.Flags().StringVar(&opts.format, "format", event.ExportNDJSON, "Format to export in; one of "+strings.Join(event.ExportFormats, " or "))
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "File to write the events to, rather than stdout")
+	return cmd
+}
+
+// eventExporter is the part of the API client that streams the event
+// history; it's not part of api.Server, since it can't go through an
+// RPC connection.
+type eventExporter interface {
+	ExportEvents(ctx context.Context, opts v22.EventHistoryOptions, format string, out io.Writer) error
+}
+
+func (opts *exportEventsOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	query, err := opts.query(time.Now())
+	if err != nil {
+		return err
+	}
+	exporter, ok := opts.API.(eventExporter)
+	if !ok {
+		return fmt.Errorf("exporting events needs a connection to the daemon's HTTP API")
+	}
+
+	out := cmd.OutOrStdout()
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return exporter.ExportEvents(context.Background(), query, opts.format, out)
+}
+
+// query gives the options for the events to export, having checked
+// the flags.
+func (opts *exportEventsOpts) query(now time.Time) (v22.EventHistoryOptions, error) {
+	var query v22.EventHistoryOptions
+	if _, err := event.NewExportWriter(nil, opts.format); err != nil {
+		return query, newUsageError(err.Error())
+	}
+	if opts.level != "" {
+		if err := event.ValidateLogLevel(opts.level); err != nil {
+			return query, newUsageError(err.Error())
+		}
+	}
+	since, err := parseAt(opts.since, now)
+	if err != nil {
+		return query, newUsageError(err.Error())
+	}
+	until, err := parseAt(opts.until, now)
+	if err != nil {
+		return query, newUsageError(err.Error())
+	}
+	query.Filter = event.EventFilter{
+		Types:       opts.types,
+		Namespaces:  opts.namespaces,
+		Since:       since,
+		Until:       until,
+		MinLogLevel: opts.level,
+	}
+	if opts.controller != "" {
+		id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
+		if err != nil {
+			return query, err
+		}
+		query.Workload = &id
+	}
+	return query, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/event"
+)
+
+func TestExportEventsQuery(t *testing.T) {
+	now := time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC)
+	opts := &exportEventsOpts{
+		namespace:  "default",
+		controller: "deployment/helloworld",
+		types:      []string{event.EventRelease},
+		since:      "720h",
+		format:     event.ExportCSV,
+	}
+	query, err := opts.query(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Workload == nil || query.Workload.String() != "default:deployment/helloworld" {
+		t.Errorf("expected the controller to be resolved, got %v", query.Workload)
+	}
+	if !query.Filter.Since.Equal(now.Add(-720*time.Hour)) || !query.Filter.Until.IsZero() {
+		t.Errorf("expected events from 30 days ago onwards, got %+v", query.Filter)
+	}
+
+	for _, bad := range []*exportEventsOpts{
+		{format: "xml"},
+		{format: event.ExportNDJSON, level: "loud"},
+		{format: event.ExportNDJSON, since: "last tuesday"},
+	} {
+		if _, err := bad.query(now); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
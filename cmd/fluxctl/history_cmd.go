@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
+)
+
+type historyOpts struct {
+	*rootOpts
+	namespace  string
+	controller string
+	before     int64
+	after      int64
+	limit      int
+	all        bool
+	types      []string
+	namespaces []string
+	since      time.Duration
+	level      string
+	correlated string
+	format     string
+	noColor    bool
+}
+
+func newHistory(parent *rootOpts) *historyOpts {
+	return &historyOpts{rootOpts: parent}
+}
+
+func (opts *historyOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of events kept by the daemon, a page at a time.",
+		Long: `
+Show a page of the events kept by the daemon, optionally only those
+concerning one controller, or of some types, or from a recent period,
+oldest first. If there are older events, the command to show the page
+before is printed after the events; give --all to show every page.
+`,
+		Example: makeExample(
+			"fluxctl history --controller=default:deployment/helloworld",
+			"fluxctl history --before=120 --limit=50",
+			"fluxctl history --after=100 --all",
+			"fluxctl history --type=release,autorelease --level=error --since=24h",
+			"fluxctl history --in-namespace=team-a",
+			"fluxctl history --correlation-id=6ed8bd5e-8d1c-4f1a-a5c6-2a8b3a4c0f0e",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
+	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Show only the events concerning this controller")
+	cmd.Flags().Int64Var(&opts.before, "before", 0, "Show only events before the event with this ID")
+	cmd.Flags().Int64Var(&opts.after, "after", 0, "Show only events after the event with this ID")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "l", 20, "Number of events in each page (the daemon may show fewer)")
+	cmd.Flags().BoolVar(&opts.all, "all", false, "Show every page, rather than only the most recent")
+	cmd.Flags().StringSliceVar(&opts.types, "type", nil, "Show only events of these types, e.g., release,autorelease")
+	cmd.Flags().StringSliceVar(&opts.namespaces, "in-namespace", nil, "Show only the events in these namespaces, or concerning controllers in them")
+	cmd.Flags().DurationVar(&opts.since, "since", 0, "Show only events from this long ago onwards, e.g., 24h")
+	cmd.Flags().StringVar(&opts.level, "level", "", "Show only events logged at this level or above; one of debug, info, warn or error")
+	cmd.Flags().StringVar(&opts.correlated, "correlation-id", "", "Show only the events for the change with this correlation ID (the ID of the job that made it)")
+	cmd.Flags().StringVar(&opts.format, "format", eventsFormatPlain, "How to show events; one of 'plain' or 'pretty' (one colourised line per event)")
+	cmd.Flags().BoolVar(&opts.noColor, "no-color", false, "Don't colourise 'pretty' output")
+	return cmd
+}
+
+func (opts *historyOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	var format func(event.Event) string
+	switch opts.format {
+	case eventsFormatPlain:
+		format = plainEvent
+	case eventsFormatPretty:
+		color := !opts.noColor && os.Getenv("NO_COLOR") == ""
+		format = func(e event.Event) string { return prettyEvent(e, color) }
+	default:
+		return newUsageError(fmt.Sprintf("unknown format %q; expected one of %q or %q", opts.format, eventsFormatPlain, eventsFormatPretty))
+	}
+
+	query := v22.EventHistoryOptions{
+		After:  event.EventID(opts.after),
+		Before: event.EventID(opts.before),
+		Limit:  opts.limit,
+		Filter: event.EventFilter{
+			Types:         opts.types,
+			Namespaces:    opts.namespaces,
+			MinLogLevel:   opts.level,
+			CorrelationID: opts.correlated,
+		},
+	}
+	if opts.level != "" {
+		if err := event.ValidateLogLevel(opts.level); err != nil {
+			return newUsageError(err.Error())
+		}
+	}
+	if opts.since > 0 {
+		query.Filter.Since = time.Now().Add(-opts.since).UTC()
+	}
+	if opts.controller != "" {
+		id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
+		if err != nil {
+			return err
+		}
+		query.Workload = &id
+	}
+
+	pages, err := fetchHistory(context.Background(), opts.API.EventHistory, query, opts.all)
+	if err != nil {
+		return err
+	}
+	return writeHistory(cmd.OutOrStdout(), cmd.OutOrStderr(), pages, format)
+}
+
+// fetchHistory gets the page of events asked for, and if all is
+// true, each page before it in turn. The pages are given most recent
+// first.
+func fetchHistory(ctx context.Context, get func(context.Context, v22.EventHistoryOptions) (v22.EventHistory, error), opts v22.EventHistoryOptions, all bool) ([]v22.EventHistory, error) {
+	var pages []v22.EventHistory
+	for {
+		page, err := get(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+		if !all || page.Older == 0 {
+			return pages, nil
+		}
+		opts.Before = page.Older
+	}
+}
+
+// writeHistory writes the events in the pages, oldest first, and, if
+// there are older events not shown, how to see them.
+func writeHistory(out, hints io.Writer, pages []v22.EventHistory, format func(event.Event) string) error {
+	for i := len(pages) - 1; i >= 0; i-- {
+		for _, e := range pages[i].Events {
+			if _, err := fmt.Fprintln(out, format(e)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(pages) > 0 {
+		if older := pages[len(pages)-1].Older; older != 0 {
+			fmt.Fprintf(hints, "More events before #%d; to see them, run again with --before=%d\n", older, older)
+		}
+	}
+	return nil
+}
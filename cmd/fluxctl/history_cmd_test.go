@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestFetchAndWriteHistory(t *testing.T) {
+	// Three pages of two events: 5-6, 3-4, 1-2
+	var asked []v22.EventHistoryOptions
+	get := func(_ context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+		asked = append(asked, opts)
+		newest := event.EventID(6)
+		if opts.Before > 0 {
+			newest = opts.Before - 1
+		}
+		page := v22.EventHistory{Events: []event.Event{{ID: newest - 1}, {ID: newest}}}
+		if newest > 2 {
+			page.Older = newest - 1
+		}
+		return page, nil
+	}
+	format := func(e event.Event) string { return fmt.Sprint(e.ID) }
+
+	pages, err := fetchHistory(context.Background(), get, v22.EventHistoryOptions{Limit: 2}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, hints := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := writeHistory(out, hints, pages, format); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "5\n6\n" {
+		t.Errorf("expected only the most recent page, got:\n%s", out)
+	}
+	if !strings.Contains(hints.String(), "--before=5") {
+		t.Errorf("expected a hint about older events, got %q", hints)
+	}
+
+	asked = nil
+	pages, err = fetchHistory(context.Background(), get, v22.EventHistoryOptions{Limit: 2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	hints.Reset()
+	if err := writeHistory(out, hints, pages, format); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n2\n3\n4\n5\n6\n" {
+		t.Errorf("expected every page, oldest first, got:\n%s", out)
+	}
+	if hints.Len() != 0 {
+		t.Errorf("expected no hint when every page is shown, got %q", hints)
+	}
+	if len(asked) != 3 || asked[1].Before != 5 || asked[2].Before != 3 || asked[2].Limit != 2 {
+		t.Errorf("expected each page to be asked for before the last, got %+v", asked)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v15"
+)
+
+type imagesOpts struct {
+	*rootOpts
+}
+
+func newImages(parent *rootOpts) *imagesOpts {
+	return &imagesOpts{rootOpts: parent}
+}
+
+func (opts *imagesOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Report on the images running in the cluster.",
+	}
+	cmd.AddCommand(newImageReport(opts.rootOpts).Command())
+	return cmd
+}
+
+type imageReportOpts struct {
+	*rootOpts
+	namespace string
+	format    string
+}
+
+func newImageReport(parent *rootOpts) *imageReportOpts {
+	return &imageReportOpts{rootOpts: parent}
+}
+
+func (opts *imageReportOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "List the images in use, how far behind the newest matching tag each is, and which controllers use it.",
+		Example: makeExample(
+			"fluxctl images report",
+			"fluxctl images report --namespace=default --format=csv > images.csv",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only report images used in this namespace")
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Output format; one of table, csv or json")
+	return cmd
+}
+
+func (opts *imageReportOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	switch opts.format {
+	case "table", "csv", "json":
+	default:
+		return newUsageError("--format must be one of table, csv or json")
+	}
+
+	report, err := opts.API.ImageReport(context.Background(), v15.ImageReportOptions{Namespace: opts.namespace})
+	if err != nil {
+		return err
+	}
+	return writeImageReport(os.Stdout, opts.format, report)
+}
+
+func writeImageReport(out io.Writer, format string, report []v15.ImageUsage) error {
+	switch format {
+	case "json":
+		if report == nil {
+			report = []v15.ImageUsage{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write([]string{"image", "repository", "tag", "pattern", "latest", "behind", "created", "latest_created", "used_by"})
+		for _, u := range report {
+			w.Write([]string{
+				u.Image,
+				u.Repository,
+				u.Tag,
+				u.Pattern,
+				u.Latest,
+				strconv.Itoa(u.Behind),
+				formatReportTime(u.CreatedAt),
+				formatReportTime(u.LatestAt),
+				strings.Join(imageUsers(u), ";"),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+		fmt.Fprintln(w, "IMAGE\tBEHIND\tLATEST\tUSED BY")
+		for _, u := range report {
+			behind := "?"
+			if u.Behind >= 0 {
+				behind = strconv.Itoa(u.Behind)
+			}
+			latest := u.Latest
+			if latest == "" {
+				latest = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Image, behind, latest, strings.Join(imageUsers(u), ", "))
+		}
+		return w.Flush()
+	}
+}
+
+func imageUsers(u v15.ImageUsage) []string {
+	var users []string
+	for _, user := range u.UsedBy {
+		users = append(users, user.Workload.String()+"("+user.Container+")")
+	}
+	return users
+}
+
+func formatReportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
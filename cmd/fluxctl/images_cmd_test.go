@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v15"
+)
+
+func imageReportFixture() []v15.ImageUsage {
+	return []v15.ImageUsage{
+		{
+			Image:      "quay.io/foo/app:1.0",
+			Repository: "quay.io/foo/app",
+			Tag:        "1.0",
+			Pattern:    "*",
+			Latest:     "1.2",
+			Behind:     2,
+			UsedBy: []v15.ImageUser{
+				{Workload: flux.MustParseResourceID("default:deployment/a"), Container: "app"},
+				{Workload: flux.MustParseResourceID("default:deployment/b"), Container: "app"},
+			},
+		},
+		{
+			Image:      "quay.io/foo/sidecar:dev",
+			Repository: "quay.io/foo/sidecar",
+			Tag:        "dev",
+			Pattern:    "*",
+			Behind:     -1,
+			UsedBy: []v15.ImageUser{
+				{Workload: flux.MustParseResourceID("default:deployment/a"), Container: "sidecar"},
+			},
+		},
+	}
+}
+
+func TestImageReportCSV(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeImageReport(out, "csv", imageReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header and two rows, got %d rows", len(rows))
+	}
+	expected := []string{"quay.io/foo/app:1.0", "quay.io/foo/app", "1.0", "*", "1.2", "2", "", "", "default:deployment/a(app);default:deployment/b(app)"}
+	if !reflect.DeepEqual(rows[1], expected) {
+		t.Errorf("expected %q, got %q", expected, rows[1])
+	}
+	if rows[2][5] != "-1" {
+		t.Errorf("expected unknown behind to be -1, got %q", rows[2][5])
+	}
+}
+
+func TestImageReportJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeImageReport(out, "json", imageReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	var report []v15.ImageUsage
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, imageReportFixture()) {
+		t.Errorf("expected report to round-trip, got %#v", report)
+	}
+
+	out.Reset()
+	if err := writeImageReport(out, "json", nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("expected empty array for empty report, got %q", out.String())
+	}
+}
+
+func TestImageReportTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeImageReport(out, "table", imageReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected three lines, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[2], "?") || !strings.Contains(lines[2], "-") {
+		t.Errorf("expected unknown behind and latest to be marked, got %q", lines[2])
+	}
+}
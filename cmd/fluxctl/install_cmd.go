@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/install"
+)
+
+type installOpts struct {
+	install.TemplateParameters
+	outputDir string
+	apply     bool
+}
+
+func newInstall() *installOpts {
+	return &installOpts{}
+}
+
+func (opts *installOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Print or apply the manifests for running flux in a cluster.",
+		Long: `
+Generate the manifests for running the flux daemon in a cluster -- its
+deployment, the secret for its deploy key, its service account and
+RBAC role binding, and memcached -- with the git repo given. They are
+printed, or with --output-dir written to files ready to commit to git,
+or with --apply given to kubectl apply.
+`,
+		Example: makeExample(
+			"fluxctl install --git-url=git@github.com:weaveworks/flux-example --namespace=flux | kubectl apply -f -",
+			"fluxctl install --git-url=git@github.com:weaveworks/flux-example --git-path=namespaces,workloads --output-dir=flux/",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVar(&opts.GitURL, "git-url", "", "URL of the git repo with Kubernetes manifests; e.g., git@github.com:weaveworks/flux-example")
+	cmd.Flags().StringVar(&opts.GitBranch, "git-branch", "master", "branch of the git repo to use for Kubernetes manifests")
+	cmd.Flags().StringSliceVar(&opts.GitPaths, "git-path", []string{}, "relative paths within the git repo to locate Kubernetes manifests")
+	cmd.Flags().StringVar(&opts.GitLabel, "git-label", "", "label to keep track of sync progress; by default, the daemon's own")
+	cmd.Flags().StringVar(&opts.GitUser, "git-user", "", "username to use as git committer; by default, the daemon's own")
+	cmd.Flags().StringVar(&opts.GitEmail, "git-email", "", "email to use as git committer; by default, the daemon's own")
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "default", "namespace to run flux in; it must already exist")
+	cmd.Flags().StringVar(&opts.Image, "image", install.DefaultImage, "the flux daemon image to run")
+	cmd.Flags().StringVarP(&opts.outputDir, "output-dir", "o", "", "directory to write a file for each manifest to, rather than printing them")
+	cmd.Flags().BoolVar(&opts.apply, "apply", false, "apply the manifests to the cluster, with kubectl, rather than printing them")
+	return cmd
+}
+
+func (opts *installOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	if opts.GitURL == "" {
+		return newUsageError("--git-url is required")
+	}
+	manifests, err := install.FillInTemplates(opts.TemplateParameters)
+	if err != nil {
+		return err
+	}
+
+	if opts.outputDir != "" {
+		if err := writeManifests(cmd, manifests, opts.outputDir); err != nil {
+			return err
+		}
+	}
+	if opts.apply {
+		kubectl := execCommand("kubectl", "apply", "-f", "-")
+		kubectl.Stdin = bytes.NewReader(install.Concat(manifests))
+		kubectl.Stdout = cmd.OutOrStdout()
+		kubectl.Stderr = cmd.OutOrStderr()
+		return errors.Wrap(kubectl.Run(), "applying manifests with kubectl")
+	}
+	if opts.outputDir == "" {
+		_, err = cmd.OutOrStdout().Write(install.Concat(manifests))
+	}
+	return err
+}
+
+// writeManifests writes a file for each manifest in the directory
+// given, making the directory if it doesn't exist.
+func writeManifests(cmd *cobra.Command, manifests map[string][]byte, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "making output directory")
+	}
+	var names []string
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		fmt.Fprintf(cmd.OutOrStderr(), "Writing %s\n", path)
+		if err := ioutil.WriteFile(path, manifests[name], 0666); err != nil {
+			return errors.Wrap(err, "writing manifest")
+		}
+	}
+	return nil
+}
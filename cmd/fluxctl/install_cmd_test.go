@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallCommand(t *testing.T) {
+	var out bytes.Buffer
+	cmd := newInstall().Command()
+	cmd.SetOutput(&out)
+	cmd.SetArgs([]string{"--git-url=git@github.com:weaveworks/flux-example", "--namespace=flux"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: Deployment", "--git-url=git@github.com:weaveworks/flux-example", "namespace: flux"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to include %q", want)
+		}
+	}
+}
+
+func TestInstallCommand_OutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fluxctl-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := newInstall().Command()
+	cmd.SetOutput(ioutil.Discard)
+	cmd.SetArgs([]string{"--git-url=git@github.com:weaveworks/flux-example", "--output-dir=" + dir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "flux-deployment.yaml")); err != nil {
+		t.Errorf("expected the deployment to be written: %v", err)
+	}
+}
+
+func TestInstallCommand_InputFailures(t *testing.T) {
+	for _, args := range [][]string{{}, {"--git-url=git@github.com:weaveworks/flux-example", "extra"}} {
+		cmd := newInstall().Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected error with args %v", args)
+		}
+	}
+}
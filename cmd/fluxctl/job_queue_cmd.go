@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v29"
+)
+
+type jobQueueOpts struct {
+	*rootOpts
+}
+
+func newJobQueue(parent *rootOpts) *jobQueueOpts {
+	return &jobQueueOpts{rootOpts: parent}
+}
+
+func (opts *jobQueueOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "job-queue",
+		Short: "Show the jobs running, and those waiting to run, in the order they'll run.",
+		Example: makeExample(
+			"fluxctl job-queue",
+		),
+		RunE: opts.RunE,
+	}
+	return cmd
+}
+
+func (opts *jobQueueOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	queue, err := opts.API.JobQueue(context.Background())
+	if err != nil {
+		return err
+	}
+	writeJobQueue(os.Stdout, queue, time.Now())
+	return nil
+}
+
+func writeJobQueue(out io.Writer, queue v29.JobQueue, now time.Time) {
+	fmt.Fprintf(out, "Running at most %d at once\n", queue.MaxRunning)
+	if len(queue.Jobs) == 0 {
+		fmt.Fprintln(out, "No jobs queued")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tPRIORITY\tSTATE\tWORKLOADS")
+	for _, j := range queue.Jobs {
+		state := fmt.Sprintf("waiting for %s", roundDuration(now.Sub(j.QueuedAt)))
+		if j.StartedAt != nil {
+			state = fmt.Sprintf("running for %s", roundDuration(now.Sub(*j.StartedAt)))
+		}
+		var workloads []string
+		for _, id := range j.Workloads {
+			workloads = append(workloads, id.String())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", j.ID, j.Kind, j.Priority, state, strings.Join(workloads, ", "))
+	}
+	w.Flush()
+}
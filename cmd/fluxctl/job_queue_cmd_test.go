@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v29"
+	"github.com/weaveworks/flux/job"
+)
+
+func TestWriteJobQueue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC)
+	started := now.Add(-30 * time.Second)
+	queue := v29.JobQueue{
+		MaxRunning: 2,
+		Jobs: []job.QueuedJob{
+			{ID: "6ed8bd5e", Kind: "image", Workloads: []flux.ResourceID{flux.MustParseResourceID("default:deployment/hello")}, QueuedAt: now.Add(-time.Minute), StartedAt: &started},
+			{ID: "a6b1c2d3", Kind: "auto", Priority: -1, QueuedAt: now.Add(-5 * time.Minute)},
+		},
+	}
+	out := &bytes.Buffer{}
+	writeJobQueue(out, queue, now)
+	for _, expected := range []string{
+		"Running at most 2 at once",
+		"6ed8bd5e  image  0         running for 30s   default:deployment/hello",
+		"a6b1c2d3  auto   -1        waiting for 5m0s",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out)
+		}
+	}
+
+	out.Reset()
+	writeJobQueue(out, v29.JobQueue{MaxRunning: 1}, now)
+	if !strings.Contains(out.String(), "No jobs queued") {
+		t.Errorf("expected to be told there are no jobs, got:\n%s", out)
+	}
+}
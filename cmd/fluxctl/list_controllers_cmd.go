@@ -52,17 +52,36 @@ func (opts *controllerListOpts) RunE(cmd *cobra.Command, args []string) error {
 
 	sort.Sort(controllerStatusByName(controllers))
 
+	// Only show owners if the daemon knows about them
+	var withOwners bool
+	for _, controller := range controllers {
+		if controller.Owner != "" {
+			withOwners = true
+			break
+		}
+	}
+	owner := func(controller v6.ControllerStatus) string {
+		if !withOwners {
+			return ""
+		}
+		return "\t" + controller.Owner
+	}
+	header := "CONTROLLER\tCONTAINER\tIMAGE\tRELEASE\tPOLICY"
+	if withOwners {
+		header += "\tOWNER"
+	}
+
 	w := newTabwriter()
-	fmt.Fprintf(w, "CONTROLLER\tCONTAINER\tIMAGE\tRELEASE\tPOLICY\n")
+	fmt.Fprintln(w, header)
 	for _, controller := range controllers {
 		if len(controller.Containers) > 0 {
 			c := controller.Containers[0]
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", controller.ID, c.Name, c.Current.ID, controller.Status, policies(controller))
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s%s\n", controller.ID, c.Name, c.Current.ID, controller.Status, policies(controller), owner(controller))
 			for _, c := range controller.Containers[1:] {
 				fmt.Fprintf(w, "\t%s\t%s\t\t\n", c.Name, c.Current.ID)
 			}
 		} else {
-			fmt.Fprintf(w, "%s\t\t\t\t\n", controller.ID)
+			fmt.Fprintf(w, "%s\t\t\t\t%s\n", controller.ID, owner(controller))
 		}
 	}
 	w.Flush()
@@ -94,6 +113,9 @@ func policies(s v6.ControllerStatus) string {
 	if s.Ignore {
 		ps = append(ps, string(policy.Ignore))
 	}
+	if _, ok := s.Policies[string(policy.Observe)]; ok {
+		ps = append(ps, string(policy.Observe))
+	}
 	sort.Strings(ps)
 	return strings.Join(ps, ",")
 }
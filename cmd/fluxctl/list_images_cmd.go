@@ -91,6 +91,9 @@ func (opts *controllerShowOpts) RunE(cmd *cobra.Command, args []string) error {
 			if reg != "" {
 				reg += "/"
 			}
+			if container.Pinned != "" {
+				repo += " (pinned to " + container.Pinned + ")"
+			}
 			if len(container.Available) == 0 {
 				availableErr := container.AvailableError
 				if availableErr == "" {
@@ -12,6 +12,7 @@ type controllerLockOpts struct {
 	controller string
 	outputOpts
 	cause update.Cause
+	until string
 
 	// Deprecated
 	service string
@@ -27,6 +28,7 @@ func (opts *controllerLockOpts) Command() *cobra.Command {
 		Short: "Lock a controller, so it cannot be deployed.",
 		Example: makeExample(
 			"fluxctl lock --controller=default:deployment/helloworld",
+			"fluxctl lock --controller=default:deployment/helloworld -m 'bad config, fix in review' --until-revision=af4bf73",
 		),
 		RunE: opts.RunE,
 	}
@@ -34,6 +36,7 @@ func (opts *controllerLockOpts) Command() *cobra.Command {
 	AddCauseFlags(cmd, &opts.cause)
 	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Controller namespace")
 	cmd.Flags().StringVarP(&opts.controller, "controller", "c", "", "Controller to lock")
+	cmd.Flags().StringVar(&opts.until, "until-revision", "", "The revision the controller is meant to stay locked until, e.g., that of a fix yet to be merged")
 
 	// Deprecated
 	cmd.Flags().StringVarP(&opts.service, "service", "s", "", "Service to lock")
@@ -54,6 +57,7 @@ func (opts *controllerLockOpts) RunE(cmd *cobra.Command, args []string) error {
 		controller: opts.controller,
 		cause:      opts.cause,
 		lock:       true,
+		lockUntil:  opts.until,
 	}
 	return policyOpts.RunE(cmd, args)
 }
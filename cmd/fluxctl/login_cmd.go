@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/auth"
+)
+
+const (
+	envVariableOIDCIssuer   = "FLUX_OIDC_ISSUER_URL"
+	envVariableOIDCClientID = "FLUX_OIDC_CLIENT_ID"
+	envVariableIDTokenFile  = "FLUX_ID_TOKEN_FILE"
+)
+
+type loginOpts struct {
+	*rootOpts
+	issuerURL string
+	clientID  string
+	scopes    []string
+}
+
+func newLogin(parent *rootOpts) *loginOpts {
+	return &loginOpts{rootOpts: parent}
+}
+
+func (opts *loginOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to an OpenID Connect provider, to authenticate to fluxd",
+		Example: makeExample(
+			"fluxctl login --oidc-issuer-url=https://accounts.example.com --oidc-client-id=fluxctl",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVar(&opts.issuerURL, "oidc-issuer-url", "",
+		fmt.Sprintf("URL of the OpenID Connect issuer; you can also set the environment variable %s", envVariableOIDCIssuer))
+	cmd.Flags().StringVar(&opts.clientID, "oidc-client-id", "",
+		fmt.Sprintf("client ID registered with the issuer for fluxctl; you can also set the environment variable %s", envVariableOIDCClientID))
+	cmd.Flags().StringSliceVar(&opts.scopes, "oidc-scope", []string{"email", "groups"}, "scopes to request in addition to openid")
+	return cmd
+}
+
+func (opts *loginOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errorWantedNoArgs
+	}
+	opts.issuerURL = getFromEnvIfNotSet(cmd.Flags(), "oidc-issuer-url", opts.issuerURL, envVariableOIDCIssuer)
+	opts.clientID = getFromEnvIfNotSet(cmd.Flags(), "oidc-client-id", opts.clientID, envVariableOIDCClientID)
+	if opts.issuerURL == "" || opts.clientID == "" {
+		return newUsageError("both --oidc-issuer-url and --oidc-client-id are required")
+	}
+
+	ctx := context.Background()
+	provider, err := auth.Discover(ctx, http.DefaultClient, opts.issuerURL)
+	if err != nil {
+		return err
+	}
+	flow := &auth.DeviceFlow{
+		Client:   http.DefaultClient,
+		Provider: provider,
+		ClientID: opts.clientID,
+		Scopes:   opts.scopes,
+	}
+	da, err := flow.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if da.VerificationURIComplete != "" {
+		fmt.Fprintf(cmd.OutOrStderr(), "To log in, visit %s\n", da.VerificationURIComplete)
+	} else {
+		fmt.Fprintf(cmd.OutOrStderr(), "To log in, visit %s and enter the code %s\n", da.VerificationURI, da.UserCode)
+	}
+
+	token, err := flow.Wait(ctx, da)
+	if err != nil {
+		return err
+	}
+
+	path := idTokenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "creating directory for token")
+	}
+	if err := ioutil.WriteFile(path, []byte(token), 0600); err != nil {
+		return errors.Wrap(err, "saving token")
+	}
+	fmt.Fprintf(cmd.OutOrStderr(), "Logged in; token saved to %s\n", path)
+	return nil
+}
+
+// idTokenPath gives the location of the ID token saved by `fluxctl
+// login`.
+func idTokenPath() string {
+	if path := os.Getenv(envVariableIDTokenFile); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".fluxctl", "id-token")
+}
+
+// readIDToken returns the saved ID token, or the empty string if
+// there isn't one.
+func readIDToken() string {
+	bytes, err := ioutil.ReadFile(idTokenPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bytes))
+}
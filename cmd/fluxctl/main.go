@@ -9,7 +9,11 @@ import (
 )
 
 func run(args []string) int {
-	rootCmd := newRoot().Command()
+	root := newRoot()
+	rootCmd := root.Command()
+	if plugin, pluginArgs, ok := findPlugin(rootCmd, args); ok {
+		return root.runPlugin(rootCmd, plugin, pluginArgs)
+	}
 	rootCmd.SetArgs(args)
 	if cmd, err := rootCmd.ExecuteC(); err != nil {
 		err = errors.Cause(err)
@@ -21,7 +21,7 @@ func mockServiceOpts(trip *genericMockRoundTripper) *rootOpts {
 	c := http.Client{
 		Transport: trip,
 	}
-	mockAPI := client.New(&c, transport.NewAPIRouter(), "", "")
+	mockAPI := client.New(&c, transport.NewAPIRouter(), "", client.Token(""))
 	return &rootOpts{
 		API: mockAPI,
 	}
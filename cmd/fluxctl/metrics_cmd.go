@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v23"
+)
+
+// The metrics shown unless --all is given: those most useful when
+// working out why the daemon is slow or stuck.
+var keyMetricPrefixes = []string{
+	"flux_daemon_queue_",
+	"flux_daemon_sync_",
+	"flux_daemon_job_",
+	"flux_daemon_manifest_cache_",
+	"flux_daemon_automation_",
+	"flux_cache_",
+	"flux_event_store_",
+}
+
+type metricsOpts struct {
+	*rootOpts
+	all    bool
+	format string
+}
+
+func newMetrics(parent *rootOpts) *metricsOpts {
+	return &metricsOpts{rootOpts: parent}
+}
+
+func (opts *metricsOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Show a snapshot of the daemon's key metrics, e.g., queue length, cache hit rates and sync durations.",
+		Long: `
+Show the current value of the daemon's key metrics, for when they
+can't be scraped by Prometheus. Histograms are shown as the number of
+observations and their mean; counters of cache lookups are also shown
+as a hit rate.
+`,
+		Example: makeExample(
+			"fluxctl metrics",
+			"fluxctl metrics --all --format=json",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().BoolVar(&opts.all, "all", false, "Show every metric, rather than only the key metrics")
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Output format; one of table or json")
+	return cmd
+}
+
+func (opts *metricsOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	switch opts.format {
+	case "table", "json":
+	default:
+		return newUsageError("--format must be one of table or json")
+	}
+
+	snapshot, err := opts.API.MetricsSnapshot(context.Background())
+	if err != nil {
+		return err
+	}
+	if !opts.all {
+		snapshot.Metrics = keyMetrics(snapshot.Metrics)
+	}
+	return writeMetrics(cmd.OutOrStdout(), opts.format, snapshot)
+}
+
+func keyMetrics(metrics []v23.Metric) []v23.Metric {
+	var res []v23.Metric
+	for _, m := range metrics {
+		for _, prefix := range keyMetricPrefixes {
+			if strings.HasPrefix(m.Name, prefix) {
+				res = append(res, m)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func writeMetrics(out io.Writer, format string, snapshot v23.MetricsSnapshot) error {
+	metrics := append([]v23.Metric(nil), snapshot.Metrics...)
+	sort.SliceStable(metrics, func(i, j int) bool {
+		if metrics[i].Name != metrics[j].Name {
+			return metrics[i].Name < metrics[j].Name
+		}
+		return formatLabels(metrics[i].Labels, "") < formatLabels(metrics[j].Labels, "")
+	})
+
+	if format == "json" {
+		snapshot.Metrics = metrics
+		if snapshot.Metrics == nil {
+			snapshot.Metrics = []v23.Metric{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tLABELS\tVALUE")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.Name, formatLabels(m.Labels, ""), formatMetricValue(m))
+	}
+	for _, r := range hitRates(metrics) {
+		fmt.Fprintf(w, "%s\t%s\t%.0f%% of %d\n", r.name, r.labels, 100*r.rate, r.lookups)
+	}
+	return w.Flush()
+}
+
+func formatMetricValue(m v23.Metric) string {
+	switch m.Type {
+	case v23.MetricHistogram, v23.MetricSummary:
+		if m.Count == 0 {
+			return "count=0"
+		}
+		return fmt.Sprintf("count=%d mean=%.3g", m.Count, m.Value/float64(m.Count))
+	default:
+		return fmt.Sprintf("%g", m.Value)
+	}
+}
+
+// formatLabels gives the labels as `name=value` pairs, sorted by
+// name, leaving out the label named by except.
+func formatLabels(labels map[string]string, except string) string {
+	var pairs []string
+	for k, v := range labels {
+		if k != except {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	if len(pairs) == 0 {
+		return "-"
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type hitRate struct {
+	name, labels string
+	rate         float64
+	lookups      uint64
+}
+
+// hitRates works out, for each counter of lookups labelled with
+// whether they were hits, the proportion that were.
+func hitRates(metrics []v23.Metric) []hitRate {
+	type key struct{ name, labels string }
+	hits, totals := map[key]float64{}, map[key]float64{}
+	var keys []key
+	for _, m := range metrics {
+		hit, ok := m.Labels["hit"]
+		if !ok || m.Type != v23.MetricCounter {
+			continue
+		}
+		k := key{strings.TrimSuffix(strings.TrimSuffix(m.Name, "_total"), "_lookups") + "_hit_rate", formatLabels(m.Labels, "hit")}
+		if _, seen := totals[k]; !seen {
+			keys = append(keys, k)
+		}
+		totals[k] += m.Value
+		if hit == "true" {
+			hits[k] += m.Value
+		}
+	}
+	var res []hitRate
+	for _, k := range keys {
+		if totals[k] == 0 {
+			continue
+		}
+		res = append(res, hitRate{name: k.name, labels: k.labels, rate: hits[k] / totals[k], lookups: uint64(totals[k])})
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/api/v23"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	snapshot := v23.MetricsSnapshot{Metrics: []v23.Metric{
+		{Name: "flux_daemon_queue_length_count", Type: v23.MetricGauge, Value: 2},
+		{Name: "flux_daemon_sync_duration_seconds", Type: v23.MetricHistogram, Labels: map[string]string{"success": "true"}, Value: 30, Count: 3},
+		{Name: "flux_daemon_manifest_cache_lookups_total", Type: v23.MetricCounter, Labels: map[string]string{"hit": "true"}, Value: 3},
+		{Name: "flux_daemon_manifest_cache_lookups_total", Type: v23.MetricCounter, Labels: map[string]string{"hit": "false"}, Value: 1},
+		{Name: "flux_fluxd_connection_duration_seconds", Type: v23.MetricHistogram},
+	}}
+
+	snapshot.Metrics = keyMetrics(snapshot.Metrics)
+	if len(snapshot.Metrics) != 4 {
+		t.Fatalf("expected only the key metrics, got %+v", snapshot.Metrics)
+	}
+
+	out := &bytes.Buffer{}
+	if err := writeMetrics(out, "table", snapshot); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected a header, four metrics and a hit rate, got:\n%s", out)
+	}
+	for _, expected := range []string{
+		"hit=false",
+		"count=3 mean=10",
+		"75% of 4",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out)
+		}
+	}
+	if fields := strings.Fields(lines[5]); fields[0] != "flux_daemon_manifest_cache_hit_rate" || fields[1] != "-" {
+		t.Errorf("expected the hit rate last, without the hit label, got %q", lines[5])
+	}
+	if !strings.HasPrefix(lines[1], "flux_daemon_manifest_cache_lookups_total  hit=false") {
+		t.Errorf("expected metrics sorted by name then labels, got:\n%s", out)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v17"
+)
+
+type outOfSyncOpts struct {
+	*rootOpts
+	namespace string
+	all       bool
+}
+
+func newOutOfSync(parent *rootOpts) *outOfSyncOpts {
+	return &outOfSyncOpts{rootOpts: parent}
+}
+
+func (opts *outOfSyncOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "out-of-sync",
+		Short: "Show the containers not running the image given in git.",
+		Long: `
+Compare the image given for each container in git with the image it's
+running in the cluster, and show those that differ. The status says
+why: pending-sync means the image in git is yet to be synced;
+changed-in-cluster means the container isn't running the image last
+synced, perhaps because it was changed with kubectl, or is still
+rolling out; and not-running means it's not in the cluster at all.
+`,
+		Example: makeExample(
+			"fluxctl out-of-sync",
+			"fluxctl out-of-sync --namespace=default --all",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only compare controllers in this namespace")
+	cmd.Flags().BoolVarP(&opts.all, "all", "a", false, "Show containers that are in sync too")
+	return cmd
+}
+
+func (opts *outOfSyncOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+
+	compared, err := opts.API.CompareImages(context.Background(), v17.CompareImagesOptions{
+		Namespace: opts.namespace,
+	})
+	if err != nil {
+		return err
+	}
+	writeOutOfSync(os.Stdout, compared, opts.all)
+	return nil
+}
+
+func writeOutOfSync(out io.Writer, compared []v17.ImageComparison, all bool) {
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTROLLER\tCONTAINER\tGIT\tRUNNING\tSTATUS")
+	var shown int
+	for _, c := range compared {
+		if c.InSync() && !all {
+			continue
+		}
+		running := c.Running
+		if running == "" {
+			running = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Workload, c.Container, c.Desired, running, c.Status)
+		shown++
+	}
+	if shown == 0 {
+		fmt.Fprintln(out, "All containers are running the images in git.")
+		return
+	}
+	w.Flush()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v17"
+)
+
+func TestWriteOutOfSync(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/hello")
+	compared := []v17.ImageComparison{
+		{Workload: id, Container: "greeter", Desired: "hello:2", Running: "hello:2", Status: v17.ImageInSync},
+		{Workload: id, Container: "sidecar", Desired: "envoy:2", Running: "envoy:1", Status: v17.ImagePendingSync},
+	}
+
+	out := &bytes.Buffer{}
+	writeOutOfSync(out, compared, false)
+	if strings.Contains(out.String(), "greeter") {
+		t.Errorf("expected only containers out of sync, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), v17.ImagePendingSync) {
+		t.Errorf("expected the container pending sync, got:\n%s", out)
+	}
+
+	out.Reset()
+	writeOutOfSync(out, compared, true)
+	if !strings.Contains(out.String(), "greeter") {
+		t.Errorf("expected all containers, got:\n%s", out)
+	}
+
+	out.Reset()
+	writeOutOfSync(out, compared[:1], false)
+	if !strings.HasPrefix(out.String(), "All containers") {
+		t.Errorf("expected to be told everything is in sync, got:\n%s", out)
+	}
+}
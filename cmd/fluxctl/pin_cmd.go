@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/update"
+)
+
+type containerPinOpts struct {
+	*rootOpts
+	namespace string
+	workload  string
+	container string
+	tag       string
+	outputOpts
+	cause update.Cause
+}
+
+func newContainerPin(parent *rootOpts) *containerPinOpts {
+	return &containerPinOpts{rootOpts: parent}
+}
+
+func (opts *containerPinOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pin",
+		Short: "Pin a container to an image tag, so automation keeps it there.",
+		Long: `
+Pin a container of a workload to an image tag. Automation releases the
+pinned tag to the container, and no other, until it's unpinned. Unlike
+locking, this applies only to the container named, and only to
+automation; the image can still be released by hand.
+`,
+		Example: makeExample(
+			"fluxctl pin --workload=default:deployment/helloworld --container=app --tag=v1.4.2",
+		),
+		RunE: opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Workload namespace")
+	cmd.Flags().StringVarP(&opts.workload, "workload", "w", "", "Workload to pin a container of")
+	cmd.Flags().StringVar(&opts.container, "container", "", "Container to pin")
+	cmd.Flags().StringVar(&opts.tag, "tag", "", "Image tag to pin the container to")
+	return cmd
+}
+
+func (opts *containerPinOpts) RunE(cmd *cobra.Command, args []string) error {
+	if opts.workload == "" {
+		return newUsageError("-w, --workload is required")
+	}
+	if opts.container == "" {
+		return newUsageError("--container is required")
+	}
+	if opts.tag == "" {
+		return newUsageError("--tag is required")
+	}
+
+	policyOpts := &controllerPolicyOpts{
+		rootOpts:   opts.rootOpts,
+		outputOpts: opts.outputOpts,
+		namespace:  opts.namespace,
+		controller: opts.workload,
+		cause:      opts.cause,
+		pins:       []string{opts.container + "=" + opts.tag},
+	}
+	return policyOpts.RunE(cmd, args)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/weaveworks/flux/http/client"
+)
+
+// Plugins are executables named with this prefix, found on the PATH;
+// e.g., `fluxctl foo` runs `fluxctl-foo`.
+const pluginPrefix = "fluxctl-"
+
+// findPlugin looks for the plugin to run given the arguments, if the
+// first argument after any of fluxctl's own flags isn't one of
+// fluxctl's commands. It gives the path to the plugin, and the
+// arguments to pass to it.
+func findPlugin(rootCmd *cobra.Command, args []string) (string, []string, bool) {
+	// Only fluxctl's flags may come before the plugin name; anything
+	// else (e.g., --help) is left for fluxctl to deal with as usual
+	flags := pflag.NewFlagSet("fluxctl", pflag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.SetInterspersed(false)
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+	if err := flags.Parse(args); err != nil {
+		return "", nil, false
+	}
+	rest := flags.Args()
+	if len(rest) == 0 || rest[0] == "help" {
+		return "", nil, false
+	}
+	if _, _, err := rootCmd.Find(rest[:1]); err == nil {
+		return "", nil, false
+	}
+	path, err := exec.LookPath(pluginPrefix + rest[0])
+	if err != nil {
+		return "", nil, false
+	}
+	return path, rest[1:], true
+}
+
+// runPlugin runs the plugin at the path given, with the connection to
+// the API in its environment (see client.NewFromEnv), and gives its
+// exit code.
+func (opts *rootOpts) runPlugin(rootCmd *cobra.Command, path string, args []string) int {
+	if err := opts.connect(rootCmd.PersistentFlags()); err != nil {
+		rootCmd.Println("Error: " + err.Error())
+		return 1
+	}
+
+	plugin := exec.Command(path, args...)
+	plugin.Stdin = os.Stdin
+	plugin.Stdout = os.Stdout
+	plugin.Stderr = os.Stderr
+	plugin.Env = append(os.Environ(),
+		client.EnvURL+"="+opts.URL,
+		client.EnvToken+"="+opts.Token,
+		client.EnvIDToken+"="+opts.IDToken,
+	)
+	if err := plugin.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				return status.ExitStatus()
+			}
+		}
+		rootCmd.Println("Error: " + err.Error())
+		return 1
+	}
+	return 0
+}
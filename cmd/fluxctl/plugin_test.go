@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupPlugin(t *testing.T, script string) func() {
+	dir, err := ioutil.TempDir("", "fluxctl-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "fluxctl-hello"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestFindPlugin(t *testing.T) {
+	defer setupPlugin(t, "exit 0\n")()
+
+	rootCmd := newRoot().Command()
+	path, args, ok := findPlugin(rootCmd, []string{"--url", "http://example.com/api/flux", "hello", "--name", "world"})
+	if !ok {
+		t.Fatal("expected plugin to be found")
+	}
+	if filepath.Base(path) != "fluxctl-hello" {
+		t.Errorf("expected fluxctl-hello, got %q", path)
+	}
+	if len(args) != 2 || args[0] != "--name" || args[1] != "world" {
+		t.Errorf("expected plugin flags to be passed on, got %v", args)
+	}
+
+	for _, args := range [][]string{
+		{"list-controllers"},
+		{"--help"},
+		{"help", "hello"},
+		{"goodbye"},
+	} {
+		if _, _, ok := findPlugin(newRoot().Command(), args); ok {
+			t.Errorf("%v: expected no plugin to be run", args)
+		}
+	}
+}
+
+func TestRunPlugin(t *testing.T) {
+	defer setupPlugin(t, `[ "$FLUX_URL" = "http://example.com/api/flux" ] || exit 2
+[ "$FLUX_SERVICE_TOKEN" = "secret" ] || exit 2
+[ "$1" = "world" ] || exit 2
+exit 3
+`)()
+
+	if code := run([]string{"--url", "http://example.com/api/flux", "--token", "secret", "hello", "world"}); code != 3 {
+		t.Errorf("expected the plugin's exit code 3, got %d", code)
+	}
+}
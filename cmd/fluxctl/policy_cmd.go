@@ -19,9 +19,13 @@ type controllerPolicyOpts struct {
 	controller string
 	tagAll     string
 	tags       []string
+	pins       []string
+	unpins     []string
 
 	automate, deautomate bool
 	lock, unlock         bool
+	lockUntil            string
+	observe, unobserve   bool
 
 	cause update.Cause
 
@@ -46,12 +50,17 @@ Surrounding these with single-quotes are recommended to avoid shell expansion.
 
 If both --tag-all and --tag are specified, --tag-all will apply to all
 containers which aren't explicitly named.
+
+Pins must be specified as 'container=tag'. A pinned container is kept at
+the tag it's pinned to by automation, until it's unpinned.
         `,
 		Example: makeExample(
 			"fluxctl policy --controller=default:deployment/foo --automate",
 			"fluxctl policy --controller=default:deployment/foo --lock",
+			"fluxctl policy --controller=default:deployment/foo --observe",
 			"fluxctl policy --controller=default:deployment/foo --tag='bar=1.*' --tag='baz=2.*'",
 			"fluxctl policy --controller=default:deployment/foo --tag-all='master-*' --tag='bar=1.*'",
+			"fluxctl policy --controller=default:deployment/foo --pin='bar=v1.4.2'",
 		),
 		RunE: opts.RunE,
 	}
@@ -63,10 +72,15 @@ containers which aren't explicitly named.
 	flags.StringVarP(&opts.controller, "controller", "c", "", "Controller to modify")
 	flags.StringVar(&opts.tagAll, "tag-all", "", "Tag filter pattern to apply to all containers")
 	flags.StringSliceVar(&opts.tags, "tag", nil, "Tag filter container/pattern pairs")
+	flags.StringSliceVar(&opts.pins, "pin", nil, "Container/tag pairs, pinning each container to the tag")
+	flags.StringSliceVar(&opts.unpins, "unpin", nil, "Containers to unpin")
 	flags.BoolVar(&opts.automate, "automate", false, "Automate controller")
 	flags.BoolVar(&opts.deautomate, "deautomate", false, "Deautomate controller")
 	flags.BoolVar(&opts.lock, "lock", false, "Lock controller")
 	flags.BoolVar(&opts.unlock, "unlock", false, "Unlock controller")
+	flags.StringVar(&opts.lockUntil, "until-revision", "", "When locking, the revision the controller is meant to stay locked until, e.g., that of a fix yet to be merged")
+	flags.BoolVar(&opts.observe, "observe", false, "Only observe automation of controller, recording what would be released")
+	flags.BoolVar(&opts.unobserve, "unobserve", false, "Stop only observing automation of controller")
 
 	// Deprecated
 	flags.StringVarP(&opts.service, "service", "s", "", "Service to modify")
@@ -91,6 +105,12 @@ func (opts *controllerPolicyOpts) RunE(cmd *cobra.Command, args []string) error
 	if opts.lock && opts.unlock {
 		return newUsageError("lock and unlock both specified")
 	}
+	if opts.observe && opts.unobserve {
+		return newUsageError("observe and unobserve both specified")
+	}
+	if opts.lockUntil != "" && !opts.lock {
+		return newUsageError("--until-revision is only for locking")
+	}
 
 	resourceID, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, opts.controller)
 	if err != nil {
@@ -129,6 +149,9 @@ func calculatePolicyChanges(opts *controllerPolicyOpts) (policy.Update, error) {
 				Set(policy.LockedUser, opts.cause.User).
 				Set(policy.LockedMsg, opts.cause.Message)
 		}
+		if opts.lockUntil != "" {
+			add = add.Set(policy.LockedUntil, opts.lockUntil)
+		}
 	}
 
 	remove := policy.Set{}
@@ -139,7 +162,14 @@ func calculatePolicyChanges(opts *controllerPolicyOpts) (policy.Update, error) {
 		remove = remove.
 			Add(policy.Locked).
 			Add(policy.LockedMsg).
-			Add(policy.LockedUser)
+			Add(policy.LockedUser).
+			Add(policy.LockedUntil)
+	}
+	if opts.observe {
+		add = add.Add(policy.Observe)
+	}
+	if opts.unobserve {
+		remove = remove.Add(policy.Observe)
 	}
 	if opts.tagAll != "" {
 		add = add.Set(policy.TagAll, policy.NewPattern(opts.tagAll).String())
@@ -159,6 +189,17 @@ func calculatePolicyChanges(opts *controllerPolicyOpts) (policy.Update, error) {
 		}
 	}
 
+	for _, pinPair := range opts.pins {
+		parts := strings.Split(pinPair, "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return policy.Update{}, fmt.Errorf("invalid container/tag pair: %q. Expected format is 'container=tag'", pinPair)
+		}
+		add = add.Set(policy.PinPrefix(parts[0]), parts[1])
+	}
+	for _, container := range opts.unpins {
+		remove = remove.Add(policy.PinPrefix(container))
+	}
+
 	return policy.Update{
 		Add:    add,
 		Remove: remove,
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/api/v26"
+)
+
+type reportOpts struct {
+	*rootOpts
+	namespace string
+	period    time.Duration
+	since     string
+	until     string
+	format    string
+	verifyKey string
+}
+
+func newReport(parent *rootOpts) *reportOpts {
+	return &reportOpts{rootOpts: parent}
+}
+
+func (opts *reportOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report the releases, policy changes, approvals and failures in a period, e.g., for auditors.",
+		Long: `
+Report the releases (including automated releases, rollbacks and
+restarts), policy changes and failures in a period, and the
+acknowledgements of them, from the events the daemon keeps. If the
+daemon has a signing key, the report is signed with it; given the
+public key with --verify-key, the signature is checked before the
+report is written.
+`,
+		Example: makeExample(
+			"fluxctl report --format=html > report.html",
+			"fluxctl report --since=2018-09-01 --until=2018-10-01 --namespace=payments --format=pdf > september.pdf",
+			"fluxctl report --verify-key=report-key.pub --format=json",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "", "Only report on changes to workloads in this namespace")
+	cmd.Flags().DurationVar(&opts.period, "period", 7*24*time.Hour, "Report on this long, back from --until, if --since isn't given")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Start of the period, as a date (2006-01-02) or RFC3339 time")
+	cmd.Flags().StringVar(&opts.until, "until", "", "End of the period, as a date (2006-01-02) or RFC3339 time; by default, now")
+	cmd.Flags().StringVar(&opts.format, "format", "json", "Output format; one of json, html or pdf")
+	cmd.Flags().StringVar(&opts.verifyKey, "verify-key", "", "File with the ed25519 public key (PEM-encoded, or base64) the report must be signed with")
+	return cmd
+}
+
+func (opts *reportOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	switch opts.format {
+	case "json", "html", "pdf":
+	default:
+		return newUsageError("--format must be one of json, html or pdf")
+	}
+	var verifyKey ed25519.PublicKey
+	if opts.verifyKey != "" {
+		data, err := ioutil.ReadFile(opts.verifyKey)
+		if err != nil {
+			return err
+		}
+		if verifyKey, err = v26.ParseReportPublicKey(data); err != nil {
+			return newUsageError("--verify-key: " + err.Error())
+		}
+	}
+	until := time.Now().UTC()
+	if opts.until != "" {
+		t, err := parseReportTime(opts.until)
+		if err != nil {
+			return newUsageError("--until: " + err.Error())
+		}
+		until = t
+	}
+	since := until.Add(-opts.period)
+	if opts.since != "" {
+		t, err := parseReportTime(opts.since)
+		if err != nil {
+			return newUsageError("--since: " + err.Error())
+		}
+		since = t
+	}
+	if !since.Before(until) {
+		return newUsageError("the start of the period must be before the end")
+	}
+
+	report, err := opts.API.ComplianceReport(context.Background(), v26.ComplianceReportOptions{
+		Since:     since,
+		Until:     until,
+		Namespace: opts.namespace,
+	})
+	if err != nil {
+		return err
+	}
+	if verifyKey != nil {
+		if !v26.VerifyReport(report, verifyKey) {
+			return errors.New("the report isn't signed with the key in " + opts.verifyKey)
+		}
+		fmt.Fprintln(cmd.OutOrStderr(), "Report signature verified")
+	}
+	return writeComplianceReport(cmd.OutOrStdout(), opts.format, report)
+}
+
+func parseReportTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func writeComplianceReport(out io.Writer, format string, report v26.ComplianceReport) error {
+	switch format {
+	case "html":
+		return complianceReportHTML.Execute(out, report)
+	case "pdf":
+		return writeComplianceReportPDF(out, report)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+var complianceReportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Flux change report, {{time .Since}} to {{time .Until}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Flux change report</h1>
+<p>From {{time .Since}} to {{time .Until}}{{with .Namespace}}, namespace {{.}}{{end}}; generated {{time .GeneratedAt}}.</p>
+{{define "events"}}{{if .}}<table>
+<tr><th>Event</th><th>Time</th><th>Type</th><th>Workloads</th><th>User</th><th>Message</th></tr>
+{{range .}}<tr><td>#{{.EventID}}</td><td>{{time .Time}}</td><td>{{.Type}}</td><td>{{range .Workloads}}{{.}}<br>{{end}}</td><td>{{.User}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>
+{{end}}{{end}}
+<h2>Releases</h2>
+{{template "events" .Releases}}
+<h2>Policy changes</h2>
+{{template "events" .PolicyChanges}}
+<h2>Approvals</h2>
+{{if .Approvals}}<table>
+<tr><th>Event</th><th>Time</th><th>User</th><th>Comment</th></tr>
+{{range .Approvals}}<tr><td>#{{.EventID}}</td><td>{{time .Time}}</td><td>{{.User}}</td><td>{{.Comment}}</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>
+{{end}}
+<h2>Failures</h2>
+{{template "events" .Failures}}
+{{if .Signature}}<p>Signed with ed25519 public key <code>{{.PublicKey}}</code>; signature of the JSON report: <code>{{.Signature}}</code></p>
+{{else}}<p>Unsigned.</p>
+{{end}}
+</body>
+</html>
+`))
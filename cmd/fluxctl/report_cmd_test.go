@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/event"
+	transport "github.com/weaveworks/flux/http"
+)
+
+func complianceReportFixture() v26.ComplianceReport {
+	at := time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC)
+	return v26.ComplianceReport{
+		Since: time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2018, 9, 8, 0, 0, 0, 0, time.UTC),
+		Releases: []v26.ReportedEvent{
+			{EventID: 12, Type: event.EventRelease, Time: at, Workloads: []flux.ResourceID{flux.MustParseResourceID("default:deployment/hello")}, User: "jane", Message: "Released: <script> to default:deployment/hello"},
+		},
+		Approvals: []v26.ReportedApproval{{EventID: 12, Time: at, User: "sam", Comment: "change 1234"}},
+	}
+}
+
+func TestComplianceReportJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeComplianceReport(out, "json", complianceReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	var got v26.ComplianceReport
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if expected := complianceReportFixture(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestComplianceReportHTML(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := writeComplianceReport(out, "html", complianceReportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{"default:deployment/hello", "change 1234", "&lt;script&gt;", "Unsigned"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected the report to include %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestComplianceReportPDF(t *testing.T) {
+	report := complianceReportFixture()
+	// Enough to go over a page
+	for i := 0; i < 40; i++ {
+		report.Failures = append(report.Failures, v26.ReportedEvent{EventID: event.EventID(100 + i), Type: event.EventSync, Message: "sync failed (exit status 1)"})
+	}
+	out := &bytes.Buffer{}
+	if err := writeComplianceReport(out, "pdf", report); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("expected a PDF header and trailer")
+	}
+	for _, want := range []string{"/Count 2", "(Page 2 of 2)", "(      default:deployment/hello)", "by jane", "change 1234", `sync failed \(exit status 1\)`, "(  Unsigned.)"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("expected the PDF to include %q", want)
+		}
+	}
+
+	// The cross-reference table must point at each object, and
+	// startxref at the table
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if startxref == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref doesn't point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) != 9 {
+		t.Errorf("expected nine objects for two pages, got %d", len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("xref entry %d doesn't point at its object", i+1)
+		}
+	}
+}
+
+func TestReportCommand_VerifyKey(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := complianceReportFixture()
+	if err := v26.SignReport(&signed, private); err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "flux-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "report-key.pub")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := signed
+	tampered.Failures = nil
+	tampered.Releases = nil
+	for _, c := range []struct {
+		report  v26.ComplianceReport
+		success bool
+	}{
+		{signed, true},
+		{tampered, false},
+		{complianceReportFixture(), false},
+	} {
+		svc := newMockService()
+		svc.mockResponses[transport.NewAPIRouter().Get(transport.ComplianceReport)] = c.report
+		cmd := newReport(mockServiceOpts(svc)).Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs([]string{"--verify-key=" + keyFile})
+		if err := cmd.Execute(); (err == nil) != c.success {
+			t.Errorf("expected success to be %v, got error %v", c.success, err)
+		}
+	}
+}
+
+func TestReportCommand_InputFailures(t *testing.T) {
+	for _, args := range [][]string{
+		{"extra"},
+		{"--format=docx"},
+		{"--since=yesterday"},
+		{"--since=2018-09-08", "--until=2018-09-01"},
+	} {
+		cmd := newReport(mockServiceOpts(newMockService())).Command()
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected error with args %v", args)
+		} else if _, ok := err.(usageError); !ok && args[0] != "extra" {
+			t.Errorf("expected a usage error with args %v, got %v", args, err)
+		}
+	}
+}
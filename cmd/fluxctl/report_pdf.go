@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/flux/api/v26"
+)
+
+// The PDF made is plain text, set in Courier on A4 pages, which is
+// all a change report needs, and keeps fluxctl from depending on a
+// PDF library.
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfFontSize     = 9
+	pdfLeading      = 11
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	// Courier's characters are 0.6em wide
+	pdfLineWidth = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6)
+)
+
+type pdfLine struct {
+	text string
+	bold bool
+}
+
+func writeComplianceReportPDF(out io.Writer, report v26.ComplianceReport) error {
+	return writeTextPDF(out, "Flux change report", complianceReportLines(report))
+}
+
+// complianceReportLines lays the report out as lines of text, with
+// the same sections as the HTML report.
+func complianceReportLines(report v26.ComplianceReport) []pdfLine {
+	at := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") }
+	var lines []pdfLine
+	add := func(bold bool, indent int, text string) {
+		for _, l := range wrapText(text, pdfLineWidth-indent) {
+			lines = append(lines, pdfLine{text: strings.Repeat(" ", indent) + l, bold: bold})
+		}
+	}
+
+	add(true, 0, "Flux change report")
+	period := "From " + at(report.Since) + " to " + at(report.Until)
+	if report.Namespace != "" {
+		period += ", namespace " + report.Namespace
+	}
+	add(false, 0, period+"; generated "+at(report.GeneratedAt)+".")
+
+	events := func(heading string, events []v26.ReportedEvent) {
+		add(false, 0, "")
+		add(true, 0, heading)
+		if len(events) == 0 {
+			add(false, 2, "None.")
+		}
+		for _, e := range events {
+			summary := fmt.Sprintf("#%d  %s  %s", e.EventID, at(e.Time), e.Type)
+			if e.User != "" {
+				summary += "  by " + e.User
+			}
+			add(false, 2, summary)
+			for _, w := range e.Workloads {
+				add(false, 6, w.String())
+			}
+			add(false, 6, e.Message)
+		}
+	}
+	events("Releases", report.Releases)
+	events("Policy changes", report.PolicyChanges)
+
+	add(false, 0, "")
+	add(true, 0, "Approvals")
+	if len(report.Approvals) == 0 {
+		add(false, 2, "None.")
+	}
+	for _, a := range report.Approvals {
+		approval := fmt.Sprintf("#%d  %s", a.EventID, at(a.Time))
+		if a.User != "" {
+			approval += "  by " + a.User
+		}
+		add(false, 2, approval)
+		if a.Comment != "" {
+			add(false, 6, a.Comment)
+		}
+	}
+	events("Failures", report.Failures)
+
+	add(false, 0, "")
+	add(true, 0, "Signature")
+	if report.Signature == "" {
+		add(false, 2, "Unsigned.")
+	} else {
+		add(false, 2, "ed25519 public key:")
+		add(false, 4, report.PublicKey)
+		add(false, 2, "Signature of the JSON report:")
+		add(false, 4, report.Signature)
+	}
+	return lines
+}
+
+// wrapText breaks the text into lines at most width characters long,
+// at spaces where possible.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, word[:width])
+				word = word[width:]
+			}
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// writeTextPDF writes the lines as a PDF document, with page numbers.
+func writeTextPDF(out io.Writer, title string, lines []pdfLine) error {
+	var pages [][]pdfLine
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	buf := &bytes.Buffer{}
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// The catalog, page tree, fonts and document info come first, then
+	// each page followed by its contents
+	const firstPage = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title %s /Producer (fluxctl) >>", pdfString(title)))
+
+	for i, page := range pages {
+		content := &bytes.Buffer{}
+		fmt.Fprintf(content, "BT\n%d TL\n%d %d Td\n", pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, l := range page {
+			font := "F1"
+			if l.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(content, "/%s %d Tf %s Tj T*\n", font, pdfFontSize, pdfString(l.text))
+		}
+		fmt.Fprintf(content, "ET\nBT\n/F1 %d Tf %d %d Td %s Tj\nET\n", pdfFontSize, pdfMargin, pdfMargin/2, pdfString(fmt.Sprintf("Page %d of %d", i+1, len(pages))))
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := buf.WriteTo(out)
+	return err
+}
+
+// pdfString gives the text as a PDF literal string, in the fonts'
+// encoding; characters outside Latin-1 become question marks.
+func pdfString(text string) string {
+	s := []byte{'('}
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			s = append(s, '\\', byte(r))
+		case r >= ' ' && r <= '~', r >= 0xa0 && r <= 0xff:
+			s = append(s, byte(r))
+		default:
+			s = append(s, '?')
+		}
+	}
+	return string(append(s, ')'))
+}
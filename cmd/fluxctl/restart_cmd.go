@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+type restartOpts struct {
+	*rootOpts
+	namespace string
+	workloads []string
+	reason    string
+	dryRun    bool
+	force     bool
+	outputOpts
+	cause update.Cause
+}
+
+func newRestart(parent *rootOpts) *restartOpts {
+	return &restartOpts{rootOpts: parent}
+}
+
+func (opts *restartOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart workloads, rolling out new pods without changing their images.",
+		Long: `
+Restart the workloads given, as kubectl rollout restart does: new pods
+are rolled out for them, without changing their images or anything
+else about them. This is useful after changing a config map or secret
+the workloads read only when they start. Nothing is committed to git;
+the restart is recorded as a restart event.
+`,
+		Example: makeExample(
+			`fluxctl restart --workload=default:deployment/helloworld --reason="rotated credentials"`,
+			"fluxctl restart -n prod --workload=deployment/api --workload=deployment/worker --dry-run",
+		),
+		RunE: opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Workload namespace")
+	cmd.Flags().StringSliceVarP(&opts.workloads, "workload", "w", []string{}, "Workloads to restart <namespace>:<kind>/<name>")
+	cmd.Flags().StringVar(&opts.reason, "reason", "", "Why the workloads are being restarted, recorded in the event")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Do not restart anything; just report back what would have been done")
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "Disregard any release freeze")
+	return cmd
+}
+
+func (opts *restartOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errorWantedNoArgs
+	}
+	if len(opts.workloads) == 0 {
+		return newUsageError("-w, --workload is required")
+	}
+	var workloads []flux.ResourceID
+	for _, w := range opts.workloads {
+		id, err := flux.ParseResourceIDOptionalNamespace(opts.namespace, w)
+		if err != nil {
+			return err
+		}
+		workloads = append(workloads, id)
+	}
+
+	kind := update.ReleaseKindExecute
+	if opts.dryRun {
+		kind = update.ReleaseKindPlan
+		fmt.Fprintf(cmd.OutOrStderr(), "Submitting dry-run restart...\n")
+	} else {
+		fmt.Fprintf(cmd.OutOrStderr(), "Submitting restart...\n")
+	}
+
+	ctx := context.Background()
+	jobID, err := opts.API.UpdateManifests(ctx, update.Spec{
+		Type:  update.Restart,
+		Cause: opts.cause,
+		Spec: update.RestartSpec{
+			Workloads: workloads,
+			Reason:    opts.reason,
+			Kind:      kind,
+			Force:     opts.force,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return await(ctx, cmd.OutOrStdout(), cmd.OutOrStderr(), opts.API, jobID, false, opts.verbosity)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestRestartCommand(t *testing.T) {
+	svc := newMockService()
+	cmd := newRestart(mockServiceOpts(svc)).Command()
+	cmd.SetOutput(ioutil.Discard)
+	cmd.SetArgs([]string{"-n", "prod", "--workload=deployment/api", "--workload=default:deployment/web", "--reason=rotated credentials"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := svc.calledRequest("UpdateManifests")
+	if r == nil {
+		t.Fatal("expected fluxctl to request UpdateManifests")
+	}
+	var spec update.Spec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	expected := update.RestartSpec{
+		Workloads: []flux.ResourceID{
+			flux.MustParseResourceID("prod:deployment/api"),
+			flux.MustParseResourceID("default:deployment/web"),
+		},
+		Reason: "rotated credentials",
+		Kind:   update.ReleaseKindExecute,
+	}
+	if spec.Type != update.Restart || !reflect.DeepEqual(spec.Spec, expected) {
+		t.Errorf("expected restart spec %#v, got %#v", expected, spec)
+	}
+}
+
+func TestRestartCommand_InputFailures(t *testing.T) {
+	for _, args := range [][]string{{}, {"deployment/api"}, {"--workload=not a workload"}} {
+		cmd := newRestart(mockServiceOpts(newMockService())).Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected error with args %v", args)
+		}
+	}
+}
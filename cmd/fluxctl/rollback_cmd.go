@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/update"
+)
+
+type rollbackOpts struct {
+	*rootOpts
+	reason string
+	dryRun bool
+	force  bool
+	outputOpts
+	cause update.Cause
+}
+
+func newRollback(parent *rootOpts) *rollbackOpts {
+	return &rollbackOpts{rootOpts: parent}
+}
+
+func (opts *rollbackOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <event ID>",
+		Short: "Roll back a release, setting the images it changed back to what they were.",
+		Long: `
+Roll back the release recorded in the event given (as listed by
+fluxctl events). Each container the release changed is set back to
+the image it had before, unless it's been released again since. The
+rollback is committed to git, and recorded as a rollback event.
+`,
+		Example: makeExample(
+			`fluxctl rollback 42 --reason="errors after upgrade"`,
+			"fluxctl rollback 42 --dry-run",
+		),
+		RunE: opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().StringVar(&opts.reason, "reason", "", "Why the release is being rolled back, recorded in the commit and the event")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Do not roll back anything; just report back what would have been done")
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "Disregard locks, and any release freeze")
+	return cmd
+}
+
+func (opts *rollbackOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return newUsageError("expected the ID of the release event to roll back")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return newUsageError(fmt.Sprintf("expected an event ID, as shown by fluxctl events, got %q", args[0]))
+	}
+
+	kind := update.ReleaseKindExecute
+	if opts.dryRun {
+		kind = update.ReleaseKindPlan
+		fmt.Fprintf(cmd.OutOrStderr(), "Submitting dry-run rollback...\n")
+	} else {
+		fmt.Fprintf(cmd.OutOrStderr(), "Submitting rollback...\n")
+	}
+
+	ctx := context.Background()
+	jobID, err := opts.API.UpdateManifests(ctx, update.Spec{
+		Type:  update.Rollback,
+		Cause: opts.cause,
+		Spec: update.RollbackSpec{
+			ReleaseID: id,
+			Reason:    opts.reason,
+			Kind:      kind,
+			Force:     opts.force,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return await(ctx, cmd.OutOrStdout(), cmd.OutOrStderr(), opts.API, jobID, !opts.dryRun, opts.verbosity)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/flux/update"
+)
+
+func TestRollbackCommand(t *testing.T) {
+	svc := newMockService()
+	cmd := newRollback(mockServiceOpts(svc)).Command()
+	cmd.SetOutput(ioutil.Discard)
+	cmd.SetArgs([]string{"42", "--reason=errors after upgrade"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := svc.calledRequest("UpdateManifests")
+	if r == nil {
+		t.Fatal("expected fluxctl to request UpdateManifests")
+	}
+	var spec update.Spec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	expected := update.RollbackSpec{
+		ReleaseID: 42,
+		Reason:    "errors after upgrade",
+		Kind:      update.ReleaseKindExecute,
+	}
+	if spec.Type != update.Rollback || !reflect.DeepEqual(spec.Spec, expected) {
+		t.Errorf("expected rollback spec %#v, got %#v", expected, spec)
+	}
+}
+
+func TestRollbackCommand_InputFailures(t *testing.T) {
+	for _, args := range [][]string{{}, {"forty-two"}, {"42", "43"}} {
+		cmd := newRollback(mockServiceOpts(newMockService())).Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected error with args %v", args)
+		}
+	}
+}
@@ -21,6 +21,7 @@ type rootOpts struct {
 	URL       string
 	Token     string
 	Namespace string
+	IDToken   string
 	API       api.Server
 }
 
@@ -42,10 +43,20 @@ Connecting:
   # To a Weave Cloud instance, with your instance token in $TOKEN
   fluxctl --token $TOKEN list-controllers
 
+  # To a fluxd that authenticates using an OpenID Connect provider
+  fluxctl login --oidc-issuer-url=https://accounts.example.com --oidc-client-id=fluxctl
+  fluxctl list-controllers
+
 Workflow:
   fluxctl list-controllers                                                   # Which controllers are running?
   fluxctl list-images --controller=default:deployment/foo                    # Which images are running/available?
   fluxctl release --controller=default:deployment/foo --update-image=bar:v2  # Release new version.
+
+Plugins:
+  Any executable named fluxctl-<name> on your PATH can be run as
+  "fluxctl <name>"; it is given the connection to the API in the
+  environment variables FLUX_URL, and FLUX_SERVICE_TOKEN or
+  FLUX_ID_TOKEN.
 `)
 
 const (
@@ -74,18 +85,38 @@ func (opts *rootOpts) Command() *cobra.Command {
 
 	cmd.AddCommand(
 		newVersionCommand(),
+		newInstall().Command(),
 		newServiceList(opts).Command(),
 		newControllerShow(opts).Command(),
 		newControllerList(opts).Command(),
 		newControllerRelease(opts).Command(),
+		newRollback(opts).Command(),
+		newRestart(opts).Command(),
 		newServiceAutomate(opts).Command(),
 		newControllerDeautomate(opts).Command(),
 		newControllerLock(opts).Command(),
 		newControllerUnlock(opts).Command(),
+		newContainerPin(opts).Command(),
+		newContainerUnpin(opts).Command(),
 		newControllerPolicy(opts).Command(),
 		newSave(opts).Command(),
+		newExportCluster(opts).Command(),
 		newIdentity(opts).Command(),
 		newSync(opts).Command(),
+		newEvents(opts).Command(),
+		newHistory(opts).Command(),
+		newExportEvents(opts).Command(),
+		newWorkloadHistory(opts).Command(),
+		newJobQueue(opts).Command(),
+		newCheckWorkload(opts).Command(),
+		newImages(opts).Command(),
+		newDeliveryReport(opts).Command(),
+		newReport(opts).Command(),
+		newOutOfSync(opts).Command(),
+		newDeployedAt(opts).Command(),
+		newDiff(opts).Command(),
+		newMetrics(opts).Command(),
+		newLogin(opts).Command(),
 	)
 
 	return cmd
@@ -94,13 +125,18 @@ func (opts *rootOpts) Command() *cobra.Command {
 func (opts *rootOpts) PersistentPreRunE(cmd *cobra.Command, _ []string) error {
 	// skip port forward for version command
 	switch cmd.Use {
-	case "version":
+	case "version", "login", "install":
 		return nil
 	}
+	return opts.connect(cmd.Flags())
+}
 
-	opts.Namespace = getFromEnvIfNotSet(cmd.Flags(), "k8s-fwd-ns", opts.Namespace, envVariableNamespace)
-	opts.Token = getFromEnvIfNotSet(cmd.Flags(), "token", opts.Token, envVariableToken, envVariableCloudToken)
-	opts.URL = getFromEnvIfNotSet(cmd.Flags(), "url", opts.URL, envVariableURL)
+// connect works out where the API is, from the flags given and the
+// environment, and sets up the client for it.
+func (opts *rootOpts) connect(flags *pflag.FlagSet) error {
+	opts.Namespace = getFromEnvIfNotSet(flags, "k8s-fwd-ns", opts.Namespace, envVariableNamespace)
+	opts.Token = getFromEnvIfNotSet(flags, "token", opts.Token, envVariableToken, envVariableCloudToken)
+	opts.URL = getFromEnvIfNotSet(flags, "url", opts.URL, envVariableURL)
 
 	if opts.Token != "" && opts.URL == "" {
 		opts.URL = defaultURLGivenToken
@@ -131,7 +167,13 @@ func (opts *rootOpts) PersistentPreRunE(cmd *cobra.Command, _ []string) error {
 		return errors.Wrapf(err, "parsing URL")
 	}
 
-	opts.API = client.New(http.DefaultClient, transport.NewAPIRouter(), opts.URL, client.Token(opts.Token))
+	var creds client.Credentials = client.Token(opts.Token)
+	if opts.Token == "" {
+		// Use the token from `fluxctl login`, if there is one
+		opts.IDToken = readIDToken()
+		creds = client.BearerToken(opts.IDToken)
+	}
+	opts.API = client.New(http.DefaultClient, transport.NewAPIRouter(), opts.URL, creds)
 	return nil
 }
 
@@ -12,6 +12,7 @@ import (
 
 type syncOpts struct {
 	*rootOpts
+	confirm string
 }
 
 func newSync(parent *rootOpts) *syncOpts {
@@ -24,6 +25,7 @@ func (opts *syncOpts) Command() *cobra.Command {
 		Short: "synchronize the cluster with the git repository, now",
 		RunE:  opts.RunE,
 	}
+	cmd.Flags().StringVar(&opts.confirm, "confirm", "", "confirm the sync of a revision held back for making too many changes")
 	return cmd
 }
 
@@ -45,6 +47,9 @@ func (opts *syncOpts) RunE(cmd *cobra.Command, args []string) error {
 	case git.RepoReady:
 		break
 	default:
+		if p := gitConfig.Progress; p != nil && !p.Done {
+			return fmt.Errorf("git repository %s is not ready to sync (status: %s; %s)", gitConfig.Remote.URL, string(gitConfig.Status), p)
+		}
 		return fmt.Errorf("git repository %s is not ready to sync (status: %s)", gitConfig.Remote.URL, string(gitConfig.Status))
 	}
 
@@ -52,7 +57,7 @@ func (opts *syncOpts) RunE(cmd *cobra.Command, args []string) error {
 
 	updateSpec := update.Spec{
 		Type: update.Sync,
-		Spec: update.ManualSync{},
+		Spec: update.ManualSync{Confirm: opts.confirm},
 	}
 	jobID, err := opts.API.UpdateManifests(ctx, updateSpec)
 	if err != nil {
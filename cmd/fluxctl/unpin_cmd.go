@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux/update"
+)
+
+type containerUnpinOpts struct {
+	*rootOpts
+	namespace string
+	workload  string
+	container string
+	outputOpts
+	cause update.Cause
+}
+
+func newContainerUnpin(parent *rootOpts) *containerUnpinOpts {
+	return &containerUnpinOpts{rootOpts: parent}
+}
+
+func (opts *containerUnpinOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unpin",
+		Short: "Unpin a container, so automation can update it again.",
+		Example: makeExample(
+			"fluxctl unpin --workload=default:deployment/helloworld --container=app",
+		),
+		RunE: opts.RunE,
+	}
+	AddOutputFlags(cmd, &opts.outputOpts)
+	AddCauseFlags(cmd, &opts.cause)
+	cmd.Flags().StringVarP(&opts.namespace, "namespace", "n", "default", "Workload namespace")
+	cmd.Flags().StringVarP(&opts.workload, "workload", "w", "", "Workload to unpin a container of")
+	cmd.Flags().StringVar(&opts.container, "container", "", "Container to unpin")
+	return cmd
+}
+
+func (opts *containerUnpinOpts) RunE(cmd *cobra.Command, args []string) error {
+	if opts.workload == "" {
+		return newUsageError("-w, --workload is required")
+	}
+	if opts.container == "" {
+		return newUsageError("--container is required")
+	}
+
+	policyOpts := &controllerPolicyOpts{
+		rootOpts:   opts.rootOpts,
+		outputOpts: opts.outputOpts,
+		namespace:  opts.namespace,
+		controller: opts.workload,
+		cause:      opts.cause,
+		unpins:     []string{opts.container},
+	}
+	return policyOpts.RunE(cmd, args)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v28"
+)
+
+type workloadHistoryOpts struct {
+	*rootOpts
+	since string
+}
+
+func newWorkloadHistory(parent *rootOpts) *workloadHistoryOpts {
+	return &workloadHistoryOpts{rootOpts: parent}
+}
+
+func (opts *workloadHistoryOpts) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workload-history <namespace>/<name>",
+		Short: "Show a timeline of a workload's releases, images, locks and policy changes.",
+		Long: `
+Show a timeline of the changes made to one workload: its releases, the
+images each container was given and how long each ran, how long it was
+locked or automated, and other changes to its policies. The workload
+can be given as <namespace>/<name>, or as <namespace>:<kind>/<name> if
+there's more than one workload with that name in the namespace.
+`,
+		Example: makeExample(
+			"fluxctl workload-history default/helloworld",
+			"fluxctl workload-history default:deployment/helloworld --since=168h",
+		),
+		RunE: opts.RunE,
+	}
+	cmd.Flags().StringVar(&opts.since, "since", "", "Only show changes since this time, in RFC3339 format (e.g., 2026-10-14T03:12:00Z), or as a duration ago (e.g., 168h)")
+	return cmd
+}
+
+func (opts *workloadHistoryOpts) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return newUsageError("expected the workload, as <namespace>/<name>")
+	}
+	now := time.Now()
+	since, err := parseAt(opts.since, now)
+	if err != nil {
+		return newUsageError(err.Error())
+	}
+
+	ctx := context.Background()
+	id, err := opts.resolveWorkload(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	history, err := opts.API.WorkloadHistory(ctx, v28.WorkloadHistoryOptions{
+		Workload: id,
+		Since:    since,
+	})
+	if err != nil {
+		return err
+	}
+	writeWorkloadHistory(os.Stdout, history, now)
+	return nil
+}
+
+// resolveWorkload gives the ID of the workload named, either in full
+// (<namespace>:<kind>/<name>), or as <namespace>/<name>, in which case
+// the workloads in the namespace are looked through for the name.
+func (opts *workloadHistoryOpts) resolveWorkload(ctx context.Context, arg string) (flux.ResourceID, error) {
+	if flux.ResourceIDRegexp.MatchString(arg) {
+		return flux.ParseResourceID(arg)
+	}
+	parts := strings.Split(arg, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return flux.ResourceID{}, newUsageError(fmt.Sprintf("expected the workload as <namespace>/<name> or <namespace>:<kind>/<name>, got %q", arg))
+	}
+	namespace, name := parts[0], parts[1]
+	controllers, err := opts.API.ListServices(ctx, namespace)
+	if err != nil {
+		return flux.ResourceID{}, err
+	}
+	var found []flux.ResourceID
+	for _, c := range controllers {
+		if _, _, n := c.ID.Components(); n == name {
+			found = append(found, c.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return flux.ResourceID{}, fmt.Errorf("no workload named %q in namespace %q", name, namespace)
+	case 1:
+		return found[0], nil
+	}
+	var ids []string
+	for _, id := range found {
+		ids = append(ids, id.String())
+	}
+	return flux.ResourceID{}, newUsageError(fmt.Sprintf("more than one workload is named %q in namespace %q; give one of %s", name, namespace, strings.Join(ids, ", ")))
+}
+
+func writeWorkloadHistory(out io.Writer, history v28.WorkloadHistory, now time.Time) {
+	fmt.Fprintln(out, history.Workload)
+	if len(history.Entries) == 0 {
+		fmt.Fprintln(out, "No changes recorded")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tDURATION\tKIND\tCHANGE")
+	for _, e := range history.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Start.Format(time.RFC3339), entryDuration(e, now), e.Kind, entryChange(e))
+	}
+	w.Flush()
+}
+
+// entryDuration gives how long the entry lasted, or for images, locks
+// and automation that are still the case, how long so far.
+func entryDuration(e v28.WorkloadHistoryEntry, now time.Time) string {
+	if e.End != nil {
+		return roundDuration(e.End.Sub(e.Start)).String()
+	}
+	switch e.Kind {
+	case v28.HistoryImage, v28.HistoryLock, v28.HistoryAutomation:
+		return roundDuration(now.Sub(e.Start)).String() + " (ongoing)"
+	}
+	return ""
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Minute {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Minute)
+}
+
+func entryChange(e v28.WorkloadHistoryEntry) string {
+	change := e.Message
+	if e.Kind == v28.HistoryImage {
+		change = fmt.Sprintf("%s: %s -> %s", e.Container, e.From, e.To)
+	}
+	if e.User != "" {
+		change += " (" + e.User + ")"
+	}
+	return change
+}
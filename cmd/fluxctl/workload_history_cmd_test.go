@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v6"
+	transport "github.com/weaveworks/flux/http"
+)
+
+func newWorkloadHistoryService() *genericMockRoundTripper {
+	return &genericMockRoundTripper{
+		mockResponses: map[*mux.Route]interface{}{
+			transport.NewAPIRouter().Get("ListServices"): []v6.ControllerStatus{
+				{ID: flux.MustParseResourceID("default:deployment/hello")},
+				{ID: flux.MustParseResourceID("default:deployment/other")},
+				{ID: flux.MustParseResourceID("default:deployment/twin")},
+				{ID: flux.MustParseResourceID("default:statefulset/twin")},
+			},
+			transport.NewAPIRouter().Get("WorkloadHistory"): v28.WorkloadHistory{},
+		},
+		requestHistory: make(map[string]*http.Request),
+	}
+}
+
+func TestWorkloadHistoryCommand(t *testing.T) {
+	for arg, expected := range map[string]string{
+		"default/hello":                "default:deployment/hello",
+		"default:statefulset/twin":     "default:statefulset/twin",
+		"default:deployment/not-known": "default:deployment/not-known",
+	} {
+		svc := newWorkloadHistoryService()
+		cmd := newWorkloadHistory(mockServiceOpts(svc)).Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs([]string{arg})
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("%s: %s", arg, err)
+			continue
+		}
+		r := svc.calledRequest("WorkloadHistory")
+		if r == nil {
+			t.Fatalf("%s: expected fluxctl to request WorkloadHistory", arg)
+		}
+		if got := r.URL.Query().Get("workload"); got != expected {
+			t.Errorf("%s: expected history of %s, got %s", arg, expected, got)
+		}
+	}
+}
+
+func TestWorkloadHistoryCommand_InputFailures(t *testing.T) {
+	for _, args := range [][]string{{}, {"hello"}, {"default/nothing"}, {"default/twin"}, {"default/hello", "--since=yesterday"}} {
+		cmd := newWorkloadHistory(mockServiceOpts(newWorkloadHistoryService())).Command()
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected error with args %v", args)
+		}
+	}
+}
+
+func TestWriteWorkloadHistory(t *testing.T) {
+	start := time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC)
+	lockEnded := start.Add(90 * time.Minute)
+	history := v28.WorkloadHistory{
+		Workload: flux.MustParseResourceID("default:deployment/hello"),
+		Entries: []v28.WorkloadHistoryEntry{
+			{Kind: v28.HistoryImage, Start: start, Container: "greeter", From: "hello:1", To: "hello:2", User: "jane"},
+			{Kind: v28.HistoryLock, Start: start, End: &lockEnded, Message: "Locked: default:deployment/hello"},
+		},
+	}
+	out := &bytes.Buffer{}
+	writeWorkloadHistory(out, history, start.Add(3*time.Hour))
+	for _, expected := range []string{
+		"default:deployment/hello",
+		"3h0m0s (ongoing)  image  greeter: hello:1 -> hello:2 (jane)",
+		"1h30m0s           lock   Locked: default:deployment/hello",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	_ "net/http/pprof"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -18,25 +23,43 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/pflag"
 	k8sifclient "github.com/weaveworks/flux/integrations/client/clientset/versioned"
+	"k8s.io/client-go/dynamic"
 	k8sclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/auth"
 	"github.com/weaveworks/flux/checkpoint"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/cluster/kubernetes"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
 	"github.com/weaveworks/flux/daemon"
+	"github.com/weaveworks/flux/event"
+	_ "github.com/weaveworks/flux/event/sqlite"
+	"github.com/weaveworks/flux/freeze"
 	"github.com/weaveworks/flux/git"
 	transport "github.com/weaveworks/flux/http"
 	"github.com/weaveworks/flux/http/client"
 	daemonhttp "github.com/weaveworks/flux/http/daemon"
+	"github.com/weaveworks/flux/http/webhook"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/integrations/helm/chartrepo"
 	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/notify"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/registry"
 	"github.com/weaveworks/flux/registry/cache"
 	registryMemcache "github.com/weaveworks/flux/registry/cache/memcached"
 	registryMiddleware "github.com/weaveworks/flux/registry/middleware"
+	"github.com/weaveworks/flux/release"
 	"github.com/weaveworks/flux/remote"
+	"github.com/weaveworks/flux/scm"
+	"github.com/weaveworks/flux/source"
 	"github.com/weaveworks/flux/ssh"
+	"github.com/weaveworks/flux/supplychain"
+	fluxsync "github.com/weaveworks/flux/sync"
+	"github.com/weaveworks/flux/tracing"
 )
 
 var version = "unversioned"
@@ -62,6 +85,58 @@ func optionalVar(fs *pflag.FlagSet, value ssh.OptionalValue, name, usage string)
 	return value
 }
 
+type extraRepoSpec struct {
+	url      string
+	branch   string
+	paths    []string
+	interval time.Duration
+}
+
+// parseExtraRepo reads the value of --git-extra-repo, which is the
+// URL of a git repo followed by options in the manner of a query
+// string.
+func parseExtraRepo(s, defaultBranch string, defaultInterval time.Duration) (extraRepoSpec, error) {
+	spec := extraRepoSpec{url: s, branch: defaultBranch, interval: defaultInterval}
+	i := strings.LastIndex(s, "?")
+	if i < 0 {
+		return spec, nil
+	}
+	spec.url = s[:i]
+	opts, err := url.ParseQuery(s[i+1:])
+	if err != nil {
+		return spec, fmt.Errorf("parsing options of --git-extra-repo %q: %v", s, err)
+	}
+	for name, values := range opts {
+		switch name {
+		case "branch":
+			spec.branch = values[len(values)-1]
+		case "path":
+			spec.paths = values
+		case "poll-interval":
+			if spec.interval, err = time.ParseDuration(values[len(values)-1]); err != nil {
+				return spec, fmt.Errorf("parsing poll-interval of --git-extra-repo %q: %v", s, err)
+			}
+		default:
+			return spec, fmt.Errorf("unknown option %q in --git-extra-repo %q; expected branch, path or poll-interval", name, s)
+		}
+	}
+	if spec.url == "" {
+		return spec, fmt.Errorf("no URL given in --git-extra-repo %q", s)
+	}
+	return spec, nil
+}
+
+// gitRemote gives the remote for the git repo at the URL, with the
+// credentials given if it's served over HTTP(S); other repos (e.g.,
+// using SSH) don't get them.
+func gitRemote(repoURL string, creds, submoduleCreds git.CredentialsProvider) git.Remote {
+	r := git.Remote{URL: repoURL, SubmoduleCredentials: submoduleCreds}
+	if strings.HasPrefix(repoURL, "https://") || strings.HasPrefix(repoURL, "http://") {
+		r.Credentials = creds
+	}
+	return r
+}
+
 func main() {
 	// Flag domain.
 	fs := pflag.NewFlagSet("default", pflag.ContinueOnError)
@@ -91,8 +166,36 @@ func main() {
 		gitNotesRef    = fs.String("git-notes-ref", defaultGitNotesRef, "ref to use for keeping commit annotations in git notes")
 		gitSkip        = fs.Bool("git-ci-skip", false, `append "[ci skip]" to commit messages so that CI will skip builds`)
 		gitSkipMessage = fs.String("git-ci-skip-message", "", "additional text for commit messages, useful for skipping builds in CI. Use this to supply specific text, or set --git-ci-skip")
+		gitSecretScan  = fs.String("git-secret-scan", git.SecretScanWarn, "scan changes for things that look like credentials before committing them; 'warn' lists any found in the commit message, 'refuse' doesn't commit the change, and 'off' doesn't scan")
+
+		gitSkipSyncMarkers = fs.StringSlice("git-skip-sync-marker", []string{"[flux skip]", "[skip flux]"}, "a commit with this in its message (ignoring case) is recorded, but not synced until there's a commit after it without any marker, with the commit before it synced meanwhile; may be given more than once, and given as empty to sync every commit. Not used with --git-sync-from-tags")
+
+		gitPreserveFormatting = fs.Bool("git-preserve-formatting", true, "when updating an image in a manifest, change only the image value where possible, so comments, key order and quoting are left as they were; if false, the whole of the resource is rewritten")
+
+		manifestJsonnet       = fs.Bool("manifest-jsonnet", false, "evaluate .jsonnet files in the git repo, using the jsonnet executable, and sync the resources they result in; these resources cannot have their images or policies updated, since there's no manifest to write to")
+		gitExtraRepos         = fs.StringArray("git-extra-repo", nil, "also sync the manifests in this git repo, as <url>?branch=<branch>&path=<path>&poll-interval=<duration>, with the branch and poll interval defaulting to --git-branch and --git-poll-interval, and path given more than once for more than one path; may be given more than once. If an extra repo can't be fetched or loaded, what was last synced from it is synced again, and the sync fails only if it hasn't been synced since fluxd started. Releases and policy changes are only made in the --git-url repo")
+		gitExtraRepoHostLimit = fs.Int("git-extra-repo-host-limit", 4, "the most clones and fetches of the extra git repos to make at once from any one git host; 0 means no limit. The polls of the extra repos are also staggered across their poll interval")
+		manifestStore         = fs.String("manifest-store", "", "fetch the manifests to sync from here rather than the git repo: oci://<image ref> for an OCI artifact (using the crane executable), or s3://<bucket>/<key> for a tarball in S3 (using the aws executable); --git-path gives the paths within it. Releases and policy changes still need a git repo")
+		manifestCache         = fs.Int("manifest-cache-revisions", daemon.DefaultManifestCacheSize, "keep the resources loaded from the git repo at this many of the most recent revisions, so they aren't parsed (or generated) again for each sync, release or comparison at the same revision; 0 means don't cache")
 
 		gitPollInterval = fs.Duration("git-poll-interval", 5*time.Minute, "period at which to poll git repo for new commits")
+		gitCloneDepth   = fs.Int("git-clone-depth", 0, "clone the git repo with only this many commits of history, to save time and space with a big repo; commits fetched after that are kept. 0 means clone all of the history")
+		gitSyncFromTags = fs.String("git-sync-from-tags", "", "sync the latest tag that matches this pattern, rather than the head of --git-branch; e.g., 'prod-*' for the most recent of those tags, or 'semver:~1' for the highest version. Releases and policy changes are still committed to --git-branch")
+		gitSparse       = fs.Bool("git-sparse-checkout", false, "check out only the --git-path paths when working with the git repo, rather than all of it")
+		gitTimeout      = fs.Duration("git-timeout", git.DefaultTimeout, "how long each try at fetching from or pushing to the git repo is given before it's abandoned")
+		gitCloneTimeout = fs.Duration("git-clone-timeout", git.DefaultCloneTimeout, "how long each try at cloning the git repo is given before it's abandoned")
+		gitRetries      = fs.Int("git-retries", git.DefaultRetries, "how many more times to try cloning, fetching or pushing, when it fails or times out in a way that may not last; 0 means don't try again")
+		gitRetryBackoff = fs.Duration("git-retry-backoff", git.DefaultRetryBackoff, "how long to wait before trying a git operation again the first time; it's twice as long each time after, up to a minute")
+		gitTreePool     = fs.Int("git-tree-pool-size", git.DefaultTreePoolSize, "keep this many working trees of the git repo for reading manifests from (e.g., to list workloads), so those needn't wait for, or make, a clone of their own; 0 means make a new tree each time")
+
+		gitHTTPSUsername     = fs.String("git-https-username", "git", "username to give, along with the token, to a git repo served over HTTPS")
+		gitHTTPSTokenFile    = fs.String("git-https-token-file", "", "read the token (or password) for a git repo served over HTTPS from this file, each time it's needed, so it can be updated (e.g., a mounted secret) without restarting")
+		gitHTTPSTokenCommand = fs.String("git-https-token-command", "", "run this command, with sh -c, each time a token for a git repo served over HTTPS is needed, and use what it prints; e.g., to get a GitHub App installation token, or a cloud provider's access token")
+
+		gitSubmodules          = fs.Bool("git-submodules", false, "check out the submodules of the git repo, and theirs, along with it; e.g., for manifests kept in a shared repo that's included as a submodule")
+		gitSubmoduleTokenFiles = fs.StringArray("git-submodule-token-file", nil, "read the token for the submodules served over HTTPS whose URLs start with a prefix from a file, given as <url prefix>=<path>; may be given more than once. Other submodules on the same host as the git repo are given its token")
+
+		gitWebhookSecretFile = fs.String("git-webhook-secret-file", "", "receive push webhooks from GitHub, GitLab or Bitbucket at /hooks/git, with the secret in this file, and fetch and sync straight away when the branch synced is pushed to")
 		// syncing
 		syncInterval = fs.Duration("sync-interval", 5*time.Minute, "apply config in git to cluster at least this often, even if there are no new commits")
 		// registry
@@ -104,22 +207,145 @@ func main() {
 		registryBurst        = fs.Int("registry-burst", defaultRemoteConnections, "maximum number of warmer connections to remote and memcache")
 		registryTrace        = fs.Bool("registry-trace", false, "output trace of image registry requests to log")
 		registryInsecure     = fs.StringSlice("registry-insecure-host", []string{}, "use HTTP for this image registry domain (e.g., registry.cluster.local), instead of HTTPS")
+		verifyCosign         = fs.Bool("registry-verify-cosign", false, "only automatically release images with a cosign signature that can be verified (requires the cosign executable)")
+		verifyCosignKey      = fs.String("registry-verify-cosign-key", "", "public key (a path, or KMS URI) that signatures must be made with; if not given, keyless verification is done")
+		verifyCosignIdentity = fs.String("registry-verify-cosign-identity", "", "for keyless verification, the identity that must be in the signing certificate")
+		verifyCosignIssuer   = fs.String("registry-verify-cosign-oidc-issuer", "", "for keyless verification, the OIDC issuer that must be in the signing certificate")
+		verifyProvenance     = fs.Bool("registry-verify-provenance", false, "also require a verifiable SLSA provenance attestation before automatically releasing an image")
+		releaseSBOM          = fs.Bool("release-sbom", false, "record in release events where to find the SBOM (as attached with cosign) for each image released")
+		releaseGateTimeout   = fs.Duration("release-gate-timeout", 10*time.Second, "how long to wait for a workload's release gate to respond before treating the release as denied")
+		releaseGateAllow     = fs.StringSlice("release-gate-allow", []string{}, "URL under which workloads' release gates may be, e.g., https://change.example.com/flux/; a gate must have the same scheme and host, and a path below it. May be given more than once; gates not allowed by any are treated as denying the release")
+		releasePullCheck     = fs.Bool("release-pull-check", false, "before committing an automated release, check that each image can be pulled with the credentials its workloads use, and is for an architecture that can be run")
+		registryRewrite      = fs.StringSlice("registry-rewrite", []string{}, "rewrite image names when releasing, as <from>=<to>, e.g., docker.io/*=harbor.internal/proxy/* to use a mirror; the first matching rule is used")
+		registryPromote      = fs.StringSlice("registry-promote", []string{}, "promote images from one registry to another before releasing them, as <from>=<to>, e.g., staging.example.com/*=prod.example.com/*; new images for workloads using the <to> images are looked for in the <from> registry, and copied over when released")
+		registryTagTimestamp = fs.StringSlice("registry-tag-timestamp", []string{}, "read when images were built from their tags, rather than fetching each image's manifest, as <image>=<tag>, e.g., example.com/app=master-{20060102.1504}-* with the timestamp given as a Go time layout in braces; the first rule for an image is used")
+		registryPromoteTool  = fs.String("registry-promote-tool", "crane", "tool used to copy images when promoting them: crane or skopeo (the executable must be available)")
 
 		// k8s-secret backed ssh keyring configuration
 		k8sSecretName            = fs.String("k8s-secret-name", "flux-git-deploy", "Name of the k8s secret used to store the private SSH key")
 		k8sSecretVolumeMountPath = fs.String("k8s-secret-volume-mount-path", "/etc/fluxd/ssh", "Mount location of the k8s secret storing the private SSH key")
 		k8sSecretDataKey         = fs.String("k8s-secret-data-key", "identity", "Data key holding the private SSH key within the k8s secret")
 		k8sNamespaceWhitelist    = fs.StringSlice("k8s-namespace-whitelist", []string{}, "Experimental, optional: restrict the view of the cluster to the namespaces listed. All namespaces are included if this is not set.")
+		k8sShardCount            = fs.Int("k8s-shard-count", 1, "Experimental, optional: number of daemon instances the cluster's namespaces are shared among")
+		k8sShardIndex            = fs.Int("k8s-shard-index", 0, "Experimental, optional: the shard (from 0 to --k8s-shard-count - 1) this daemon is responsible for")
+		k8sShardAssign           = fs.StringSlice("k8s-shard-assign", []string{}, "Experimental, optional: explicitly assign a namespace to a shard, as <namespace>=<index>, rather than by hashing its name")
+		k8sImagePaths            = fs.StringSlice("k8s-image-path", []string{}, "Optional: treat resources of a kind flux doesn't otherwise know about, e.g., a custom resource, as workloads with an image at the path given, as <apiVersion>/<Kind>=<path>, e.g., example.com/v1/Widget=spec.image; give a kind more than once for more than one path")
+		k8sStateConfigMap        = fs.String("k8s-state-configmap", "", "Optional: name of a config map, in the daemon's namespace, in which to remember its version and configuration between runs, so that upgrades and configuration changes are recorded in its start events; created if it doesn't exist")
 		// SSH key generation
 		sshKeyBits   = optionalVar(fs, &ssh.KeyBitsValue{}, "ssh-keygen-bits", "-b argument to ssh-keygen (default unspecified)")
 		sshKeyType   = optionalVar(fs, &ssh.KeyTypeValue{}, "ssh-keygen-type", "-t argument to ssh-keygen (default unspecified)")
 		sshKeygenDir = fs.String("ssh-keygen-dir", "", "directory, ideally on a tmpfs volume, in which to generate new SSH keys when necessary")
 
-		upstreamURL = fs.String("connect", "", "Connect to an upstream service e.g., Weave Cloud, at this base address")
-		token       = fs.String("token", "", "Authentication token for upstream service")
+		upstreamURL                 = fs.String("connect", "", "Connect to an upstream service e.g., Weave Cloud, at this base address")
+		token                       = fs.String("token", "", "Authentication token for upstream service")
+		eventThrottleWindow         = fs.Duration("event-throttle-window", time.Hour, "send an event reporting the same errors (e.g., a sync failing the same way) upstream at most once in this period, with a count of the repeats; 0 to send every one")
+		eventStoreURL               = fs.String("event-store", "memory:", "URL of the store in which to keep events for listing with fluxctl events; memory: (or memory:?size=<n>) keeps the most recent in memory, sqlite://<path> keeps them in a SQLite database file (in builds with the tag sqlite), and other stores can be registered with event.RegisterStore")
+		eventRetentionMaxAge        = fs.Duration("event-retention-max-age", 0, "if given, prune events older than this (e.g., 720h) from the event store, every ten minutes")
+		eventRetentionMaxPerService = fs.Int("event-retention-max-per-service", 0, "if given, prune events from the event store once there are this many more recent events for each workload they concern")
+		eventHashChain              = fs.Bool("event-hash-chain", false, "record in each event kept the hash of the event before, so that changes to the history can be detected; the hashes are HMACs with the key in $FLUX_EVENT_CHAIN_KEY, which must be set")
+		eventHashChainHeadInterval  = fs.Duration("event-hash-chain-head-interval", time.Hour, "with --event-hash-chain, log an event giving the head of the chain this often, so it can be kept somewhere other than the event store; 0 to not log it")
+		eventEscalateAfter          = fs.Int("event-escalate-after", 10, "after this many failures of the same kind in a row for a workload (e.g., its sync failing), log one error event with the count, and no more until it stops failing; 0 to log every failure as it is")
+
+		// digests
+		digestPeriod    = fs.Duration("digest-period", 0, "if given, send a summary of releases, policy changes and errors in each namespace this often (e.g., 24h or 168h) to the Slack webhook and/or email addresses given")
+		digestSlackURL  = fs.String("digest-slack-url", "", "URL of a Slack incoming webhook to send digests to")
+		digestEmailTo   = fs.StringSlice("digest-email-to", []string{}, "email addresses to send digests to")
+		digestEmailFrom = fs.String("digest-email-from", "flux@localhost", "sender address for digest emails")
+		digestSMTPAddr  = fs.String("digest-smtp-addr", "localhost:25", "SMTP server (host:port) to send digest emails through")
+		digestSMTPUser  = fs.String("digest-smtp-user", "", "username for the SMTP server, if it needs authentication; the password is read from the environment variable FLUX_DIGEST_SMTP_PASSWORD")
+
+		// event webhooks
+		eventWebhookURLs    = fs.StringSlice("event-webhook-url", []string{}, "post each event, as JSON, to this URL; may be given more than once. If the environment variable FLUX_EVENT_WEBHOOK_SECRET is set, requests are signed with it, in the header X-Flux-Signature")
+		eventWebhookRetries = fs.Int("event-webhook-retries", 5, "how many times to try again to post an event to a webhook, backing off exponentially, before giving up on it")
+		eventWebhookConfig  = fs.String("event-webhook-config", "", "path to a YAML file of webhook subscribers, each with a name, URL, optional secret, and the event types, namespaces and minimum log level it wants events for; each event is posted with a cursor, in the header X-Flux-Cursor, from which a subscriber can replay the events it missed")
+
+		// release notes
+		releaseNotesURL       = fs.String("release-notes-url", "", "post a record of each release (the workloads and images changed, the commit and the cause), as JSON, to this URL, e.g., for a changelog. If the environment variable FLUX_RELEASE_NOTES_SECRET is set, requests are signed with it, in the header X-Flux-Signature")
+		releaseNotesCommitURL = fs.String("release-notes-commit-url", "", "link to each commit in release notes, at this URL with {revision} replaced by the commit's revision, e.g., https://github.com/example/config/commit/{revision}")
+
+		// compliance reports
+		complianceReportKey = fs.String("compliance-report-signing-key", "", "path to an ed25519 private key, PEM-encoded in PKCS #8 form (e.g., made with `openssl genpkey -algorithm ed25519`), to sign compliance reports with; the public key is logged at startup, and given in each report")
+
+		// event bus
+		eventBusURL     = fs.String("event-bus-url", "", "publish each event, as JSON, to the NATS server at this URL, e.g., nats://[user[:password]@]nats:4222, or tls://... to connect with TLS; give several, separated by commas, for a cluster")
+		eventBusSubject = fs.String("event-bus-subject", "flux.events", "publish events under this subject, followed by the event type; e.g., flux.events.release")
+		eventBusTLSCA   = fs.String("event-bus-tls-ca", "", "path to a PEM file of the CA certificate(s) to verify the NATS server with, if not the system's")
+		eventBusTLSCert = fs.String("event-bus-tls-cert", "", "path to a PEM file of the client certificate to present to the NATS server; give --event-bus-tls-key too")
+		eventBusTLSKey  = fs.String("event-bus-tls-key", "", "path to a PEM file of the key for --event-bus-tls-cert")
+
+		// stale images
+		staleImageAge    = fs.Duration("stale-image-age", 0, "if given, warn (with an event) about workloads running an image older than this (e.g., 720h), when there are newer images matching their tag filter")
+		staleImageNotify = fs.Bool("stale-image-notify", false, "also send stale image warnings straight away to the Slack webhook and/or email addresses given for digests")
+
+		// ownership
+		workloadOwnerKeys = fs.StringSlice("workload-owner-keys", []string{}, "annotations or labels giving the team that owns a workload, in order of preference (e.g., 'example.com/team,team'); the owner is reported in the API and in events")
+
+		// criticality
+		workloadCriticalityKey = fs.String("workload-criticality-key", "flux.weave.works/criticality", "annotation or label saying how critical a workload is (low, medium or high); warnings about high criticality workloads are raised to errors, and errors about low criticality workloads lowered to warnings. Set to empty to not look")
+		criticalNotify         = fs.Bool("critical-notify", false, "send errors affecting high criticality workloads straight away to the Slack webhook and/or email addresses given for digests")
+		notifyTemplates        = fs.String("notify-templates", "", "path of a YAML file of Go templates for the messages in critical alerts, by event type, to use instead of the default messages")
+
+		// release freezes
+		releaseFreezeCalendar = fs.String("release-freeze-calendar", "", "path or http(s) URL of a calendar of release freezes, either an iCalendar (each event is a freeze) or YAML; during a freeze, automated releases are suspended and other releases must be forced")
+		releaseFreezeRefresh  = fs.Duration("release-freeze-refresh", 10*time.Minute, "how often to reload the release freeze calendar")
+
+		// proposing automated releases in pull requests
+		automationPullRequests         = fs.String("automation-pull-requests", "", "push automated releases to a branch of their own and open a pull request for each with this kind of git host, github or gitlab, rather than committing them to --git-branch; the token is that given with --git-https-token-file or --git-https-token-command")
+		automationPullRequestsAPIURL   = fs.String("automation-pull-requests-api-url", "", "URL of the git host's API, for opening pull requests; if not given, it's worked out from --git-url")
+		automationPullRequestsGroup    = fs.String("automation-pull-requests-group", "", "how to group automated releases into pull requests: image, workload or namespace, for a pull request for each in each automation run; or interval, for a single pull request every --automation-pull-requests-interval. If not given, there's a pull request for each automation run")
+		automationPullRequestsInterval = fs.Duration("automation-pull-requests-interval", 24*time.Hour, "with --automation-pull-requests-group=interval, the least time between pull requests")
+
+		// observing automation
+		automationObserveOnly = fs.Bool("automation-observe-only", false, "only observe automation: record what would be released automatically as events, without committing anything; workloads can also be given the observe policy individually")
+
+		// backing off automation when busy
+		automationMaxQueue          = fs.Int("automation-max-queue", 0, "defer automated releases while at least this many jobs are queued, backing off for longer each time; 0 means don't")
+		automationMaxClusterLatency = fs.Duration("automation-max-cluster-latency", 0, "defer automated releases when listing the automated workloads from the cluster takes longer than this, backing off for longer each time; 0 means don't")
+		automationDeferWarn         = fs.Duration("automation-defer-warn", 30*time.Minute, "log a warning event when automated releases have been deferred for longer than this")
+		automationMaxFailures       = fs.Int("automation-max-failures", 0, "turn automation off for a workload when this many of its automated releases fail, or are rolled back, within --automation-failure-window; 0 means don't")
+		automationFailureWindow     = fs.Duration("automation-failure-window", time.Hour, "the period in which failures of automated releases are counted for --automation-max-failures")
+
+		// job queue
+		jobConcurrency = fs.Int("job-concurrency", 1, "how many jobs (releases, policy changes, and so on) to run at once; jobs concerning the same workload are always run one at a time, in the order they were asked for")
+		jobPriorities  = fs.StringSlice("job-priorities", []string{"auto=-1"}, "the priority of each kind of job, as <kind>=<priority>; jobs with a higher priority run first, and kinds not given have priority 0. Kinds are image (releases), auto (automated releases), containers, rollback, restart, policy, charts and sync")
+
+		// tracing
+		tracingEndpoint = fs.String("tracing-otlp-endpoint", "", "send spans for syncs, releases and commits to the OpenTelemetry collector (or Jaeger, Tempo, etc.) at this base URL, as OTLP over HTTP, e.g., http://otel-collector:4318; events are given the trace and span IDs, and a W3C traceparent given to the API is followed")
+		tracingService  = fs.String("tracing-service-name", "fluxd", "the service name to report spans under")
+
+		// holding back big syncs
+		syncMaxChanges = fs.Int("sync-max-changes", 0, "hold back a sync that would add or change more than this many resources, until it is confirmed with fluxctl sync --confirm; 0 means no limit")
+		syncConflicts  = fs.Bool("sync-conflicts", false, "before each sync, look for fields flux applies that something else (e.g., an autoscaler, an operator, or someone with kubectl) has since changed in the cluster, and record a warning event saying which field and what changed it")
+		syncMaxDeletes = fs.Int("sync-max-deletes", 0, "hold back a sync of a revision that removes more than this many resources from the repo, until it is confirmed with fluxctl sync --confirm; 0 means no limit")
+
+		// verifying commits
+		gitVerifySignatures    = fs.Bool("git-verify-signatures", false, "only sync commits that are signed by one of the GPG keys given with --git-verify-signatures-key (or if none are given, by any key imported); a sync including a commit that isn't is refused, and recorded as an error event")
+		gitVerifySignaturesKey = fs.StringSlice("git-verify-signatures-key", []string{}, "fingerprint (or long key ID) of a GPG key trusted to sign commits, when --git-verify-signatures is set; may be given more than once")
+		gitGPGKeyImport        = fs.StringSlice("git-gpg-key-import", []string{}, "import the GPG keys in this file, or in the files in this directory (e.g., a mounted secret), at startup, for verifying and signing commits")
+		gitSigningKey          = fs.String("git-signing-key", "", "sign the commits fluxd makes with this GPG key (which must be imported, e.g., with --git-gpg-key-import); needed for fluxd's own commits to be synced when --git-verify-signatures is set")
+
+		// checking there's room for releases
+		releaseCapacityCheck = fs.String("release-capacity-check", "off", "check, before committing a release, whether the cluster has room (by CPU and memory requested, against that allocatable on nodes) for the pods it will start while rolling out; 'warn' logs a warning and records the analysis with the release event, 'block' refuses releases that aren't forced")
+
+		// bootstrapping namespaces
+		bootstrapNamespaces      = fs.Bool("bootstrap-namespaces", false, "when resources in git are in a namespace that isn't defined in git, commit a manifest for the namespace (with a role binding and image pull secrets, if given) so it is synced before the resources in it")
+		bootstrapClusterRole     = fs.String("bootstrap-cluster-role", "", "cluster role to bind, in each namespace bootstrapped, to the service accounts given with --bootstrap-service-account")
+		bootstrapServiceAccounts = fs.StringSlice("bootstrap-service-account", []string{}, "service account, as <namespace>/<name>, that the cluster role given with --bootstrap-cluster-role is bound to in each namespace bootstrapped")
+		bootstrapPullSecrets     = fs.StringSlice("bootstrap-pull-secret", []string{}, "name of an image pull secret for the default service account of each namespace bootstrapped to use; the secret itself is not generated")
 
 		dockerConfig = fs.String("docker-config", "", "path to a docker config to use for image registry credentials")
 
+		// authentication
+		oidcIssuerURL   = fs.String("oidc-issuer-url", "", "URL of an OpenID Connect issuer; if given, API requests must present an ID token from this issuer")
+		oidcClientID    = fs.String("oidc-client-id", "", "client ID that ID tokens must be issued for (required with --oidc-issuer-url)")
+		oidcGroupsClaim = fs.String("oidc-groups-claim", "groups", "name of the ID token claim listing the groups a user belongs to")
+		// authorization
+		rbacConfig     = fs.String("rbac-config", "", "path to a file assigning roles to users and groups; if given, API requests are only allowed according to the roles assigned")
+		rbacKubernetes = fs.Bool("rbac-kubernetes", false, "decide whether API requests are allowed by asking the Kubernetes API server, using resource fluxapi in API group flux.weave.works")
+		// auditing
+		auditLog    = fs.String("audit-log", "", "path of a file to which a JSON record of each API call is appended; use - for stdout")
+		auditEvents = fs.Bool("audit-events", false, "record each API call that changes something (releases, policy changes, syncs and so on, but not reads) as an event, alongside the daemon's other events; --audit-log records every call")
+
 		_ = fs.Duration("registry-cache-expiry", 0, "")
 	)
 	fs.MarkDeprecated("registry-cache-expiry", "no longer used; cache entries are expired adaptively according to how often they change")
@@ -164,6 +390,21 @@ func main() {
 	if *gitSkipMessage == "" && *gitSkip {
 		*gitSkipMessage = defaultGitSkipMessage
 	}
+	// fluxd's own commits have to be synced, or releases would never
+	// make it to the cluster
+	for _, marker := range *gitSkipSyncMarkers {
+		if marker != "" && strings.Contains(strings.ToLower(*gitSkipMessage), strings.ToLower(marker)) {
+			logger.Log("err", fmt.Sprintf("--git-skip-sync-marker %q is in the message appended to fluxd's own commits (--git-ci-skip or --git-ci-skip-message), so they would never be synced", marker))
+			os.Exit(1)
+		}
+	}
+
+	switch *gitSecretScan {
+	case git.SecretScanOff, git.SecretScanWarn, git.SecretScanRefuse:
+	default:
+		logger.Log("err", "--git-secret-scan must be one of off, warn or refuse")
+		os.Exit(1)
+	}
 
 	for _, path := range *gitPath {
 		if len(path) > 0 && path[0] == '/' {
@@ -171,6 +412,168 @@ func main() {
 			os.Exit(1)
 		}
 	}
+	if len(*gitVerifySignaturesKey) > 0 && !*gitVerifySignatures {
+		logger.Log("err", "--git-verify-signatures-key is only used with --git-verify-signatures")
+		os.Exit(1)
+	}
+	if len(*gitGPGKeyImport) > 0 {
+		if err := git.ImportKeys(context.Background(), *gitGPGKeyImport); err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+	}
+
+	if *gitSparse && len(*gitPath) == 0 {
+		logger.Log("err", "--git-sparse-checkout needs the paths to check out given with --git-path")
+		os.Exit(1)
+	}
+	if *gitCloneDepth < 0 {
+		logger.Log("err", "--git-clone-depth must not be negative")
+		os.Exit(1)
+	}
+	if *gitRetries < 0 {
+		logger.Log("err", "--git-retries must not be negative")
+		os.Exit(1)
+	}
+	if *gitTreePool < 0 {
+		logger.Log("err", "--git-tree-pool-size must not be negative")
+		os.Exit(1)
+	}
+	var syncTags policy.Pattern
+	if *gitSyncFromTags != "" {
+		if syncTags = policy.NewPattern(*gitSyncFromTags); !syncTags.Valid() {
+			logger.Log("err", fmt.Sprintf("--git-sync-from-tags: invalid pattern %q", *gitSyncFromTags))
+			os.Exit(1)
+		}
+		if *bootstrapNamespaces {
+			logger.Log("err", "--bootstrap-namespaces can't be used with --git-sync-from-tags, since there's no branch to commit the namespaces to")
+			os.Exit(1)
+		}
+	}
+	var gitWebhookSecret []byte
+	if *gitWebhookSecretFile != "" {
+		secret, err := ioutil.ReadFile(*gitWebhookSecretFile)
+		if err != nil {
+			logger.Log("err", fmt.Errorf("reading --git-webhook-secret-file: %v", err))
+			os.Exit(1)
+		}
+		if gitWebhookSecret = bytes.TrimSpace(secret); len(gitWebhookSecret) == 0 {
+			logger.Log("err", "--git-webhook-secret-file is empty")
+			os.Exit(1)
+		}
+	}
+	var gitCredentials git.CredentialsProvider
+	switch {
+	case *gitHTTPSTokenFile != "" && *gitHTTPSTokenCommand != "":
+		logger.Log("err", "only one of --git-https-token-file and --git-https-token-command can be given")
+		os.Exit(1)
+	case *gitHTTPSTokenFile != "":
+		gitCredentials = git.TokenFile{Username: *gitHTTPSUsername, Path: *gitHTTPSTokenFile}
+	case *gitHTTPSTokenCommand != "":
+		gitCredentials = git.TokenCommand{Username: *gitHTTPSUsername, Command: *gitHTTPSTokenCommand}
+	}
+	var submoduleCredentials git.CredentialsProvider
+	if len(*gitSubmoduleTokenFiles) > 0 {
+		if !*gitSubmodules {
+			logger.Log("err", "--git-submodule-token-file can only be given along with --git-submodules")
+			os.Exit(1)
+		}
+		byURL := git.CredentialsByURL{}
+		for _, spec := range *gitSubmoduleTokenFiles {
+			i := strings.LastIndex(spec, "=")
+			if i <= 0 || i == len(spec)-1 {
+				logger.Log("err", fmt.Sprintf("--git-submodule-token-file %q is not <url prefix>=<path>", spec))
+				os.Exit(1)
+			}
+			byURL[spec[:i]] = git.TokenFile{Username: *gitHTTPSUsername, Path: spec[i+1:]}
+		}
+		submoduleCredentials = byURL
+	}
+
+	if *oidcIssuerURL != "" && *oidcClientID == "" {
+		logger.Log("err", "--oidc-client-id must be given along with --oidc-issuer-url")
+		os.Exit(1)
+	}
+
+	if (*rbacConfig != "" || *rbacKubernetes) && *oidcIssuerURL == "" {
+		logger.Log("err", "--rbac-config and --rbac-kubernetes need requests to be authenticated; supply --oidc-issuer-url")
+		os.Exit(1)
+	}
+	if *rbacConfig != "" && *rbacKubernetes {
+		logger.Log("err", "only one of --rbac-config and --rbac-kubernetes may be given")
+		os.Exit(1)
+	}
+
+	if *digestPeriod > 0 && *digestSlackURL == "" && len(*digestEmailTo) == 0 {
+		logger.Log("err", "--digest-period needs somewhere to send digests; supply --digest-slack-url and/or --digest-email-to")
+		os.Exit(1)
+	}
+
+	if *staleImageNotify && (*staleImageAge == 0 || (*digestSlackURL == "" && len(*digestEmailTo) == 0)) {
+		logger.Log("err", "--stale-image-notify needs --stale-image-age, and somewhere to send warnings; supply --digest-slack-url and/or --digest-email-to")
+		os.Exit(1)
+	}
+
+	if *criticalNotify && (*workloadCriticalityKey == "" || (*digestSlackURL == "" && len(*digestEmailTo) == 0)) {
+		logger.Log("err", "--critical-notify needs --workload-criticality-key, and somewhere to send alerts; supply --digest-slack-url and/or --digest-email-to")
+		os.Exit(1)
+	}
+
+	if *bootstrapClusterRole != "" && len(*bootstrapServiceAccounts) == 0 {
+		logger.Log("err", "--bootstrap-cluster-role needs service accounts to bind it to; supply --bootstrap-service-account")
+		os.Exit(1)
+	}
+
+	var imageRewrites image.RewriteRules
+	for _, s := range *registryRewrite {
+		rule, err := image.ParseRewriteRule(s)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		imageRewrites = append(imageRewrites, rule)
+	}
+
+	releaseGateURLs, err := release.ParseGateURLs(*releaseGateAllow)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+
+	var tagTimestamps image.TagTimestampRules
+	for _, s := range *registryTagTimestamp {
+		rule, err := image.ParseTagTimestampRule(s)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		tagTimestamps = append(tagTimestamps, rule)
+	}
+
+	// Promotion rules are given from the registry promoted from, but
+	// used to look up where the images in manifests come from
+	var promotionSources image.RewriteRules
+	for _, s := range *registryPromote {
+		rule, err := image.ParseRewriteRule(s)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		promotionSources = append(promotionSources, rule.Inverse())
+	}
+	// The image credentials are looked up once there's a cluster to
+	// look in, below
+	var imageCreds func() registry.ImageCreds
+	promotionCopier, err := supplychain.NewCopier(*registryPromoteTool, func() registry.ImageCreds {
+		if imageCreds == nil {
+			return registry.ImageCreds{}
+		}
+		return imageCreds()
+	})
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
 
 	if *sshKeygenDir == "" {
 		logger.Log("info", fmt.Sprintf("SSH keygen dir (--ssh-keygen-dir) not provided, so using the deploy key volume (--k8s-secret-volume-mount-path=%s); this may cause problems if the deploy key volume is mounted read-only", *k8sSecretVolumeMountPath))
@@ -181,8 +584,9 @@ func main() {
 	var clusterVersion string
 	var sshKeyRing ssh.KeyRing
 	var k8s cluster.Cluster
-	var imageCreds func() registry.ImageCreds
 	var k8sManifests cluster.Manifests
+	var authorizer auth.Authorizer
+	var stateStore daemon.StateStore
 	{
 		restClientConfig, err := rest.InClusterConfig()
 		if err != nil {
@@ -232,6 +636,13 @@ func main() {
 			os.Exit(1)
 		}
 
+		if *k8sStateConfigMap != "" {
+			stateStore = kubernetes.ConfigMapStateStore{
+				API:  clientset.Core().ConfigMaps(string(namespace)),
+				Name: *k8sStateConfigMap,
+			}
+		}
+
 		publicKey, privateKeyPath := sshKeyRing.KeyPair()
 
 		logger := log.With(logger, "component", "cluster")
@@ -252,7 +663,42 @@ func main() {
 		logger.Log("kubectl", kubectl)
 
 		kubectlApplier := kubernetes.NewKubectl(kubectl, restClientConfig)
-		k8sInst := kubernetes.NewCluster(clientset, ifclientset, kubectlApplier, sshKeyRing, logger, *k8sNamespaceWhitelist)
+		shardAssignments, err := kubernetes.ParseShardAssignments(*k8sShardAssign)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		shard := kubernetes.Shard{
+			Index:       *k8sShardIndex,
+			Count:       *k8sShardCount,
+			Assignments: shardAssignments,
+		}
+		if err := shard.Validate(); err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		if shard.Count > 1 {
+			logger.Log("shard", shard.Index, "shards", shard.Count)
+		}
+
+		workloadKinds, err := kresource.ParseWorkloadKinds(*k8sImagePaths)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+
+		k8sInst := kubernetes.NewCluster(clientset, ifclientset, kubectlApplier, sshKeyRing, logger, *k8sNamespaceWhitelist, shard)
+		if len(workloadKinds) > 0 {
+			dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+			if err != nil {
+				logger.Log("err", err)
+				os.Exit(1)
+			}
+			k8sInst.SetWorkloadKinds(dynamicClient, workloadKinds)
+			for _, k := range workloadKinds {
+				logger.Log("workload-kind", k.APIVersion+"/"+k.Kind, "image-paths", strings.Join(k.ImagePaths, ","))
+			}
+		}
 
 		if err := k8sInst.Ping(); err != nil {
 			logger.Log("ping", err)
@@ -269,15 +715,27 @@ func main() {
 				imageCreds = credsWithDefaults
 			}
 		}
+		if len(promotionSources) > 0 {
+			imageCreds = registry.ImageCredsWithSources(imageCreds, promotionSources)
+		}
 		k8s = k8sInst
+
+		if *rbacKubernetes {
+			authorizer = &auth.KubernetesAuthorizer{Client: clientset}
+		}
 		// There is only one way we currently interpret a repo of
 		// files as manifests, and that's as Kubernetes yamels.
-		k8sManifests = &kubernetes.Manifests{}
+		k8sManifests = &kubernetes.Manifests{
+			PreserveFormatting: *gitPreserveFormatting,
+			Jsonnet:            *manifestJsonnet,
+			Workloads:          workloadKinds,
+		}
 	}
 
 	// Registry components
 	var cacheRegistry registry.Registry
 	var cacheWarmer *cache.Warmer
+	var remoteFactory *registry.RemoteClientFactory
 	{
 		// Cache client, for use by registry and cache warmer
 		var cacheClient cache.Client
@@ -296,6 +754,9 @@ func main() {
 			Reader: cacheClient,
 		}
 		cacheRegistry = registry.NewInstrumentedRegistry(cacheRegistry)
+		if len(promotionSources) > 0 {
+			cacheRegistry = registry.NewPromotingRegistry(cacheRegistry, promotionSources)
+		}
 
 		// Remote client, for warmer to refresh entries
 		registryLogger := log.With(logger, "component", "registry")
@@ -304,7 +765,7 @@ func main() {
 			Burst:  *registryBurst,
 			Logger: log.With(logger, "component", "ratelimiter"),
 		}
-		remoteFactory := &registry.RemoteClientFactory{
+		remoteFactory = &registry.RemoteClientFactory{
 			Logger:        registryLogger,
 			Limiters:      registryLimits,
 			Trace:         *registryTrace,
@@ -336,11 +797,16 @@ func main() {
 		errc <- fmt.Errorf("%s", <-c)
 	}()
 
+	// This is set once there's a daemon to record stopping
+	stopped := func(error) {}
+
 	// This means we can return, and it will use the shutdown
 	// protocol.
 	defer func() {
 		// wait here until stopping.
-		logger.Log("exiting", <-errc)
+		reason := <-errc
+		logger.Log("exiting", reason)
+		stopped(reason)
 		close(shutdown)
 		shutdownWg.Wait()
 	}()
@@ -357,7 +823,7 @@ func main() {
 	}
 	checkpoint.CheckForUpdates(product, version, checkpointFlags, updateCheckLogger)
 
-	gitRemote := git.Remote{URL: *gitURL}
+	origin := gitRemote(*gitURL, gitCredentials, submoduleCredentials)
 	gitConfig := git.Config{
 		Paths:       *gitPath,
 		Branch:      *gitBranch,
@@ -367,9 +833,22 @@ func main() {
 		UserEmail:   *gitEmail,
 		SetAuthor:   *gitSetAuthor,
 		SkipMessage: *gitSkipMessage,
+		SecretScan:  *gitSecretScan,
+
+		SparseCheckout: *gitSparse,
+		SigningKey:     *gitSigningKey,
 	}
 
-	repo := git.NewRepo(gitRemote, git.PollInterval(*gitPollInterval))
+	// The same for each git repo
+	gitOptions := []git.Option{
+		git.Timeouts{Clone: *gitCloneTimeout, Fetch: *gitTimeout, Push: *gitTimeout},
+		git.Retries{Times: *gitRetries, Backoff: *gitRetryBackoff},
+	}
+	if *gitSubmodules {
+		gitOptions = append(gitOptions, git.Submodules)
+	}
+
+	repo := git.NewRepo(origin, append([]git.Option{git.PollInterval(*gitPollInterval), git.CloneDepth(*gitCloneDepth), git.TreePool(*gitTreePool)}, gitOptions...)...)
 	{
 		shutdownWg.Add(1)
 		go func() {
@@ -378,6 +857,29 @@ func main() {
 				errc <- err
 			}
 		}()
+		// Log each phase of cloning and fetching, so a long clone
+		// can be seen to be getting somewhere
+		go func() {
+			gitLogger := log.With(logger, "component", "git")
+			var last git.Progress
+			for {
+				select {
+				case <-shutdown:
+					return
+				case p := <-repo.ProgressC:
+					// fetching happens every poll, so only
+					// say when it's slow
+					if p.Operation == "fetch" && p.Error == "" && time.Since(p.Started) < time.Minute {
+						last = p
+						continue
+					}
+					if p.Operation != last.Operation || p.Phase != last.Phase || p.Done != last.Done {
+						gitLogger.Log("progress", p.String(), "elapsed", time.Since(p.Started).Round(time.Second))
+					}
+					last = p
+				}
+			}
+		}()
 	}
 
 	logger.Log(
@@ -389,11 +891,76 @@ func main() {
 		"set-author", *gitSetAuthor,
 	)
 
+	if *rbacConfig != "" {
+		config, err := auth.LoadRBACConfig(*rbacConfig)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		authorizer = config
+	}
+
+	var auditSinks []auth.AuditSink
+	switch *auditLog {
+	case "":
+	case "-":
+		auditSinks = append(auditSinks, auth.NewJSONAuditSink(os.Stdout))
+	default:
+		f, err := os.OpenFile(*auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditSinks = append(auditSinks, auth.NewJSONAuditSink(f))
+	}
+
 	var jobs *job.Queue
 	{
-		jobs = job.NewQueue(shutdown, shutdownWg)
+		if *jobConcurrency < 1 {
+			logger.Log("err", "--job-concurrency must be at least 1")
+			os.Exit(1)
+		}
+		priorities, err := job.ParsePriorities(*jobPriorities)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		jobs = job.NewQueueWithOptions(shutdown, shutdownWg, job.QueueOptions{
+			MaxRunning: *jobConcurrency,
+			Priorities: priorities,
+		})
+	}
+
+	config := map[string]string{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		config[f.Name] = f.Value.String()
+	})
+	lifecycle := daemon.Lifecycle{
+		Version: version,
+		Config:  config,
+		Store:   stateStore,
+	}
+
+	var resourceCache *daemon.ManifestCache
+	if *manifestCache > 0 {
+		resourceCache = &daemon.ManifestCache{Size: *manifestCache}
 	}
 
+	capacityCheck, err := daemon.ParseCapacityCheck(*releaseCapacityCheck)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+
+	switch *automationPullRequestsGroup {
+	case "", daemon.PullRequestsPerImage, daemon.PullRequestsPerWorkload, daemon.PullRequestsPerNamespace, daemon.PullRequestsPerInterval:
+	default:
+		logger.Log("err", fmt.Errorf("--automation-pull-requests-group: expected image, workload, namespace or interval, got %q", *automationPullRequestsGroup))
+		os.Exit(1)
+	}
+	extraRepoSchedule := &daemon.ExtraRepoSchedule{HostLimit: *gitExtraRepoHostLimit, Count: len(*gitExtraRepos)}
+
 	daemon := &daemon.Daemon{
 		V:              version,
 		Cluster:        k8s,
@@ -405,12 +972,145 @@ func main() {
 		Jobs:           jobs,
 		JobStatusCache: &job.StatusCache{Size: 100},
 		Logger:         log.With(logger, "component", "daemon"),
+		ImageRewrites:  imageRewrites,
+		ReleaseGate:    release.HTTPGate{Client: &http.Client{Timeout: *releaseGateTimeout}, Allowed: releaseGateURLs},
+		ChartRepos:     &chartrepo.Client{HTTP: &http.Client{}},
+		OwnerKeys:      *workloadOwnerKeys,
+		CriticalityKey: *workloadCriticalityKey,
+		ManifestCache:  resourceCache,
 		LoopVars: &daemon.LoopVars{
 			SyncInterval:         *syncInterval,
 			RegistryPollInterval: *registryPollInterval,
+			StaleImageAge:        *staleImageAge,
 		},
 	}
 
+	if *automationObserveOnly {
+		daemon.ObserveAutomation = true
+	}
+	if *automationPullRequests != "" {
+		provider, err := scm.NewProvider(*automationPullRequests, *gitURL, *automationPullRequestsAPIURL, gitCredentials)
+		if err != nil {
+			logger.Log("err", fmt.Errorf("--automation-pull-requests: %v", err))
+			os.Exit(1)
+		}
+		daemon.PullRequests = provider
+		daemon.PullRequestGrouping = *automationPullRequestsGroup
+		daemon.PullRequestInterval = *automationPullRequestsInterval
+	}
+	if *bootstrapNamespaces {
+		daemon.NamespaceBootstrap = &cluster.NamespaceBootstrap{
+			ClusterRole: *bootstrapClusterRole,
+			Subjects:    *bootstrapServiceAccounts,
+			PullSecrets: *bootstrapPullSecrets,
+		}
+	}
+	daemon.CapacityCheck = capacityCheck
+	daemon.SyncGuard = fluxsync.Guard{MaxChanges: *syncMaxChanges, MaxDeletes: *syncMaxDeletes}
+	daemon.VerifySignatures = *gitVerifySignatures
+	daemon.SyncTags = syncTags
+	daemon.SkipSyncMarkers = *gitSkipSyncMarkers
+	daemon.SignatureKeys = *gitVerifySignaturesKey
+	daemon.DetectSyncConflicts = *syncConflicts
+	daemon.AutomationBackoff.MaxQueue = *automationMaxQueue
+	daemon.AutomationBackoff.MaxClusterLatency = *automationMaxClusterLatency
+	daemon.AutomationBackoff.WarnAfter = *automationDeferWarn
+	daemon.AutomationBreaker.MaxFailures = *automationMaxFailures
+	daemon.AutomationBreaker.Window = *automationFailureWindow
+	if *releaseSBOM {
+		daemon.SBOMs = supplychain.CosignSBOMLocator{
+			Registry:    cacheRegistry,
+			Clients:     remoteFactory,
+			Credentials: imageCreds,
+		}
+	}
+	if *verifyCosign {
+		daemon.ImageVerifier = &supplychain.VerificationCache{
+			Verifier: supplychain.CosignVerifier{
+				Key:                   *verifyCosignKey,
+				CertificateIdentity:   *verifyCosignIdentity,
+				CertificateOIDCIssuer: *verifyCosignIssuer,
+				RequireProvenance:     *verifyProvenance,
+			},
+			RetryFailedAfter: *registryPollInterval,
+		}
+	}
+	if len(promotionSources) > 0 {
+		daemon.Promotion = &release.Promotion{
+			Sources: promotionSources,
+			Copier:  promotionCopier,
+		}
+		if daemon.ImageVerifier != nil {
+			daemon.Promotion.Verifier = daemon.ImageVerifier
+		}
+	}
+	if *manifestStore != "" {
+		store, err := source.Open(*manifestStore, *gitPath)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		daemon.ManifestStore = store
+	}
+	for _, extra := range *gitExtraRepos {
+		spec, err := parseExtraRepo(extra, *gitBranch, *gitPollInterval)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		extraRepoOptions := append([]git.Option{git.PollInterval(spec.interval), git.ReadOnly}, gitOptions...)
+		extraRepoOptions = append(extraRepoOptions, extraRepoSchedule.Options(spec.url, spec.interval)...)
+		extraRepo := git.NewRepo(gitRemote(spec.url, gitCredentials, submoduleCredentials), extraRepoOptions...)
+		shutdownWg.Add(1)
+		go func() {
+			if err := extraRepo.Start(shutdown, shutdownWg); err != nil {
+				errc <- err
+			}
+		}()
+		daemon.ExtraRepos = append(daemon.ExtraRepos, source.GitExport{Repo: extraRepo, Branch: spec.branch, Paths: spec.paths})
+		logger.Log("extra-git-url", spec.url, "branch", spec.branch, "paths", strings.Join(spec.paths, ","), "poll-interval", spec.interval)
+	}
+	if *tracingEndpoint != "" {
+		tracer := tracing.NewTracer(*tracingService,
+			tracing.NewOTLP(*tracingEndpoint, &http.Client{Timeout: 30 * time.Second}))
+		shutdownWg.Add(1)
+		go func() {
+			defer shutdownWg.Done()
+			<-shutdown
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				logger.Log("component", "tracing", "err", err)
+			}
+		}()
+		daemon.Tracer = tracer
+	}
+	if *releasePullCheck {
+		daemon.PullCheck = &release.PullCheck{
+			Clients:     remoteFactory,
+			Credentials: imageCreds,
+		}
+	}
+	if *releaseFreezeCalendar != "" {
+		calendar := &freeze.Calendar{
+			Source:  *releaseFreezeCalendar,
+			Refresh: *releaseFreezeRefresh,
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			Logger:  log.With(logger, "component", "freeze"),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := calendar.Load(ctx)
+		cancel()
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		shutdownWg.Add(1)
+		go calendar.Loop(shutdown, shutdownWg)
+		daemon.Freeze = calendar
+	}
+
+	var eventWriters event.Writers
 	{
 		// Connect to fluxsvc if given an upstream address
 		if *upstreamURL != "" {
@@ -429,7 +1129,7 @@ func main() {
 				logger.Log("err", err)
 				os.Exit(1)
 			}
-			daemon.EventWriter = upstream
+			eventWriters = append(eventWriters, &event.Throttle{Writer: upstream, Window: *eventThrottleWindow})
 			go func() {
 				<-shutdown
 				upstream.Close()
@@ -439,12 +1139,192 @@ func main() {
 		}
 	}
 
+	var senders []notify.Sender
+	if *digestSlackURL != "" {
+		senders = append(senders, notify.SlackSender{
+			WebhookURL: *digestSlackURL,
+			Client:     &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	if len(*digestEmailTo) > 0 {
+		senders = append(senders, notify.EmailSender{
+			Addr:     *digestSMTPAddr,
+			From:     *digestEmailFrom,
+			To:       *digestEmailTo,
+			Username: *digestSMTPUser,
+			Password: os.Getenv("FLUX_DIGEST_SMTP_PASSWORD"),
+		})
+	}
+	if *digestPeriod > 0 {
+		digest := &notify.Digest{
+			Period:  *digestPeriod,
+			Senders: senders,
+			Logger:  log.With(logger, "component", "digest"),
+		}
+		eventWriters = append(eventWriters, digest)
+		shutdownWg.Add(1)
+		go digest.Loop(shutdown, shutdownWg)
+	}
+	if *staleImageNotify {
+		eventWriters = append(eventWriters, notify.StaleImageAlert{
+			Senders: senders,
+			Logger:  log.With(logger, "component", "stale-images"),
+		})
+	}
+	if *criticalNotify {
+		alert := notify.CriticalAlert{
+			Senders: senders,
+			Logger:  log.With(logger, "component", "critical-alerts"),
+		}
+		if *notifyTemplates != "" {
+			renderer, err := notify.LoadMessageTemplates(*notifyTemplates)
+			if err != nil {
+				logger.Log("err", err)
+				os.Exit(1)
+			}
+			alert.Renderer = renderer
+		}
+		eventWriters = append(eventWriters, alert)
+	}
+	for _, u := range *eventWebhookURLs {
+		webhook := &notify.Webhook{
+			URL:     u,
+			Secret:  os.Getenv("FLUX_EVENT_WEBHOOK_SECRET"),
+			Retries: *eventWebhookRetries,
+			Client:  &http.Client{Timeout: 10 * time.Second},
+			Logger:  log.With(logger, "component", "event-webhook"),
+		}
+		eventWriters = append(eventWriters, webhook)
+		shutdownWg.Add(1)
+		go webhook.Loop(shutdown, shutdownWg)
+	}
+	if *eventWebhookConfig != "" {
+		subscribers, err := notify.LoadWebhookSubscribers(*eventWebhookConfig)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		daemon.EventSubscriptions = map[string]event.EventFilter{}
+		for _, s := range subscribers {
+			webhook := &notify.Webhook{
+				Name:    s.Name,
+				URL:     s.URL,
+				Secret:  s.Secret,
+				Filter:  s.Filter(),
+				Retries: *eventWebhookRetries,
+				Client:  &http.Client{Timeout: 10 * time.Second},
+				Logger:  log.With(logger, "component", "event-webhook", "subscriber", s.Name),
+			}
+			daemon.EventSubscriptions[s.Name] = webhook.Filter
+			eventWriters = append(eventWriters, webhook)
+			shutdownWg.Add(1)
+			go webhook.Loop(shutdown, shutdownWg)
+		}
+	}
+	// Compliance reports are signed, if there's a key to sign them with
+	if *complianceReportKey != "" {
+		data, err := ioutil.ReadFile(*complianceReportKey)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		key, err := v26.ParseReportSigningKey(data)
+		if err != nil {
+			logger.Log("err", fmt.Errorf("reading compliance report signing key %s: %s", *complianceReportKey, err))
+			os.Exit(1)
+		}
+		daemon.ReportKey = key
+		logger.Log("compliance-report-public-key", base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+	}
+	if *releaseNotesURL != "" {
+		webhook := &notify.Webhook{
+			URL:     *releaseNotesURL,
+			Secret:  os.Getenv("FLUX_RELEASE_NOTES_SECRET"),
+			Retries: *eventWebhookRetries,
+			Client:  &http.Client{Timeout: 10 * time.Second},
+			Logger:  log.With(logger, "component", "release-notes"),
+		}
+		eventWriters = append(eventWriters, notify.ReleaseNotes{
+			Webhook:   webhook,
+			CommitURL: *releaseNotesCommitURL,
+		})
+		shutdownWg.Add(1)
+		go webhook.Loop(shutdown, shutdownWg)
+	}
+	if *eventBusURL != "" {
+		busLogger := log.With(logger, "component", "event-bus")
+		bus := &notify.Bus{
+			Publisher: &notify.NATS{
+				URL:    *eventBusURL,
+				Name:   "fluxd",
+				CACert: *eventBusTLSCA,
+				Cert:   *eventBusTLSCert,
+				Key:    *eventBusTLSKey,
+				Logger: busLogger,
+			},
+			Subject: *eventBusSubject,
+			Logger:  busLogger,
+		}
+		eventWriters = append(eventWriters, bus)
+		shutdownWg.Add(1)
+		go bus.Loop(shutdown, shutdownWg)
+	}
+	if len(eventWriters) > 0 {
+		daemon.EventWriter = eventWriters
+	}
+	{
+		store, err := event.OpenStore(*eventStoreURL)
+		if err != nil {
+			logger.Log("component", "event-store", "err", err)
+			os.Exit(1)
+		}
+		if *eventHashChain {
+			// Pruning workload by workload would leave gaps in the
+			// chain, where pruning by age only cuts the oldest off
+			if *eventRetentionMaxPerService > 0 {
+				logger.Log("component", "event-store", "err", "--event-retention-max-per-service cannot be used with --event-hash-chain")
+				os.Exit(1)
+			}
+			key := []byte(os.Getenv("FLUX_EVENT_CHAIN_KEY"))
+			if store, err = event.ChainStore(store, key); err != nil {
+				logger.Log("component", "event-store", "err", "--event-hash-chain needs a key, in $FLUX_EVENT_CHAIN_KEY")
+				os.Exit(1)
+			}
+			daemon.EventChainKey = key
+			daemon.EventChainHeadInterval = *eventHashChainHeadInterval
+		}
+		daemon.EventStore = event.InstrumentStore(store)
+		daemon.EventRetention = event.RetentionPolicy{
+			MaxAge:        *eventRetentionMaxAge,
+			MaxPerService: *eventRetentionMaxPerService,
+		}
+		if *eventEscalateAfter > 0 {
+			daemon.EventEscalation = &event.Escalator{Threshold: *eventEscalateAfter}
+		}
+	}
+
+	lifecycle.Events = daemon
+	lifecycleLogger := log.With(logger, "component", "lifecycle")
+	lifecycle.Started(lifecycleLogger)
+	stopped = func(reason error) {
+		lifecycle.Stopped(reason, lifecycleLogger)
+	}
+
 	shutdownWg.Add(1)
 	go daemon.Loop(shutdown, shutdownWg, log.With(logger, "component", "sync-loop"))
+	if !daemon.EventRetention.IsZero() {
+		shutdownWg.Add(1)
+		go daemon.PruneLoop(shutdown, shutdownWg, log.With(logger, "component", "event-store"))
+	}
+	if len(daemon.EventChainKey) > 0 {
+		shutdownWg.Add(1)
+		go daemon.ChainHeadLoop(shutdown, shutdownWg, log.With(logger, "component", "event-store"))
+	}
 
 	cacheWarmer.Notify = daemon.AskForImagePoll
 	cacheWarmer.Priority = daemon.ImageRefresh
 	cacheWarmer.Trace = *registryTrace
+	cacheWarmer.TagTimestamps = tagTimestamps
 	shutdownWg.Add(1)
 	go cacheWarmer.Loop(log.With(logger, "component", "warmer"), shutdown, shutdownWg, imageCreds)
 
@@ -454,8 +1334,33 @@ func main() {
 		if *listenMetricsAddr == "" {
 			mux.Handle("/metrics", promhttp.Handler())
 		}
-		handler := daemonhttp.NewHandler(daemon, daemonhttp.NewRouter())
+		var apiServer api.Server = daemon
+		if authorizer != nil {
+			apiServer = auth.NewAuthorizingServer(apiServer, authorizer, daemon, log.With(logger, "component", "authorization"))
+		}
+		if *auditEvents {
+			auditSinks = append(auditSinks, auth.EventAuditSink{Events: daemon})
+		}
+		if len(auditSinks) > 0 {
+			apiServer = auth.NewAuditingServer(apiServer, log.With(logger, "component", "audit"), auditSinks...)
+		}
+		handler := tracing.Middleware(daemonhttp.NewHandler(apiServer, daemonhttp.NewRouter()))
+		if *oidcIssuerURL != "" {
+			verifier := auth.NewOIDCVerifier(&http.Client{Timeout: 10 * time.Second}, *oidcIssuerURL, *oidcClientID)
+			verifier.GroupsClaim = *oidcGroupsClaim
+			handler = auth.Authenticate(verifier, handler)
+			logger.Log("oidc-issuer", *oidcIssuerURL)
+		}
 		mux.Handle("/api/flux/", http.StripPrefix("/api/flux", handler))
+		if gitWebhookSecret != nil {
+			// Not behind the authentication above; the webhook
+			// has to show it knows the secret instead
+			mux.Handle("/hooks/git", &webhook.Handler{
+				Secret: gitWebhookSecret,
+				Notify: daemon.NotifyPush,
+				Logger: log.With(logger, "component", "webhook"),
+			})
+		}
 		logger.Log("addr", *listenAddr)
 		errc <- http.ListenAndServe(*listenAddr, mux)
 	}()
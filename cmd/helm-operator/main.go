@@ -57,6 +57,8 @@ var (
 	gitChartsPath   *string
 	gitPollInterval *time.Duration
 
+	chartCacheDir *string
+
 	queueWorkerCount *int
 
 	name       *string
@@ -108,6 +110,8 @@ func init() {
 	gitChartsPath = fs.String("git-charts-path", defaultGitChartsPath, "path within git repo to locate Helm Charts (relative path)")
 	gitPollInterval = fs.Duration("git-poll-interval", 5*time.Minute, "period on which to poll for changes to the git repo")
 
+	chartCacheDir = fs.String("chart-cache-dir", "/tmp/flux-charts", "directory in which to keep charts downloaded from chart repositories")
+
 	queueWorkerCount = fs.Int("queue-worker-count", 2, "Number of workers to process queue with Chart release jobs. Two by default")
 }
 
@@ -211,7 +215,8 @@ func main() {
 	}
 
 	releaseConfig := release.Config{
-		ChartsPath: *gitChartsPath,
+		ChartsPath:    *gitChartsPath,
+		ChartCacheDir: *chartCacheDir,
 	}
 	repoConfig := helmop.RepoConfig{
 		Repo:       repo,
@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/event"
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+)
+
+// When automation is deferred, it's first put off for this long, then
+// twice as long each time it's deferred again, up to the maximum.
+const (
+	automationBackoffInitial = 30 * time.Second
+	automationBackoffMax     = 10 * time.Minute
+)
+
+// Reasons for deferring automation
+const (
+	deferredForQueue   = "job queue"
+	deferredForCluster = "cluster API"
+)
+
+// AutomationBackoff says when automation should back off, rather
+// than add to the load on the job queue or the cluster API.
+type AutomationBackoff struct {
+	// Defer automation while there are at least this many jobs
+	// queued; zero means don't
+	MaxQueue int
+	// Defer automation when asking the cluster for the automated
+	// workloads takes longer than this; zero means don't
+	MaxClusterLatency time.Duration
+	// Log a warning event once automation has been deferred for
+	// longer than this; zero means don't
+	WarnAfter time.Duration
+}
+
+// deferAutomation says whether automation should be deferred, and if
+// so, for how long, either because it's backing off already or
+// because the job queue is too long.
+func (d *Daemon) deferAutomation(now time.Time, logger log.Logger) (time.Duration, bool) {
+	d.backoffMu.Lock()
+	until := d.backoffUntil
+	d.backoffMu.Unlock()
+	if now.Before(until) {
+		return until.Sub(now), true
+	}
+	if max := d.AutomationBackoff.MaxQueue; max > 0 && d.Jobs != nil && d.Jobs.Len() >= max {
+		return d.backOffAutomation(now, deferredForQueue, logger), true
+	}
+	return 0, false
+}
+
+// checkClusterLatency backs off automation if the cluster took longer
+// than allowed to respond, and says whether it did.
+func (d *Daemon) checkClusterLatency(now time.Time, latency time.Duration, logger log.Logger) bool {
+	if max := d.AutomationBackoff.MaxClusterLatency; max > 0 && latency > max {
+		d.backOffAutomation(now, deferredForCluster, logger)
+		return true
+	}
+	return false
+}
+
+// backOffAutomation defers automation for longer than last time,
+// and gives how long for.
+func (d *Daemon) backOffAutomation(now time.Time, reason string, logger log.Logger) time.Duration {
+	d.backoffMu.Lock()
+	if d.backoff == 0 {
+		d.backoff = automationBackoffInitial
+	} else if d.backoff *= 2; d.backoff > automationBackoffMax {
+		d.backoff = automationBackoffMax
+	}
+	backoff := d.backoff
+	d.backoffUntil = now.Add(backoff)
+	if d.deferredSince.IsZero() {
+		d.deferredSince = now
+	}
+	since := d.deferredSince
+	warnAfter := d.AutomationBackoff.WarnAfter
+	warn := warnAfter > 0 && now.Sub(since) > warnAfter && !d.deferralWarned
+	if warn {
+		d.deferralWarned = true
+	}
+	d.backoffMu.Unlock()
+
+	automationDeferrals.With(fluxmetrics.LabelReason, reason).Add(1)
+	automationDeferred.Set(now.Sub(since).Seconds())
+	logger.Log("info", "automation deferred", "reason", reason, "for", backoff, "since", since)
+	if warn {
+		if err := d.LogEvent(event.Event{
+			Type:      event.EventAutomationDeferred,
+			StartedAt: since,
+			EndedAt:   now,
+			LogLevel:  event.LogLevelWarn,
+			Metadata: &event.AutomationDeferredEventMetadata{
+				Reason: reason,
+				Since:  since,
+			},
+		}); err != nil {
+			logger.Log("error", errors.Wrap(err, "logging automation deferred event"))
+		}
+	}
+	return backoff
+}
+
+// resumeAutomation clears any backoff, once automation has gone
+// ahead.
+func (d *Daemon) resumeAutomation(logger log.Logger) {
+	d.backoffMu.Lock()
+	since := d.deferredSince
+	d.backoff, d.backoffUntil, d.deferredSince, d.deferralWarned = 0, time.Time{}, time.Time{}, false
+	d.backoffMu.Unlock()
+	if !since.IsZero() {
+		automationDeferred.Set(0)
+		logger.Log("info", "automation resumed", "deferred-since", since)
+	}
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/job"
+)
+
+func TestDeferAutomationForQueue(t *testing.T) {
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	events := &eventRecorder{}
+	d := &Daemon{
+		Jobs:              job.NewQueue(stop, wg),
+		EventWriter:       events,
+		Logger:            log.NewNopLogger(),
+		AutomationBackoff: AutomationBackoff{MaxQueue: 2, WarnAfter: time.Minute},
+		LoopVars:          &LoopVars{},
+	}
+	logger := log.NewNopLogger()
+	now := time.Now()
+
+	if _, deferred := d.deferAutomation(now, logger); deferred {
+		t.Fatal("expected automation to go ahead with an empty queue")
+	}
+
+	for i := 0; i < 2; i++ {
+		d.Jobs.Enqueue(&job.Job{ID: job.ID("job"), Do: func(log.Logger) error { return nil }})
+	}
+	d.Jobs.Sync()
+	wait, deferred := d.deferAutomation(now, logger)
+	if !deferred || wait != automationBackoffInitial {
+		t.Fatalf("expected automation to be deferred for %s, got %v, %s", automationBackoffInitial, deferred, wait)
+	}
+	// While backing off, it's deferred for what's left of the backoff
+	if wait, deferred := d.deferAutomation(now.Add(10*time.Second), logger); !deferred || wait != 20*time.Second {
+		t.Errorf("expected automation to be deferred for another 20s, got %v, %s", deferred, wait)
+	}
+	// .. and when that's over, for twice as long
+	later := now.Add(2 * time.Minute)
+	if wait, deferred := d.deferAutomation(later, logger); !deferred || wait != 2*automationBackoffInitial {
+		t.Errorf("expected automation to be deferred for %s, got %v, %s", 2*automationBackoffInitial, deferred, wait)
+	}
+	if len(*events) != 1 || (*events)[0].Type != event.EventAutomationDeferred {
+		t.Fatalf("expected a warning once deferred for longer than a minute, got %+v", *events)
+	}
+	d.deferAutomation(later.Add(time.Hour), logger)
+	if len(*events) != 1 {
+		t.Errorf("expected only one warning, got %d events", len(*events))
+	}
+
+	d.resumeAutomation(logger)
+	if d.backoff != 0 || !d.deferredSince.IsZero() {
+		t.Errorf("expected backoff to be reset on resuming, got %s since %s", d.backoff, d.deferredSince)
+	}
+}
+
+func TestBackoffLimit(t *testing.T) {
+	d := &Daemon{LoopVars: &LoopVars{}}
+	now := time.Now()
+	var wait time.Duration
+	for i := 0; i < 10; i++ {
+		wait = d.backOffAutomation(now, deferredForCluster, log.NewNopLogger())
+	}
+	if wait != automationBackoffMax {
+		t.Errorf("expected backoff to be capped at %s, got %s", automationBackoffMax, wait)
+	}
+}
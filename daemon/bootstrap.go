@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/resource"
+)
+
+// Namespaces that every cluster has, so are never bootstrapped
+var builtinNamespaces = map[string]bool{
+	"default":     true,
+	"kube-system": true,
+	"kube-public": true,
+}
+
+// bootstrapNamespaces writes and commits manifests for the namespaces
+// that resources in the repo are in, but which aren't defined in the
+// repo. It says whether anything was committed.
+func (d *Daemon) bootstrapNamespaces(ctx context.Context, working *git.Checkout, resources map[string]resource.Resource, logger log.Logger) (bool, error) {
+	missing := missingNamespaces(resources)
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	// Generated manifests go in the first of the paths synced, so
+	// they're loaded with everything else
+	dir := working.ManifestDirs()[0]
+	var written []string
+	for _, ns := range missing {
+		path := filepath.Join(dir, ns+"-bootstrap.yaml")
+		if _, err := os.Stat(path); err == nil {
+			logger.Log("namespace", ns, "bootstrap", "skipped", "reason", "file exists", "path", path)
+			continue
+		}
+		manifests, err := d.Manifests.BootstrapNamespace(ns, *d.NamespaceBootstrap)
+		if err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(path, manifests, os.FileMode(0600)); err != nil {
+			return false, err
+		}
+		written = append(written, ns)
+	}
+	if len(written) == 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	commitAction := git.CommitAction{Message: bootstrapCommitMessage(written)}
+	if err := working.CommitAndPush(ctx, commitAction, nil); err != nil {
+		return false, errors.Wrap(err, "committing bootstrap manifests")
+	}
+	logger.Log("bootstrap", "committed", "namespaces", strings.Join(written, ","))
+	return true, nil
+}
+
+// missingNamespaces gives the namespaces, other than those every
+// cluster has, that resources are in but that aren't themselves among
+// the resources.
+func missingNamespaces(resources map[string]resource.Resource) []string {
+	defined := map[string]bool{}
+	used := map[string]bool{}
+	for _, r := range resources {
+		ns, kind, name := r.ResourceID().Components()
+		if kind == "namespace" {
+			defined[name] = true
+			continue
+		}
+		used[ns] = true
+	}
+	var missing []string
+	for ns := range used {
+		if !defined[ns] && !builtinNamespaces[ns] {
+			missing = append(missing, ns)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func bootstrapCommitMessage(namespaces []string) string {
+	if len(namespaces) == 1 {
+		return fmt.Sprintf("Bootstrap namespace %s", namespaces[0])
+	}
+	return fmt.Sprintf("Bootstrap namespaces %s", strings.Join(namespaces, ", "))
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes"
+)
+
+const bootstrapManifests = `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: defined
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: defined
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: payments
+  namespace: payments
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: payments
+  namespace: payments
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: billing
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: helloworld
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: proxy
+  namespace: kube-system
+`
+
+func TestMissingNamespaces(t *testing.T) {
+	resources, err := (&kubernetes.Manifests{}).ParseManifests([]byte(bootstrapManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := missingNamespaces(resources)
+	if expected := []string{"billing", "payments"}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v to be missing, got %v", expected, missing)
+	}
+}
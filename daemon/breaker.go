@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// Reasons for counting a failure of automation
+const (
+	automationReleaseFailed = "automated release failed"
+	automationRolledBack    = "automated release rolled back"
+)
+
+// The user given as the cause of suspending automation
+const automationBreakerUser = "flux"
+
+// AutomationBreaker says when to suspend automation for a workload,
+// because its automated releases keep failing, or being rolled back,
+// rather than keep making releases that don't stick.
+type AutomationBreaker struct {
+	// Suspend automation after this many failures within the
+	// window; zero means never
+	MaxFailures int
+	Window      time.Duration
+}
+
+// Enabled says whether automation is ever suspended.
+func (b AutomationBreaker) Enabled() bool {
+	return b.MaxFailures > 0 && b.Window > 0
+}
+
+// automationFailed counts a failure of automation for each of the
+// workloads given, and suspends automation for any that have failed
+// too often.
+func (d *Daemon) automationFailed(ids []flux.ResourceID, reason string, now time.Time, logger log.Logger) {
+	if !d.AutomationBreaker.Enabled() || len(ids) == 0 || d.LoopVars == nil {
+		return
+	}
+	var tripped []flux.ResourceID
+	var failures []int
+	d.breakerMu.Lock()
+	if d.automationFailures == nil {
+		d.automationFailures = map[string][]time.Time{}
+	}
+	cutoff := now.Add(-d.AutomationBreaker.Window)
+	for _, id := range ids {
+		var recent []time.Time
+		for _, t := range d.automationFailures[id.String()] {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		recent = append(recent, now)
+		if len(recent) >= d.AutomationBreaker.MaxFailures {
+			// Start counting again, should it be automated again
+			delete(d.automationFailures, id.String())
+			tripped = append(tripped, id)
+			failures = append(failures, len(recent))
+			continue
+		}
+		d.automationFailures[id.String()] = recent
+	}
+	d.breakerMu.Unlock()
+
+	for _, id := range ids {
+		automationFailures.With(fluxmetrics.LabelReason, reason).Add(1)
+		logger.Log("workload", id, "automation", "failed", "reason", reason)
+	}
+	for i, id := range tripped {
+		d.suspendAutomation(id, failures[i], reason, now, logger)
+	}
+}
+
+// suspendAutomation turns automation off for the workload, by
+// queueing a policy update, and logs an event saying why. It stays
+// off until it's turned back on.
+func (d *Daemon) suspendAutomation(id flux.ResourceID, failures int, reason string, now time.Time, logger log.Logger) {
+	window := d.AutomationBreaker.Window
+	logger.Log("workload", id, "automation", "suspended", "failures", failures, "window", window)
+	automationSuspensions.Add(1)
+	if d.Jobs != nil {
+		updates := policy.Updates{
+			id: policy.Update{Remove: policy.Set{policy.Automated: "true"}},
+		}
+		spec := update.Spec{
+			Type: update.Policy,
+			Cause: update.Cause{
+				User:    automationBreakerUser,
+				Message: fmt.Sprintf("Automation suspended after %d failures in %s", failures, window),
+			},
+			Spec: updates,
+		}
+		d.queueJob(context.Background(), spec.Type, []flux.ResourceID{id}, d.makeLoggingJobFunc(d.makeJobFromUpdate(d.updatePolicy(spec, updates))))
+	}
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: []flux.ResourceID{id},
+		Type:       event.EventAutomationSuspended,
+		StartedAt:  now,
+		EndedAt:    now,
+		LogLevel:   event.LogLevelError,
+		Metadata: &event.AutomationSuspendedEventMetadata{
+			Failures: failures,
+			Window:   window,
+			Reason:   reason,
+		},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging automation suspended event"))
+	}
+}
+
+// failedReleases gives the workloads a release failed for.
+func failedReleases(result update.Result) []flux.ResourceID {
+	var ids []flux.ResourceID
+	for id, res := range result {
+		if res.Status == update.ReleaseStatusFailed {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// lastAutomated gives those of the workloads given whose most recent
+// release was automated.
+func (loop *LoopVars) lastAutomated(ids []flux.ResourceID) map[flux.ResourceID]bool {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	automated := map[flux.ResourceID]bool{}
+	for _, id := range ids {
+		if last, ok := loop.lastReleases[id.String()]; ok && last.Type == event.EventAutoRelease {
+			automated[id] = true
+		}
+	}
+	return automated
+}
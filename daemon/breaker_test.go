@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestAutomationBreaker(t *testing.T) {
+	events := &eventRecorder{}
+	d := &Daemon{
+		EventWriter:       events,
+		Logger:            log.NewNopLogger(),
+		AutomationBreaker: AutomationBreaker{MaxFailures: 3, Window: time.Hour},
+		LoopVars:          &LoopVars{},
+	}
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	logger := log.NewNopLogger()
+	now := time.Now().UTC()
+
+	// A failure that's fallen out of the window doesn't count
+	d.automationFailed([]flux.ResourceID{hello}, automationReleaseFailed, now.Add(-2*time.Hour), logger)
+	d.automationFailed([]flux.ResourceID{hello}, automationReleaseFailed, now.Add(-time.Minute), logger)
+	d.automationFailed([]flux.ResourceID{hello}, automationReleaseFailed, now.Add(-time.Second), logger)
+	if len(*events) != 0 {
+		t.Fatalf("expected no events before the third failure in the window, got %+v", *events)
+	}
+	d.automationFailed([]flux.ResourceID{hello}, automationReleaseFailed, now, logger)
+	if len(*events) != 1 || (*events)[0].Type != event.EventAutomationSuspended {
+		t.Fatalf("expected automation to be suspended, got %+v", *events)
+	}
+	metadata := (*events)[0].Metadata.(*event.AutomationSuspendedEventMetadata)
+	if metadata.Failures != 3 || metadata.Reason != automationReleaseFailed {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	// Counting starts again, should it be automated again
+	d.automationFailed([]flux.ResourceID{hello}, automationReleaseFailed, now.Add(time.Second), logger)
+	if len(*events) != 1 {
+		t.Errorf("expected counting to start again after suspending, got %d events", len(*events))
+	}
+}
+
+func TestAutomationBreakerRollback(t *testing.T) {
+	events := &eventRecorder{}
+	d := &Daemon{
+		EventWriter:       events,
+		Logger:            log.NewNopLogger(),
+		AutomationBreaker: AutomationBreaker{MaxFailures: 1, Window: time.Hour},
+		LoopVars:          &LoopVars{},
+	}
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	now := time.Now().UTC()
+
+	result := func(from, to string) update.Result {
+		current, _ := image.ParseRef("quay.io/example/hello:" + from)
+		target, _ := image.ParseRef("quay.io/example/hello:" + to)
+		return update.Result{
+			hello: update.ControllerResult{
+				Status:       update.ReleaseStatusSuccess,
+				PerContainer: []update.ContainerUpdate{{Container: "hello", Current: current, Target: target}},
+			},
+		}
+	}
+	release := func(at time.Time, from, to string) {
+		d.LogEvent(event.Event{
+			Type:       event.EventRelease,
+			ServiceIDs: []flux.ResourceID{hello},
+			StartedAt:  at,
+			EndedAt:    at,
+			Metadata: &event.ReleaseEventMetadata{
+				ReleaseEventCommon: event.ReleaseEventCommon{Result: result(from, to)},
+			},
+		})
+	}
+	autoRelease := func(at time.Time, from, to string) {
+		d.LogEvent(event.Event{
+			Type:       event.EventAutoRelease,
+			ServiceIDs: []flux.ResourceID{hello},
+			StartedAt:  at,
+			EndedAt:    at,
+			Metadata: &event.AutoReleaseEventMetadata{
+				ReleaseEventCommon: event.ReleaseEventCommon{Result: result(from, to)},
+			},
+		})
+	}
+
+	// Rolling back a release made by hand isn't a failure of
+	// automation
+	release(now.Add(-3*time.Minute), "1.0", "1.1")
+	release(now.Add(-2*time.Minute), "1.1", "1.0")
+	if len(*events) != 2 {
+		t.Fatalf("expected only the release events, got %+v", *events)
+	}
+
+	autoRelease(now.Add(-time.Minute), "1.0", "1.1")
+	release(now, "1.1", "1.0")
+	if len(*events) != 5 || (*events)[4].Type != event.EventAutomationSuspended {
+		t.Fatalf("expected automation to be suspended after the rollback, got %+v", *events)
+	}
+	if reason := (*events)[4].Metadata.(*event.AutomationSuspendedEventMetadata).Reason; reason != automationRolledBack {
+		t.Errorf("expected the reason to be the rollback, got %q", reason)
+	}
+}
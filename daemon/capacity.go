@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/update"
+)
+
+// CapacityCheck says whether to check, before committing a release,
+// that the cluster has room for the pods it will start, and what to
+// do if not.
+type CapacityCheck string
+
+const (
+	CapacityCheckOff   CapacityCheck = "off"
+	CapacityCheckWarn  CapacityCheck = "warn"  // log a warning, and release anyway
+	CapacityCheckBlock CapacityCheck = "block" // refuse the release, unless it's forced
+)
+
+// ParseCapacityCheck gives the capacity check named, with the empty
+// string meaning off.
+func ParseCapacityCheck(s string) (CapacityCheck, error) {
+	switch c := CapacityCheck(s); c {
+	case "":
+		return CapacityCheckOff, nil
+	case CapacityCheckOff, CapacityCheckWarn, CapacityCheckBlock:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown capacity check %q; expected off, warn or block", s)
+}
+
+// checkCapacity checks, if asked to, whether the workloads changed by
+// a release can roll out without leaving pods unschedulable. It gives
+// the analysis, to be recorded with the release, or an error if the
+// release should not go ahead. Failing to do the check is not reason
+// enough to hold back a release.
+func (d *Daemon) checkCapacity(spec update.Spec, result update.Result, logger log.Logger) (*cluster.CapacityReport, error) {
+	if d.CapacityCheck == "" || d.CapacityCheck == CapacityCheckOff {
+		return nil, nil
+	}
+	ids := result.AffectedResources()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	report, err := d.Cluster.CheckCapacity(ids)
+	if err != nil {
+		logger.Log("warning", "checking cluster capacity for release", "err", err)
+		return nil, nil
+	}
+	if report.Sufficient() {
+		return &report, nil
+	}
+	if d.CapacityCheck == CapacityCheckBlock && !releaseForced(spec) {
+		return nil, insufficientCapacityError(report)
+	}
+	logger.Log("warning", "release may leave pods unschedulable", "unschedulable", report.Unschedulable())
+	return &report, nil
+}
+
+func releaseForced(spec update.Spec) bool {
+	switch s := spec.Spec.(type) {
+	case update.ReleaseSpec:
+		return s.Force
+	case update.ContainerSpecs:
+		return s.Force
+	case update.RollbackSpec:
+		return s.Force
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestCheckCapacity(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	result := update.Result{
+		id: update.ControllerResult{Status: update.ReleaseStatusSuccess},
+		flux.MustParseResourceID("default:deployment/skipped"): update.ControllerResult{Status: update.ReleaseStatusSkipped},
+	}
+	report := cluster.CapacityReport{
+		Workloads: []cluster.WorkloadCapacity{{ID: id, ExtraPods: 2, CPU: 500, Unschedulable: 1}},
+	}
+	var checked []flux.ResourceID
+	var checkErr error
+	d := &Daemon{
+		Cluster: &cluster.Mock{
+			CheckCapacityFunc: func(ids []flux.ResourceID) (cluster.CapacityReport, error) {
+				checked = ids
+				return report, checkErr
+			},
+		},
+	}
+	spec := update.Spec{Type: update.Images, Spec: update.ReleaseSpec{Kind: update.ReleaseKindExecute}}
+	forced := update.Spec{Type: update.Images, Spec: update.ReleaseSpec{Kind: update.ReleaseKindExecute, Force: true}}
+	logger := log.NewNopLogger()
+
+	// Not checked unless asked for
+	if r, err := d.checkCapacity(spec, result, logger); r != nil || err != nil || checked != nil {
+		t.Fatalf("expected no check, got %+v, %v", r, err)
+	}
+
+	d.CapacityCheck = CapacityCheckWarn
+	r, err := d.checkCapacity(spec, result, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checked) != 1 || checked[0] != id {
+		t.Errorf("expected only the workload released to be checked, got %v", checked)
+	}
+	if r == nil || r.Unschedulable() != 1 {
+		t.Errorf("expected the report to be given, got %+v", r)
+	}
+
+	d.CapacityCheck = CapacityCheckBlock
+	_, err = d.checkCapacity(spec, result, logger)
+	if err == nil {
+		t.Fatal("expected release to be blocked")
+	}
+	if ferr, ok := err.(*fluxerr.Error); !ok || ferr.Type != fluxerr.User {
+		t.Errorf("expected a user error, got %v", err)
+	}
+	if r, err = d.checkCapacity(forced, result, logger); err != nil || r == nil {
+		t.Errorf("expected forced release to go ahead with a report, got %+v, %v", r, err)
+	}
+
+	// Failing to check doesn't stop a release
+	checkErr = errors.New("nodes is forbidden")
+	if r, err = d.checkCapacity(spec, result, logger); err != nil || r != nil {
+		t.Errorf("expected release to go ahead without a report, got %+v, %v", r, err)
+	}
+}
+
+func TestParseCapacityCheck(t *testing.T) {
+	for s, expected := range map[string]CapacityCheck{
+		"":      CapacityCheckOff,
+		"off":   CapacityCheckOff,
+		"warn":  CapacityCheckWarn,
+		"block": CapacityCheckBlock,
+	} {
+		c, err := ParseCapacityCheck(s)
+		if err != nil || c != expected {
+			t.Errorf("%q: expected %s, got %s, %v", s, expected, c, err)
+		}
+	}
+	if _, err := ParseCapacityCheck("always"); err == nil {
+		t.Error("expected an error for an unknown check")
+	}
+}
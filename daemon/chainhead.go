@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// logChainHead logs an event giving the head of the event store's
+// chain of hashes, so that it goes to the event writers (and from
+// there, somewhere other than the store) as well as into the store.
+// Nothing is logged if the most recent event is itself the head
+// logged last time, so the daemon doesn't go on logging one head
+// after another when nothing else has happened.
+func (d *Daemon) logChainHead() (bool, error) {
+	head, ok, err := event.ChainHead(d.eventStore(), d.EventChainKey)
+	if err != nil || !ok {
+		return false, err
+	}
+	last, err := d.eventStore().GetEvent(head.ID)
+	if err != nil {
+		return false, err
+	}
+	if last.Type == event.EventChainHead {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	return true, d.LogEvent(event.Event{
+		Type:      event.EventChainHead,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelInfo,
+		Metadata:  &head,
+	})
+}
+
+// ChainHeadLoop logs the head of the event store's chain of hashes
+// every EventChainHeadInterval, until told to stop.
+func (d *Daemon) ChainHeadLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger) {
+	defer wg.Done()
+	if d.EventChainHeadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.EventChainHeadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if _, err := d.logChainHead(); err != nil {
+			logger.Log("err", err)
+		}
+	}
+}
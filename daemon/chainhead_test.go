@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+func TestLogChainHead(t *testing.T) {
+	key := []byte("secret")
+	store, err := event.ChainStore(&event.Buffer{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Daemon{EventStore: store, EventChainKey: key, Logger: log.NewNopLogger()}
+	if logged, err := d.logChainHead(); err != nil || logged {
+		t.Fatalf("expected nothing logged for an empty store, got %v, %v", logged, err)
+	}
+
+	if err := d.LogEvent(event.Event{Type: event.EventCommit, Metadata: &event.CommitEventMetadata{Revision: "abc"}}); err != nil {
+		t.Fatal(err)
+	}
+	head, _, err := event.ChainHead(store, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logged, err := d.logChainHead(); err != nil || !logged {
+		t.Fatalf("expected the head to be logged, got %v, %v", logged, err)
+	}
+	events, err := store.AllEvents(event.Page{}, event.EventFilter{Types: []string{event.EventChainHead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || *events[0].Metadata.(*event.ChainHeadEventMetadata) != head {
+		t.Fatalf("expected an event with the head %+v, got %+v", head, events)
+	}
+	if ok, err := event.CheckHead(store, key, head); err != nil || !ok {
+		t.Errorf("expected the head logged to check out, got %v, %v", ok, err)
+	}
+
+	// Only once, until something else is logged
+	if logged, err := d.logChainHead(); err != nil || logged {
+		t.Errorf("expected the head not to be logged again, got %v, %v", logged, err)
+	}
+}
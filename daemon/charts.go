@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/integrations/helm/chartrepo"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
+)
+
+// How long to wait for a chart repository index
+const chartIndexTimeout = 30 * time.Second
+
+// pollForNewCharts looks for newer versions of the charts used by
+// automated FluxHelmReleases, and updates the manifests to use them.
+func (d *Daemon) pollForNewCharts(logger log.Logger) {
+	if d.ChartRepos == nil {
+		return
+	}
+	if _, frozen := d.activeFreeze(time.Now()); frozen {
+		return
+	}
+	ctx := context.Background()
+
+	candidates, err := d.getUnlockedAutomatedResources(ctx)
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "getting unlocked automated resources"))
+		return
+	}
+
+	indexes := map[string]*chartrepo.Index{}
+	changes := update.ChartUpdates{}
+	for id, res := range candidates {
+		if d.observing(res.Policy()) {
+			// Observing chart automation isn't supported, so
+			// leave it be
+			continue
+		}
+		release, ok := res.(resource.ChartRelease)
+		if !ok {
+			continue
+		}
+		chart, ok := release.Chart()
+		if !ok {
+			continue
+		}
+		pattern := policy.GetChartVersionPattern(res.Policy())
+		logger := log.With(logger, "resource", id, "chart", chart.Name, "repo", chart.Repository, "pattern", pattern, "current", chart.Version)
+
+		index, ok := indexes[chart.Repository]
+		if !ok {
+			ictx, cancel := context.WithTimeout(ctx, chartIndexTimeout)
+			index, err = d.ChartRepos.Index(ictx, chart.Repository)
+			cancel()
+			if err != nil {
+				logger.Log("error", errors.Wrap(err, "fetching chart repository index"))
+			}
+			// remember failures too, so they're not retried for
+			// every release using the repository
+			indexes[chart.Repository] = index
+		}
+		if index == nil {
+			continue
+		}
+
+		latest, ok := latestChartVersion(index.Versions(chart.Name), pattern)
+		if !ok || latest.Version == chart.Version {
+			continue
+		}
+		changes.Add(id, chart, latest.Version, latest.AppVersion)
+		logger.Log("info", "added chart update to automation run", "new", latest.Version, "appVersion", latest.AppVersion)
+	}
+
+	if len(changes.Changes) > 0 {
+		d.UpdateManifests(ctx, update.Spec{Type: update.Charts, Spec: changes})
+	}
+}
+
+// latestChartVersion picks the newest of the versions given that
+// matches the pattern, using the same ordering as for image tags.
+func latestChartVersion(versions []chartrepo.ChartVersion, pattern policy.Pattern) (chartrepo.ChartVersion, bool) {
+	var latest chartrepo.ChartVersion
+	var latestInfo *image.Info
+	for _, v := range versions {
+		if !pattern.Matches(v.Version) {
+			continue
+		}
+		info := &image.Info{ID: image.Ref{Tag: v.Version}, CreatedAt: v.Created}
+		if latestInfo == nil || pattern.Newer(info, latestInfo) {
+			latest, latestInfo = v, info
+		}
+	}
+	return latest, latestInfo != nil
+}
+
+func (d *Daemon) updateCharts(spec update.Spec, charts update.ChartUpdates) updateFunc {
+	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (job.Result, error) {
+		result := job.Result{
+			Spec:   &spec,
+			Result: update.Result{},
+		}
+
+		var changed int
+		for _, ch := range charts.Changes {
+			id := ch.ResourceID
+			err := cluster.UpdateManifest(d.Manifests, working.Dir(), working.ManifestDirs(), id, func(def []byte) ([]byte, error) {
+				newDef, err := d.Manifests.UpdateChartVersion(def, id, ch.Target)
+				if err != nil {
+					return nil, err
+				}
+				if string(newDef) == string(def) {
+					result.Result[id] = update.ControllerResult{
+						Status: update.ReleaseStatusSkipped,
+					}
+				} else {
+					changed++
+					result.Result[id] = update.ControllerResult{
+						Status: update.ReleaseStatusSuccess,
+					}
+				}
+				return newDef, nil
+			})
+			if err != nil {
+				switch err := err.(type) {
+				case cluster.ManifestError:
+					result.Result[id] = update.ControllerResult{
+						Status: update.ReleaseStatusFailed,
+						Error:  err.Error(),
+					}
+				default:
+					result.Result[id] = update.ControllerResult{
+						Status: update.ReleaseStatusFailed,
+						Error:  fmt.Sprintf("updating chart version: %s", err),
+					}
+				}
+			}
+		}
+		if changed == 0 {
+			return result, nil
+		}
+
+		commitAction := git.CommitAction{
+			Message:  charts.CommitMessage(),
+			Trailers: commitTrailers(jobID, spec, result.Result.AffectedResources()),
+		}
+		if err := d.commitAndPush(ctx, working, commitAction, &note{JobID: jobID, Spec: spec, Result: result.Result}); err != nil {
+			d.AskForSync()
+			return result, err
+		}
+
+		var err error
+		result.Revision, err = working.HeadRevision(ctx)
+		if err != nil {
+			return result, err
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux/integrations/helm/chartrepo"
+	"github.com/weaveworks/flux/policy"
+)
+
+func TestLatestChartVersion(t *testing.T) {
+	versions := []chartrepo.ChartVersion{
+		{Name: "redis", Version: "1.2.0"},
+		{Name: "redis", Version: "1.10.1"},
+		{Name: "redis", Version: "2.0.0"},
+		{Name: "redis", Version: "1.3.0-rc1"},
+	}
+	for pattern, expected := range map[string]string{
+		"semver:~1":     "1.10.1",
+		"semver:~1.2":   "1.2.0",
+		"semver:>=2":    "2.0.0",
+		"glob:1.3.*":    "1.3.0-rc1",
+		"semver:~3.0.0": "",
+	} {
+		latest, ok := latestChartVersion(versions, policy.NewPattern(pattern))
+		if expected == "" {
+			if ok {
+				t.Errorf("%s: expected no version, got %s", pattern, latest.Version)
+			}
+			continue
+		}
+		if !ok || latest.Version != expected {
+			t.Errorf("%s: expected %s, got %s", pattern, expected, latest.Version)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+)
+
+// CheckWorkload explains whether the workload given could be
+// automated, and if not, why not; the same conditions otherwise make
+// automation skip a workload without saying anything.
+func (d *Daemon) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (v14.WorkloadCheck, error) {
+	id := opts.Workload
+	check := v14.WorkloadCheck{ID: id}
+	problem := func(format string, args ...interface{}) {
+		check.Problems = append(check.Problems, fmt.Sprintf(format, args...))
+	}
+	note := func(format string, args ...interface{}) {
+		check.Notes = append(check.Notes, fmt.Sprintf(format, args...))
+	}
+
+	running, err := d.Cluster.SomeControllers([]flux.ResourceID{id})
+	if err != nil {
+		return check, errors.Wrap(err, "getting workload from cluster")
+	}
+	var inCluster []resource.Container
+	switch {
+	case len(running) == 0:
+		note("the workload is not running in the cluster (yet)")
+	case running[0].IsSystem:
+		problem("the workload is a system workload, which Flux leaves alone")
+	case running[0].Immutable:
+		problem("the workload's images can't be changed once it's created (e.g., it's a Job or a Pod), so Flux only syncs it")
+	default:
+		inCluster = running[0].ContainersOrNil()
+	}
+
+	var resources map[string]resource.Resource
+	var loadErr error
+	err = d.WithReadOnlyClone(ctx, func(checkout *git.Checkout) error {
+		resources, loadErr = d.loadCheckout(ctx, checkout)
+		return nil
+	})
+	if _, notReady := err.(git.NotReadyError); notReady || err == git.ErrNoConfig {
+		problem("the git repo is not ready: %s", err)
+		return finishCheck(check), nil
+	}
+	if err != nil {
+		return check, err
+	}
+	if loadErr != nil {
+		problem("the manifests in the git repo could not be loaded, so none can be updated: %s", loadErr)
+		return finishCheck(check), nil
+	}
+
+	res, ok := resources[id.String()]
+	if !ok {
+		problem("no manifest for the workload was found in the git repo (under the paths Flux is configured to look at)")
+		return finishCheck(check), nil
+	}
+
+	policies := res.Policy()
+	check.Automated = policies.Has(policy.Automated)
+	check.Locked = policies.Has(policy.Locked)
+	if policies.Has(policy.Ignore) {
+		problem("the manifest has the ignore policy, so Flux does not touch it")
+	}
+	if !check.Automated {
+		note("the workload is not automated; use fluxctl automate to automate it")
+	}
+	if check.Locked {
+		note("the workload is locked, so it will not be updated until it is unlocked")
+	}
+
+	if bytes.Contains(res.Bytes(), []byte("{{")) {
+		problem("the manifest in %s looks like a template (it contains {{ ... }}) generated by another tool; Flux can only update plain YAML", res.Source())
+	}
+
+	workload, ok := res.(resource.Workload)
+	if !ok {
+		problem("the manifest in %s is not a kind of resource Flux can update images in", res.Source())
+		return finishCheck(check), nil
+	}
+	containers := workload.Containers()
+	if len(containers) == 0 {
+		problem("no containers (or, for a FluxHelmRelease, images in values) were found in the manifest in %s", res.Source())
+	}
+	inManifest := map[string]bool{}
+	for _, c := range containers {
+		inManifest[c.Name] = true
+		if c.Image.Name.Image == "" {
+			problem("the image field of container %q in %s could not be parsed as an image reference", c.Name, res.Source())
+		}
+	}
+	for _, c := range inCluster {
+		if !inManifest[c.Name] {
+			problem("container %q is running in the cluster, but is missing from the manifest in %s", c.Name, res.Source())
+		}
+	}
+	return finishCheck(check), nil
+}
+
+func finishCheck(check v14.WorkloadCheck) v14.WorkloadCheck {
+	check.Eligible = len(check.Problems) == 0
+	return check
+}
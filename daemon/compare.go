@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+)
+
+// CompareImages compares, for each container of the workloads in the
+// git repo, the image given at the head of the repo with the image
+// running in the cluster. Where they differ, the image as of the last
+// sync says whether it's because the head is yet to be synced, or
+// because the workload has been changed in the cluster.
+func (d *Daemon) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	var head map[string]resource.Resource
+	var headRev, syncRev string
+	var loadErr error
+	err := d.WithReadOnlyClone(ctx, func(checkout *git.Checkout) error {
+		var err error
+		if headRev, err = checkout.HeadRevision(ctx); err != nil {
+			return err
+		}
+		if syncRev, err = checkout.SyncRevision(ctx); err != nil && !isUnknownRevision(err) {
+			return err
+		}
+		head, loadErr = d.ManifestCache.Load(d.Manifests, headRev, checkout.Dir(), checkout.ManifestDirs())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if loadErr != nil {
+		return nil, manifestLoadError(loadErr)
+	}
+
+	// Nothing has been synced yet if there's no sync tag
+	synced := map[string]resource.Resource{}
+	switch syncRev {
+	case "":
+	case headRev:
+		synced = head
+	default:
+		if synced, err = d.resourcesAt(ctx, syncRev); err != nil {
+			return nil, errors.Wrap(err, "loading resources last synced")
+		}
+	}
+
+	controllers, err := d.Cluster.AllControllers(opts.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting workloads from cluster")
+	}
+	running := map[flux.ResourceID]cluster.Controller{}
+	for _, c := range controllers {
+		running[c.ID] = c
+	}
+
+	var res []v17.ImageComparison
+	for id, r := range head {
+		workload, ok := r.(resource.Workload)
+		if !ok || r.Policy().Has(policy.Ignore) {
+			continue
+		}
+		resourceID := r.ResourceID()
+		if ns, _, _ := resourceID.Components(); opts.Namespace != "" && ns != opts.Namespace {
+			continue
+		}
+		var syncedImages map[string]string
+		if s, ok := synced[id].(resource.Workload); ok {
+			syncedImages = containerImages(s.Containers())
+		}
+		var runningImages map[string]string
+		if c, ok := running[resourceID]; ok {
+			if c.IsSystem {
+				continue
+			}
+			runningImages = containerImages(c.ContainersOrNil())
+		}
+		for _, c := range workload.Containers() {
+			res = append(res, compareImage(resourceID, c.Name, c.Image.String(), syncedImages[c.Name], runningImages[c.Name]))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Workload != res[j].Workload {
+			return res[i].Workload.String() < res[j].Workload.String()
+		}
+		return res[i].Container < res[j].Container
+	})
+	return res, nil
+}
+
+func compareImage(id flux.ResourceID, container, desired, synced, running string) v17.ImageComparison {
+	c := v17.ImageComparison{
+		Workload:  id,
+		Container: container,
+		Desired:   desired,
+		Synced:    synced,
+		Running:   running,
+	}
+	switch {
+	case running == desired:
+		c.Status = v17.ImageInSync
+	case synced != desired && (running == "" || running == synced):
+		c.Status = v17.ImagePendingSync
+	case running == "":
+		c.Status = v17.ImageNotRunning
+	default:
+		c.Status = v17.ImageChangedInCluster
+	}
+	return c
+}
+
+func containerImages(containers []resource.Container) map[string]string {
+	images := map[string]string{}
+	for _, c := range containers {
+		images[c.Name] = c.Image.String()
+	}
+	return images
+}
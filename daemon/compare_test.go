@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v17"
+)
+
+func TestCompareImage(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	for _, c := range []struct {
+		desired, synced, running string
+		status                   string
+	}{
+		{"hello:2", "hello:2", "hello:2", v17.ImageInSync},
+		// Committed, but not yet synced
+		{"hello:2", "hello:1", "hello:1", v17.ImagePendingSync},
+		// A new workload, not yet synced
+		{"hello:2", "", "", v17.ImagePendingSync},
+		// Synced, but edited with kubectl since
+		{"hello:2", "hello:2", "hello:3", v17.ImageChangedInCluster},
+		// Synced, but deleted from the cluster since
+		{"hello:2", "hello:2", "", v17.ImageNotRunning},
+		// Not synced, and not what was last synced either
+		{"hello:2", "hello:1", "hello:3", v17.ImageChangedInCluster},
+		// The cluster has already caught up with git, e.g., because
+		// it was released by hand with kubectl
+		{"hello:2", "hello:1", "hello:2", v17.ImageInSync},
+	} {
+		got := compareImage(id, "greeter", c.desired, c.synced, c.running)
+		if got.Status != c.status {
+			t.Errorf("desired %q, synced %q, running %q: expected %s, got %s", c.desired, c.synced, c.running, c.status, got.Status)
+		}
+	}
+}
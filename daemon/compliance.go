@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/event"
+)
+
+// The period reported on, if no start is given
+const defaultCompliancePeriod = 7 * 24 * time.Hour
+
+var (
+	complianceReleaseTypes = []string{event.EventRelease, event.EventAutoRelease, event.EventRollback, event.EventRestart}
+	compliancePolicyTypes  = []string{event.EventAutomate, event.EventDeautomate, event.EventLock, event.EventUnlock, event.EventUpdatePolicy}
+)
+
+// ComplianceReport reports the releases, policy changes and failures
+// in the period given, from the events kept, and the acknowledgements
+// of them; e.g., for handing to auditors. If the daemon has a
+// ReportKey, the report is signed with it.
+func (d *Daemon) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	now := time.Now().UTC()
+	report := v26.ComplianceReport{
+		Since:       opts.Since,
+		Until:       opts.Until,
+		Namespace:   opts.Namespace,
+		GeneratedAt: now,
+	}
+	if report.Until.IsZero() {
+		report.Until = now
+	}
+	if report.Since.IsZero() {
+		report.Since = report.Until.Add(-defaultCompliancePeriod)
+	}
+
+	if store := d.eventStore(); store != nil {
+		filter := event.EventFilter{Since: report.Since, Until: report.Until}
+		if opts.Namespace != "" {
+			filter.Namespaces = []string{opts.Namespace}
+		}
+		events, err := store.AllEvents(event.Page{}, filter)
+		if err != nil {
+			return report, err
+		}
+		for _, e := range events {
+			var reported bool
+			if isOneOf(e.Type, complianceReleaseTypes) {
+				report.Releases = append(report.Releases, reportedEvent(e))
+				reported = true
+			}
+			if isOneOf(e.Type, compliancePolicyTypes) {
+				report.PolicyChanges = append(report.PolicyChanges, reportedEvent(e))
+				reported = true
+			}
+			if e.LogLevel == event.LogLevelError {
+				report.Failures = append(report.Failures, reportedEvent(e))
+				reported = true
+			}
+			if !reported {
+				continue
+			}
+			for _, a := range e.Annotations {
+				if a.Acknowledged {
+					report.Approvals = append(report.Approvals, v26.ReportedApproval{
+						EventID: e.ID,
+						Time:    a.Time,
+						User:    a.User,
+						Comment: a.Comment,
+					})
+				}
+			}
+		}
+	}
+
+	if d.ReportKey != nil {
+		if err := v26.SignReport(&report, d.ReportKey); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func reportedEvent(e event.Event) v26.ReportedEvent {
+	return v26.ReportedEvent{
+		EventID:   e.ID,
+		Type:      e.Type,
+		Time:      e.StartedAt,
+		Workloads: e.ServiceIDs,
+		User:      causeUser(e),
+		Message:   e.String(),
+	}
+}
+
+// causeUser gives who asked for the change recorded in the event, if
+// it's known.
+func causeUser(e event.Event) string {
+	switch metadata := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		return metadata.Cause.User
+	case *event.RollbackEventMetadata:
+		return metadata.Cause.User
+	case *event.RestartEventMetadata:
+		return metadata.Cause.User
+	case *event.PolicyUpdateEventMetadata:
+		return metadata.Cause.User
+	case *event.LockEventMetadata:
+		return metadata.User
+	}
+	return ""
+}
+
+func isOneOf(s string, list []string) bool {
+	for _, item := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestComplianceReport(t *testing.T) {
+	start := time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("other:deployment/hello")
+	store := &event.Buffer{}
+	for _, e := range []event.Event{
+		{Type: event.EventRelease, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(time.Hour), LogLevel: event.LogLevelInfo,
+			Metadata: &event.ReleaseEventMetadata{Cause: update.Cause{User: "jane"}},
+			Annotations: []event.Annotation{
+				{Time: start.Add(2 * time.Hour), User: "sam", Comment: "change 1234", Acknowledged: true},
+				{Time: start.Add(3 * time.Hour), User: "sam", Comment: "just a comment"},
+			}},
+		{Type: event.EventLock, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(2 * time.Hour), LogLevel: event.LogLevelInfo},
+		{Type: event.EventSync, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(3 * time.Hour), LogLevel: event.LogLevelError, Message: "sync failed"},
+		// Not a change, nor a failure
+		{Type: event.EventSync, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(4 * time.Hour), LogLevel: event.LogLevelInfo, Message: "synced"},
+		// Another namespace
+		{Type: event.EventUnlock, ServiceIDs: []flux.ResourceID{other}, StartedAt: start.Add(5 * time.Hour), LogLevel: event.LogLevelInfo},
+		// After the period
+		{Type: event.EventLock, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(10 * 24 * time.Hour), LogLevel: event.LogLevelInfo},
+	} {
+		store.LogEvent(e)
+	}
+	public, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Daemon{EventStore: store, ReportKey: key}
+
+	report, err := d.ComplianceReport(context.Background(), v26.ComplianceReportOptions{
+		Since:     start,
+		Until:     start.Add(7 * 24 * time.Hour),
+		Namespace: "default",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Releases) != 1 || report.Releases[0].EventID != 1 || report.Releases[0].User != "jane" {
+		t.Errorf("expected the release, by jane, got %+v", report.Releases)
+	}
+	if len(report.PolicyChanges) != 1 || report.PolicyChanges[0].EventID != 2 {
+		t.Errorf("expected only the lock in the period, got %+v", report.PolicyChanges)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Message != "sync failed" {
+		t.Errorf("expected the failed sync, got %+v", report.Failures)
+	}
+	if len(report.Approvals) != 1 || report.Approvals[0].User != "sam" || report.Approvals[0].EventID != 1 {
+		t.Errorf("expected the acknowledgement of the release, got %+v", report.Approvals)
+	}
+
+	if !v26.VerifyReport(report, public) {
+		t.Error("expected the report to be signed with the key")
+	}
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	if v26.VerifyReport(report, otherKey) {
+		t.Error("expected the signature not to verify with another key")
+	}
+	if published, err := v26.ParseReportPublicKey([]byte(report.PublicKey)); err != nil || !published.Equal(public) {
+		t.Errorf("expected the public key in the report, got %q (%v)", report.PublicKey, err)
+	}
+	report.Failures = nil
+	if v26.VerifyReport(report, public) {
+		t.Error("expected the signature not to verify once the report is changed")
+	}
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/resource"
+)
+
+// checkSyncConflicts looks for fields of the resources about to be
+// synced that something other than flux has changed since flux last
+// applied them, and logs a warning event for those not already
+// reported. A conflict is reported once while it lasts; if it goes
+// away (e.g., because the other actor gave up, or the field was taken
+// out of git) and comes back, it's reported again.
+func (d *Daemon) checkSyncConflicts(resources map[string]resource.Resource, logger log.Logger) {
+	var ids []flux.ResourceID
+	for _, r := range resources {
+		ids = append(ids, r.ResourceID())
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	conflicts, err := d.Cluster.SyncConflicts(ids)
+	if err != nil {
+		logger.Log("err", errors.Wrap(err, "checking for sync conflicts"))
+		return
+	}
+
+	var unreported []cluster.SyncConflict
+	serviceIDs := flux.ResourceIDSet{}
+	found := map[string]bool{}
+	d.conflictsMu.Lock()
+	for _, c := range conflicts {
+		key := c.ID.String() + " " + c.Field + " " + c.Manager
+		found[key] = true
+		if !d.reportedConflicts[key] {
+			unreported = append(unreported, c)
+			serviceIDs.Add([]flux.ResourceID{c.ID})
+		}
+	}
+	d.reportedConflicts = found
+	d.conflictsMu.Unlock()
+	if len(unreported) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: serviceIDs.ToSlice(),
+		Type:       event.EventSyncConflict,
+		StartedAt:  now,
+		EndedAt:    now,
+		LogLevel:   event.LogLevelWarn,
+		Metadata:   &event.SyncConflictEventMetadata{Conflicts: unreported},
+	}); err != nil {
+		logger.Log("err", errors.Wrap(err, "logging sync conflict event"))
+	}
+}
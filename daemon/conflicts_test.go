@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/cluster/kubernetes"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestCheckSyncConflicts(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	replicas := cluster.SyncConflict{ID: hello, Field: "spec.replicas", Applied: "3", Live: "5", Manager: "kube-controller-manager"}
+	var conflicts []cluster.SyncConflict
+	var asked []flux.ResourceID
+	store := &event.Buffer{}
+	d := &Daemon{
+		Cluster: &cluster.Mock{
+			SyncConflictsFunc: func(ids []flux.ResourceID) ([]cluster.SyncConflict, error) {
+				asked = ids
+				return conflicts, nil
+			},
+		},
+		EventStore: store,
+		Logger:     log.NewNopLogger(),
+		LoopVars:   &LoopVars{},
+	}
+	resources, err := (&kubernetes.Manifests{}).ParseManifests([]byte(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+  namespace: default
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lastID event.EventID
+	check := func() []event.Event {
+		d.checkSyncConflicts(resources, log.NewNopLogger())
+		events := store.Since(lastID, 0)
+		if len(events) > 0 {
+			lastID = events[len(events)-1].ID
+		}
+		return events
+	}
+
+	if events := check(); len(events) != 0 {
+		t.Errorf("expected no event without conflicts, got %+v", events)
+	}
+	if len(asked) != 1 || asked[0] != hello {
+		t.Errorf("expected to be asked about the resources synced, got %v", asked)
+	}
+
+	conflicts = []cluster.SyncConflict{replicas}
+	events := check()
+	if len(events) != 1 || events[0].Type != event.EventSyncConflict || events[0].LogLevel != event.LogLevelWarn {
+		t.Fatalf("expected a sync conflict warning, got %+v", events)
+	}
+	if metadata := events[0].Metadata.(*event.SyncConflictEventMetadata); len(metadata.Conflicts) != 1 || metadata.Conflicts[0] != replicas {
+		t.Errorf("expected the conflict in the event, got %+v", metadata.Conflicts)
+	}
+
+	// The same conflict isn't reported again while it lasts ...
+	if events := check(); len(events) != 0 {
+		t.Errorf("expected a conflict to be reported once, got %+v", events)
+	}
+	// ... but is if it comes back
+	conflicts = nil
+	check()
+	conflicts = []cluster.SyncConflict{replicas}
+	if events := check(); len(events) != 1 {
+		t.Errorf("expected a conflict that came back to be reported, got %+v", events)
+	}
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+)
+
+// In order, so that the highest criticality of several workloads can
+// be found
+var criticalities = map[string]int{
+	event.CriticalityLow:    1,
+	event.CriticalityMedium: 2,
+	event.CriticalityHigh:   3,
+}
+
+// criticalityOf gives the criticality of a workload: the value of the
+// annotation or label CriticalityKey (annotation first), if it's one
+// of low, medium and high; or the empty string otherwise.
+func (d *Daemon) criticalityOf(c cluster.Controller) string {
+	value := c.Annotations[d.CriticalityKey]
+	if value == "" {
+		value = c.Labels[d.CriticalityKey]
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, ok := criticalities[value]; !ok {
+		return ""
+	}
+	return value
+}
+
+// addCriticality fills in the highest criticality of the workloads an
+// event concerns, if it doesn't have one already, and adjusts its log
+// level to suit: warnings about high criticality workloads are raised
+// to errors, and errors about low criticality workloads are lowered
+// to warnings.
+func (d *Daemon) addCriticality(ev *event.Event) error {
+	if d.CriticalityKey == "" || ev.Criticality != "" || len(ev.ServiceIDs) == 0 {
+		return nil
+	}
+	controllers, err := d.Cluster.SomeControllers(ev.ServiceIDs)
+	if err != nil {
+		return errors.Wrap(err, "getting workloads to find their criticality")
+	}
+	for _, c := range controllers {
+		if crit := d.criticalityOf(c); criticalities[crit] > criticalities[ev.Criticality] {
+			ev.Criticality = crit
+		}
+	}
+	switch {
+	case ev.Criticality == event.CriticalityHigh && ev.LogLevel == event.LogLevelWarn:
+		ev.LogLevel = event.LogLevelError
+	case ev.Criticality == event.CriticalityLow && ev.LogLevel == event.LogLevelError:
+		ev.LogLevel = event.LogLevelWarn
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestCriticality(t *testing.T) {
+	const key = "flux.weave.works/criticality"
+	payments := cluster.Controller{
+		ID:          flux.MustParseResourceID("default:deployment/payments"),
+		Annotations: map[string]string{key: "High"},
+	}
+	batch := cluster.Controller{
+		ID:     flux.MustParseResourceID("default:deployment/batch"),
+		Labels: map[string]string{key: "low"},
+	}
+	other := cluster.Controller{
+		ID:          flux.MustParseResourceID("default:deployment/other"),
+		Annotations: map[string]string{key: "extreme"},
+	}
+	byID := map[flux.ResourceID]cluster.Controller{payments.ID: payments, batch.ID: batch, other.ID: other}
+	d := &Daemon{
+		Cluster: &cluster.Mock{
+			SomeServicesFunc: func(ids []flux.ResourceID) ([]cluster.Controller, error) {
+				var res []cluster.Controller
+				for _, id := range ids {
+					res = append(res, byID[id])
+				}
+				return res, nil
+			},
+		},
+		CriticalityKey: key,
+	}
+
+	for _, c := range []struct {
+		ids         []flux.ResourceID
+		level       string
+		criticality string
+		expected    string
+	}{
+		{[]flux.ResourceID{payments.ID, batch.ID}, event.LogLevelWarn, event.CriticalityHigh, event.LogLevelError},
+		{[]flux.ResourceID{batch.ID}, event.LogLevelError, event.CriticalityLow, event.LogLevelWarn},
+		{[]flux.ResourceID{batch.ID}, event.LogLevelInfo, event.CriticalityLow, event.LogLevelInfo},
+		// Unrecognised values are ignored
+		{[]flux.ResourceID{other.ID}, event.LogLevelWarn, "", event.LogLevelWarn},
+	} {
+		ev := event.Event{ServiceIDs: c.ids, LogLevel: c.level}
+		if err := d.addCriticality(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Criticality != c.criticality || ev.LogLevel != c.expected {
+			t.Errorf("%v at %s: expected criticality %q and level %s, got %q and %s", c.ids, c.level, c.criticality, c.expected, ev.Criticality, ev.LogLevel)
+		}
+	}
+
+	d.CriticalityKey = ""
+	ev := event.Event{ServiceIDs: []flux.ResourceID{payments.ID}, LogLevel: event.LogLevelWarn}
+	if err := d.addCriticality(&ev); err != nil || ev.Criticality != "" || ev.LogLevel != event.LogLevelWarn {
+		t.Errorf("expected nothing to change without a criticality key, got %q and %s (err %v)", ev.Criticality, ev.LogLevel, err)
+	}
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/api"
@@ -18,16 +20,29 @@ import (
 	"github.com/weaveworks/flux/api/v9"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/freeze"
 	"github.com/weaveworks/flux/git"
 	"github.com/weaveworks/flux/guid"
 	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/integrations/helm/chartrepo"
 	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/registry"
 	"github.com/weaveworks/flux/release"
 	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/scm"
+	"github.com/weaveworks/flux/source"
+	"github.com/weaveworks/flux/supplychain"
+	fluxsync "github.com/weaveworks/flux/sync"
+	"github.com/weaveworks/flux/tracing"
 	"github.com/weaveworks/flux/update"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v27"
 )
 
 const (
@@ -53,6 +68,110 @@ type Daemon struct {
 	JobStatusCache *job.StatusCache
 	EventWriter    event.EventWriter
 	Logger         log.Logger
+	// Where events are kept for listing and annotating; if not set,
+	// the most recent are kept in memory
+	EventStore event.EventStore
+	// If set, used to record where to find SBOMs for released images
+	SBOMs supplychain.SBOMLocator
+	// If set, images must pass verification before being
+	// automatically released
+	ImageVerifier *supplychain.VerificationCache
+	// Applied to images when writing them to manifests, e.g., to
+	// pull from a mirror
+	ImageRewrites image.RewriteRules
+	// Consulted before releasing to workloads with a release gate
+	// policy
+	ReleaseGate release.Gate
+	// If set, images are copied from the registry they're promoted
+	// from before being released
+	Promotion *release.Promotion
+	// If set, automated releases are checked for images that can't
+	// be pulled before they're committed
+	PullCheck *release.PullCheck
+	// If set, the manifests synced are fetched from here (e.g., an
+	// OCI artifact) rather than the git repo
+	ManifestStore source.Store
+	// If set, what's synced from the git repo is the latest tag that
+	// matches (e.g., to promote by tagging), rather than the head of
+	// the branch; releases and policy changes are still committed to
+	// the branch
+	SyncTags policy.Pattern
+	// If a commit at the head of the branch has one of these in its
+	// message (e.g., "[flux skip]"), it's recorded but not synced,
+	// until there's a commit after it that doesn't
+	SkipSyncMarkers []string
+	// Other git repos, each with its own branch and paths, whose
+	// manifests are synced along with those above. Releases, policy
+	// changes and the sync tag are only for the git repo
+	ExtraRepos []source.GitExport
+	// If set, spans are recorded around syncs, releases and commits,
+	// and events are given the IDs of the spans they're logged in
+	Tracer *tracing.Tracer
+	// If set, used to look for new versions of charts for automated
+	// FluxHelmReleases
+	ChartRepos *chartrepo.Client
+	// The annotations and labels, in order of preference, that say
+	// which team owns a workload
+	OwnerKeys []string
+	// The annotation or label that says how critical a workload is
+	// (low, medium or high); if empty, criticality is not looked for
+	CriticalityKey string
+	// If set, says when releases are frozen
+	Freeze freeze.Schedule
+	// If true, automation is only observed: what would be released
+	// is recorded as events, but not committed
+	ObserveAutomation bool
+	// If set, automated releases are pushed to a branch of their
+	// own, and a pull request opened for each, rather than committed
+	// to the branch synced
+	PullRequests scm.Provider
+	// How automated releases are grouped into pull requests; one of
+	// the PullRequestsPer* groupings, or per automation run if empty
+	PullRequestGrouping string
+	// With PullRequestsPerInterval, how long to wait after proposing
+	// automated releases before proposing more
+	PullRequestInterval time.Duration
+	// Limits on how much a sync can change without being confirmed
+	SyncGuard fluxsync.Guard
+	// If true, commits are only synced if they're signed by one of
+	// the GPG keys given, or if none are given, by any key imported
+	VerifySignatures bool
+	SignatureKeys    []string
+	// When automation backs off rather than add to the load
+	AutomationBackoff AutomationBackoff
+	// When automation is suspended for a workload that keeps failing
+	AutomationBreaker AutomationBreaker
+	// If set, manifests are generated and committed for namespaces
+	// that resources in git are in, but which aren't defined in git
+	NamespaceBootstrap *cluster.NamespaceBootstrap
+	// If set, the resources loaded from the repo are cached by
+	// revision
+	ManifestCache *ManifestCache
+	// Whether to check that the cluster has room for the pods a
+	// release will start, before committing it
+	CapacityCheck CapacityCheck
+	// Where to get metrics from for MetricsSnapshot; if not set, the
+	// default Prometheus registry
+	MetricsGatherer stdprometheus.Gatherer
+	// Which events to keep, when pruning the event store
+	EventRetention event.RetentionPolicy
+	// The key the event store's chain of hashes is made with, if
+	// events are chained, and how often to log the head of the chain
+	// (see ChainHeadLoop)
+	EventChainKey          []byte
+	EventChainHeadInterval time.Duration
+	// If set, a run of failures of the same kind for a workload is
+	// logged as one escalated error, rather than event by event
+	EventEscalation *event.Escalator
+	// Whether to look, before each sync, for fields that something
+	// else has changed since flux applied them
+	DetectSyncConflicts bool
+	// The event filter of each webhook subscriber, by name, for
+	// replaying the events a subscriber missed
+	EventSubscriptions map[string]event.EventFilter
+	// The key compliance reports are signed with; if nil, they
+	// aren't signed
+	ReportKey ed25519.PrivateKey
 	// bookkeeping
 	*LoopVars
 }
@@ -72,12 +191,19 @@ func (d *Daemon) Export(ctx context.Context) ([]byte, error) {
 	return d.Cluster.Export()
 }
 
+// ExportCluster exports the resources selected from the cluster,
+// cleaned up so they can be committed to git, e.g., when starting to
+// manage an existing cluster with flux.
+func (d *Daemon) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) ([]byte, error) {
+	return d.Cluster.ExportResources(opts.Namespaces, opts.Kinds)
+}
+
 func (d *Daemon) getResources(ctx context.Context) (map[string]resource.Resource, v6.ReadOnlyReason, error) {
 	var resources map[string]resource.Resource
 	var globalReadOnly v6.ReadOnlyReason
-	err := d.WithClone(ctx, func(checkout *git.Checkout) error {
+	err := d.WithReadOnlyClone(ctx, func(checkout *git.Checkout) error {
 		var err error
-		resources, err = d.Manifests.LoadManifests(checkout.Dir(), checkout.ManifestDirs())
+		resources, err = d.loadCheckout(ctx, checkout)
 		return err
 	})
 
@@ -135,6 +261,8 @@ func (d *Daemon) ListServicesWithOptions(ctx context.Context, opts v11.ListServi
 			readOnly = missingReason
 		case service.IsSystem:
 			readOnly = v6.ReadOnlySystem
+		case service.Immutable:
+			readOnly = v6.ReadOnlyNotAutomatable
 		}
 		res = append(res, v6.ControllerStatus{
 			ID:         service.ID,
@@ -148,12 +276,71 @@ func (d *Daemon) ListServicesWithOptions(ctx context.Context, opts v11.ListServi
 			Locked:     policies.Has(policy.Locked),
 			Ignore:     policies.Has(policy.Ignore),
 			Policies:   policies.ToStringMap(),
+			Owner:      d.ownerOf(service),
 		})
 	}
 
 	return res, nil
 }
 
+// ListDeployments reports what each workload is running, along with
+// the revision last synced and the workload's latest release. This is
+// for the benefit of service catalogs, which want a summary rather
+// than everything ListServices gives.
+func (d *Daemon) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) ([]v12.WorkloadDeployment, error) {
+	clusterServices, err := d.Cluster.AllControllers(opts.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting services from cluster")
+	}
+
+	res := []v12.WorkloadDeployment{}
+	for _, service := range clusterServices {
+		if service.IsSystem {
+			continue
+		}
+		var containers []v12.DeployedContainer
+		for _, c := range service.ContainersOrNil() {
+			containers = append(containers, v12.DeployedContainer{
+				Name:  c.Name,
+				Image: c.Image.String(),
+			})
+		}
+		deployment := v12.WorkloadDeployment{
+			ID:         service.ID,
+			Containers: containers,
+			Status:     service.Status,
+			Rollout:    v12.Rollout(service.Rollout),
+			Labels:     service.Labels,
+			Owner:      d.ownerOf(service),
+		}
+		if d.LoopVars != nil {
+			deployment.Revision = d.lastSyncedRevision()
+		}
+		if deployment.LastRelease, err = d.lastRelease(service.ID); err != nil {
+			return nil, errors.Wrapf(err, "looking up last release of %s", service.ID)
+		}
+		res = append(res, deployment)
+	}
+	return res, nil
+}
+
+// lastRelease gives the most recent release, automated release or
+// rollback of the workload in the event store, or nil if there's
+// none there (or no event store).
+func (d *Daemon) lastRelease(id flux.ResourceID) (*event.Event, error) {
+	store := d.eventStore()
+	if store == nil {
+		return nil, nil
+	}
+	events, err := store.EventsForService(id, event.Page{Limit: 1}, event.EventFilter{
+		Types: []string{event.EventRelease, event.EventAutoRelease, event.EventRollback},
+	})
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return &events[len(events)-1], nil
+}
+
 type clusterContainers []cluster.Controller
 
 func (cs clusterContainers) Len() int {
@@ -164,6 +351,134 @@ func (cs clusterContainers) Containers(i int) []resource.Container {
 	return cs[i].ContainersOrNil()
 }
 
+// ListEvents returns the events logged recently by the daemon, so
+// they can be followed e.g., by fluxctl.
+func (d *Daemon) ListEvents(ctx context.Context, opts v13.ListEventsOptions) ([]event.Event, error) {
+	store := d.eventStore()
+	if store == nil {
+		return nil, nil
+	}
+	return store.AllEvents(event.Page{After: opts.After, Limit: opts.Limit}, event.EventFilter{})
+}
+
+// The most events EventHistory returns at once
+const maxEventHistoryLimit = 500
+
+// EventHistory returns a page of the events kept by the daemon,
+// optionally only those concerning a workload, along with where to
+// carry on from if some were left out.
+func (d *Daemon) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	var history v22.EventHistory
+	store := d.eventStore()
+	if store == nil {
+		return history, nil
+	}
+	if level := opts.Filter.MinLogLevel; level != "" {
+		if err := event.ValidateLogLevel(level); err != nil {
+			return history, err
+		}
+	}
+	limit := opts.Limit
+	if limit <= 0 || limit > maxEventHistoryLimit {
+		limit = maxEventHistoryLimit
+	}
+	// Ask for one more than the limit, to find out whether there are
+	// older events than those returned
+	page := event.Page{After: opts.After, Before: opts.Before, Limit: limit + 1}
+	var err error
+	if opts.Workload != nil {
+		history.Events, err = store.EventsForService(*opts.Workload, page, opts.Filter)
+	} else if len(opts.Filter.Namespaces) == 1 {
+		// The filter would select the same events, but the store may
+		// be able to find those in a namespace more directly
+		history.Events, err = store.EventsForNamespace(opts.Filter.Namespaces[0], page, opts.Filter)
+	} else {
+		history.Events, err = store.AllEvents(page, opts.Filter)
+	}
+	if err != nil {
+		return history, err
+	}
+	if len(history.Events) > limit {
+		history.Events = history.Events[len(history.Events)-limit:]
+		history.Older = history.Events[0].ID
+	}
+	return history, nil
+}
+
+// AnnotateEvent attaches a comment or acknowledgement to one of the
+// events kept by the daemon.
+func (d *Daemon) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (event.Event, error) {
+	if opts.Comment == "" && !opts.Acknowledge {
+		return event.Event{}, errors.New("an annotation needs a comment, or to acknowledge the event")
+	}
+	store := d.eventStore()
+	if store == nil {
+		return event.Event{}, unknownEventError(opts.ID)
+	}
+	annotated, err := store.AnnotateEvent(opts.ID, event.Annotation{
+		Time:         time.Now().UTC(),
+		User:         opts.User,
+		Comment:      opts.Comment,
+		Acknowledged: opts.Acknowledge,
+	})
+	if err == event.ErrNoSuchEvent {
+		return event.Event{}, unknownEventError(opts.ID)
+	}
+	return annotated, err
+}
+
+// LogEvents records a batch of events logged elsewhere, e.g., by an
+// agent that kept them while it couldn't reach the daemon. They are
+// kept in the event store in one go, then sent upstream in turn.
+func (d *Daemon) LogEvents(ctx context.Context, opts v27.LogEventsOptions) error {
+	events := make([]event.Event, len(opts.Events))
+	for i, ev := range opts.Events {
+		if ev.Type == "" {
+			return errors.Errorf("event %d in the batch has no type", i)
+		}
+		// The store gives each event its ID
+		ev.ID = 0
+		d.addWorkloadDetails(&ev)
+		events[i] = ev
+	}
+	if d.EventEscalation != nil {
+		var escalated []event.Event
+		for _, ev := range events {
+			if ev, ok := d.EventEscalation.Escalate(ev); ok {
+				escalated = append(escalated, ev)
+			}
+		}
+		events = escalated
+	}
+	if store := d.eventStore(); store != nil {
+		if err := store.LogEvents(events); err != nil {
+			return errors.Wrap(err, "storing events")
+		}
+	}
+	if d.EventWriter == nil {
+		return nil
+	}
+	var firstErr error
+	for _, ev := range events {
+		if err := d.EventWriter.LogEvent(ev); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// eventStore gives the store for events logged: the one given, or
+// else the events kept by the loop.
+func (d *Daemon) eventStore() event.EventStore {
+	if d.EventStore != nil {
+		return d.EventStore
+	}
+	if d.LoopVars != nil {
+		return &d.recentEvents
+	}
+	return nil
+}
+
 // ListImages - deprecated from v10, lists the images available for set of services
 func (d *Daemon) ListImages(ctx context.Context, spec update.ResourceSpec) ([]v6.ImageStatus, error) {
 	return d.ListImagesWithOptions(ctx, v10.ListImagesOptions{Spec: spec})
@@ -225,6 +540,13 @@ func (d *Daemon) makeJobFromUpdate(update updateFunc) jobFunc {
 			if err != nil {
 				return err
 			}
+			if result.Revision != "" && d.GitConfig.SigningKey != "" {
+				sig, err := working.HeadSignature(ctx)
+				if err != nil {
+					return err
+				}
+				result.SigningKey = sig.Fingerprint
+			}
 			return nil
 		})
 		if err != nil {
@@ -236,8 +558,10 @@ func (d *Daemon) makeJobFromUpdate(update updateFunc) jobFunc {
 
 // executeJob runs a job func and keeps track of its status, so the
 // daemon can report it when asked.
-func (d *Daemon) executeJob(id job.ID, do jobFunc, logger log.Logger) (job.Result, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultJobTimeout)
+func (d *Daemon) executeJob(parent context.Context, id job.ID, do jobFunc, logger log.Logger) (job.Result, error) {
+	// The job can carry on after whatever asked for it (e.g., an API
+	// request) is done, but is still part of its trace
+	ctx, cancel := context.WithTimeout(tracing.Detach(parent), defaultJobTimeout)
 	defer cancel()
 	d.JobStatusCache.SetStatus(id, job.Status{StatusString: job.StatusRunning})
 	result, err := do(ctx, id, logger)
@@ -254,7 +578,9 @@ func (d *Daemon) executeJob(id job.ID, do jobFunc, logger log.Logger) (job.Resul
 func (d *Daemon) makeLoggingJobFunc(f jobFunc) jobFunc {
 	return func(ctx context.Context, id job.ID, logger log.Logger) (job.Result, error) {
 		started := time.Now().UTC()
+		ctx, span := d.Tracer.Start(ctx, "job", "job.id", string(id))
 		result, err := f(ctx, id, logger)
+		span.End(err)
 		if err != nil {
 			return result, err
 		}
@@ -268,33 +594,42 @@ func (d *Daemon) makeLoggingJobFunc(f jobFunc) jobFunc {
 			}
 
 			metadata := &event.CommitEventMetadata{
-				Revision: result.Revision,
-				Spec:     result.Spec,
-				Result:   result.Result,
+				Revision:   result.Revision,
+				Spec:       result.Spec,
+				Result:     result.Result,
+				SigningKey:  result.SigningKey,
+				PullRequest: result.PullRequest,
 			}
 
 			return result, d.LogEvent(event.Event{
-				ServiceIDs: serviceIDs,
-				Type:       event.EventCommit,
-				StartedAt:  started,
-				EndedAt:    started,
-				LogLevel:   event.LogLevelInfo,
-				Metadata:   metadata,
+				ServiceIDs:    serviceIDs,
+				Type:          event.EventCommit,
+				StartedAt:     started,
+				EndedAt:       started,
+				LogLevel:      event.LogLevelInfo,
+				Metadata:      metadata,
+				CorrelationID: string(id),
+				TraceID:       span.TraceID(),
+				SpanID:        span.SpanID(),
 			})
 		}
 		return result, nil
 	}
 }
 
-// queueJob queues a job func to be executed.
-func (d *Daemon) queueJob(do jobFunc) job.ID {
+// queueJob queues a job func to be executed. The kind of job and the
+// workloads it concerns decide where in the queue it goes. The job is
+// traced as part of the span in ctx, if there is one.
+func (d *Daemon) queueJob(ctx context.Context, kind string, workloads []flux.ResourceID, do jobFunc) job.ID {
 	id := job.ID(guid.New())
 	enqueuedAt := time.Now()
 	d.Jobs.Enqueue(&job.Job{
-		ID: id,
+		ID:        id,
+		Kind:      kind,
+		Workloads: workloads,
 		Do: func(logger log.Logger) error {
 			queueDuration.Observe(time.Since(enqueuedAt).Seconds())
-			_, err := d.executeJob(id, do, logger)
+			_, err := d.executeJob(ctx, id, do, logger)
 			if err != nil {
 				return err
 			}
@@ -306,24 +641,93 @@ func (d *Daemon) queueJob(do jobFunc) job.ID {
 	return id
 }
 
+// jobWorkloads gives the workloads the changes given concern, so
+// that jobs for the same workload are run in the order they were
+// asked for. It gives nil if the changes don't name the workloads,
+// e.g., for a release to all workloads.
+func jobWorkloads(changes interface{}) []flux.ResourceID {
+	var ids []flux.ResourceID
+	switch c := changes.(type) {
+	case update.ReleaseSpec:
+		for _, s := range c.ServiceSpecs {
+			id, err := s.AsID()
+			if err != nil {
+				// <all>, or something that won't parse
+				return nil
+			}
+			ids = append(ids, id)
+		}
+	case *update.Automated:
+		for _, change := range c.Changes {
+			ids = append(ids, change.ServiceID)
+		}
+	case update.ContainerSpecs:
+		for id := range c.ContainerSpecs {
+			ids = append(ids, id)
+		}
+	case rollbackChanges:
+		return jobWorkloads(c.ContainerSpecs)
+	case update.RestartSpec:
+		ids = c.Workloads
+	case policy.Updates:
+		for id := range c {
+			ids = append(ids, id)
+		}
+	case update.ChartUpdates:
+		for _, change := range c.Changes {
+			ids = append(ids, change.ResourceID)
+		}
+	}
+	return ids
+}
+
 // Apply the desired changes to the config files
 func (d *Daemon) UpdateManifests(ctx context.Context, spec update.Spec) (job.ID, error) {
 	var id job.ID
 	if spec.Type == "" {
 		return id, errors.New("no type in update spec")
 	}
+	if err := d.checkFreeze(spec); err != nil {
+		return id, err
+	}
 	switch s := spec.Spec.(type) {
 	case release.Changes:
 		if s.ReleaseKind() == update.ReleaseKindPlan {
 			id := job.ID(guid.New())
-			_, err := d.executeJob(id, d.makeJobFromUpdate(d.release(spec, s)), d.Logger)
+			_, err := d.executeJob(ctx, id, d.makeJobFromUpdate(d.release(spec, s)), d.Logger)
+			return id, err
+		}
+		return d.queueJob(ctx, spec.Type, jobWorkloads(s), d.makeLoggingJobFunc(d.makeJobFromUpdate(d.release(spec, s)))), nil
+	case update.RollbackSpec:
+		changes, err := d.rollback(s)
+		if err != nil {
+			return id, err
+		}
+		spec.Spec = changes.spec
+		if changes.ReleaseKind() == update.ReleaseKindPlan {
+			id := job.ID(guid.New())
+			_, err := d.executeJob(ctx, id, d.makeJobFromUpdate(d.release(spec, changes)), d.Logger)
+			return id, err
+		}
+		return d.queueJob(ctx, spec.Type, jobWorkloads(changes), d.makeLoggingJobFunc(d.makeJobFromUpdate(d.release(spec, changes)))), nil
+	case update.RestartSpec:
+		if s.Kind == update.ReleaseKindPlan {
+			id := job.ID(guid.New())
+			_, err := d.executeJob(ctx, id, d.restart(spec, s), d.Logger)
 			return id, err
 		}
-		return d.queueJob(d.makeLoggingJobFunc(d.makeJobFromUpdate(d.release(spec, s)))), nil
+		return d.queueJob(ctx, spec.Type, jobWorkloads(s), d.restart(spec, s)), nil
 	case policy.Updates:
-		return d.queueJob(d.makeLoggingJobFunc(d.makeJobFromUpdate(d.updatePolicy(spec, s)))), nil
+		return d.queueJob(ctx, spec.Type, jobWorkloads(s), d.makeLoggingJobFunc(d.makeJobFromUpdate(d.updatePolicy(spec, s)))), nil
+	case update.ChartUpdates:
+		return d.queueJob(ctx, spec.Type, jobWorkloads(s), d.makeLoggingJobFunc(d.makeJobFromUpdate(d.updateCharts(spec, s)))), nil
 	case update.ManualSync:
-		return d.queueJob(d.sync()), nil
+		if s.Confirm != "" {
+			if err := d.confirmSync(s.Confirm); err != nil {
+				return id, err
+			}
+		}
+		return d.queueJob(ctx, spec.Type, nil, d.sync()), nil
 	default:
 		return id, fmt.Errorf(`unknown update type "%s"`, spec.Type)
 	}
@@ -342,6 +746,9 @@ func (d *Daemon) sync() jobFunc {
 		if err != nil {
 			return result, err
 		}
+		if held, reason := d.heldSync(); held != "" && held == head {
+			return result, syncHeldError(held, reason)
+		}
 		result.Revision = head
 		return result, nil
 	}
@@ -351,6 +758,7 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) updateFu
 	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (job.Result, error) {
 		// For each update
 		var serviceIDs []flux.ResourceID
+		var changes []event.PolicyChange
 		result := job.Result{
 			Spec:   &spec,
 			Result: update.Result{},
@@ -362,7 +770,7 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) updateFu
 		var anythingAutomated bool
 
 		for serviceID, u := range updates {
-			if policy.Set(u.Add).Has(policy.Automated) {
+			if policy.Set(u.Add).Has(policy.Automated) || policy.Set(u.Add).Has(policy.Observe) {
 				anythingAutomated = true
 			}
 			// find the service manifest
@@ -381,6 +789,11 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) updateFu
 					}
 				} else {
 					serviceIDs = append(serviceIDs, serviceID)
+					changes = append(changes, event.PolicyChange{
+						ID:     serviceID,
+						Before: d.manifestPolicies(def, serviceID),
+						After:  d.manifestPolicies(newDef, serviceID),
+					})
 					result.Result[serviceID] = update.ControllerResult{
 						Status: update.ReleaseStatusSuccess,
 					}
@@ -407,8 +820,12 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) updateFu
 		if d.GitConfig.SetAuthor {
 			commitAuthor = spec.Cause.User
 		}
-		commitAction := git.CommitAction{Author: commitAuthor, Message: policyCommitMessage(updates, spec.Cause)}
-		if err := working.CommitAndPush(ctx, commitAction, &note{JobID: jobID, Spec: spec}); err != nil {
+		commitAction := git.CommitAction{
+			Author:   commitAuthor,
+			Message:  policyCommitMessage(updates, spec.Cause),
+			Trailers: commitTrailers(jobID, spec, serviceIDs),
+		}
+		if err := d.commitAndPush(ctx, working, commitAction, &note{JobID: jobID, Spec: spec}); err != nil {
 			// On the chance pushing failed because it was not
 			// possible to fast-forward, ask for a sync so the
 			// next attempt is more likely to succeed.
@@ -424,23 +841,114 @@ func (d *Daemon) updatePolicy(spec update.Spec, updates policy.Updates) updateFu
 		if err != nil {
 			return result, err
 		}
+		d.logPolicyEvents(ctx, updates, changes, result.Revision, spec.Cause, logger)
 		return result, nil
 	}
 }
 
+// manifestPolicies gives the policies of the workload given, as they
+// are in the manifest, or nil if they can't be read.
+func (d *Daemon) manifestPolicies(def []byte, id flux.ResourceID) policy.Set {
+	resources, err := d.Manifests.ParseManifests(def)
+	if err != nil {
+		return nil
+	}
+	for _, res := range resources {
+		if res.ResourceID() == id {
+			return res.Policy()
+		}
+	}
+	return nil
+}
+
+// logPolicyEvents logs an event for each kind of policy change
+// committed (automating, locking, and so on), with the policies each
+// workload changed had before and after.
+func (d *Daemon) logPolicyEvents(ctx context.Context, updates policy.Updates, changes []event.PolicyChange, revision string, cause update.Cause, logger log.Logger) {
+	changed := policy.Updates{}
+	byID := map[flux.ResourceID]event.PolicyChange{}
+	for _, c := range changes {
+		changed[c.ID] = updates[c.ID]
+		byID[c.ID] = c
+	}
+	span := tracing.SpanFromContext(ctx)
+	events := policyEvents(changed, time.Now().UTC())
+	for _, eventType := range sortedEventTypes(events) {
+		e := events[eventType]
+		sort.Slice(e.ServiceIDs, func(i, j int) bool { return e.ServiceIDs[i].String() < e.ServiceIDs[j].String() })
+		metadata := &event.PolicyUpdateEventMetadata{Revision: revision, Cause: cause}
+		for _, id := range e.ServiceIDs {
+			metadata.Changes = append(metadata.Changes, byID[id])
+		}
+		e.Metadata = metadata
+		if eventType == event.EventLock || eventType == event.EventUnlock {
+			e.Metadata = lockEventMetadata(metadata)
+		}
+		e.TraceID = span.TraceID()
+		e.SpanID = span.SpanID()
+		if err := d.LogEvent(e); err != nil {
+			logger.Log("err", errors.Wrap(err, "logging policy event"))
+		}
+	}
+}
+
+// lockEventMetadata gives who locked or unlocked the workloads, why,
+// and until when: as recorded in the lock's policies, if they were
+// set; or otherwise, from the cause of the change.
+func lockEventMetadata(m *event.PolicyUpdateEventMetadata) *event.LockEventMetadata {
+	lock := &event.LockEventMetadata{
+		PolicyUpdateEventMetadata: *m,
+		User:                      m.Cause.User,
+		Reason:                    m.Cause.Message,
+	}
+	for _, c := range m.Changes {
+		if user, ok := c.After.Get(policy.LockedUser); ok && user != "" {
+			lock.User = user
+		}
+		if msg, ok := c.After.Get(policy.LockedMsg); ok && msg != "" {
+			lock.Reason = msg
+		}
+		if until, ok := c.After.Get(policy.LockedUntil); ok {
+			lock.UntilRevision = until
+		}
+	}
+	return lock
+}
+
+func sortedEventTypes(events map[string]event.Event) []string {
+	var types []string
+	for t := range events {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
 func (d *Daemon) release(spec update.Spec, c release.Changes) updateFunc {
-	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (job.Result, error) {
-		rc := release.NewReleaseContext(d.Cluster, d.Manifests, d.Registry, working)
-		result, err := release.Release(rc, c, logger)
+	return func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (_ job.Result, err error) {
+		ctx, span := d.Tracer.Start(ctx, "release",
+			"release.type", string(spec.Type),
+			"release.kind", string(c.ReleaseKind()))
+		defer func() { span.End(err) }()
+
+		rc := d.releaseContext(ctx, working)
+		result, err := release.Release(ctx, rc, c, logger)
 
 		var zero job.Result
 		if err != nil {
 			return zero, err
 		}
+		if spec.Type == update.Auto && c.ReleaseKind() == update.ReleaseKindExecute {
+			d.automationFailed(failedReleases(result), automationReleaseFailed, time.Now().UTC(), logger)
+		}
 
-		var revision string
+		var revision, pullRequest string
 
 		if c.ReleaseKind() == update.ReleaseKindExecute {
+			capacity, err := d.checkCapacity(spec, result, logger)
+			if err != nil {
+				return zero, err
+			}
 			commitMsg := spec.Cause.Message
 			if commitMsg == "" {
 				commitMsg = c.CommitMessage(result)
@@ -449,8 +957,22 @@ func (d *Daemon) release(spec update.Spec, c release.Changes) updateFunc {
 			if d.GitConfig.SetAuthor {
 				commitAuthor = spec.Cause.User
 			}
-			commitAction := git.CommitAction{Author: commitAuthor, Message: commitMsg}
-			if err := working.CommitAndPush(ctx, commitAction, &note{JobID: jobID, Spec: spec, Result: result}); err != nil {
+			commitAction := git.CommitAction{
+				Author:   commitAuthor,
+				Message:  commitMsg,
+				Trailers: commitTrailers(jobID, spec, result.AffectedResources()),
+			}
+			n := &note{JobID: jobID, Spec: spec, Result: result, Capacity: capacity}
+			if spec.Type == update.Auto && d.PullRequests != nil {
+				pullRequest, err = d.commitAndOpenPullRequest(ctx, working, commitAction, n, logger)
+				if err != nil {
+					return zero, err
+				}
+				if pullRequest == "" {
+					// Already proposed, so nothing's committed
+					return job.Result{Spec: &spec, Result: result}, nil
+				}
+			} else if err := d.commitAndPush(ctx, working, commitAction, n); err != nil {
 				// On the chance pushing failed because it was not
 				// possible to fast-forward, ask the repo to fetch
 				// from upstream ASAP, so the next attempt is more
@@ -464,13 +986,23 @@ func (d *Daemon) release(spec update.Spec, c release.Changes) updateFunc {
 			}
 		}
 		return job.Result{
-			Revision: revision,
-			Spec:     &spec,
-			Result:   result,
+			Revision:    revision,
+			Spec:        &spec,
+			Result:      result,
+			PullRequest: pullRequest,
 		}, nil
 	}
 }
 
+// commitAndPush commits and pushes the changes made in the working
+// clone, in a span of its own.
+func (d *Daemon) commitAndPush(ctx context.Context, working *git.Checkout, commitAction git.CommitAction, n *note) error {
+	ctx, span := d.Tracer.Start(ctx, "commit")
+	err := working.CommitAndPush(ctx, commitAction, n)
+	span.End(err)
+	return err
+}
+
 // Tell the daemon to synchronise the cluster with the manifests in
 // the git repo. This has an error return value because upstream there
 // may be comms difficulties or other sources of problems; here, we
@@ -508,7 +1040,7 @@ func (d *Daemon) JobStatus(ctx context.Context, jobID job.ID) (job.Status, error
 	// means that even if fluxd restarts, we will at least remember
 	// jobs which have pushed a commit.
 	// FIXME(michael): consider looking at the repo for this, since read op
-	err := d.WithClone(ctx, func(working *git.Checkout) error {
+	err := d.WithReadOnlyClone(ctx, func(working *git.Checkout) error {
 		notes, err := working.NoteRevList(ctx)
 		if err != nil {
 			return errors.Wrap(err, "enumerating commit notes")
@@ -579,6 +1111,7 @@ func (d *Daemon) GitRepoConfig(ctx context.Context, regenerate bool) (v6.GitConf
 		},
 		PublicSSHKey: publicSSHKey,
 		Status:       status,
+		Progress:     d.Repo.Progress(),
 	}, nil
 }
 
@@ -593,7 +1126,49 @@ func (d *Daemon) WithClone(ctx context.Context, fn func(*git.Checkout) error) er
 	return fn(co)
 }
 
+// WithReadOnlyClone is like WithClone, for when the checkout is only
+// read from; it doesn't have to wait for, or copy, a clone of its own,
+// so it can be used while a release is in progress.
+func (d *Daemon) WithReadOnlyClone(ctx context.Context, fn func(*git.Checkout) error) error {
+	co, err := d.Repo.ReadOnlyClone(ctx, d.GitConfig)
+	if err != nil {
+		return err
+	}
+	defer co.Clean()
+	return fn(co)
+}
+
 func (d *Daemon) LogEvent(ev event.Event) error {
+	d.addWorkloadDetails(&ev)
+	var rolledBack []flux.ResourceID
+	if d.LoopVars != nil {
+		automated := d.lastAutomated(ev.ServiceIDs)
+		d.recordRelease(ev)
+		for _, id := range d.recordDelivery(ev) {
+			if automated[id] {
+				rolledBack = append(rolledBack, id)
+			}
+		}
+	}
+	// Once this event is logged, it can be followed by one saying
+	// automation is suspended
+	defer d.automationFailed(rolledBack, automationRolledBack, ev.StartedAt, d.Logger)
+	if d.EventEscalation != nil {
+		var ok bool
+		if ev, ok = d.EventEscalation.Escalate(ev); !ok {
+			d.Logger.Log("event", ev, "escalated", "held back")
+			return nil
+		}
+	}
+	if store := d.eventStore(); store != nil {
+		id, err := storeEvent(store, ev)
+		if err != nil {
+			d.Logger.Log("event", ev.Type, "err", errors.Wrap(err, "storing event"))
+		}
+		// So that webhook subscribers can ask for the events after
+		// this one
+		ev.ID = id
+	}
 	if d.EventWriter == nil {
 		d.Logger.Log("event", ev, "logupstream", "false")
 		return nil
@@ -604,6 +1179,33 @@ func (d *Daemon) LogEvent(ev event.Event) error {
 
 // vvv helpers vvv
 
+// addWorkloadDetails adds the owners and criticality of the workloads
+// concerned to the event. Better to have the event without them than
+// no event, so failures are only logged.
+func (d *Daemon) addWorkloadDetails(ev *event.Event) {
+	if err := d.addOwners(ev); err != nil {
+		d.Logger.Log("event", ev.Type, "err", err)
+	}
+	if err := d.addCriticality(ev); err != nil {
+		d.Logger.Log("event", ev.Type, "err", err)
+	}
+	if ev.Namespace == "" {
+		ev.Namespace = event.WorkloadNamespace(ev.ServiceIDs)
+	}
+}
+
+// storeEvent keeps the event in the store, coalescing it with the
+// most recent event if the store can and they're alike, so that,
+// e.g., a sync failing in the same way over and over is kept as one
+// event with a count of repeats. It returns the ID of the event kept,
+// if the store can say what that is.
+func storeEvent(store event.EventStore, ev event.Event) (event.EventID, error) {
+	if r, ok := store.(event.Recorder); ok {
+		return r.RecordEvent(ev)
+	}
+	return 0, store.LogEvent(ev)
+}
+
 func containers2containers(cs []resource.Container) []v6.Container {
 	res := make([]v6.Container, len(cs))
 	for i, c := range cs {
@@ -629,7 +1231,9 @@ func getServiceContainers(service cluster.Controller, imageRepos update.ImageRep
 		images := imageRepos.GetRepoImages(imageRepo)
 		currentImage := images.FindWithRef(c.Image)
 
-		container, err := v6.NewContainer(c.Name, images, currentImage, tagPattern, fields)
+		pinned, _ := policy.GetPin(policies, c.Name)
+
+		container, err := v6.NewPinnedContainer(c.Name, images, currentImage, tagPattern, pinned, fields)
 		if err != nil {
 			return res, err
 		}
@@ -692,6 +1296,8 @@ func policyEventTypes(u policy.Update) []string {
 			types[event.EventAutomate] = struct{}{}
 		case p == policy.Locked:
 			types[event.EventLock] = struct{}{}
+		case lockDetail(p) && policy.Set(u.Add).Has(policy.Locked):
+			// Part of the lock, so said in the lock event
 		default:
 			types[event.EventUpdatePolicy] = struct{}{}
 		}
@@ -703,6 +1309,7 @@ func policyEventTypes(u policy.Update) []string {
 			types[event.EventDeautomate] = struct{}{}
 		case p == policy.Locked:
 			types[event.EventUnlock] = struct{}{}
+		case lockDetail(p) && policy.Set(u.Remove).Has(policy.Locked):
 		default:
 			types[event.EventUpdatePolicy] = struct{}{}
 		}
@@ -714,3 +1321,9 @@ func policyEventTypes(u policy.Update) []string {
 	sort.Strings(result)
 	return result
 }
+
+// lockDetail says whether the policy is one saying more about a lock:
+// who locked the workload, why, and until when.
+func lockDetail(p policy.Policy) bool {
+	return p == policy.LockedUser || p == policy.LockedMsg || p == policy.LockedUntil
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,9 @@ import (
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
 	"github.com/weaveworks/flux/cluster"
@@ -136,6 +140,185 @@ func TestDaemon_ListServices(t *testing.T) {
 	}
 }
 
+// When I call list deployments, it should give the image each
+// workload is running, and the last release of it
+func TestDaemon_ListDeployments(t *testing.T) {
+	d, start, clean, _, _, _ := mockDaemon(t)
+	start()
+	defer clean()
+
+	ctx := context.Background()
+
+	release := event.Event{
+		ServiceIDs: []flux.ResourceID{flux.MustParseResourceID(svc)},
+		Type:       event.EventRelease,
+		StartedAt:  time.Now().UTC(),
+		EndedAt:    time.Now().UTC(),
+		LogLevel:   event.LogLevelInfo,
+	}
+	if err := d.LogEvent(release); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := d.ListDeployments(ctx, v12.ListDeploymentsOptions{Namespace: ns})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if len(s) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(s))
+	}
+	if len(s[0].Containers) != 1 || s[0].Containers[0].Image != currentHelloImage {
+		t.Errorf("Expected container running %q but got %#v", currentHelloImage, s[0].Containers)
+	}
+	if s[0].LastRelease == nil || s[0].LastRelease.Type != event.EventRelease {
+		t.Errorf("Expected last release to be recorded, but got %#v", s[0].LastRelease)
+	}
+
+	s, err = d.ListDeployments(ctx, v12.ListDeploymentsOptions{})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	for _, deployment := range s {
+		if deployment.ID.String() == anotherSvc && deployment.LastRelease != nil {
+			t.Errorf("Expected no release for %s, but got %#v", anotherSvc, deployment.LastRelease)
+		}
+	}
+
+	// The last release is whatever's in the event store, e.g., from
+	// before a restart
+	rollback := release
+	rollback.ServiceIDs = []flux.ResourceID{flux.MustParseResourceID(anotherSvc)}
+	rollback.Type = event.EventRollback
+	if err := d.eventStore().LogEvent(rollback); err != nil {
+		t.Fatal(err)
+	}
+	s, err = d.ListDeployments(ctx, v12.ListDeploymentsOptions{})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	var found bool
+	for _, deployment := range s {
+		if deployment.ID.String() == anotherSvc {
+			found = true
+			if deployment.LastRelease == nil || deployment.LastRelease.Type != event.EventRollback {
+				t.Errorf("Expected the rollback from the event store, but got %#v", deployment.LastRelease)
+			}
+			bytes, err := json.Marshal(deployment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(bytes), `"rollout":{"desired":`) {
+				t.Errorf("Expected lower-case rollout fields, but got %s", bytes)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected %s in the deployments", anotherSvc)
+	}
+}
+
+func TestDaemon_CheckWorkload(t *testing.T) {
+	d, start, clean, _, _, _ := mockDaemon(t)
+	start()
+	defer clean()
+
+	ctx := context.Background()
+
+	check, err := d.CheckWorkload(ctx, v14.CheckWorkloadOptions{Workload: flux.MustParseResourceID(svc)})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if !check.Eligible {
+		t.Errorf("Expected %s to be eligible for automation, but got problems %v", svc, check.Problems)
+	}
+
+	missing := flux.MustParseResourceID("default:deployment/nope")
+	check, err = d.CheckWorkload(ctx, v14.CheckWorkloadOptions{Workload: missing})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if check.Eligible || len(check.Problems) != 1 || !strings.Contains(check.Problems[0], "no manifest") {
+		t.Errorf("Expected %s to be ineligible because it has no manifest, but got %#v", missing, check)
+	}
+}
+
+// When I ask for an image report, it should say how far behind each
+// image is, and who uses it
+func TestDaemon_ImageReport(t *testing.T) {
+	d, start, clean, _, _, _ := mockDaemon(t)
+	start()
+	defer clean()
+
+	ctx := context.Background()
+
+	report, err := d.ImageReport(ctx, v15.ImageReportOptions{Namespace: ns})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if len(report) != 1 {
+		t.Fatalf("Expected one image in %s but got %#v", ns, report)
+	}
+	usage := report[0]
+	if usage.Image != currentHelloImage {
+		t.Errorf("Expected image %q but got %q", currentHelloImage, usage.Image)
+	}
+	// The newest image in the registry is newHelloImage, with
+	// currentHelloImage next
+	if usage.Latest != "2" || usage.Behind != 1 {
+		t.Errorf("Expected to be one behind tag %q but got %d behind %q", "2", usage.Behind, usage.Latest)
+	}
+	if len(usage.UsedBy) != 1 || usage.UsedBy[0].Workload.String() != svc || usage.UsedBy[0].Container != container {
+		t.Errorf("Expected to be used by %s (%s) but got %#v", svc, container, usage.UsedBy)
+	}
+
+	report, err = d.ImageReport(ctx, v15.ImageReportOptions{})
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if len(report) != 2 {
+		t.Errorf("Expected two images across the cluster but got %#v", report)
+	}
+}
+
+// When a workload has been running an image for longer than allowed,
+// and there's a newer one, I should be warned about it, once
+func TestDaemon_StaleImages(t *testing.T) {
+	d, start, clean, _, events, _ := mockDaemon(t)
+	start()
+	defer clean()
+
+	staleEvents := func() []event.Event {
+		events.Lock()
+		defer events.Unlock()
+		var stale []event.Event
+		for _, e := range events.events {
+			if e.Type == event.EventStaleImage {
+				stale = append(stale, e)
+			}
+		}
+		return stale
+	}
+
+	d.StaleImageAge = time.Nanosecond
+	d.checkStaleImages(log.NewNopLogger())
+	stale := staleEvents()
+	if len(stale) != 1 {
+		t.Fatalf("Expected one stale image event but got %#v", stale)
+	}
+	if stale[0].LogLevel != event.LogLevelWarn {
+		t.Errorf("Expected a warning, got log level %q", stale[0].LogLevel)
+	}
+	images := stale[0].Metadata.(*event.StaleImageEventMetadata).Images
+	if len(images) != 1 || images[0].Current != currentHelloImage || images[0].Latest != "2" {
+		t.Errorf("Expected %s to be reported as behind tag %q but got %#v", currentHelloImage, "2", images)
+	}
+
+	d.checkStaleImages(log.NewNopLogger())
+	if len(staleEvents()) != 1 {
+		t.Errorf("Expected the stale image to be reported only once")
+	}
+}
+
 // When I call list services with options, it should list all the requested services
 func TestDaemon_ListServicesWithOptions(t *testing.T) {
 	d, start, clean, _, _, _ := mockDaemon(t)
@@ -490,6 +673,60 @@ func TestDaemon_PolicyUpdate(t *testing.T) {
 	}, "Waiting for new annotation")
 }
 
+func TestDaemon_PolicyUpdateEvent(t *testing.T) {
+	events := &mockEventWriter{}
+	d := &Daemon{
+		Manifests:   &kubernetes.Manifests{},
+		EventWriter: events,
+		Logger:      log.NewNopLogger(),
+	}
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	def := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: helloworld
+  namespace: default
+  annotations:
+    flux.weave.works/tag.greeter: glob:master-*
+`)
+	newDef := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: helloworld
+  namespace: default
+  annotations:
+    flux.weave.works/locked: "true"
+    flux.weave.works/locked_until: abc456
+    flux.weave.works/tag.greeter: glob:master-*
+`)
+	u := policy.Update{Add: policy.Set{policy.Locked: "true", policy.LockedUntil: "abc456"}}
+	change := event.PolicyChange{
+		ID:     id,
+		Before: d.manifestPolicies(def, id),
+		After:  d.manifestPolicies(newDef, id),
+	}
+	if change.Before.Has(policy.Locked) || !change.After.Has(policy.Locked) || !change.After.Has(policy.TagPrefix("greeter")) {
+		t.Fatalf("expected the workload to be locked after and not before, and keep its tag filter, got %+v", change)
+	}
+
+	d.logPolicyEvents(context.Background(), policy.Updates{id: u}, []event.PolicyChange{change}, "abc123", update.Cause{User: "jane", Message: "bad config"}, log.NewNopLogger())
+	// The revision to lock until is part of the lock, rather than a
+	// policy update of its own
+	if len(events.events) != 1 || events.events[0].Type != event.EventLock {
+		t.Fatalf("expected just a lock event, got %+v", events.events)
+	}
+	metadata, ok := events.events[0].Metadata.(*event.LockEventMetadata)
+	if !ok {
+		t.Fatalf("expected lock metadata, got %#v", events.events[0].Metadata)
+	}
+	if metadata.Cause.User != "jane" || metadata.Revision != "abc123" || len(metadata.Changes) != 1 {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if metadata.User != "jane" || metadata.Reason != "bad config" || metadata.UntilRevision != "abc456" {
+		t.Errorf("expected who locked it, why and until when, got %+v", metadata)
+	}
+}
+
 // When I call sync status, it should return a commit showing the sync
 // that is about to take place. Then it should return empty once it is
 // complete
@@ -587,6 +824,39 @@ func TestDaemon_Automated_semver(t *testing.T) {
 	w.ForImageTag(t, d, resid.String(), container, "3")
 }
 
+func TestDaemon_Automated_pinned(t *testing.T) {
+	d, start, clean, k8s, _, _ := mockDaemon(t)
+	start()
+	defer clean()
+	w := newWait(t)
+
+	resid := flux.MustParseResourceID("default:deployment/semver")
+	service := cluster.Controller{
+		ID: resid,
+		Containers: cluster.ContainersOrExcuse{
+			Containers: []resource.Container{
+				{
+					Name:  container,
+					Image: mustParseImageRef(currentHelloImage),
+				},
+			},
+		},
+	}
+	k8s.SomeServicesFunc = func([]flux.ResourceID) ([]cluster.Controller, error) {
+		return []cluster.Controller{service}, nil
+	}
+
+	updateManifest(context.Background(), t, d, update.Spec{
+		Type: update.Policy,
+		Spec: policy.Updates{
+			resid: {Add: policy.Set{}.Set(policy.PinPrefix(container), "2")},
+		},
+	})
+	// helloworld:3 would be chosen by semver, but the container is
+	// pinned to helloworld:2
+	w.ForImageTag(t, d, resid.String(), container, "2")
+}
+
 func makeImageInfo(ref string, t time.Time) image.Info {
 	return image.Info{ID: mustParseImageRef(ref), CreatedAt: t}
 }
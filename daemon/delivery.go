@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+	"github.com/weaveworks/flux/update"
+)
+
+// How long to remember deploys and rollbacks, for reporting on
+const deliveryHistory = 90 * 24 * time.Hour
+
+type deploy struct {
+	at time.Time
+	// From the earliest commit synced to the sync; zero if not
+	// known
+	leadTime time.Duration
+}
+
+// workloadDeliveries records the deploys and rollbacks of a workload.
+type workloadDeliveries struct {
+	deploys   []deploy
+	rollbacks []time.Time
+	// The images released to each container, so that a release back
+	// to one of them can be counted as a rollback
+	images map[string]map[image.Ref]struct{}
+}
+
+// recordDelivery counts syncs that change workloads as deploys of
+// them, and releases back to an image a workload container has run
+// before as rollbacks. It gives the workloads rolled back.
+func (loop *LoopVars) recordDelivery(ev event.Event) []flux.ResourceID {
+	var result update.Result
+	switch metadata := ev.Metadata.(type) {
+	case *event.SyncEventMetadata:
+		if metadata.InitialSync || len(metadata.Commits) == 0 {
+			return nil
+		}
+		var leadTime time.Duration
+		for _, c := range metadata.Commits {
+			if !c.Time.IsZero() && ev.StartedAt.Sub(c.Time) > leadTime {
+				leadTime = ev.StartedAt.Sub(c.Time)
+			}
+		}
+		loop.deliveryMu.Lock()
+		defer loop.deliveryMu.Unlock()
+		for _, id := range ev.ServiceIDs {
+			w := loop.workloadDeliveries(id, ev.StartedAt)
+			w.deploys = append(w.deploys, deploy{at: ev.StartedAt, leadTime: leadTime})
+			workloadDeploys.With(fluxmetrics.LabelWorkload, id.String()).Add(1)
+			if leadTime > 0 {
+				workloadLeadTime.With(fluxmetrics.LabelWorkload, id.String()).Observe(leadTime.Seconds())
+			}
+		}
+		return nil
+	case *event.ReleaseEventMetadata:
+		result = metadata.Result
+	case *event.AutoReleaseEventMetadata:
+		result = metadata.Result
+	case *event.RollbackEventMetadata:
+		result = metadata.Result
+	default:
+		return nil
+	}
+
+	loop.deliveryMu.Lock()
+	defer loop.deliveryMu.Unlock()
+	var rolledBack []flux.ResourceID
+	for id, res := range result {
+		if res.Status != update.ReleaseStatusSuccess {
+			continue
+		}
+		w := loop.workloadDeliveries(id, ev.StartedAt)
+		var rollback bool
+		for _, c := range res.PerContainer {
+			released, ok := w.images[c.Container]
+			if !ok {
+				released = map[image.Ref]struct{}{}
+				w.images[c.Container] = released
+			}
+			if _, ok := released[c.Target]; ok && c.Target != c.Current {
+				rollback = true
+			}
+			released[c.Current] = struct{}{}
+			released[c.Target] = struct{}{}
+		}
+		if rollback {
+			w.rollbacks = append(w.rollbacks, ev.StartedAt)
+			workloadRollbacks.With(fluxmetrics.LabelWorkload, id.String()).Add(1)
+			rolledBack = append(rolledBack, id)
+		}
+	}
+	return rolledBack
+}
+
+// workloadDeliveries gives the record for the workload, forgetting
+// anything older than is kept. It must be called with deliveryMu
+// held.
+func (loop *LoopVars) workloadDeliveries(id flux.ResourceID, now time.Time) *workloadDeliveries {
+	if loop.deliveries == nil {
+		loop.deliveries = map[string]*workloadDeliveries{}
+		loop.deliveriesSince = now
+	}
+	w, ok := loop.deliveries[id.String()]
+	if !ok {
+		w = &workloadDeliveries{images: map[string]map[image.Ref]struct{}{}}
+		loop.deliveries[id.String()] = w
+	}
+	cutoff := now.Add(-deliveryHistory)
+	for len(w.deploys) > 0 && w.deploys[0].at.Before(cutoff) {
+		w.deploys = w.deploys[1:]
+	}
+	for len(w.rollbacks) > 0 && w.rollbacks[0].Before(cutoff) {
+		w.rollbacks = w.rollbacks[1:]
+	}
+	return w
+}
+
+// DeliveryReport gives, for each workload deployed in the period
+// asked for, how often it's been deployed, how long changes took to
+// be deployed, and how often it's been rolled back. Only what's
+// happened since the daemon started is known.
+func (d *Daemon) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	now := time.Now().UTC()
+	period := opts.Period
+	if period <= 0 || period > deliveryHistory {
+		period = deliveryHistory
+	}
+	since := now.Add(-period)
+
+	d.deliveryMu.Lock()
+	defer d.deliveryMu.Unlock()
+	if len(d.deliveries) == 0 {
+		return nil, nil
+	}
+	// Frequencies are over the time there's a record for
+	if d.deliveriesSince.After(since) {
+		period = now.Sub(d.deliveriesSince)
+	}
+	days := period.Hours() / 24
+
+	var report []v16.WorkloadDelivery
+	for idStr, w := range d.deliveries {
+		id, err := flux.ParseResourceID(idStr)
+		if err != nil {
+			continue
+		}
+		if ns, _, _ := id.Components(); opts.Namespace != "" && ns != opts.Namespace {
+			continue
+		}
+
+		delivery := v16.WorkloadDelivery{Workload: id}
+		var leadTimes []time.Duration
+		for _, dep := range w.deploys {
+			if dep.at.Before(since) {
+				continue
+			}
+			delivery.Deploys++
+			if dep.leadTime > 0 {
+				leadTimes = append(leadTimes, dep.leadTime)
+			}
+			at := dep.at
+			delivery.LastDeployed = &at
+		}
+		for _, at := range w.rollbacks {
+			if !at.Before(since) {
+				delivery.Rollbacks++
+			}
+		}
+		if delivery.Deploys == 0 && delivery.Rollbacks == 0 {
+			continue
+		}
+
+		if days > 0 {
+			delivery.DeployFrequency = float64(delivery.Deploys) / days
+		}
+		if len(leadTimes) > 0 {
+			sort.Slice(leadTimes, func(i, j int) bool { return leadTimes[i] < leadTimes[j] })
+			mid := len(leadTimes) / 2
+			median := leadTimes[mid]
+			if len(leadTimes)%2 == 0 {
+				median = (leadTimes[mid-1] + leadTimes[mid]) / 2
+			}
+			delivery.MedianLeadTimeSeconds = median.Seconds()
+		}
+		if delivery.Deploys > 0 {
+			delivery.ChangeFailureRate = float64(delivery.Rollbacks) / float64(delivery.Deploys)
+		}
+		report = append(report, delivery)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Workload.String() < report[j].Workload.String()
+	})
+	return report, nil
+}
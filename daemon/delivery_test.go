@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestDeliveryReport(t *testing.T) {
+	d := &Daemon{LoopVars: &LoopVars{}}
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("other:deployment/world")
+	now := time.Now().UTC()
+
+	sync := func(at time.Time, leadTime time.Duration, ids ...flux.ResourceID) {
+		d.recordDelivery(event.Event{
+			Type:       event.EventSync,
+			ServiceIDs: ids,
+			StartedAt:  at,
+			Metadata: &event.SyncEventMetadata{
+				Commits: []event.Commit{{Revision: "abc123", Time: at.Add(-leadTime)}},
+			},
+		})
+	}
+	release := func(at time.Time, from, to string) {
+		current, _ := image.ParseRef("quay.io/example/hello:" + from)
+		target, _ := image.ParseRef("quay.io/example/hello:" + to)
+		d.recordDelivery(event.Event{
+			Type:      event.EventRelease,
+			StartedAt: at,
+			Metadata: &event.ReleaseEventMetadata{
+				ReleaseEventCommon: event.ReleaseEventCommon{
+					Result: update.Result{
+						hello: update.ControllerResult{
+							Status:       update.ReleaseStatusSuccess,
+							PerContainer: []update.ContainerUpdate{{Container: "hello", Current: current, Target: target}},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	sync(now.Add(-3*time.Hour), 10*time.Minute, hello, other)
+	release(now.Add(-3*time.Hour), "1.0", "1.1")
+	sync(now.Add(-2*time.Hour), 20*time.Minute, hello)
+	release(now.Add(-2*time.Hour), "1.1", "1.2")
+	sync(now.Add(-time.Hour), 30*time.Minute, hello)
+	release(now.Add(-time.Hour), "1.2", "1.1")
+
+	report, err := d.DeliveryReport(context.Background(), v16.DeliveryReportOptions{Namespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 {
+		t.Fatalf("expected one workload in the namespace, got %+v", report)
+	}
+	r := report[0]
+	if r.Workload != hello || r.Deploys != 3 || r.Rollbacks != 1 {
+		t.Errorf("expected three deploys and a rollback of %s, got %+v", hello, r)
+	}
+	if r.MedianLeadTimeSeconds != (20 * time.Minute).Seconds() {
+		t.Errorf("expected median lead time of 20 minutes, got %vs", r.MedianLeadTimeSeconds)
+	}
+	if r.ChangeFailureRate != 1.0/3 {
+		t.Errorf("expected change failure rate of 1/3, got %v", r.ChangeFailureRate)
+	}
+
+	report, err = d.DeliveryReport(context.Background(), v16.DeliveryReportOptions{Period: 90 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 || report[0].Deploys != 1 {
+		t.Errorf("expected only the last deploy of %s in the period, got %+v", hello, report)
+	}
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/resource"
+)
+
+// How many changes of synced revision are remembered
+const maxSyncHistory = 1000
+
+// syncRecord says that a revision was synced, from the time given
+// until the next record.
+type syncRecord struct {
+	Revision string
+	At       time.Time
+}
+
+// appendSyncRecord adds the record to the history, if it's a
+// different revision to that last synced, dropping the oldest records
+// if there's too many.
+func appendSyncRecord(history []syncRecord, rec syncRecord) []syncRecord {
+	if n := len(history); n > 0 && history[n-1].Revision == rec.Revision {
+		return history
+	}
+	history = append(history, rec)
+	if len(history) > maxSyncHistory {
+		history = history[len(history)-maxSyncHistory:]
+	}
+	return history
+}
+
+// syncedAt gives the revision synced at the time given, if there's a
+// record of it.
+func (loop *LoopVars) syncedAt(t time.Time) (syncRecord, bool) {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	for i := len(loop.syncHistory) - 1; i >= 0; i-- {
+		if !loop.syncHistory[i].At.After(t) {
+			return loop.syncHistory[i], true
+		}
+	}
+	return syncRecord{}, false
+}
+
+// DeployedAt gives the revision synced at the time asked about, and
+// the images the workloads were given in that revision. If the
+// daemon has no record of a sync by then, the last commit before then
+// is used instead, and the result marked as estimated.
+func (d *Daemon) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (v19.DeployedState, error) {
+	at := opts.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	res := v19.DeployedState{At: at.UTC()}
+
+	synced, ok := d.syncedAt(at)
+	if !ok {
+		commit, err := d.commitBefore(ctx, at)
+		if err != nil {
+			return res, err
+		}
+		synced = syncRecord{Revision: commit.Revision, At: commit.Time}
+		res.Estimated = true
+	}
+	res.Revision, res.SyncedAt = synced.Revision, synced.At
+
+	resources, err := d.resourcesAt(ctx, synced.Revision)
+	if err != nil {
+		return res, errors.Wrapf(err, "loading resources at %s", synced.Revision)
+	}
+	for _, r := range resources {
+		workload, ok := r.(resource.Workload)
+		if !ok {
+			continue
+		}
+		id := r.ResourceID()
+		if ns, _, _ := id.Components(); opts.Namespace != "" && ns != opts.Namespace {
+			continue
+		}
+		deployed := v19.DeployedWorkload{ID: id}
+		for _, c := range workload.Containers() {
+			deployed.Containers = append(deployed.Containers, v19.DeployedContainer{
+				Name:  c.Name,
+				Image: c.Image.String(),
+			})
+		}
+		res.Workloads = append(res.Workloads, deployed)
+	}
+	sort.Slice(res.Workloads, func(i, j int) bool {
+		return res.Workloads[i].ID.String() < res.Workloads[j].ID.String()
+	})
+	return res, nil
+}
+
+// commitBefore gives the last commit, to the paths synced, made at or
+// before the time given.
+func (d *Daemon) commitBefore(ctx context.Context, t time.Time) (git.Commit, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	commits, err := d.Repo.CommitsBefore(ctx, d.GitConfig.Branch, d.GitConfig.Paths...)
+	if err != nil {
+		return git.Commit{}, err
+	}
+	// Commits are given newest first
+	for _, c := range commits {
+		if !c.Time.After(t) {
+			return c, nil
+		}
+	}
+	return git.Commit{}, nothingDeployedError(t)
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncedAt(t *testing.T) {
+	start := time.Date(2026, 10, 14, 3, 0, 0, 0, time.UTC)
+	loop := &LoopVars{}
+	for i, rev := range []string{"a", "a", "b", "c"} {
+		loop.syncHistory = appendSyncRecord(loop.syncHistory, syncRecord{Revision: rev, At: start.Add(time.Duration(i) * 10 * time.Minute)})
+	}
+	if len(loop.syncHistory) != 3 {
+		t.Fatalf("expected a record for each change of revision, got %v", loop.syncHistory)
+	}
+
+	for _, c := range []struct {
+		at       time.Duration
+		revision string
+		ok       bool
+	}{
+		{-time.Minute, "", false},
+		{0, "a", true},
+		{15 * time.Minute, "a", true},
+		{20 * time.Minute, "b", true},
+		{time.Hour, "c", true},
+	} {
+		rec, ok := loop.syncedAt(start.Add(c.at))
+		if ok != c.ok || rec.Revision != c.revision {
+			t.Errorf("at %s: expected %q (%v), got %q (%v)", c.at, c.revision, c.ok, rec.Revision, ok)
+		}
+	}
+}
+
+func TestSyncHistoryLimit(t *testing.T) {
+	var history []syncRecord
+	for i := 0; i < maxSyncHistory+10; i++ {
+		history = appendSyncRecord(history, syncRecord{Revision: string(rune('a' + i%2)), At: time.Unix(int64(i), 0)})
+	}
+	if len(history) != maxSyncHistory {
+		t.Errorf("expected %d records, got %d", maxSyncHistory, len(history))
+	}
+	if last := history[len(history)-1]; last.At != time.Unix(int64(maxSyncHistory+9), 0) {
+		t.Errorf("expected the latest record to be kept, got %v", last)
+	}
+}
@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/resource"
+)
+
+// DiffRevisions compares the resources defined in the repo at two
+// revisions: which have been added or removed, and which changed,
+// with the images changed for workloads.
+func (d *Daemon) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	var res v21.RevisionDiff
+	if opts.From == "" {
+		return res, errors.New("no revision given to compare from")
+	}
+	to := opts.To
+	if to == "" {
+		to = d.GitConfig.Branch
+	}
+	var err error
+	if res.From, err = d.resolveRevision(ctx, opts.From); err != nil {
+		return res, err
+	}
+	if res.To, err = d.resolveRevision(ctx, to); err != nil {
+		return res, err
+	}
+
+	before, err := d.resourcesAt(ctx, res.From)
+	if err != nil {
+		return res, manifestLoadError(err)
+	}
+	after, err := d.resourcesAt(ctx, res.To)
+	if err != nil {
+		return res, manifestLoadError(err)
+	}
+
+	ids := map[string]struct{}{}
+	for id := range before {
+		ids[id] = struct{}{}
+	}
+	for id := range after {
+		ids[id] = struct{}{}
+	}
+	for id := range ids {
+		prev, next := before[id], after[id]
+		if prev != nil && next != nil && bytes.Equal(prev.Bytes(), next.Bytes()) {
+			continue
+		}
+		change := v21.ResourceChange{Status: v21.ResourceChanged}
+		switch {
+		case prev == nil:
+			change.ID, change.Status = next.ResourceID(), v21.ResourceAdded
+		case next == nil:
+			change.ID, change.Status = prev.ResourceID(), v21.ResourceRemoved
+		default:
+			change.ID = next.ResourceID()
+		}
+		if ns, _, _ := change.ID.Components(); opts.Namespace != "" && ns != opts.Namespace {
+			continue
+		}
+		change.Images = imageChanges(prev, next)
+		if opts.Manifests {
+			if change.Diff, err = manifestDiff(res.From, prev, res.To, next); err != nil {
+				return res, err
+			}
+		}
+		res.Changes = append(res.Changes, change)
+	}
+	sort.Slice(res.Changes, func(i, j int) bool {
+		return res.Changes[i].ID.String() < res.Changes[j].ID.String()
+	})
+	return res, nil
+}
+
+// resolveRevision gives the full revision for the one given, which
+// may be abbreviated, or a branch or tag.
+func (d *Daemon) resolveRevision(ctx context.Context, rev string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	full, err := d.Repo.Revision(ctx, rev)
+	if isUnknownRevision(err) {
+		return "", unknownRevisionError(rev)
+	}
+	return full, err
+}
+
+// imageChanges gives the containers whose images differ between the
+// two versions of a resource, either of which may be missing.
+func imageChanges(prev, next resource.Resource) []v21.ImageChange {
+	prevImages, nextImages := map[string]string{}, map[string]string{}
+	var names []string
+	if w, ok := prev.(resource.Workload); ok {
+		prevImages = containerImages(w.Containers())
+		for _, c := range w.Containers() {
+			names = append(names, c.Name)
+		}
+	}
+	if w, ok := next.(resource.Workload); ok {
+		nextImages = containerImages(w.Containers())
+		for _, c := range w.Containers() {
+			if _, ok := prevImages[c.Name]; !ok {
+				names = append(names, c.Name)
+			}
+		}
+	}
+	var changes []v21.ImageChange
+	for _, name := range names {
+		if prevImages[name] != nextImages[name] {
+			changes = append(changes, v21.ImageChange{
+				Container: name,
+				From:      prevImages[name],
+				To:        nextImages[name],
+			})
+		}
+	}
+	return changes
+}
+
+// manifestDiff gives a unified diff of the two versions of a
+// resource, either of which may be missing.
+func manifestDiff(fromRev string, prev resource.Resource, toRev string, next resource.Resource) (string, error) {
+	diff := difflib.UnifiedDiff{Context: 3}
+	if prev != nil {
+		diff.A = difflib.SplitLines(string(prev.Bytes()))
+		diff.FromFile = shortRevision(fromRev) + ":" + prev.Source()
+	}
+	if next != nil {
+		diff.B = difflib.SplitLines(string(next.Bytes()))
+		diff.ToFile = shortRevision(toRev) + ":" + next.Source()
+	}
+	return difflib.GetUnifiedDiffString(diff)
+}
+
+func shortRevision(rev string) string {
+	if len(rev) <= 7 {
+		return rev
+	}
+	return rev[:7]
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/api/v21"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+)
+
+const diffBefore = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: greeter
+        image: quay.io/example/hello:1
+      - name: sidecar
+        image: quay.io/example/sidecar:1
+`
+
+const diffAfter = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: greeter
+        image: quay.io/example/hello:2
+      - name: logger
+        image: quay.io/example/logger:1
+`
+
+func TestImageChanges(t *testing.T) {
+	before, err := kresource.ParseMultidoc([]byte(diffBefore), "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := kresource.ParseMultidoc([]byte(diffAfter), "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := "default:deployment/hello"
+
+	changes := imageChanges(before[id], after[id])
+	expected := []v21.ImageChange{
+		{Container: "greeter", From: "quay.io/example/hello:1", To: "quay.io/example/hello:2"},
+		{Container: "sidecar", From: "quay.io/example/sidecar:1"},
+		{Container: "logger", To: "quay.io/example/logger:1"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %#v, got %#v", expected, changes)
+	}
+
+	// A workload that's been added has all its images as changes
+	if added := imageChanges(nil, after[id]); len(added) != 2 || added[0].From != "" {
+		t.Errorf("expected images of added workload, got %#v", added)
+	}
+
+	diff, err := manifestDiff("3a5b4c1d9e0f", before[id], "9c0d2e4f6a83", after[id])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"--- 3a5b4c1:before", "+++ 9c0d2e4:after", "-        image: quay.io/example/hello:1", "+        image: quay.io/example/hello:2"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("expected %q in diff:\n%s", line, diff)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package daemon
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/weaveworks/flux/cluster"
 	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/freeze"
 	"github.com/weaveworks/flux/job"
 )
 
@@ -60,3 +65,196 @@ daemon if possible:
 `,
 	}
 }
+
+func releaseFrozenError(w freeze.Window) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("releases are frozen: %s", w),
+		Help: `Releases are frozen
+
+There is a release freeze in effect:
+
+    ` + w.String() + `
+
+While it lasts, automated releases are suspended, and releases made
+with fluxctl must be forced; e.g.,
+
+    fluxctl release --controller=default:deployment/helloworld --update-image=... --force
+
+A dry run is still allowed, if you want to see what would be
+released.
+`,
+	}
+}
+
+func syncNotHeldError(confirm, held string) error {
+	var what string
+	if held == "" {
+		what = "no sync is being held back"
+	} else {
+		what = fmt.Sprintf("the sync being held back is of %s", held)
+	}
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("cannot confirm sync of %s: %s", confirm, what),
+		Help: `Cannot confirm sync
+
+A sync is only held back when it would add, change or remove more
+resources than the daemon's limits allow; confirming it lets that sync
+go ahead. Either there's no sync being held back, or the revision
+given is not the one being held back; if more commits have arrived,
+the sync of the newest is what needs confirming.
+
+The events logged by the daemon (` + "`fluxctl events`" + `) say which revision
+is being held back, and why.
+`,
+	}
+}
+
+func syncHeldError(rev, reason string) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("sync of %s is held back: %s", rev, reason),
+		Help: `Sync held back
+
+The commits to be synced would add, change or remove more resources
+than the daemon allows without confirmation, so nothing has been
+applied. This can happen after a bad merge, so check that the commits
+are what you expect, e.g., with
+
+    git diff <last synced revision> ` + rev + `
+
+If they are, let the sync go ahead with
+
+    fluxctl sync --confirm=` + rev + `
+`,
+	}
+}
+
+func nothingDeployedError(t time.Time) error {
+	return &fluxerr.Error{
+		Type: fluxerr.Missing,
+		Err:  fmt.Errorf("nothing was committed to the git repo by %s", t.UTC().Format(time.RFC3339)),
+		Help: `Nothing deployed at that time
+
+There's no record of a sync at the time given, and there are no
+commits from before then, so there's nothing to say was deployed.
+Check that the time is right, and that you have given a time zone if
+it's not in UTC.
+`,
+	}
+}
+
+func unknownEventError(id event.EventID) error {
+	return &fluxerr.Error{
+		Type: fluxerr.Missing,
+		Err:  fmt.Errorf("unknown event %d", id),
+		Help: `Event not found
+
+Unless it's given an event store, the daemon only keeps its most
+recent events, and forgets them all when it restarts, so the event
+may no longer be there.
+Check the event ID against those listed by
+
+    fluxctl events
+`,
+	}
+}
+
+func notAReleaseError(ev event.Event) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("event %d is not a release that changed images", ev.ID),
+		Help: `Not a release
+
+Only releases that changed images (including automated releases, and
+rollbacks themselves) can be rolled back. Event ` + fmt.Sprint(ev.ID) + ` is a ` + ev.Type + ` event,
+or a release that didn't change any images. Check the event ID against
+those listed by
+
+    fluxctl events
+`,
+	}
+}
+
+func unknownRevisionError(rev string) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("unknown revision %q", rev),
+		Help: `Revision not found
+
+The revision isn't in the git repo as flux last fetched it. Check
+that it's a commit, branch or tag that has been pushed to the repo
+flux uses; if it was pushed only recently, it may help to run
+
+    fluxctl sync
+
+so that flux fetches it.
+`,
+	}
+}
+
+func insufficientCapacityError(report cluster.CapacityReport) error {
+	var workloads []string
+	for _, w := range report.Workloads {
+		if w.Unschedulable > 0 {
+			workloads = append(workloads, fmt.Sprintf("    %s: %d of %d extra pods\n", w.ID, w.Unschedulable, w.ExtraPods))
+		}
+	}
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("not enough room in the cluster for %d pods needed by the release", report.Unschedulable()),
+		Help: `Not enough capacity in the cluster
+
+The release would start pods, while rolling out, that would not fit
+on any node, given the CPU and memory they request and what's already
+requested by the pods running on each node:
+
+` + strings.Join(workloads, "") + `
+Add nodes or free up some room and try again, or force the release
+(e.g., with fluxctl release --force) to go ahead anyway.
+`,
+	}
+}
+
+func noRetentionPolicyError() error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("no retention policy to prune events by"),
+		Help: `No retention policy
+
+The daemon wasn't started with a retention policy for events, and none
+was given with the request to prune them, so there's nothing to say
+which events to remove. Give a maximum age or a maximum number of
+events per workload, or start the daemon with
+--event-retention-max-age and/or --event-retention-max-per-service.
+`,
+	}
+}
+
+func eventStoreNotPrunableError() error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("the event store does not support pruning"),
+		Help: `Events can't be pruned
+
+The store in which the daemon keeps events (given with --event-store)
+doesn't support removing events. Events kept in memory are pruned;
+other stores may need to be pruned by their own means.
+`,
+	}
+}
+
+func unknownSubscriberError(name string) error {
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  fmt.Errorf("unknown webhook subscriber %q", name),
+		Help: `Unknown webhook subscriber
+
+The cursor given is for a webhook subscriber the daemon doesn't know
+about. Check that the cursor is one the subscriber was sent (in the
+X-Flux-Cursor header), and that the subscriber is still listed in the
+file given to the daemon with --event-webhook-config.
+`,
+	}
+}
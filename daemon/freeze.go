@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/freeze"
+	"github.com/weaveworks/flux/update"
+)
+
+// How often to check whether a release freeze has started or ended,
+// so it can be recorded as an event.
+const freezeCheckInterval = time.Minute
+
+func (d *Daemon) activeFreeze(now time.Time) (freeze.Window, bool) {
+	if d.Freeze == nil {
+		return freeze.Window{}, false
+	}
+	return d.Freeze.Active(now)
+}
+
+// checkFreeze returns an error if the update given isn't allowed
+// because of a release freeze. During a freeze, automated releases
+// (of images or charts) are refused, and other releases must be
+// forced, unless they are only planned.
+func (d *Daemon) checkFreeze(spec update.Spec) error {
+	w, frozen := d.activeFreeze(time.Now())
+	if !frozen {
+		return nil
+	}
+	switch s := spec.Spec.(type) {
+	case update.ReleaseSpec:
+		if s.Kind == update.ReleaseKindPlan || s.Force {
+			return nil
+		}
+	case update.ContainerSpecs:
+		if s.Kind == update.ReleaseKindPlan || s.Force {
+			return nil
+		}
+	case update.RollbackSpec:
+		if s.Kind == update.ReleaseKindPlan || s.Force {
+			return nil
+		}
+	case update.RestartSpec:
+		if s.Kind == update.ReleaseKindPlan || s.Force {
+			return nil
+		}
+	case *update.Automated, update.ChartUpdates:
+	default:
+		return nil
+	}
+	return releaseFrozenError(w)
+}
+
+// recordFreezeChanges logs an event when a release freeze starts,
+// and when it's over.
+func (d *Daemon) recordFreezeChanges(logger log.Logger) {
+	now := time.Now().UTC()
+	w, frozen := d.activeFreeze(now)
+
+	d.freezeMu.Lock()
+	last, wasFrozen := d.lastFreeze, d.frozen
+	d.lastFreeze, d.frozen = w, frozen
+	d.freezeMu.Unlock()
+
+	changed := !sameWindow(w, last)
+	if wasFrozen && (!frozen || changed) {
+		d.logFreezeEvent(logger, now, last, true)
+	}
+	if frozen && (!wasFrozen || changed) {
+		d.logFreezeEvent(logger, now, w, false)
+	}
+}
+
+// sameWindow compares freezes by value, since they may have been
+// read afresh from the calendar.
+func sameWindow(a, b freeze.Window) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Reason == b.Reason
+}
+
+func (d *Daemon) logFreezeEvent(logger log.Logger, now time.Time, w freeze.Window, over bool) {
+	if err := d.LogEvent(event.Event{
+		Type:      event.EventFreeze,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelInfo,
+		Metadata: &event.FreezeEventMetadata{
+			Reason: w.Reason,
+			Start:  w.Start,
+			End:    w.End,
+			Over:   over,
+		},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging release freeze event"))
+	}
+}
@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/freeze"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestCheckFreeze(t *testing.T) {
+	now := time.Now()
+	d := &Daemon{
+		Freeze: freeze.Windows{
+			{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Reason: "testing"},
+		},
+	}
+
+	for name, c := range map[string]struct {
+		spec    interface{}
+		allowed bool
+	}{
+		"release":        {update.ReleaseSpec{Kind: update.ReleaseKindExecute}, false},
+		"forced release": {update.ReleaseSpec{Kind: update.ReleaseKindExecute, Force: true}, true},
+		"dry run":        {update.ReleaseSpec{Kind: update.ReleaseKindPlan}, true},
+		"containers":     {update.ContainerSpecs{Kind: update.ReleaseKindExecute}, false},
+		"forced":         {update.ContainerSpecs{Kind: update.ReleaseKindExecute, Force: true}, true},
+		"automated":      {&update.Automated{}, false},
+		"charts":         {update.ChartUpdates{}, false},
+		"policy":         {policy.Updates{}, true},
+		"sync":           {update.ManualSync{}, true},
+	} {
+		err := d.checkFreeze(update.Spec{Spec: c.spec})
+		if c.allowed && err != nil {
+			t.Errorf("%s: expected to be allowed, got %v", name, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s: expected to be refused during freeze", name)
+		}
+	}
+
+	d.Freeze = freeze.Windows{
+		{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+	}
+	if err := d.checkFreeze(update.Spec{Spec: &update.Automated{}}); err != nil {
+		t.Errorf("expected automated release to be allowed outside freeze, got %v", err)
+	}
+}
+
+func TestRecordFreezeChanges(t *testing.T) {
+	now := time.Now()
+	events := &mockEventWriter{}
+	d := &Daemon{
+		Logger:      log.NewNopLogger(),
+		EventWriter: events,
+		LoopVars:    &LoopVars{},
+		Freeze:      freeze.Windows{},
+	}
+	logger := log.NewNopLogger()
+
+	d.recordFreezeChanges(logger)
+	if len(events.events) != 0 {
+		t.Fatalf("expected no events without a freeze, got %v", events.events)
+	}
+
+	holiday := freeze.Window{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Reason: "holiday"}
+	d.Freeze = freeze.Windows{holiday}
+	d.recordFreezeChanges(logger)
+	d.recordFreezeChanges(logger)
+	if len(events.events) != 1 {
+		t.Fatalf("expected one event for the start of the freeze, got %v", events.events)
+	}
+	started := events.events[0].Metadata.(*event.FreezeEventMetadata)
+	if started.Over || started.Reason != "holiday" {
+		t.Errorf("expected freeze started event, got %+v", started)
+	}
+
+	d.Freeze = freeze.Windows{}
+	d.recordFreezeChanges(logger)
+	if len(events.events) != 2 {
+		t.Fatalf("expected an event for the end of the freeze, got %v", events.events)
+	}
+	over := events.events[1].Metadata.(*event.FreezeEventMetadata)
+	if !over.Over || over.Reason != "holiday" {
+		t.Errorf("expected freeze over event, got %+v", over)
+	}
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/resource"
+	fluxsync "github.com/weaveworks/flux/sync"
+)
+
+// guardSync checks the resources to be synced at newRev against those
+// synced at oldRev, and says whether the sync may go ahead. If it
+// would change more than the sync guard allows, the sync is held back
+// (and an event logged, the first time) until it's confirmed.
+func (d *Daemon) guardSync(ctx context.Context, oldRev, newRev string, resources map[string]resource.Resource, logger log.Logger) (bool, error) {
+	if !d.SyncGuard.Enabled() || oldRev == "" || oldRev == newRev {
+		return true, nil
+	}
+
+	d.syncHoldMu.Lock()
+	held, confirmed := d.heldRevision, d.confirmedRevision
+	d.syncHoldMu.Unlock()
+	switch newRev {
+	case confirmed:
+		logger.Log("sync", "confirmed", "revision", newRev)
+		d.releaseSyncHold()
+		return true, nil
+	case held:
+		// Already checked, and nothing has changed since
+		return false, nil
+	}
+
+	before, err := d.resourcesAt(ctx, oldRev)
+	if err != nil {
+		return false, errors.Wrap(err, "loading resources last synced")
+	}
+	diff := fluxsync.DiffResources(before, resources)
+	reason := d.SyncGuard.Check(diff)
+	if reason == nil {
+		d.releaseSyncHold()
+		return true, nil
+	}
+
+	logger.Log("sync", "held", "revision", newRev, "reason", reason)
+	d.syncHoldMu.Lock()
+	d.heldRevision, d.heldReason, d.confirmedRevision = newRev, reason.Error(), ""
+	d.syncHoldMu.Unlock()
+	now := time.Now().UTC()
+	if err := d.LogEvent(event.Event{
+		Type:      event.EventSyncHeld,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelWarn,
+		Metadata: &event.SyncHeldEventMetadata{
+			Revision: newRev,
+			Reason:   reason.Error(),
+			Changed:  len(diff.Changed),
+			Deleted:  len(diff.Deleted),
+		},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging sync held event"))
+	}
+	return false, nil
+}
+
+func (d *Daemon) releaseSyncHold() {
+	d.syncHoldMu.Lock()
+	d.heldRevision, d.heldReason, d.confirmedRevision = "", "", ""
+	d.syncHoldMu.Unlock()
+}
+
+// resourcesAt loads the resources defined in the repo at the revision
+// given.
+func (d *Daemon) resourcesAt(ctx context.Context, rev string) (map[string]resource.Resource, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	export, err := d.Repo.Export(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	defer export.Clean()
+	dirs := []string{export.Dir()}
+	if len(d.GitConfig.Paths) > 0 {
+		dirs = nil
+		for _, p := range d.GitConfig.Paths {
+			dirs = append(dirs, filepath.Join(export.Dir(), p))
+		}
+	}
+	return d.ManifestCache.Load(d.Manifests, rev, export.Dir(), dirs)
+}
+
+// heldSync gives the revision whose sync is being held back, and
+// why, if there is one and it's not been confirmed.
+func (d *Daemon) heldSync() (string, string) {
+	d.syncHoldMu.Lock()
+	defer d.syncHoldMu.Unlock()
+	if d.confirmedRevision == d.heldRevision {
+		return "", ""
+	}
+	return d.heldRevision, d.heldReason
+}
+
+// confirmSync lets the sync being held back go ahead. The revision
+// may be abbreviated, as long as it's that of the sync held back.
+func (d *Daemon) confirmSync(rev string) error {
+	d.syncHoldMu.Lock()
+	held := d.heldRevision
+	ok := held != "" && strings.HasPrefix(held, rev)
+	if ok {
+		d.confirmedRevision = held
+	}
+	d.syncHoldMu.Unlock()
+	if !ok {
+		return syncNotHeldError(rev, held)
+	}
+	d.AskForSync()
+	return nil
+}
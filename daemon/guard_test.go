@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"testing"
+)
+
+func TestConfirmSync(t *testing.T) {
+	d := &Daemon{LoopVars: &LoopVars{}}
+
+	if err := d.confirmSync("abc123"); err == nil {
+		t.Error("expected error confirming when no sync is held back")
+	}
+
+	d.heldRevision, d.heldReason = "abc1234567", "too many changes"
+	if err := d.confirmSync("def"); err == nil {
+		t.Error("expected error confirming a revision other than that held back")
+	}
+	if rev, _ := d.heldSync(); rev != "abc1234567" {
+		t.Errorf("expected sync of abc1234567 to be held, got %q", rev)
+	}
+
+	if err := d.confirmSync("abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if rev, _ := d.heldSync(); rev != "" {
+		t.Errorf("expected no sync to be held after confirming, got %q", rev)
+	}
+}
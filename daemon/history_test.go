@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestEventHistory(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("default:deployment/other")
+	store := &event.Buffer{}
+	d := &Daemon{EventStore: store}
+	// Events 1-5 are for hello, 6-10 alternate between the two
+	for i := 1; i <= 10; i++ {
+		id := hello
+		if i > 5 && i%2 == 0 {
+			id = other
+		}
+		if err := store.LogEvent(event.Event{Type: event.EventLock, ServiceIDs: []flux.ResourceID{id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	ids := func(events []event.Event) []event.EventID {
+		var res []event.EventID
+		for _, e := range events {
+			res = append(res, e.ID)
+		}
+		return res
+	}
+
+	// Going back through the history of hello, three at a time
+	var pages [][]event.EventID
+	opts := v22.EventHistoryOptions{Workload: &hello, Limit: 3}
+	for {
+		history, err := d.EventHistory(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, ids(history.Events))
+		if history.Older == 0 {
+			break
+		}
+		opts.Before = history.Older
+	}
+	expected := [][]event.EventID{{5, 7, 9}, {2, 3, 4}, {1}}
+	if len(pages) != len(expected) {
+		t.Fatalf("expected pages %v, got %v", expected, pages)
+	}
+	for i := range expected {
+		if len(pages[i]) != len(expected[i]) {
+			t.Fatalf("expected pages %v, got %v", expected, pages)
+		}
+		for j := range expected[i] {
+			if pages[i][j] != expected[i][j] {
+				t.Fatalf("expected pages %v, got %v", expected, pages)
+			}
+		}
+	}
+
+	// After is a lower bound; events between it and those returned
+	// are still there for the asking
+	history, err := d.EventHistory(ctx, v22.EventHistoryOptions{After: 6, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(history.Events); len(got) != 2 || got[0] != 9 || history.Older != 9 {
+		t.Errorf("expected events 9 and 10, with older events left, got %v (older %d)", got, history.Older)
+	}
+	history, err = d.EventHistory(ctx, v22.EventHistoryOptions{After: 6, Before: history.Older, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(history.Events); len(got) != 2 || got[0] != 7 || history.Older != 0 {
+		t.Errorf("expected events 7 and 8, and nothing older, got %v (older %d)", got, history.Older)
+	}
+}
+
+func TestLogEvents(t *testing.T) {
+	store := &event.Buffer{}
+	upstream := &event.Buffer{}
+	d := &Daemon{EventStore: store, EventWriter: upstream}
+	ctx := context.Background()
+
+	// IDs given with the events are ignored, since the store gives them
+	batch := v27.LogEventsOptions{Events: []event.Event{
+		{ID: 41, Type: event.EventSync},
+		{ID: 42, Type: event.EventRelease},
+	}}
+	if err := d.LogEvents(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	kept := store.Since(0, 0)
+	if len(kept) != 2 || kept[0].ID != 1 || kept[1].Type != event.EventRelease {
+		t.Errorf("expected the batch to be kept in order with new IDs, got %+v", kept)
+	}
+	if sent := upstream.Since(0, 0); len(sent) != 2 {
+		t.Errorf("expected the batch to be sent upstream, got %+v", sent)
+	}
+
+	bad := v27.LogEventsOptions{Events: []event.Event{{Type: event.EventSync}, {}}}
+	if err := d.LogEvents(ctx, bad); err == nil {
+		t.Error("expected an error for an event with no type")
+	}
+	if kept := store.Since(0, 0); len(kept) != 2 {
+		t.Errorf("expected none of a bad batch to be kept, got %+v", kept)
+	}
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+// recordImagePoll logs an event saying which image repositories were
+// polled for the workloads given, starting at the time given, and
+// which tags are new since each was last polled. The event concerns
+// the workloads using repositories with new tags; if there are none,
+// it's logged at debug level, since it's made every poll.
+func (d *Daemon) recordImagePoll(services []cluster.Controller, imageRepos update.ImageRepos, started time.Time, logger log.Logger) {
+	duration := time.Since(started)
+
+	// The workloads using each repository, and how to look it up
+	users := map[string]flux.ResourceIDSet{}
+	repoNames := map[string]image.Name{}
+	var names []string
+	for _, service := range services {
+		for _, c := range service.ContainersOrNil() {
+			name := c.Image.Name.CanonicalName().String()
+			if _, ok := users[name]; !ok {
+				users[name] = flux.ResourceIDSet{}
+				repoNames[name] = c.Image.Name
+				names = append(names, name)
+			}
+			users[name].Add([]flux.ResourceID{service.ID})
+		}
+	}
+	sort.Strings(names)
+
+	var repos []event.PolledRepository
+	serviceIDs := flux.ResourceIDSet{}
+	d.polledMu.Lock()
+	if d.polledTags == nil {
+		d.polledTags = map[string]map[string]bool{}
+	}
+	for _, name := range names {
+		images := imageRepos.GetRepoImages(repoNames[name])
+		repo := event.PolledRepository{Name: name, Tags: len(images)}
+		previous, seen := d.polledTags[name]
+		tags := map[string]bool{}
+		for _, info := range images {
+			tags[info.ID.Tag] = true
+			if seen && !previous[info.ID.Tag] {
+				repo.NewTags = append(repo.NewTags, info.ID.Tag)
+			}
+		}
+		d.polledTags[name] = tags
+		if len(repo.NewTags) > 0 {
+			sort.Strings(repo.NewTags)
+			serviceIDs.Add(users[name].ToSlice())
+		}
+		repos = append(repos, repo)
+	}
+	d.polledMu.Unlock()
+
+	level := event.LogLevelDebug
+	if len(serviceIDs) > 0 {
+		level = event.LogLevelInfo
+	}
+	now := time.Now().UTC()
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: serviceIDs.ToSlice(),
+		Type:       event.EventImagePolled,
+		StartedAt:  started.UTC(),
+		EndedAt:    now,
+		LogLevel:   level,
+		Metadata: &event.ImagePolledEventMetadata{
+			Repositories: repos,
+			Duration:     duration,
+		},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging image poll event"))
+	}
+}
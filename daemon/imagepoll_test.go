@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry/mock"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestRecordImagePoll(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("default:deployment/other")
+	current, _ := image.ParseRef("quay.io/weaveworks/helloworld:v1")
+	sidecar, _ := image.ParseRef("quay.io/weaveworks/sidecar:v1")
+	services := []cluster.Controller{
+		{ID: hello, Containers: cluster.ContainersOrExcuse{Containers: []resource.Container{{Name: "greeter", Image: current}, {Name: "sidecar", Image: sidecar}}}},
+		{ID: other, Containers: cluster.ContainersOrExcuse{Containers: []resource.Container{{Name: "sidecar", Image: sidecar}}}},
+	}
+	reg := &mock.Registry{Images: []image.Info{{ID: current}, {ID: sidecar}}}
+
+	store := &event.Buffer{}
+	d := &Daemon{EventStore: store, Logger: log.NewNopLogger(), LoopVars: &LoopVars{}}
+	poll := func() event.Event {
+		imageRepos, err := update.FetchImageRepos(reg, clusterContainers(services), log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.recordImagePoll(services, imageRepos, time.Now(), log.NewNopLogger())
+		events := store.Since(0, 1)
+		if len(events) != 1 || events[0].Type != event.EventImagePolled {
+			t.Fatalf("expected an image polled event, got %+v", events)
+		}
+		return events[0]
+	}
+
+	// The first time, there's nothing to compare with
+	first := poll()
+	metadata := first.Metadata.(*event.ImagePolledEventMetadata)
+	if len(metadata.Repositories) != 2 || metadata.Repositories[0].Tags != 1 || len(metadata.Repositories[0].NewTags) != 0 {
+		t.Errorf("expected two repositories with no new tags, got %+v", metadata.Repositories)
+	}
+	if first.LogLevel != event.LogLevelDebug || len(first.ServiceIDs) != 0 {
+		t.Errorf("expected a debug event concerning no workloads, got %+v", first)
+	}
+
+	newer, _ := image.ParseRef("quay.io/weaveworks/sidecar:v2")
+	reg.Images = append(reg.Images, image.Info{ID: newer})
+	second := poll()
+	metadata = second.Metadata.(*event.ImagePolledEventMetadata)
+	if repo := metadata.Repositories[1]; repo.Tags != 2 || len(repo.NewTags) != 1 || repo.NewTags[0] != "v2" {
+		t.Errorf("expected the new sidecar tag, got %+v", repo)
+	}
+	if len(metadata.Repositories[0].NewTags) != 0 {
+		t.Errorf("expected no new helloworld tags, got %+v", metadata.Repositories[0])
+	}
+	if second.LogLevel != event.LogLevelInfo || len(second.ServiceIDs) != 2 {
+		t.Errorf("expected an info event concerning both workloads using the sidecar, got %+v", second)
+	}
+}
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// ImageReport lists every image running in the cluster (or the
+// namespace given), the workloads that use it, and how far behind
+// the newest image matching their tag filter it is.
+func (d *Daemon) ImageReport(ctx context.Context, opts v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	services, err := d.Cluster.AllControllers(opts.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting workloads from cluster")
+	}
+
+	resources, _, err := d.getResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRepos, err := update.FetchImageRepos(d.Registry, clusterContainers(services), d.Logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting images for workloads")
+	}
+
+	// Workloads using the same image may have different tag
+	// filters, so there's an entry for each image and filter
+	type usageKey struct{ image, pattern string }
+	usages := map[usageKey]*v15.ImageUsage{}
+	for _, service := range services {
+		if service.IsSystem {
+			continue
+		}
+		var policies policy.Set
+		if res, ok := resources[service.ID.String()]; ok {
+			policies = res.Policy()
+		}
+		for _, c := range service.ContainersOrNil() {
+			pattern := policy.GetTagPattern(policies, c.Name)
+			key := usageKey{c.Image.String(), pattern.String()}
+			usage, ok := usages[key]
+			if !ok {
+				usage = &v15.ImageUsage{
+					Image:      c.Image.String(),
+					Repository: c.Image.Name.String(),
+					Tag:        c.Image.Tag,
+					Pattern:    pattern.String(),
+					Behind:     -1,
+				}
+				images := imageRepos.GetRepoImages(c.Image.Name)
+				sorted := images.FilterAndSort(pattern)
+				if latest, ok := sorted.Latest(); ok {
+					usage.Latest = latest.ID.Tag
+					if !latest.CreatedAt.IsZero() {
+						usage.LatestAt = &latest.CreatedAt
+					}
+				}
+				for i, info := range sorted {
+					if info.ID.Tag == c.Image.Tag {
+						usage.Behind = i
+						break
+					}
+				}
+				if current := images.FindWithRef(c.Image); !current.CreatedAt.IsZero() {
+					usage.CreatedAt = &current.CreatedAt
+				}
+				usages[key] = usage
+			}
+			usage.UsedBy = append(usage.UsedBy, v15.ImageUser{
+				Workload:  service.ID,
+				Container: c.Name,
+				Owner:     d.ownerOf(service),
+			})
+		}
+	}
+
+	res := []v15.ImageUsage{}
+	for _, usage := range usages {
+		sort.Slice(usage.UsedBy, func(i, j int) bool {
+			a, b := usage.UsedBy[i], usage.UsedBy[j]
+			if a.Workload.String() != b.Workload.String() {
+				return a.Workload.String() < b.Workload.String()
+			}
+			return a.Container < b.Container
+		})
+		res = append(res, *usage)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Image != res[j].Image {
+			return res[i].Image < res[j].Image
+		}
+		return res[i].Pattern < res[j].Pattern
+	})
+	return res, nil
+}
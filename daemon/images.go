@@ -3,22 +3,31 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
 
 	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/resource"
 	"github.com/weaveworks/flux/update"
 )
 
+// How long to wait for an image's signatures to be verified
+const verifyTimeout = 30 * time.Second
+
 func (d *Daemon) pollForNewImages(logger log.Logger) {
 	logger.Log("msg", "polling images")
+	if w, frozen := d.activeFreeze(time.Now()); frozen {
+		logger.Log("msg", "automated releases suspended", "freeze", w)
+		return
+	}
 
 	ctx := context.Background()
 
-	candidateServices, err := d.getUnlockedAutomatedResources(ctx)
+	candidateServices, err := d.getAutomationCandidates(ctx)
 	if err != nil {
 		logger.Log("error", errors.Wrap(err, "getting unlocked automated resources"))
 		return
@@ -28,20 +37,32 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 		return
 	}
 	// Find images to check
+	asked := time.Now()
 	services, err := d.Cluster.SomeControllers(candidateServices.IDs())
 	if err != nil {
 		logger.Log("error", errors.Wrap(err, "checking services for new images"))
 		return
 	}
+	if d.checkClusterLatency(time.Now(), time.Since(asked), logger) {
+		return
+	}
+	d.resumeAutomation(logger)
 	// Check the latest available image(s) for each service
+	polled := time.Now()
 	imageRepos, err := update.FetchImageRepos(d.Registry, clusterContainers(services), logger)
 	if err != nil {
 		logger.Log("error", errors.Wrap(err, "fetching image updates"))
 		return
 	}
+	d.recordImagePoll(services, imageRepos, polled, logger)
 
 	changes := &update.Automated{}
+	// Changes to workloads whose automation is only being observed
+	observed := &update.Automated{}
 	for _, service := range services {
+		if service.Immutable {
+			continue
+		}
 		var p policy.Set
 		if resource, ok := candidateServices[service.ID]; ok {
 			p = resource.Policy()
@@ -53,9 +74,23 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 			repo := currentImageID.Name
 			logger := log.With(logger, "service", service.ID, "container", container.Name, "repo", repo, "pattern", pattern, "current", currentImageID)
 
-			filteredImages := imageRepos.GetRepoImages(repo).FilterAndSort(pattern)
-
-			if latest, ok := filteredImages.Latest(); ok && latest.ID != currentImageID {
+			var newImage image.Ref
+			var digest, reason string
+			if pin, ok := policy.GetPin(p, container.Name); ok {
+				// A pinned container is kept at the tag it's pinned to,
+				// whatever else is available
+				if currentImageID.Tag == pin {
+					continue containers
+				}
+				newImage = currentImageID.WithNewTag(pin)
+				digest = imageRepos.GetRepoImages(repo).FindWithRef(newImage).Digest
+				reason = fmt.Sprintf("pinned to %s, current %s", pin, currentImageID.Tag)
+			} else {
+				filteredImages := imageRepos.GetRepoImages(repo).FilterAndSort(pattern)
+				latest, ok := filteredImages.Latest()
+				if !ok || latest.ID == currentImageID {
+					continue containers
+				}
 				if latest.ID.Tag == "" {
 					logger.Log("warning", "untagged image in available images", "action", "skip container")
 					continue containers
@@ -74,15 +109,37 @@ func (d *Daemon) pollForNewImages(logger log.Logger) {
 					currentCreatedAt = "filtered out or missing"
 					logger.Log("warning", "current image not in filtered images", "action", "proceed anyway")
 				}
-				newImage := currentImageID.WithNewTag(latest.ID.Tag)
+				newImage = currentImageID.WithNewTag(latest.ID.Tag)
+				digest = latest.Digest
+				reason = fmt.Sprintf("latest %s (%s) > current %s (%s)", latest.ID.Tag, latest.CreatedAt, currentImageID.Tag, currentCreatedAt)
+			}
+			if d.ImageVerifier != nil {
+				vctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+				// If the image is to be promoted, it's the image
+				// promoted from that exists to be verified
+				_, err := d.ImageVerifier.Verify(vctx, d.Promotion.Source(newImage), digest)
+				cancel()
+				if err != nil {
+					logger.Log("warning", "image failed verification", "image", newImage, "action", "skip container", "err", err)
+					continue containers
+				}
+			}
+			if d.observing(p) {
+				observed.Add(service.ID, container, newImage)
+			} else {
 				changes.Add(service.ID, container, newImage)
-				logger.Log("info", "added update to automation run", "new", newImage, "reason", fmt.Sprintf("latest %s (%s) > current %s (%s)", latest.ID.Tag, latest.CreatedAt, currentImageID.Tag, currentCreatedAt))
 			}
+			logger.Log("info", "added update to automation run", "observe-only", d.observing(p), "new", newImage, "reason", reason)
 		}
 	}
 
 	if len(changes.Changes) > 0 {
-		d.UpdateManifests(ctx, update.Spec{Type: update.Auto, Spec: changes})
+		for _, group := range d.groupAutomated(changes, time.Now(), logger) {
+			d.UpdateManifests(ctx, update.Spec{Type: update.Auto, Spec: group})
+		}
+	}
+	if len(observed.Changes) > 0 {
+		d.observeAutomation(observed)
 	}
 }
 
@@ -95,6 +152,24 @@ func (r resources) IDs() (ids []flux.ResourceID) {
 	return ids
 }
 
+// getAutomationCandidates returns all the resources that are not
+// locked, and either automated or having their automation observed.
+func (d *Daemon) getAutomationCandidates(ctx context.Context) (resources, error) {
+	resources, _, err := d.getResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[flux.ResourceID]resource.Resource{}
+	for _, resource := range resources {
+		policies := resource.Policy()
+		if (policies.Has(policy.Automated) || policies.Has(policy.Observe)) && !policies.Has(policy.Locked) {
+			result[resource.ResourceID()] = resource
+		}
+	}
+	return result, nil
+}
+
 // getUnlockedAutomatedServices returns all the resources that are
 // both automated, and not locked.
 func (d *Daemon) getUnlockedAutomatedResources(ctx context.Context) (resources, error) {
@@ -0,0 +1,24 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v29"
+)
+
+// JobQueue gives the jobs running and waiting to run, in the order
+// they'll run, and how the queue orders them.
+func (d *Daemon) JobQueue(ctx context.Context) (v29.JobQueue, error) {
+	var queue v29.JobQueue
+	if d.Jobs == nil {
+		return queue, nil
+	}
+	// Unless the queue allows more, the daemon runs one job at a time
+	queue.MaxRunning = d.Jobs.MaxRunning()
+	if queue.MaxRunning < 1 {
+		queue.MaxRunning = 1
+	}
+	queue.Priorities = d.Jobs.Priorities()
+	queue.Jobs = d.Jobs.Jobs()
+	return queue, nil
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestJobWorkloads(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	for _, c := range []struct {
+		name     string
+		changes  interface{}
+		expected []flux.ResourceID
+	}{
+		{"release", update.ReleaseSpec{ServiceSpecs: []update.ResourceSpec{update.MakeResourceSpec(hello)}}, []flux.ResourceID{hello}},
+		{"release to all", update.ReleaseSpec{ServiceSpecs: []update.ResourceSpec{update.ResourceSpecAll}}, nil},
+		{"automated", &update.Automated{Changes: []update.Change{{ServiceID: hello}}}, []flux.ResourceID{hello}},
+		{"policy", policy.Updates{hello: policy.Update{}}, []flux.ResourceID{hello}},
+		{"restart", update.RestartSpec{Workloads: []flux.ResourceID{hello}}, []flux.ResourceID{hello}},
+		{"rollback", rollbackChanges{ContainerSpecs: update.ContainerSpecs{ContainerSpecs: map[flux.ResourceID][]update.ContainerUpdate{hello: nil}}}, []flux.ResourceID{hello}},
+		{"sync", update.ManualSync{}, nil},
+	} {
+		got := jobWorkloads(c.changes)
+		if len(got) != len(c.expected) || (len(got) > 0 && got[0] != c.expected[0]) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	d := &Daemon{Jobs: job.NewQueueWithOptions(stop, &sync.WaitGroup{}, job.QueueOptions{
+		Priorities: map[string]int{update.Auto: -1},
+	})}
+	d.Jobs.Enqueue(&job.Job{ID: "auto", Kind: update.Auto})
+	d.Jobs.Enqueue(&job.Job{ID: "release", Kind: update.Images})
+	d.Jobs.Sync()
+
+	queue, err := d.JobQueue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue.MaxRunning != 1 {
+		t.Errorf("expected jobs to be run one at a time, got %d", queue.MaxRunning)
+	}
+	if len(queue.Jobs) != 2 || queue.Jobs[0].ID != "release" || queue.Jobs[1].Priority != -1 {
+		t.Errorf("expected the release before the automated release, got %+v", queue.Jobs)
+	}
+}
@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// StateStore keeps what the daemon remembers between runs.
+type StateStore interface {
+	// Load gives the state last saved, or nil if there's none
+	Load() ([]byte, error)
+	Save([]byte) error
+}
+
+// lifecycleState is what's remembered of the last run of the daemon,
+// so that upgrades and configuration changes can be reported.
+type lifecycleState struct {
+	Version string `json:"version"`
+	// A digest of the value of each configuration option, so that
+	// the options changed can be named without keeping their values
+	// (which may be secret)
+	Config map[string]string `json:"config"`
+}
+
+// Lifecycle records the daemon starting and stopping as events,
+// along with any change of version or configuration since its last
+// run.
+type Lifecycle struct {
+	Events  event.EventWriter
+	Version string
+	// The value of each configuration option (e.g., command-line
+	// flag) given to the daemon
+	Config map[string]string
+	// If nil, the last run isn't known, so changes aren't reported
+	Store StateStore
+}
+
+func configDigests(config map[string]string) map[string]string {
+	digests := map[string]string{}
+	for option, value := range config {
+		sum := sha256.Sum256([]byte(value))
+		digests[option] = hex.EncodeToString(sum[:8])
+	}
+	return digests
+}
+
+// configDigest gives a digest of the configuration as a whole, from
+// the digests of each option.
+func configDigest(digests map[string]string) string {
+	var options []string
+	for option := range digests {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	h := sha256.New()
+	for _, option := range options {
+		fmt.Fprintf(h, "%s=%s\n", option, digests[option])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// changedConfig names the options that differ between two sets of
+// config digests.
+func changedConfig(before, after map[string]string) []string {
+	var changed []string
+	for option, digest := range after {
+		if before[option] != digest {
+			changed = append(changed, option)
+		}
+	}
+	for option := range before {
+		if _, ok := after[option]; !ok {
+			changed = append(changed, option)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Started records the daemon starting, and remembers its version and
+// configuration for the next run.
+func (l Lifecycle) Started(logger log.Logger) {
+	digests := configDigests(l.Config)
+	metadata := &event.DaemonStartEventMetadata{
+		Version:      l.Version,
+		ConfigDigest: configDigest(digests),
+	}
+
+	if l.Store != nil {
+		var last lifecycleState
+		bytes, err := l.Store.Load()
+		if err == nil && len(bytes) > 0 {
+			err = json.Unmarshal(bytes, &last)
+		}
+		if err != nil {
+			logger.Log("err", errors.Wrap(err, "loading state of last run"))
+		} else if last.Version != "" {
+			metadata.PreviousVersion = last.Version
+			metadata.PreviousConfigDigest = configDigest(last.Config)
+			if metadata.ConfigChanged() {
+				metadata.ChangedConfig = changedConfig(last.Config, digests)
+			}
+		}
+
+		bytes, err = json.Marshal(lifecycleState{Version: l.Version, Config: digests})
+		if err == nil {
+			err = l.Store.Save(bytes)
+		}
+		if err != nil {
+			logger.Log("err", errors.Wrap(err, "saving state for next run"))
+		}
+	}
+
+	l.logEvent(event.EventDaemonStart, metadata, logger)
+}
+
+// Stopped records the daemon stopping, for the reason given.
+func (l Lifecycle) Stopped(reason error, logger log.Logger) {
+	l.logEvent(event.EventDaemonStop, &event.DaemonStopEventMetadata{
+		Version:      l.Version,
+		ConfigDigest: configDigest(configDigests(l.Config)),
+		Reason:       reason.Error(),
+	}, logger)
+}
+
+func (l Lifecycle) logEvent(eventType string, metadata event.EventMetadata, logger log.Logger) {
+	now := time.Now().UTC()
+	if err := l.Events.LogEvent(event.Event{
+		Type:      eventType,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelInfo,
+		Metadata:  metadata,
+	}); err != nil {
+		logger.Log("err", errors.Wrapf(err, "logging %s event", eventType))
+	}
+}
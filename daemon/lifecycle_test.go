@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+type memStateStore struct {
+	state []byte
+}
+
+func (s *memStateStore) Load() ([]byte, error) {
+	return s.state, nil
+}
+
+func (s *memStateStore) Save(state []byte) error {
+	s.state = state
+	return nil
+}
+
+type eventRecorder []event.Event
+
+func (r *eventRecorder) LogEvent(e event.Event) error {
+	*r = append(*r, e)
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	store := &memStateStore{}
+	events := &eventRecorder{}
+	logger := log.NewNopLogger()
+
+	first := Lifecycle{
+		Events:  events,
+		Version: "1.4.0",
+		Config:  map[string]string{"git-url": "git@example.com:org/repo", "sync-interval": "5m0s"},
+		Store:   store,
+	}
+	first.Started(logger)
+	first.Stopped(errors.New("terminated"), logger)
+
+	second := first
+	second.Version = "1.5.0"
+	second.Config = map[string]string{"git-url": "git@example.com:org/repo", "sync-interval": "1m0s"}
+	second.Started(logger)
+
+	if len(*events) != 3 {
+		t.Fatalf("expected three events, got %d", len(*events))
+	}
+	start := (*events)[0].Metadata.(*event.DaemonStartEventMetadata)
+	if start.PreviousVersion != "" || start.ConfigChanged() {
+		t.Errorf("expected first start to have nothing to compare with, got %+v", start)
+	}
+	stop := (*events)[1].Metadata.(*event.DaemonStopEventMetadata)
+	if stop.Reason != "terminated" || stop.ConfigDigest != start.ConfigDigest {
+		t.Errorf("unexpected stop event %+v", stop)
+	}
+	restart := (*events)[2].Metadata.(*event.DaemonStartEventMetadata)
+	if restart.PreviousVersion != "1.4.0" || restart.PreviousConfigDigest != start.ConfigDigest {
+		t.Errorf("expected restart to refer to the first run, got %+v", restart)
+	}
+	if !reflect.DeepEqual(restart.ChangedConfig, []string{"sync-interval"}) {
+		t.Errorf("expected sync-interval to have changed, got %v", restart.ChangedConfig)
+	}
+	if s := (*events)[2].String(); s != "Daemon started, upgraded from version 1.4.0 to 1.5.0; configuration changed: sync-interval" {
+		t.Errorf("unexpected description %q", s)
+	}
+}
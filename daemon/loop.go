@@ -13,25 +13,109 @@ import (
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/freeze"
 	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/job"
 	fluxmetrics "github.com/weaveworks/flux/metrics"
 	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/source"
+	"github.com/weaveworks/flux/supplychain"
 	fluxsync "github.com/weaveworks/flux/sync"
+	"github.com/weaveworks/flux/tracing"
 	"github.com/weaveworks/flux/update"
 )
 
 const (
 	// Timeout for git operations we're prepared to abandon
 	gitOpTimeout = 15 * time.Second
+	// Timeout for fetching manifests from a store other than git,
+	// which may mean downloading a tarball
+	manifestFetchTimeout = 2 * time.Minute
 )
 
 type LoopVars struct {
 	SyncInterval         time.Duration
 	RegistryPollInterval time.Duration
+	// If not zero, warn about workloads running images older than
+	// this, when there are newer images they could be running
+	StaleImageAge time.Duration
 
 	initOnce       sync.Once
 	syncSoon       chan struct{}
 	pollImagesSoon chan struct{}
+
+	// What's been deployed, for reporting
+	deployedMu     sync.Mutex
+	syncedRevision string
+	syncHistory    []syncRecord
+	lastReleases   map[string]event.Event
+	// Recent events, for following
+	recentEvents event.Buffer
+	// Whether the last sync had errors, so we can say when they're
+	// resolved
+	syncErrored bool
+	// The image last reported as stale, for each workload container
+	staleMu       sync.Mutex
+	staleReported map[string]string
+	// The release freeze in effect when last checked, so we can say
+	// when one starts or is over
+	freezeMu   sync.Mutex
+	lastFreeze freeze.Window
+	frozen     bool
+	// The image last recorded as observed, for each workload
+	// container whose automation is only observed
+	observedMu sync.Mutex
+	observed   map[string]string
+	// The revision whose sync is held back by the sync guard, and
+	// the revision confirmed as OK to sync anyway
+	syncHoldMu        sync.Mutex
+	heldRevision      string
+	heldReason        string
+	confirmedRevision string
+	// Deploys and rollbacks of each workload, for reporting on
+	// delivery
+	deliveryMu      sync.Mutex
+	deliveries      map[string]*workloadDeliveries
+	deliveriesSince time.Time
+	// When automation is backing off, because the job queue or
+	// cluster API is busy
+	backoffMu      sync.Mutex
+	backoff        time.Duration
+	backoffUntil   time.Time
+	deferredSince  time.Time
+	deferralWarned bool
+	// When automated releases of each workload failed, or were
+	// rolled back, within the automation breaker's window
+	breakerMu          sync.Mutex
+	automationFailures map[string][]time.Time
+	// The tags in each image repository when it was last polled, so
+	// new tags can be recorded
+	polledMu   sync.Mutex
+	polledTags map[string]map[string]bool
+	// The sync conflicts found at the last sync, so each is reported
+	// once while it lasts
+	conflictsMu       sync.Mutex
+	reportedConflicts map[string]bool
+	// What was last synced from each extra git repo, by URL
+	extraReposMu   sync.Mutex
+	extraRepoSyncs map[string]extraRepoSync
+	// The commit last reported as not verified, so it's reported
+	// once rather than at every sync
+	unverifiedMu       sync.Mutex
+	unverifiedRevision string
+	// The rewrite last reported, as `<from>..<to>`, so it's reported
+	// once rather than at every sync until one succeeds
+	rewriteMu       sync.Mutex
+	rewriteReported string
+	// The revision last skipped because it was marked to be, so the
+	// commits skipped are each recorded once
+	skippedMu       sync.Mutex
+	skippedRevision string
+	// When automated releases were last proposed in a pull request,
+	// for proposing them once an interval
+	proposedMu   sync.Mutex
+	lastProposed time.Time
 }
 
 func (loop *LoopVars) ensureInit() {
@@ -57,9 +141,23 @@ func (d *Daemon) Loop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger)
 	// every timer tick as well as every mirror refresh.
 	syncHead := ""
 
+	// Sync when any extra git repo has fetched new commits; each
+	// polls at its own interval
+	d.watchExtraRepos(stop)
+
 	// Ask for a sync, and to poll images, straight away
 	d.AskForSync()
 	d.AskForImagePoll()
+
+	// If there's a release freeze calendar, check every so often
+	// whether a freeze has started or ended
+	var freezeCheck <-chan time.Time
+	if d.Freeze != nil {
+		d.recordFreezeChanges(logger)
+		ticker := time.NewTicker(freezeCheckInterval)
+		defer ticker.Stop()
+		freezeCheck = ticker.C
+	}
 	for {
 		select {
 		case <-stop:
@@ -72,10 +170,21 @@ func (d *Daemon) Loop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger)
 				default:
 				}
 			}
-			d.pollForNewImages(logger)
-			imagePollTimer.Reset(d.RegistryPollInterval)
+			pollInterval := d.RegistryPollInterval
+			if wait, deferred := d.deferAutomation(time.Now(), logger); deferred {
+				pollInterval = wait
+			} else {
+				d.pollForNewImages(logger)
+				d.pollForNewCharts(logger)
+			}
+			if d.StaleImageAge > 0 {
+				d.checkStaleImages(logger)
+			}
+			imagePollTimer.Reset(pollInterval)
 		case <-imagePollTimer.C:
 			d.AskForImagePoll()
+		case <-freezeCheck:
+			d.recordFreezeChanges(logger)
 		case <-d.syncSoon:
 			if !syncTimer.Stop() {
 				select {
@@ -91,44 +200,110 @@ func (d *Daemon) Loop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger)
 			d.AskForSync()
 		case <-d.Repo.C:
 			ctx, cancel := context.WithTimeout(context.Background(), gitOpTimeout)
-			newSyncHead, err := d.Repo.Revision(ctx, d.GitConfig.Branch)
+			newSyncHead, ref, err := d.gitSource().Head(ctx)
 			cancel()
 			if err != nil {
 				logger.Log("url", d.Repo.Origin().URL, "err", err)
 				continue
 			}
-			logger.Log("event", "refreshed", "url", d.Repo.Origin().URL, "branch", d.GitConfig.Branch, "HEAD", newSyncHead)
+			refKey := "branch"
+			if d.SyncTags != nil {
+				refKey = "tag"
+			}
+			logger.Log("event", "refreshed", "url", d.Repo.Origin().URL, refKey, ref, "HEAD", newSyncHead)
 			if newSyncHead != syncHead {
 				syncHead = newSyncHead
 				d.AskForSync()
 			}
 		case job := <-d.Jobs.Ready():
 			queueLength.Set(float64(d.Jobs.Len()))
-			jobLogger := log.With(logger, "jobID", job.ID)
-			jobLogger.Log("state", "in-progress")
-			// It's assumed that (successful) jobs will push commits
-			// to the upstream repo, and therefore we probably want to
-			// pull from there and sync the cluster afterwards.
-			start := time.Now()
-			err := job.Do(jobLogger)
-			jobDuration.With(
-				fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
-			).Observe(time.Since(start).Seconds())
-			if err != nil {
-				jobLogger.Log("state", "done", "success", "false", "err", err)
+			// If the queue lets more than one job run at once, run
+			// them alongside the loop; otherwise, one at a time in
+			// the loop, as ever.
+			if d.Jobs.MaxRunning() > 1 {
+				go d.runJob(job, logger)
 			} else {
-				jobLogger.Log("state", "done", "success", "true")
-				ctx, cancel := context.WithTimeout(context.Background(), gitOpTimeout)
-				err := d.Repo.Refresh(ctx)
-				if err != nil {
-					logger.Log("err", err)
-				}
-				cancel()
+				d.runJob(job, logger)
 			}
 		}
 	}
 }
 
+// runJob runs a job handed out by the queue, and tells the queue
+// when it's done.
+func (d *Daemon) runJob(j *job.Job, logger log.Logger) {
+	defer d.Jobs.Done(j.ID)
+	jobLogger := log.With(logger, "jobID", j.ID)
+	jobLogger.Log("state", "in-progress")
+	// It's assumed that (successful) jobs will push commits
+	// to the upstream repo, and therefore we probably want to
+	// pull from there and sync the cluster afterwards.
+	start := time.Now()
+	err := j.Do(jobLogger)
+	jobDuration.With(
+		fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+	).Observe(time.Since(start).Seconds())
+	if err != nil {
+		jobLogger.Log("state", "done", "success", "false", "err", err)
+	} else {
+		jobLogger.Log("state", "done", "success", "true")
+		ctx, cancel := context.WithTimeout(context.Background(), gitOpTimeout)
+		err := d.Repo.Refresh(ctx)
+		if err != nil {
+			logger.Log("err", err)
+		}
+		cancel()
+	}
+}
+
+// recordSyncRevision remembers the revision the cluster was last
+// synced to, and when it was first synced.
+func (loop *LoopVars) recordSyncRevision(rev string) {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	loop.syncedRevision = rev
+	loop.syncHistory = appendSyncRecord(loop.syncHistory, syncRecord{Revision: rev, At: time.Now().UTC()})
+}
+
+// lastSyncedRevision gives the revision the cluster was last synced
+// to, if it's been synced since the daemon started.
+func (loop *LoopVars) lastSyncedRevision() string {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	return loop.syncedRevision
+}
+
+// recordRelease remembers the event as the latest release of each of
+// the workloads it affects, if it's a release.
+func (loop *LoopVars) recordRelease(ev event.Event) {
+	if ev.Type != event.EventRelease && ev.Type != event.EventAutoRelease && ev.Type != event.EventRollback {
+		return
+	}
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	if loop.lastReleases == nil {
+		loop.lastReleases = map[string]event.Event{}
+	}
+	for _, id := range ev.ServiceIDs {
+		if last, ok := loop.lastReleases[id.String()]; ok && last.EndedAt.After(ev.EndedAt) {
+			continue
+		}
+		loop.lastReleases[id.String()] = ev
+	}
+}
+
+func (loop *LoopVars) syncHadErrors() bool {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	return loop.syncErrored
+}
+
+func (loop *LoopVars) recordSyncErrors(errored bool) {
+	loop.deployedMu.Lock()
+	defer loop.deployedMu.Unlock()
+	loop.syncErrored = errored
+}
+
 // Ask for a sync, or if there's one waiting, let that happen.
 func (d *LoopVars) AskForSync() {
 	d.ensureInit()
@@ -152,27 +327,38 @@ func (d *LoopVars) AskForImagePoll() {
 func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 	started := time.Now().UTC()
 	defer func() {
+		took := time.Since(started).Seconds()
 		syncDuration.With(
 			fluxmetrics.LabelSuccess, fmt.Sprint(retErr == nil),
-		).Observe(time.Since(started).Seconds())
+		).Observe(took)
+		syncLastDuration.With(
+			fluxmetrics.LabelSuccess, fmt.Sprint(retErr == nil),
+		).Set(took)
 	}()
 	// We don't care how long this takes overall, only about not
 	// getting bogged down in certain operations, so use an
 	// undeadlined context in general.
-	ctx := context.Background()
+	ctx, span := d.Tracer.Start(context.Background(), "sync")
+	defer func() { span.End(retErr) }()
 
-	// checkout a working clone so we can mess around with tags later
-	var working *git.Checkout
+	// Fetch the manifests; from git, this is a working clone so we
+	// can mess around with tags later
+	var snapshot *source.Snapshot
 	{
 		var err error
-		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+		store, timeout := d.manifestStore()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
-		working, err = d.Repo.Clone(ctx, d.GitConfig)
+		snapshot, err = store.Fetch(ctx)
 		if err != nil {
 			return err
 		}
-		defer working.Clean()
+		defer snapshot.Clean()
 	}
+	if snapshot.Checkout == nil {
+		return d.syncSnapshot(ctx, started, snapshot, logger)
+	}
+	working := snapshot.Checkout
 
 	// For comparison later.
 	oldTagRev, err := working.SyncRevision(ctx)
@@ -180,35 +366,92 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 		return err
 	}
 
-	newTagRev, err := working.HeadRevision(ctx)
-	if err != nil {
+	newTagRev := snapshot.Revision
+
+	// Tags needn't follow on from one another, so only a branch can
+	// be said to have been rewritten
+	if oldTagRev != "" && d.SyncTags == nil {
+		if err := d.checkRewrite(ctx, oldTagRev, newTagRev, logger); err != nil {
+			return err
+		}
+	}
+
+	if d.VerifySignatures {
+		if err := d.verifyCommits(ctx, d.Repo, oldTagRev, newTagRev, logger); err != nil {
+			return err
+		}
+	}
+
+	// Commits marked to be skipped are held back until there's one
+	// after them that isn't; meanwhile, the commit before them is
+	// synced (or what was synced before, is again)
+	headRev := newTagRev
+	if newTagRev, err = d.skipSync(ctx, oldTagRev, headRev, logger); err != nil {
 		return err
 	}
+	if newTagRev == "" {
+		logger.Log("sync", "skipped", "reason", "every commit is marked to skip, and nothing has been synced before")
+		return nil
+	}
+	if newTagRev != headRev {
+		ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+		err := working.CheckoutRevision(ctx, newTagRev)
+		cancel()
+		if err != nil {
+			return errors.Wrap(err, "checking out the revision before those skipped")
+		}
+	}
 
 	// Get a map of all resources defined in the repo
-	allResources, err := d.Manifests.LoadManifests(working.Dir(), working.ManifestDirs())
+	allResources, err := d.ManifestCache.Load(d.Manifests, newTagRev, working.Dir(), working.ManifestDirs())
 	if err != nil {
 		return errors.Wrap(err, "loading resources from repo")
 	}
 
-	var syncErrors []event.ResourceError
-	// TODO supply deletes argument from somewhere (command-line?)
-	if err := fluxsync.Sync(d.Manifests, allResources, d.Cluster, false, logger); err != nil {
-		logger.Log("err", err)
-		switch syncerr := err.(type) {
-		case cluster.SyncError:
-			for _, e := range syncerr {
-				syncErrors = append(syncErrors, event.ResourceError{
-					ID:    e.ResourceID(),
-					Path:  e.Source(),
-					Error: e.Error.Error(),
-				})
+	// Commit manifests for any namespaces missing from the repo, so
+	// they're synced along with the resources in them. That can't be
+	// done when syncing a tag, since there's no branch to commit to.
+	if d.NamespaceBootstrap != nil && d.SyncTags == nil && newTagRev == headRev {
+		committed, err := d.bootstrapNamespaces(ctx, working, allResources, logger)
+		if err != nil {
+			return errors.Wrap(err, "bootstrapping namespaces")
+		}
+		if committed {
+			if newTagRev, err = working.HeadRevision(ctx); err != nil {
+				return err
+			}
+			if allResources, err = d.ManifestCache.Load(d.Manifests, newTagRev, working.Dir(), working.ManifestDirs()); err != nil {
+				return errors.Wrap(err, "loading resources from repo")
 			}
-		default:
-			return err
 		}
 	}
 
+	// Check the sync wouldn't change more than expected
+	span.SetAttributes("revision", newTagRev)
+	if ok, err := d.guardSync(ctx, oldTagRev, newTagRev, allResources, logger); err != nil || !ok {
+		return err
+	}
+
+	// The guard only knows the history of the git repo, so the
+	// resources in any extra repos are added after it's had a look
+	extraSynced, err := d.addExtraRepos(ctx, allResources, logger)
+	if err != nil {
+		return err
+	}
+
+	if d.DetectSyncConflicts {
+		d.checkSyncConflicts(allResources, logger)
+	}
+
+	syncErrors, err := d.syncResources(allResources, logger)
+	if err != nil {
+		return err
+	}
+	d.recordExtraRepoSyncs(extraSynced, logger)
+
+	// If the last sync had errors and this one doesn't, say so
+	recovered := len(syncErrors) == 0 && d.syncHadErrors()
+
 	// update notes and emit events for applied commits
 
 	var initialSync bool
@@ -273,6 +516,9 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 		var noteEvents []event.Event
 
 		// Find notes in revisions.
+		// The job IDs from the notes, by revision, to correlate the
+		// commits with the changes they were made for
+		correlations := map[string]string{}
 		for i := len(commits) - 1; i >= 0; i-- {
 			if _, ok := notes[commits[i].Revision]; !ok {
 				includes[event.NoneOfTheAbove] = true
@@ -304,21 +550,26 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 				break
 			}
 
+			correlations[commits[i].Revision] = string(n.JobID)
+
 			// Interpret some notes as events to send to the upstream
 			switch n.Spec.Type {
 			case update.Images:
 				spec := n.Spec.Spec.(update.ReleaseSpec)
 				noteEvents = append(noteEvents, event.Event{
-					ServiceIDs: n.Result.AffectedResources(),
-					Type:       event.EventRelease,
-					StartedAt:  started,
-					EndedAt:    time.Now().UTC(),
-					LogLevel:   event.LogLevelInfo,
+					ServiceIDs:    n.Result.AffectedResources(),
+					Type:          event.EventRelease,
+					StartedAt:     started,
+					EndedAt:       time.Now().UTC(),
+					LogLevel:      event.LogLevelInfo,
+					CorrelationID: string(n.JobID),
 					Metadata: &event.ReleaseEventMetadata{
 						ReleaseEventCommon: event.ReleaseEventCommon{
 							Revision: commits[i].Revision,
 							Result:   n.Result,
 							Error:    n.Result.Error(),
+							SBOMs:    d.locateSBOMs(n.Result, logger),
+							Capacity: n.Capacity,
 						},
 						Spec:  spec,
 						Cause: n.Spec.Cause,
@@ -328,21 +579,48 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 			case update.Auto:
 				spec := n.Spec.Spec.(update.Automated)
 				noteEvents = append(noteEvents, event.Event{
-					ServiceIDs: n.Result.AffectedResources(),
-					Type:       event.EventAutoRelease,
-					StartedAt:  started,
-					EndedAt:    time.Now().UTC(),
-					LogLevel:   event.LogLevelInfo,
+					ServiceIDs:    n.Result.AffectedResources(),
+					Type:          event.EventAutoRelease,
+					StartedAt:     started,
+					EndedAt:       time.Now().UTC(),
+					LogLevel:      event.LogLevelInfo,
+					CorrelationID: string(n.JobID),
 					Metadata: &event.AutoReleaseEventMetadata{
+						ReleaseEventCommon: event.ReleaseEventCommon{
+							Revision:      commits[i].Revision,
+							Result:        n.Result,
+							Error:         n.Result.Error(),
+							SBOMs:         d.locateSBOMs(n.Result, logger),
+							Verifications: d.imageVerifications(n.Result),
+							Capacity:      n.Capacity,
+						},
+						Spec: spec,
+					},
+				})
+				includes[event.EventAutoRelease] = true
+			case update.Rollback:
+				spec := n.Spec.Spec.(update.RollbackSpec)
+				noteEvents = append(noteEvents, event.Event{
+					ServiceIDs:    n.Result.AffectedResources(),
+					Type:          event.EventRollback,
+					StartedAt:     started,
+					EndedAt:       time.Now().UTC(),
+					LogLevel:      event.LogLevelInfo,
+					CorrelationID: string(n.JobID),
+					Metadata: &event.RollbackEventMetadata{
 						ReleaseEventCommon: event.ReleaseEventCommon{
 							Revision: commits[i].Revision,
 							Result:   n.Result,
 							Error:    n.Result.Error(),
+							Capacity: n.Capacity,
 						},
-						Spec: spec,
+						ReleaseID:       event.EventID(spec.ReleaseID),
+						ReleaseRevision: spec.ReleaseRevision,
+						Reason:          spec.Reason,
+						Cause:           n.Spec.Cause,
 					},
 				})
-				includes[event.EventAutoRelease] = true
+				includes[event.EventRollback] = true
 			case update.Policy:
 				// Use this to mean any change to policy
 				includes[event.EventUpdatePolicy] = true
@@ -357,18 +635,24 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 		for i, c := range commits {
 			cs[i].Revision = c.Revision
 			cs[i].Message = c.Message
+			cs[i].Time = c.Time
+			cs[i].CorrelationID = correlations[c.Revision]
 		}
 		if err = d.LogEvent(event.Event{
-			ServiceIDs: serviceIDs.ToSlice(),
-			Type:       event.EventSync,
-			StartedAt:  started,
-			EndedAt:    started,
-			LogLevel:   event.LogLevelInfo,
+			ServiceIDs:    serviceIDs.ToSlice(),
+			Type:          event.EventSync,
+			StartedAt:     started,
+			EndedAt:       started,
+			LogLevel:      event.LogLevelInfo,
+			CorrelationID: soleCorrelation(cs),
+			TraceID:       span.TraceID(),
+			SpanID:        span.SpanID(),
 			Metadata: &event.SyncEventMetadata{
 				Commits:     cs,
 				InitialSync: initialSync,
 				Includes:    includes,
 				Errors:      syncErrors,
+				Recovered:   recovered,
 			},
 		}); err != nil {
 			logger.Log("err", err)
@@ -377,13 +661,34 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 		}
 
 		for _, event := range noteEvents {
+			event.TraceID, event.SpanID = span.TraceID(), span.SpanID()
 			if err = d.LogEvent(event); err != nil {
 				logger.Log("err", err)
 				// Abort early to ensure at least once delivery of events
 				return err
 			}
 		}
+	} else if len(syncErrors) > 0 || recovered {
+		// Report errors, and their resolution, even when there's
+		// nothing new to sync; repeated errors are throttled before
+		// they become notifications.
+		if err = d.LogEvent(event.Event{
+			Type:      event.EventSync,
+			StartedAt: started,
+			EndedAt:   started,
+			LogLevel:  event.LogLevelInfo,
+			TraceID:   span.TraceID(),
+			SpanID:    span.SpanID(),
+			Metadata: &event.SyncEventMetadata{
+				Errors:    syncErrors,
+				Recovered: recovered,
+			},
+		}); err != nil {
+			logger.Log("err", err)
+			return err
+		}
 	}
+	d.recordSyncErrors(len(syncErrors) > 0)
 
 	// Move the tag and push it so we know how far we've gotten.
 	{
@@ -394,6 +699,7 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 			return err
 		}
 	}
+	d.recordSyncRevision(newTagRev)
 
 	if oldTagRev != newTagRev {
 		logger.Log("tag", d.GitConfig.SyncTag, "old", oldTagRev, "new", newTagRev)
@@ -406,8 +712,157 @@ func (d *Daemon) doSync(logger log.Logger) (retErr error) {
 	return nil
 }
 
+// manifestStore gives where to fetch the manifests to sync from, and
+// how long to allow for fetching them: the store given, or else the
+// git repo.
+func (d *Daemon) manifestStore() (source.Store, time.Duration) {
+	if d.ManifestStore != nil {
+		return d.ManifestStore, manifestFetchTimeout
+	}
+	return d.gitSource(), gitOpTimeout
+}
+
+// gitSource gives the git repo as a manifest store, at the head of
+// the branch or at the latest tag that matches.
+func (d *Daemon) gitSource() source.Git {
+	return source.Git{Repo: d.Repo, Config: d.GitConfig, Tags: d.SyncTags}
+}
+
+// syncResources applies the resources to the cluster, giving any
+// errors particular to resources, or an error if the sync couldn't be
+// done at all.
+func (d *Daemon) syncResources(resources map[string]resource.Resource, logger log.Logger) ([]event.ResourceError, error) {
+	var syncErrors []event.ResourceError
+	// TODO supply deletes argument from somewhere (command-line?)
+	if err := fluxsync.Sync(d.Manifests, resources, d.Cluster, false, logger); err != nil {
+		logger.Log("err", err)
+		switch syncerr := err.(type) {
+		case cluster.SyncError:
+			for _, e := range syncerr {
+				syncErrors = append(syncErrors, event.ResourceError{
+					ID:    e.ResourceID(),
+					Path:  e.Source(),
+					Error: e.Error.Error(),
+				})
+			}
+		default:
+			return nil, err
+		}
+	}
+	return syncErrors, nil
+}
+
+// syncSnapshot syncs the manifests fetched from a store other than
+// git. There are no commits, notes or sync tag to go by, so the
+// revision last synced is only remembered while the daemon runs, and
+// the sync guard isn't consulted.
+func (d *Daemon) syncSnapshot(ctx context.Context, started time.Time, snapshot *source.Snapshot, logger log.Logger) error {
+	span := tracing.SpanFromContext(ctx)
+	span.SetAttributes("revision", snapshot.Revision)
+	oldRev := d.lastSyncedRevision()
+	allResources, err := d.ManifestCache.Load(d.Manifests, snapshot.Revision, snapshot.Dir(), snapshot.ManifestDirs())
+	if err != nil {
+		return errors.Wrap(err, "loading resources from manifest store")
+	}
+	extraSynced, err := d.addExtraRepos(ctx, allResources, logger)
+	if err != nil {
+		return err
+	}
+	if d.DetectSyncConflicts {
+		d.checkSyncConflicts(allResources, logger)
+	}
+	syncErrors, err := d.syncResources(allResources, logger)
+	if err != nil {
+		return err
+	}
+	d.recordExtraRepoSyncs(extraSynced, logger)
+	recovered := len(syncErrors) == 0 && d.syncHadErrors()
+
+	metadata := &event.SyncEventMetadata{
+		Errors:    syncErrors,
+		Recovered: recovered,
+	}
+	var serviceIDs []flux.ResourceID
+	if snapshot.Revision != oldRev {
+		for _, r := range allResources {
+			serviceIDs = append(serviceIDs, r.ResourceID())
+		}
+		metadata.Commits = []event.Commit{{Revision: snapshot.Revision, Time: started}}
+		metadata.InitialSync = oldRev == ""
+		metadata.Includes = map[string]bool{event.NoneOfTheAbove: true}
+	}
+	if snapshot.Revision != oldRev || len(syncErrors) > 0 || recovered {
+		if err := d.LogEvent(event.Event{
+			ServiceIDs: serviceIDs,
+			Type:       event.EventSync,
+			StartedAt:  started,
+			EndedAt:    started,
+			LogLevel:   event.LogLevelInfo,
+			Metadata:   metadata,
+			TraceID:    span.TraceID(),
+			SpanID:     span.SpanID(),
+		}); err != nil {
+			logger.Log("err", err)
+			return err
+		}
+	}
+	d.recordSyncErrors(len(syncErrors) > 0)
+	if snapshot.Revision != oldRev {
+		logger.Log("revision", snapshot.Revision, "old", oldRev)
+	}
+	d.recordSyncRevision(snapshot.Revision)
+	return nil
+}
+
+// locateSBOMs finds the SBOMs for the images in a release, if we've
+// been asked to record them.
+func (d *Daemon) locateSBOMs(result update.Result, logger log.Logger) []event.SBOM {
+	if d.SBOMs == nil {
+		return nil
+	}
+	sboms, err := supplychain.LocateSBOMs(d.SBOMs, result)
+	if err != nil {
+		logger.Log("warning", "locating SBOMs for release", "err", err)
+	}
+	return sboms
+}
+
+// imageVerifications gives the outcome of verifying the images in an
+// automated release, if they were verified.
+func (d *Daemon) imageVerifications(result update.Result) []event.ImageVerification {
+	if d.ImageVerifier == nil {
+		return nil
+	}
+	var verifications []event.ImageVerification
+	for _, ref := range result.ChangedImages() {
+		id, err := image.ParseRef(ref)
+		if err != nil {
+			continue
+		}
+		if v, ok := d.ImageVerifier.Lookup(id); ok {
+			verifications = append(verifications, v)
+		}
+	}
+	return verifications
+}
+
 func isUnknownRevision(err error) bool {
 	return err != nil &&
 		(strings.Contains(err.Error(), "unknown revision or path not in the working tree.") ||
-			strings.Contains(err.Error(), "bad revision"))
+			strings.Contains(err.Error(), "bad revision") ||
+			strings.Contains(err.Error(), "Invalid revision range"))
+}
+
+// soleCorrelation gives the correlation ID of the commits synced, if
+// they were all made for the same change; otherwise, the sync can't
+// be attributed to one change, and it gives the empty string.
+func soleCorrelation(commits []event.Commit) string {
+	var id string
+	for _, c := range commits {
+		if c.CorrelationID == "" || (id != "" && c.CorrelationID != id) {
+			return ""
+		}
+		id = c.CorrelationID
+	}
+	return id
 }
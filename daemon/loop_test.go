@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"context"
 
@@ -23,6 +24,8 @@ import (
 	"github.com/weaveworks/flux/job"
 	registryMock "github.com/weaveworks/flux/registry/mock"
 	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/source"
+	"github.com/weaveworks/flux/tracing"
 )
 
 const (
@@ -309,3 +312,129 @@ func TestDoSync_WithNewCommit(t *testing.T) {
 		t.Errorf("Should have moved sync tag to HEAD (%s), but was moved to: %s", newRevision, revs[len(revs)-1].Revision)
 	}
 }
+
+func TestSoleCorrelation(t *testing.T) {
+	for _, c := range []struct {
+		commits  []event.Commit
+		expected string
+	}{
+		{nil, ""},
+		{[]event.Commit{{CorrelationID: "job1"}, {CorrelationID: "job1"}}, "job1"},
+		{[]event.Commit{{CorrelationID: "job1"}, {CorrelationID: "job2"}}, ""},
+		{[]event.Commit{{CorrelationID: "job1"}, {Revision: "abc"}}, ""},
+	} {
+		if got := soleCorrelation(c.commits); got != c.expected {
+			t.Errorf("%v: expected %q, got %q", c.commits, c.expected, got)
+		}
+	}
+}
+
+// fixedStore gives snapshots of the test files, at the revision
+// it's set to.
+type fixedStore struct {
+	t        *testing.T
+	revision string
+}
+
+func (s *fixedStore) Fetch(context.Context) (*source.Snapshot, error) {
+	dir, _ := testfiles.TempDir(s.t)
+	return source.NewSnapshot(s.revision, dir, nil), nil
+}
+
+func TestDoSync_ManifestStore(t *testing.T) {
+	k8s = &cluster.Mock{}
+	k8s.LoadManifestsFunc = kresource.Load
+	k8s.ParseManifestsFunc = func(allDefs []byte) (map[string]resource.Resource, error) {
+		return kresource.ParseMultidoc(allDefs, "exported")
+	}
+	k8s.ExportFunc = func() ([]byte, error) { return nil, nil }
+	syncs := 0
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		syncs++
+		return nil
+	}
+	events = &mockEventWriter{}
+	defer func() { k8s, events = nil, nil }()
+	store := &fixedStore{t: t, revision: "sha256:abc123"}
+	d := &Daemon{
+		Cluster:       k8s,
+		Manifests:     k8s,
+		ManifestStore: store,
+		EventWriter:   events,
+		Logger:        log.NewNopLogger(),
+		LoopVars:      &LoopVars{},
+	}
+
+	logger := log.NewNopLogger()
+	for _, rev := range []string{"sha256:abc123", "sha256:abc123", "sha256:def456"} {
+		store.revision = rev
+		if err := d.doSync(logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if syncs != 3 {
+		t.Errorf("expected a sync each time, got %d", syncs)
+	}
+	// Only the syncs of a new revision are recorded
+	if len(events.events) != 2 {
+		t.Fatalf("expected two sync events, got %+v", events.events)
+	}
+	for i, rev := range []string{"sha256:abc123", "sha256:def456"} {
+		metadata := events.events[i].Metadata.(*event.SyncEventMetadata)
+		if len(metadata.Commits) != 1 || metadata.Commits[0].Revision != rev {
+			t.Errorf("expected sync of %s, got %+v", rev, metadata.Commits)
+		}
+	}
+	if rev := d.lastSyncedRevision(); rev != "sha256:def456" {
+		t.Errorf("expected the last revision synced to be recorded, got %q", rev)
+	}
+}
+
+func TestDoSync_Traced(t *testing.T) {
+	k8s = &cluster.Mock{}
+	k8s.LoadManifestsFunc = kresource.Load
+	k8s.ParseManifestsFunc = func(allDefs []byte) (map[string]resource.Resource, error) {
+		return kresource.ParseMultidoc(allDefs, "exported")
+	}
+	k8s.ExportFunc = func() ([]byte, error) { return nil, nil }
+	k8s.SyncFunc = func(def cluster.SyncDef) error { return nil }
+	events = &mockEventWriter{}
+	defer func() { k8s, events = nil, nil }()
+	recorder := tracetest.NewInMemoryExporter()
+	d := &Daemon{
+		Cluster:       k8s,
+		Manifests:     k8s,
+		ManifestStore: &fixedStore{t: t, revision: "sha256:abc123"},
+		EventWriter:   events,
+		Logger:        log.NewNopLogger(),
+		LoopVars:      &LoopVars{},
+		Tracer:        tracing.NewTracer("fluxd", recorder),
+	}
+
+	if err := d.doSync(log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := recorder.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "sync" {
+		t.Fatalf("expected a sync span, got %+v", spans)
+	}
+	span := spans[0]
+	var revision string
+	for _, kv := range span.Attributes {
+		if kv.Key == "revision" {
+			revision = kv.Value.AsString()
+		}
+	}
+	if revision != "sha256:abc123" {
+		t.Errorf("expected the revision in the span, got %v", span.Attributes)
+	}
+	if len(events.events) != 1 {
+		t.Fatalf("expected a sync event, got %+v", events.events)
+	}
+	if ev := events.events[0]; ev.TraceID != span.SpanContext.TraceID().String() || ev.SpanID != span.SpanContext.SpanID().String() {
+		t.Errorf("expected the event to be in the sync span, got trace %q, span %q", ev.TraceID, ev.SpanID)
+	}
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/git"
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+	"github.com/weaveworks/flux/release"
+	"github.com/weaveworks/flux/resource"
+)
+
+// DefaultManifestCacheSize is how many revisions a ManifestCache
+// keeps the resources of, if not told otherwise.
+const DefaultManifestCacheSize = 10
+
+// ManifestCache keeps the resources loaded from the git repo at the
+// most recent revisions, so that operations at the same revision
+// (syncs, release dry-runs, comparisons) don't have to parse, or
+// generate, the manifests again. The zero value is ready to use.
+type ManifestCache struct {
+	Size int
+
+	mu      sync.Mutex
+	keys    []string
+	entries map[string]map[string]resource.Resource
+}
+
+// Load gives the resources under the paths given, in a checkout of
+// the revision given, using those cached for the revision if there
+// are any. The checkout must not have been changed from the
+// revision, since what's in the files is not checked. If the revision
+// is empty, or the cache is nil, the resources are loaded as usual.
+func (c *ManifestCache) Load(m cluster.Manifests, rev, base string, paths []string) (map[string]resource.Resource, error) {
+	if c == nil || rev == "" {
+		return m.LoadManifests(base, paths)
+	}
+	key, err := manifestCacheKey(rev, base, paths)
+	if err != nil {
+		return m.LoadManifests(base, paths)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	manifestCacheLookups.With(fluxmetrics.LabelHit, fmt.Sprint(ok)).Add(1)
+	if ok {
+		return copyResources(cached), nil
+	}
+
+	resources, err := m.LoadManifests(base, paths)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, resources)
+	return copyResources(resources), nil
+}
+
+func (c *ManifestCache) put(key string, resources map[string]resource.Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size := c.Size
+	if size <= 0 {
+		size = DefaultManifestCacheSize
+	}
+	if c.entries == nil {
+		c.entries = map[string]map[string]resource.Resource{}
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.entries[key] = resources
+	for len(c.keys) > size {
+		delete(c.entries, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+}
+
+// loadCheckout loads the resources in a checkout that's not been
+// changed, using those cached for its revision if there are any.
+func (d *Daemon) loadCheckout(ctx context.Context, checkout *git.Checkout) (map[string]resource.Resource, error) {
+	var rev string
+	if d.ManifestCache != nil {
+		// Without the revision, the resources are loaded as usual
+		rev, _ = checkout.HeadRevision(ctx)
+	}
+	return d.ManifestCache.Load(d.Manifests, rev, checkout.Dir(), checkout.ManifestDirs())
+}
+
+// releaseContext makes a release context for the checkout, which
+// loads the resources from the cache until it has made changes.
+func (d *Daemon) releaseContext(ctx context.Context, working *git.Checkout) *release.ReleaseContext {
+	rc := release.NewReleaseContext(d.Cluster, d.Manifests, d.Registry, working, d.ImageRewrites, d.ReleaseGate, d.Promotion, d.PullCheck)
+	if d.ManifestCache != nil {
+		if rev, err := working.HeadRevision(ctx); err == nil {
+			rc.LoadCleanManifestsWith(func() (map[string]resource.Resource, error) {
+				return d.ManifestCache.Load(d.Manifests, rev, working.Dir(), working.ManifestDirs())
+			})
+		}
+	}
+	return rc
+}
+
+// manifestCacheKey identifies the resources at a revision, under
+// paths given relative to the checkout, since each checkout is in a
+// directory of its own.
+func manifestCacheKey(rev, base string, paths []string) (string, error) {
+	rels := make([]string, len(paths))
+	for i, p := range paths {
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return "", err
+		}
+		rels[i] = rel
+	}
+	return fmt.Sprintf("%s:%s", rev, strings.Join(rels, ",")), nil
+}
+
+// copyResources copies the map of resources, so that callers can add
+// to or remove from it without changing what's cached.
+func copyResources(resources map[string]resource.Resource) map[string]resource.Resource {
+	res := make(map[string]resource.Resource, len(resources))
+	for k, v := range resources {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/cluster/kubernetes"
+	"github.com/weaveworks/flux/resource"
+)
+
+func TestManifestCache(t *testing.T) {
+	var loads int
+	manifests := &cluster.Mock{
+		LoadManifestsFunc: func(base string, paths []string) (map[string]resource.Resource, error) {
+			loads++
+			return (&kubernetes.Manifests{}).ParseManifests([]byte(bootstrapManifests))
+		},
+	}
+	cache := &ManifestCache{Size: 2}
+
+	load := func(rev, base string, paths ...string) map[string]resource.Resource {
+		resources, err := cache.Load(manifests, rev, base, paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resources
+	}
+
+	first := load("a", "/tmp/one", "/tmp/one")
+	delete(first, "payments:deployment/payments")
+	// Another checkout of the same revision
+	if again := load("a", "/tmp/two", "/tmp/two"); loads != 1 {
+		t.Errorf("expected resources at the same revision to be cached, loaded %d times", loads)
+	} else if _, ok := again["payments:deployment/payments"]; !ok {
+		t.Error("expected changing the resources returned not to change those cached")
+	}
+
+	load("a", "/tmp/one", "/tmp/one/config")
+	load("b", "/tmp/one", "/tmp/one")
+	if loads != 3 {
+		t.Errorf("expected a different revision or path to be loaded, loaded %d times", loads)
+	}
+	// Only the two most recent are kept
+	load("a", "/tmp/one", "/tmp/one")
+	if loads != 4 {
+		t.Errorf("expected the oldest revision to have been dropped, loaded %d times", loads)
+	}
+
+	// Without a revision, or a cache, there's no caching
+	load("", "/tmp/one", "/tmp/one")
+	var none *ManifestCache
+	if _, err := none.Load(manifests, "b", "/tmp/one", []string{"/tmp/one"}); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 6 {
+		t.Errorf("expected no caching, loaded %d times", loads)
+	}
+}
@@ -1,9 +1,16 @@
 package daemon
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/pkg/errors"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
+	"github.com/weaveworks/flux/api/v23"
 	fluxmetrics "github.com/weaveworks/flux/metrics"
 )
 
@@ -18,6 +25,13 @@ var (
 		Buckets:   []float64{0.5, 5, 10, 20, 30, 40, 50, 60, 75, 90, 120, 240},
 	}, []string{fluxmetrics.LabelSuccess})
 
+	syncLastDuration = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "sync_last_duration_seconds",
+		Help:      "Duration of the most recent git-to-cluster synchronisation, in seconds.",
+	}, []string{fluxmetrics.LabelSuccess})
+
 	// For most jobs, the majority of the time will be spent pushing
 	// changes (git objects and refs) upstream.
 	jobDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
@@ -44,4 +58,110 @@ var (
 		Name:      "queue_length_count",
 		Help:      "Count of jobs waiting in the queue to be run.",
 	}, []string{})
+
+	// Delivery metrics, per workload: from these, the frequency of
+	// deploys and the (change) failure rate can be worked out over
+	// whatever period is of interest.
+	workloadDeploys = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "workload_deploys_total",
+		Help:      "Count of syncs that changed a workload.",
+	}, []string{fluxmetrics.LabelWorkload})
+
+	// Lead times range from a minute or so, for automated releases,
+	// to days or weeks for changes that have waited to be merged.
+	workloadLeadTime = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "workload_lead_time_seconds",
+		Help:      "Time from the earliest commit in a sync to the sync, for each workload it changed, in seconds.",
+		Buckets:   []float64{60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 24 * 3600, 3 * 24 * 3600, 7 * 24 * 3600, 30 * 24 * 3600},
+	}, []string{fluxmetrics.LabelWorkload})
+
+	automationDeferrals = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "automation_deferrals_total",
+		Help:      "Count of times automation was deferred, because the job queue or cluster API was busy.",
+	}, []string{fluxmetrics.LabelReason})
+
+	automationDeferred = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "automation_deferred_seconds",
+		Help:      "How long automation has been deferred for, in seconds; zero when it isn't.",
+	}, []string{})
+
+	automationFailures = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "automation_failures_total",
+		Help:      "Count of automated releases of a workload that failed, or were rolled back.",
+	}, []string{fluxmetrics.LabelReason})
+
+	automationSuspensions = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "automation_suspensions_total",
+		Help:      "Count of times automation was turned off for a workload, because it failed too often.",
+	}, []string{})
+
+	workloadRollbacks = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "workload_rollbacks_total",
+		Help:      "Count of releases of a workload back to an image it ran before.",
+	}, []string{fluxmetrics.LabelWorkload})
+
+	manifestCacheLookups = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "daemon",
+		Name:      "manifest_cache_lookups_total",
+		Help:      "Count of loads of the manifests at a revision, by whether they were already cached.",
+	}, []string{fluxmetrics.LabelHit})
 )
+
+// MetricsSnapshot gives the current value of each of flux's metrics,
+// for looking at when they can't be scraped.
+func (d *Daemon) MetricsSnapshot(ctx context.Context) (v23.MetricsSnapshot, error) {
+	gatherer := d.MetricsGatherer
+	if gatherer == nil {
+		gatherer = stdprometheus.DefaultGatherer
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		return v23.MetricsSnapshot{}, errors.Wrap(err, "gathering metrics")
+	}
+	snapshot := v23.MetricsSnapshot{Time: time.Now().UTC()}
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "flux_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			metric := v23.Metric{Name: family.GetName()}
+			if len(m.GetLabel()) > 0 {
+				metric.Labels = map[string]string{}
+				for _, l := range m.GetLabel() {
+					metric.Labels[l.GetName()] = l.GetValue()
+				}
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				metric.Type, metric.Value = v23.MetricCounter, m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				metric.Type, metric.Value = v23.MetricGauge, m.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				metric.Type = v23.MetricHistogram
+				metric.Value, metric.Count = m.GetHistogram().GetSampleSum(), m.GetHistogram().GetSampleCount()
+			case dto.MetricType_SUMMARY:
+				metric.Type = v23.MetricSummary
+				metric.Value, metric.Count = m.GetSummary().GetSampleSum(), m.GetSummary().GetSampleCount()
+			default:
+				metric.Type, metric.Value = v23.MetricUntyped, m.GetUntyped().GetValue()
+			}
+			snapshot.Metrics = append(snapshot.Metrics, metric)
+		}
+	}
+	return snapshot, nil
+}
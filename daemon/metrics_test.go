@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/weaveworks/flux/api/v23"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	queue := stdprometheus.NewGauge(stdprometheus.GaugeOpts{Name: "flux_test_queue_length_count", Help: "Queue length."})
+	syncs := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{Name: "flux_test_sync_duration_seconds", Help: "Sync durations."}, []string{"success"})
+	other := stdprometheus.NewCounter(stdprometheus.CounterOpts{Name: "go_other_total", Help: "Something else."})
+	registry.MustRegister(queue, syncs, other)
+	queue.Set(3)
+	syncs.WithLabelValues("true").Observe(10)
+	syncs.WithLabelValues("true").Observe(20)
+	other.Inc()
+
+	d := &Daemon{MetricsGatherer: registry}
+	snapshot, err := d.MetricsSnapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Time.IsZero() {
+		t.Error("expected the snapshot to say when it was taken")
+	}
+	if len(snapshot.Metrics) != 2 {
+		t.Fatalf("expected only the flux metrics, got %+v", snapshot.Metrics)
+	}
+	byName := map[string]v23.Metric{}
+	for _, m := range snapshot.Metrics {
+		byName[m.Name] = m
+	}
+	if m := byName["flux_test_queue_length_count"]; m.Type != v23.MetricGauge || m.Value != 3 {
+		t.Errorf("unexpected gauge %+v", m)
+	}
+	if m := byName["flux_test_sync_duration_seconds"]; m.Type != v23.MetricHistogram || m.Count != 2 || m.Value != 30 || m.Labels["success"] != "true" {
+		t.Errorf("unexpected histogram %+v", m)
+	}
+}
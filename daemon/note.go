@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/update"
 )
@@ -9,4 +10,7 @@ type note struct {
 	JobID  job.ID        `json:"jobID"`
 	Spec   update.Spec   `json:"spec"`
 	Result update.Result `json:"result"`
+	// The analysis of whether the cluster has room for the release,
+	// if it was checked
+	Capacity *cluster.CapacityReport `json:"capacity,omitempty"`
 }
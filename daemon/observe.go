@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/release"
+	"github.com/weaveworks/flux/update"
+)
+
+// observedAutomation is an automated release that is only planned,
+// and not committed.
+type observedAutomation struct {
+	*update.Automated
+}
+
+func (observedAutomation) ReleaseKind() update.ReleaseKind {
+	return update.ReleaseKindPlan
+}
+
+// observing says whether automation of a workload with the policies
+// given is only to be observed; either because it has the observe
+// policy, or because all automation is.
+func (d *Daemon) observing(policies policy.Set) bool {
+	return d.ObserveAutomation || policies.Has(policy.Observe)
+}
+
+// observeAutomation works out what would be released, were the
+// changes given made by automation, and records it as an event. Each
+// image is recorded once for each workload container, until there's
+// another image to release to it.
+func (d *Daemon) observeAutomation(changes *update.Automated) {
+	d.observedMu.Lock()
+	if d.observed == nil {
+		d.observed = map[string]string{}
+	}
+	fresh := &update.Automated{}
+	var keys []string
+	for _, c := range changes.Changes {
+		key := c.ServiceID.String() + ":" + c.Container.Name
+		if d.observed[key] == c.ImageID.String() {
+			continue
+		}
+		d.observed[key] = c.ImageID.String()
+		keys = append(keys, key)
+		fresh.Changes = append(fresh.Changes, c)
+	}
+	d.observedMu.Unlock()
+	if len(fresh.Changes) == 0 {
+		return
+	}
+
+	spec := update.Spec{Type: update.Auto, Spec: fresh}
+	d.queueJob(context.Background(), spec.Type, jobWorkloads(fresh), d.makeJobFromUpdate(func(ctx context.Context, jobID job.ID, working *git.Checkout, logger log.Logger) (job.Result, error) {
+		result, err := d.planObservedRelease(ctx, working, fresh, logger)
+		if err != nil {
+			// Try again next time
+			d.observedMu.Lock()
+			for _, key := range keys {
+				delete(d.observed, key)
+			}
+			d.observedMu.Unlock()
+			return job.Result{}, err
+		}
+		return job.Result{Spec: &spec, Result: result}, nil
+	}))
+}
+
+func (d *Daemon) planObservedRelease(ctx context.Context, working *git.Checkout, changes *update.Automated, logger log.Logger) (update.Result, error) {
+	started := time.Now().UTC()
+	rc := d.releaseContext(ctx, working)
+	result, err := release.Release(ctx, rc, observedAutomation{changes}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceIDs []flux.ResourceID
+	for id, res := range result {
+		if res.Status == update.ReleaseStatusSuccess {
+			serviceIDs = append(serviceIDs, id)
+		}
+	}
+	if len(serviceIDs) == 0 {
+		return result, nil
+	}
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: serviceIDs,
+		Type:       event.EventObservedRelease,
+		StartedAt:  started,
+		EndedAt:    time.Now().UTC(),
+		LogLevel:   event.LogLevelInfo,
+		Metadata: &event.ObservedReleaseEventMetadata{
+			Spec:   *changes,
+			Result: result,
+		},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging observed release event"))
+	}
+	return result, nil
+}
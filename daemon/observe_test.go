@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestObserving(t *testing.T) {
+	d := &Daemon{}
+	if d.observing(policy.Set{policy.Automated: "true"}) {
+		t.Error("expected automated workload to be released to")
+	}
+	if !d.observing(policy.Set{policy.Automated: "true", policy.Observe: "true"}) {
+		t.Error("expected workload with observe policy to be observed")
+	}
+	d.ObserveAutomation = true
+	if !d.observing(policy.Set{policy.Automated: "true"}) {
+		t.Error("expected all automation to be observed")
+	}
+}
+
+func TestObserveAutomationOnce(t *testing.T) {
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	d := &Daemon{
+		Jobs:           job.NewQueue(stop, wg),
+		JobStatusCache: &job.StatusCache{Size: 10},
+		LoopVars:       &LoopVars{},
+	}
+
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	container := resource.Container{Name: "greeter"}
+	changes := func(tag string) *update.Automated {
+		ref, _ := image.ParseRef("quay.io/weaveworks/helloworld:" + tag)
+		a := &update.Automated{}
+		a.Add(id, container, ref)
+		return a
+	}
+
+	d.observeAutomation(changes("v2"))
+	d.observeAutomation(changes("v2"))
+	d.Jobs.Sync()
+	if n := d.Jobs.Len(); n != 1 {
+		t.Fatalf("expected one observation to be queued, got %d", n)
+	}
+	d.observeAutomation(changes("v3"))
+	d.Jobs.Sync()
+	if n := d.Jobs.Len(); n != 2 {
+		t.Errorf("expected newer image to be observed, got %d jobs queued", n)
+	}
+}
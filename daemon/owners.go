@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+)
+
+// ownerOf gives the team owning a workload: the value of the first of
+// OwnerKeys found among its annotations or labels (annotations first,
+// for each key), or the empty string if none is present.
+func (d *Daemon) ownerOf(c cluster.Controller) string {
+	for _, key := range d.OwnerKeys {
+		if owner := c.Annotations[key]; owner != "" {
+			return owner
+		}
+		if owner := c.Labels[key]; owner != "" {
+			return owner
+		}
+	}
+	return ""
+}
+
+// addOwners fills in the owners of the workloads an event concerns,
+// if it doesn't have them already.
+func (d *Daemon) addOwners(ev *event.Event) error {
+	if len(d.OwnerKeys) == 0 || len(ev.Owners) > 0 || len(ev.ServiceIDs) == 0 {
+		return nil
+	}
+	controllers, err := d.Cluster.SomeControllers(ev.ServiceIDs)
+	if err != nil {
+		return errors.Wrap(err, "getting workloads to find their owners")
+	}
+	seen := map[string]bool{}
+	for _, c := range controllers {
+		if owner := d.ownerOf(c); owner != "" && !seen[owner] {
+			seen[owner] = true
+			ev.Owners = append(ev.Owners, owner)
+		}
+	}
+	sort.Strings(ev.Owners)
+	return nil
+}
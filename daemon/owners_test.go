@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestOwners(t *testing.T) {
+	frontend := cluster.Controller{
+		ID:          flux.MustParseResourceID("default:deployment/frontend"),
+		Labels:      map[string]string{"team": "web-label"},
+		Annotations: map[string]string{"example.com/team": "web"},
+	}
+	backend := cluster.Controller{
+		ID:     flux.MustParseResourceID("default:deployment/backend"),
+		Labels: map[string]string{"team": "data"},
+	}
+	unowned := cluster.Controller{
+		ID: flux.MustParseResourceID("default:deployment/unowned"),
+	}
+	d := &Daemon{
+		Cluster: &cluster.Mock{
+			SomeServicesFunc: func([]flux.ResourceID) ([]cluster.Controller, error) {
+				return []cluster.Controller{frontend, backend, unowned, backend}, nil
+			},
+		},
+		OwnerKeys: []string{"example.com/team", "team"},
+	}
+
+	for c, expected := range map[*cluster.Controller]string{
+		&frontend: "web",
+		&backend:  "data",
+		&unowned:  "",
+	} {
+		if owner := d.ownerOf(*c); owner != expected {
+			t.Errorf("%s: expected owner %q, got %q", c.ID, expected, owner)
+		}
+	}
+
+	ev := event.Event{
+		ServiceIDs: []flux.ResourceID{frontend.ID, backend.ID, unowned.ID},
+		Type:       event.EventSync,
+	}
+	if err := d.addOwners(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"data", "web"}; !reflect.DeepEqual(ev.Owners, expected) {
+		t.Errorf("expected owners %v, got %v", expected, ev.Owners)
+	}
+
+	d.OwnerKeys = nil
+	ev.Owners = nil
+	if err := d.addOwners(&ev); err != nil || ev.Owners != nil {
+		t.Errorf("expected no owners without owner keys, got %v (err %v)", ev.Owners, err)
+	}
+}
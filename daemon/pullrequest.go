@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/scm"
+	"github.com/weaveworks/flux/update"
+)
+
+// The branches automated releases are pushed to, when they're
+// proposed in pull requests, are named with this prefix.
+const pullRequestBranchPrefix = "flux-update/"
+
+// The ways automated releases can be grouped into pull requests, when
+// they're proposed in pull requests. Otherwise, what's found in each
+// automation run is proposed in one pull request.
+const (
+	PullRequestsPerImage     = "image"
+	PullRequestsPerWorkload  = "workload"
+	PullRequestsPerNamespace = "namespace"
+	// What's found in the automation runs over an interval, in one
+	// pull request
+	PullRequestsPerInterval = "interval"
+)
+
+// How much of the diff goes in the preview comment on a pull
+// request; git hosts limit the size of comments (GitHub to 65536
+// characters), and the full diff is in the pull request anyway.
+const maxPreviewDiff = 48 << 10
+
+// pullRequestBranch gives the name of the branch for the changes in
+// the release. It's the same for the same changes, so they aren't
+// proposed again each time automation runs while the pull request is
+// open.
+func pullRequestBranch(result update.Result) string {
+	var changes []string
+	for id, res := range result {
+		if res.Status != update.ReleaseStatusSuccess {
+			continue
+		}
+		for _, c := range res.PerContainer {
+			changes = append(changes, fmt.Sprintf("%s %s %s", id, c.Container, c.Target))
+		}
+	}
+	sort.Strings(changes)
+	sum := sha256.Sum256([]byte(strings.Join(changes, "\n")))
+	return pullRequestBranchPrefix + hex.EncodeToString(sum[:])[:12]
+}
+
+// commitAndOpenPullRequest commits the changes made in the working
+// clone, pushes them to a branch of their own, and opens a pull
+// request to merge them into the branch synced, with a comment
+// previewing the release. It gives the URL of the pull request, or an
+// empty string if the same changes have been pushed before (and so
+// are waiting on a pull request already).
+func (d *Daemon) commitAndOpenPullRequest(ctx context.Context, working *git.Checkout, commitAction git.CommitAction, n *note, logger log.Logger) (string, error) {
+	branch := pullRequestBranch(n.Result)
+	exists, err := working.BranchExists(ctx, branch)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		logger.Log("info", "changes already proposed", "branch", branch)
+		return "", nil
+	}
+
+	patch, err := working.Diff(ctx)
+	if err != nil {
+		// The preview will just be without it
+		logger.Log("warning", "diffing changes for preview", "err", err)
+	}
+
+	ctx, span := d.Tracer.Start(ctx, "commit", "branch", branch)
+	err = working.CommitAndPushBranch(ctx, branch, commitAction, n)
+	span.End(err)
+	if err != nil {
+		return "", err
+	}
+
+	title, body := commitAction.Message, ""
+	if i := strings.Index(title, "\n"); i >= 0 {
+		title, body = title[:i], strings.TrimSpace(title[i+1:])
+	}
+	opened, err := d.PullRequests.Open(ctx, scm.PullRequest{
+		Head:  branch,
+		Base:  d.GitConfig.Branch,
+		Title: title,
+		Body:  body,
+	})
+	if err != nil {
+		return "", err
+	}
+	logger.Log("pull-request", opened.URL, "branch", branch)
+	// The pull request is open whether or not this works, so it's
+	// not a reason to fail the release
+	if err := d.PullRequests.Comment(ctx, opened, releasePreview(n.Result, patch)); err != nil {
+		logger.Log("warning", "commenting on pull request", "pull-request", opened.URL, "err", err)
+	}
+	return opened.URL, nil
+}
+
+// groupAutomated splits the changes found in an automation run into
+// those to be proposed in each pull request, according to the
+// grouping; or gives them as they are, if releases aren't proposed in
+// pull requests. Grouped by interval, it gives none if a pull request
+// was proposed less than the interval ago; the changes will be found
+// again, along with any others, once the interval is up.
+func (d *Daemon) groupAutomated(changes *update.Automated, now time.Time, logger log.Logger) []*update.Automated {
+	if d.PullRequests == nil {
+		return []*update.Automated{changes}
+	}
+	var key func(update.Change) string
+	switch d.PullRequestGrouping {
+	case PullRequestsPerImage:
+		key = func(c update.Change) string { return c.ImageID.Name.String() }
+	case PullRequestsPerWorkload:
+		key = func(c update.Change) string { return c.ServiceID.String() }
+	case PullRequestsPerNamespace:
+		key = func(c update.Change) string {
+			ns, _, _ := c.ServiceID.Components()
+			return ns
+		}
+	case PullRequestsPerInterval:
+		if next, due := d.proposalDue(now, d.PullRequestInterval); !due {
+			logger.Log("info", "holding automated releases until the next pull request is due", "changes", len(changes.Changes), "due", next)
+			return nil
+		}
+		return []*update.Automated{changes}
+	default:
+		return []*update.Automated{changes}
+	}
+
+	groups := map[string]*update.Automated{}
+	var keys []string
+	for _, c := range changes.Changes {
+		k := key(c)
+		group, ok := groups[k]
+		if !ok {
+			group = &update.Automated{}
+			groups[k] = group
+			keys = append(keys, k)
+		}
+		group.Changes = append(group.Changes, c)
+	}
+	sort.Strings(keys)
+	var grouped []*update.Automated
+	for _, k := range keys {
+		grouped = append(grouped, groups[k])
+	}
+	return grouped
+}
+
+// proposalDue says whether the interval is up since automated
+// releases were last proposed, and if so, records them as proposed
+// now; if not, it gives when it will be.
+func (loop *LoopVars) proposalDue(now time.Time, interval time.Duration) (time.Time, bool) {
+	loop.proposedMu.Lock()
+	defer loop.proposedMu.Unlock()
+	if next := loop.lastProposed.Add(interval); now.Before(next) {
+		return next, false
+	}
+	loop.lastProposed = now
+	return now, true
+}
+
+// releasePreview gives a comment (in Markdown) for the pull request
+// of a release, summarising the images changed in each workload and
+// giving the diff of the manifests, so it can be reviewed without
+// checking out the branch.
+func releasePreview(result update.Result, patch []byte) string {
+	ids := result.AffectedResources()
+	ids.Sort()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "### Release preview\n\nThis changes %d workload(s):\n\n", len(ids))
+	b.WriteString("| Workload | Container | Current | Target |\n|---|---|---|---|\n")
+	for _, id := range ids {
+		for _, c := range result[id].PerContainer {
+			fmt.Fprintf(&b, "| `%s` | %s | `%s` | `%s` |\n", id, c.Container, c.Current, c.Target)
+		}
+	}
+	if len(patch) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n#### Changes to manifests\n\n```diff\n")
+	if len(patch) > maxPreviewDiff {
+		b.Write(patch[:maxPreviewDiff])
+		b.WriteString("\n```\n\nThe diff is too long to show all of; see the changes in the pull request for the rest.\n")
+		return b.String()
+	}
+	b.Write(patch)
+	b.WriteString("```\n")
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/scm"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestPullRequestBranch(t *testing.T) {
+	ref := func(s string) image.Ref {
+		r, err := image.ParseRef(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	result := func(target string) update.Result {
+		return update.Result{
+			flux.MustParseResourceID("default:deployment/helloworld"): update.ControllerResult{
+				Status: update.ReleaseStatusSuccess,
+				PerContainer: []update.ContainerUpdate{
+					{Container: "greeter", Current: ref("quay.io/weaveworks/helloworld:1"), Target: ref(target)},
+				},
+			},
+			flux.MustParseResourceID("default:deployment/locked"): update.ControllerResult{
+				Status: update.ReleaseStatusSkipped,
+			},
+		}
+	}
+
+	branch := pullRequestBranch(result("quay.io/weaveworks/helloworld:2"))
+	if !strings.HasPrefix(branch, pullRequestBranchPrefix) {
+		t.Errorf("expected the branch to start with %s, got %s", pullRequestBranchPrefix, branch)
+	}
+	if again := pullRequestBranch(result("quay.io/weaveworks/helloworld:2")); again != branch {
+		t.Errorf("expected the same branch for the same changes, got %s then %s", branch, again)
+	}
+	if other := pullRequestBranch(result("quay.io/weaveworks/helloworld:3")); other == branch {
+		t.Errorf("expected another branch for other changes, got %s for both", branch)
+	}
+
+	preview := releasePreview(result("quay.io/weaveworks/helloworld:2"), []byte("-image: quay.io/weaveworks/helloworld:1\n+image: quay.io/weaveworks/helloworld:2\n"))
+	for _, expected := range []string{
+		"1 workload(s)",
+		"| `default:deployment/helloworld` | greeter | `quay.io/weaveworks/helloworld:1` | `quay.io/weaveworks/helloworld:2` |",
+		"```diff\n-image: quay.io/weaveworks/helloworld:1\n+image: quay.io/weaveworks/helloworld:2\n```",
+	} {
+		if !strings.Contains(preview, expected) {
+			t.Errorf("expected the preview to have %q in it, got:\n%s", expected, preview)
+		}
+	}
+	if strings.Contains(preview, "locked") {
+		t.Errorf("expected workloads skipped to be left out of the preview, got:\n%s", preview)
+	}
+	long := releasePreview(result("quay.io/weaveworks/helloworld:2"), bytes.Repeat([]byte("+x\n"), maxPreviewDiff))
+	if len(long) > maxPreviewDiff+1024 || !strings.Contains(long, "too long") {
+		t.Errorf("expected a long diff to be cut short, got %d bytes", len(long))
+	}
+}
+
+type stubPullRequests struct{}
+
+func (stubPullRequests) Open(ctx context.Context, pr scm.PullRequest) (scm.Opened, error) {
+	return scm.Opened{}, nil
+}
+
+func (stubPullRequests) Comment(ctx context.Context, pr scm.Opened, body string) error {
+	return nil
+}
+
+func TestGroupAutomated(t *testing.T) {
+	changes := &update.Automated{}
+	for _, c := range []struct{ id, container, image string }{
+		{"default:deployment/helloworld", "greeter", "quay.io/weaveworks/helloworld:2"},
+		{"default:deployment/helloworld", "sidecar", "quay.io/weaveworks/sidecar:2"},
+		{"prod:deployment/helloworld", "greeter", "quay.io/weaveworks/helloworld:2"},
+	} {
+		ref, err := image.ParseRef(c.image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes.Add(flux.MustParseResourceID(c.id), resource.Container{Name: c.container, Image: ref}, ref)
+	}
+	// Each group, as the containers it changes
+	grouped := func(d *Daemon, now time.Time) [][]string {
+		var groups [][]string
+		for _, g := range d.groupAutomated(changes, now, log.NewNopLogger()) {
+			var containers []string
+			for _, c := range g.Changes {
+				containers = append(containers, c.ServiceID.String()+" "+c.Container.Name)
+			}
+			groups = append(groups, containers)
+		}
+		return groups
+	}
+
+	now := time.Now()
+	all := [][]string{{"default:deployment/helloworld greeter", "default:deployment/helloworld sidecar", "prod:deployment/helloworld greeter"}}
+	for grouping, expected := range map[string][][]string{
+		"": all,
+		PullRequestsPerImage: {
+			{"default:deployment/helloworld greeter", "prod:deployment/helloworld greeter"},
+			{"default:deployment/helloworld sidecar"},
+		},
+		PullRequestsPerWorkload: {
+			{"default:deployment/helloworld greeter", "default:deployment/helloworld sidecar"},
+			{"prod:deployment/helloworld greeter"},
+		},
+		PullRequestsPerNamespace: {
+			{"default:deployment/helloworld greeter", "default:deployment/helloworld sidecar"},
+			{"prod:deployment/helloworld greeter"},
+		},
+	} {
+		d := &Daemon{PullRequests: stubPullRequests{}, PullRequestGrouping: grouping, LoopVars: &LoopVars{}}
+		if got := grouped(d, now); !reflect.DeepEqual(got, expected) {
+			t.Errorf("grouping %q: expected %v, got %v", grouping, expected, got)
+		}
+	}
+
+	// Not proposed in pull requests, the changes aren't grouped
+	d := &Daemon{PullRequestGrouping: PullRequestsPerImage, LoopVars: &LoopVars{}}
+	if got := grouped(d, now); !reflect.DeepEqual(got, all) {
+		t.Errorf("expected the changes as they are, got %v", got)
+	}
+
+	// Grouped by interval, the changes are proposed at most once an
+	// interval
+	d = &Daemon{PullRequests: stubPullRequests{}, PullRequestGrouping: PullRequestsPerInterval, PullRequestInterval: time.Hour, LoopVars: &LoopVars{}}
+	if got := grouped(d, now); !reflect.DeepEqual(got, all) {
+		t.Errorf("expected the changes to be proposed first time, got %v", got)
+	}
+	if got := grouped(d, now.Add(30*time.Minute)); got != nil {
+		t.Errorf("expected nothing to be proposed within the interval, got %v", got)
+	}
+	if got := grouped(d, now.Add(time.Hour)); !reflect.DeepEqual(got, all) {
+		t.Errorf("expected the changes to be proposed once the interval is up, got %v", got)
+	}
+}
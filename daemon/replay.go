@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/event"
+)
+
+// The most events replayed to a webhook subscriber at once
+const maxReplayEventsLimit = 500
+
+// ReplayEvents gives the events after the cursor that the webhook
+// subscriber it was sent to would have been sent, oldest first, so
+// that a subscriber that was down can catch up.
+func (d *Daemon) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	res := v25.ReplayEventsResult{Cursor: opts.Cursor}
+	name, after, err := event.ParseCursor(opts.Cursor)
+	if err != nil {
+		return res, err
+	}
+	filter, ok := d.EventSubscriptions[name]
+	if !ok {
+		return res, unknownSubscriberError(name)
+	}
+	store := d.eventStore()
+	if store == nil {
+		return res, nil
+	}
+	limit := opts.Limit
+	if limit <= 0 || limit > maxReplayEventsLimit {
+		limit = maxReplayEventsLimit
+	}
+	events, err := store.AllEvents(event.Page{After: after}, filter)
+	if err != nil {
+		return res, err
+	}
+	if len(events) > limit {
+		events, res.More = events[:limit], true
+	}
+	res.Events = events
+	if len(events) > 0 {
+		res.Cursor = event.MakeCursor(name, events[len(events)-1].ID)
+	}
+	return res, nil
+}
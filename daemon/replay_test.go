@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestReplayEvents(t *testing.T) {
+	store := &event.Buffer{}
+	for _, ns := range []string{"default", "other", "default", "default"} {
+		store.LogEvent(event.Event{Type: event.EventSync, ServiceIDs: []flux.ResourceID{flux.MakeResourceID(ns, "deployment", "hello")}})
+	}
+	d := &Daemon{
+		EventStore:         store,
+		EventSubscriptions: map[string]event.EventFilter{"alerts": {Namespaces: []string{"default"}}},
+	}
+	ctx := context.Background()
+
+	res, err := d.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: event.MakeCursor("alerts", 0), Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 2 || res.Events[0].ID != 1 || res.Events[1].ID != 3 || !res.More {
+		t.Fatalf("expected events 1 and 3, and more to come, got %+v", res)
+	}
+	res, err = d.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: res.Cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].ID != 4 || res.More {
+		t.Fatalf("expected only event 4, got %+v", res)
+	}
+	last := res.Cursor
+	res, err = d.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: last})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 || res.Cursor != last {
+		t.Errorf("expected no events, and the same cursor back, got %+v", res)
+	}
+
+	if _, err := d.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: event.MakeCursor("nobody", 0)}); err == nil {
+		t.Error("expected an error for an unknown subscriber")
+	}
+	if _, err := d.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: "garbage!"}); err == nil {
+		t.Error("expected an error for an invalid cursor")
+	}
+}
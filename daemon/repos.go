@@ -0,0 +1,241 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/source"
+)
+
+// ExtraRepoSchedule schedules the polling of the extra git repos, so
+// that having many of them doesn't hold up fetching any one more than
+// it must. Each repo polls by itself, so at the same time as the
+// others; but with at most HostLimit clones or fetches from any one
+// git host at once, and with the first polls staggered across the
+// interval, so the repos aren't all fetched at the same moment.
+type ExtraRepoSchedule struct {
+	// The most clones and fetches from the one host at once; if
+	// zero, there's no limit
+	HostLimit int
+	// How many repos are to be scheduled, to stagger them across
+	// the interval
+	Count int
+
+	scheduled int
+	throttles map[string]git.Throttle
+}
+
+// Options gives the options for the next extra repo, at the URL and
+// with the poll interval given.
+func (s *ExtraRepoSchedule) Options(url string, interval time.Duration) []git.Option {
+	var opts []git.Option
+	if s.HostLimit > 0 {
+		host, _ := git.SplitRepoURL(strings.ToLower(url))
+		throttle, ok := s.throttles[host]
+		if !ok {
+			if s.throttles == nil {
+				s.throttles = map[string]git.Throttle{}
+			}
+			throttle = git.NewThrottle(s.HostLimit)
+			s.throttles[host] = throttle
+		}
+		opts = append(opts, throttle)
+	}
+	if s.Count > 1 {
+		opts = append(opts, git.FirstPoll(interval*time.Duration(s.scheduled)/time.Duration(s.Count)))
+	}
+	s.scheduled++
+	return opts
+}
+
+// extraRepoSync is what's synced from an extra git repo: the
+// revision, and the resources defined at it.
+type extraRepoSync struct {
+	url       string
+	revision  string
+	resources map[string]resource.Resource
+}
+
+// addExtraRepos adds the resources in each of the extra git repos to
+// those given, so they are synced as one desired state, and gives
+// what was added from each, to be recorded with
+// recordExtraRepoSyncs once the sync has been done.
+//
+// If an extra repo can't be fetched or loaded, or its commits can't
+// be verified, or it defines a resource that's defined elsewhere,
+// the resources it had when it was last synced are used instead, so
+// that one repo going wrong doesn't hold up syncing the rest. Only if
+// it hasn't been synced since the daemon started is that an error,
+// failing the sync, since there's nothing to use in its place.
+func (d *Daemon) addExtraRepos(ctx context.Context, resources map[string]resource.Resource, logger log.Logger) ([]extraRepoSync, error) {
+	var synced []extraRepoSync
+	for _, repo := range d.ExtraRepos {
+		url := repo.Repo.Origin().URL
+		extra, err := d.loadExtraRepo(ctx, repo, logger)
+		if err == nil {
+			err = addExtraResources(resources, extra)
+		}
+		if err != nil {
+			last, ok := d.lastExtraRepoSync(url)
+			if !ok {
+				return nil, err
+			}
+			if addErr := addExtraResources(resources, last); addErr != nil {
+				return nil, err
+			}
+			logger.Log("url", url, "err", err, "using", "last synced", "revision", last.revision)
+			extra = last
+		}
+		synced = append(synced, extra)
+	}
+	return synced, nil
+}
+
+// addExtraResources adds the resources from an extra git repo to
+// those given, if none of them is defined there already. A resource
+// defined in more than one repo is an error, since there'd be no
+// telling which definition would be applied.
+func addExtraResources(resources map[string]resource.Resource, extra extraRepoSync) error {
+	for id, res := range extra.resources {
+		if _, ok := resources[id]; ok {
+			return fmt.Errorf("%s in git repo %s (%s) is defined in another git repo too", id, extra.url, res.Source())
+		}
+	}
+	for id, res := range extra.resources {
+		resources[id] = res
+	}
+	return nil
+}
+
+// loadExtraRepo fetches an extra git repo, and loads the resources
+// defined in it.
+func (d *Daemon) loadExtraRepo(ctx context.Context, repo source.GitExport, logger log.Logger) (extraRepoSync, error) {
+	url := repo.Repo.Origin().URL
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	snapshot, err := repo.Fetch(ctx)
+	if err != nil {
+		return extraRepoSync{}, errors.Wrapf(err, "fetching git repo %s", url)
+	}
+	defer snapshot.Clean()
+
+	if d.VerifySignatures {
+		if err := d.verifyCommits(ctx, repo.Repo, d.extraRepoRevision(url), snapshot.Revision, logger); err != nil {
+			return extraRepoSync{}, err
+		}
+	}
+
+	extra, err := d.ManifestCache.Load(d.Manifests, snapshot.Revision, snapshot.Dir(), snapshot.ManifestDirs())
+	if err != nil {
+		return extraRepoSync{}, errors.Wrapf(err, "loading resources from git repo %s", url)
+	}
+	return extraRepoSync{url: url, revision: snapshot.Revision, resources: extra}, nil
+}
+
+// NotifyPush asks for the git repo that was pushed to, given as the
+// URLs it's known by and the branches and tags pushed, to be fetched
+// from straight away (and synced, after), if it's the git repo or one
+// of the extra repos, and the branch synced was pushed -- or, when
+// syncing tags, a tag that would be synced. It says whether any was.
+func (d *Daemon) NotifyPush(urls, branches, tags []string) bool {
+	var notified bool
+	pushed := branches
+	want := d.GitConfig.Branch
+	if d.SyncTags != nil {
+		// Any tag that matches will do; the sync tag is flux's own,
+		// so a push of it is only flux catching up
+		pushed, want = nil, ""
+		for _, tag := range tags {
+			if tag != d.GitConfig.SyncTag && d.SyncTags.Matches(tag) {
+				pushed, want = []string{tag}, tag
+				break
+			}
+		}
+	}
+	if d.Repo != nil && want != "" && pushedTo(d.Repo.Origin().URL, want, urls, pushed) {
+		d.Repo.Notify()
+		notified = true
+	}
+	for _, repo := range d.ExtraRepos {
+		if pushedTo(repo.Repo.Origin().URL, repo.Branch, urls, branches) {
+			repo.Repo.Notify()
+			notified = true
+		}
+	}
+	return notified
+}
+
+func pushedTo(url, branch string, urls, branches []string) bool {
+	var sameRepo bool
+	for _, u := range urls {
+		if git.SameRepo(url, u) {
+			sameRepo = true
+			break
+		}
+	}
+	if !sameRepo {
+		return false
+	}
+	for _, b := range branches {
+		if b == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// watchExtraRepos asks for a sync whenever one of the extra git repos
+// has been refreshed, until stopped.
+func (d *Daemon) watchExtraRepos(stop <-chan struct{}) {
+	for _, repo := range d.ExtraRepos {
+		go func(refreshed <-chan struct{}) {
+			for {
+				select {
+				case <-stop:
+					return
+				case <-refreshed:
+					d.AskForSync()
+				}
+			}
+		}(repo.Repo.C)
+	}
+}
+
+// extraRepoRevision gives the revision of the extra git repo last
+// synced, if it's been synced since the daemon started.
+func (loop *LoopVars) extraRepoRevision(url string) string {
+	last, _ := loop.lastExtraRepoSync(url)
+	return last.revision
+}
+
+// lastExtraRepoSync gives what was last synced from the extra git
+// repo, and whether it's been synced since the daemon started.
+func (loop *LoopVars) lastExtraRepoSync(url string) (extraRepoSync, bool) {
+	loop.extraReposMu.Lock()
+	defer loop.extraReposMu.Unlock()
+	last, ok := loop.extraRepoSyncs[url]
+	return last, ok
+}
+
+// recordExtraRepoSyncs remembers what was synced from each of the
+// extra git repos, once the sync has been done.
+func (loop *LoopVars) recordExtraRepoSyncs(synced []extraRepoSync, logger log.Logger) {
+	loop.extraReposMu.Lock()
+	defer loop.extraReposMu.Unlock()
+	if loop.extraRepoSyncs == nil {
+		loop.extraRepoSyncs = map[string]extraRepoSync{}
+	}
+	for _, s := range synced {
+		if old := loop.extraRepoSyncs[s.url].revision; old != s.revision {
+			logger.Log("url", s.url, "revision", s.revision, "old", old)
+		}
+		loop.extraRepoSyncs[s.url] = s
+	}
+}
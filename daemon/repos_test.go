@@ -0,0 +1,200 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/cluster"
+	kresource "github.com/weaveworks/flux/cluster/kubernetes/resource"
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/git/gittest"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/source"
+)
+
+func extraRepo(t *testing.T) (source.GitExport, func()) {
+	repo, cleanup := gittest.Repo(t)
+	if err := repo.Ready(context.Background()); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return source.GitExport{Repo: repo, Branch: "master"}, cleanup
+}
+
+func TestDoSync_ExtraRepos(t *testing.T) {
+	k8s = &cluster.Mock{}
+	k8s.LoadManifestsFunc = kresource.Load
+	k8s.ParseManifestsFunc = func(allDefs []byte) (map[string]resource.Resource, error) {
+		return kresource.ParseMultidoc(allDefs, "exported")
+	}
+	k8s.ExportFunc = func() ([]byte, error) { return nil, nil }
+	var synced int
+	var syncErr error
+	k8s.SyncFunc = func(def cluster.SyncDef) error {
+		synced = len(def.Actions)
+		return syncErr
+	}
+	events = &mockEventWriter{}
+	defer func() { k8s, events = nil, nil }()
+
+	extra, cleanup := extraRepo(t)
+	defer cleanup()
+	// The manifest store has nothing in it, so everything synced is
+	// from the extra repo
+	d := &Daemon{
+		Cluster:       k8s,
+		Manifests:     k8s,
+		ManifestStore: &fixedStore{t: t, revision: "sha256:abc123"},
+		ExtraRepos:    []source.GitExport{extra},
+		EventWriter:   events,
+		Logger:        log.NewNopLogger(),
+		LoopVars:      &LoopVars{},
+	}
+	url := extra.Repo.Origin().URL
+
+	// The revision of an extra repo is only recorded once it's
+	// been synced
+	syncErr = errors.New("cluster unavailable")
+	if err := d.doSync(log.NewNopLogger()); err == nil {
+		t.Fatal("expected the sync to fail")
+	}
+	if rev := d.extraRepoRevision(url); rev != "" {
+		t.Errorf("expected no revision recorded for a failed sync, got %q", rev)
+	}
+	syncErr = nil
+
+	if err := d.doSync(log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if synced != len(testfiles.ResourceMap) {
+		t.Errorf("expected the %d resources in the extra repo to be synced, got %d", len(testfiles.ResourceMap), synced)
+	}
+	if len(events.events) != 1 || len(events.events[0].ServiceIDs) != len(testfiles.ResourceMap) {
+		t.Errorf("expected a sync event with the resources in the extra repo, got %+v", events.events)
+	}
+	rev, err := extra.Repo.Revision(context.Background(), "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded := d.extraRepoRevision(url); recorded != rev {
+		t.Errorf("expected the revision of the extra repo synced to be recorded, got %q", recorded)
+	}
+
+	// An extra repo that can't be fetched is synced as it was last
+	// time, rather than holding up the sync
+	d.ExtraRepos[0].Branch = "nonesuch"
+	synced = 0
+	if err := d.doSync(log.NewNopLogger()); err != nil {
+		t.Fatalf("expected the last resources synced from the extra repo to be used, got %v", err)
+	}
+	if synced != len(testfiles.ResourceMap) || d.extraRepoRevision(url) != rev {
+		t.Errorf("expected the extra repo to be synced at %s, got %d resources at %s", rev, synced, d.extraRepoRevision(url))
+	}
+	d.ExtraRepos[0].Branch = "master"
+
+	// Defining the same resources in another repo is an error,
+	// rather than one being picked
+	another, cleanupAnother := extraRepo(t)
+	defer cleanupAnother()
+	d.ExtraRepos = append(d.ExtraRepos, another)
+	synced = 0
+	if err := d.doSync(log.NewNopLogger()); err == nil || !strings.Contains(err.Error(), "defined in another git repo too") {
+		t.Errorf("expected an error about resources defined twice, got %v", err)
+	}
+	if synced != 0 {
+		t.Error("expected nothing to be synced")
+	}
+}
+
+func TestWatchExtraRepos(t *testing.T) {
+	repo := git.NewRepo(git.Remote{URL: "file:///nowhere"})
+	d := &Daemon{ExtraRepos: []source.GitExport{{Repo: repo}}, LoopVars: &LoopVars{}}
+	d.ensureInit()
+	stop := make(chan struct{})
+	defer close(stop)
+	d.watchExtraRepos(stop)
+
+	repo.C <- struct{}{}
+	select {
+	case <-d.syncSoon:
+	case <-time.After(5 * time.Second):
+		t.Error("expected a sync to be asked for when the extra repo was refreshed")
+	}
+}
+
+func TestNotifyPush(t *testing.T) {
+	d := &Daemon{
+		Repo:       git.NewRepo(git.Remote{URL: "git@github.com:example/config"}),
+		GitConfig:  git.Config{Branch: "master"},
+		ExtraRepos: []source.GitExport{{Repo: git.NewRepo(git.Remote{URL: "https://gitlab.com/example/extra.git"}), Branch: "prod"}},
+	}
+	for _, c := range []struct {
+		urls, branches []string
+		notified       bool
+	}{
+		{[]string{"https://github.com/example/config.git", "git@github.com:example/config.git"}, []string{"master"}, true},
+		{[]string{"https://github.com/example/config.git"}, []string{"dev"}, false},
+		{[]string{"git@gitlab.com:example/extra.git"}, []string{"dev", "prod"}, true},
+		{[]string{"git@gitlab.com:example/extra.git"}, []string{"master"}, false},
+		{[]string{"https://github.com/example/other"}, []string{"master"}, false},
+	} {
+		if notified := d.NotifyPush(c.urls, c.branches, nil); notified != c.notified {
+			t.Errorf("expected a push to %v of %v to notify %v, got %v", c.urls, c.branches, c.notified, notified)
+		}
+	}
+
+	// When syncing tags, it's a tag that matches that counts, and
+	// not the branch
+	d.SyncTags = policy.NewPattern("glob:release-*")
+	d.GitConfig.SyncTag = "release-synced"
+	for _, c := range []struct {
+		urls, branches, tags []string
+		notified             bool
+	}{
+		{[]string{"git@github.com:example/config.git"}, nil, []string{"release-1"}, true},
+		{[]string{"git@github.com:example/config.git"}, []string{"master"}, nil, false},
+		{[]string{"git@github.com:example/config.git"}, nil, []string{"v1"}, false},
+		{[]string{"git@github.com:example/config.git"}, nil, []string{"release-synced"}, false},
+		{[]string{"https://github.com/example/other"}, nil, []string{"release-1"}, false},
+		{[]string{"git@gitlab.com:example/extra.git"}, []string{"prod"}, nil, true},
+	} {
+		if notified := d.NotifyPush(c.urls, c.branches, c.tags); notified != c.notified {
+			t.Errorf("expected a push to %v of %v and %v to notify %v, got %v", c.urls, c.branches, c.tags, c.notified, notified)
+		}
+	}
+}
+
+func TestExtraRepoSchedule(t *testing.T) {
+	s := &ExtraRepoSchedule{HostLimit: 2, Count: 3}
+	var throttles []git.Throttle
+	var firstPolls []time.Duration
+	for _, url := range []string{"git@github.com:example/one", "https://GitHub.com/example/two.git", "git@gitlab.com:example/three"} {
+		for _, opt := range s.Options(url, 3*time.Minute) {
+			switch opt := opt.(type) {
+			case git.Throttle:
+				throttles = append(throttles, opt)
+			case git.FirstPoll:
+				firstPolls = append(firstPolls, time.Duration(opt))
+			}
+		}
+	}
+	if len(throttles) != 3 || throttles[0] != throttles[1] || throttles[0] == throttles[2] || cap(throttles[0]) != 2 {
+		t.Errorf("expected the repos on the same host to share a throttle of 2, and the other its own, got %v", throttles)
+	}
+	if expected := []time.Duration{0, time.Minute, 2 * time.Minute}; !reflect.DeepEqual(firstPolls, expected) {
+		t.Errorf("expected the first polls to be staggered as %v, got %v", expected, firstPolls)
+	}
+
+	// With no limit, and only the one repo, there's nothing to do
+	if opts := (&ExtraRepoSchedule{Count: 1}).Options("git@github.com:example/one", time.Minute); len(opts) != 0 {
+		t.Errorf("expected no options, got %v", opts)
+	}
+}
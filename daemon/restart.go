@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/update"
+)
+
+// restart gives a job that rolls out new pods for each of the
+// workloads in the spec (or, for a plan, reports which it would), and
+// records the restart as an event. Unlike releases, nothing is
+// committed; the cluster is told to restart the workloads directly.
+func (d *Daemon) restart(spec update.Spec, s update.RestartSpec) jobFunc {
+	return func(ctx context.Context, jobID job.ID, logger log.Logger) (job.Result, error) {
+		result := job.Result{
+			Spec:   &spec,
+			Result: update.Result{},
+		}
+		started := time.Now().UTC()
+		for _, id := range s.Workloads {
+			if controllers, err := d.Cluster.SomeControllers([]flux.ResourceID{id}); err != nil || len(controllers) == 0 {
+				result.Result[id] = update.ControllerResult{
+					Status: update.ReleaseStatusSkipped,
+					Error:  update.NotInCluster,
+				}
+				continue
+			}
+			if s.Kind == update.ReleaseKindPlan {
+				result.Result[id] = update.ControllerResult{Status: update.ReleaseStatusSuccess}
+				continue
+			}
+			if err := d.Cluster.Restart(id, started); err != nil {
+				result.Result[id] = update.ControllerResult{
+					Status: update.ReleaseStatusFailed,
+					Error:  err.Error(),
+				}
+				continue
+			}
+			result.Result[id] = update.ControllerResult{Status: update.ReleaseStatusSuccess}
+		}
+		if s.Kind == update.ReleaseKindPlan {
+			return result, nil
+		}
+
+		restarted := result.Result.AffectedResources()
+		if len(restarted) == 0 && result.Result.Error() == "" {
+			return result, nil
+		}
+		logLevel := event.LogLevelInfo
+		if result.Result.Error() != "" {
+			logLevel = event.LogLevelError
+		}
+		return result, d.LogEvent(event.Event{
+			ServiceIDs:    restarted,
+			Type:          event.EventRestart,
+			StartedAt:     started,
+			EndedAt:       time.Now().UTC(),
+			LogLevel:      logLevel,
+			CorrelationID: string(jobID),
+			Metadata: &event.RestartEventMetadata{
+				Result: result.Result,
+				Reason: s.Reason,
+				Cause:  spec.Cause,
+			},
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestRestart(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	broken := flux.MustParseResourceID("default:deployment/broken")
+	missing := flux.MustParseResourceID("default:deployment/missing")
+
+	var restarted []flux.ResourceID
+	k8s := &cluster.Mock{
+		SomeServicesFunc: func(ids []flux.ResourceID) ([]cluster.Controller, error) {
+			var res []cluster.Controller
+			for _, id := range ids {
+				if id != missing {
+					res = append(res, cluster.Controller{ID: id})
+				}
+			}
+			return res, nil
+		},
+		RestartFunc: func(id flux.ResourceID, _ time.Time) error {
+			if id == broken {
+				return errors.New("no such luck")
+			}
+			restarted = append(restarted, id)
+			return nil
+		},
+	}
+	store := &event.Buffer{}
+	d := &Daemon{Cluster: k8s, EventStore: store, Logger: log.NewNopLogger()}
+	ctx := context.Background()
+
+	spec := update.Spec{Type: update.Restart, Cause: update.Cause{User: "jane"}}
+	plan := update.RestartSpec{Workloads: []flux.ResourceID{hello, missing}, Kind: update.ReleaseKindPlan}
+	result, err := d.restart(spec, plan)(ctx, "plan", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted) != 0 || result.Result[hello].Status != update.ReleaseStatusSuccess || result.Result[missing].Status != update.ReleaseStatusSkipped {
+		t.Errorf("expected a plan to restart hello, skipping missing, and nothing restarted; got %+v", result.Result)
+	}
+	if events, _ := store.AllEvents(event.Page{}, event.EventFilter{}); len(events) != 0 {
+		t.Errorf("expected no events for a plan, got %+v", events)
+	}
+
+	execute := update.RestartSpec{Workloads: []flux.ResourceID{hello, broken, missing}, Reason: "new config", Kind: update.ReleaseKindExecute}
+	result, err = d.restart(spec, execute)(ctx, "execute", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted) != 1 || restarted[0] != hello {
+		t.Errorf("expected only hello to be restarted, got %v", restarted)
+	}
+	if result.Result[broken].Status != update.ReleaseStatusFailed {
+		t.Errorf("expected restarting broken to fail, got %+v", result.Result[broken])
+	}
+	events, _ := store.AllEvents(event.Page{}, event.EventFilter{})
+	if len(events) != 1 || events[0].Type != event.EventRestart || events[0].LogLevel != event.LogLevelError {
+		t.Fatalf("expected a restart event, logged as an error, got %+v", events)
+	}
+	if len(events[0].ServiceIDs) != 1 || events[0].ServiceIDs[0] != hello {
+		t.Errorf("expected the event to concern only hello, got %v", events[0].ServiceIDs)
+	}
+	metadata := events[0].Metadata.(*event.RestartEventMetadata)
+	if metadata.Reason != "new config" || metadata.Cause.User != "jane" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/event"
+)
+
+// How often the event store is pruned, when there's a retention
+// policy.
+const eventPruneInterval = 10 * time.Minute
+
+// PruneEvents removes the events that the policy given, or else the
+// daemon's own retention policy, doesn't keep.
+func (d *Daemon) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	policy := opts.Policy
+	if policy.IsZero() {
+		policy = d.EventRetention
+	}
+	if policy.IsZero() {
+		return v24.PruneEventsResult{}, noRetentionPolicyError()
+	}
+	pruner, ok := d.eventStore().(event.Pruner)
+	if !ok {
+		return v24.PruneEventsResult{}, eventStoreNotPrunableError()
+	}
+	pruned, err := pruner.PruneEvents(policy, time.Now().UTC())
+	if err != nil {
+		return v24.PruneEventsResult{}, err
+	}
+	return v24.PruneEventsResult{Policy: policy, Pruned: pruned}, nil
+}
+
+// PruneLoop prunes the event store by the daemon's retention policy,
+// every so often, until told to stop.
+func (d *Daemon) PruneLoop(stop chan struct{}, wg *sync.WaitGroup, logger log.Logger) {
+	defer wg.Done()
+	if d.EventRetention.IsZero() {
+		return
+	}
+	ticker := time.NewTicker(eventPruneInterval)
+	defer ticker.Stop()
+	for {
+		res, err := d.PruneEvents(context.Background(), v24.PruneEventsOptions{})
+		if err != nil {
+			// Pruning won't start working later, if the store can't do it
+			logger.Log("err", err)
+			return
+		}
+		if res.Pruned > 0 {
+			logger.Log("pruned", res.Pruned)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/event"
+)
+
+// Hides the Buffer's PruneEvents, like a store that can't prune
+type unprunableStore struct {
+	event.EventStore
+}
+
+func TestPruneEvents(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	store := &event.Buffer{}
+	now := time.Now().UTC()
+	for i := 5; i > 0; i-- {
+		store.LogEvent(event.Event{ServiceIDs: []flux.ResourceID{hello}, StartedAt: now.Add(-time.Duration(i) * 24 * time.Hour)})
+	}
+	ctx := context.Background()
+
+	d := &Daemon{EventStore: store}
+	if _, err := d.PruneEvents(ctx, v24.PruneEventsOptions{}); err == nil {
+		t.Error("expected an error when there's no retention policy")
+	}
+
+	d.EventRetention = event.RetentionPolicy{MaxAge: 72 * time.Hour}
+	res, err := d.PruneEvents(ctx, v24.PruneEventsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Pruned != 3 || res.Policy != d.EventRetention {
+		t.Errorf("expected three events pruned by the daemon's policy, got %+v", res)
+	}
+
+	res, err = d.PruneEvents(ctx, v24.PruneEventsOptions{Policy: event.RetentionPolicy{MaxPerService: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Pruned != 1 || res.Policy.MaxPerService != 1 {
+		t.Errorf("expected one event pruned by the policy given, got %+v", res)
+	}
+
+	d.EventStore = unprunableStore{store}
+	if _, err := d.PruneEvents(ctx, v24.PruneEventsOptions{}); err == nil {
+		t.Error("expected an error when the store can't prune events")
+	}
+}
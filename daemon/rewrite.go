@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// checkRewrite looks at whether the history of the git repo has been
+// rewritten (e.g., by a force-push) since the last sync; that is,
+// whether the revision `from`, last synced, is no longer in the
+// history of `to`. If it has, it records an event saying which
+// commits were dropped, once for each rewrite. The sync goes ahead
+// either way, since what's in the repo is what's meant to be synced.
+func (d *Daemon) checkRewrite(ctx context.Context, from, to string, logger log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	defer cancel()
+	ok, err := d.Repo.IsAncestor(ctx, from, to)
+	switch {
+	case isUnknownRevision(err):
+		// The revision last synced has gone altogether, so there's
+		// no telling which commits were dropped
+	case err != nil:
+		return err
+	case ok:
+		return nil
+	}
+	if !d.reportRewrite(from, to) {
+		return nil
+	}
+
+	var dropped []event.Commit
+	if err == nil {
+		commits, err := d.Repo.CommitsBetween(ctx, to, from, d.GitConfig.Paths...)
+		if err != nil {
+			return err
+		}
+		for _, c := range commits {
+			dropped = append(dropped, event.Commit{Revision: c.Revision, Message: c.Message, Time: c.Time})
+		}
+	}
+	url := d.Repo.Origin().URL
+	logger.Log("warning", "git history rewritten since last sync", "url", url, "from", from, "to", to, "dropped", len(dropped))
+	now := time.Now().UTC()
+	if err := d.LogEvent(event.Event{
+		Type:      event.EventSyncRewrite,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelWarn,
+		Metadata: &event.SyncRewriteEventMetadata{
+			URL:     url,
+			Branch:  d.GitConfig.Branch,
+			From:    from,
+			To:      to,
+			Dropped: dropped,
+		},
+	}); err != nil {
+		logger.Log("err", err)
+	}
+	return nil
+}
+
+// reportRewrite says whether the rewrite is yet to be reported, and
+// remembers that it has been.
+func (loop *LoopVars) reportRewrite(from, to string) bool {
+	loop.rewriteMu.Lock()
+	defer loop.rewriteMu.Unlock()
+	if loop.rewriteReported == from+".."+to {
+		return false
+	}
+	loop.rewriteReported = from + ".." + to
+	return true
+}
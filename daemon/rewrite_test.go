@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+)
+
+func TestCheckRewrite(t *testing.T) {
+	extra, cleanup := extraRepo(t)
+	defer cleanup()
+	events := &mockEventWriter{}
+	d := &Daemon{
+		Repo:        extra.Repo,
+		GitConfig:   git.Config{Branch: "master"},
+		EventWriter: events,
+		Logger:      log.NewNopLogger(),
+		LoopVars:    &LoopVars{},
+	}
+
+	ctx := context.Background()
+	parent, err := extra.Repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add a commit to the (bare) upstream
+	upstream := strings.TrimPrefix(extra.Repo.Origin().URL, "file://")
+	out, err := exec.Command("git", "-C", upstream, "-c", "user.name=example", "-c", "user.email=example@example.com",
+		"commit-tree", "-p", "master", "-m", "Another", "master^{tree}").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "-C", upstream, "update-ref", "refs/heads/master", strings.TrimSpace(string(out))).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := extra.Repo.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	head, err := extra.Repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving on from the parent isn't a rewrite
+	if err := d.checkRewrite(ctx, parent, head, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 0 {
+		t.Fatalf("expected no events for a fast-forward, got %+v", events.events)
+	}
+
+	// Going back to the parent is, as though the head were
+	// force-pushed away; and it's reported once
+	for i := 0; i < 2; i++ {
+		if err := d.checkRewrite(ctx, head, parent, log.NewNopLogger()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events.events) != 1 {
+		t.Fatalf("expected one event for the rewrite, got %+v", events.events)
+	}
+	ev := events.events[0]
+	metadata, ok := ev.Metadata.(*event.SyncRewriteEventMetadata)
+	if ev.Type != event.EventSyncRewrite || !ok || metadata.From != head || metadata.To != parent || metadata.Branch != "master" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if len(metadata.Dropped) != 1 || metadata.Dropped[0].Revision != head {
+		t.Errorf("expected the head to be dropped, got %+v", metadata.Dropped)
+	}
+
+	// A revision that's gone altogether is a rewrite too
+	if err := d.checkRewrite(ctx, "1234567890123456789012345678901234567890", head, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 2 || len(events.events[1].Metadata.(*event.SyncRewriteEventMetadata).Dropped) != 0 {
+		t.Errorf("expected a rewrite with nothing known to be dropped, got %+v", events.events)
+	}
+}
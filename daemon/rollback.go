@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+// rollbackChanges sets the containers changed by a release back to
+// the images they had before it. It's a release of containers, but
+// committed (and recorded in the commit note) as a rollback.
+type rollbackChanges struct {
+	update.ContainerSpecs
+	spec update.RollbackSpec
+}
+
+func (c rollbackChanges) CommitMessage(result update.Result) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Roll back release #%d", c.spec.ReleaseID)
+	if c.spec.ReleaseRevision != "" {
+		fmt.Fprintf(buf, " (%s)", c.spec.ReleaseRevision)
+	}
+	fmt.Fprintln(buf)
+	if c.spec.Reason != "" {
+		fmt.Fprintf(buf, "\n%s\n", c.spec.Reason)
+	}
+	for _, res := range result.AffectedResources() {
+		fmt.Fprintf(buf, "\n%s", res)
+		for _, upd := range result[res].PerContainer {
+			fmt.Fprintf(buf, "\n- %s", upd.Target)
+		}
+		fmt.Fprintln(buf)
+	}
+	if err := result.Error(); err != "" {
+		fmt.Fprintf(buf, "\n%s", err)
+	}
+	return buf.String()
+}
+
+// rollback works out how to roll back the release recorded in the
+// event given: each container the release changed is set back to the
+// image it had before, as long as it still has the image released.
+// The spec is returned with the release's revision filled in.
+func (d *Daemon) rollback(s update.RollbackSpec) (rollbackChanges, error) {
+	id := event.EventID(s.ReleaseID)
+	store := d.eventStore()
+	if store == nil {
+		return rollbackChanges{}, unknownEventError(id)
+	}
+	ev, err := store.GetEvent(id)
+	if err == event.ErrNoSuchEvent {
+		return rollbackChanges{}, unknownEventError(id)
+	}
+	if err != nil {
+		return rollbackChanges{}, err
+	}
+
+	var release event.ReleaseEventCommon
+	switch metadata := ev.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		release = metadata.ReleaseEventCommon
+	case *event.AutoReleaseEventMetadata:
+		release = metadata.ReleaseEventCommon
+	case *event.RollbackEventMetadata:
+		release = metadata.ReleaseEventCommon
+	default:
+		return rollbackChanges{}, notAReleaseError(ev)
+	}
+
+	specs := map[flux.ResourceID][]update.ContainerUpdate{}
+	for workload, res := range release.Result {
+		if res.Status != update.ReleaseStatusSuccess {
+			continue
+		}
+		for _, c := range res.PerContainer {
+			if c.Current == c.Target {
+				continue
+			}
+			specs[workload] = append(specs[workload], update.ContainerUpdate{
+				Container: c.Container,
+				Current:   c.Target,
+				Target:    c.Current,
+			})
+		}
+	}
+	if len(specs) == 0 {
+		return rollbackChanges{}, notAReleaseError(ev)
+	}
+
+	s.ReleaseRevision = release.Revision
+	return rollbackChanges{
+		ContainerSpecs: update.ContainerSpecs{
+			Kind:           s.Kind,
+			ContainerSpecs: specs,
+			// Workloads that have been released again since are
+			// left as they are
+			SkipMismatches: true,
+			Force:          s.Force,
+		},
+		spec: s,
+	}, nil
+}
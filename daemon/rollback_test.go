@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestRollback(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	locked := flux.MustParseResourceID("default:deployment/locked")
+	v1, v2 := image.Ref{Name: image.Name{Image: "example/hello"}, Tag: "v1"}, image.Ref{Name: image.Name{Image: "example/hello"}, Tag: "v2"}
+
+	store := &event.Buffer{}
+	store.LogEvent(event.Event{Type: event.EventSync, Metadata: &event.SyncEventMetadata{}})
+	store.LogEvent(event.Event{
+		Type:       event.EventRelease,
+		ServiceIDs: []flux.ResourceID{hello},
+		Metadata: &event.ReleaseEventMetadata{
+			ReleaseEventCommon: event.ReleaseEventCommon{
+				Revision: "abc1234def",
+				Result: update.Result{
+					hello: update.ControllerResult{
+						Status:       update.ReleaseStatusSuccess,
+						PerContainer: []update.ContainerUpdate{{Container: "hello", Current: v1, Target: v2}},
+					},
+					locked: update.ControllerResult{Status: update.ReleaseStatusSkipped},
+				},
+			},
+		},
+	})
+	d := &Daemon{EventStore: store}
+
+	changes, err := d.rollback(update.RollbackSpec{ReleaseID: 2, Reason: "errors", Kind: update.ReleaseKindExecute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[flux.ResourceID][]update.ContainerUpdate{
+		hello: {{Container: "hello", Current: v2, Target: v1}},
+	}
+	if !reflect.DeepEqual(changes.ContainerSpecs.ContainerSpecs, expected) {
+		t.Errorf("expected %#v, got %#v", expected, changes.ContainerSpecs.ContainerSpecs)
+	}
+	if changes.spec.ReleaseRevision != "abc1234def" {
+		t.Errorf("expected release revision to be filled in, got %q", changes.spec.ReleaseRevision)
+	}
+	msg := changes.CommitMessage(update.Result{hello: update.ControllerResult{
+		Status:       update.ReleaseStatusSuccess,
+		PerContainer: expected[hello],
+	}})
+	if !strings.HasPrefix(msg, "Roll back release #2 (abc1234def)\n\nerrors\n") {
+		t.Errorf("unexpected commit message %q", msg)
+	}
+
+	if _, err := d.rollback(update.RollbackSpec{ReleaseID: 1}); err == nil {
+		t.Error("expected error rolling back an event that's not a release")
+	}
+	if _, err := d.rollback(update.RollbackSpec{ReleaseID: 3}); err == nil {
+		t.Error("expected error rolling back an unknown event")
+	}
+}
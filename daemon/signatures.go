@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+)
+
+// verifyCommits checks that each of the commits after `from`, up to
+// `to`, is signed by a trusted key; or if `from` is empty, that `to`
+// is. If one isn't, it records an event (once for each such commit)
+// and gives an error, so the sync doesn't go ahead.
+func (d *Daemon) verifyCommits(ctx context.Context, repo *git.Repo, from, to string, logger log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	sigs, err := repo.CommitSignatures(ctx, from, to)
+	cancel()
+	if err != nil {
+		return err
+	}
+	for _, sig := range sigs {
+		reason := sig.Verify(d.SignatureKeys)
+		if reason == nil {
+			continue
+		}
+		url := repo.Origin().URL
+		if d.reportUnverified(sig.Revision) {
+			now := time.Now().UTC()
+			if err := d.LogEvent(event.Event{
+				Type:      event.EventSyncUnverified,
+				StartedAt: now,
+				EndedAt:   now,
+				LogLevel:  event.LogLevelError,
+				Metadata: &event.SyncUnverifiedEventMetadata{
+					URL:         url,
+					Revision:    sig.Revision,
+					Reason:      reason.Error(),
+					Fingerprint: sig.Fingerprint,
+				},
+			}); err != nil {
+				logger.Log("err", err)
+			}
+		}
+		return fmt.Errorf("not syncing %s: commit %s is not verified: %s", url, sig.Revision, reason)
+	}
+	return nil
+}
+
+// reportUnverified says whether the commit is yet to be reported as
+// unverified, and remembers that it has been.
+func (loop *LoopVars) reportUnverified(rev string) bool {
+	loop.unverifiedMu.Lock()
+	defer loop.unverifiedMu.Unlock()
+	if loop.unverifiedRevision == rev {
+		return false
+	}
+	loop.unverifiedRevision = rev
+	return true
+}
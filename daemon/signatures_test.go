@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+func TestVerifyCommits(t *testing.T) {
+	extra, cleanup := extraRepo(t)
+	defer cleanup()
+	events := &mockEventWriter{}
+	d := &Daemon{
+		VerifySignatures: true,
+		EventWriter:      events,
+		Logger:           log.NewNopLogger(),
+		LoopVars:         &LoopVars{},
+	}
+
+	ctx := context.Background()
+	head, err := extra.Repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test repo's commits aren't signed, so can't be synced
+	for i := 0; i < 2; i++ {
+		err := d.verifyCommits(ctx, extra.Repo, "", "master", log.NewNopLogger())
+		if err == nil || !strings.Contains(err.Error(), "not signed") {
+			t.Errorf("expected the unsigned commit to be refused, got %v", err)
+		}
+	}
+	// ... and that's recorded, once
+	if len(events.events) != 1 {
+		t.Fatalf("expected one event for the unsigned commit, got %+v", events.events)
+	}
+	ev := events.events[0]
+	metadata, ok := ev.Metadata.(*event.SyncUnverifiedEventMetadata)
+	if ev.Type != event.EventSyncUnverified || ev.LogLevel != event.LogLevelError || !ok || metadata.Revision != head || metadata.Reason != "not signed" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	// With nothing new to sync, there's nothing to verify
+	if err := d.verifyCommits(ctx, extra.Repo, head, head, log.NewNopLogger()); err != nil {
+		t.Errorf("expected no commits to verify, got %v", err)
+	}
+}
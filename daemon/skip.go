@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// skipSync gives the revision to sync in place of newRev: newRev
+// itself, unless its commit has one of the skip markers in its
+// message. If it has, those commits of the branch that are marked,
+// from newRev back to the first that isn't, are skipped, and the
+// revision to sync is that of the first that isn't; or oldRev, if
+// they're all marked, so that what was synced before is still kept
+// in place. It's empty if there's nothing to sync, i.e., nothing has
+// been synced before and every commit is marked. The commits skipped
+// are recorded in a sync event, each once, and are synced along with
+// the first commit after them that isn't marked. Tags are synced
+// whatever their commits say, since tagging a commit is asking for it
+// to be synced.
+func (d *Daemon) skipSync(ctx context.Context, oldRev, newRev string, logger log.Logger) (string, error) {
+	if len(d.SkipSyncMarkers) == 0 || d.SyncTags != nil || oldRev == newRev {
+		return newRev, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, gitOpTimeout)
+	commits, err := d.Repo.CommitMessages(ctx, oldRev, newRev)
+	cancel()
+	switch {
+	case isUnknownRevision(err):
+		// The revision last synced has gone (it's been rewritten
+		// away), so there's no telling what's new; sync as usual
+		return newRev, nil
+	case err != nil:
+		return "", err
+	}
+
+	syncRev := oldRev
+	var skipped []event.Commit
+	for _, c := range commits {
+		if !hasSkipMarker(c.Message, d.SkipSyncMarkers) {
+			syncRev = c.Revision
+			break
+		}
+		skipped = append(skipped, event.Commit{Revision: c.Revision, Message: firstLine(c.Message), Time: c.Time})
+	}
+	if len(skipped) == 0 {
+		return newRev, nil
+	}
+
+	// Leave out those recorded when an earlier one was skipped
+	last := d.reportSkipped(newRev)
+	for i, c := range skipped {
+		if c.Revision == last {
+			skipped = skipped[:i]
+			break
+		}
+	}
+	if len(skipped) == 0 {
+		return syncRev, nil
+	}
+
+	logger.Log("sync", "skipped", "revision", newRev, "commits", len(skipped), "syncing", syncRev)
+	now := time.Now().UTC()
+	if err := d.LogEvent(event.Event{
+		Type:      event.EventSync,
+		StartedAt: now,
+		EndedAt:   now,
+		LogLevel:  event.LogLevelInfo,
+		Metadata: &event.SyncEventMetadata{
+			Commits:  skipped,
+			Includes: map[string]bool{event.NoneOfTheAbove: true},
+			Skipped:  true,
+		},
+	}); err != nil {
+		logger.Log("err", err)
+	}
+	return syncRev, nil
+}
+
+// reportSkipped remembers newRev as the revision last skipped, and
+// gives the one skipped before it.
+func (loop *LoopVars) reportSkipped(newRev string) string {
+	loop.skippedMu.Lock()
+	defer loop.skippedMu.Unlock()
+	last := loop.skippedRevision
+	loop.skippedRevision = newRev
+	return last
+}
+
+// hasSkipMarker says whether the commit message has any of the
+// markers in it, ignoring case.
+func hasSkipMarker(message string, markers []string) bool {
+	message = strings.ToLower(message)
+	for _, m := range markers {
+		if m != "" && strings.Contains(message, strings.ToLower(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/policy"
+)
+
+func TestSkipSync(t *testing.T) {
+	extra, cleanup := extraRepo(t)
+	defer cleanup()
+	events := &mockEventWriter{}
+	d := &Daemon{
+		Repo:            extra.Repo,
+		GitConfig:       git.Config{Branch: "master"},
+		EventWriter:     events,
+		Logger:          log.NewNopLogger(),
+		LoopVars:        &LoopVars{},
+		SkipSyncMarkers: []string{"[flux skip]", "[skip flux]"},
+	}
+
+	ctx := context.Background()
+	synced, err := extra.Repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add a commit to the (bare) upstream, and give its revision
+	upstream := strings.TrimPrefix(extra.Repo.Origin().URL, "file://")
+	commit := func(message string) string {
+		out, err := exec.Command("git", "-C", upstream, "-c", "user.name=example", "-c", "user.email=example@example.com",
+			"commit-tree", "-p", "master", "-m", message, "master^{tree}").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rev := strings.TrimSpace(string(out))
+		if err := exec.Command("git", "-C", upstream, "update-ref", "refs/heads/master", rev).Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := extra.Repo.Refresh(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return rev
+	}
+	syncRev := func(rev string) string {
+		syncRev, err := d.skipSync(ctx, synced, rev, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return syncRev
+	}
+
+	// What was synced before is synced again, in place of the
+	// commits marked
+	first := commit("Scale down [flux skip]")
+	if rev := syncRev(first); rev != synced {
+		t.Fatalf("expected a commit marked to skip to be skipped, and %s synced, got %s", synced, rev)
+	}
+	// The marker may be anywhere in the message
+	second := commit("Scale down some more\n\nNot yet. [Flux Skip]")
+	if syncRev(second) != synced || syncRev(second) != synced {
+		t.Fatal("expected the second commit marked to skip to be skipped")
+	}
+	// Each marked commit is recorded once, as not anything flux did
+	if len(events.events) != 2 {
+		t.Fatalf("expected an event for each commit skipped, got %+v", events.events)
+	}
+	for i, rev := range []string{first, second} {
+		ev := events.events[i]
+		metadata, ok := ev.Metadata.(*event.SyncEventMetadata)
+		if ev.Type != event.EventSync || !ok || !metadata.Skipped || !metadata.Includes[event.NoneOfTheAbove] {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		if len(metadata.Commits) != 1 || metadata.Commits[0].Revision != rev {
+			t.Errorf("expected the event to record %s, got %+v", rev, metadata.Commits)
+		}
+	}
+	if msg := events.events[1].Metadata.(*event.SyncEventMetadata).Commits[0].Message; msg != "Scale down some more" {
+		t.Errorf("expected the first line of the message, got %q", msg)
+	}
+
+	// A commit after them that isn't marked lets them all be synced
+	unmarked := commit("Scale back up")
+	if syncRev(unmarked) != unmarked {
+		t.Error("expected a commit not marked to skip to be synced")
+	}
+	if len(events.events) != 2 {
+		t.Errorf("expected no more events, got %+v", events.events)
+	}
+
+	// A commit that isn't marked, before those that are, is synced
+	// in their place
+	marked := commit("Scale down again [skip flux]")
+	if rev := syncRev(marked); rev != unmarked {
+		t.Errorf("expected %s to be synced in place of %s, got %s", unmarked, marked, rev)
+	}
+	// .. as it is if nothing has been synced before
+	if rev, err := d.skipSync(ctx, "", marked, log.NewNopLogger()); err != nil || rev != unmarked {
+		t.Errorf("expected %s to be synced first, got %s (%v)", unmarked, rev, err)
+	}
+
+	// Tags are synced regardless
+	d.SyncTags = policy.NewPattern("glob:release-*")
+	if tagged := commit("Tag me [skip flux]"); syncRev(tagged) != tagged {
+		t.Error("expected a tag to be synced even if its commit is marked to skip")
+	}
+}
+
+func TestHasSkipMarker(t *testing.T) {
+	markers := []string{"[flux skip]", "[ci skip]", ""}
+	for message, expected := range map[string]bool{
+		"Scale down [flux skip]":    true,
+		"Scale down\n\n[CI SKIP]":   true,
+		"Scale down":                false,
+		"Skip flux when scaling up": false,
+	} {
+		if got := hasSkipMarker(message, markers); got != expected {
+			t.Errorf("expected %v for %q, got %v", expected, message, got)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// checkStaleImages looks for workloads that have been running an
+// image older than StaleImageAge, while there are newer images
+// matching their tag filter; i.e., those whose automation is broken,
+// or that have been locked (or never automated) and forgotten. Each
+// image is reported once per workload container, in a warning event.
+func (d *Daemon) checkStaleImages(logger log.Logger) {
+	ctx := context.Background()
+
+	services, err := d.Cluster.AllControllers("")
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "getting workloads to check for stale images"))
+		return
+	}
+	resources, _, err := d.getResources(ctx)
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "getting resources to check for stale images"))
+		return
+	}
+	imageRepos, err := update.FetchImageRepos(d.Registry, clusterContainers(services), logger)
+	if err != nil {
+		logger.Log("error", errors.Wrap(err, "fetching images to check for staleness"))
+		return
+	}
+
+	now := time.Now()
+	var stale []event.StaleImage
+	serviceIDs := flux.ResourceIDSet{}
+	d.staleMu.Lock()
+	if d.staleReported == nil {
+		d.staleReported = map[string]string{}
+	}
+	for _, service := range services {
+		if service.IsSystem || service.Immutable {
+			continue
+		}
+		var policies policy.Set
+		if res, ok := resources[service.ID.String()]; ok {
+			policies = res.Policy()
+		}
+		if policies.Has(policy.Ignore) {
+			continue
+		}
+		for _, c := range service.ContainersOrNil() {
+			key := service.ID.String() + "/" + c.Name
+			if d.staleReported[key] == c.Image.String() {
+				continue
+			}
+			images := imageRepos.GetRepoImages(c.Image.Name)
+			current := images.FindWithRef(c.Image)
+			if current.CreatedAt.IsZero() || now.Sub(current.CreatedAt) < d.StaleImageAge {
+				continue
+			}
+			sorted := images.FilterAndSort(policy.GetTagPattern(policies, c.Name))
+			latest, ok := sorted.Latest()
+			if !ok || latest.ID == c.Image || !latest.CreatedAt.After(current.CreatedAt) {
+				continue
+			}
+			newer := 0
+			for _, info := range sorted {
+				if info.CreatedAt.After(current.CreatedAt) {
+					newer++
+				}
+			}
+			stale = append(stale, event.StaleImage{
+				ID:        service.ID,
+				Container: c.Name,
+				Current:   c.Image.String(),
+				CreatedAt: current.CreatedAt,
+				Latest:    latest.ID.Tag,
+				LatestAt:  latest.CreatedAt,
+				Newer:     newer,
+				Owner:     d.ownerOf(service),
+			})
+			serviceIDs.Add([]flux.ResourceID{service.ID})
+			d.staleReported[key] = c.Image.String()
+		}
+	}
+	d.staleMu.Unlock()
+
+	if len(stale) == 0 {
+		return
+	}
+	now = now.UTC()
+	if err := d.LogEvent(event.Event{
+		ServiceIDs: serviceIDs.ToSlice(),
+		Type:       event.EventStaleImage,
+		StartedAt:  now,
+		EndedAt:    now,
+		LogLevel:   event.LogLevelWarn,
+		Metadata:   &event.StaleImageEventMetadata{Images: stale},
+	}); err != nil {
+		logger.Log("error", errors.Wrap(err, "logging stale image event"))
+	}
+}
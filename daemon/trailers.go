@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/job"
+	"github.com/weaveworks/flux/update"
+)
+
+// commitTrailers gives the trailers for a commit made by a job, so
+// tools can match commits with jobs (and the events about them)
+// without parsing the commit message. The event for a commit isn't
+// logged until the commit is synced, so it's the job that's named.
+func commitTrailers(jobID job.ID, spec update.Spec, workloads []flux.ResourceID) []git.Trailer {
+	trailers := []git.Trailer{
+		{Key: "Flux-Job-ID", Value: string(jobID)},
+		{Key: "Flux-Update-Type", Value: spec.Type},
+	}
+	if spec.Cause.User != "" {
+		trailers = append(trailers, git.Trailer{Key: "Flux-Cause-User", Value: spec.Cause.User})
+	}
+	if len(workloads) > 0 {
+		var strs []string
+		for _, id := range workloads {
+			strs = append(strs, id.String())
+		}
+		sort.Strings(strs)
+		trailers = append(trailers, git.Trailer{Key: "Flux-Workloads", Value: strings.Join(strs, ", ")})
+	}
+	return trailers
+}
@@ -0,0 +1,33 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestCommitTrailers(t *testing.T) {
+	spec := update.Spec{Type: update.Images, Cause: update.Cause{User: "jane"}}
+	workloads := []flux.ResourceID{
+		flux.MustParseResourceID("default:deployment/web"),
+		flux.MustParseResourceID("default:deployment/api"),
+	}
+	expected := []git.Trailer{
+		{Key: "Flux-Job-ID", Value: "job-1"},
+		{Key: "Flux-Update-Type", Value: update.Images},
+		{Key: "Flux-Cause-User", Value: "jane"},
+		{Key: "Flux-Workloads", Value: "default:deployment/api, default:deployment/web"},
+	}
+	if got := commitTrailers("job-1", spec, workloads); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+
+	// No user or workloads, no trailers for them
+	expected = expected[:2]
+	if got := commitTrailers("job-1", update.Spec{Type: update.Images}, nil); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+}
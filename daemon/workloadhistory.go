@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+var workloadHistoryTypes = []string{
+	event.EventRelease, event.EventAutoRelease, event.EventRollback, event.EventRestart,
+	event.EventLock, event.EventUnlock, event.EventAutomate, event.EventDeautomate, event.EventUpdatePolicy,
+}
+
+// WorkloadHistory gives a timeline of the changes made to one
+// workload -- its releases, the images its containers were given, and
+// when it was locked, automated, or had its policies changed -- from
+// the events kept, with how long each lasted.
+func (d *Daemon) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	history := v28.WorkloadHistory{Workload: opts.Workload}
+	store := d.eventStore()
+	if store == nil {
+		return history, nil
+	}
+	events, err := store.EventsForService(opts.Workload, event.Page{}, event.EventFilter{
+		Types: workloadHistoryTypes,
+		Since: opts.Since,
+	})
+	if err != nil {
+		return history, err
+	}
+	history.Entries = workloadHistory(opts.Workload, events)
+	return history, nil
+}
+
+// workloadHistory makes the entries for the workload from its events,
+// oldest first. An image lasts until the container is given another,
+// a lock until the workload is unlocked, and automation until it's
+// turned off.
+func workloadHistory(id flux.ResourceID, events []event.Event) []v28.WorkloadHistoryEntry {
+	var entries []v28.WorkloadHistoryEntry
+	// The entries still going, by kind, or by container for images
+	going := map[string]int{}
+	start := func(key string, entry v28.WorkloadHistoryEntry) {
+		if _, ok := going[key]; ok {
+			return
+		}
+		going[key] = len(entries)
+		entries = append(entries, entry)
+	}
+	end := func(key string, at time.Time) {
+		if i, ok := going[key]; ok {
+			entries[i].End = &at
+			delete(going, key)
+		}
+	}
+
+	for _, e := range events {
+		entry := v28.WorkloadHistoryEntry{
+			EventID: e.ID,
+			Start:   e.StartedAt,
+			User:    causeUser(e),
+			Message: e.String(),
+		}
+		switch e.Type {
+		case event.EventLock:
+			entry.Kind = v28.HistoryLock
+			start(v28.HistoryLock, entry)
+		case event.EventUnlock:
+			end(v28.HistoryLock, e.StartedAt)
+		case event.EventAutomate:
+			entry.Kind = v28.HistoryAutomation
+			start(v28.HistoryAutomation, entry)
+		case event.EventDeautomate:
+			end(v28.HistoryAutomation, e.StartedAt)
+		case event.EventUpdatePolicy:
+			entry.Kind = v28.HistoryPolicy
+			entry.End = endedAt(e)
+			entries = append(entries, entry)
+		default:
+			entry.Kind = v28.HistoryRelease
+			entry.End = endedAt(e)
+			entries = append(entries, entry)
+			res, ok := releaseResult(e)[id]
+			if !ok || res.Status != update.ReleaseStatusSuccess {
+				continue
+			}
+			for _, c := range res.PerContainer {
+				if c.Current == c.Target {
+					continue
+				}
+				key := v28.HistoryImage + ":" + c.Container
+				end(key, e.StartedAt)
+				start(key, v28.WorkloadHistoryEntry{
+					Kind:      v28.HistoryImage,
+					EventID:   e.ID,
+					Start:     e.StartedAt,
+					Container: c.Container,
+					From:      c.Current.String(),
+					To:        c.Target.String(),
+					User:      entry.User,
+				})
+			}
+		}
+	}
+	return entries
+}
+
+func endedAt(e event.Event) *time.Time {
+	if e.EndedAt.IsZero() {
+		return nil
+	}
+	t := e.EndedAt
+	return &t
+}
+
+// releaseResult gives the result recorded in a release, automated
+// release, rollback or restart event.
+func releaseResult(e event.Event) update.Result {
+	switch metadata := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		return metadata.Result
+	case *event.AutoReleaseEventMetadata:
+		return metadata.Result
+	case *event.RollbackEventMetadata:
+		return metadata.Result
+	case *event.RestartEventMetadata:
+		return metadata.Result
+	}
+	return nil
+}
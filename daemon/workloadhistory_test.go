@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestWorkloadHistory(t *testing.T) {
+	start := time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("default:deployment/other")
+	imageUpdate := func(from, to string) update.Result {
+		current, _ := image.ParseRef("quay.io/example/hello:" + from)
+		target, _ := image.ParseRef("quay.io/example/hello:" + to)
+		return update.Result{hello: update.ControllerResult{
+			Status: update.ReleaseStatusSuccess,
+			PerContainer: []update.ContainerUpdate{
+				{Container: "greeter", Current: current, Target: target},
+			},
+		}}
+	}
+	store := &event.Buffer{}
+	for _, e := range []event.Event{
+		{Type: event.EventRelease, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start, EndedAt: start.Add(time.Minute), LogLevel: event.LogLevelInfo,
+			Metadata: &event.ReleaseEventMetadata{
+				ReleaseEventCommon: event.ReleaseEventCommon{Result: imageUpdate("1", "2")},
+				Cause:              update.Cause{User: "jane"},
+			}},
+		{Type: event.EventLock, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(time.Hour), LogLevel: event.LogLevelInfo},
+		// Another workload
+		{Type: event.EventUnlock, ServiceIDs: []flux.ResourceID{other}, StartedAt: start.Add(2 * time.Hour), LogLevel: event.LogLevelInfo},
+		{Type: event.EventUnlock, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(3 * time.Hour), LogLevel: event.LogLevelInfo},
+		// Not a change
+		{Type: event.EventSync, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(4 * time.Hour), LogLevel: event.LogLevelInfo},
+		{Type: event.EventAutoRelease, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(5 * time.Hour), EndedAt: start.Add(5 * time.Hour), LogLevel: event.LogLevelInfo,
+			Metadata: &event.AutoReleaseEventMetadata{
+				ReleaseEventCommon: event.ReleaseEventCommon{Result: imageUpdate("2", "3")},
+			}},
+		{Type: event.EventAutomate, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start.Add(6 * time.Hour), LogLevel: event.LogLevelInfo},
+	} {
+		store.LogEvent(e)
+	}
+	d := &Daemon{EventStore: store}
+
+	history, err := d.WorkloadHistory(context.Background(), v28.WorkloadHistoryOptions{Workload: hello})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d time.Duration) *time.Time {
+		t := start.Add(d)
+		return &t
+	}
+	expected := []v28.WorkloadHistoryEntry{
+		{Kind: v28.HistoryRelease, EventID: 1, Start: start, End: at(time.Minute), User: "jane"},
+		{Kind: v28.HistoryImage, EventID: 1, Start: start, End: at(5 * time.Hour), Container: "greeter", From: "quay.io/example/hello:1", To: "quay.io/example/hello:2", User: "jane"},
+		{Kind: v28.HistoryLock, EventID: 2, Start: start.Add(time.Hour), End: at(3 * time.Hour)},
+		{Kind: v28.HistoryRelease, EventID: 6, Start: start.Add(5 * time.Hour), End: at(5 * time.Hour)},
+		{Kind: v28.HistoryImage, EventID: 6, Start: start.Add(5 * time.Hour), Container: "greeter", From: "quay.io/example/hello:2", To: "quay.io/example/hello:3"},
+		{Kind: v28.HistoryAutomation, EventID: 7, Start: start.Add(6 * time.Hour)},
+	}
+	if len(history.Entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), history.Entries)
+	}
+	for i, e := range expected {
+		got := history.Entries[i]
+		got.Message = ""
+		if got.Kind != e.Kind || got.EventID != e.EventID || !got.Start.Equal(e.Start) ||
+			got.Container != e.Container || got.From != e.From || got.To != e.To || got.User != e.User {
+			t.Errorf("entry %d: expected %+v, got %+v", i, e, got)
+		}
+		if (got.End == nil) != (e.End == nil) || (got.End != nil && !got.End.Equal(*e.End)) {
+			t.Errorf("entry %d: expected end %v, got %v", i, e.End, got.End)
+		}
+	}
+}
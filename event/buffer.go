@@ -0,0 +1,203 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+// DefaultBufferSize is how many events a Buffer keeps, if not told
+// otherwise.
+const DefaultBufferSize = 500
+
+// Buffer keeps the most recent events logged, so they can be listed
+// (and followed) by clients. Each event is given an ID, increasing
+// in the order events are logged, so a client can ask for only the
+// events after the last it saw. The zero value is ready to use.
+type Buffer struct {
+	Size int
+
+	mu     sync.Mutex
+	events []Event
+	lastID EventID
+}
+
+var (
+	_ EventStore = &Buffer{}
+	_ Pruner     = &Buffer{}
+	_ Recorder   = &Buffer{}
+)
+
+func (b *Buffer) LogEvent(e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log(e)
+	return nil
+}
+
+// LogEvents logs the events in turn, as an EventStore. No other
+// events are logged between them.
+func (b *Buffer) LogEvents(events []Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range events {
+		b.log(e)
+	}
+	return nil
+}
+
+// Seed makes the buffer give IDs after the one given, if that's later
+// than the last it gave.
+func (b *Buffer) Seed(after EventID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if after > b.lastID {
+		b.lastID = after
+	}
+}
+
+// ClockSeed gives an ID to Seed a new buffer with, from the time
+// given: the milliseconds since the Unix epoch. Since a buffer gives
+// out IDs one at a time, a buffer seeded like this when the daemon
+// starts gives IDs greater than any given before the daemon was
+// restarted (unless it had been logging more than an event a
+// millisecond). So a client that remembers an ID -- following events,
+// or as a webhook cursor -- doesn't mistake new events for ones it's
+// already seen, as it would if IDs started again at 1.
+func ClockSeed(t time.Time) EventID {
+	return EventID(t.UnixNano() / int64(time.Millisecond))
+}
+
+// log keeps the event, giving it the next ID, and returns the ID. It
+// must be called with the lock held.
+func (b *Buffer) log(e Event) EventID {
+	size := b.Size
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	b.lastID++
+	e.ID = b.lastID
+	b.events = append(b.events, e)
+	if len(b.events) > size {
+		b.events = append([]Event(nil), b.events[len(b.events)-size:]...)
+	}
+	return e.ID
+}
+
+// Annotate adds the annotation to the event with the ID given, and
+// returns the event as annotated. It returns false if there's no such
+// event, or it's no longer kept.
+func (b *Buffer) Annotate(id EventID, a Annotation) (Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.events {
+		if b.events[i].ID == id {
+			// Copy, since events already returned share the slice
+			annotations := append([]Annotation(nil), b.events[i].Annotations...)
+			b.events[i].Annotations = append(annotations, a)
+			return b.events[i], true
+		}
+	}
+	return Event{}, false
+}
+
+// Since returns the events logged after the event with the ID given,
+// oldest first. If limit is more than zero, only that many of the
+// most recent are returned.
+func (b *Buffer) Since(after EventID, limit int) []Event {
+	return b.page(Page{After: after, Limit: limit}, EventFilter{})
+}
+
+// AllEvents gives the events in the page, as an EventStore.
+func (b *Buffer) AllEvents(page Page, filter EventFilter) ([]Event, error) {
+	return b.page(page, filter), nil
+}
+
+func (b *Buffer) EventsForService(id flux.ResourceID, page Page, filter EventFilter) ([]Event, error) {
+	return b.page(page, filter, id), nil
+}
+
+func (b *Buffer) EventsForNamespace(namespace string, page Page, filter EventFilter) ([]Event, error) {
+	return b.pageWhere(page, filter, func(e Event) bool {
+		return e.InNamespace(namespace)
+	}), nil
+}
+
+// page gives the events in the page that match the filter and, if
+// any IDs are given, concern one of those workloads.
+func (b *Buffer) page(p Page, filter EventFilter, ids ...flux.ResourceID) []Event {
+	return b.pageWhere(p, filter, func(e Event) bool {
+		return len(ids) == 0 || concernsAny(e, ids)
+	})
+}
+
+// pageWhere gives the events in the page that match the filter and
+// are also selected by the func given.
+func (b *Buffer) pageWhere(p Page, filter EventFilter, selected func(Event) bool) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []Event
+	for _, e := range b.events {
+		if p.Includes(e.ID) && filter.Matches(e) && selected(e) {
+			events = append(events, e)
+		}
+	}
+	if p.Limit > 0 && len(events) > p.Limit {
+		events = events[len(events)-p.Limit:]
+	}
+	return events
+}
+
+func (b *Buffer) GetEvent(id EventID) (Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.events {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Event{}, ErrNoSuchEvent
+}
+
+// AnnotateEvent is Annotate, as an EventStore.
+func (b *Buffer) AnnotateEvent(id EventID, a Annotation) (Event, error) {
+	annotated, ok := b.Annotate(id, a)
+	if !ok {
+		return Event{}, ErrNoSuchEvent
+	}
+	return annotated, nil
+}
+
+// RecordEvent counts the event as a repeat of the most recent event
+// of the same type, if they can be coalesced, or else logs it, as a
+// Recorder.
+func (b *Buffer) RecordEvent(e Event) (EventID, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.events) - 1; i >= 0; i-- {
+		if b.events[i].Type != e.Type {
+			continue
+		}
+		if Coalesces(b.events[i], e) {
+			b.events[i].Repeated++
+			b.events[i].LastSeen = e.StartedAt
+			return b.events[i].ID, nil
+		}
+		break
+	}
+	return b.log(e), nil
+}
+
+// PruneEvents removes the events not kept by the policy, as a Pruner.
+func (b *Buffer) PruneEvents(policy RetentionPolicy, now time.Time) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := policy.Retain(b.events, now)
+	pruned := len(b.events) - len(kept)
+	if pruned > 0 {
+		// Copy, since events already returned share the slice
+		b.events = append([]Event(nil), kept...)
+	}
+	return pruned, nil
+}
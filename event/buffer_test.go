@@ -0,0 +1,122 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestBuffer(t *testing.T) {
+	b := &Buffer{Size: 3}
+	for _, typ := range []string{EventSync, EventRelease, EventCommit, EventLock} {
+		if err := b.LogEvent(Event{Type: typ}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := b.Since(0, 0)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 events kept, got %d", len(all))
+	}
+	if all[0].Type != EventRelease || all[0].ID != 2 || all[2].ID != 4 {
+		t.Errorf("expected oldest event to have been dropped, got %+v", all)
+	}
+
+	after := b.Since(3, 0)
+	if len(after) != 1 || after[0].Type != EventLock {
+		t.Errorf("expected only the event after ID 3, got %+v", after)
+	}
+
+	latest := b.Since(0, 2)
+	if len(latest) != 2 || latest[0].ID != 3 {
+		t.Errorf("expected the two most recent events, got %+v", latest)
+	}
+
+	between, _ := b.AllEvents(Page{After: 2, Before: 4}, EventFilter{})
+	if len(between) != 1 || between[0].ID != 3 {
+		t.Errorf("expected only the event between IDs 2 and 4, got %+v", between)
+	}
+	older, _ := b.AllEvents(Page{Before: 4, Limit: 1}, EventFilter{})
+	if len(older) != 1 || older[0].ID != 3 {
+		t.Errorf("expected the most recent event before ID 4, got %+v", older)
+	}
+}
+
+func TestBufferAnnotate(t *testing.T) {
+	b := &Buffer{Size: 2}
+	for _, typ := range []string{EventSync, EventRelease, EventCommit} {
+		if err := b.LogEvent(Event{Type: typ}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := b.Since(0, 0)
+
+	if _, ok := b.Annotate(1, Annotation{Comment: "dropped"}); ok {
+		t.Error("expected event no longer kept not to be annotated")
+	}
+	annotated, ok := b.Annotate(2, Annotation{User: "jane", Comment: "expected, maintenance", Acknowledged: true})
+	if !ok {
+		t.Fatal("expected event to be annotated")
+	}
+	if len(annotated.Annotations) != 1 || !annotated.Acknowledged() {
+		t.Errorf("expected an acknowledgement, got %+v", annotated.Annotations)
+	}
+	if after := b.Since(1, 1); len(after) != 1 || len(after[0].Annotations) != 0 {
+		t.Errorf("expected other events not to be annotated, got %+v", after)
+	}
+	if after := b.Since(0, 0); len(after[0].Annotations) != 1 {
+		t.Errorf("expected annotation to be kept with the event, got %+v", after[0])
+	}
+	if len(before[0].Annotations) != 0 {
+		t.Errorf("expected events already listed to be left as they were, got %+v", before[0])
+	}
+}
+
+func TestBufferEventsForNamespace(t *testing.T) {
+	b := &Buffer{}
+	hello := flux.MustParseResourceID("default:deployment/helloworld")
+	other := flux.MustParseResourceID("other:deployment/service")
+	if err := b.LogEvents([]Event{
+		{Type: EventRelease, ServiceIDs: []flux.ResourceID{hello}},
+		{Type: EventSync, ServiceIDs: []flux.ResourceID{hello, other}},
+		{Type: EventCommit, Namespace: "other"},
+		{Type: EventCommit},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := b.EventsForNamespace("other", Page{}, EventFilter{})
+	if len(events) != 2 || events[0].ID != 2 || events[1].ID != 3 {
+		t.Errorf("expected the events concerning or logged in the namespace, got %+v", events)
+	}
+	events, _ = b.EventsForNamespace("other", Page{}, EventFilter{Types: []string{EventCommit}})
+	if len(events) != 1 || events[0].ID != 3 {
+		t.Errorf("expected only the commit logged in the namespace, got %+v", events)
+	}
+}
+
+func TestBufferLogEvents(t *testing.T) {
+	b := &Buffer{}
+	if err := b.LogEvent(Event{Type: EventSync}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.LogEvents([]Event{{Type: EventRelease}, {Type: EventCommit}}); err != nil {
+		t.Fatal(err)
+	}
+	all := b.Since(0, 0)
+	if len(all) != 3 || all[1].Type != EventRelease || all[1].ID != 2 || all[2].ID != 3 {
+		t.Errorf("expected the batch to be logged in order after the first event, got %+v", all)
+	}
+}
+
+func TestBufferSeed(t *testing.T) {
+	b := &Buffer{}
+	b.LogEvent(Event{Type: EventSync})
+	b.Seed(100)
+	b.LogEvent(Event{Type: EventSync})
+	b.Seed(50)
+	b.LogEvent(Event{Type: EventSync})
+	all := b.Since(0, 0)
+	if len(all) != 3 || all[0].ID != 1 || all[1].ID != 101 || all[2].ID != 102 {
+		t.Errorf("expected IDs to follow the seed, and never go back, got %+v", all)
+	}
+}
@@ -0,0 +1,264 @@
+package event
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoChainKey is returned when there's no key to hash a chain of
+// events with.
+var ErrNoChainKey = errors.New("a key is needed to chain events")
+
+// ChainStore wraps the store so that each event logged records the
+// hash of the event logged before it, in PrevHash, making a chain
+// that VerifyHistory can check: an event changed or removed from the
+// store afterwards no longer matches the hash recorded by the event
+// after it. The hashes are HMACs with the key given, which must not
+// be empty; without a secret key, whoever can change the store could
+// simply hash the events again. If the store is a Pruner or a
+// Recorder, so is the store returned.
+//
+// An event's hash covers what it says when it's logged; the count of
+// repeats coalesced into it, and its annotations, can change without
+// breaking the chain.
+func ChainStore(next EventStore, key []byte) (EventStore, error) {
+	if len(key) == 0 {
+		return nil, ErrNoChainKey
+	}
+	s := &chainStore{EventStore: next, key: key}
+	pruner, canPrune := next.(Pruner)
+	recorder, canRecord := next.(Recorder)
+	switch {
+	case canPrune && canRecord:
+		return &chainPruningRecordingStore{s, pruner, chainRecorder{s, recorder}}, nil
+	case canPrune:
+		return &chainPruningStore{s, pruner}, nil
+	case canRecord:
+		return &chainRecordingStore{s, chainRecorder{s, recorder}}, nil
+	}
+	return s, nil
+}
+
+type chainStore struct {
+	EventStore
+	key []byte
+	// held while logging, so each event is chained to the one
+	// actually logged before it
+	mu sync.Mutex
+}
+
+func (s *chainStore) LogEvent(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, err := s.prevHash()
+	if err != nil {
+		return err
+	}
+	e.PrevHash = prev
+	return s.EventStore.LogEvent(e)
+}
+
+// LogEvents chains the events given to the last one logged, and to
+// each other, in order.
+func (s *chainStore) LogEvents(events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, err := s.prevHash()
+	if err != nil {
+		return err
+	}
+	chained := make([]Event, len(events))
+	for i, e := range events {
+		e.PrevHash = prev
+		chained[i] = e
+		prev = ChainHash(e, s.key)
+	}
+	return s.EventStore.LogEvents(chained)
+}
+
+// lastEvent gives the most recent event in the store, and whether
+// there is one.
+func lastEvent(store EventStore) (Event, bool, error) {
+	events, err := store.AllEvents(Page{Limit: 1}, EventFilter{})
+	if err != nil || len(events) == 0 {
+		return Event{}, false, err
+	}
+	return events[0], true, nil
+}
+
+func (s *chainStore) prevHash() (string, error) {
+	last, ok, err := lastEvent(s.EventStore)
+	if err != nil || !ok {
+		return "", err
+	}
+	return ChainHash(last, s.key), nil
+}
+
+type chainRecorder struct {
+	s        *chainStore
+	recorder Recorder
+}
+
+// RecordEvent chains the event to the last one logged. If it's
+// coalesced with an event already kept, it's not added to the chain,
+// since it doesn't change what's hashed.
+func (r chainRecorder) RecordEvent(e Event) (EventID, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	prev, err := r.s.prevHash()
+	if err != nil {
+		return 0, err
+	}
+	e.PrevHash = prev
+	return r.recorder.RecordEvent(e)
+}
+
+type chainPruningStore struct {
+	*chainStore
+	Pruner
+}
+
+type chainRecordingStore struct {
+	*chainStore
+	chainRecorder
+}
+
+type chainPruningRecordingStore struct {
+	*chainStore
+	Pruner
+	chainRecorder
+}
+
+// ChainHash gives the hash of the event as recorded in the PrevHash
+// of the event after it, an HMAC with the key given of everything it
+// says (including the hash it records in turn) except what can
+// change once it's kept, i.e., its repeats and annotations, and the
+// ID the store gives it.
+func ChainHash(e Event, key []byte) string {
+	e.ID = 0
+	e.Repeated = 0
+	e.LastSeen = time.Time{}
+	e.Annotations = nil
+	e.StartedAt = e.StartedAt.UTC()
+	e.EndedAt = e.EndedAt.UTC()
+	h := hmac.New(sha256.New, key)
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		// Events are logged as JSON, so this isn't expected; but
+		// an event that can't be hashed mustn't match
+		bytes = []byte(err.Error())
+	}
+	h.Write(bytes)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainHead gives the ID and hash of the most recent event in the
+// store, i.e., the hash the next event logged will record, and
+// whether there are any events. Since the most recent event isn't
+// covered by the chain until another is logged after it, and the
+// chain as a whole can be made again by whoever has the key, the
+// head should be kept somewhere other than the store (and out of
+// reach of whoever can change it), so that the history up to it can
+// be checked against it later.
+func ChainHead(store EventStore, key []byte) (ChainHeadEventMetadata, bool, error) {
+	if len(key) == 0 {
+		return ChainHeadEventMetadata{}, false, ErrNoChainKey
+	}
+	last, ok, err := lastEvent(store)
+	if err != nil || !ok {
+		return ChainHeadEventMetadata{}, false, err
+	}
+	return ChainHeadEventMetadata{ID: last.ID, Hash: ChainHash(last, key)}, true, nil
+}
+
+// CheckHead says whether the event given by a head from ChainHead,
+// recorded elsewhere, is still in the store, with the same hash. If
+// it is, and VerifyHistory finds no breaks, the history up to that
+// event is as it was when the head was recorded. A head that's since
+// been pruned from the store is taken as missing.
+func CheckHead(store EventStore, key []byte, head ChainHeadEventMetadata) (bool, error) {
+	if len(key) == 0 {
+		return false, ErrNoChainKey
+	}
+	e, err := store.GetEvent(head.ID)
+	if err == ErrNoSuchEvent {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(ChainHash(e, key)), []byte(head.Hash)), nil
+}
+
+// ChainBreak is a place where the chain of events in a store doesn't
+// check out.
+type ChainBreak struct {
+	// The event whose PrevHash doesn't match the event before it
+	ID EventID `json:"id"`
+	// What's wrong, e.g., that events are missing
+	Reason string `json:"reason"`
+}
+
+// The number of events VerifyHistory asks the store for at a time
+const verifyPageSize = 500
+
+// VerifyHistory checks the chain of hashes recorded by the events in
+// the store (see ChainStore), with the key they were hashed with,
+// most recent first, and gives each place it's broken: where an event
+// is missing, or has been changed, or its hash removed. Events from
+// before the chain started (with no PrevHash) are taken as they are.
+//
+// That the chain checks out only shows the history hasn't been
+// changed by someone without the key, and only up to the most recent
+// event: the most recent event can't be checked until another is
+// logged after it, and events at the end of the history can be
+// removed without breaking what's left. To know that nothing's been
+// taken from the end, check with CheckHead that a head recorded
+// elsewhere is still in the store.
+func VerifyHistory(store EventStore, key []byte) ([]ChainBreak, error) {
+	if len(key) == 0 {
+		return nil, ErrNoChainKey
+	}
+	var breaks []ChainBreak
+	var next *Event
+	page := Page{Limit: verifyPageSize}
+	for {
+		events, err := store.AllEvents(page, EventFilter{})
+		if err != nil {
+			return nil, err
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			e := events[i]
+			if next != nil {
+				if b, ok := chainBreak(e, *next, key); !ok {
+					breaks = append(breaks, b)
+				}
+			}
+			next = &e
+		}
+		if len(events) < verifyPageSize {
+			return breaks, nil
+		}
+		page.Before = events[0].ID
+	}
+}
+
+// chainBreak checks that the event after records the hash of the
+// event given.
+func chainBreak(e, after Event, key []byte) (ChainBreak, bool) {
+	switch {
+	case after.PrevHash == "" && e.PrevHash != "":
+		return ChainBreak{ID: after.ID, Reason: "no hash recorded, though the chain had started"}, false
+	case after.PrevHash == "" || after.PrevHash == ChainHash(e, key):
+		return ChainBreak{}, true
+	case after.ID != e.ID+1:
+		return ChainBreak{ID: after.ID, Reason: fmt.Sprintf("events missing between %d and %d, or changed", e.ID, after.ID)}, false
+	}
+	return ChainBreak{ID: after.ID, Reason: fmt.Sprintf("event %d has changed since this event was logged", e.ID)}, false
+}
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+var chainKey = []byte("secret")
+
+func chainedBuffer(t *testing.T, key []byte) (*Buffer, EventStore) {
+	b := &Buffer{}
+	s, err := ChainStore(b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := s.LogEvent(Event{Type: EventCommit, StartedAt: start.Add(time.Duration(i) * time.Minute), Metadata: &CommitEventMetadata{Revision: "abc"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.LogEvents([]Event{
+		{Type: EventCommit, StartedAt: start.Add(3 * time.Minute), Metadata: &CommitEventMetadata{Revision: "def"}},
+		{Type: EventCommit, StartedAt: start.Add(4 * time.Minute), Metadata: &CommitEventMetadata{Revision: "ghi"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return b, s
+}
+
+func verify(t *testing.T, s EventStore, key []byte) []ChainBreak {
+	breaks, err := VerifyHistory(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return breaks
+}
+
+func TestChainStore(t *testing.T) {
+	if _, err := ChainStore(&Buffer{}, nil); err != ErrNoChainKey {
+		t.Errorf("expected a chain without a key to be refused, got %v", err)
+	}
+	if _, err := VerifyHistory(&Buffer{}, nil); err != ErrNoChainKey {
+		t.Errorf("expected verifying without a key to be refused, got %v", err)
+	}
+
+	b, s := chainedBuffer(t, chainKey)
+	if b.events[0].PrevHash != "" || b.events[1].PrevHash == "" || b.events[4].PrevHash == "" {
+		t.Fatalf("expected every event but the first to record a hash, got %+v", b.events)
+	}
+	if breaks := verify(t, s, chainKey); len(breaks) != 0 {
+		t.Fatalf("expected the chain to check out, got %+v", breaks)
+	}
+
+	// Annotations and repeats don't count
+	if _, err := s.AnnotateEvent(2, Annotation{Comment: "expected"}); err != nil {
+		t.Fatal(err)
+	}
+	b.events[2].Repeated = 3
+	if breaks := verify(t, s, chainKey); len(breaks) != 0 {
+		t.Errorf("expected annotations and repeats not to break the chain, got %+v", breaks)
+	}
+
+	// Changing what an event says does
+	b.events[1].Metadata = &CommitEventMetadata{Revision: "xyz"}
+	breaks := verify(t, s, chainKey)
+	if len(breaks) != 1 || breaks[0].ID != 3 {
+		t.Errorf("expected the chain to be broken after event 2, got %+v", breaks)
+	}
+}
+
+func TestVerifyHistory_Missing(t *testing.T) {
+	b, s := chainedBuffer(t, chainKey)
+	b.events = append(b.events[:2], b.events[3:]...)
+	breaks := verify(t, s, chainKey)
+	if len(breaks) != 1 || breaks[0].ID != 4 {
+		t.Errorf("expected the chain to be broken where event 3 is missing, got %+v", breaks)
+	}
+
+	// Nor can the hashes just be taken away
+	b, s = chainedBuffer(t, chainKey)
+	b.events[4].PrevHash = ""
+	if breaks := verify(t, s, chainKey); len(breaks) != 1 || breaks[0].ID != 5 {
+		t.Errorf("expected a missing hash to break the chain, got %+v", breaks)
+	}
+}
+
+func TestVerifyHistory_Key(t *testing.T) {
+	_, s := chainedBuffer(t, chainKey)
+	if breaks := verify(t, s, chainKey); len(breaks) != 0 {
+		t.Errorf("expected the chain to check out with the key, got %+v", breaks)
+	}
+	if breaks := verify(t, s, []byte("guess")); len(breaks) != 4 {
+		t.Errorf("expected every link to fail with the wrong key, got %+v", breaks)
+	}
+}
+
+func TestChainStore_Recorder(t *testing.T) {
+	b := &Buffer{}
+	s, err := ChainStore(b, chainKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := s.(Recorder)
+	if !ok {
+		t.Fatal("expected the chained store to be a Recorder, since a Buffer is")
+	}
+	if _, ok := s.(Pruner); !ok {
+		t.Fatal("expected the chained store to be a Pruner, since a Buffer is")
+	}
+	sync := Event{Type: EventSync, Metadata: &SyncEventMetadata{}}
+	for i := 0; i < 3; i++ {
+		if _, err := r.RecordEvent(sync); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.LogEvent(Event{Type: EventCommit, Metadata: &CommitEventMetadata{}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.events) != 2 || b.events[0].Repeated != 2 {
+		t.Fatalf("expected the syncs to be coalesced, got %+v", b.events)
+	}
+	if breaks := verify(t, s, chainKey); len(breaks) != 0 {
+		t.Errorf("expected coalesced events not to break the chain, got %+v", breaks)
+	}
+}
+
+func TestChainHead(t *testing.T) {
+	b, s := chainedBuffer(t, chainKey)
+	head, ok, err := ChainHead(s, chainKey)
+	if err != nil || !ok {
+		t.Fatalf("expected a head, got %v, %v", ok, err)
+	}
+	if head.ID != 5 || head.Hash != ChainHash(b.events[4], chainKey) {
+		t.Errorf("expected the head to be the last event, got %+v", head)
+	}
+	if ok, err := CheckHead(s, chainKey, head); err != nil || !ok {
+		t.Errorf("expected the head to check out, got %v, %v", ok, err)
+	}
+
+	// Taking events from the end doesn't break the chain, but the
+	// head recorded is missing
+	b.events = b.events[:3]
+	if breaks := verify(t, s, chainKey); len(breaks) != 0 {
+		t.Errorf("expected what's left of the chain to check out, got %+v", breaks)
+	}
+	if ok, err := CheckHead(s, chainKey, head); err != nil || ok {
+		t.Errorf("expected the head to be missing, got %v, %v", ok, err)
+	}
+
+	if _, ok, err := ChainHead(&Buffer{}, chainKey); err != nil || ok {
+		t.Errorf("expected no head for an empty store, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,47 @@
+package event
+
+import (
+	"sort"
+	"strings"
+)
+
+// Recorder is implemented by event stores that can fold an event
+// into the most recent event of the same type they keep, when the
+// two say the same thing, rather than keep both; e.g., syncs that
+// keep failing with the same errors, with nothing new to sync. Events
+// of other types may come in between.
+type Recorder interface {
+	// RecordEvent counts the event as a repeat of the most recent
+	// event of its type, if they can be coalesced (see Coalesces), or
+	// else logs it as usual; and returns the ID of the event kept, so
+	// that whoever is told about the event can ask for those after it.
+	RecordEvent(e Event) (EventID, error)
+}
+
+// coalesceKey gives a key identifying what a sync event reports,
+// and true, if it's a sync that changed nothing -- with no new
+// commits, and no errors resolved -- which can be coalesced with
+// others like it; or false for any other event.
+func coalesceKey(e Event) (string, bool) {
+	m, ok := e.Metadata.(*SyncEventMetadata)
+	if e.Type != EventSync || !ok || len(m.Commits) > 0 || m.InitialSync || m.Recovered {
+		return "", false
+	}
+	var errs []string
+	for _, re := range m.Errors {
+		errs = append(errs, re.ID.String()+": "+re.Error)
+	}
+	sort.Strings(errs)
+	return strings.Join(e.ServiceIDStrings(), ",") + "\n" + strings.Join(errs, "\n"), true
+}
+
+// Coalesces says whether the event can be coalesced with the event
+// before, as a repeat of it.
+func Coalesces(before, e Event) bool {
+	key, ok := coalesceKey(e)
+	if !ok {
+		return false
+	}
+	beforeKey, ok := coalesceKey(before)
+	return ok && key == beforeKey
+}
@@ -0,0 +1,64 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestRecordEvent(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	start := time.Now().UTC()
+	failing := func(at time.Duration, errs ...string) Event {
+		m := &SyncEventMetadata{}
+		for _, err := range errs {
+			m.Errors = append(m.Errors, ResourceError{ID: foo, Error: err})
+		}
+		return Event{Type: EventSync, StartedAt: start.Add(at), Metadata: m}
+	}
+	b := &Buffer{}
+	var lastID EventID
+	log := func(e Event) bool {
+		id, err := b.RecordEvent(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		coalesced := id <= lastID
+		if id > lastID {
+			lastID = id
+		}
+		return coalesced
+	}
+
+	if log(failing(0, "broken")) {
+		t.Error("expected the first event not to be coalesced")
+	}
+	// Other events may come between repeats
+	log(Event{Type: EventImagePolled, Metadata: &ImagePolledEventMetadata{}})
+	if !log(failing(time.Minute, "broken")) || !log(failing(2*time.Minute, "broken")) {
+		t.Error("expected repeats of a sync with the same errors to be coalesced")
+	}
+	if log(failing(3*time.Minute, "broken", "also broken")) {
+		t.Error("expected a sync with different errors not to be coalesced")
+	}
+	resolved := Event{Type: EventSync, StartedAt: start.Add(4 * time.Minute), Metadata: &SyncEventMetadata{Recovered: true}}
+	if log(resolved) || log(resolved) {
+		t.Error("expected syncs resolving errors not to be coalesced")
+	}
+	withCommits := Event{Type: EventSync, Metadata: &SyncEventMetadata{Commits: []Commit{{Revision: "abc"}}}}
+	if log(withCommits) || log(withCommits) {
+		t.Error("expected syncs with commits not to be coalesced")
+	}
+
+	first, err := b.GetEvent(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Repeated != 2 || !first.LastSeen.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("expected two repeats, the last at %s, got %d at %s", start.Add(2*time.Minute), first.Repeated, first.LastSeen)
+	}
+	if all, _ := b.AllEvents(Page{}, EventFilter{}); len(all) != 7 {
+		t.Errorf("expected 7 events kept, got %d", len(all))
+	}
+}
@@ -0,0 +1,35 @@
+package event
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MakeCursor gives the cursor token for the event with the ID given,
+// as sent to the webhook subscriber named. The subscriber can hand it
+// back to be given the events it would have been sent after that one
+// (e.g., because it was down); to the subscriber, it's opaque.
+func MakeCursor(subscriber string, id EventID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", subscriber, id)))
+}
+
+// ParseCursor gives the subscriber and event ID from a cursor token
+// made by MakeCursor.
+func ParseCursor(cursor string) (string, EventID, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	s := string(bytes)
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	id, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil || id < 0 {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return s[:i], EventID(id), nil
+}
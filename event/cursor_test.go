@@ -0,0 +1,21 @@
+package event
+
+import (
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := MakeCursor("ops:alerts", 42)
+	name, id, err := ParseCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "ops:alerts" || id != 42 {
+		t.Errorf("expected subscriber ops:alerts and event 42, got %q and %d", name, id)
+	}
+	for _, bad := range []string{"", "not a cursor", MakeCursor("", 1)} {
+		if _, _, err := ParseCursor(bad); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package event
+
+import (
+	"strings"
+	"sync"
+)
+
+// Escalator stands in for a run of failures of the same kind for a
+// workload (e.g., its sync failing again and again) with one event
+// at the error level. The first failures in a run are logged as they
+// are; the one making Threshold in a row is escalated to an error,
+// with the count in Failures; and those after that are not logged
+// at all, until the workload stops failing in that way and a new run
+// can start.
+type Escalator struct {
+	Threshold int
+
+	mu sync.Mutex
+	// failures in a row, by event type and workload
+	failures map[string]int
+}
+
+// Escalate gives the event as it should be logged -- escalated, if
+// it makes Threshold failures in a row -- and whether to log it at
+// all. An event is held back only if every workload it fails for
+// has already had its failures escalated.
+func (x *Escalator) Escalate(e Event) (Event, bool) {
+	if x.Threshold <= 0 {
+		return e, true
+	}
+	failing, all := escalationFailures(e)
+	failed := map[string]bool{}
+	for _, s := range failing {
+		failed[escalationKey(e.Type, s)] = true
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.failures == nil {
+		x.failures = map[string]int{}
+	}
+	// Whatever the event concerns that didn't fail this time has
+	// recovered, so its run is over
+	if all {
+		for k := range x.failures {
+			if strings.HasPrefix(k, e.Type+"\n") && !failed[k] {
+				delete(x.failures, k)
+			}
+		}
+	} else {
+		for _, s := range escalationServices(e) {
+			if k := escalationKey(e.Type, s); !failed[k] {
+				delete(x.failures, k)
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return e, true
+	}
+
+	var escalate, fresh bool
+	for k := range failed {
+		x.failures[k]++
+		switch n := x.failures[k]; {
+		case n == x.Threshold:
+			escalate = true
+		case n < x.Threshold:
+			fresh = true
+		}
+	}
+	if escalate {
+		e.LogLevel = LogLevelError
+		e.Failures = x.Threshold
+		return e, true
+	}
+	return e, fresh
+}
+
+// escalationFailures gives the workloads the event reports failures
+// for, and whether it speaks for every workload (as a sync does), so
+// that those it doesn't mention have not failed.
+func escalationFailures(e Event) ([]string, bool) {
+	switch m := e.Metadata.(type) {
+	case *SyncEventMetadata:
+		var failing []string
+		for _, re := range m.Errors {
+			failing = append(failing, re.ID.String())
+		}
+		return failing, true
+	case *AutoReleaseEventMetadata:
+		if m.Error != "" {
+			return escalationServices(e), false
+		}
+	}
+	if e.LogLevel == LogLevelWarn || e.LogLevel == LogLevelError {
+		return escalationServices(e), false
+	}
+	return nil, false
+}
+
+// escalationServices gives the workloads the event concerns; events
+// concerning none are counted as if for one workload of their own.
+func escalationServices(e Event) []string {
+	if ids := e.ServiceIDStrings(); len(ids) > 0 {
+		return ids
+	}
+	return []string{""}
+}
+
+func escalationKey(eventType, service string) string {
+	return eventType + "\n" + service
+}
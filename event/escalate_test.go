@@ -0,0 +1,85 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestEscalator(t *testing.T) {
+	x := &Escalator{Threshold: 3}
+
+	var logged []Event
+	escalate := func(e Event) {
+		if e, ok := x.Escalate(e); ok {
+			logged = append(logged, e)
+		}
+	}
+
+	// The first failures go through as they are, the third is
+	// escalated, and those after are held back
+	for i := 0; i < 6; i++ {
+		escalate(syncEvent("bad manifest"))
+	}
+	if len(logged) != 3 {
+		t.Fatalf("expected three of six failures logged, got %d", len(logged))
+	}
+	if logged[1].LogLevel != LogLevelInfo || logged[1].Failures != 0 {
+		t.Errorf("expected the second failure as it was, got %+v", logged[1])
+	}
+	if logged[2].LogLevel != LogLevelError || logged[2].Failures != 3 {
+		t.Errorf("expected the third failure escalated, got %+v", logged[2])
+	}
+
+	// Other kinds of event aren't held back
+	escalate(Event{Type: EventCommit, LogLevel: LogLevelInfo})
+	if len(logged) != 4 {
+		t.Fatalf("expected an event that isn't a failure to be logged, got %d events", len(logged))
+	}
+
+	// Once the sync succeeds, the run is over
+	escalate(syncEvent())
+	escalate(syncEvent("bad manifest"))
+	if len(logged) != 6 || logged[5].Failures != 0 {
+		t.Errorf("expected a new run of failures to start, got %+v", logged)
+	}
+}
+
+func TestEscalator_ByWorkload(t *testing.T) {
+	x := &Escalator{Threshold: 2}
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("default:deployment/bar")
+	warn := func(ids ...flux.ResourceID) Event {
+		return Event{Type: EventAutomationDeferred, LogLevel: LogLevelWarn, ServiceIDs: ids}
+	}
+
+	for _, e := range []Event{warn(foo), warn(foo)} {
+		x.Escalate(e)
+	}
+	if _, ok := x.Escalate(warn(foo)); ok {
+		t.Errorf("expected further failures for foo to be held back")
+	}
+	// bar's failures are its own, so this is fresh for bar
+	e, ok := x.Escalate(warn(foo, bar))
+	if !ok || e.Failures != 0 {
+		t.Errorf("expected the first failure for bar to be logged as it is, got %+v, %v", e, ok)
+	}
+	// foo recovering doesn't end bar's run
+	x.Escalate(Event{Type: EventAutomationDeferred, LogLevel: LogLevelInfo, ServiceIDs: []flux.ResourceID{foo}})
+	e, ok = x.Escalate(warn(bar))
+	if !ok || e.Failures != 2 {
+		t.Errorf("expected bar's second failure to be escalated, got %+v, %v", e, ok)
+	}
+	e, ok = x.Escalate(warn(foo))
+	if !ok || e.Failures != 0 {
+		t.Errorf("expected foo to start a new run, got %+v, %v", e, ok)
+	}
+}
+
+func TestEscalatedString(t *testing.T) {
+	e := syncEvent("bad manifest")
+	e.Failures = 10
+	if s := e.String(); s != "Sync: <no revision>, no services changed, 1 errors (failed 10 times in a row)" {
+		t.Errorf("unexpected message %q", s)
+	}
+}
@@ -1,15 +1,15 @@
 package event
 
 import (
-	"fmt"
 	"sort"
-	"strings"
 	"time"
 
 	"encoding/json"
 	"errors"
 
 	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/update"
 )
 
@@ -24,6 +24,46 @@ const (
 	EventLock         = "lock"
 	EventUnlock       = "unlock"
 	EventUpdatePolicy = "update_policy"
+	EventAccessDenied = "access_denied"
+	EventAudit        = "audit"
+	EventStaleImage   = "stale_image"
+	EventFreeze       = "freeze"
+	// An automated release that would have been made, if automation
+	// weren't only being observed
+	EventObservedRelease = "observed_release"
+	// A sync held back because it would change too much, until
+	// it's confirmed
+	EventSyncHeld = "sync_held"
+	// A sync refused, because a commit to be synced isn't signed by
+	// a trusted key
+	EventSyncUnverified = "sync_unverified"
+	// The history of the git repo rewritten (e.g., force-pushed)
+	// since it was last synced
+	EventSyncRewrite = "sync_rewrite"
+	// The daemon starting and stopping
+	EventDaemonStart = "daemon_start"
+	EventDaemonStop  = "daemon_stop"
+	// Automation put off for a while, because the daemon or cluster
+	// is busy
+	EventAutomationDeferred = "automation_deferred"
+	// Automation turned off for a workload, because its automated
+	// releases kept failing or being rolled back
+	EventAutomationSuspended = "automation_suspended"
+	// A release undone, by setting the images it changed back to
+	// what they were
+	EventRollback = "rollback"
+	// The image registry scanned for new images for automated
+	// workloads
+	EventImagePolled = "image_polled"
+	// Fields flux applies that something else keeps changing in the
+	// cluster
+	EventSyncConflict = "sync_conflict"
+	// Workloads restarted, rolling out new pods without changing
+	// their images or anything else
+	EventRestart = "restart"
+	// The head of the chain of hashes recorded by the events kept,
+	// given so it can be kept somewhere other than the event store
+	EventChainHead = "event_chain_head"
 
 	// This is used to label e.g., commits that we _don't_ consider an event in themselves.
 	NoneOfTheAbove = "other"
@@ -32,6 +72,13 @@ const (
 	LogLevelInfo  = "info"
 	LogLevelWarn  = "warn"
 	LogLevelError = "error"
+
+	// How critical the workloads affected by an event are, as set by
+	// the workloads themselves; this may raise or lower the log level
+	// of the event, and decide how it's notified
+	CriticalityLow    = "low"
+	CriticalityMedium = "medium"
+	CriticalityHigh   = "high"
 )
 
 type EventID int64
@@ -43,6 +90,20 @@ type Event struct {
 	// ServiceIDs affected by this event.
 	ServiceIDs []flux.ResourceID `json:"serviceIDs"`
 
+	// Namespace is the namespace the event belongs to: that of the
+	// services affected, if they are all in one, or as given by
+	// whoever logged the event. Events across namespaces, or
+	// concerning the cluster as a whole, have none.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Owners are the teams owning the services affected, where
+	// known, so the event can be routed to them.
+	Owners []string `json:"owners,omitempty"`
+
+	// Criticality is the highest criticality of the services
+	// affected, where it's known.
+	Criticality string `json:"criticality,omitempty"`
+
 	// Type is the type of event, usually "release" for now, but could be other
 	// things later
 	Type string `json:"type"`
@@ -66,6 +127,63 @@ type Event struct {
 	// Metadata is Event.Type-specific metadata. If an event has no metadata,
 	// this will be nil.
 	Metadata EventMetadata `json:"metadata,omitempty"`
+
+	// Repeated is the number of events like this one that were
+	// suppressed (see Throttle) since the last one was sent; or, for
+	// an event kept in a store, the number of repeats coalesced into
+	// it (see Recorder).
+	Repeated int `json:"repeated,omitempty"`
+
+	// LastSeen is when the last repeat coalesced into this event
+	// started, if there were any.
+	LastSeen time.Time `json:"lastSeen,omitempty"`
+
+	// Failures is, for an event escalated to an error (see
+	// Escalator), the number of failures in a row it stands for.
+	Failures int `json:"failures,omitempty"`
+
+	// PrevHash is the hash of the event logged before this one, if
+	// the store keeps a chain of them (see ChainStore).
+	PrevHash string `json:"prevHash,omitempty"`
+
+	// Annotations are comments (and acknowledgements) that people
+	// have attached to the event since it was logged.
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	// CorrelationID ties together the events for one change: it's
+	// the ID of the job that made the change, given to the commit
+	// event for the job, the release (or rollback) event once the
+	// commit is synced, and the sync event if that's the only change
+	// it synced.
+	CorrelationID string `json:"correlationID,omitempty"`
+
+	// TraceID and SpanID locate the span the event was logged in, in
+	// the W3C trace context format, if the daemon is sending traces;
+	// so the event can be looked up alongside the traces of the
+	// applications it concerns.
+	TraceID string `json:"traceID,omitempty"`
+	SpanID  string `json:"spanID,omitempty"`
+}
+
+// Annotation is a comment on an event, e.g., to say that a failure
+// was expected because of maintenance.
+type Annotation struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	// Acknowledged is true if the annotation acknowledges the event,
+	// i.e., says that someone has seen to it
+	Acknowledged bool `json:"acknowledged,omitempty"`
+}
+
+// Acknowledged says whether any annotation acknowledges the event.
+func (e Event) Acknowledged() bool {
+	for _, a := range e.Annotations {
+		if a.Acknowledged {
+			return true
+		}
+	}
+	return false
 }
 
 type EventWriter interface {
@@ -82,90 +200,42 @@ func (e Event) ServiceIDStrings() []string {
 	return strServiceIDs
 }
 
-func (e Event) String() string {
-	if e.Message != "" {
-		return e.Message
+// WorkloadNamespace gives the namespace the workloads given are all
+// in, or the empty string if they are in more than one (or there are
+// none).
+func WorkloadNamespace(ids []flux.ResourceID) string {
+	var namespace string
+	for i, id := range ids {
+		ns, _, _ := id.Components()
+		if i > 0 && ns != namespace {
+			return ""
+		}
+		namespace = ns
 	}
+	return namespace
+}
 
-	strServiceIDs := e.ServiceIDStrings()
-	switch e.Type {
-	case EventRelease:
-		metadata := e.Metadata.(*ReleaseEventMetadata)
-		strImageIDs := metadata.Result.ChangedImages()
-		if len(strImageIDs) == 0 {
-			strImageIDs = []string{"no image changes"}
-		}
-		for _, spec := range metadata.Spec.ServiceSpecs {
-			if spec == update.ResourceSpecAll {
-				strServiceIDs = []string{"all services"}
-				break
-			}
-		}
-		if len(strServiceIDs) == 0 {
-			strServiceIDs = []string{"no services"}
-		}
-		var user string
-		if metadata.Cause.User != "" {
-			user = fmt.Sprintf(", by %s", metadata.Cause.User)
-		}
-		var msg string
-		if metadata.Cause.Message != "" {
-			msg = fmt.Sprintf(", with message %q", metadata.Cause.Message)
-		}
-		return fmt.Sprintf(
-			"Released: %s to %s%s%s",
-			strings.Join(strImageIDs, ", "),
-			strings.Join(strServiceIDs, ", "),
-			user,
-			msg,
-		)
-	case EventAutoRelease:
-		metadata := e.Metadata.(*AutoReleaseEventMetadata)
-		strImageIDs := metadata.Result.ChangedImages()
-		if len(strImageIDs) == 0 {
-			strImageIDs = []string{"no image changes"}
-		}
-		return fmt.Sprintf(
-			"Automated release of %s",
-			strings.Join(strImageIDs, ", "),
-		)
-	case EventCommit:
-		metadata := e.Metadata.(*CommitEventMetadata)
-		svcStr := "<no changes>"
-		if len(strServiceIDs) > 0 {
-			svcStr = strings.Join(strServiceIDs, ", ")
+// InNamespace says whether the event belongs to the namespace given:
+// if it was logged in the namespace, or concerns a service in it.
+func (e Event) InNamespace(namespace string) bool {
+	if e.Namespace == namespace {
+		return true
+	}
+	for _, id := range e.ServiceIDs {
+		if ns, _, _ := id.Components(); ns == namespace {
+			return true
 		}
-		return fmt.Sprintf("Commit: %s, %s", shortRevision(metadata.Revision), svcStr)
-	case EventSync:
-		metadata := e.Metadata.(*SyncEventMetadata)
-		revStr := "<no revision>"
-		if 0 < len(metadata.Commits) && len(metadata.Commits) <= 2 {
-			revStr = shortRevision(metadata.Commits[0].Revision)
-		} else if len(metadata.Commits) > 2 {
-			revStr = fmt.Sprintf(
-				"%s..%s",
-				shortRevision(metadata.Commits[len(metadata.Commits)-1].Revision),
-				shortRevision(metadata.Commits[0].Revision),
-			)
-		}
-		svcStr := "no services changed"
-		if len(strServiceIDs) > 0 {
-			svcStr = strings.Join(strServiceIDs, ", ")
-		}
-		return fmt.Sprintf("Sync: %s, %s", revStr, svcStr)
-	case EventAutomate:
-		return fmt.Sprintf("Automated: %s", strings.Join(strServiceIDs, ", "))
-	case EventDeautomate:
-		return fmt.Sprintf("Deautomated: %s", strings.Join(strServiceIDs, ", "))
-	case EventLock:
-		return fmt.Sprintf("Locked: %s", strings.Join(strServiceIDs, ", "))
-	case EventUnlock:
-		return fmt.Sprintf("Unlocked: %s", strings.Join(strServiceIDs, ", "))
-	case EventUpdatePolicy:
-		return fmt.Sprintf("Updated policies: %s", strings.Join(strServiceIDs, ", "))
-	default:
-		return fmt.Sprintf("Unknown event: %s", e.Type)
 	}
+	return false
+}
+
+// String renders the event with the default templates; see Renderer.
+func (e Event) String() string {
+	msg, err := defaultRenderer.Render(e)
+	if err != nil {
+		return err.Error()
+	}
+	return msg
 }
 
 func shortRevision(rev string) string {
@@ -180,6 +250,12 @@ type CommitEventMetadata struct {
 	Revision string        `json:"revision,omitempty"`
 	Spec     *update.Spec  `json:"spec"`
 	Result   update.Result `json:"result,omitempty"`
+	// The fingerprint of the GPG key the commit was signed with, if
+	// flux signs its commits
+	SigningKey string `json:"signingKey,omitempty"`
+	// The URL of the pull request opened for the commit, if it was
+	// pushed to a branch of its own rather than the branch synced
+	PullRequest string `json:"pullRequest,omitempty"`
 }
 
 func (c CommitEventMetadata) ShortRevision() string {
@@ -191,8 +267,11 @@ func (c CommitEventMetadata) ShortRevision() string {
 // anyway represent coupling (of an internal API to serialised data)
 // that we don't want.
 type Commit struct {
-	Revision string `json:"revision"`
-	Message  string `json:"message"`
+	Revision string    `json:"revision"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time,omitempty"`
+	// The correlation ID of the change, for commits flux made
+	CorrelationID string `json:"correlationID,omitempty"`
 }
 
 type ResourceError struct {
@@ -214,6 +293,11 @@ type SyncEventMetadata struct {
 	Errors []ResourceError `json:"errors,omitempty"`
 	// `true` if we have no record of having synced before
 	InitialSync bool `json:"initialSync,omitempty"`
+	// `true` if the previous sync had errors, and this one doesn't
+	Recovered bool `json:"recovered,omitempty"`
+	// `true` if the commits were marked to be skipped, and so are
+	// recorded but not (yet) applied
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 // Account for old events, which used the revisions field rather than commits
@@ -237,6 +321,34 @@ type ReleaseEventCommon struct {
 	Result   update.Result `json:"result"`
 	// Message of the error if there was one.
 	Error string `json:"error,omitempty"`
+	// Where to find the SBOMs for the images released, if recorded
+	SBOMs []SBOM `json:"sboms,omitempty"`
+	// The outcome of verifying signatures for the images released,
+	// if they were verified
+	Verifications []ImageVerification `json:"verifications,omitempty"`
+	// Whether the cluster had room for the pods started by the
+	// release, if that was checked
+	Capacity *cluster.CapacityReport `json:"capacity,omitempty"`
+}
+
+// ImageVerification records the outcome of checking the signatures
+// and attestations of an image.
+type ImageVerification struct {
+	Image        string `json:"image"`
+	Digest       string `json:"digest,omitempty"`
+	Verified     bool   `json:"verified"`
+	Signatures   int    `json:"signatures,omitempty"`
+	Attestations int    `json:"attestations,omitempty"`
+	// Output from the verifier, e.g., why verification failed
+	Output string `json:"output,omitempty"`
+}
+
+// SBOM refers to the software bill of materials for a released image.
+type SBOM struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	// Ref is the image reference under which the SBOM is stored
+	Ref string `json:"ref"`
 }
 
 // ReleaseEventMetadata is the metadata for when service(s) are released
@@ -253,6 +365,251 @@ type AutoReleaseEventMetadata struct {
 	Spec update.Automated `json:"spec"`
 }
 
+// RollbackEventMetadata is for when a release is rolled back. The
+// revision (in ReleaseEventCommon) is that of the commit setting the
+// images back, i.e., the revision that's the target of the rollback.
+type RollbackEventMetadata struct {
+	ReleaseEventCommon
+	// The event recording the release rolled back, and the revision
+	// at which it was committed
+	ReleaseID       EventID      `json:"releaseID"`
+	ReleaseRevision string       `json:"releaseRevision"`
+	Reason          string       `json:"reason,omitempty"`
+	Cause           update.Cause `json:"cause"`
+}
+
+// AccessDeniedEventMetadata is for when an API request is refused
+// because the identity making it isn't allowed to do so.
+type AccessDeniedEventMetadata struct {
+	User   string `json:"user"`
+	Verb   string `json:"verb"`
+	Method string `json:"method"`
+}
+
+// AuditEventMetadata is a record of an API call.
+type AuditEventMetadata struct {
+	User    string   `json:"user"`
+	Method  string   `json:"method"`
+	Verbs   []string `json:"verbs"`
+	Targets []string `json:"targets,omitempty"`
+	Result  string   `json:"result"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// StaleImageEventMetadata is for when workloads have been running
+// images older than the age allowed, though there are newer images
+// matching their tag filters.
+type StaleImageEventMetadata struct {
+	Images []StaleImage `json:"images"`
+}
+
+// StaleImage describes a container running a stale image.
+type StaleImage struct {
+	ID        flux.ResourceID `json:"id"`
+	Container string          `json:"container"`
+	Current   string          `json:"current"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Latest    string          `json:"latest"`
+	LatestAt  time.Time       `json:"latestAt"`
+	// How many images matching the tag filter are newer than Current
+	Newer int `json:"newer"`
+	// The team owning the workload, if known
+	Owner string `json:"owner,omitempty"`
+}
+
+// ObservedReleaseEventMetadata is for what would have been released
+// automatically, to workloads whose automation is only observed.
+type ObservedReleaseEventMetadata struct {
+	Spec   update.Automated `json:"spec"`
+	Result update.Result    `json:"result,omitempty"`
+}
+
+// SyncHeldEventMetadata is for when a sync is held back by the sync
+// guardrails, because it would change too much.
+type SyncHeldEventMetadata struct {
+	Revision string `json:"revision"`
+	Reason   string `json:"reason"`
+	// How many resources would be added or changed, and how many
+	// have been removed from the repo
+	Changed int `json:"changed"`
+	Deleted int `json:"deleted"`
+}
+
+// SyncUnverifiedEventMetadata is for when a sync is refused, because
+// a commit to be synced isn't signed by one of the keys trusted.
+type SyncUnverifiedEventMetadata struct {
+	// The git repo, and the commit that couldn't be verified
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+	Reason   string `json:"reason"`
+	// The key the commit was signed with, if it was signed
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// SyncRewriteEventMetadata is for when the history of the git repo
+// was rewritten since the last sync, e.g., by a force-push, so the
+// revision last synced is no longer in the history of the one synced.
+type SyncRewriteEventMetadata struct {
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	// The revision last synced, and the revision synced now
+	From string `json:"from"`
+	To   string `json:"to"`
+	// The commits that were synced, but aren't in the history any
+	// more, newest first
+	Dropped []Commit `json:"dropped,omitempty"`
+}
+
+// FreezeEventMetadata is for when a release freeze starts or is over.
+type FreezeEventMetadata struct {
+	Reason string    `json:"reason"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// False when the freeze has just started, and true once it's
+	// over
+	Over bool `json:"over,omitempty"`
+}
+
+// DaemonStartEventMetadata is for when the daemon starts. The
+// previous version and configuration are those of the daemon's last
+// run, if they are known.
+type DaemonStartEventMetadata struct {
+	Version              string `json:"version"`
+	PreviousVersion      string `json:"previousVersion,omitempty"`
+	ConfigDigest         string `json:"configDigest"`
+	PreviousConfigDigest string `json:"previousConfigDigest,omitempty"`
+	// The configuration options that have changed since the last run
+	ChangedConfig []string `json:"changedConfig,omitempty"`
+}
+
+// ConfigChanged says whether the daemon was started with a different
+// configuration to its last run.
+func (m DaemonStartEventMetadata) ConfigChanged() bool {
+	return m.PreviousConfigDigest != "" && m.PreviousConfigDigest != m.ConfigDigest
+}
+
+// DaemonStopEventMetadata is for when the daemon stops.
+type DaemonStopEventMetadata struct {
+	Version      string `json:"version"`
+	ConfigDigest string `json:"configDigest"`
+	Reason       string `json:"reason"`
+}
+
+// AutomationDeferredEventMetadata is for when automation has been
+// deferred for a while.
+type AutomationDeferredEventMetadata struct {
+	// What's busy; e.g., the job queue
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// AutomationSuspendedEventMetadata is for when automation has been
+// turned off for a workload, because its automated releases failed
+// (or were rolled back) too often.
+type AutomationSuspendedEventMetadata struct {
+	Failures int           `json:"failures"`
+	Window   time.Duration `json:"window"`
+	// Why the last of the failures counted; e.g., that it was
+	// rolled back
+	Reason string `json:"reason"`
+}
+
+// ImagePolledEventMetadata is for a scan of the image registry for
+// new images for automated workloads.
+type ImagePolledEventMetadata struct {
+	Repositories []PolledRepository `json:"repositories"`
+	// How long it took to get the images in all the repositories
+	Duration time.Duration `json:"duration"`
+}
+
+// PolledRepository is what was found in one image repository.
+type PolledRepository struct {
+	Name string `json:"name"`
+	// How many tags there are
+	Tags int `json:"tags"`
+	// The tags there weren't when the repository was last polled
+	// (none, the first time it's polled)
+	NewTags []string `json:"newTags,omitempty"`
+}
+
+// SyncConflictEventMetadata is for when fields flux applies were
+// found changed in the cluster by something else, which flux will
+// keep setting back.
+type SyncConflictEventMetadata struct {
+	Conflicts []cluster.SyncConflict `json:"conflicts"`
+}
+
+// RestartEventMetadata is for when workloads are restarted, i.e.,
+// have new pods rolled out for them, without anything about them
+// being changed; e.g., after a config map or secret they use is
+// changed.
+type RestartEventMetadata struct {
+	Result update.Result `json:"result"`
+	Reason string        `json:"reason,omitempty"`
+	Cause  update.Cause  `json:"cause"`
+}
+
+// ChainHeadEventMetadata is the head of the chain of event hashes
+// (see ChainStore): the most recent event logged, and its hash.
+type ChainHeadEventMetadata struct {
+	ID   EventID `json:"id"`
+	Hash string  `json:"hash"`
+}
+
+// PolicyUpdateEventMetadata is for when the policies of workloads
+// are changed -- automated, locked, tag filters and so on -- whether
+// the event is for automating, locking, or any other change. It has
+// the policies each workload had before and after the change.
+type PolicyUpdateEventMetadata struct {
+	// The revision at which the change was committed
+	Revision string         `json:"revision,omitempty"`
+	Changes  []PolicyChange `json:"changes"`
+	Cause    update.Cause   `json:"cause"`
+}
+
+// PolicyChange is the policies of one workload, before and after a
+// change.
+type PolicyChange struct {
+	ID     flux.ResourceID `json:"id"`
+	Before policy.Set      `json:"before"`
+	After  policy.Set      `json:"after"`
+}
+
+// Added gives the policies that weren't there before the change, or
+// have a different value after it.
+func (c PolicyChange) Added() policy.Set {
+	added := policy.Set{}
+	for p, v := range c.After {
+		if before, ok := c.Before.Get(p); !ok || before != v {
+			added[p] = v
+		}
+	}
+	return added
+}
+
+// Removed gives the policies that were there before the change, and
+// aren't after it.
+func (c PolicyChange) Removed() policy.Set {
+	removed := policy.Set{}
+	for p, v := range c.Before {
+		if _, ok := c.After.Get(p); !ok {
+			removed[p] = v
+		}
+	}
+	return removed
+}
+
+// LockEventMetadata is for when workloads are locked or unlocked. As
+// well as the policies before and after, it has who locked (or
+// unlocked) them, why, and the revision they're meant to be locked
+// until, if one was given.
+type LockEventMetadata struct {
+	PolicyUpdateEventMetadata
+	User          string `json:"user,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	UntilRevision string `json:"untilRevision,omitempty"`
+}
+
 type UnknownEventMetadata map[string]interface{}
 
 func (e *Event) UnmarshalJSON(in []byte) error {
@@ -288,6 +645,13 @@ func (e *Event) UnmarshalJSON(in []byte) error {
 		}
 		e.Metadata = &metadata
 		break
+	case EventRollback:
+		var metadata RollbackEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
 	case EventCommit:
 		var metadata CommitEventMetadata
 		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
@@ -302,14 +666,156 @@ func (e *Event) UnmarshalJSON(in []byte) error {
 		}
 		e.Metadata = &metadata
 		break
-	default:
+	case EventAccessDenied:
+		var metadata AccessDeniedEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventAudit:
+		var metadata AuditEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventStaleImage:
+		var metadata StaleImageEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventObservedRelease:
+		var metadata ObservedReleaseEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventSyncHeld:
+		var metadata SyncHeldEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventSyncUnverified:
+		var metadata SyncUnverifiedEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventSyncRewrite:
+		var metadata SyncRewriteEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventFreeze:
+		var metadata FreezeEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventDaemonStart:
+		var metadata DaemonStartEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventDaemonStop:
+		var metadata DaemonStopEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventAutomationDeferred:
+		var metadata AutomationDeferredEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventAutomationSuspended:
+		var metadata AutomationSuspendedEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventImagePolled:
+		var metadata ImagePolledEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventSyncConflict:
+		var metadata SyncConflictEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventRestart:
+		var metadata RestartEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventChainHead:
+		var metadata ChainHeadEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
+		}
+		e.Metadata = &metadata
+		break
+	case EventLock, EventUnlock:
+		// Lock events from before they had metadata have none
+		if len(wireEvent.MetadataBytes) > 0 {
+			var metadata LockEventMetadata
+			if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+				return err
+			}
+			e.Metadata = &metadata
+		}
+		break
+	case EventAutomate, EventDeautomate, EventUpdatePolicy:
+		// Policy events from before they had metadata have none
 		if len(wireEvent.MetadataBytes) > 0 {
-			var metadata UnknownEventMetadata
+			var metadata PolicyUpdateEventMetadata
 			if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
 				return err
 			}
+			e.Metadata = &metadata
+		}
+		break
+	default:
+		// Events of registered and unknown types may be logged
+		// without metadata
+		if len(wireEvent.MetadataBytes) == 0 {
+			break
+		}
+		if metadata, ok := registeredMetadata(wireEvent.Type); ok {
+			if err := json.Unmarshal(wireEvent.MetadataBytes, metadata); err != nil {
+				return err
+			}
 			e.Metadata = metadata
+			break
+		}
+		var metadata UnknownEventMetadata
+		if err := json.Unmarshal(wireEvent.MetadataBytes, &metadata); err != nil {
+			return err
 		}
+		e.Metadata = metadata
 	}
 
 	// By default, leave the Event Metadata as map[string]interface{}
@@ -341,8 +847,99 @@ func (rem *AutoReleaseEventMetadata) Type() string {
 	return EventAutoRelease
 }
 
+func (rem *RollbackEventMetadata) Type() string {
+	return EventRollback
+}
+
+func (adm *AccessDeniedEventMetadata) Type() string {
+	return EventAccessDenied
+}
+
+func (aem *AuditEventMetadata) Type() string {
+	return EventAudit
+}
+
+func (sem *StaleImageEventMetadata) Type() string {
+	return EventStaleImage
+}
+
+func (oem *ObservedReleaseEventMetadata) Type() string {
+	return EventObservedRelease
+}
+
+func (shm *SyncHeldEventMetadata) Type() string {
+	return EventSyncHeld
+}
+
+func (sum *SyncUnverifiedEventMetadata) Type() string {
+	return EventSyncUnverified
+}
+
+func (srm *SyncRewriteEventMetadata) Type() string {
+	return EventSyncRewrite
+}
+
+func (fem *FreezeEventMetadata) Type() string {
+	return EventFreeze
+}
+
+func (dsm *DaemonStartEventMetadata) Type() string {
+	return EventDaemonStart
+}
+
+func (dsm *DaemonStopEventMetadata) Type() string {
+	return EventDaemonStop
+}
+
+func (adm *AutomationDeferredEventMetadata) Type() string {
+	return EventAutomationDeferred
+}
+
+func (asm *AutomationSuspendedEventMetadata) Type() string {
+	return EventAutomationSuspended
+}
+
+func (ipm *ImagePolledEventMetadata) Type() string {
+	return EventImagePolled
+}
+
+func (scm *SyncConflictEventMetadata) Type() string {
+	return EventSyncConflict
+}
+
+func (rem *RestartEventMetadata) Type() string {
+	return EventRestart
+}
+
+func (chm *ChainHeadEventMetadata) Type() string {
+	return EventChainHead
+}
+
+func (pem *PolicyUpdateEventMetadata) Type() string {
+	return EventUpdatePolicy
+}
+
+func (lem *LockEventMetadata) Type() string {
+	return EventLock
+}
+
 // Special exception from pointer receiver rule, as UnknownEventMetadata is a
 // type alias for a map
 func (uem UnknownEventMetadata) Type() string {
 	return "unknown"
 }
+
+// Writers is an EventWriter that logs events to each of the writers
+// in it, returning the first error encountered (but continuing with
+// the others regardless).
+type Writers []EventWriter
+
+func (ws Writers) LogEvent(e Event) error {
+	var firstErr error
+	for _, w := range ws {
+		if err := w.LogEvent(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -3,7 +3,11 @@ package event
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/cluster"
+	"github.com/weaveworks/flux/policy"
 	"github.com/weaveworks/flux/update"
 )
 
@@ -60,3 +64,299 @@ func TestEvent_ParseNoMetadata(t *testing.T) {
 		t.Fatal("Hasn't been unmarshalled properly")
 	}
 }
+
+func TestEvent_ParseRollbackMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventRollback,
+		Metadata: &RollbackEventMetadata{
+			ReleaseEventCommon: ReleaseEventCommon{Revision: "def5678"},
+			ReleaseID:          42,
+			ReleaseRevision:    "abc1234",
+			Reason:             "errors after upgrade",
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*RollbackEventMetadata)
+	if !ok {
+		t.Fatalf("expected rollback metadata, got %#v", e.Metadata)
+	}
+	if metadata.ReleaseID != 42 || metadata.ReleaseRevision != "abc1234" || metadata.Reason != "errors after upgrade" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+}
+
+func TestEvent_ParseImagePolledMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventImagePolled,
+		Metadata: &ImagePolledEventMetadata{
+			Repositories: []PolledRepository{
+				{Name: "quay.io/weaveworks/helloworld", Tags: 12, NewTags: []string{"master-a000002"}},
+				{Name: "quay.io/weaveworks/sidecar", Tags: 3},
+			},
+			Duration: 1500 * time.Millisecond,
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*ImagePolledEventMetadata)
+	if !ok {
+		t.Fatalf("expected image polled metadata, got %#v", e.Metadata)
+	}
+	if len(metadata.Repositories) != 2 || metadata.Repositories[0].NewTags[0] != "master-a000002" || metadata.Duration != 1500*time.Millisecond {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	expected := "Polled 2 image repositories in 1.5s; new tags: quay.io/weaveworks/helloworld (master-a000002)"
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+func TestEvent_ParseSyncConflictMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventSyncConflict,
+		Metadata: &SyncConflictEventMetadata{
+			Conflicts: []cluster.SyncConflict{
+				{ID: flux.MustParseResourceID("default:deployment/hello"), Field: "spec.replicas", Applied: "3", Live: "5", Manager: "kube-controller-manager"},
+			},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*SyncConflictEventMetadata)
+	if !ok {
+		t.Fatalf("expected sync conflict metadata, got %#v", e.Metadata)
+	}
+	if len(metadata.Conflicts) != 1 || metadata.Conflicts[0] != origEvent.Metadata.(*SyncConflictEventMetadata).Conflicts[0] {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	expected := "Sync conflict: default:deployment/hello spec.replicas changed by kube-controller-manager (flux applies 3, cluster has 5)"
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+func TestEvent_ParseSyncRewriteMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventSyncRewrite,
+		Metadata: &SyncRewriteEventMetadata{
+			URL:     "git@github.com:example/config",
+			Branch:  "master",
+			From:    "a000001a000001a000001a000001a000001a0001",
+			To:      "b000002b000002b000002b000002b000002b0002",
+			Dropped: []Commit{{Revision: "a000001a000001a000001a000001a000001a0001", Message: "Oops"}},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*SyncRewriteEventMetadata)
+	if !ok {
+		t.Fatalf("expected sync rewrite metadata, got %#v", e.Metadata)
+	}
+	if metadata.From != "a000001a000001a000001a000001a000001a0001" || len(metadata.Dropped) != 1 || metadata.Dropped[0].Message != "Oops" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	expected := "History of git@github.com:example/config rewritten: a000001 is no longer in the history of b000002, dropping 1 commits"
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+func TestEvent_ParseSkippedSyncMetadata(t *testing.T) {
+	origEvent := Event{
+		Type: EventSync,
+		Metadata: &SyncEventMetadata{
+			Commits:  []Commit{{Revision: "a000001a000001a000001a000001a000001a0001", Message: "Scale down [flux skip]"}},
+			Includes: map[string]bool{NoneOfTheAbove: true},
+			Skipped:  true,
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*SyncEventMetadata)
+	if !ok {
+		t.Fatalf("expected sync metadata, got %#v", e.Metadata)
+	}
+	if !metadata.Skipped || !metadata.Includes[NoneOfTheAbove] || len(metadata.Commits) != 1 {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	expected := "Sync skipped: a000001, marked to skip"
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+func TestEvent_ParseRestartMetadata(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	origEvent := Event{
+		Type:       EventRestart,
+		ServiceIDs: []flux.ResourceID{id},
+		Metadata: &RestartEventMetadata{
+			Result: update.Result{id: update.ControllerResult{Status: update.ReleaseStatusSuccess}},
+			Reason: "rotated the database password",
+			Cause:  update.Cause{User: "jane"},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.Metadata.(*RestartEventMetadata); !ok {
+		t.Fatalf("expected restart metadata, got %#v", e.Metadata)
+	}
+	expected := `Restarted default:deployment/helloworld, by jane, because "rotated the database password"`
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+func TestEvent_ParsePolicyUpdateMetadata(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	origEvent := Event{
+		Type:       EventUpdatePolicy,
+		ServiceIDs: []flux.ResourceID{id},
+		Metadata: &PolicyUpdateEventMetadata{
+			Changes: []PolicyChange{{
+				ID:     id,
+				Before: policy.Set{policy.Locked: "true", policy.TagPrefix("greeter"): "glob:master-*"},
+				After:  policy.Set{policy.Locked: "true", policy.TagPrefix("greeter"): "semver:~1"},
+			}},
+			Cause: update.Cause{User: "jane"},
+		},
+	}
+
+	bytes, _ := json.Marshal(origEvent)
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*PolicyUpdateEventMetadata)
+	if !ok {
+		t.Fatalf("expected policy update metadata, got %#v", e.Metadata)
+	}
+	change := metadata.Changes[0]
+	if added := change.Added(); len(added) != 1 || added[policy.TagPrefix("greeter")] != "semver:~1" {
+		t.Errorf("expected just the tag filter to be changed, got %v", added)
+	}
+	if removed := change.Removed(); len(removed) != 0 {
+		t.Errorf("expected nothing to be removed, got %v", removed)
+	}
+	expected := `Updated policies: default:deployment/helloworld, by jane`
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+
+	// Policy events from before they had metadata still parse
+	bytes, _ = json.Marshal(Event{Type: EventLock, ServiceIDs: []flux.ResourceID{id}})
+	e = Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != "Locked: default:deployment/helloworld" {
+		t.Errorf("unexpected message %q", e.String())
+	}
+}
+
+func TestEvent_ParseLockMetadata(t *testing.T) {
+	id := flux.MustParseResourceID("default:deployment/helloworld")
+	bytes, _ := json.Marshal(Event{
+		Type:       EventLock,
+		ServiceIDs: []flux.ResourceID{id},
+		Metadata: &LockEventMetadata{
+			PolicyUpdateEventMetadata: PolicyUpdateEventMetadata{
+				Revision: "abc123",
+				Changes:  []PolicyChange{{ID: id, After: policy.Set{policy.Locked: "true"}}},
+			},
+			User:          "jane",
+			Reason:        "bad config, fix in review",
+			UntilRevision: "af4bf73e25a1c1b6",
+		},
+	})
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*LockEventMetadata)
+	if !ok {
+		t.Fatalf("expected lock metadata, got %#v", e.Metadata)
+	}
+	if metadata.Revision != "abc123" || len(metadata.Changes) != 1 {
+		t.Errorf("expected the policy changes to be kept, got %+v", metadata)
+	}
+	expected := `Locked: default:deployment/helloworld, by jane, because "bad config, fix in review", until af4bf73`
+	if e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+}
+
+type canaryEventMetadata struct {
+	Stage string `json:"stage"`
+}
+
+func (m *canaryEventMetadata) Type() string {
+	return "canary"
+}
+
+func TestEvent_ParseRegisteredMetadata(t *testing.T) {
+	RegisterEventType("canary", func() EventMetadata { return &canaryEventMetadata{} })
+	bytes, _ := json.Marshal(Event{Type: "canary", Metadata: &canaryEventMetadata{Stage: "10%"}})
+
+	e := Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatal(err)
+	}
+	metadata, ok := e.Metadata.(*canaryEventMetadata)
+	if !ok {
+		t.Fatalf("expected the registered metadata, got %#v", e.Metadata)
+	}
+	if metadata.Stage != "10%" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	// .. and one logged without metadata is read back without it
+	bytes, _ = json.Marshal(Event{Type: "canary"})
+	e = Event{}
+	if err := e.UnmarshalJSON(bytes); err != nil {
+		t.Fatalf("expected an event without metadata to be read, got %v", err)
+	}
+	if e.Type != "canary" || e.Metadata != nil {
+		t.Errorf("expected no metadata, got %#v", e)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering the type again to panic")
+		}
+	}()
+	RegisterEventType("canary", func() EventMetadata { return &canaryEventMetadata{} })
+}
@@ -0,0 +1,127 @@
+package event
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats events can be exported in
+const (
+	// One JSON object per line, as events are sent in the API
+	ExportNDJSON = "ndjson"
+	// One row per event, with the columns in ExportColumns
+	ExportCSV = "csv"
+)
+
+// ExportFormats are the formats events can be exported in.
+var ExportFormats = []string{ExportNDJSON, ExportCSV}
+
+// ExportColumns are the columns of events exported as CSV. The
+// metadata is given as JSON, since each type of event has its own.
+var ExportColumns = []string{"id", "type", "startedAt", "endedAt", "logLevel", "services", "correlationID", "message", "annotations", "metadata"}
+
+// ExportContentType gives the MIME type of events exported in the
+// format given.
+func ExportContentType(format string) string {
+	if format == ExportCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// ExportWriter writes events, one at a time, in one of the export
+// formats, e.g., for archiving the history kept by the daemon.
+type ExportWriter struct {
+	json   *json.Encoder
+	csv    *csv.Writer
+	header bool
+}
+
+// NewExportWriter makes a writer of events in the format given, or
+// returns an error if there's no such format.
+func NewExportWriter(w io.Writer, format string) (*ExportWriter, error) {
+	switch format {
+	case ExportNDJSON:
+		return &ExportWriter{json: json.NewEncoder(w)}, nil
+	case ExportCSV:
+		return &ExportWriter{csv: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q; expected one of %v", format, ExportFormats)
+}
+
+// Write writes the event; for CSV, after the header, if it's the
+// first event written.
+func (w *ExportWriter) Write(e Event) error {
+	if w.json != nil {
+		return w.json.Encode(e)
+	}
+	if !w.header {
+		if err := w.csv.Write(ExportColumns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	metadata := ""
+	if e.Metadata != nil {
+		bytes, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(bytes)
+	}
+	var annotations []string
+	for _, a := range e.Annotations {
+		annotations = append(annotations, annotationString(a))
+	}
+	return w.csv.Write([]string{
+		strconv.FormatInt(int64(e.ID), 10),
+		e.Type,
+		formatExportTime(e.StartedAt),
+		formatExportTime(e.EndedAt),
+		e.LogLevel,
+		strings.Join(e.ServiceIDStrings(), " "),
+		e.CorrelationID,
+		e.String(),
+		strings.Join(annotations, "; "),
+		metadata,
+	})
+}
+
+// Flush writes anything buffered, so that what's been written so far
+// can be sent on.
+func (w *ExportWriter) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// annotationString gives an annotation as, e.g., "acknowledged by
+// jane at 2018-06-13T16:02:11Z: expected".
+func annotationString(a Annotation) string {
+	s := "comment"
+	if a.Acknowledged {
+		s = "acknowledged"
+	}
+	if a.User != "" {
+		s += " by " + a.User
+	}
+	s += " at " + formatExportTime(a.Time)
+	if a.Comment != "" {
+		s += ": " + a.Comment
+	}
+	return s
+}
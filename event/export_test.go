@@ -0,0 +1,102 @@
+package event
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+func exportedEvents() []Event {
+	at := time.Date(2018, 6, 13, 16, 2, 11, 0, time.UTC)
+	return []Event{
+		{
+			ID:         2,
+			Type:       EventRestart,
+			ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("default:deployment/helloworld")},
+			StartedAt:  at,
+			EndedAt:    at,
+			LogLevel:   LogLevelInfo,
+			Metadata:   &RestartEventMetadata{Reason: "rotated, the password"},
+			Annotations: []Annotation{
+				{Time: at, User: "jane", Comment: "expected", Acknowledged: true},
+			},
+		},
+		{ID: 1, Type: EventDaemonStop, StartedAt: at, Message: "Daemon stopped: SIGTERM"},
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	w, err := NewExportWriter(out, ExportNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range exportedEvents() {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per event, got:\n%s", out)
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.Metadata.(*RestartEventMetadata); !ok || e.ID != 2 {
+		t.Errorf("expected the restart event back, got %+v", e)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	out := &bytes.Buffer{}
+	w, err := NewExportWriter(out, ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range exportedEvents() {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(ExportColumns, ",") {
+		t.Fatalf("expected a header and a row per event, got %q", rows)
+	}
+	restart := rows[1]
+	for i, expected := range []string{"2", EventRestart, "2018-06-13T16:02:11Z", "2018-06-13T16:02:11Z", LogLevelInfo, "default:deployment/helloworld", ""} {
+		if restart[i] != expected {
+			t.Errorf("expected %s to be %q, got %q", ExportColumns[i], expected, restart[i])
+		}
+	}
+	if restart[8] != "acknowledged by jane at 2018-06-13T16:02:11Z: expected" {
+		t.Errorf("unexpected annotations %q", restart[8])
+	}
+	if !strings.Contains(restart[9], `"reason":"rotated, the password"`) {
+		t.Errorf("expected the metadata as JSON, got %q", restart[9])
+	}
+	if rows[2][7] != "Daemon stopped: SIGTERM" || rows[2][9] != "" {
+		t.Errorf("unexpected row %q", rows[2])
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if _, err := NewExportWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
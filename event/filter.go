@@ -0,0 +1,126 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+// EventFilter selects events by what they are, rather than by where
+// they are in a store. The zero value selects every event.
+type EventFilter struct {
+	// If given, only events of these types
+	Types []string `json:"types,omitempty"`
+	// If given, only events concerning at least one of these
+	// workloads
+	Services []flux.ResourceID `json:"services,omitempty"`
+	// If given, only events belonging to one of these namespaces,
+	// or concerning at least one workload in them
+	Namespaces []string `json:"namespaces,omitempty"`
+	// If not zero, only events started at or after this time
+	Since time.Time `json:"since,omitempty"`
+	// If not zero, only events started before this time
+	Until time.Time `json:"until,omitempty"`
+	// If given, only events logged at this level or above (e.g.,
+	// warn includes errors)
+	MinLogLevel string `json:"minLogLevel,omitempty"`
+	// If given, only events for the change with this correlation ID,
+	// including syncs of its commit
+	CorrelationID string `json:"correlationID,omitempty"`
+}
+
+// The log levels, least severe first
+var logLevels = []string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}
+
+func logLevelRank(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	// Events without a level are treated as informative
+	return 1
+}
+
+// ValidateLogLevel returns an error if the level given is not a known
+// log level.
+func ValidateLogLevel(level string) error {
+	for _, l := range logLevels {
+		if l == level {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q; expected one of %v", level, logLevels)
+}
+
+// Matches says whether the event given is selected by the filter.
+func (f EventFilter) Matches(e Event) bool {
+	if len(f.Types) > 0 && !containsString(f.Types, e.Type) {
+		return false
+	}
+	if len(f.Services) > 0 && !concernsAny(e, f.Services) {
+		return false
+	}
+	if len(f.Namespaces) > 0 && !inAnyNamespace(e, f.Namespaces) {
+		return false
+	}
+	if !f.Since.IsZero() && e.StartedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.StartedAt.Before(f.Until) {
+		return false
+	}
+	if f.MinLogLevel != "" && logLevelRank(e.LogLevel) < logLevelRank(f.MinLogLevel) {
+		return false
+	}
+	if f.CorrelationID != "" && !correlatedWith(e, f.CorrelationID) {
+		return false
+	}
+	return true
+}
+
+// correlatedWith says whether the event is for the change with the
+// correlation ID given, or is a sync including its commit.
+func correlatedWith(e Event, id string) bool {
+	if e.CorrelationID == id {
+		return true
+	}
+	if metadata, ok := e.Metadata.(*SyncEventMetadata); ok {
+		for _, c := range metadata.Commits {
+			if c.CorrelationID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, each := range ss {
+		if each == s {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyNamespace(e Event, namespaces []string) bool {
+	for _, ns := range namespaces {
+		if e.InNamespace(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+func concernsAny(e Event, ids []flux.ResourceID) bool {
+	for _, s := range e.ServiceIDs {
+		for _, id := range ids {
+			if s == id {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestEventFilter(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("default:deployment/bar")
+	baz := flux.MustParseResourceID("other:deployment/baz")
+	now := time.Now().UTC()
+	b := &Buffer{}
+	for _, e := range []Event{
+		{Type: EventRelease, LogLevel: LogLevelError, ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-48 * time.Hour)},
+		{Type: EventRelease, LogLevel: LogLevelError, ServiceIDs: []flux.ResourceID{bar}, StartedAt: now.Add(-2 * time.Hour)},
+		{Type: EventSync, LogLevel: LogLevelError, ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-time.Hour)},
+		{Type: EventRelease, LogLevel: LogLevelInfo, ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-time.Minute)},
+		{Type: EventAutoRelease, LogLevel: LogLevelWarn, ServiceIDs: []flux.ResourceID{foo, bar}, StartedAt: now},
+		{Type: EventSync, LogLevel: LogLevelInfo, ServiceIDs: []flux.ResourceID{baz}, StartedAt: now},
+		{Type: EventCommit, LogLevel: LogLevelInfo, CorrelationID: "job1", StartedAt: now},
+		{Type: EventSync, LogLevel: LogLevelInfo, StartedAt: now, Metadata: &SyncEventMetadata{
+			Commits: []Commit{{Revision: "abc"}, {Revision: "def", CorrelationID: "job1"}},
+		}},
+		{Type: EventRollback, LogLevel: LogLevelInfo, ServiceIDs: []flux.ResourceID{foo}, CorrelationID: "job1", StartedAt: now},
+	} {
+		if err := b.LogEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(events []Event) []EventID {
+		var res []EventID
+		for _, e := range events {
+			res = append(res, e.ID)
+		}
+		return res
+	}
+	for _, c := range []struct {
+		name     string
+		page     Page
+		filter   EventFilter
+		expected []EventID
+	}{
+		{"release errors in the last day", Page{}, EventFilter{Types: []string{EventRelease}, Since: now.Add(-24 * time.Hour), MinLogLevel: LogLevelError}, []EventID{2}},
+		{"warnings and above", Page{}, EventFilter{MinLogLevel: LogLevelWarn}, []EventID{1, 2, 3, 5}},
+		{"until", Page{}, EventFilter{Until: now.Add(-time.Hour)}, []EventID{1, 2}},
+		{"services", Page{}, EventFilter{Services: []flux.ResourceID{bar}}, []EventID{2, 5}},
+		{"namespaces", Page{}, EventFilter{Namespaces: []string{"other"}}, []EventID{6}},
+		{"limit counts only matching events", Page{Limit: 2}, EventFilter{Types: []string{EventRelease}}, []EventID{2, 4}},
+		{"correlated, including syncs of the commit", Page{}, EventFilter{CorrelationID: "job1"}, []EventID{7, 8, 9}},
+	} {
+		events, err := b.AllEvents(c.page, c.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ids(events)
+		if len(got) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+				break
+			}
+		}
+	}
+
+	events, _ := b.EventsForService(bar, Page{}, EventFilter{Types: []string{EventAutoRelease}})
+	if got := ids(events); len(got) != 1 || got[0] != 5 {
+		t.Errorf("expected only the automated release of %s, got %v", bar, got)
+	}
+
+	if err := ValidateLogLevel("warn"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateLogLevel("fatal"); err == nil {
+		t.Error("expected an error for an unknown log level")
+	}
+}
@@ -0,0 +1,34 @@
+package event
+
+// MarkdownTemplates are used, in preference to DefaultTemplates, for
+// Event.MarkdownString. They use only code spans for formatting, which
+// Markdown and Slack's mrkdwn agree on.
+var MarkdownTemplates = map[string]string{
+	EventRelease: "Released {{with .Metadata.Result.ChangedImages}}{{code .}}{{else}}no image changes{{end}}" +
+		` to {{$to := code .ServiceIDStrings}}{{range .Metadata.Spec.ServiceSpecs}}{{if eq (print .) "<all>"}}{{$to = "all workloads"}}{{end}}{{end}}{{or $to "no workloads"}}` +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Cause.Message}}: {{.}}{{end}}`,
+	EventAutoRelease: "Automated release of {{with .Metadata.Result.ChangedImages}}{{code .}}{{else}}no image changes{{end}}",
+	EventRollback: "Rolled back release #{{.Metadata.ReleaseID}} ({{code (short .Metadata.ReleaseRevision)}}):" +
+		" {{with .Metadata.Result.ChangedImages}}{{code .}}{{else}}no image changes{{end}} to {{code .ServiceIDStrings}}" +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Reason}}, because {{.}}{{end}}`,
+	EventCommit: "Committed {{code (short .Metadata.Revision)}}{{with .ServiceIDStrings}}, changing {{code .}}{{end}}{{with .Metadata.PullRequest}}, for [a pull request]({{.}}){{end}}",
+	EventSync: "{{if .Metadata.Skipped}}Skipped sync of{{else}}Synced{{end}} {{with .Metadata.Commits}}{{if gt (len .) 2}}{{code (short (last .).Revision)}}..{{end}}{{code (short (index . 0).Revision)}}{{else}}the cluster{{end}}" +
+		"{{with .ServiceIDStrings}}, changing {{code .}}{{end}}" +
+		"{{with .Metadata.Errors}}, with {{len .}} errors:{{range .}}\n- {{code .ID}}: {{.Error}}{{end}}{{else}}{{if .Metadata.Recovered}}, errors resolved{{end}}{{end}}" +
+		"{{if gt .Repeated 0}}\n(repeated {{.Repeated}} times){{end}}",
+	EventRestart: "Restarted {{with .ServiceIDStrings}}{{code .}}{{else}}no workloads{{end}}" +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Reason}}, because {{.}}{{end}}{{with .Metadata.Result.Error}}; {{.}}{{end}}`,
+}
+
+var markdownRenderer = mustRenderer(NewRenderer(MarkdownTemplates))
+
+// MarkdownString renders the event as Markdown, e.g., for chat
+// integrations. Events without a Markdown template are given as for
+// String.
+func (e Event) MarkdownString() string {
+	msg, err := markdownRenderer.Render(e)
+	if err != nil {
+		return err.Error()
+	}
+	return msg
+}
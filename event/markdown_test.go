@@ -0,0 +1,85 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestMarkdownString(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	ref, _ := image.ParseRef("quay.io/example/app:1.1")
+	result := update.Result{
+		foo: update.ControllerResult{
+			Status:       update.ReleaseStatusSuccess,
+			PerContainer: []update.ContainerUpdate{{Container: "app", Target: ref}},
+		},
+	}
+	for _, c := range []struct {
+		event    Event
+		expected string
+	}{
+		{
+			Event{Type: EventRelease, ServiceIDs: []flux.ResourceID{foo}, Metadata: &ReleaseEventMetadata{
+				ReleaseEventCommon: ReleaseEventCommon{Result: result},
+				Cause:              update.Cause{User: "jane", Message: "fixes #12"},
+			}},
+			"Released `quay.io/example/app:1.1` to `default:deployment/foo`, by jane: fixes #12",
+		},
+		{
+			Event{Type: EventSync, ServiceIDs: []flux.ResourceID{foo}, Metadata: &SyncEventMetadata{
+				Commits: []Commit{{Revision: "a1b2c3d4e5f6"}},
+				Errors:  []ResourceError{{ID: foo, Error: "invalid"}},
+			}},
+			"Synced `a1b2c3d`, changing `default:deployment/foo`, with 1 errors:\n- `default:deployment/foo`: invalid",
+		},
+		{
+			Event{Type: EventSync, Metadata: &SyncEventMetadata{
+				Commits: []Commit{{Revision: "a1b2c3d4e5f6"}},
+				Skipped: true,
+			}},
+			"Skipped sync of `a1b2c3d`",
+		},
+		// No Markdown template, so the same as String
+		{
+			Event{Type: EventLock, ServiceIDs: []flux.ResourceID{foo}},
+			"Locked: default:deployment/foo",
+		},
+	} {
+		if got := c.event.MarkdownString(); got != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, got)
+		}
+	}
+}
+
+func TestSlackBlocks(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("default:deployment/bar")
+	e := Event{
+		ID:         7,
+		Type:       EventRestart,
+		ServiceIDs: []flux.ResourceID{foo},
+		LogLevel:   LogLevelError,
+		StartedAt:  time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC),
+		Metadata: &RestartEventMetadata{Result: update.Result{
+			foo: update.ControllerResult{Status: update.ReleaseStatusSuccess},
+			bar: update.ControllerResult{Status: update.ReleaseStatusFailed, Error: "not found"},
+		}},
+	}
+	text := mrkdwn(e.MarkdownString())
+	expected := []SlackBlock{
+		{Type: "section", Text: &text},
+		{Type: "section", Fields: []SlackText{
+			mrkdwn("`default:deployment/bar`\nfailed: not found"),
+			mrkdwn("`default:deployment/foo`\nsuccess"),
+		}},
+		{Type: "context", Elements: []SlackText{mrkdwn("restart event #7, error, at 2018-09-01T12:00:00Z")}},
+	}
+	if got := e.SlackBlocks(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+}
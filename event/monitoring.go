@@ -0,0 +1,182 @@
+package event
+
+// Monitoring middleware for event stores
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/weaveworks/flux"
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+)
+
+var (
+	eventsLogged = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "event",
+		Name:      "logged_total",
+		Help:      "Count of events logged to the event store, by type of event.",
+	}, []string{fluxmetrics.LabelEventType, fluxmetrics.LabelSuccess})
+
+	storeDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "flux",
+		Subsystem: "event",
+		Name:      "store_duration_seconds",
+		Help:      "Duration of event store reads and writes, in seconds.",
+		Buckets:   stdprometheus.DefBuckets,
+	}, []string{fluxmetrics.LabelMethod, fluxmetrics.LabelSuccess})
+
+	storeErrors = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "event",
+		Name:      "store_errors_total",
+		Help:      "Count of event store reads and writes that failed.",
+	}, []string{fluxmetrics.LabelMethod})
+)
+
+// InstrumentStore wraps the store so that each read and write is
+// recorded in metrics. If the store is a Pruner or a Recorder, so is
+// the store returned.
+func InstrumentStore(next EventStore) EventStore {
+	s := &instrumentedStore{next: next}
+	pruner, canPrune := next.(Pruner)
+	recorder, canRecord := next.(Recorder)
+	switch {
+	case canPrune && canRecord:
+		return &instrumentedPruningRecordingStore{s, instrumentedPruner{pruner}, instrumentedRecorder{recorder}}
+	case canPrune:
+		return &instrumentedPruningStore{s, instrumentedPruner{pruner}}
+	case canRecord:
+		return &instrumentedRecordingStore{s, instrumentedRecorder{recorder}}
+	}
+	return s
+}
+
+type instrumentedStore struct {
+	next EventStore
+}
+
+func observeStore(method string, begin time.Time, err error) {
+	storeDuration.With(
+		fluxmetrics.LabelMethod, method,
+		fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+	).Observe(time.Since(begin).Seconds())
+	if err != nil {
+		storeErrors.With(fluxmetrics.LabelMethod, method).Add(1)
+	}
+}
+
+func (s *instrumentedStore) LogEvent(e Event) (err error) {
+	defer func(begin time.Time) {
+		observeStore("LogEvent", begin, err)
+		eventsLogged.With(
+			fluxmetrics.LabelEventType, e.Type,
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Add(1)
+	}(time.Now())
+	return s.next.LogEvent(e)
+}
+
+func (s *instrumentedStore) LogEvents(events []Event) (err error) {
+	defer func(begin time.Time) {
+		observeStore("LogEvents", begin, err)
+		for _, e := range events {
+			eventsLogged.With(
+				fluxmetrics.LabelEventType, e.Type,
+				fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+			).Add(1)
+		}
+	}(time.Now())
+	return s.next.LogEvents(events)
+}
+
+func (s *instrumentedStore) AllEvents(page Page, filter EventFilter) (_ []Event, err error) {
+	defer func(begin time.Time) {
+		observeStore("AllEvents", begin, err)
+	}(time.Now())
+	return s.next.AllEvents(page, filter)
+}
+
+func (s *instrumentedStore) EventsForService(id flux.ResourceID, page Page, filter EventFilter) (_ []Event, err error) {
+	defer func(begin time.Time) {
+		observeStore("EventsForService", begin, err)
+	}(time.Now())
+	return s.next.EventsForService(id, page, filter)
+}
+
+func (s *instrumentedStore) EventsForNamespace(namespace string, page Page, filter EventFilter) (_ []Event, err error) {
+	defer func(begin time.Time) {
+		observeStore("EventsForNamespace", begin, err)
+	}(time.Now())
+	return s.next.EventsForNamespace(namespace, page, filter)
+}
+
+func (s *instrumentedStore) GetEvent(id EventID) (_ Event, err error) {
+	defer func(begin time.Time) {
+		// Asking for an event that isn't kept is not the store failing
+		if err == ErrNoSuchEvent {
+			observeStore("GetEvent", begin, nil)
+			return
+		}
+		observeStore("GetEvent", begin, err)
+	}(time.Now())
+	return s.next.GetEvent(id)
+}
+
+func (s *instrumentedStore) AnnotateEvent(id EventID, a Annotation) (_ Event, err error) {
+	defer func(begin time.Time) {
+		if err == ErrNoSuchEvent {
+			observeStore("AnnotateEvent", begin, nil)
+			return
+		}
+		observeStore("AnnotateEvent", begin, err)
+	}(time.Now())
+	return s.next.AnnotateEvent(id, a)
+}
+
+type instrumentedPruner struct {
+	pruner Pruner
+}
+
+func (s instrumentedPruner) PruneEvents(policy RetentionPolicy, now time.Time) (_ int, err error) {
+	defer func(begin time.Time) {
+		observeStore("PruneEvents", begin, err)
+	}(time.Now())
+	return s.pruner.PruneEvents(policy, now)
+}
+
+type instrumentedRecorder struct {
+	recorder Recorder
+}
+
+// RecordEvent counts as logging the event, whether or not it's
+// coalesced.
+func (s instrumentedRecorder) RecordEvent(e Event) (_ EventID, err error) {
+	defer func(begin time.Time) {
+		observeStore("RecordEvent", begin, err)
+		eventsLogged.With(
+			fluxmetrics.LabelEventType, e.Type,
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Add(1)
+	}(time.Now())
+	return s.recorder.RecordEvent(e)
+}
+
+type instrumentedPruningStore struct {
+	*instrumentedStore
+	instrumentedPruner
+}
+
+type instrumentedRecordingStore struct {
+	*instrumentedStore
+	instrumentedRecorder
+}
+
+type instrumentedPruningRecordingStore struct {
+	*instrumentedStore
+	instrumentedPruner
+	instrumentedRecorder
+}
@@ -0,0 +1,68 @@
+package event
+
+import (
+	"errors"
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// failingStore fails to log events, and (since it embeds only the
+// interface) isn't a Pruner
+type failingStore struct {
+	EventStore
+}
+
+func (failingStore) LogEvent(Event) error {
+	return errors.New("store is down")
+}
+
+func storeErrorCount(t *testing.T, method string) float64 {
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "flux_event_store_errors_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "method" && l.GetValue() == method {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestInstrumentStore(t *testing.T) {
+	if _, ok := InstrumentStore(&Buffer{}).(Pruner); !ok {
+		t.Error("expected an instrumented Buffer to be a Pruner")
+	}
+	if _, ok := InstrumentStore(&Buffer{}).(Recorder); !ok {
+		t.Error("expected an instrumented Buffer to be a Recorder")
+	}
+	failing := InstrumentStore(failingStore{&Buffer{}})
+	if _, ok := failing.(Pruner); ok {
+		t.Error("expected an instrumented store that isn't a Pruner not to be one")
+	}
+
+	before := storeErrorCount(t, "LogEvent")
+	if err := failing.LogEvent(Event{Type: EventSync}); err == nil {
+		t.Fatal("expected the store's error to be returned")
+	}
+	if after := storeErrorCount(t, "LogEvent"); after != before+1 {
+		t.Errorf("expected the failed write to be counted, got %v errors, from %v", after, before)
+	}
+
+	// Asking for an event that isn't there isn't counted as an error
+	before = storeErrorCount(t, "GetEvent")
+	if _, err := failing.GetEvent(1); err != ErrNoSuchEvent {
+		t.Errorf("expected ErrNoSuchEvent, got %v", err)
+	}
+	if after := storeErrorCount(t, "GetEvent"); after != before {
+		t.Errorf("expected no error counted for a missing event, got %v, from %v", after, before)
+	}
+}
@@ -0,0 +1,41 @@
+package event
+
+import (
+	"sync"
+)
+
+var (
+	eventTypesMu sync.RWMutex
+	eventTypes   = map[string]func() EventMetadata{}
+)
+
+// RegisterEventType makes the metadata of events of the type given
+// be unmarshalled as what factory returns, rather than as
+// UnknownEventMetadata; so that plugins and forks can add kinds of
+// event of their own, and still have typed metadata when the events
+// are read back (e.g., from an event store, or by fluxctl). factory
+// must return a pointer, for unmarshalling into. It's meant to be
+// called from the init of the package adding the type; it panics if
+// the type is already registered. The types of event defined here are
+// always unmarshalled as they are defined, and can't be registered
+// again.
+func RegisterEventType(name string, factory func() EventMetadata) {
+	eventTypesMu.Lock()
+	defer eventTypesMu.Unlock()
+	if _, ok := eventTypes[name]; ok {
+		panic("event type already registered: " + name)
+	}
+	eventTypes[name] = factory
+}
+
+// registeredMetadata gives new metadata for the event type given, if
+// the type is registered.
+func registeredMetadata(name string) (EventMetadata, bool) {
+	eventTypesMu.RLock()
+	defer eventTypesMu.RUnlock()
+	factory, ok := eventTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
@@ -0,0 +1,166 @@
+package event
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTemplates are the templates used to render each type of
+// event, unless overridden. Each is a Go text/template, given the
+// Event; see templateFuncs for the functions available beyond the
+// builtins.
+var DefaultTemplates = map[string]string{
+	EventRelease: `Released: {{with .Metadata.Result.ChangedImages}}{{join . ", "}}{{else}}no image changes{{end}}` +
+		` to {{$to := join .ServiceIDStrings ", "}}{{range .Metadata.Spec.ServiceSpecs}}{{if eq (print .) "<all>"}}{{$to = "all services"}}{{end}}{{end}}{{or $to "no services"}}` +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Cause.Message}}, with message {{printf "%q" .}}{{end}}`,
+	EventAutoRelease: `Automated release of {{with .Metadata.Result.ChangedImages}}{{join . ", "}}{{else}}no image changes{{end}}`,
+	EventRollback: `Rolled back release #{{.Metadata.ReleaseID}} ({{short .Metadata.ReleaseRevision}}):` +
+		` {{with .Metadata.Result.ChangedImages}}{{join . ", "}}{{else}}no image changes{{end}} to {{join .ServiceIDStrings ", "}}` +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Reason}}, because {{printf "%q" .}}{{end}}`,
+	EventCommit: `Commit: {{short .Metadata.Revision}}, {{with .ServiceIDStrings}}{{join . ", "}}{{else}}<no changes>{{end}}{{with .Metadata.PullRequest}} (pull request {{.}}){{end}}`,
+	EventSync: `Sync{{if .Metadata.Skipped}} skipped{{end}}: {{with .Metadata.Commits}}{{if gt (len .) 2}}{{short (last .).Revision}}..{{end}}{{short (index . 0).Revision}}{{else}}<no revision>{{end}}` +
+		`{{if .Metadata.Skipped}}, marked to skip{{else}}, {{with .ServiceIDStrings}}{{join . ", "}}{{else}}no services changed{{end}}{{end}}` +
+		`{{if .Metadata.Errors}}, {{len .Metadata.Errors}} errors{{else if .Metadata.Recovered}}, errors resolved{{end}}` +
+		`{{if gt .Repeated 0}} (repeated {{.Repeated}} times{{if not .LastSeen.IsZero}}, last at {{rfc3339 .LastSeen}}{{end}}){{end}}`,
+	EventAutomate:        `Automated: {{join .ServiceIDStrings ", "}}` + policyCauseTemplate,
+	EventDeautomate:      `Deautomated: {{join .ServiceIDStrings ", "}}` + policyCauseTemplate,
+	EventLock:            `Locked: {{join .ServiceIDStrings ", "}}` + lockTemplate,
+	EventUnlock:          `Unlocked: {{join .ServiceIDStrings ", "}}` + lockTemplate,
+	EventUpdatePolicy:    `Updated policies: {{join .ServiceIDStrings ", "}}` + policyCauseTemplate,
+	EventAccessDenied:    `Access denied: {{.Metadata.User}} may not {{.Metadata.Verb}} ({{.Metadata.Method}})`,
+	EventAudit:           `API call: {{.Metadata.Method}} by {{.Metadata.User}}, {{.Metadata.Result}}`,
+	EventStaleImage:      `Stale images: {{join .ServiceIDStrings ", "}}`,
+	EventSyncHeld:        `Sync of {{short .Metadata.Revision}} held back: {{.Metadata.Reason}}`,
+	EventSyncUnverified:  `Sync refused: commit {{short .Metadata.Revision}} in {{.Metadata.URL}} is not verified ({{.Metadata.Reason}})`,
+	EventObservedRelease: `Automation would release {{join .Metadata.Result.ChangedImages ", "}}`,
+	EventFreeze:          `{{with .Metadata}}{{if .Over}}Release freeze over{{else}}Release freeze until {{rfc3339 .End}}{{end}}{{with .Reason}} ({{.}}){{end}}{{end}}`,
+	EventDaemonStart: `{{with .Metadata}}Daemon started, {{if and .PreviousVersion (ne .PreviousVersion .Version)}}upgraded from version {{.PreviousVersion}} to {{.Version}}{{else}}version {{.Version}}{{end}}` +
+		`{{if .ConfigChanged}}; configuration changed{{with .ChangedConfig}}: {{join . ", "}}{{end}}{{end}}{{end}}`,
+	EventDaemonStop:         `Daemon stopped: {{.Metadata.Reason}}`,
+	EventAutomationDeferred: `Automation deferred since {{rfc3339 .Metadata.Since}}: {{.Metadata.Reason}} busy`,
+	EventAutomationSuspended: `Automation suspended for {{join .ServiceIDStrings ", "}} after {{.Metadata.Failures}} failures in {{.Metadata.Window}}` +
+		` (last: {{.Metadata.Reason}}); automate it again to resume`,
+	EventImagePolled: `Polled {{len .Metadata.Repositories}} image repositories in {{.Metadata.Duration}}; ` +
+		`{{$sep := "new tags: "}}{{range .Metadata.Repositories}}{{if .NewTags}}{{$sep}}{{.Name}} ({{join .NewTags ", "}}){{$sep = ", "}}{{end}}{{end}}` +
+		`{{if eq $sep "new tags: "}}no new tags{{end}}`,
+	EventSyncConflict: `Sync conflict: {{range $i, $c := .Metadata.Conflicts}}{{if $i}}; {{end}}` +
+		`{{.ID}} {{.Field}} changed by {{or .Manager "something other than flux"}} (flux applies {{.Applied}}, cluster has {{.Live}}){{end}}`,
+	EventSyncRewrite: `History of {{.Metadata.URL}} rewritten: {{short .Metadata.From}} is no longer in the history of {{short .Metadata.To}}` +
+		`{{with .Metadata.Dropped}}, dropping {{len .}} commits{{end}}`,
+	EventRestart: `Restarted {{with .ServiceIDStrings}}{{join . ", "}}{{else}}no workloads{{end}}` +
+		`{{with .Metadata.Cause.User}}, by {{.}}{{end}}{{with .Metadata.Reason}}, because {{printf "%q" .}}{{end}}{{with .Metadata.Result.Error}}; {{.}}{{end}}`,
+	EventChainHead: `Event chain head: event {{.Metadata.ID}}, hash {{.Metadata.Hash}}`,
+}
+
+// policyCauseTemplate says who changed policies, for those events
+// that have it; policy events from before they had metadata don't.
+const policyCauseTemplate = `{{with .Metadata}}{{with .Cause.User}}, by {{.}}{{end}}{{end}}`
+
+// lockTemplate says who locked or unlocked workloads, why, and until
+// when, for those lock events that have it.
+const lockTemplate = `{{with .Metadata}}{{with .User}}, by {{.}}{{end}}{{with .Reason}}, because {{printf "%q" .}}{{end}}` +
+	`{{with .UntilRevision}}, until {{short .}}{{end}}{{end}}`
+
+// templateFuncs are the functions available to templates, as well as
+// the builtins.
+var templateFuncs = template.FuncMap{
+	// join is strings.Join
+	"join": strings.Join,
+	// short abbreviates a git revision
+	"short": shortRevision,
+	// rfc3339 formats a time in UTC
+	"rfc3339": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	// code formats a string, or each of a list of strings, as
+	// Markdown code
+	"code": code,
+	// last gives the last item of a slice
+	"last": func(list interface{}) (interface{}, error) {
+		v := reflect.ValueOf(list)
+		if v.Kind() != reflect.Slice || v.Len() == 0 {
+			return nil, errors.New("last of empty or non-slice value")
+		}
+		return v.Index(v.Len() - 1).Interface(), nil
+	},
+}
+
+func code(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return "`" + v + "`", nil
+	case fmt.Stringer:
+		return "`" + v.String() + "`", nil
+	case []string:
+		var codes []string
+		for _, s := range v {
+			codes = append(codes, "`"+s+"`")
+		}
+		return strings.Join(codes, ", "), nil
+	}
+	return "", fmt.Errorf("cannot format %T as code", v)
+}
+
+// Renderer formats events as human-readable messages, using a
+// template for each type of event. It's what Event.String uses; make
+// one with different templates to change the messages given, e.g.,
+// in notifications.
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+var defaultRenderer = mustRenderer(NewRenderer(nil))
+
+func mustRenderer(r *Renderer, err error) *Renderer {
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// NewRenderer makes a renderer using the templates given, by event
+// type, in preference to DefaultTemplates.
+func NewRenderer(overrides map[string]string) (*Renderer, error) {
+	r := &Renderer{templates: map[string]*template.Template{}}
+	for _, texts := range []map[string]string{DefaultTemplates, overrides} {
+		for eventType, text := range texts {
+			t, err := template.New(eventType).Funcs(templateFuncs).Parse(text)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing template for %s events", eventType)
+			}
+			r.templates[eventType] = t
+		}
+	}
+	return r, nil
+}
+
+// Render gives the message for the event. Events with a pre-formatted
+// Message are given that, and those of a type with no template are
+// reported as unknown. Escalated events are followed by how many
+// failures in a row they stand for, whatever the template.
+func (r *Renderer) Render(e Event) (string, error) {
+	msg, err := r.render(e)
+	if err == nil && e.Failures > 0 {
+		msg += fmt.Sprintf(" (failed %d times in a row)", e.Failures)
+	}
+	return msg, err
+}
+
+func (r *Renderer) render(e Event) (string, error) {
+	if e.Message != "" {
+		return e.Message, nil
+	}
+	t, ok := r.templates[e.Type]
+	if !ok {
+		return fmt.Sprintf("Unknown event: %s", e.Type), nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, e); err != nil {
+		return "", errors.Wrapf(err, "rendering %s event", e.Type)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,53 @@
+package event
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestRenderer_Overrides(t *testing.T) {
+	r, err := NewRenderer(map[string]string{
+		EventLock: `:lock: {{join .ServiceIDStrings " and "}} locked`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []flux.ResourceID{
+		flux.MustParseResourceID("default:deployment/foo"),
+		flux.MustParseResourceID("default:deployment/bar"),
+	}
+	for _, c := range []struct {
+		event    Event
+		expected string
+	}{
+		{Event{Type: EventLock, ServiceIDs: ids}, ":lock: default:deployment/bar and default:deployment/foo locked"},
+		// Types not overridden get the default
+		{Event{Type: EventUnlock, ServiceIDs: ids}, "Unlocked: default:deployment/bar, default:deployment/foo"},
+		{Event{Type: EventLock, Message: "pre-formatted"}, "pre-formatted"},
+		{Event{Type: "bogus"}, "Unknown event: bogus"},
+	} {
+		got, err := r.Render(c.event)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, got)
+		}
+	}
+}
+
+func TestRenderer_Errors(t *testing.T) {
+	if _, err := NewRenderer(map[string]string{EventLock: "{{.Unclosed"}); err == nil {
+		t.Error("expected an error parsing a bad template")
+	}
+	// Missing metadata can't be rendered, but shouldn't panic
+	e := Event{Type: EventDaemonStop}
+	if _, err := defaultRenderer.Render(e); err == nil {
+		t.Error("expected an error rendering an event without its metadata")
+	}
+	if s := e.String(); !strings.HasPrefix(s, "rendering daemon_stop event: ") {
+		t.Errorf("expected the error from String, got %q", s)
+	}
+}
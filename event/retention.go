@@ -0,0 +1,71 @@
+package event
+
+import (
+	"time"
+)
+
+// RetentionPolicy says which events a store should keep: those no
+// older than a maximum age, and for each workload, only the most
+// recent so many events concerning it. The zero value keeps every
+// event.
+type RetentionPolicy struct {
+	// If more than zero, events started longer ago than this are
+	// pruned
+	MaxAge time.Duration `json:"maxAge,omitempty"`
+	// If more than zero, events are pruned once there are this many
+	// more recent events concerning each of the workloads they
+	// concern. Events concerning no workload are pruned only by age.
+	MaxPerService int `json:"maxPerService,omitempty"`
+}
+
+// IsZero says whether the policy keeps every event.
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxAge <= 0 && p.MaxPerService <= 0
+}
+
+// Retain gives those of the events, oldest first, that are kept by
+// the policy as at the time given, in the same order.
+func (p RetentionPolicy) Retain(events []Event, now time.Time) []Event {
+	if p.IsZero() {
+		return events
+	}
+	keep := make([]bool, len(events))
+	perService := map[string]int{}
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		// An event with repeats coalesced into it is as old as the
+		// last repeat
+		seen := e.StartedAt
+		if e.LastSeen.After(seen) {
+			seen = e.LastSeen
+		}
+		if p.MaxAge > 0 && now.Sub(seen) > p.MaxAge {
+			continue
+		}
+		if p.MaxPerService <= 0 || len(e.ServiceIDs) == 0 {
+			keep[i] = true
+			continue
+		}
+		for _, id := range e.ServiceIDs {
+			perService[id.String()]++
+			if perService[id.String()] <= p.MaxPerService {
+				keep[i] = true
+			}
+		}
+	}
+	var res []Event
+	for i, e := range events {
+		if keep[i] {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Pruner is implemented by event stores that can remove the events
+// a retention policy doesn't keep.
+type Pruner interface {
+	// PruneEvents removes the events not kept by the policy, as at
+	// the time given, and returns how many were removed.
+	PruneEvents(policy RetentionPolicy, now time.Time) (int, error)
+}
@@ -0,0 +1,57 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestPruneEvents(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("default:deployment/bar")
+	now := time.Now().UTC()
+	b := &Buffer{}
+	for _, e := range []Event{
+		{ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-72 * time.Hour)},
+		{ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-3 * time.Hour)},
+		{ServiceIDs: []flux.ResourceID{bar}, StartedAt: now.Add(-3 * time.Hour)},
+		{StartedAt: now.Add(-2 * time.Hour)},
+		{ServiceIDs: []flux.ResourceID{foo}, StartedAt: now.Add(-time.Hour)},
+		{ServiceIDs: []flux.ResourceID{foo, bar}, StartedAt: now},
+	} {
+		if err := b.LogEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := b.PruneEvents(RetentionPolicy{}, now)
+	if err != nil || pruned != 0 {
+		t.Errorf("expected the zero policy to keep every event, got %d pruned (%v)", pruned, err)
+	}
+
+	// Event 1 is too old; event 2 has two more recent events
+	// concerning foo; event 3 is one of the two most recent
+	// concerning bar; event 4 concerns no workload.
+	pruned, err = b.PruneEvents(RetentionPolicy{MaxAge: 24 * time.Hour, MaxPerService: 2}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected two events pruned, got %d", pruned)
+	}
+	kept, _ := b.AllEvents(Page{}, EventFilter{})
+	var ids []EventID
+	for _, e := range kept {
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 4 || ids[0] != 3 || ids[1] != 4 || ids[2] != 5 || ids[3] != 6 {
+		t.Errorf("expected events 3 to 6 kept, got %v", ids)
+	}
+
+	// IDs carry on from where they were
+	b.LogEvent(Event{})
+	if latest := b.Since(0, 1); latest[0].ID != 7 {
+		t.Errorf("expected the next event to be #7, got #%d", latest[0].ID)
+	}
+}
@@ -0,0 +1,98 @@
+package event
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/weaveworks/flux/update"
+)
+
+// The most fields Slack will show in a section block
+const maxSlackFields = 10
+
+// SlackBlock is a block of a Slack message, as in Slack's Block Kit.
+// Only the kinds used for events are covered: sections (with Text
+// and/or Fields), and context (with Elements).
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a text object in a Slack block.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(text string) SlackText {
+	return SlackText{Type: "mrkdwn", Text: text}
+}
+
+// SlackBlocks renders the event as Slack Block Kit blocks: its Markdown
+// message; the outcome for each workload, for events with an update
+// result, or the errors, for syncs; and the event's ID, level and
+// time, as context.
+func (e Event) SlackBlocks() []SlackBlock {
+	text := mrkdwn(e.MarkdownString())
+	blocks := []SlackBlock{{Type: "section", Text: &text}}
+
+	var fields []SlackText
+	switch m := e.Metadata.(type) {
+	case *ReleaseEventMetadata:
+		fields = resultFields(m.Result)
+	case *AutoReleaseEventMetadata:
+		fields = resultFields(m.Result)
+	case *RollbackEventMetadata:
+		fields = resultFields(m.Result)
+	case *RestartEventMetadata:
+		fields = resultFields(m.Result)
+	case *SyncEventMetadata:
+		for _, err := range m.Errors {
+			fields = append(fields, mrkdwn(fmt.Sprintf("`%s`\n%s", err.ID, err.Error)))
+		}
+	}
+	if len(fields) > maxSlackFields {
+		more := len(fields) - maxSlackFields + 1
+		fields = append(fields[:maxSlackFields-1], mrkdwn(fmt.Sprintf("and %d more", more)))
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, SlackBlock{Type: "section", Fields: fields})
+	}
+
+	context := fmt.Sprintf("%s event", e.Type)
+	if e.ID != 0 {
+		context = fmt.Sprintf("%s #%d", context, e.ID)
+	}
+	if e.LogLevel != "" {
+		context += ", " + e.LogLevel
+	}
+	if !e.StartedAt.IsZero() {
+		context += ", at " + e.StartedAt.UTC().Format(time.RFC3339)
+	}
+	return append(blocks, SlackBlock{Type: "context", Elements: []SlackText{mrkdwn(context)}})
+}
+
+// resultFields gives a field for each workload in the result, saying
+// how it went.
+func resultFields(result update.Result) []SlackText {
+	var ids []string
+	statuses := map[string]update.ControllerResult{}
+	for id, r := range result {
+		ids = append(ids, id.String())
+		statuses[id.String()] = r
+	}
+	sort.Strings(ids)
+	var fields []SlackText
+	for _, id := range ids {
+		r := statuses[id]
+		status := string(r.Status)
+		if r.Error != "" {
+			status += ": " + r.Error
+		}
+		fields = append(fields, mrkdwn(fmt.Sprintf("`%s`\n%s", id, status)))
+	}
+	return fields
+}
@@ -0,0 +1,9 @@
+// +build sqlite
+
+package sqlite
+
+import (
+	// The SQLite driver needs cgo, which fluxd is usually built
+	// without; so it's linked in only when asked for.
+	_ "github.com/mattn/go-sqlite3"
+)
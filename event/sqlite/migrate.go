@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// migrations are the changes to the schema, in order; the schema's
+// version is how many have been applied. Add to the end, and never
+// change one that's been released.
+var migrations = []string{
+	// 1: events, and the workloads each concerns
+	`CREATE TABLE events (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		type           TEXT NOT NULL,
+		started_at     INTEGER NOT NULL,
+		last_seen      INTEGER NOT NULL,
+		log_level      TEXT NOT NULL,
+		correlation_id TEXT NOT NULL,
+		data           TEXT NOT NULL
+	);
+	CREATE INDEX events_type ON events (type);
+	CREATE INDEX events_started_at ON events (started_at);
+	CREATE TABLE event_services (
+		event_id  INTEGER NOT NULL REFERENCES events (id),
+		service   TEXT NOT NULL,
+		namespace TEXT NOT NULL
+	);
+	CREATE INDEX event_services_event_id ON event_services (event_id);
+	CREATE INDEX event_services_service ON event_services (service);
+	CREATE INDEX event_services_namespace ON event_services (namespace);`,
+	// 2: the namespace each event was logged in, if any
+	`ALTER TABLE events ADD COLUMN namespace TEXT NOT NULL DEFAULT '';
+	CREATE INDEX events_namespace ON events (namespace);`,
+}
+
+// migrate brings the schema up to date, applying each migration not
+// yet applied in a transaction of its own.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return errors.Errorf("schema version %d is newer than this version of flux knows about (%d)", version, len(migrations))
+	}
+	for v := version; v < len(migrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[v]); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "applying migration %d", v+1)
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_version`); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, v+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaVersion gives how many migrations have been applied.
+func schemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
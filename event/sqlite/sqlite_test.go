@@ -0,0 +1,216 @@
+// +build sqlite
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+func tempStore(t *testing.T) (*Store, string, func()) {
+	dir, err := ioutil.TempDir("", "flux-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "events.db")
+	s, err := Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return s, path, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, path, clean := tempStore(t)
+	defer clean()
+
+	hello := flux.MustParseResourceID("default:deployment/helloworld")
+	other := flux.MustParseResourceID("other:deployment/service")
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.LogEvents([]event.Event{
+		{Type: event.EventRelease, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start, LogLevel: event.LogLevelInfo},
+		{Type: event.EventSync, ServiceIDs: []flux.ResourceID{hello, other}, StartedAt: start.Add(time.Minute), LogLevel: event.LogLevelError},
+		{Type: event.EventRelease, ServiceIDs: []flux.ResourceID{other}, StartedAt: start.Add(2 * time.Minute), LogLevel: event.LogLevelInfo},
+		{Type: event.EventCommit, Namespace: "other", StartedAt: start.Add(3 * time.Minute), LogLevel: event.LogLevelInfo, Metadata: &event.CommitEventMetadata{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := s.AllEvents(event.Page{}, event.EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all[0].ID != 1 || all[3].ID != 4 {
+		t.Fatalf("expected four events, oldest first, got %+v", all)
+	}
+
+	for name, c := range map[string]struct {
+		page      event.Page
+		filter    event.EventFilter
+		id        flux.ResourceID
+		namespace string
+		ids       []event.EventID
+	}{
+		"by workload":  {id: hello, ids: []event.EventID{1, 2}},
+		"by type":      {filter: event.EventFilter{Types: []string{event.EventRelease}}, ids: []event.EventID{1, 3}},
+		"by time":      {filter: event.EventFilter{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)}, ids: []event.EventID{2}},
+		"by level":     {filter: event.EventFilter{MinLogLevel: event.LogLevelWarn}, ids: []event.EventID{2}},
+		"by page":      {page: event.Page{Before: 3, Limit: 1}, ids: []event.EventID{2}},
+		"in namespace": {namespace: "other", ids: []event.EventID{2, 3, 4}},
+		"by namespace": {filter: event.EventFilter{Namespaces: []string{"other"}, Types: []string{event.EventCommit}}, ids: []event.EventID{4}},
+	} {
+		var events []event.Event
+		if c.id != (flux.ResourceID{}) {
+			events, err = s.EventsForService(c.id, c.page, c.filter)
+		} else if c.namespace != "" {
+			events, err = s.EventsForNamespace(c.namespace, c.page, c.filter)
+		} else {
+			events, err = s.AllEvents(c.page, c.filter)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []event.EventID
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if len(ids) != len(c.ids) || (len(ids) > 0 && ids[0] != c.ids[0]) {
+			t.Errorf("%s: expected events %v, got %v", name, c.ids, ids)
+		}
+	}
+
+	annotated, err := s.AnnotateEvent(2, event.Annotation{User: "jane", Comment: "known flake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotated.Annotations) != 1 {
+		t.Errorf("expected the annotation, got %+v", annotated)
+	}
+	if _, err := s.GetEvent(42); err != event.ErrNoSuchEvent {
+		t.Errorf("expected ErrNoSuchEvent, got %v", err)
+	}
+
+	// The events are still there when the store is opened again
+	s.Close()
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.GetEvent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Annotations) != 1 || e.Annotations[0].Comment != "known flake" {
+		t.Errorf("expected the annotation to be kept, got %+v", e)
+	}
+
+	pruned, err := s.PruneEvents(event.RetentionPolicy{MaxPerService: 1}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected one event to be pruned, got %d", pruned)
+	}
+	if _, err := s.GetEvent(1); err != event.ErrNoSuchEvent {
+		t.Errorf("expected the first event to be pruned, got %v", err)
+	}
+}
+
+func TestStore_RecordEvent(t *testing.T) {
+	s, _, clean := tempStore(t)
+	defer clean()
+
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	sync := func(at time.Time) event.Event {
+		return event.Event{Type: event.EventSync, StartedAt: at, Metadata: &event.SyncEventMetadata{}}
+	}
+	first, err := s.RecordEvent(sync(start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.RecordEvent(sync(start.Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatalf("expected the second sync to be coalesced into the first")
+	}
+	e, err := s.GetEvent(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Repeated != 1 || !e.LastSeen.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected one repeat, last seen a minute later, got %+v", e)
+	}
+}
+
+// Events logged without metadata are read back without it, whatever
+// their type
+func TestStore_NoMetadata(t *testing.T) {
+	s, _, clean := tempStore(t)
+	defer clean()
+
+	hello := flux.MustParseResourceID("default:deployment/helloworld")
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.LogEvent(event.Event{Type: event.EventRelease, ServiceIDs: []flux.ResourceID{hello}, StartedAt: start, LogLevel: event.LogLevelInfo}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LogEvent(event.Event{Type: event.EventCommit, StartedAt: start.Add(time.Minute), Metadata: &event.CommitEventMetadata{Revision: "abc"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.EventsForService(hello, event.Page{}, event.EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != event.EventRelease || events[0].Metadata != nil || events[0].ServiceIDs[0] != hello {
+		t.Errorf("expected the release back, without metadata, got %+v", events)
+	}
+	e, err := s.GetEvent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := e.Metadata.(*event.CommitEventMetadata); !ok || m.Revision != "abc" {
+		t.Errorf("expected the commit's metadata back, got %#v", e.Metadata)
+	}
+}
+
+func TestStore_Chain(t *testing.T) {
+	s, _, clean := tempStore(t)
+	defer clean()
+
+	chained, err := event.ChainStore(s, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2018, 6, 1, 0, 0, 0, 0, time.Local)
+	hello := flux.MustParseResourceID("default:deployment/helloworld")
+	for i := 0; i < 3; i++ {
+		if err := chained.LogEvent(event.Event{
+			Type:       event.EventCommit,
+			ServiceIDs: []flux.ResourceID{hello},
+			StartedAt:  start.Add(time.Duration(i) * time.Minute),
+			Metadata:   &event.CommitEventMetadata{Revision: "abc"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The hashes hold once the events have been through the database
+	breaks, err := event.VerifyHistory(s, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breaks) != 0 {
+		t.Errorf("expected the chain to check out, got %+v", breaks)
+	}
+}
@@ -0,0 +1,509 @@
+/*
+Package sqlite keeps events in a SQLite database file, for a daemon
+running on its own that should remember its events across restarts,
+without running a database server for them.
+
+The store is registered for URLs with the scheme sqlite, e.g.,
+`sqlite:///var/lib/flux/events.db`. The SQLite driver needs cgo, so
+it's only linked in when fluxd is built with the tag `sqlite` (see
+driver.go); otherwise opening the store fails, saying so.
+*/
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+// DriverName is the database/sql driver the store is opened with.
+const DriverName = "sqlite3"
+
+// Store is an event store backed by a SQLite database. Each event is
+// kept as JSON, alongside the fields it's queried by, and the
+// workloads it concerns.
+type Store struct {
+	db *sql.DB
+	// Serialises writers, since SQLite allows only one at a time
+	mu sync.Mutex
+}
+
+var (
+	_ event.EventStore = &Store{}
+	_ event.Pruner     = &Store{}
+	_ event.Recorder   = &Store{}
+)
+
+func init() {
+	event.RegisterStore("sqlite", func(u *url.URL) (event.EventStore, error) {
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("expected sqlite://<path to database file>, got %q", u.String())
+		}
+		return Open(path)
+	})
+}
+
+// Open opens the database file at the path given, creating it if
+// need be, and makes sure its schema is up to date.
+func Open(path string) (*Store, error) {
+	if !driverRegistered() {
+		return nil, errors.New("this build has no SQLite driver; build with the tag sqlite (and cgo) to use a SQLite event store")
+	}
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening SQLite event store")
+	}
+	// SQLite allows only one writer at a time, and in-memory
+	// databases are per connection
+	db.SetMaxOpenConns(1)
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func driverRegistered() bool {
+	for _, d := range sql.Drivers() {
+		if d == DriverName {
+			return true
+		}
+	}
+	return false
+}
+
+// New makes a store using the database given, migrating its schema
+// to the latest version.
+func New(db *sql.DB) (*Store, error) {
+	if err := migrate(db); err != nil {
+		return nil, errors.Wrap(err, "migrating SQLite event store")
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) LogEvent(e event.Event) error {
+	_, err := s.RecordEvent(e)
+	return err
+}
+
+// LogEvents logs the events in one transaction, as an EventStore.
+func (s *Store) LogEvents(events []event.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if _, err := insert(tx, e); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordEvent counts the event as a repeat of the most recent event
+// of the same type, if they can be coalesced, or else logs it, as a
+// Recorder.
+func (s *Store) RecordEvent(e event.Event) (event.EventID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	id, err := record(tx, e)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func record(tx *sql.Tx, e event.Event) (event.EventID, error) {
+	before, err := scanEvents(tx.Query(`SELECT id, data FROM events WHERE type = ? ORDER BY id DESC LIMIT 1`, e.Type))
+	if err != nil {
+		return 0, err
+	}
+	if len(before) == 1 && event.Coalesces(before[0], e) {
+		repeated := before[0]
+		repeated.Repeated++
+		repeated.LastSeen = e.StartedAt
+		return repeated.ID, update(tx, repeated)
+	}
+	return insert(tx, e)
+}
+
+func insert(tx *sql.Tx, e event.Event) (event.EventID, error) {
+	e.ID = 0
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`INSERT INTO events (type, started_at, last_seen, log_level, correlation_id, namespace, data) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.Type, unixNano(e.StartedAt), unixNano(lastSeen(e)), e.LogLevel, e.CorrelationID, e.Namespace, string(data))
+	if err != nil {
+		return 0, errors.Wrap(err, "inserting event")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, sid := range e.ServiceIDs {
+		ns, _, _ := sid.Components()
+		if _, err := tx.Exec(`INSERT INTO event_services (event_id, service, namespace) VALUES (?, ?, ?)`, id, sid.String(), ns); err != nil {
+			return 0, errors.Wrap(err, "inserting event's workloads")
+		}
+	}
+	return event.EventID(id), nil
+}
+
+// update rewrites an event already kept, e.g., once it's annotated.
+func update(tx *sql.Tx, e event.Event) error {
+	id := e.ID
+	e.ID = 0
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE events SET last_seen = ?, data = ? WHERE id = ?`, unixNano(lastSeen(e)), string(data), int64(id))
+	return errors.Wrap(err, "updating event")
+}
+
+// AllEvents returns the events in the page that match the filter,
+// oldest first, as an EventStore.
+func (s *Store) AllEvents(page event.Page, filter event.EventFilter) ([]event.Event, error) {
+	return s.query(page, filter, "")
+}
+
+func (s *Store) EventsForService(id flux.ResourceID, page event.Page, filter event.EventFilter) ([]event.Event, error) {
+	return s.query(page, filter, "", id)
+}
+
+func (s *Store) EventsForNamespace(namespace string, page event.Page, filter event.EventFilter) ([]event.Event, error) {
+	return s.query(page, filter, namespace)
+}
+
+func (s *Store) query(page event.Page, filter event.EventFilter, namespace string, ids ...flux.ResourceID) ([]event.Event, error) {
+	q, args := selectEvents(page, filter, ids, namespace)
+	events, err := scanEvents(s.db.Query(q, args...))
+	if err != nil {
+		return nil, err
+	}
+	// Most recent first, from the query; and not all of the filter
+	// can be done in SQL (e.g., syncs including the commit with a
+	// correlation ID)
+	var res []event.Event
+	for i := len(events) - 1; i >= 0; i-- {
+		if filter.Matches(events[i]) {
+			res = append(res, events[i])
+		}
+	}
+	if page.Limit > 0 && len(res) > page.Limit {
+		res = res[len(res)-page.Limit:]
+	}
+	return res, nil
+}
+
+// selectEvents gives the query, and its arguments, for the events in
+// the page that match the filter and, if any IDs are given, concern
+// one of those workloads, and if a namespace is given, belong to it;
+// most recent first. The correlation ID in the filter is matched only
+// loosely, so the events must be filtered again.
+func selectEvents(page event.Page, filter event.EventFilter, ids []flux.ResourceID, namespace string) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	in := func(column string, values []string) string {
+		for _, v := range values {
+			args = append(args, v)
+		}
+		return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")"
+	}
+	concerning := func(column string, values []string) string {
+		return "EXISTS (SELECT 1 FROM event_services es WHERE es.event_id = events.id AND " + in("es."+column, values) + ")"
+	}
+	// As in Event.InNamespace: logged in one of the namespaces, or
+	// concerning a workload in one
+	inNamespaces := func(namespaces []string) string {
+		return "(" + in("namespace", namespaces) + " OR " + concerning("namespace", namespaces) + ")"
+	}
+
+	if page.After > 0 {
+		where = append(where, "id > ?")
+		args = append(args, int64(page.After))
+	}
+	if page.Before > 0 {
+		where = append(where, "id < ?")
+		args = append(args, int64(page.Before))
+	}
+	if len(ids) > 0 {
+		where = append(where, concerning("service", resourceIDStrings(ids)))
+	}
+	if namespace != "" {
+		where = append(where, inNamespaces([]string{namespace}))
+	}
+	if len(filter.Types) > 0 {
+		where = append(where, in("type", filter.Types))
+	}
+	if len(filter.Services) > 0 {
+		where = append(where, concerning("service", resourceIDStrings(filter.Services)))
+	}
+	if len(filter.Namespaces) > 0 {
+		where = append(where, inNamespaces(filter.Namespaces))
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, unixNano(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, unixNano(filter.Until))
+	}
+	if filter.MinLogLevel != "" {
+		where = append(where, in("log_level", levelsFrom(filter.MinLogLevel)))
+	}
+	if filter.CorrelationID != "" {
+		where = append(where, "(correlation_id = ? OR type = ?)")
+		args = append(args, filter.CorrelationID, event.EventSync)
+	}
+
+	q := "SELECT id, data FROM events"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id DESC"
+	// Syncs matched loosely by correlation ID may be filtered out
+	// afterwards, so can't count towards the limit
+	if page.Limit > 0 && filter.CorrelationID == "" {
+		q += " LIMIT ?"
+		args = append(args, page.Limit)
+	}
+	return q, args
+}
+
+// levelsFrom gives the log levels at or above the one given; events
+// without a level count as informative, as in EventFilter.
+func levelsFrom(min string) []string {
+	levels := []string{event.LogLevelDebug, event.LogLevelInfo, event.LogLevelWarn, event.LogLevelError}
+	for i, l := range levels {
+		if l == min {
+			levels = levels[i:]
+			break
+		}
+	}
+	for _, l := range levels {
+		if l == event.LogLevelInfo {
+			return append(levels, "")
+		}
+	}
+	return levels
+}
+
+func (s *Store) GetEvent(id event.EventID) (event.Event, error) {
+	events, err := scanEvents(s.db.Query(`SELECT id, data FROM events WHERE id = ?`, int64(id)))
+	if err != nil {
+		return event.Event{}, err
+	}
+	if len(events) == 0 {
+		return event.Event{}, event.ErrNoSuchEvent
+	}
+	return events[0], nil
+}
+
+// AnnotateEvent adds the annotation to the event with the ID given,
+// as an EventStore.
+func (s *Store) AnnotateEvent(id event.EventID, a event.Annotation) (event.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return event.Event{}, err
+	}
+	events, err := scanEvents(tx.Query(`SELECT id, data FROM events WHERE id = ?`, int64(id)))
+	if err != nil {
+		tx.Rollback()
+		return event.Event{}, err
+	}
+	if len(events) == 0 {
+		tx.Rollback()
+		return event.Event{}, event.ErrNoSuchEvent
+	}
+	annotated := events[0]
+	annotated.Annotations = append(annotated.Annotations, a)
+	if err := update(tx, annotated); err != nil {
+		tx.Rollback()
+		return event.Event{}, err
+	}
+	return annotated, tx.Commit()
+}
+
+// PruneEvents removes the events not kept by the policy, as a Pruner.
+func (s *Store) PruneEvents(policy event.RetentionPolicy, now time.Time) (int, error) {
+	if policy.IsZero() {
+		return 0, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := prune(tx, policy, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return pruned, tx.Commit()
+}
+
+func prune(tx *sql.Tx, policy event.RetentionPolicy, now time.Time) (int, error) {
+	// The policy is applied to just what it looks at -- when each
+	// event was seen, and the workloads it concerns -- rather than
+	// to whole events
+	rows, err := tx.Query(`SELECT e.id, e.started_at, e.last_seen, es.service FROM events e LEFT JOIN event_services es ON es.event_id = e.id ORDER BY e.id`)
+	if err != nil {
+		return 0, err
+	}
+	var events []event.Event
+	for rows.Next() {
+		var id, started, seen int64
+		var service sql.NullString
+		if err := rows.Scan(&id, &started, &seen, &service); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if len(events) == 0 || events[len(events)-1].ID != event.EventID(id) {
+			events = append(events, event.Event{
+				ID:        event.EventID(id),
+				StartedAt: time.Unix(0, started).UTC(),
+				LastSeen:  time.Unix(0, seen).UTC(),
+			})
+		}
+		if service.Valid {
+			sid, err := flux.ParseResourceID(service.String)
+			if err != nil {
+				rows.Close()
+				return 0, err
+			}
+			last := &events[len(events)-1]
+			last.ServiceIDs = append(last.ServiceIDs, sid)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	kept := map[event.EventID]bool{}
+	for _, e := range policy.Retain(events, now) {
+		kept[e.ID] = true
+	}
+	var pruned int
+	for _, e := range events {
+		if kept[e.ID] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM event_services WHERE event_id = ?`, int64(e.ID)); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM events WHERE id = ?`, int64(e.ID)); err != nil {
+			return 0, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// scanEvents reads the id and data of each of the rows into an event.
+func scanEvents(rows *sql.Rows, err error) ([]event.Event, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []event.Event
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		e, err := decodeEvent([]byte(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading event %d", id)
+		}
+		e.ID = event.EventID(id)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// storedEvent is an event without its own JSON decoding, which
+// expects metadata for most types of event.
+type storedEvent event.Event
+
+// decodeEvent reads an event as stored. Events logged without
+// metadata are stored without it too, and are read back still
+// without it, rather than refused for not having any.
+func decodeEvent(data []byte) (event.Event, error) {
+	var e event.Event
+	var fields struct {
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return e, err
+	}
+	if len(fields.Metadata) == 0 {
+		err := json.Unmarshal(data, (*storedEvent)(&e))
+		return e, err
+	}
+	err := json.Unmarshal(data, &e)
+	return e, err
+}
+
+// lastSeen gives when the event (or the last of its repeats) was
+// started.
+func lastSeen(e event.Event) time.Time {
+	if e.LastSeen.After(e.StartedAt) {
+		return e.LastSeen
+	}
+	return e.StartedAt
+}
+
+// unixNano gives the time in nanoseconds, and zero for the zero time
+// (rather than the time's UnixNano, which overflows).
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func resourceIDStrings(ids []flux.ResourceID) []string {
+	ss := make([]string, len(ids))
+	for i, id := range ids {
+		ss[i] = id.String()
+	}
+	return ss
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestSelectEvents(t *testing.T) {
+	since := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	q, args := selectEvents(event.Page{After: 10, Limit: 5}, event.EventFilter{
+		Types:      []string{event.EventRelease, event.EventAutoRelease},
+		Namespaces: []string{"default"},
+		Since:      since,
+	}, []flux.ResourceID{flux.MustParseResourceID("default:deployment/helloworld")}, "")
+
+	expected := "SELECT id, data FROM events WHERE id > ?" +
+		" AND EXISTS (SELECT 1 FROM event_services es WHERE es.event_id = events.id AND es.service IN (?))" +
+		" AND type IN (?, ?)" +
+		" AND (namespace IN (?) OR EXISTS (SELECT 1 FROM event_services es WHERE es.event_id = events.id AND es.namespace IN (?)))" +
+		" AND started_at >= ? ORDER BY id DESC LIMIT ?"
+	if q != expected {
+		t.Errorf("expected query\n%s\ngot\n%s", expected, q)
+	}
+	expectedArgs := []interface{}{int64(10), "default:deployment/helloworld", event.EventRelease, event.EventAutoRelease, "default", "default", since.UnixNano(), 5}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, args)
+	}
+}
+
+func TestSelectEvents_Correlated(t *testing.T) {
+	// Syncs are matched loosely, and filtered afterwards, so the limit
+	// can't be applied in the query
+	q, args := selectEvents(event.Page{Limit: 5}, event.EventFilter{CorrelationID: "job-1"}, nil, "")
+	expected := "SELECT id, data FROM events WHERE (correlation_id = ? OR type = ?) ORDER BY id DESC"
+	if q != expected {
+		t.Errorf("expected query\n%s\ngot\n%s", expected, q)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"job-1", event.EventSync}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestSelectEvents_Namespace(t *testing.T) {
+	q, args := selectEvents(event.Page{Before: 20}, event.EventFilter{}, nil, "team-a")
+	expected := "SELECT id, data FROM events WHERE id < ?" +
+		" AND (namespace IN (?) OR EXISTS (SELECT 1 FROM event_services es WHERE es.event_id = events.id AND es.namespace IN (?)))" +
+		" ORDER BY id DESC"
+	if q != expected {
+		t.Errorf("expected query\n%s\ngot\n%s", expected, q)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(20), "team-a", "team-a"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestLevelsFrom(t *testing.T) {
+	for min, expected := range map[string][]string{
+		event.LogLevelDebug: {event.LogLevelDebug, event.LogLevelInfo, event.LogLevelWarn, event.LogLevelError, ""},
+		event.LogLevelInfo:  {event.LogLevelInfo, event.LogLevelWarn, event.LogLevelError, ""},
+		event.LogLevelWarn:  {event.LogLevelWarn, event.LogLevelError},
+	} {
+		if got := levelsFrom(min); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", min, expected, got)
+		}
+	}
+}
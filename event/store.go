@@ -0,0 +1,122 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+// ErrNoSuchEvent is returned by an EventStore when asked for an event
+// it doesn't have (or no longer keeps).
+var ErrNoSuchEvent = errors.New("no such event")
+
+// EventStore keeps the events logged, so they can be listed, looked
+// up and annotated later. Each event logged is given an ID,
+// increasing in the order events are logged.
+type EventStore interface {
+	EventWriter
+	// LogEvents records the events given, in order, as one batch:
+	// either all of them are kept, or (if there's an error) none.
+	LogEvents(events []Event) error
+	// AllEvents returns the events in the page given that match the
+	// filter, oldest first. The page's limit counts only events that
+	// match.
+	AllEvents(page Page, filter EventFilter) ([]Event, error)
+	// EventsForService is like AllEvents, but returns only the events
+	// concerning the workload given.
+	EventsForService(id flux.ResourceID, page Page, filter EventFilter) ([]Event, error)
+	// EventsForNamespace is like AllEvents, but returns only the
+	// events belonging to the namespace given (see
+	// Event.InNamespace), e.g., for a team's view of the history.
+	EventsForNamespace(namespace string, page Page, filter EventFilter) ([]Event, error)
+	// GetEvent returns the event with the ID given, or ErrNoSuchEvent.
+	GetEvent(id EventID) (Event, error)
+	// AnnotateEvent adds the annotation to the event with the ID
+	// given, and returns the event as annotated, or ErrNoSuchEvent.
+	AnnotateEvent(id EventID, a Annotation) (Event, error)
+}
+
+// Page selects some of the events kept: those between two events,
+// and at most a limited number of the most recent of those. To go
+// back through the events a page at a time, give the ID of the oldest
+// event in one page as Before, for the next.
+type Page struct {
+	// If more than zero, only events with an ID greater than this
+	After EventID
+	// If more than zero, only events with an ID less than this
+	Before EventID
+	// If more than zero, at most this many, of the most recent
+	Limit int
+}
+
+// Includes says whether the event with the ID given is between the
+// bounds of the page (not counting the limit).
+func (p Page) Includes(id EventID) bool {
+	return id > p.After && (p.Before <= 0 || id < p.Before)
+}
+
+// StoreOpener opens an event store given its URL, e.g.,
+// `postgres://flux@db/events`.
+type StoreOpener func(u *url.URL) (EventStore, error)
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]StoreOpener{}
+)
+
+// RegisterStore makes a kind of event store available to OpenStore,
+// for URLs with the scheme given. It's meant to be called from the
+// init of the package providing the store; it panics if the scheme
+// is already registered.
+func RegisterStore(scheme string, open StoreOpener) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if _, ok := stores[scheme]; ok {
+		panic("event store already registered for scheme " + scheme)
+	}
+	stores[scheme] = open
+}
+
+// OpenStore opens the event store at the URL given, using the store
+// registered for its scheme.
+func OpenStore(rawurl string) (EventStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	storesMu.Lock()
+	open, ok := stores[u.Scheme]
+	var schemes []string
+	for s := range stores {
+		schemes = append(schemes, s)
+	}
+	storesMu.Unlock()
+	if !ok {
+		sort.Strings(schemes)
+		return nil, fmt.Errorf("no event store registered for %q; expected one of %v", u.Scheme, schemes)
+	}
+	return open(u)
+}
+
+func init() {
+	// memory:, or memory:?size=<n>, keeps the most recent events in a
+	// Buffer, with IDs carrying on from before a restart
+	RegisterStore("memory", func(u *url.URL) (EventStore, error) {
+		b := &Buffer{}
+		b.Seed(ClockSeed(time.Now()))
+		if s := u.Query().Get("size"); s != "" {
+			size, err := strconv.Atoi(s)
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("event store size %q is not a positive number", s)
+			}
+			b.Size = size
+		}
+		return b, nil
+	})
+}
@@ -0,0 +1,93 @@
+package event
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+func TestOpenMemoryStore(t *testing.T) {
+	store, err := OpenStore("memory:?size=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := store.(*Buffer); !ok || b.Size != 2 {
+		t.Errorf("expected a buffer of size 2, got %#v", store)
+	}
+
+	// A store opened again, as after a restart, gives later IDs than
+	// the first, rather than starting again
+	store.LogEvent(Event{Type: EventSync})
+	store.LogEvent(Event{Type: EventSync})
+	before, _ := store.AllEvents(Page{}, EventFilter{})
+	time.Sleep(2 * time.Millisecond)
+	restarted, _ := OpenStore("memory:")
+	restarted.LogEvent(Event{Type: EventSync})
+	after, _ := restarted.AllEvents(Page{}, EventFilter{})
+	if len(before) != 2 || len(after) != 1 || after[0].ID <= before[1].ID {
+		t.Errorf("expected IDs to carry on after a restart, got %+v then %+v", before, after)
+	}
+	for _, bad := range []string{"memory:?size=none", "memory:?size=0", "nonesuch://events"} {
+		if _, err := OpenStore(bad); err == nil {
+			t.Errorf("expected error opening %q", bad)
+		}
+	}
+}
+
+func TestRegisterStore(t *testing.T) {
+	var opened string
+	RegisterStore("test-store", func(u *url.URL) (EventStore, error) {
+		opened = u.Host
+		return &Buffer{}, nil
+	})
+	if _, err := OpenStore("test-store://events.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if opened != "events.example.com" {
+		t.Errorf("expected registered store to be opened with the URL given, got %q", opened)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering the same scheme twice to panic")
+		}
+	}()
+	RegisterStore("test-store", nil)
+}
+
+func TestBufferAsStore(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("default:deployment/bar")
+	var store EventStore = &Buffer{}
+	for _, ids := range [][]flux.ResourceID{{foo}, {bar}, {foo, bar}} {
+		if err := store.LogEvent(Event{Type: EventRelease, ServiceIDs: ids}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := store.EventsForService(foo, Page{}, EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 3 {
+		t.Errorf("expected the events concerning %s, got %+v", foo, events)
+	}
+	if events, _ := store.EventsForService(bar, Page{After: 2}, EventFilter{}); len(events) != 1 || events[0].ID != 3 {
+		t.Errorf("expected only the event after ID 2, got %+v", events)
+	}
+	if events, _ := store.EventsForService(foo, Page{Before: 3}, EventFilter{}); len(events) != 1 || events[0].ID != 1 {
+		t.Errorf("expected only the event before ID 3, got %+v", events)
+	}
+
+	if e, err := store.GetEvent(2); err != nil || e.ID != 2 {
+		t.Errorf("expected event 2, got %+v, %v", e, err)
+	}
+	if _, err := store.GetEvent(4); err != ErrNoSuchEvent {
+		t.Errorf("expected ErrNoSuchEvent, got %v", err)
+	}
+	if _, err := store.AnnotateEvent(4, Annotation{Comment: "missing"}); err != ErrNoSuchEvent {
+		t.Errorf("expected ErrNoSuchEvent annotating a missing event, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package event
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Throttle is an EventWriter that stops the same errors being sent
+// over and over, e.g., when a sync keeps failing. An event reporting
+// errors is passed on to Writer the first time it's seen; after
+// that, events reporting the same errors (for the same resources)
+// are counted, and only passed on once Window has elapsed, with the
+// count in Repeated. Events that don't report errors, including
+// those saying errors have been resolved, are always passed on.
+type Throttle struct {
+	Writer EventWriter
+	Window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*throttled
+	// for testing
+	now func() time.Time
+}
+
+type throttled struct {
+	sent       time.Time
+	suppressed int
+}
+
+var _ EventWriter = &Throttle{}
+
+func (t *Throttle) LogEvent(e Event) error {
+	key, ok := throttleKey(e)
+	if !ok || t.Window <= 0 {
+		if e.Type == EventSync {
+			t.forgetSyncErrors()
+		}
+		return t.Writer.LogEvent(e)
+	}
+
+	now := time.Now()
+	if t.now != nil {
+		now = t.now()
+	}
+	t.mu.Lock()
+	if t.seen == nil {
+		t.seen = map[string]*throttled{}
+	}
+	last, ok := t.seen[key]
+	if ok && now.Sub(last.sent) < t.Window {
+		last.suppressed++
+		t.mu.Unlock()
+		return nil
+	}
+	if ok {
+		e.Repeated = last.suppressed
+	}
+	t.seen[key] = &throttled{sent: now}
+	t.mu.Unlock()
+	return t.Writer.LogEvent(e)
+}
+
+// forgetSyncErrors clears the record of sync errors once a sync
+// doesn't have them, so if they come back they're reported straight
+// away.
+func (t *Throttle) forgetSyncErrors() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k := range t.seen {
+		if strings.HasPrefix(k, EventSync+"\n") {
+			delete(t.seen, k)
+		}
+	}
+}
+
+// throttleKey gives a key identifying the errors an event reports,
+// and true; or false if it doesn't report errors.
+func throttleKey(e Event) (string, bool) {
+	var errs []string
+	switch m := e.Metadata.(type) {
+	case *SyncEventMetadata:
+		for _, re := range m.Errors {
+			errs = append(errs, re.ID.String()+": "+re.Error)
+		}
+	case *ReleaseEventMetadata:
+		// Someone asked for this release, so they should hear
+		// about it every time.
+		return "", false
+	case *AutoReleaseEventMetadata:
+		if m.Error != "" {
+			errs = append(errs, m.Error)
+		}
+	default:
+		if e.LogLevel == LogLevelError {
+			errs = append(errs, e.String())
+		}
+	}
+	if len(errs) == 0 {
+		return "", false
+	}
+	sort.Strings(errs)
+	return e.Type + "\n" + strings.Join(e.ServiceIDStrings(), ",") + "\n" + strings.Join(errs, "\n"), true
+}
@@ -0,0 +1,86 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+)
+
+type recordingWriter []Event
+
+func (w *recordingWriter) LogEvent(e Event) error {
+	*w = append(*w, e)
+	return nil
+}
+
+func syncEvent(errs ...string) Event {
+	var resourceErrors []ResourceError
+	for _, e := range errs {
+		resourceErrors = append(resourceErrors, ResourceError{
+			ID:    flux.MustParseResourceID("default:deployment/foo"),
+			Error: e,
+		})
+	}
+	return Event{
+		Type:     EventSync,
+		LogLevel: LogLevelInfo,
+		Metadata: &SyncEventMetadata{
+			Errors:    resourceErrors,
+			Recovered: len(errs) == 0,
+		},
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	now := time.Now()
+	w := &recordingWriter{}
+	throttle := &Throttle{Writer: w, Window: time.Hour, now: func() time.Time { return now }}
+
+	// The first error goes through; repeats within the window don't
+	for i := 0; i < 5; i++ {
+		throttle.LogEvent(syncEvent("bad manifest"))
+		now = now.Add(time.Minute)
+	}
+	if len(*w) != 1 {
+		t.Fatalf("expected one event through, got %d", len(*w))
+	}
+
+	// A different error isn't held back by the first
+	throttle.LogEvent(syncEvent("another bad manifest"))
+	if len(*w) != 2 {
+		t.Fatalf("expected a different error to go through, got %d events", len(*w))
+	}
+
+	// After the window, the next repeat goes through with a count
+	now = now.Add(time.Hour)
+	throttle.LogEvent(syncEvent("bad manifest"))
+	if len(*w) != 3 {
+		t.Fatalf("expected repeat after window to go through, got %d events", len(*w))
+	}
+	if r := (*w)[2].Repeated; r != 4 {
+		t.Errorf("expected repeated count of 4, got %d", r)
+	}
+
+	// Recovery goes through, and forgets the errors, so they're
+	// reported again straight away if they recur
+	throttle.LogEvent(syncEvent())
+	throttle.LogEvent(syncEvent("bad manifest"))
+	if len(*w) != 5 {
+		t.Fatalf("expected recovery and recurrence to go through, got %d events", len(*w))
+	}
+	if (*w)[4].Repeated != 0 {
+		t.Errorf("expected no repeated count after recovery, got %d", (*w)[4].Repeated)
+	}
+}
+
+func TestThrottlePassesOtherEvents(t *testing.T) {
+	w := &recordingWriter{}
+	throttle := &Throttle{Writer: w, Window: time.Hour}
+	for i := 0; i < 3; i++ {
+		throttle.LogEvent(Event{Type: EventLock, LogLevel: LogLevelInfo})
+	}
+	if len(*w) != 3 {
+		t.Errorf("expected all non-error events through, got %d", len(*w))
+	}
+}
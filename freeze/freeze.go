@@ -0,0 +1,176 @@
+/*
+Package freeze has calendars of release freezes: periods during which
+automated releases are suspended, and manual releases have to be
+forced.
+
+A calendar is read from a file or URL, and is either an iCalendar
+(e.g., a feed exported from a shared calendar), in which each event is
+a freeze, or YAML listing the freezes:
+
+	freezes:
+	- start: 2026-12-21T00:00:00Z
+	  end: 2027-01-04T00:00:00Z
+	  reason: End of year
+*/
+package freeze
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Window is a period of freeze, from Start (inclusive) to End
+// (exclusive).
+type Window struct {
+	Start  time.Time `yaml:"start"`
+	End    time.Time `yaml:"end"`
+	Reason string    `yaml:"reason"`
+}
+
+func (w Window) contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+func (w Window) String() string {
+	reason := w.Reason
+	if reason == "" {
+		reason = "release freeze"
+	}
+	return fmt.Sprintf("%s (%s to %s)", reason, w.Start.UTC().Format(time.RFC3339), w.End.UTC().Format(time.RFC3339))
+}
+
+// Schedule says whether there's a freeze at a given time.
+type Schedule interface {
+	// Active returns the freeze in effect at the time given, and
+	// true; or false if there's none
+	Active(t time.Time) (Window, bool)
+}
+
+// Windows is a fixed Schedule.
+type Windows []Window
+
+func (ws Windows) Active(t time.Time) (Window, bool) {
+	// If freezes overlap, report the one that ends last, since
+	// that's when releases can resume
+	var found Window
+	var ok bool
+	for _, w := range ws {
+		if w.contains(t) && (!ok || w.End.After(found.End)) {
+			found, ok = w, true
+		}
+	}
+	return found, ok
+}
+
+// Parse reads a calendar of freezes, either as an iCalendar or as
+// YAML.
+func Parse(data []byte) (Windows, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("BEGIN:VCALENDAR")) {
+		return ParseICal(data)
+	}
+	var config struct {
+		Freezes []Window `yaml:"freezes"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing freeze calendar")
+	}
+	for i, w := range config.Freezes {
+		if w.Start.IsZero() || w.End.IsZero() {
+			return nil, fmt.Errorf("freeze %d in calendar must have a start and an end", i+1)
+		}
+		if !w.End.After(w.Start) {
+			return nil, fmt.Errorf("freeze %d in calendar ends before it starts", i+1)
+		}
+	}
+	ws := Windows(config.Freezes)
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Start.Before(ws[j].Start) })
+	return ws, nil
+}
+
+// Calendar is a Schedule read from a file, or fetched from a URL,
+// and refreshed every Refresh.
+type Calendar struct {
+	Source  string
+	Refresh time.Duration
+	Client  *http.Client
+	Logger  log.Logger
+
+	mu      sync.RWMutex
+	windows Windows
+}
+
+// Load reads the calendar from its source. If it can't be read, the
+// freezes loaded previously are kept.
+func (c *Calendar) Load(ctx context.Context) error {
+	data, err := c.read(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "reading freeze calendar from %s", c.Source)
+	}
+	windows, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.windows = windows
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Calendar) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(c.Source, "http://") && !strings.HasPrefix(c.Source, "https://") {
+		return ioutil.ReadFile(c.Source)
+	}
+	req, err := http.NewRequest("GET", c.Source, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *Calendar) Active(t time.Time) (Window, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.windows.Active(t)
+}
+
+// Loop reloads the calendar every Refresh, until told to stop.
+func (c *Calendar) Loop(stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(c.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := c.Load(ctx); err != nil {
+				c.Logger.Log("freeze-calendar", c.Source, "err", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package freeze
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	ws, err := Parse([]byte(`
+freezes:
+- start: 2026-12-21T00:00:00Z
+  end: 2027-01-04T00:00:00Z
+  reason: End of year
+- start: 2026-11-01T09:00:00+01:00
+  end: 2026-11-01T17:00:00+01:00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 2 {
+		t.Fatalf("expected two freezes, got %v", ws)
+	}
+	if ws[0].Reason != "" || ws[1].Reason != "End of year" {
+		t.Errorf("expected freezes in order of start, got %v", ws)
+	}
+
+	for _, bad := range []string{
+		"freezes:\n- start: 2026-12-21T00:00:00Z\n",
+		"freezes:\n- start: 2026-12-21T00:00:00Z\n  end: 2026-12-20T00:00:00Z\n",
+		"freezes: [",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	ws := Windows{
+		{Start: at("2026-12-21T00:00:00Z"), End: at("2027-01-04T00:00:00Z"), Reason: "End of year"},
+		{Start: at("2026-12-31T00:00:00Z"), End: at("2027-01-10T00:00:00Z"), Reason: "Migration"},
+	}
+	for when, expected := range map[string]string{
+		"2026-12-20T23:59:59Z": "",
+		"2026-12-21T00:00:00Z": "End of year",
+		"2027-01-01T00:00:00Z": "Migration",
+		"2027-01-09T12:00:00Z": "Migration",
+		"2027-01-10T00:00:00Z": "",
+	} {
+		w, ok := ws.Active(at(when))
+		if ok != (expected != "") || w.Reason != expected {
+			t.Errorf("%s: expected freeze %q, got %q (%v)", when, expected, w.Reason, ok)
+		}
+	}
+}
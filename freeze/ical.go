@@ -0,0 +1,121 @@
+package freeze
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseICal reads the events in an iCalendar (RFC 5545) as freezes.
+// Only what's needed to find when each event happens is understood:
+// DTSTART, DTEND and SUMMARY, with dates, UTC or zoned times (times
+// without a zone are taken to be UTC). Recurring events are treated
+// as happening once, and cancelled events are ignored.
+func ParseICal(data []byte) (Windows, error) {
+	var windows Windows
+	var inEvent bool
+	var w Window
+	var startIsDate, cancelled bool
+	for n, line := range unfoldICal(string(data)) {
+		name, params, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, w, startIsDate, cancelled = true, Window{}, false, false
+		case !inEvent:
+			continue
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if cancelled {
+				continue
+			}
+			if w.Start.IsZero() {
+				return nil, fmt.Errorf("event ending on line %d of calendar has no start", n+1)
+			}
+			if w.End.IsZero() && startIsDate {
+				// An all-day event without an end lasts the day
+				w.End = w.Start.AddDate(0, 0, 1)
+			}
+			if w.End.After(w.Start) {
+				windows = append(windows, w)
+			}
+		case name == "DTSTART":
+			t, isDate, err := parseICalTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d of calendar: %s", n+1, err)
+			}
+			w.Start, startIsDate = t, isDate
+		case name == "DTEND":
+			t, _, err := parseICalTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d of calendar: %s", n+1, err)
+			}
+			w.End = t
+		case name == "SUMMARY":
+			w.Reason = unescapeICal(value)
+		case name == "STATUS":
+			cancelled = value == "CANCELLED"
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, nil
+}
+
+// unfoldICal splits the calendar into lines, joining any that have
+// been folded (continued on the next line, which starts with a space
+// or tab).
+func unfoldICal(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitICalLine splits a content line `NAME;PARAM=VALUE:value` into
+// its parts.
+func splitICalLine(line string) (name string, params map[string]string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params = map[string]string{}
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, strings.TrimSpace(line[colon+1:])
+}
+
+func parseICalTime(params map[string]string, value string) (time.Time, bool, error) {
+	loc := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		loc = l
+	}
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+var icalUnescaper = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+
+func unescapeICal(s string) string {
+	return icalUnescaper.Replace(s)
+}
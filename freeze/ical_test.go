@@ -0,0 +1,70 @@
+package freeze
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const calendar = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Calendar//EN
+BEGIN:VEVENT
+UID:1
+DTSTART;VALUE=DATE:20261224
+DTEND;VALUE=DATE:20261227
+SUMMARY:Christmas\, and
+  Boxing Day
+END:VEVENT
+BEGIN:VEVENT
+UID:2
+DTSTART;TZID=Europe/London:20261101T090000
+DTEND;TZID=Europe/London:20261101T170000
+SUMMARY:Conference
+END:VEVENT
+BEGIN:VEVENT
+UID:3
+DTSTART:20261001T120000Z
+DTEND:20261001T130000
+SUMMARY:Cancelled
+STATUS:CANCELLED
+END:VEVENT
+BEGIN:VEVENT
+UID:4
+DTSTART;VALUE=DATE:20261231
+SUMMARY:New Year's Eve
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParseICal(t *testing.T) {
+	ws, err := Parse([]byte(strings.Replace(calendar, "\n", "\r\n", -1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 3 {
+		t.Fatalf("expected three freezes, got %v", ws)
+	}
+
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	expected := Windows{
+		{Start: time.Date(2026, 11, 1, 9, 0, 0, 0, london), End: time.Date(2026, 11, 1, 17, 0, 0, 0, london), Reason: "Conference"},
+		{Start: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 12, 27, 0, 0, 0, 0, time.UTC), Reason: "Christmas, and Boxing Day"},
+		{Start: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), End: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Reason: "New Year's Eve"},
+	}
+	for i := range expected {
+		if !ws[i].Start.Equal(expected[i].Start) || !ws[i].End.Equal(expected[i].End) || ws[i].Reason != expected[i].Reason {
+			t.Errorf("freeze %d: expected %v, got %v", i, expected[i], ws[i])
+		}
+	}
+}
+
+func TestParseICalNoStart(t *testing.T) {
+	_, err := ParseICal([]byte("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Whenever\nEND:VEVENT\nEND:VCALENDAR\n"))
+	if err == nil {
+		t.Error("expected error for event without a start")
+	}
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Credentials are a username and password (or token) for a git repo
+// served over HTTPS.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// credentialHelper answers git's requests for credentials with those
+// in the environment, so the password isn't on the command line, nor
+// kept in the repo's config.
+const credentialHelper = `!f() { test "$1" = get && echo "username=${FLUX_GIT_USERNAME}" && echo "password=${FLUX_GIT_PASSWORD}"; }; f`
+
+// args gives the arguments that make git ask for the credentials,
+// ahead of the command.
+func (c Credentials) args() []string {
+	if c.Password == "" {
+		return nil
+	}
+	// The first, empty, helper resets any configured elsewhere,
+	// so only these credentials are used
+	return []string{"-c", "credential.helper=", "-c", "credential.helper=" + credentialHelper}
+}
+
+func (c Credentials) env() []string {
+	if c.Password == "" {
+		return nil
+	}
+	return []string{"FLUX_GIT_USERNAME=" + c.Username, "FLUX_GIT_PASSWORD=" + c.Password}
+}
+
+// CredentialsProvider gives the credentials for a git repo. It's
+// asked each time the repo is fetched from or pushed to, so it can
+// hand out a new token when the one before has expired, e.g., for a
+// GitHub App installation, or a cloud provider's IAM.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context, url string) (Credentials, error)
+}
+
+// CredentialsFunc is a function that's a CredentialsProvider.
+type CredentialsFunc func(ctx context.Context, url string) (Credentials, error)
+
+func (f CredentialsFunc) Credentials(ctx context.Context, url string) (Credentials, error) {
+	return f(ctx, url)
+}
+
+// StaticCredentials always gives the same username and token.
+func StaticCredentials(username, token string) CredentialsProvider {
+	return CredentialsFunc(func(context.Context, string) (Credentials, error) {
+		return Credentials{Username: username, Password: token}, nil
+	})
+}
+
+// TokenFile reads the token from a file each time it's asked, so the
+// file can be updated (e.g., a mounted secret, or one written by a
+// sidecar that refreshes the token) without restarting.
+type TokenFile struct {
+	Username string
+	Path     string
+}
+
+func (f TokenFile) Credentials(ctx context.Context, url string) (Credentials, error) {
+	token, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "reading git token file")
+	}
+	return Credentials{Username: f.Username, Password: strings.TrimSpace(string(token))}, nil
+}
+
+// TokenCommand runs a command, with `sh -c`, each time it's asked,
+// and uses what it prints as the token; e.g., `gcloud auth
+// print-access-token`.
+type TokenCommand struct {
+	Username string
+	Command  string
+}
+
+func (c TokenCommand) Credentials(ctx context.Context, url string) (Credentials, error) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stdout, cmd.Stderr = out, errOut
+	if err := cmd.Run(); err != nil {
+		return Credentials{}, errors.Wrapf(err, "running git token command: %s", strings.TrimSpace(errOut.String()))
+	}
+	token := strings.TrimSpace(out.String())
+	if token == "" {
+		return Credentials{}, errors.New("git token command printed no token")
+	}
+	return Credentials{Username: c.Username, Password: token}, nil
+}
+
+// credentials gives the credentials for the remote, if it has a
+// provider.
+func (r Remote) credentials(ctx context.Context) (Credentials, error) {
+	if r.Credentials == nil {
+		return Credentials{}, nil
+	}
+	creds, err := r.Credentials.Credentials(ctx, r.URL)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "getting credentials for %s", r.URL)
+	}
+	return creds, nil
+}
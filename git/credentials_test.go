@@ -0,0 +1,132 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+// httpRepo serves the repo in dir over HTTP, with git's own
+// http-backend, to those who give the token given.
+func httpRepo(t *testing.T, dir, token string) *httptest.Server {
+	execPath, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skipf("could not find git's exec path: %v", err)
+	}
+	backend := &cgi.Handler{
+		Path: filepath.Join(strings.TrimSpace(string(execPath)), "git-http-backend"),
+		Env:  []string{"GIT_PROJECT_ROOT=" + dir, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, ok := r.BasicAuth(); !ok || password != token {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+}
+
+func TestCredentials(t *testing.T) {
+	root, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := os.Mkdir(filepath.Join(root, "repo"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := createRepo(filepath.Join(root, "repo"), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	server := httpRepo(t, root, "s3cr3t")
+	defer server.Close()
+	url := server.URL + "/repo"
+	ctx := context.Background()
+
+	dir, cleanupDir := testfiles.TempDir(t)
+	defer cleanupDir()
+	if err := mirrorAsync(ctx, dir, url, 0, Credentials{}).Wait(); err == nil {
+		t.Error("expected cloning without credentials to fail")
+	}
+	if err := mirrorAsync(ctx, dir, url, 0, Credentials{Username: "flux", Password: "wrong"}).Wait(); err == nil {
+		t.Error("expected cloning with the wrong token to fail")
+	}
+
+	// The token can change from one fetch to the next
+	var mu sync.Mutex
+	token := "s3cr3t"
+	var asked int
+	repo := NewRepo(Remote{URL: url, Credentials: CredentialsFunc(func(_ context.Context, u string) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if u != url {
+			t.Errorf("expected credentials to be asked for %s, got %s", url, u)
+		}
+		asked++
+		return Credentials{Username: "flux", Password: token}, nil
+	})}, ReadOnly)
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if asked < 3 {
+		t.Errorf("expected credentials to be asked for each time the repo was fetched from, got %d times", asked)
+	}
+	token = "expired"
+	mu.Unlock()
+	if err := repo.Refresh(ctx); err == nil {
+		t.Error("expected fetching with an expired token to fail")
+	}
+
+	// The token isn't kept in the repo's config
+	config, err := ioutil.ReadFile(filepath.Join(repo.Dir(), "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(config), "s3cr3t") {
+		t.Error("expected the token not to be written to the repo's config")
+	}
+}
+
+func TestTokenProviders(t *testing.T) {
+	dir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "token")
+	ctx := context.Background()
+
+	for _, token := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		creds, err := TokenFile{Username: "flux", Path: path}.Credentials(ctx, "https://example.com/repo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds != (Credentials{Username: "flux", Password: token}) {
+			t.Errorf("expected the token in the file, %q, got %+v", token, creds)
+		}
+	}
+
+	creds, err := TokenCommand{Username: "flux", Command: "cat " + path}.Credentials(ctx, "https://example.com/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Password != "second" {
+		t.Errorf("expected the token printed by the command, got %+v", creds)
+	}
+	if _, err := (TokenCommand{Command: "true"}).Credentials(ctx, "https://example.com/repo"); err == nil {
+		t.Error("expected an error when the command prints no token")
+	}
+}
@@ -21,12 +21,19 @@ func (e *Export) Clean() {
 
 // Export creates a minimal clone of the repo, at the ref given.
 func (r *Repo) Export(ctx context.Context, ref string) (*Export, error) {
-	dir, err := r.workingClone(ctx, "")
+	dir, err := r.workingClone(ctx, "", nil)
 	if err != nil {
 		return nil, err
 	}
 	if err = checkout(ctx, dir, ref); err != nil {
+		os.RemoveAll(dir)
 		return nil, err
 	}
+	if r.submodules {
+		if err := r.updateSubmodules(ctx, dir, r.Origin().URL); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
 	return &Export{dir}, nil
 }
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommitSignature is what git makes of the GPG signature on a commit.
+type CommitSignature struct {
+	Revision string
+	// As given by git's `%G?`: G for a good signature, U for a good
+	// signature from a key of unknown validity, N for no signature,
+	// B for a bad signature, E if it can't be checked (e.g., the
+	// key isn't in the keyring), and X, Y or R for an expired
+	// signature, expired key or revoked key
+	Status string
+	// The fingerprints of the key that made the signature, and its
+	// primary key, if the signature could be checked
+	Fingerprint        string
+	PrimaryFingerprint string
+}
+
+var signatureProblems = map[string]string{
+	"N": "not signed",
+	"B": "bad signature",
+	"E": "signature can't be checked; the key may not have been imported",
+	"X": "signature has expired",
+	"Y": "signed by a key that has expired",
+	"R": "signed by a key that has been revoked",
+}
+
+// Verify gives an error saying why the commit isn't verified, if it
+// isn't signed by one of the keys given. The keys are fingerprints
+// (or long key IDs, i.e., the end of a fingerprint); if none are
+// given, any key in the keyring will do. Keys in the keyring are
+// taken as trusted, so a good signature of unknown validity is
+// accepted.
+func (s CommitSignature) Verify(keys []string) error {
+	if s.Status != "G" && s.Status != "U" {
+		if problem, ok := signatureProblems[s.Status]; ok {
+			return errors.New(problem)
+		}
+		return fmt.Errorf("unknown signature status %q", s.Status)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	for _, key := range keys {
+		key = strings.ToUpper(strings.Replace(key, " ", "", -1))
+		if key != "" && (strings.HasSuffix(s.Fingerprint, key) || strings.HasSuffix(s.PrimaryFingerprint, key)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signed by key %s, which is not one of the keys trusted", s.Fingerprint)
+}
+
+// commitSignatures gives the signatures of the commits given by the
+// revisions (as for `git log`), oldest first.
+func commitSignatures(ctx context.Context, workingDir string, revs ...string) ([]CommitSignature, error) {
+	out := &bytes.Buffer{}
+	args := append([]string{"log", "--reverse", "--pretty=format:%H|%G?|%GF|%GP"}, revs...)
+	if err := execGitCmd(ctx, workingDir, out, args...); err != nil {
+		return nil, err
+	}
+	var sigs []CommitSignature
+	for _, line := range splitList(out.String()) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected signature line %q", line)
+		}
+		sigs = append(sigs, CommitSignature{
+			Revision:           fields[0],
+			Status:             fields[1],
+			Fingerprint:        fields[2],
+			PrimaryFingerprint: fields[3],
+		})
+	}
+	return sigs, nil
+}
+
+// ImportKeys imports the GPG keys in the files given, or in the files
+// in the directories given, into the keyring used to check commit
+// signatures (that of $GNUPGHOME, if set).
+func ImportKeys(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return err
+		} else if info.IsDir() {
+			infos, err := ioutil.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			files = nil
+			for _, info := range infos {
+				// e.g., the ..data links in a mounted secret
+				if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+					files = append(files, filepath.Join(path, info.Name()))
+				}
+			}
+		}
+		for _, file := range files {
+			errOut := &bytes.Buffer{}
+			c := exec.CommandContext(ctx, "gpg", "--batch", "--import", file)
+			c.Stderr = errOut
+			if err := c.Run(); err != nil {
+				return errors.Wrapf(err, "importing GPG keys from %s: %s", file, strings.TrimSpace(errOut.String()))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestVerifySignature(t *testing.T) {
+	good := CommitSignature{Status: "U", Fingerprint: "F9AD2239E21DD09400A7436C59183BF4B4B5AD54", PrimaryFingerprint: "F9AD2239E21DD09400A7436C59183BF4B4B5AD54"}
+	for _, keys := range [][]string{nil, {"F9AD2239E21DD09400A7436C59183BF4B4B5AD54"}, {"other", "59183bf4b4b5ad54"}} {
+		if err := good.Verify(keys); err != nil {
+			t.Errorf("expected a good signature to be verified with keys %v, got %v", keys, err)
+		}
+	}
+	if err := good.Verify([]string{"0123456789ABCDEF"}); err == nil {
+		t.Error("expected a signature from a key not trusted not to be verified")
+	}
+	for _, status := range []string{"N", "B", "E", "X", "Y", "R", "?"} {
+		if err := (CommitSignature{Status: status}).Verify(nil); err == nil {
+			t.Errorf("expected a signature with status %q not to be verified", status)
+		}
+	}
+}
+
+// gpgHome makes a keyring with a new signing key in it, and uses it
+// for the test; it gives the key's fingerprint.
+func gpgHome(t *testing.T) (string, string, func()) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not available")
+	}
+	home, cleanup := testfiles.TempDir(t)
+	old, wasSet := os.LookupEnv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", home)
+	restore := func() {
+		if wasSet {
+			os.Setenv("GNUPGHOME", old)
+		} else {
+			os.Unsetenv("GNUPGHOME")
+		}
+		cleanup()
+	}
+	if err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "Flux Test <flux@example.com>", "ed25519", "sign", "never").Run(); err != nil {
+		restore()
+		t.Skipf("could not generate a GPG key: %v", err)
+	}
+	out, err := exec.Command("gpg", "--batch", "--with-colons", "--list-keys").Output()
+	if err != nil {
+		restore()
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "fpr:") {
+			return home, strings.Split(line, ":")[9], restore
+		}
+	}
+	restore()
+	t.Fatal("no fingerprint for the key generated")
+	return "", "", nil
+}
+
+func TestCommitSignatures(t *testing.T) {
+	home, fingerprint, cleanup := gpgHome(t)
+	defer cleanup()
+
+	upstream, cleanupUpstream := testfiles.TempDir(t)
+	defer cleanupUpstream()
+	if err := createRepo(upstream, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := updateFile(filepath.Join(upstream, "a"), map[string]string{"signed.yaml": "kind: ConfigMap"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", upstream, "add", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(ctx, upstream, fingerprint, CommitAction{Message: "Signed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// As for a commit flux has made in a working clone
+	sig, err := (&Checkout{dir: upstream}).HeadSignature(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.Fingerprint != fingerprint {
+		t.Errorf("expected the head commit to be signed with %s, got %+v", fingerprint, sig)
+	}
+
+	repo := NewRepo(Remote{URL: upstream}, ReadOnly)
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sigs, err := repo.CommitSignatures(ctx, "", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sigs) != 1 || sigs[0].Verify([]string{fingerprint}) != nil {
+		t.Fatalf("expected the head commit to be signed with %s, got %+v", fingerprint, sigs)
+	}
+
+	// The commits before the signed one aren't signed
+	sigs, err = repo.CommitSignatures(ctx, "master~2", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sigs) != 2 || sigs[0].Status != "N" || sigs[1].Status != "G" {
+		t.Errorf("expected an unsigned then a signed commit, oldest first, got %+v", sigs)
+	}
+
+	// With the key imported into another keyring, it's still good
+	exported, err := exec.Command("gpg", "--batch", "--armor", "--export", fingerprint).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, cleanupKeys := testfiles.TempDir(t)
+	defer cleanupKeys()
+	if err := ioutil.WriteFile(filepath.Join(keys, "flux.asc"), exported, 0600); err != nil {
+		t.Fatal(err)
+	}
+	other, cleanupOther := testfiles.TempDir(t)
+	defer cleanupOther()
+	os.Setenv("GNUPGHOME", other)
+	defer os.Setenv("GNUPGHOME", home)
+	if sigs, err = repo.CommitSignatures(ctx, "", "master"); err != nil || sigs[0].Verify(nil) == nil {
+		t.Fatalf("expected the signature not to be checked without the key, got %+v (%v)", sigs, err)
+	}
+	if err := ImportKeys(ctx, []string{keys}); err != nil {
+		t.Fatal(err)
+	}
+	if sigs, err = repo.CommitSignatures(ctx, "", "master"); err != nil || sigs[0].Verify([]string{fingerprint}) != nil {
+		t.Errorf("expected the signature to be good once the key was imported, got %+v (%v)", sigs, err)
+	}
+}
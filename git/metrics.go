@@ -0,0 +1,37 @@
+package git
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+)
+
+var (
+	// Fetches and pushes usually take a second or two; clones of a
+	// big repo can take minutes.
+	operationDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "flux",
+		Subsystem: "git",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of each try at cloning, fetching from, or pushing to the upstream git repo, in seconds.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{fluxmetrics.LabelOperation, fluxmetrics.LabelSuccess})
+
+	retriedOperations = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "flux",
+		Subsystem: "git",
+		Name:      "operation_retries_total",
+		Help:      "Count of clones, fetches and pushes tried again after failing.",
+	}, []string{fluxmetrics.LabelOperation})
+)
+
+func observeOperation(operation string, started time.Time, err error) {
+	operationDuration.With(
+		fluxmetrics.LabelOperation, operation,
+		fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+	).Observe(time.Since(started).Seconds())
+}
@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"context"
 
@@ -43,14 +47,64 @@ func clone(ctx context.Context, workingDir, repoURL, repoBranch string) (path st
 	return repoPath, nil
 }
 
-func mirror(ctx context.Context, workingDir, repoURL string) (path string, err error) {
-	repoPath := workingDir
-	args := []string{"clone", "--mirror"}
-	args = append(args, repoURL, repoPath)
+func mirror(ctx context.Context, workingDir, repoURL string, depth int, creds Credentials) (path string, err error) {
+	if err := mirrorAsync(ctx, workingDir, repoURL, depth, creds).Wait(); err != nil {
+		return "", err
+	}
+	return workingDir, nil
+}
+
+// mirrorAsync starts a mirror clone of the repo into workingDir, and
+// returns without waiting for it to finish. If depth is more than
+// zero, the clone is shallow, with only that many commits of the
+// history of each ref; fetching adds to it from there.
+func mirrorAsync(ctx context.Context, workingDir, repoURL string, depth int, creds Credentials) *Operation {
+	args := []string{"clone", "--mirror", "--progress"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	args = append(args, repoURL, workingDir)
+	return startGitCmd(ctx, workingDir, "clone", creds, func(err error) error {
+		if err != nil {
+			return errors.Wrap(err, "git clone --mirror")
+		}
+		return nil
+	}, args...)
+}
+
+// sparseClone clones the repo without checking anything out, then
+// checks out only the paths given, at the branch. This uses
+// core.sparseCheckout rather than `git sparse-checkout`, so it works
+// with older versions of git.
+func sparseClone(ctx context.Context, workingDir, repoURL, repoBranch string, paths []string) (path string, err error) {
+	args := []string{"clone", "--no-checkout"}
+	if repoBranch != "" {
+		args = append(args, "--branch", repoBranch)
+	}
+	args = append(args, repoURL, workingDir)
 	if err := execGitCmd(ctx, workingDir, nil, args...); err != nil {
-		return "", errors.Wrap(err, "git clone --mirror")
+		return "", errors.Wrap(err, "git clone --no-checkout")
 	}
-	return repoPath, nil
+	if err := execGitCmd(ctx, workingDir, nil, "config", "core.sparseCheckout", "true"); err != nil {
+		return "", errors.Wrap(err, "setting git config")
+	}
+	if err := ioutil.WriteFile(filepath.Join(workingDir, ".git", "info", "sparse-checkout"), []byte(sparsePatterns(paths)), 0600); err != nil {
+		return "", errors.Wrap(err, "writing sparse-checkout patterns")
+	}
+	if err := execGitCmd(ctx, workingDir, nil, "read-tree", "-mu", "HEAD"); err != nil {
+		return "", errors.Wrap(err, "git read-tree")
+	}
+	return workingDir, nil
+}
+
+// sparsePatterns gives the patterns for checking out just the
+// directories given, relative to the top of the repo.
+func sparsePatterns(paths []string) string {
+	var patterns string
+	for _, p := range paths {
+		patterns += "/" + strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/") + "/\n"
+	}
+	return patterns
 }
 
 func checkout(ctx context.Context, workingDir, ref string) error {
@@ -60,56 +114,66 @@ func checkout(ctx context.Context, workingDir, ref string) error {
 // checkPush sanity-checks that we can write to the upstream repo
 // (being able to `clone` is an adequate check that we can read the
 // upstream).
-func checkPush(ctx context.Context, workingDir, upstream string) error {
+func checkPush(ctx context.Context, workingDir, upstream string, creds Credentials) error {
 	// --force just in case we fetched the tag from upstream when cloning
 	if err := execGitCmd(ctx, workingDir, nil, "tag", "--force", CheckPushTag); err != nil {
 		return errors.Wrap(err, "tag for write check")
 	}
-	if err := execGitCmd(ctx, workingDir, nil, "push", "--force", upstream, "tag", CheckPushTag); err != nil {
+	if err := execGitCmdProgress(ctx, workingDir, nil, nil, creds, "push", "--force", upstream, "tag", CheckPushTag); err != nil {
 		return errors.Wrap(err, "attempt to push tag")
 	}
-	return execGitCmd(ctx, workingDir, nil, "push", "--delete", upstream, "tag", CheckPushTag)
+	return execGitCmdProgress(ctx, workingDir, nil, nil, creds, "push", "--delete", upstream, "tag", CheckPushTag)
 }
 
-func commit(ctx context.Context, workingDir string, commitAction CommitAction) error {
-	commitAuthor := commitAction.Author
-	if commitAuthor != "" {
-		if err := execGitCmd(ctx,
-			workingDir, nil,
-			"commit",
-			"--no-verify", "-a", "--author", commitAuthor, "-m", commitAction.Message,
-		); err != nil {
-			return errors.Wrap(err, "git commit")
-		}
-		return nil
+// commit commits all the changes in the working dir; if signingKey
+// is given, the commit is signed with that GPG key.
+func commit(ctx context.Context, workingDir, signingKey string, commitAction CommitAction) error {
+	args := []string{"commit", "--no-verify", "-a"}
+	if commitAuthor := commitAction.Author; commitAuthor != "" {
+		args = append(args, "--author", commitAuthor)
+	}
+	if signingKey != "" {
+		args = append(args, "--gpg-sign="+signingKey)
 	}
-	if err := execGitCmd(ctx,
-		workingDir, nil,
-		"commit",
-		"--no-verify", "-a", "-m", commitAction.Message,
-	); err != nil {
+	args = append(args, "-m", commitAction.Message)
+	if err := execGitCmd(ctx, workingDir, nil, args...); err != nil {
 		return errors.Wrap(err, "git commit")
 	}
 	return nil
 }
 
 // push the refs given to the upstream repo
-func push(ctx context.Context, workingDir, upstream string, refs []string) error {
+func push(ctx context.Context, workingDir, upstream string, creds Credentials, refs []string) error {
 	args := append([]string{"push", upstream}, refs...)
-	if err := execGitCmd(ctx, workingDir, nil, args...); err != nil {
+	if err := execGitCmdProgress(ctx, workingDir, nil, nil, creds, args...); err != nil {
 		return errors.Wrap(err, fmt.Sprintf("git push %s %s", upstream, refs))
 	}
 	return nil
 }
 
 // fetch updates refs from the upstream.
-func fetch(ctx context.Context, workingDir, upstream string, refspec ...string) error {
-	args := append([]string{"fetch", "--tags", upstream}, refspec...)
-	if err := execGitCmd(ctx, workingDir, nil, args...); err != nil &&
-		!strings.Contains(err.Error(), "Couldn't find remote ref") {
-		return errors.Wrap(err, fmt.Sprintf("git fetch --tags %s %s", upstream, refspec))
-	}
-	return nil
+func fetch(ctx context.Context, workingDir, upstream string, creds Credentials, refspec ...string) error {
+	return fetchAsync(ctx, workingDir, upstream, creds, refspec...).Wait()
+}
+
+// fetchAsync starts fetching refs from the upstream, and returns
+// without waiting for it to finish.
+func fetchAsync(ctx context.Context, workingDir, upstream string, creds Credentials, refspec ...string) *Operation {
+	args := append([]string{"fetch", "--progress", "--tags", upstream}, refspec...)
+	if len(refspec) > 0 {
+		// --tags won't move a tag that's been moved upstream (and
+		// fails the fetch instead), so the tags are forced. With no
+		// refspec, it's the mirror's own (+refs/*:refs/*) that's
+		// used, which forces them already; and --force would let a
+		// branch be rewritten without it being noticed.
+		args = append(args, "+refs/tags/*:refs/tags/*")
+	}
+	return startGitCmd(ctx, workingDir, "fetch", creds, func(err error) error {
+		if err != nil && !strings.Contains(err.Error(), "Couldn't find remote ref") {
+			return errors.Wrap(err, fmt.Sprintf("git fetch --tags %s %s", upstream, refspec))
+		}
+		return nil
+	}, args...)
 }
 
 func refExists(ctx context.Context, workingDir, ref string) (bool, error) {
@@ -181,6 +245,63 @@ func refRevision(ctx context.Context, path, ref string) (string, error) {
 	return strings.TrimSpace(out.String()), nil
 }
 
+// addWorktree adds a working tree of the repo at `path`, in `dir`,
+// with the revision given checked out (and not a branch, so any
+// number can be added).
+func addWorktree(ctx context.Context, path, dir, rev string) error {
+	// Forget any trees that were removed
+	if err := execGitCmd(ctx, path, nil, "worktree", "prune"); err != nil {
+		return errors.Wrap(err, "git worktree prune")
+	}
+	if err := execGitCmd(ctx, path, nil, "worktree", "add", "--detach", dir, rev); err != nil {
+		return errors.Wrap(err, "git worktree add")
+	}
+	return nil
+}
+
+// resetWorktree checks out the revision given in the working tree,
+// throwing away anything left in it.
+func resetWorktree(ctx context.Context, dir, rev string) error {
+	if err := execGitCmd(ctx, dir, nil, "checkout", "--force", "--detach", rev); err != nil {
+		return errors.Wrap(err, "git checkout")
+	}
+	return execGitCmd(ctx, dir, nil, "clean", "-ffdx")
+}
+
+// isAncestor says whether `ancestor` is in the history of `rev`
+// (including being `rev` itself).
+func isAncestor(ctx context.Context, path, ancestor, rev string) (bool, error) {
+	// `git merge-base --is-ancestor` would do, but it says no by
+	// exiting with an error, which can't be told apart from other
+	// errors
+	out := &bytes.Buffer{}
+	if err := execGitCmd(ctx, path, out, "rev-list", "--max-count", "1", rev+".."+ancestor); err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out.String()) == "", nil
+}
+
+// isHistoryError says whether the error from a fetch is because a
+// ref was rejected as not a fast-forward, i.e., the upstream history
+// was rewritten in a way the repo fetched into can't follow; rather
+// than because, e.g., the upstream couldn't be reached, or the repo
+// fetched into is broken.
+func isHistoryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{
+		"non-fast-forward",
+		"[rejected]",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func revlist(ctx context.Context, path, ref string) ([]string, error) {
 	out := &bytes.Buffer{}
 	if err := execGitCmd(ctx, path, out, "rev-list", ref); err != nil {
@@ -189,10 +310,10 @@ func revlist(ctx context.Context, path, ref string) ([]string, error) {
 	return splitList(out.String()), nil
 }
 
-// Return the revisions and one-line log commit messages
+// Return the revisions, commit times and one-line log commit messages
 func onelinelog(ctx context.Context, path, refspec string, subdirs []string) ([]Commit, error) {
 	out := &bytes.Buffer{}
-	args := []string{"log", "--oneline", "--no-abbrev-commit", refspec}
+	args := []string{"log", "--pretty=format:%H %ct %s", refspec}
 	if len(subdirs) > 0 {
 		args = append(args, "--")
 		args = append(args, subdirs...)
@@ -205,17 +326,64 @@ func onelinelog(ctx context.Context, path, refspec string, subdirs []string) ([]
 	return splitLog(out.String())
 }
 
+// Return the revisions, commit times and whole commit messages, of the
+// commits given by the arguments (e.g., a refspec)
+func fulllog(ctx context.Context, path string, args ...string) ([]Commit, error) {
+	out := &bytes.Buffer{}
+	// The messages may have more than one line, so the commits are
+	// separated with NULs rather than newlines
+	args = append([]string{"log", "-z", "--pretty=format:%H %ct %B"}, args...)
+	if err := execGitCmd(ctx, path, out, args...); err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, entry := range strings.Split(out.String(), "\x00") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		c, err := parseLogEntry(strings.TrimLeft(entry, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		c.Message = strings.TrimSpace(c.Message)
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
 func splitLog(s string) ([]Commit, error) {
 	lines := splitList(s)
 	commits := make([]Commit, len(lines))
 	for i, m := range lines {
-		revAndMessage := strings.SplitN(m, " ", 2)
-		commits[i].Revision = revAndMessage[0]
-		commits[i].Message = revAndMessage[1]
+		c, err := parseLogEntry(m)
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = c
 	}
 	return commits, nil
 }
 
+// parseLogEntry parses a commit given as `<revision> <time> <message>`.
+func parseLogEntry(m string) (Commit, error) {
+	var c Commit
+	fields := strings.SplitN(m, " ", 3)
+	if len(fields) < 2 {
+		return c, fmt.Errorf("unexpected line in git log: %q", m)
+	}
+	c.Revision = fields[0]
+	secs, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return c, errors.Wrapf(err, "parsing commit time of %s", fields[0])
+	}
+	c.Time = time.Unix(secs, 0).UTC()
+	if len(fields) > 2 {
+		c.Message = fields[2]
+	}
+	return c, nil
+}
+
 func splitList(s string) []string {
 	outStr := strings.TrimSpace(s)
 	if outStr == "" {
@@ -224,17 +392,63 @@ func splitList(s string) []string {
 	return strings.Split(outStr, "\n")
 }
 
+// tags lists the tags in the repo, with the commit each is of.
+func tags(ctx context.Context, path string) ([]Tag, error) {
+	out := &bytes.Buffer{}
+	// %(*objectname) is the commit an annotated tag is of; for a
+	// lightweight tag, it's empty, and %(objectname) is the commit
+	if err := execGitCmd(ctx, path, out, "for-each-ref", "--format=%(refname)|%(*objectname)|%(objectname)|%(creatordate:unix)", "refs/tags"); err != nil {
+		return nil, err
+	}
+	var result []Tag
+	for _, line := range splitList(out.String()) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected tag line %q", line)
+		}
+		rev := fields[1]
+		if rev == "" {
+			rev = fields[2]
+		}
+		secs, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing time of tag %s", fields[0])
+		}
+		result = append(result, Tag{
+			Name:     strings.TrimPrefix(fields[0], "refs/tags/"),
+			Revision: rev,
+			Time:     time.Unix(secs, 0),
+		})
+	}
+	return result, nil
+}
+
 // Move the tag to the ref given and push that tag upstream
-func moveTagAndPush(ctx context.Context, path string, tag, ref, msg, upstream string) error {
+func moveTagAndPush(ctx context.Context, path string, tag, ref, msg, upstream string, creds Credentials) error {
 	if err := execGitCmd(ctx, path, nil, "tag", "--force", "-a", "-m", msg, tag, ref); err != nil {
 		return errors.Wrap(err, "moving tag "+tag)
 	}
-	if err := execGitCmd(ctx, path, nil, "push", "--force", upstream, "tag", tag); err != nil {
+	if err := execGitCmdProgress(ctx, path, nil, nil, creds, "push", "--force", upstream, "tag", tag); err != nil {
 		return errors.Wrap(err, "pushing tag to origin")
 	}
 	return nil
 }
 
+// diff gives the changes in the working dir since the ref, to the
+// files under the paths given, as a patch.
+func diff(ctx context.Context, workingDir, ref string, subPaths []string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	args := []string{"diff", "--no-color", ref}
+	if len(subPaths) > 0 {
+		args = append(args, "--")
+		args = append(args, subPaths...)
+	}
+	if err := execGitCmd(ctx, workingDir, out, args...); err != nil {
+		return nil, errors.Wrap(err, "diffing changes")
+	}
+	return out.Bytes(), nil
+}
+
 func changed(ctx context.Context, path, ref string, subPaths []string) ([]string, error) {
 	out := &bytes.Buffer{}
 	// This uses --diff-filter to only look at changes for file _in
@@ -253,6 +467,13 @@ func changed(ctx context.Context, path, ref string, subPaths []string) ([]string
 }
 
 func execGitCmd(ctx context.Context, dir string, out io.Writer, args ...string) error {
+	return execGitCmdProgress(ctx, dir, out, nil, Credentials{}, args...)
+}
+
+// execGitCmdProgress runs a git command, giving any progress it
+// writes to stderr to `progress`, if that's not nil, and any
+// credentials given to git if it asks for them.
+func execGitCmdProgress(ctx context.Context, dir string, out io.Writer, progress func(Progress), creds Credentials, args ...string) error {
 	if trace {
 		print("TRACE: git")
 		for _, arg := range args {
@@ -260,18 +481,21 @@ func execGitCmd(ctx context.Context, dir string, out io.Writer, args ...string)
 		}
 		println()
 	}
-	c := exec.CommandContext(ctx, "git", args...)
+	c := exec.CommandContext(ctx, "git", append(creds.args(), args...)...)
 
 	if dir != "" {
 		c.Dir = dir
 	}
-	c.Env = env()
+	c.Env = append(env(), creds.env()...)
 	c.Stdout = ioutil.Discard
 	if out != nil {
 		c.Stdout = out
 	}
 	errOut := &bytes.Buffer{}
 	c.Stderr = errOut
+	if progress != nil {
+		c.Stderr = io.MultiWriter(errOut, &progressWriter{report: progress})
+	}
 
 	err := c.Run()
 	if err != nil {
@@ -289,7 +513,12 @@ func execGitCmd(ctx context.Context, dir string, out io.Writer, args ...string)
 }
 
 func env() []string {
-	return []string{"GIT_TERMINAL_PROMPT=0"}
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	// So that git finds the keys for signing and verifying commits
+	if gnupgHome := os.Getenv("GNUPGHOME"); gnupgHome != "" {
+		env = append(env, "GNUPGHOME="+gnupgHome)
+	}
+	return env
 }
 
 // check returns true if there are changes locally.
@@ -304,9 +533,14 @@ func check(ctx context.Context, workingDir string, subdirs []string) bool {
 }
 
 func findErrorMessage(output io.Reader) string {
+	var rejected string
 	sc := bufio.NewScanner(output)
 	for sc.Scan() {
 		switch {
+		case rejected == "" && strings.Contains(sc.Text(), "[rejected]"):
+			// e.g., a fetch that isn't a fast-forward, which
+			// gives no other message
+			rejected = strings.TrimSpace(sc.Text())
 		case strings.HasPrefix(sc.Text(), "fatal: "):
 			return sc.Text()
 		case strings.HasPrefix(sc.Text(), "ERROR fatal: "): // Saw this error on ubuntu systems
@@ -315,5 +549,5 @@ func findErrorMessage(output io.Reader) string {
 			return strings.Trim(sc.Text(), "error: ")
 		}
 	}
-	return ""
+	return rejected
 }
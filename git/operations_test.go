@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
 )
@@ -170,6 +171,22 @@ func TestOnelinelog_NoGitpath(t *testing.T) {
 	}
 }
 
+func TestSplitLog(t *testing.T) {
+	commits, err := splitLog("a1b2c3 1500000000 Release helloworld\nd4e5f6 1500000060 \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected two commits, got %d", len(commits))
+	}
+	if commits[0].Revision != "a1b2c3" || commits[0].Message != "Release helloworld" || !commits[0].Time.Equal(time.Unix(1500000000, 0)) {
+		t.Errorf("unexpected commit %+v", commits[0])
+	}
+	if commits[1].Revision != "d4e5f6" || commits[1].Message != "" {
+		t.Errorf("expected commit with empty message, got %+v", commits[1])
+	}
+}
+
 func TestOnelinelog_WithGitpath(t *testing.T) {
 	newDir, cleanup := testfiles.TempDir(t)
 	defer cleanup()
@@ -198,6 +215,37 @@ func TestOnelinelog_WithGitpath(t *testing.T) {
 	}
 }
 
+func TestFulllog(t *testing.T) {
+	newDir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+
+	if err := createRepo(newDir, []string{"dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", newDir, "commit", "--allow-empty", "-m", "Scale down", "-m", "Not yet\n\n[flux skip]"); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := fulllog(context.Background(), newDir, "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected one commit, got %+v", commits)
+	}
+	if expected := "Scale down\n\nNot yet\n\n[flux skip]"; commits[0].Message != expected {
+		t.Errorf("expected message %q, got %q", expected, commits[0].Message)
+	}
+
+	commits, err = fulllog(context.Background(), newDir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 || commits[2].Message != "'Initial revision'" || commits[2].Time.IsZero() {
+		t.Errorf("expected the whole history, got %+v", commits)
+	}
+}
+
 func TestCheckPush(t *testing.T) {
 	upstreamDir, upstreamCleanup := testfiles.TempDir(t)
 	defer upstreamCleanup()
@@ -210,7 +258,7 @@ func TestCheckPush(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = checkPush(context.Background(), working, upstreamDir)
+	err = checkPush(context.Background(), working, upstreamDir, Credentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -285,3 +333,168 @@ func updateDirAndCommit(dir, subdir string, filesUpdated map[string]string) erro
 	}
 	return nil
 }
+
+func TestShallowClone(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	repo := NewRepo(Remote{URL: "file://" + upstream}, ReadOnly, CloneDepth(1))
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := repo.CommitsBefore(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 {
+		t.Errorf("expected only the most recent commit in a clone with depth 1, got %+v", commits)
+	}
+
+	// New commits are fetched as usual
+	if err = execCommand("git", "-C", upstream, "commit", "--allow-empty", "-m", "'Third revision'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if commits, err = repo.CommitsBefore(ctx, "master"); err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Errorf("expected the new commit to be fetched on top of the shallow clone, got %+v", commits)
+	}
+}
+
+func TestSparseClone(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	working, cleanupWorking := testfiles.TempDir(t)
+	defer cleanupWorking()
+
+	ctx := context.Background()
+	dir, err := sparseClone(ctx, working, upstream, "master", []string{"./a/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err := ioutil.ReadDir(filepath.Join(dir, "a")); err != nil || len(files) == 0 {
+		t.Errorf("expected the path given to be checked out, got %v (%v)", files, err)
+	}
+	if _, err := ioutil.ReadDir(filepath.Join(dir, "b")); err == nil {
+		t.Error("expected other paths not to be checked out")
+	}
+	// The files not checked out aren't seen as deleted
+	if check(ctx, dir, nil) {
+		t.Error("expected no changes in a sparse checkout")
+	}
+}
+
+func TestSparsePaths(t *testing.T) {
+	for _, c := range []struct {
+		conf     Config
+		expected []string
+	}{
+		{Config{Paths: []string{"deploy"}}, nil},
+		{Config{Paths: []string{"deploy", "base"}, SparseCheckout: true}, []string{"deploy", "base"}},
+		{Config{Paths: []string{"deploy", "./"}, SparseCheckout: true}, nil},
+		{Config{SparseCheckout: true}, nil},
+	} {
+		if got := c.conf.sparsePaths(); fmt.Sprint(got) != fmt.Sprint(c.expected) {
+			t.Errorf("%+v: expected %v, got %v", c.conf, c.expected, got)
+		}
+	}
+	if patterns := sparsePatterns([]string{"./deploy/", "clusters/prod"}); patterns != "/deploy/\n/clusters/prod/\n" {
+		t.Errorf("unexpected patterns %q", patterns)
+	}
+}
+
+func TestTags(t *testing.T) {
+	dir, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(dir, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	first, err := refRevision(ctx, dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := refRevision(ctx, dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", dir, "tag", "prod-1", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", dir, "tag", "-a", "-m", "Promote", "prod-2", head); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := tags(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revs := map[string]string{}
+	for _, tag := range ts {
+		if tag.Time.IsZero() {
+			t.Errorf("expected tag %s to have a time", tag.Name)
+		}
+		revs[tag.Name] = tag.Revision
+	}
+	// An annotated tag gives the commit it's of, not the tag itself
+	if len(revs) != 2 || revs["prod-1"] != first || revs["prod-2"] != head {
+		t.Errorf("expected prod-1 at %s and prod-2 at %s, got %v", first, head, revs)
+	}
+}
+
+func TestFetchMovedTag(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", upstream, "tag", "prod", "HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	clone, cleanupClone := testfiles.TempDir(t)
+	defer cleanupClone()
+	if err := execCommand("git", "clone", upstream, clone); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving the tag upstream would make a plain fetch --tags fail
+	if err := execCommand("git", "-C", upstream, "tag", "-f", "prod", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := fetch(ctx, clone, upstream, Credentials{}, "refs/heads/master:refs/remotes/origin/master"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := refRevision(ctx, upstream, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := refRevision(ctx, clone, "prod"); err != nil || rev != head {
+		t.Errorf("expected the tag to have moved to %s, got %s (%v)", head, rev, err)
+	}
+}
+
+func TestIsHistoryError(t *testing.T) {
+	for msg, expected := range map[string]bool{
+		" ! [rejected]        master     -> master  (non-fast-forward)": true,
+		"fatal: shallow file has changed since we read it":              false,
+		"fatal: bad object refs/heads/master":                           false,
+		"fatal: unable to access 'https://example.com/repo/'":           false,
+	} {
+		if got := isHistoryError(fmt.Errorf("%s", msg)); got != expected {
+			t.Errorf("expected %v for %q, got %v", expected, msg, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultTreePoolSize is how many working trees are kept for reuse,
+// if not given.
+const DefaultTreePoolSize = 4
+
+// TreePool says how many working trees (for read-only checkouts) are
+// kept for reuse; zero means each is removed once it's done with.
+type TreePool int
+
+func (p TreePool) apply(r *Repo) {
+	r.trees.size = int(p)
+}
+
+// treePool keeps the working trees that read-only checkouts are
+// made in. These are worktrees of the mirror, so making one doesn't
+// copy the repo, and they can be checked out while another checkout
+// (e.g., for a release) is in use.
+type treePool struct {
+	mu   sync.Mutex
+	size int
+	idle []tree
+	// Held while adding a tree, since git can trip over itself
+	// pruning and adding worktrees at the same time
+	addMu sync.Mutex
+}
+
+type tree struct {
+	dir string // the worktree, within a temporary directory of its own
+	// The mirror the tree is a worktree of; if the repo is cloned
+	// again, the trees of the old mirror are no use
+	mirror string
+}
+
+// get gives an idle tree of the mirror, if there is one.
+func (p *treePool) get(mirror string) (tree, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.idle) > 0 {
+		t := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if t.mirror == mirror {
+			return t, true
+		}
+		t.remove()
+	}
+	return tree{}, false
+}
+
+// put keeps the tree for reuse, if there's room; otherwise it's
+// removed.
+func (p *treePool) put(t tree) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) < p.size {
+		p.idle = append(p.idle, t)
+		return
+	}
+	t.remove()
+}
+
+// add adds a new tree of the mirror, with the revision given checked
+// out.
+func (p *treePool) add(ctx context.Context, mirror, rev string) (tree, error) {
+	tmp, err := ioutil.TempDir(os.TempDir(), "flux-tree")
+	if err != nil {
+		return tree{}, err
+	}
+	// git (before 2.17 or so) won't add a worktree in a directory
+	// that exists, even if it's empty; and it names the worktree
+	// after the directory, so that has to be unique too
+	t := tree{dir: filepath.Join(tmp, filepath.Base(tmp)), mirror: mirror}
+	p.addMu.Lock()
+	defer p.addMu.Unlock()
+	if err := addWorktree(ctx, mirror, t.dir, rev); err != nil {
+		t.remove()
+		return tree{}, err
+	}
+	return t, nil
+}
+
+// clean removes all the idle trees.
+func (p *treePool) clean() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.idle {
+		t.remove()
+	}
+	p.idle = nil
+}
+
+// remove removes the tree. The mirror's record of it is left to be
+// pruned when the next tree is added.
+func (t tree) remove() {
+	os.RemoveAll(filepath.Dir(t.dir))
+}
+
+// ReadOnlyClone gives a checkout of the head of the branch in the
+// config, to read from but not commit to. It's made in a working
+// tree from a pool, so it's quicker to get than a clone, and can be
+// used while clones are in use. Cleaning the checkout up gives the
+// tree back to the pool.
+//
+// If the config asks for a sparse checkout, it's a (sparse) clone
+// instead, since a worktree has all of the files checked out; and
+// likewise if the repo has its submodules checked out, since they
+// can't be shared among worktrees.
+func (r *Repo) ReadOnlyClone(ctx context.Context, conf Config) (*Checkout, error) {
+	if paths := conf.sparsePaths(); len(paths) > 0 || r.submodules {
+		dir, err := r.workingClone(ctx, conf.Branch, paths)
+		if err != nil {
+			return nil, err
+		}
+		if r.submodules {
+			if err := r.updateSubmodules(ctx, dir, r.Origin().URL); err != nil {
+				os.RemoveAll(dir)
+				return nil, err
+			}
+		}
+		realNotesRef, err := getNotesRef(ctx, dir, conf.NotesRef)
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+		return &Checkout{
+			dir:          dir,
+			upstream:     r.Origin(),
+			realNotesRef: realNotesRef,
+			config:       conf,
+			readonly:     true,
+		}, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.errorIfNotReady(); err != nil {
+		return nil, err
+	}
+	rev, err := refRevision(ctx, r.dir, conf.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := r.trees.get(r.dir)
+	if ok {
+		if err := resetWorktree(ctx, t.dir, rev); err != nil {
+			t.remove()
+			ok = false
+		}
+	}
+	if !ok {
+		if t, err = r.trees.add(ctx, r.dir, rev); err != nil {
+			return nil, err
+		}
+	}
+
+	// The worktree shares its refs with the mirror, so the notes are
+	// there already
+	realNotesRef, err := getNotesRef(ctx, t.dir, conf.NotesRef)
+	if err != nil {
+		t.remove()
+		return nil, err
+	}
+
+	return &Checkout{
+		dir:          t.dir,
+		upstream:     r.origin,
+		realNotesRef: realNotesRef,
+		config:       conf,
+		readonly:     true,
+		release:      func() { r.trees.put(t) },
+	}, nil
+}
@@ -0,0 +1,123 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestReadOnlyClone(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"config"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := NewRepo(Remote{URL: upstream}, ReadOnly, TreePool(1))
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := Config{Branch: "master", NotesRef: "flux", SyncTag: "flux-sync"}
+
+	// Any number can be checked out at once
+	var wg sync.WaitGroup
+	checkouts := make([]*Checkout, 3)
+	errs := make([]error, 3)
+	for i := range checkouts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			checkouts[i], errs[i] = repo.ReadOnlyClone(ctx, conf)
+		}(i)
+	}
+	wg.Wait()
+	dirs := map[string]bool{}
+	for i, co := range checkouts {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		rev, err := co.HeadRevision(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rev != head {
+			t.Errorf("expected checkout of %s, got %s", head, rev)
+		}
+		if _, err := os.Stat(filepath.Join(co.ManifestDirs()[0], "config")); err != nil {
+			t.Errorf("expected files to be checked out: %v", err)
+		}
+		dirs[co.Dir()] = true
+	}
+	if len(dirs) != len(checkouts) {
+		t.Errorf("expected each checkout to have its own directory, got %v", dirs)
+	}
+
+	if err := checkouts[0].CommitAndPush(ctx, CommitAction{Message: "nope"}, nil); err != ErrReadOnlyCheckout {
+		t.Errorf("expected %v when committing to a read-only checkout, got %v", ErrReadOnlyCheckout, err)
+	}
+	if err := checkouts[0].MoveSyncTagAndPush(ctx, head, "nope"); err != ErrReadOnlyCheckout {
+		t.Errorf("expected %v when moving the sync tag in a read-only checkout, got %v", ErrReadOnlyCheckout, err)
+	}
+
+	// Only one tree is kept once they're cleaned up; the others are
+	// removed
+	reused := checkouts[0].Dir()
+	if err := ioutil.WriteFile(filepath.Join(reused, "left-behind"), []byte("junk"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, co := range checkouts {
+		co.Clean()
+	}
+	for dir := range dirs {
+		if _, err := os.Stat(dir); (dir == reused) != (err == nil) {
+			t.Errorf("expected only %s to be kept, but %s is kept: %v", reused, dir, err == nil)
+		}
+	}
+
+	// The tree kept is used again, at the (new) head, and with
+	// nothing left in it
+	if err := updateDirAndCommit(upstream, "config", map[string]string{"foo.yaml": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	newHead, err := repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	co, err := repo.ReadOnlyClone(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Clean()
+	if co.Dir() != reused {
+		t.Errorf("expected %s to be used again, got %s", reused, co.Dir())
+	}
+	if rev, err := co.HeadRevision(ctx); err != nil || rev != newHead {
+		t.Errorf("expected checkout of %s, got %s (%v)", newHead, rev, err)
+	}
+	if _, err := os.Stat(filepath.Join(reused, "left-behind")); !os.IsNotExist(err) {
+		t.Errorf("expected the tree to be cleaned before it's used again, got %v", err)
+	}
+
+	// Removing the repo removes the trees
+	co.Clean()
+	repo.Clean()
+	if _, err := os.Stat(reused); !os.IsNotExist(err) {
+		t.Errorf("expected the tree to be removed with the repo, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Progress reports how far along a clone or fetch is, as git reports
+// it with `--progress`.
+type Progress struct {
+	Operation     string    `json:"operation"`              // "clone" or "fetch"
+	Phase         string    `json:"phase,omitempty"`        // e.g., "Receiving objects"
+	Objects       int       `json:"objects,omitempty"`      // objects done in this phase
+	TotalObjects  int       `json:"totalObjects,omitempty"` // objects to do in this phase, if known
+	BytesReceived int64     `json:"bytesReceived,omitempty"`
+	Started       time.Time `json:"started"`
+	Done          bool      `json:"done"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Percent is how much of the current phase is done, or -1 if git
+// hasn't said how much there is to do.
+func (p Progress) Percent() int {
+	if p.TotalObjects <= 0 {
+		return -1
+	}
+	return p.Objects * 100 / p.TotalObjects
+}
+
+func (p Progress) String() string {
+	s := p.Operation
+	if p.Phase != "" {
+		s += ": " + p.Phase
+		if percent := p.Percent(); percent >= 0 {
+			s += fmt.Sprintf(" %d%% (%d/%d)", percent, p.Objects, p.TotalObjects)
+		} else if p.Objects > 0 {
+			s += fmt.Sprintf(" %d", p.Objects)
+		}
+	}
+	if p.BytesReceived > 0 {
+		s += fmt.Sprintf(", %.2f MiB received", float64(p.BytesReceived)/(1<<20))
+	}
+	switch {
+	case p.Error != "":
+		s += ", failed: " + p.Error
+	case p.Done:
+		s += ", done"
+	}
+	return s
+}
+
+// Operation is a git command running in the background. Its progress
+// can be received on the channel given by `Progress()`, or looked at
+// with `Status()`; `Wait()` gives its result once it's finished.
+type Operation struct {
+	progress chan Progress
+	done     chan struct{}
+
+	mu   sync.Mutex
+	last Progress
+	err  error
+}
+
+// startGitCmd runs a git command which reports progress (so it should
+// include `--progress`) in the background. `result` is given the
+// error from the command, and can wrap or dismiss it.
+func startGitCmd(ctx context.Context, dir, operation string, creds Credentials, result func(error) error, args ...string) *Operation {
+	op := &Operation{
+		progress: make(chan Progress, 1),
+		done:     make(chan struct{}),
+		last:     Progress{Operation: operation, Started: time.Now()},
+	}
+	go func() {
+		err := execGitCmdProgress(ctx, dir, nil, op.report, creds, args...)
+		if result != nil {
+			err = result(err)
+		}
+		op.mu.Lock()
+		op.err = err
+		op.last.Done = true
+		if err != nil {
+			op.last.Error = err.Error()
+		}
+		op.mu.Unlock()
+		close(op.progress)
+		close(op.done)
+	}()
+	return op
+}
+
+func (op *Operation) report(p Progress) {
+	op.mu.Lock()
+	op.last.Phase = p.Phase
+	op.last.Objects, op.last.TotalObjects = p.Objects, p.TotalObjects
+	if p.BytesReceived > 0 {
+		op.last.BytesReceived = p.BytesReceived
+	}
+	latest := op.last
+	op.mu.Unlock()
+
+	// Only the latest progress is interesting, so replace any that
+	// hasn't been received yet rather than block.
+	select {
+	case op.progress <- latest:
+	default:
+		select {
+		case <-op.progress:
+		default:
+		}
+		select {
+		case op.progress <- latest:
+		default:
+		}
+	}
+}
+
+// Progress gives a channel on which the progress of the operation is
+// sent as it changes; it is closed when the operation is finished.
+func (op *Operation) Progress() <-chan Progress {
+	return op.progress
+}
+
+// Status gives the progress of the operation as last reported.
+func (op *Operation) Status() Progress {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.last
+}
+
+// Done gives a channel that's closed when the operation is finished.
+func (op *Operation) Done() <-chan struct{} {
+	return op.done
+}
+
+// Wait blocks until the operation is finished, and returns its error,
+// if any.
+func (op *Operation) Wait() error {
+	<-op.done
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.err
+}
+
+// progressLine matches the lines git writes to stderr with
+// `--progress`, e.g.,
+//
+//	remote: Counting objects: 12, done.
+//	Receiving objects:  45% (450/1000), 1.20 MiB | 1.00 MiB/s
+var progressLine = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z]*(?: [a-z]+)*): +(?:\d+% \((\d+)/(\d+)\)|(\d+))(?:, ([0-9.]+) (bytes|KiB|MiB|GiB))?`)
+
+var byteUnits = map[string]float64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+}
+
+// parseProgress reads a line of progress from git, returning false if
+// it's not a line of progress.
+func parseProgress(line string) (Progress, bool) {
+	m := progressLine.FindStringSubmatch(line)
+	if m == nil {
+		return Progress{}, false
+	}
+	p := Progress{Phase: m[1]}
+	if m[2] != "" {
+		p.Objects, _ = strconv.Atoi(m[2])
+		p.TotalObjects, _ = strconv.Atoi(m[3])
+	} else {
+		p.Objects, _ = strconv.Atoi(m[4])
+	}
+	if m[5] != "" {
+		n, _ := strconv.ParseFloat(m[5], 64)
+		p.BytesReceived = int64(n * byteUnits[m[6]])
+	}
+	return p, true
+}
+
+// progressWriter parses the progress git writes to stderr, which is
+// lines ended with `\r` while they're being updated, and `\n` once
+// finished.
+type progressWriter struct {
+	report  func(Progress)
+	partial []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		if progress, ok := parseProgress(string(w.partial[:i])); ok {
+			w.report(progress)
+		}
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestParseProgress(t *testing.T) {
+	for line, expected := range map[string]Progress{
+		"remote: Enumerating objects: 1234, done.":                          {Phase: "Enumerating objects", Objects: 1234},
+		"remote: Compressing objects:  50% (3/6)":                           {Phase: "Compressing objects", Objects: 3, TotalObjects: 6},
+		"Receiving objects:  45% (450/1000), 1.50 MiB | 1.00 MiB/s":         {Phase: "Receiving objects", Objects: 450, TotalObjects: 1000, BytesReceived: 3 << 19},
+		"Receiving objects: 100% (1000/1000), 512 bytes | 0 bytes/s, done.": {Phase: "Receiving objects", Objects: 1000, TotalObjects: 1000, BytesReceived: 512},
+		"Resolving deltas: 100% (5/5), done.":                               {Phase: "Resolving deltas", Objects: 5, TotalObjects: 5},
+	} {
+		p, ok := parseProgress(line)
+		if !ok || p != expected {
+			t.Errorf("parsing %q: expected %+v, got %+v (%v)", line, expected, p, ok)
+		}
+	}
+	for _, line := range []string{"Cloning into bare repository 'foo'...", "fatal: repository not found", ""} {
+		if p, ok := parseProgress(line); ok {
+			t.Errorf("expected %q not to be progress, got %+v", line, p)
+		}
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	var reported []Progress
+	w := &progressWriter{report: func(p Progress) { reported = append(reported, p) }}
+	// Lines are updated with `\r`, and may be split across writes
+	for _, s := range []string{"Receiving objects:  10% (1/10)\rReceiving obj", "ects:  50% (5/10)\r", "Receiving objects: 100% (10/10), done.\nResolving"} {
+		w.Write([]byte(s))
+	}
+	if len(reported) != 3 || reported[1].Objects != 5 || reported[2].Objects != 10 {
+		t.Errorf("unexpected progress reported: %+v", reported)
+	}
+}
+
+func TestCloneProgress(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepo(Remote{URL: "file://" + upstream}, ReadOnly)
+	defer repo.Clean()
+	if repo.Progress() != nil {
+		t.Error("expected no progress before cloning")
+	}
+	if err := repo.Ready(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p := repo.Progress()
+	if p == nil || p.Operation != "fetch" || !p.Done || p.Error != "" {
+		t.Errorf("expected the progress of the fetch after cloning, got %+v", p)
+	}
+	// The clone's progress will have been replaced on the channel
+	// by the fetch's, which is done at the last
+	if p := <-repo.ProgressC; p.Operation != "fetch" || !p.Done {
+		t.Errorf("expected the latest progress on the channel, got %+v", p)
+	}
+
+	mirrorDir, cleanupMirror := testfiles.TempDir(t)
+	defer cleanupMirror()
+	op := mirrorAsync(context.Background(), mirrorDir, "file://"+upstream, 0, Credentials{})
+	var phases []string
+	for p := range op.Progress() {
+		phases = append(phases, p.Phase)
+	}
+	if err := op.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// Bytes received aren't always reported, if it's quick enough
+	status := op.Status()
+	if len(phases) == 0 || status.Phase == "" || status.Objects == 0 || !status.Done {
+		t.Errorf("expected progress reported while cloning, got %v, finishing with %+v", phases, status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 	"sync"
 
 	"context"
@@ -12,8 +13,9 @@ import (
 
 const (
 	defaultInterval = 5 * time.Minute
-	opTimeout       = 20 * time.Second
+	opTimeout       = DefaultTimeout
 
+	DefaultTimeout      = 20 * time.Second
 	DefaultCloneTimeout = 2 * time.Minute
 	CheckPushTag        = "flux-write-check"
 )
@@ -48,6 +50,47 @@ const (
 // Remote points at a git repo somewhere.
 type Remote struct {
 	URL string // clone from here
+	// For a repo served over HTTPS, where to get the username and
+	// token from; nil for no credentials (e.g., using SSH)
+	Credentials CredentialsProvider
+	// Where to get credentials for the repo's submodules, if they're
+	// checked out; those it gives none for (or all, if it's nil) are
+	// given the repo's credentials if they're on the same host, and
+	// otherwise none
+	SubmoduleCredentials CredentialsProvider
+}
+
+// SameRepo says whether the two URLs are (probably) for the same
+// repo, given the different ways of writing the URL for the one repo;
+// e.g., `git@github.com:org/repo` and `https://github.com/org/repo.git`.
+func SameRepo(a, b string) bool {
+	hostA, pathA := SplitRepoURL(strings.ToLower(a))
+	hostB, pathB := SplitRepoURL(strings.ToLower(b))
+	return hostA == hostB && pathA == pathB
+}
+
+// SplitRepoURL gives the host and the path of the repo URL, without
+// the scheme, user, port, leading slash or `.git` on the end; e.g.,
+// `github.com` and `org/repo` for `git@github.com:org/repo.git`.
+func SplitRepoURL(u string) (host, path string) {
+	u = strings.TrimSpace(u)
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if i := strings.Index(u, ":"); i >= 0 && !strings.Contains(u[:i], "/") {
+		// scp-like, e.g., git@github.com:org/repo
+		u = u[:i] + "/" + u[i+1:]
+	}
+	host = u
+	if i := strings.Index(u, "/"); i >= 0 {
+		host, path = u[:i], u[i+1:]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return host, strings.TrimSuffix(strings.Trim(path, "/"), ".git")
 }
 
 type Repo struct {
@@ -55,6 +98,23 @@ type Repo struct {
 	origin   Remote
 	interval time.Duration
 	readonly bool
+	depth    int
+	// Whether submodules are checked out
+	submodules bool
+
+	// Working trees for read-only checkouts, which has its own lock
+	trees treePool
+	// How clones, fetches and pushes are timed out and retried
+	retry retryPolicy
+	// Limits the clones and fetches made at once, with other repos
+	throttle Throttle
+	// If not zero, how long to wait before the first poll, rather
+	// than the interval
+	firstPoll time.Duration
+
+	// Refreshes are made one at a time, but without holding `mu`
+	// while fetching, so the mirror can be read meanwhile
+	refreshMu sync.Mutex
 
 	// State
 	mu     sync.RWMutex
@@ -64,6 +124,14 @@ type Repo struct {
 
 	notify chan struct{}
 	C      chan struct{}
+
+	// Progress of the last clone or fetch, which has its own lock
+	// since it's updated while other things hold `mu`.
+	progressMu sync.Mutex
+	progress   *Progress
+	// ProgressC is sent the progress of clones and fetches as it
+	// changes; only the latest is kept, so it doesn't block.
+	ProgressC chan Progress
 }
 
 type Option interface {
@@ -82,6 +150,57 @@ func (p PollInterval) apply(r *Repo) {
 	r.interval = time.Duration(p)
 }
 
+// CloneDepth makes the repo a shallow clone, with only this many
+// commits of history to start with.
+type CloneDepth int
+
+func (d CloneDepth) apply(r *Repo) {
+	r.depth = int(d)
+}
+
+// Throttle limits how many clones and fetches are made at once by the
+// repos given it (as an option); e.g., so that many repos from the one
+// git host aren't all fetched at the same time. Its capacity is the
+// limit.
+type Throttle chan struct{}
+
+// NewThrottle makes a Throttle that lets `limit` clones and fetches
+// be made at once.
+func NewThrottle(limit int) Throttle {
+	return make(Throttle, limit)
+}
+
+func (t Throttle) apply(r *Repo) {
+	r.throttle = t
+}
+
+func (t Throttle) acquire(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	select {
+	case t <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t Throttle) release() {
+	if t != nil {
+		<-t
+	}
+}
+
+// FirstPoll makes the repo poll for the first time after this long,
+// rather than after its poll interval; e.g., so that repos with the
+// same interval are polled at different times.
+type FirstPoll time.Duration
+
+func (p FirstPoll) apply(r *Repo) {
+	r.firstPoll = time.Duration(p)
+}
+
 var ReadOnly optionFunc = func(r *Repo) {
 	r.readonly = true
 }
@@ -93,12 +212,15 @@ func NewRepo(origin Remote, opts ...Option) *Repo {
 		status = RepoNoConfig
 	}
 	r := &Repo{
-		origin:   origin,
-		status:   status,
-		interval: defaultInterval,
-		err:      ErrNotCloned,
-		notify:   make(chan struct{}, 1), // `1` so that Notify doesn't block
-		C:        make(chan struct{}, 1), // `1` so we don't block on completing a refresh
+		origin:    origin,
+		status:    status,
+		interval:  defaultInterval,
+		err:       ErrNotCloned,
+		notify:    make(chan struct{}, 1), // `1` so that Notify doesn't block
+		C:         make(chan struct{}, 1), // `1` so we don't block on completing a refresh
+		ProgressC: make(chan Progress, 1),
+		trees:     treePool{size: DefaultTreePoolSize},
+		retry:     defaultRetryPolicy(),
 	}
 	for _, opt := range opts {
 		opt.apply(r)
@@ -125,6 +247,7 @@ func (r *Repo) Dir() string {
 // directory, so you may need to stop that first.
 func (r *Repo) Clean() {
 	r.mu.Lock()
+	r.trees.clean()
 	if r.dir != "" {
 		os.RemoveAll(r.dir)
 	}
@@ -142,6 +265,48 @@ func (r *Repo) Status() (GitRepoStatus, error) {
 	return r.status, r.err
 }
 
+// Progress reports how far along the last clone or fetch of this
+// repo got, or nil if there hasn't been one yet. While the repo is
+// being cloned, this is what shows it's still going.
+func (r *Repo) Progress() *Progress {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	if r.progress == nil {
+		return nil
+	}
+	p := *r.progress
+	return &p
+}
+
+func (r *Repo) setProgress(p Progress) {
+	r.progressMu.Lock()
+	r.progress = &p
+	r.progressMu.Unlock()
+	select {
+	case r.ProgressC <- p:
+	default:
+		select {
+		case <-r.ProgressC:
+		default:
+		}
+		select {
+		case r.ProgressC <- p:
+		default:
+		}
+	}
+}
+
+// await follows the progress of a clone or fetch until it's finished,
+// and returns its result.
+func (r *Repo) await(op *Operation) error {
+	for p := range op.Progress() {
+		r.setProgress(p)
+	}
+	err := op.Wait()
+	r.setProgress(op.Status())
+	return err
+}
+
 func (r *Repo) setUnready(s GitRepoStatus, err error) {
 	r.mu.Lock()
 	r.status = s
@@ -198,6 +363,17 @@ func (r *Repo) Revision(ctx context.Context, ref string) (string, error) {
 	return refRevision(ctx, r.dir, ref)
 }
 
+// Tags lists the tags in the repo, as of when it was last fetched
+// from.
+func (r *Repo) Tags(ctx context.Context) ([]Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.errorIfNotReady(); err != nil {
+		return nil, err
+	}
+	return tags(ctx, r.dir)
+}
+
 func (r *Repo) CommitsBefore(ctx context.Context, ref string, paths ...string) ([]Commit, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -216,11 +392,45 @@ func (r *Repo) CommitsBetween(ctx context.Context, ref1, ref2 string, paths ...s
 	return onelinelog(ctx, r.dir, ref1+".."+ref2, paths)
 }
 
+// CommitMessages gives the commits after ref1 up to ref2, newest
+// first, as CommitsBetween does but with the whole of each message
+// rather than its first line; or if ref1 is empty, all the commits
+// up to ref2. Only the first parent of each merge is followed, so
+// these are the commits made to (or merged into) the branch itself,
+// each of which could be checked out in turn.
+func (r *Repo) CommitMessages(ctx context.Context, ref1, ref2 string) ([]Commit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.errorIfNotReady(); err != nil {
+		return nil, err
+	}
+	refspec := ref2
+	if ref1 != "" {
+		refspec = ref1 + ".." + ref2
+	}
+	return fulllog(ctx, r.dir, "--first-parent", refspec)
+}
+
+// CommitSignatures gives the GPG signatures of the commits after ref1
+// up to ref2, oldest first; or if ref1 is empty, of ref2 alone.
+func (r *Repo) CommitSignatures(ctx context.Context, ref1, ref2 string) ([]CommitSignature, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.errorIfNotReady(); err != nil {
+		return nil, err
+	}
+	if ref1 == "" {
+		return commitSignatures(ctx, r.dir, "--max-count=1", ref2)
+	}
+	return commitSignatures(ctx, r.dir, ref1+".."+ref2)
+}
+
 // step attempts to advance the repo state machine, and returns `true`
 // if it has made progress, `false` otherwise.
 func (r *Repo) step(bg context.Context) bool {
 	r.mu.RLock()
-	url := r.origin.URL
+	origin := r.origin
+	url := origin.URL
 	dir := r.dir
 	status := r.status
 	r.mu.RUnlock()
@@ -238,18 +448,16 @@ func (r *Repo) step(bg context.Context) bool {
 			panic(err)
 		}
 
-		ctx, cancel := context.WithTimeout(bg, opTimeout)
-		dir, err = mirror(ctx, rootdir, url)
-		cancel()
+		// Cloning a large repo can take a while, so it has longer
+		// than other operations
+		err = r.mirror(bg, origin, rootdir)
 		if err == nil {
-			r.mu.Lock()
-			r.dir = dir
-			ctx, cancel := context.WithTimeout(bg, opTimeout)
-			err = r.fetch(ctx)
-			cancel()
-			r.mu.Unlock()
+			err = r.fetch(bg, origin, rootdir)
 		}
 		if err == nil {
+			r.mu.Lock()
+			r.dir = rootdir
+			r.mu.Unlock()
 			r.setUnready(RepoCloned, ErrClonedOnly)
 			return true
 		}
@@ -260,9 +468,13 @@ func (r *Repo) step(bg context.Context) bool {
 
 	case RepoCloned:
 		if !r.readonly {
-			ctx, cancel := context.WithTimeout(bg, opTimeout)
-			err := checkPush(ctx, dir, url)
-			cancel()
+			err := r.retry.do(bg, "push", func(ctx context.Context) error {
+				creds, err := origin.credentials(ctx)
+				if err != nil {
+					return err
+				}
+				return checkPush(ctx, dir, url, creds)
+			})
 			if err != nil {
 				r.setUnready(RepoCloned, err)
 				return false
@@ -299,8 +511,19 @@ func (r *Repo) Start(shutdown <-chan struct{}, done *sync.WaitGroup) error {
 	defer done.Done()
 
 	for {
-		ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+		// Each step sets its own timeouts; this is so that a clone
+		// in progress is abandoned on shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		stepped := make(chan struct{})
+		go func() {
+			select {
+			case <-shutdown:
+				cancel()
+			case <-stepped:
+			}
+		}()
 		advanced := r.step(ctx)
+		close(stepped)
 		cancel()
 
 		if advanced {
@@ -331,23 +554,79 @@ func (r *Repo) Start(shutdown <-chan struct{}, done *sync.WaitGroup) error {
 	return nil
 }
 
+// Refresh fetches from the upstream. A fetch may be tried more than
+// once, and take a while, so it's done without holding `mu`: git is
+// fine with the mirror being read while it's fetched into, so reading
+// the repo doesn't wait for it.
 func (r *Repo) Refresh(ctx context.Context) error {
-	// the lock here and below is difficult to avoid; possibly we
-	// could clone to another repo and pull there, then swap when complete.
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if err := r.errorIfNotReady(); err != nil {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+	r.mu.RLock()
+	err := r.errorIfNotReady()
+	origin, dir := r.origin, r.dir
+	r.mu.RUnlock()
+	if err != nil {
 		return err
 	}
-	if err := r.fetch(ctx); err != nil {
-		return err
+	if err := r.fetch(ctx, origin, dir); err != nil {
+		if !isHistoryError(err) {
+			return err
+		}
+		if err := r.remirror(ctx, origin); err != nil {
+			return err
+		}
 	}
 	r.refreshed()
 	return nil
 }
 
+// remirror clones the upstream afresh, for when the mirror can't be
+// brought up to date by fetching; e.g., when the upstream branch was
+// force-pushed, and fetching is fast-forward only. The old mirror is
+// used (and can be read) while the new one is cloned, and `mu` is
+// only held to swap one for the other. It must be called holding
+// `refreshMu`.
+func (r *Repo) remirror(ctx context.Context, origin Remote) error {
+	rootdir, err := ioutil.TempDir(os.TempDir(), "flux-gitclone")
+	if err != nil {
+		return err
+	}
+	if err := r.mirror(ctx, origin, rootdir); err != nil {
+		os.RemoveAll(rootdir)
+		return err
+	}
+	if err := r.fetch(ctx, origin, rootdir); err != nil {
+		os.RemoveAll(rootdir)
+		return err
+	}
+	r.mu.Lock()
+	old := r.dir
+	r.dir = rootdir
+	// The trees belong to the old mirror
+	r.trees.clean()
+	r.mu.Unlock()
+	os.RemoveAll(old)
+	return nil
+}
+
+// IsAncestor says whether `ancestor` is in the history of `rev`; if
+// it's not, the history was rewritten (e.g., force-pushed) between
+// the two.
+func (r *Repo) IsAncestor(ctx context.Context, ancestor, rev string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.errorIfNotReady(); err != nil {
+		return false, err
+	}
+	return isAncestor(ctx, r.dir, ancestor, rev)
+}
+
 func (r *Repo) refreshLoop(shutdown <-chan struct{}) error {
-	gitPoll := time.NewTimer(r.interval)
+	wait := r.interval
+	if r.firstPoll > 0 {
+		wait, r.firstPoll = r.firstPoll, 0
+	}
+	gitPoll := time.NewTimer(wait)
 	for {
 		select {
 		case <-shutdown:
@@ -364,9 +643,8 @@ func (r *Repo) refreshLoop(shutdown <-chan struct{}) error {
 				default:
 				}
 			}
-			ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
-			err := r.Refresh(ctx)
-			cancel()
+			// Each try at fetching has its own timeout
+			err := r.Refresh(context.Background())
 			if err != nil {
 				return err
 			}
@@ -375,17 +653,51 @@ func (r *Repo) refreshLoop(shutdown <-chan struct{}) error {
 	}
 }
 
-// fetch gets updated refs, and associated objects, from the upstream.
-func (r *Repo) fetch(ctx context.Context) error {
-	if err := fetch(ctx, r.dir, "origin"); err != nil {
+// fetch gets updated refs, and associated objects, from the upstream
+// into the mirror in `dir`. Waiting on the throttle doesn't count
+// against the timeout for each try.
+func (r *Repo) fetch(ctx context.Context, origin Remote, dir string) error {
+	if err := r.throttle.acquire(ctx); err != nil {
 		return err
 	}
-	return nil
+	defer r.throttle.release()
+	return r.retry.do(ctx, "fetch", func(ctx context.Context) error {
+		creds, err := origin.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		return r.await(fetchAsync(ctx, dir, "origin", creds))
+	})
+}
+
+// mirror makes a mirror clone of the upstream in `dir`, which must
+// exist, and is emptied before each try.
+func (r *Repo) mirror(ctx context.Context, origin Remote, dir string) error {
+	if err := r.throttle.acquire(ctx); err != nil {
+		return err
+	}
+	defer r.throttle.release()
+	return r.retry.do(ctx, "clone", func(ctx context.Context) error {
+		// A clone that was interrupted can leave things behind,
+		// and git won't clone into a directory that isn't empty
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.Mkdir(dir, 0700); err != nil {
+			return err
+		}
+		creds, err := origin.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		return r.await(mirrorAsync(ctx, dir, origin.URL, r.depth, creds))
+	})
 }
 
 // workingClone makes a non-bare clone, at `ref` (probably a branch),
-// and returns the filesystem path to it.
-func (r *Repo) workingClone(ctx context.Context, ref string) (string, error) {
+// and returns the filesystem path to it. If paths are given, only
+// those are checked out.
+func (r *Repo) workingClone(ctx context.Context, ref string, paths []string) (string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	if err := r.errorIfNotReady(); err != nil {
@@ -395,5 +707,8 @@ func (r *Repo) workingClone(ctx context.Context, ref string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if len(paths) > 0 {
+		return sparseClone(ctx, working, r.dir, ref, paths)
+	}
 	return clone(ctx, working, r.dir, ref)
 }
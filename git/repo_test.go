@@ -0,0 +1,188 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestSameRepo(t *testing.T) {
+	for _, urls := range [][]string{
+		{"git@github.com:weaveworks/flux", "https://github.com/weaveworks/flux.git"},
+		{"ssh://git@github.com/weaveworks/flux.git", "git://github.com/weaveworks/flux"},
+		{"https://github.com/Weaveworks/Flux/", "git@github.com:weaveworks/flux.git"},
+		{"ssh://git@bitbucket.example.com:7999/proj/repo.git", "https://user@bitbucket.example.com/proj/repo"},
+	} {
+		if !SameRepo(urls[0], urls[1]) {
+			t.Errorf("expected %s and %s to be the same repo", urls[0], urls[1])
+		}
+	}
+	for _, urls := range [][]string{
+		{"git@github.com:weaveworks/flux", "git@github.com:weaveworks/flux-example"},
+		{"git@github.com:weaveworks/flux", "git@gitlab.com:weaveworks/flux"},
+	} {
+		if SameRepo(urls[0], urls[1]) {
+			t.Errorf("expected %s and %s not to be the same repo", urls[0], urls[1])
+		}
+	}
+}
+
+func TestSplitRepoURL(t *testing.T) {
+	for u, expected := range map[string][2]string{
+		"git@github.com:weaveworks/flux.git":           {"github.com", "weaveworks/flux"},
+		"https://gitlab.example.com/group/sub/repo":    {"gitlab.example.com", "group/sub/repo"},
+		"ssh://git@gitlab.example.com:2222/group/repo": {"gitlab.example.com", "group/repo"},
+	} {
+		if host, path := SplitRepoURL(u); host != expected[0] || path != expected[1] {
+			t.Errorf("expected %s to be split into %v, got %s and %s", u, expected, host, path)
+		}
+	}
+}
+
+func TestRefreshAfterRewrite(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"config"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := NewRepo(Remote{URL: upstream}, ReadOnly)
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	oldHead, err := repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := repo.Revision(ctx, "master^")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fetch only fast-forwards, so fetching the rewritten branch
+	// fails
+	oldDir := repo.Dir()
+	if err := execCommand("git", "-C", oldDir, "config", "remote.origin.fetch", "refs/heads/*:refs/heads/*"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite the last commit, as if it were force-pushed
+	if err := execCommand("git", "-C", upstream, "commit", "--amend", "--allow-empty", "-m", "Rewritten"); err != nil {
+		t.Fatal(err)
+	}
+	newHead, err := refRevision(ctx, upstream, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := repo.Revision(ctx, "master"); err != nil || rev != newHead {
+		t.Errorf("expected the rewritten head %s after refreshing, got %s (%v)", newHead, rev, err)
+	}
+	if repo.Dir() == oldDir {
+		t.Error("expected the repo to have been cloned again")
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("expected the old clone to be removed, got %v", err)
+	}
+
+	if ok, err := repo.IsAncestor(ctx, parent, newHead); err != nil || !ok {
+		t.Errorf("expected %s to be an ancestor of %s (%v)", parent, newHead, err)
+	}
+	if ok, err := repo.IsAncestor(ctx, newHead, newHead); err != nil || !ok {
+		t.Errorf("expected %s to be an ancestor of itself (%v)", newHead, err)
+	}
+	// The old head isn't in the new clone; but if it were, it
+	// wouldn't be an ancestor
+	if ok, err := isAncestor(ctx, upstream, oldHead, newHead); err != nil || ok {
+		t.Errorf("expected %s not to be an ancestor of %s (%v)", oldHead, newHead, err)
+	}
+}
+
+func TestRefreshDoesNotBlockReads(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"config"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetching waits for credentials until told to go ahead
+	var fetching bool
+	started, proceed := make(chan struct{}), make(chan struct{})
+	creds := CredentialsFunc(func(ctx context.Context, url string) (Credentials, error) {
+		if fetching {
+			close(started)
+			<-proceed
+		}
+		return Credentials{}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := NewRepo(Remote{URL: upstream, Credentials: creds}, ReadOnly)
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	fetching = true
+	refreshed := make(chan error)
+	go func() { refreshed <- repo.Refresh(ctx) }()
+	<-started
+
+	// The repo can still be read while the fetch is under way
+	read := make(chan error)
+	go func() {
+		_, err := repo.Revision(ctx, "master")
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expected reading the repo not to wait for the fetch")
+	}
+
+	close(proceed)
+	if err := <-refreshed; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"config"}); err != nil {
+		t.Fatal(err)
+	}
+	throttle := NewThrottle(1)
+	repo := NewRepo(Remote{URL: upstream}, ReadOnly, throttle)
+	defer repo.Clean()
+	if err := repo.Ready(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// With another repo fetching, this one has to wait its turn
+	throttle <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := repo.Refresh(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the fetch to wait for the throttle, got %v", err)
+	}
+	<-throttle
+	if err := repo.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(throttle) != 0 {
+		t.Error("expected the fetch to have given back its turn")
+	}
+}
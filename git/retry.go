@@ -0,0 +1,140 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	fluxmetrics "github.com/weaveworks/flux/metrics"
+)
+
+const (
+	// DefaultRetries is how many more times a clone, fetch or push is
+	// tried after it fails, if not given.
+	DefaultRetries = 2
+	// DefaultRetryBackoff is how long to wait before trying again the
+	// first time, if not given; it's twice as long each time after.
+	DefaultRetryBackoff = time.Second
+	// The longest to wait between tries
+	maxRetryBackoff = time.Minute
+)
+
+// Timeouts says how long each try at a clone, fetch or push is given,
+// before it's abandoned (and maybe tried again). Those left as zero
+// are the defaults: DefaultCloneTimeout for a clone, and
+// DefaultTimeout for a fetch or push.
+type Timeouts struct {
+	Clone time.Duration
+	Fetch time.Duration
+	Push  time.Duration
+}
+
+func (t Timeouts) apply(r *Repo) {
+	if t.Clone > 0 {
+		r.retry.timeouts.Clone = t.Clone
+	}
+	if t.Fetch > 0 {
+		r.retry.timeouts.Fetch = t.Fetch
+	}
+	if t.Push > 0 {
+		r.retry.timeouts.Push = t.Push
+	}
+}
+
+// Retries says how many more times a clone, fetch or push is tried
+// after it fails, and how long to wait before the first of those;
+// the wait is twice as long each time after, up to a minute.
+type Retries struct {
+	Times   int
+	Backoff time.Duration
+}
+
+func (rs Retries) apply(r *Repo) {
+	r.retry.times = rs.Times
+	if rs.Backoff > 0 {
+		r.retry.backoff = rs.Backoff
+	}
+}
+
+// retryPolicy is how operations with the upstream are timed out, and
+// tried again. A Repo has one, and gives it to its checkouts for
+// pushing.
+type retryPolicy struct {
+	timeouts Timeouts
+	times    int
+	backoff  time.Duration
+}
+
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		timeouts: Timeouts{Clone: DefaultCloneTimeout, Fetch: opTimeout, Push: opTimeout},
+		times:    DefaultRetries,
+		backoff:  DefaultRetryBackoff,
+	}
+}
+
+// do runs the operation (one of "clone", "fetch" or "push"), giving
+// each try the timeout for that operation, and trying again after a
+// wait if it fails in a way that may not last. It gives up early if
+// `ctx` is done.
+func (p retryPolicy) do(ctx context.Context, operation string, fn func(context.Context) error) error {
+	var timeout time.Duration
+	switch operation {
+	case "clone":
+		timeout = p.timeouts.Clone
+	case "fetch":
+		timeout = p.timeouts.Fetch
+	case "push":
+		timeout = p.timeouts.Push
+	default:
+		panic(fmt.Sprintf("unknown git operation %q", operation))
+	}
+	wait := p.backoff
+	for try := 0; ; try++ {
+		started := time.Now()
+		tryCtx, cancel := ctx, func() {}
+		if timeout > 0 {
+			tryCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := fn(tryCtx)
+		cancel()
+		observeOperation(operation, started, err)
+		if err == nil || try >= p.times || !isRetryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+		retriedOperations.With(fluxmetrics.LabelOperation, operation).Add(1)
+	}
+}
+
+// isRetryable says whether an operation that failed with the error
+// given might succeed if tried again; e.g., if it timed out, or the
+// upstream couldn't be reached. A push that was rejected, or
+// credentials that were refused, won't get any better.
+func isRetryable(err error) bool {
+	if err == ErrReadOnly || isHistoryError(err) {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{
+		"failed to push some refs",
+		"Authentication failed",
+		"Permission denied",
+		"could not read Username",
+		"does not appear to be a git repository",
+		"not found",
+	} {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	return true
+}
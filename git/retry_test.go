@@ -0,0 +1,68 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := retryPolicy{
+		timeouts: Timeouts{Fetch: 50 * time.Millisecond},
+		times:    2,
+		backoff:  time.Millisecond,
+	}
+	ctx := context.Background()
+
+	// Each try has its own timeout; a try that times out is tried
+	// again
+	var tries int
+	err := p.do(ctx, "fetch", func(ctx context.Context) error {
+		tries++
+		if tries < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the try to have a deadline")
+		}
+		return nil
+	})
+	if err != nil || tries != 3 {
+		t.Errorf("expected success on the third try, got %v after %d tries", err, tries)
+	}
+
+	// It gives up after the retries given
+	tries = 0
+	err = p.do(ctx, "fetch", func(ctx context.Context) error {
+		tries++
+		return errors.New("fatal: unable to access 'https://example.com/repo/': Could not resolve host: example.com")
+	})
+	if err == nil || tries != 3 {
+		t.Errorf("expected failure after three tries, got %v after %d tries", err, tries)
+	}
+
+	// An error that won't get better isn't tried again
+	tries = 0
+	err = p.do(ctx, "push", func(ctx context.Context) error {
+		tries++
+		return errors.New("failed to push some refs to 'git@example.com:repo'")
+	})
+	if err == nil || tries != 1 {
+		t.Errorf("expected a rejected push to be tried once, got %v after %d tries", err, tries)
+	}
+
+	// Nor when the context is done
+	p.backoff = time.Hour
+	cancelled, cancel := context.WithCancel(ctx)
+	tries = 0
+	err = p.do(cancelled, "fetch", func(ctx context.Context) error {
+		tries++
+		cancel()
+		return errors.New("connection reset")
+	})
+	if err == nil || tries != 1 {
+		t.Errorf("expected no more tries once the context is done, got %v after %d tries", err, tries)
+	}
+}
@@ -0,0 +1,194 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	fluxerr "github.com/weaveworks/flux/errors"
+)
+
+// What to do when a change looks like it contains a secret. The scan
+// is done before committing, so it catches anything that would be
+// pushed, including e.g., a templated manifest that rendered a secret.
+const (
+	SecretScanOff = "off"
+	// Commit anyway, but list what was found in the commit message
+	SecretScanWarn = "warn"
+	// Don't commit (or push) the change
+	SecretScanRefuse = "refuse"
+)
+
+// SecretFinding is a line in a change that looks like it contains a
+// credential.
+type SecretFinding struct {
+	File string
+	Line int
+	Rule string
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Rule)
+}
+
+type secretRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// These are formats of credential that can be recognised with
+// confidence.
+var secretRules = []secretRule{
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+var (
+	// Runs of characters that might be a token, e.g., base64
+	candidateToken = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+	// Image digests look random, but aren't secret
+	digestPrefix = regexp.MustCompile(`sha(256|384|512)[:\-]$`)
+	hunkHeader   = regexp.MustCompile(`^@@ -\d+(,\d+)? \+(\d+)(,\d+)? @@`)
+	// Certificates are long and random, but public
+	certBegin = regexp.MustCompile(`-----BEGIN CERTIFICATE-----`)
+	certEnd   = regexp.MustCompile(`-----END CERTIFICATE-----`)
+	certField = regexp.MustCompile(`^\s*"?(caBundle|ca\.crt|tls\.crt)"?\s*:`)
+)
+
+// Strings more random (in bits per character) than this are taken to
+// be secrets. Hex strings, like git revisions and image digests, can
+// be no more than 4.
+const secretEntropyThreshold = 4.5
+
+// ScanForSecrets looks through the lines added in a unified diff for
+// things that look like credentials, either because they are in a
+// known format, or because they are long and random.
+func ScanForSecrets(diff []byte) []SecretFinding {
+	var findings []SecretFinding
+	var file string
+	var line int
+	var inCert bool
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+		case strings.HasPrefix(text, "@@"):
+			if m := hunkHeader.FindStringSubmatch(text); m != nil {
+				line, _ = strconv.Atoi(m[2])
+			}
+		case strings.HasPrefix(text, "+"):
+			added := text[1:]
+			switch {
+			case certBegin.MatchString(added):
+				inCert = !certEnd.MatchString(added)
+			case inCert:
+				inCert = !certEnd.MatchString(added)
+			case certField.MatchString(added):
+			default:
+				if rule := secretInLine(added); rule != "" {
+					findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule})
+				}
+			}
+			line++
+		case strings.HasPrefix(text, " "):
+			line++
+		}
+	}
+	return findings
+}
+
+func secretInLine(text string) string {
+	for _, rule := range secretRules {
+		if rule.re.MatchString(text) {
+			return rule.name
+		}
+	}
+	for _, loc := range candidateToken.FindAllStringIndex(text, -1) {
+		if digestPrefix.MatchString(text[:loc[0]]) {
+			continue
+		}
+		if token := text[loc[0]:loc[1]]; mixedCase(token) && entropy(token) > secretEntropyThreshold {
+			return "high-entropy string"
+		}
+	}
+	return ""
+}
+
+func mixedCase(s string) bool {
+	return strings.ToLower(s) != s && strings.ToUpper(s) != s
+}
+
+// entropy calculates the Shannon entropy of a string, in bits per
+// character.
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func SecretsFoundError(findings []SecretFinding) error {
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, "    "+f.String())
+	}
+	return &fluxerr.Error{
+		Type: fluxerr.User,
+		Err:  errors.New("change looks like it contains secrets; not committing it"),
+		Help: `Refusing to commit a change that may contain secrets
+
+Before committing, the change was scanned for things that look like
+credentials, and found
+
+` + strings.Join(lines, "\n") + `
+
+This can happen when a manifest is generated from a template that
+fills in secret values. Nothing has been committed or pushed. Please
+check the files mentioned; if you are sure they are safe, the scan
+can be made to just warn (in the commit message) by running fluxd
+with --git-secret-scan=warn.
+`,
+	}
+}
+
+// scanChanges scans the uncommitted changes in the working directory
+// given.
+func scanChanges(ctx context.Context, workingDir string) ([]SecretFinding, error) {
+	out := &bytes.Buffer{}
+	if err := execGitCmd(ctx, workingDir, out, "diff", "--no-color", "--unified=0", "HEAD"); err != nil {
+		return nil, errors.Wrap(err, "scanning changes for secrets")
+	}
+	return ScanForSecrets(out.Bytes()), nil
+}
+
+func secretsWarning(findings []SecretFinding) string {
+	lines := []string{
+		"",
+		"",
+		"Warning: this change may contain secrets:",
+	}
+	for _, f := range findings {
+		lines = append(lines, "- "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,194 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Submodules makes the repo's working clones (and exports) have its
+// submodules checked out, and theirs, and so on; e.g., for a repo
+// that includes a shared library of manifests as a submodule.
+var Submodules optionFunc = func(r *Repo) {
+	r.submodules = true
+}
+
+// submoduleArgs are given to git when updating submodules. It's a
+// variable so the tests can allow submodules cloned from the
+// filesystem, which git otherwise refuses.
+var submoduleArgs []string
+
+// CredentialsByURL gives the credentials from the provider for the
+// longest of the URL prefixes that the URL asked about starts with;
+// or none, if it doesn't start with any of them. It's for giving each
+// submodule (or each host with submodules on it) credentials of its
+// own.
+type CredentialsByURL map[string]CredentialsProvider
+
+func (c CredentialsByURL) Credentials(ctx context.Context, url string) (Credentials, error) {
+	var longest string
+	for prefix := range c {
+		if strings.HasPrefix(url, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return Credentials{}, nil
+	}
+	return c[longest].Credentials(ctx, url)
+}
+
+// submoduleCredentials gives the credentials for the submodule at the
+// URL given: from SubmoduleCredentials, if the remote has those and
+// they give any, or else those of the remote itself, if the
+// submodule is on the same host.
+func (r Remote) submoduleCredentials(ctx context.Context, url string) (Credentials, error) {
+	if r.SubmoduleCredentials != nil {
+		creds, err := r.SubmoduleCredentials.Credentials(ctx, url)
+		if err != nil {
+			return Credentials{}, errors.Wrapf(err, "getting credentials for submodule %s", url)
+		}
+		if creds.Password != "" {
+			return creds, nil
+		}
+	}
+	if r.Credentials == nil {
+		return Credentials{}, nil
+	}
+	if host, _ := SplitRepoURL(url); host == "" || !strings.EqualFold(host, hostOf(r.URL)) {
+		return Credentials{}, nil
+	}
+	creds, err := r.Credentials.Credentials(ctx, url)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "getting credentials for submodule %s", url)
+	}
+	return creds, nil
+}
+
+func hostOf(url string) string {
+	host, _ := SplitRepoURL(url)
+	return host
+}
+
+type submodule struct {
+	name string
+	path string
+	url  string
+}
+
+// listSubmodules gives the submodules in .gitmodules in the working
+// directory, if it has one, in order of their paths.
+func listSubmodules(ctx context.Context, workingDir string) ([]submodule, error) {
+	if _, err := os.Stat(filepath.Join(workingDir, ".gitmodules")); os.IsNotExist(err) {
+		return nil, nil
+	}
+	out := &bytes.Buffer{}
+	if err := execGitCmd(ctx, workingDir, out, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.(path|url)$`); err != nil {
+		return nil, errors.Wrap(err, "reading .gitmodules")
+	}
+	byName := map[string]*submodule{}
+	sc := bufio.NewScanner(out)
+	for sc.Scan() {
+		// e.g., `submodule.lib.path vendor/lib`; the name may have
+		// dots in it
+		fields := strings.SplitN(sc.Text(), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		key, value := fields[0], fields[1]
+		i := strings.LastIndex(key, ".")
+		name := strings.TrimPrefix(key[:i], "submodule.")
+		s, ok := byName[name]
+		if !ok {
+			s = &submodule{name: name}
+			byName[name] = s
+		}
+		if key[i+1:] == "path" {
+			s.path = value
+		} else {
+			s.url = value
+		}
+	}
+	var subs []submodule
+	for _, s := range byName {
+		if s.path != "" && s.url != "" {
+			subs = append(subs, *s)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].path < subs[j].path })
+	return subs, nil
+}
+
+// resolveSubmoduleURL resolves a submodule URL that's relative (i.e.,
+// starts with `./` or `../`) against the URL of the repo it's in, as
+// git does; each `../` takes off the last part of the repo's URL.
+// Other URLs are given as they are.
+func resolveSubmoduleURL(base, url string) string {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url
+	}
+	base = strings.TrimSuffix(base, "/")
+	sep := "/"
+	for {
+		switch {
+		case strings.HasPrefix(url, "./"):
+			url = url[2:]
+			continue
+		case strings.HasPrefix(url, "../"):
+			url = url[3:]
+			// `git@host:org/repo` as well as `https://host/org/repo`
+			if i := strings.LastIndexAny(base, "/:"); i >= 0 {
+				sep = base[i : i+1]
+				base = base[:i]
+			}
+			continue
+		}
+		return base + sep + url
+	}
+}
+
+// updateSubmodules checks out the submodules in the working
+// directory, each at the commit the repo has for it, then any
+// submodules those have, and so on. Each is cloned from its URL in
+// .gitmodules (resolved against the URL of the repo it's in, rather
+// than wherever the working directory was cloned from), with the
+// credentials the remote gives for that URL. Submodules that aren't
+// checked out (e.g., in a sparse checkout) are skipped.
+func (r *Repo) updateSubmodules(ctx context.Context, workingDir, repoURL string) error {
+	subs, err := listSubmodules(ctx, workingDir)
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		if _, err := os.Stat(filepath.Join(workingDir, s.path)); err != nil {
+			continue
+		}
+		url := resolveSubmoduleURL(repoURL, s.url)
+		// This is what `git submodule init` would do, but with the
+		// URL resolved; init leaves it as it is
+		if err := execGitCmd(ctx, workingDir, nil, "config", "submodule."+s.name+".url", url); err != nil {
+			return errors.Wrapf(err, "setting URL of submodule %s", s.path)
+		}
+		err := r.retry.do(ctx, "clone", func(ctx context.Context) error {
+			creds, err := r.origin.submoduleCredentials(ctx, url)
+			if err != nil {
+				return err
+			}
+			args := append(append([]string{}, submoduleArgs...), "submodule", "update", "--init", "--", s.path)
+			return execGitCmdProgress(ctx, workingDir, nil, nil, creds, args...)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "checking out submodule %s from %s", s.path, url)
+		}
+		if err := r.updateSubmodules(ctx, filepath.Join(workingDir, s.path), url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+// repoWithFile makes a repo in dir with a file in it, then adds the
+// submodules given, as path: url.
+func repoWithFile(t *testing.T, dir, file string, submodules map[string]string) {
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", dir, "init"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config(context.Background(), dir, "submodules_test_user", "example@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte("kind: ConfigMap\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for path, url := range submodules {
+		if err := execCommand("git", "-C", dir, "-c", "protocol.file.allow=always", "submodule", "add", url, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := execCommand("git", "-C", dir, "add", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execCommand("git", "-C", dir, "commit", "-m", "Initial revision"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	root, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	// super has lib as a submodule, which has nested as one
+	repoWithFile(t, filepath.Join(root, "nested"), "nested.yaml", nil)
+	repoWithFile(t, filepath.Join(root, "lib"), "lib.yaml", map[string]string{"sub": "../nested"})
+	repoWithFile(t, filepath.Join(root, "super"), "super.yaml", map[string]string{"vendor/lib": "../lib"})
+
+	submoduleArgs = []string{"-c", "protocol.file.allow=always"}
+	defer func() { submoduleArgs = nil }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := NewRepo(Remote{URL: filepath.Join(root, "super")}, ReadOnly, Submodules)
+	defer repo.Clean()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expectFiles := func(dir string) {
+		for _, file := range []string{"super.yaml", "vendor/lib/lib.yaml", "vendor/lib/sub/nested.yaml"} {
+			if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+				t.Errorf("expected %s to be checked out: %v", file, err)
+			}
+		}
+	}
+
+	export, err := repo.Export(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer export.Clean()
+	expectFiles(export.Dir())
+
+	co, err := repo.ReadOnlyClone(ctx, Config{Branch: "master", NotesRef: "flux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Clean()
+	expectFiles(co.Dir())
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	for _, c := range []struct {
+		base, url, expected string
+	}{
+		{"https://github.com/example/config.git", "../lib.git", "https://github.com/example/lib.git"},
+		{"https://github.com/example/config", "./lib", "https://github.com/example/config/lib"},
+		{"git@github.com:example/config", "../../other/lib", "git@github.com:other/lib"},
+		{"ssh://git@example.com/example/config/", "../lib", "ssh://git@example.com/example/lib"},
+		{"https://github.com/example/config", "https://gitlab.com/example/lib", "https://gitlab.com/example/lib"},
+	} {
+		if got := resolveSubmoduleURL(c.base, c.url); got != c.expected {
+			t.Errorf("expected %s relative to %s to be %s, got %s", c.url, c.base, c.expected, got)
+		}
+	}
+}
+
+func TestSubmoduleCredentials(t *testing.T) {
+	ctx := context.Background()
+	remote := Remote{URL: "https://github.com/example/config", Credentials: StaticCredentials("flux", "repo")}
+	for url, expected := range map[string]string{
+		"https://github.com/example/lib":  "repo",
+		"https://gitlab.com/example/lib":  "",
+		"git@github.com:example/elsewise": "repo",
+	} {
+		creds, err := remote.submoduleCredentials(ctx, url)
+		if err != nil || creds.Password != expected {
+			t.Errorf("expected %q for %s, got %+v (%v)", expected, url, creds, err)
+		}
+	}
+
+	remote.SubmoduleCredentials = CredentialsByURL{
+		"https://github.com/":             StaticCredentials("flux", "github"),
+		"https://github.com/example/lib":  StaticCredentials("flux", "lib"),
+		"https://gitlab.com/example/lib2": StaticCredentials("flux", "gitlab"),
+	}
+	for url, expected := range map[string]string{
+		"https://github.com/example/lib":   "lib",
+		"https://github.com/example/other": "github",
+		"https://gitlab.com/example/lib2":  "gitlab",
+		"https://gitlab.com/example/lib3":  "",
+	} {
+		creds, err := remote.submoduleCredentials(ctx, url)
+		if err != nil || creds.Password != expected {
+			t.Errorf("expected %q for %s, got %+v (%v)", expected, url, creds, err)
+		}
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
 	ErrReadOnly = errors.New("cannot make a working clone of a read-only git repo")
+	// ErrReadOnlyCheckout is returned when trying to commit or push
+	// from a checkout made with ReadOnlyClone.
+	ErrReadOnlyCheckout = errors.New("cannot commit or push from a read-only checkout")
 )
 
 // Config holds some values we use when working in the working clone of
@@ -22,6 +27,27 @@ type Config struct {
 	UserEmail   string
 	SetAuthor   bool
 	SkipMessage string
+	// What to do if a change looks like it contains secrets; one of
+	// SecretScanOff, SecretScanWarn or SecretScanRefuse
+	SecretScan string
+	// If set, working clones have only Paths checked out
+	SparseCheckout bool
+	// If set, commits are signed with this GPG key
+	SigningKey string
+}
+
+// sparsePaths gives the paths to check out in a working clone, or nil
+// if the whole repo is to be checked out.
+func (conf Config) sparsePaths() []string {
+	if !conf.SparseCheckout {
+		return nil
+	}
+	for _, p := range conf.Paths {
+		if p = filepath.Clean(p); p == "." || p == "/" {
+			return nil
+		}
+	}
+	return conf.Paths
 }
 
 // Checkout is a local working clone of the remote repo. It is
@@ -32,17 +58,59 @@ type Checkout struct {
 	config       Config
 	upstream     Remote
 	realNotesRef string // cache the notes ref, since we use it to push as well
+	// A checkout made with ReadOnlyClone can't be committed to, and
+	// is given back to the pool rather than removed
+	readonly bool
+	release  func()
+	// How pushes are timed out and retried
+	retry retryPolicy
 }
 
 type Commit struct {
 	Revision string
 	Message  string
+	// When the commit was made (strictly, committed, so a rebased or
+	// cherry-picked commit has the time it was rebased or picked)
+	Time time.Time
+}
+
+// Tag is a tag in the repo, and the commit it's of.
+type Tag struct {
+	Name     string
+	Revision string
+	// When the tag was made, if it's annotated; otherwise, when the
+	// commit was
+	Time time.Time
 }
 
 // CommitAction - struct holding commit information
 type CommitAction struct {
 	Author  string
 	Message string
+	// Trailers are added to the end of the message, after anything
+	// else (e.g., the skip message)
+	Trailers []Trailer
+}
+
+// Trailer is a `Key: value` line at the end of a commit message, as
+// understood by `git interpret-trailers`, for tools to read.
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// trailerLines gives the trailers as a paragraph to append to a
+// commit message, or "" if there are none.
+func trailerLines(trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return ""
+	}
+	lines := "\n"
+	for _, t := range trailers {
+		// A trailer can't span lines
+		lines += "\n" + t.Key + ": " + strings.Join(strings.Fields(t.Value), " ")
+	}
+	return lines
 }
 
 // Clone returns a local working clone of the sync'ed `*Repo`, using
@@ -53,7 +121,7 @@ func (r *Repo) Clone(ctx context.Context, conf Config) (*Checkout, error) {
 	}
 
 	upstream := r.Origin()
-	repoDir, err := r.workingClone(ctx, conf.Branch)
+	repoDir, err := r.workingClone(ctx, conf.Branch, conf.sparsePaths())
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +131,13 @@ func (r *Repo) Clone(ctx context.Context, conf Config) (*Checkout, error) {
 		return nil, err
 	}
 
+	if r.submodules {
+		if err := r.updateSubmodules(ctx, repoDir, upstream.URL); err != nil {
+			os.RemoveAll(repoDir)
+			return nil, err
+		}
+	}
+
 	// We'll need the notes ref for pushing it, so make sure we have
 	// it. This assumes we're syncing it (otherwise we'll likely get conflicts)
 	realNotesRef, err := getNotesRef(ctx, repoDir, conf.NotesRef)
@@ -72,7 +147,7 @@ func (r *Repo) Clone(ctx context.Context, conf Config) (*Checkout, error) {
 	}
 
 	r.mu.RLock()
-	if err := fetch(ctx, repoDir, r.dir, realNotesRef+":"+realNotesRef); err != nil {
+	if err := fetch(ctx, repoDir, r.dir, Credentials{}, realNotesRef+":"+realNotesRef); err != nil {
 		os.RemoveAll(repoDir)
 		r.mu.RUnlock()
 		return nil, err
@@ -84,11 +159,18 @@ func (r *Repo) Clone(ctx context.Context, conf Config) (*Checkout, error) {
 		upstream:     upstream,
 		realNotesRef: realNotesRef,
 		config:       conf,
+		retry:        r.retry,
 	}, nil
 }
 
-// Clean a Checkout up (remove the clone)
+// Clean a Checkout up (remove the clone, or give it back to the pool
+// it came from)
 func (c *Checkout) Clean() {
+	if c.release != nil {
+		c.release()
+		c.release = nil
+		return
+	}
 	if c.dir != "" {
 		os.RemoveAll(c.dir)
 	}
@@ -117,13 +199,53 @@ func (c *Checkout) ManifestDirs() []string {
 // CommitAndPush commits changes made in this checkout, along with any
 // extra data as a note, and pushes the commit and note to the remote repo.
 func (c *Checkout) CommitAndPush(ctx context.Context, commitAction CommitAction, note interface{}) error {
+	return c.commitAndPush(ctx, c.config.Branch, commitAction, note)
+}
+
+// CommitAndPushBranch is like CommitAndPush, but pushes the commit to
+// a new branch of its own, rather than the branch checked out; e.g.,
+// to open a pull request for it.
+func (c *Checkout) CommitAndPushBranch(ctx context.Context, branch string, commitAction CommitAction, note interface{}) error {
+	return c.commitAndPush(ctx, "HEAD:refs/heads/"+branch, commitAction, note)
+}
+
+// BranchExists says whether the branch is in the upstream repo, as
+// of when the repo was last fetched from.
+func (c *Checkout) BranchExists(ctx context.Context, branch string) (bool, error) {
+	return refExists(ctx, c.dir, "refs/remotes/origin/"+branch)
+}
+
+// Diff gives the changes made in this checkout, and not committed
+// yet, to the files under the paths synced, as a patch.
+func (c *Checkout) Diff(ctx context.Context) ([]byte, error) {
+	return diff(ctx, c.dir, "HEAD", c.config.Paths)
+}
+
+func (c *Checkout) commitAndPush(ctx context.Context, ref string, commitAction CommitAction, note interface{}) error {
+	if c.readonly {
+		return ErrReadOnlyCheckout
+	}
 	if !check(ctx, c.dir, c.config.Paths) {
 		return ErrNoChanges
 	}
 
+	if c.config.SecretScan == SecretScanWarn || c.config.SecretScan == SecretScanRefuse {
+		findings, err := scanChanges(ctx, c.dir)
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			if c.config.SecretScan == SecretScanRefuse {
+				return SecretsFoundError(findings)
+			}
+			commitAction.Message += secretsWarning(findings)
+		}
+	}
+
 	commitAction.Message += c.config.SkipMessage
+	commitAction.Message += trailerLines(commitAction.Trailers)
 
-	if err := commit(ctx, c.dir, commitAction); err != nil {
+	if err := commit(ctx, c.dir, c.config.SigningKey, commitAction); err != nil {
 		return err
 	}
 
@@ -137,7 +259,7 @@ func (c *Checkout) CommitAndPush(ctx context.Context, commitAction CommitAction,
 		}
 	}
 
-	refs := []string{c.config.Branch}
+	refs := []string{ref}
 	ok, err := refExists(ctx, c.dir, c.realNotesRef)
 	if ok {
 		refs = append(refs, c.realNotesRef)
@@ -145,7 +267,14 @@ func (c *Checkout) CommitAndPush(ctx context.Context, commitAction CommitAction,
 		return err
 	}
 
-	if err := push(ctx, c.dir, c.upstream.URL, refs); err != nil {
+	err = c.retry.do(ctx, "push", func(ctx context.Context) error {
+		creds, err := c.upstream.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		return push(ctx, c.dir, c.upstream.URL, creds, refs)
+	})
+	if err != nil {
 		return PushError(c.upstream.URL, err)
 	}
 	return nil
@@ -156,16 +285,48 @@ func (c *Checkout) GetNote(ctx context.Context, rev string, note interface{}) (b
 	return getNote(ctx, c.dir, c.realNotesRef, rev, note)
 }
 
+// CheckoutRevision checks out the revision given, which is usually in
+// the history of the branch, detached from the branch; e.g., to sync
+// the manifests as they were before the head of the branch. Commits
+// shouldn't be made in the checkout after that.
+func (c *Checkout) CheckoutRevision(ctx context.Context, rev string) error {
+	return checkout(ctx, c.dir, rev)
+}
+
 func (c *Checkout) HeadRevision(ctx context.Context) (string, error) {
 	return refRevision(ctx, c.dir, "HEAD")
 }
 
+// HeadSignature gives the signature of the commit at HEAD, e.g., to
+// see which key a commit just made was signed with.
+func (c *Checkout) HeadSignature(ctx context.Context) (CommitSignature, error) {
+	sigs, err := commitSignatures(ctx, c.dir, "--max-count=1", "HEAD")
+	if err != nil {
+		return CommitSignature{}, err
+	}
+	if len(sigs) == 0 {
+		return CommitSignature{}, errors.New("no commit at HEAD")
+	}
+	return sigs[0], nil
+}
+
 func (c *Checkout) SyncRevision(ctx context.Context) (string, error) {
 	return refRevision(ctx, c.dir, c.config.SyncTag)
 }
 
 func (c *Checkout) MoveSyncTagAndPush(ctx context.Context, ref, msg string) error {
-	return moveTagAndPush(ctx, c.dir, c.config.SyncTag, ref, msg, c.upstream.URL)
+	if c.readonly {
+		// A worktree shares its tags with the mirror, so this would
+		// move the tag there too
+		return ErrReadOnlyCheckout
+	}
+	return c.retry.do(ctx, "push", func(ctx context.Context) error {
+		creds, err := c.upstream.credentials(ctx)
+		if err != nil {
+			return err
+		}
+		return moveTagAndPush(ctx, c.dir, c.config.SyncTag, ref, msg, c.upstream.URL, creds)
+	})
 }
 
 // ChangedFiles does a git diff listing changed files
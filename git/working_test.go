@@ -0,0 +1,80 @@
+package git
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/testfiles"
+)
+
+func TestTrailerLines(t *testing.T) {
+	if lines := trailerLines(nil); lines != "" {
+		t.Errorf("expected nothing for no trailers, got %q", lines)
+	}
+	lines := trailerLines([]Trailer{
+		{Key: "Flux-Job-ID", Value: "abc-123"},
+		{Key: "Flux-Cause-User", Value: "Jane\nDoe "},
+	})
+	// A paragraph of its own, one line per trailer
+	expected := "\n\nFlux-Job-ID: abc-123\nFlux-Cause-User: Jane Doe"
+	if lines != expected {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestCommitAndPushBranch(t *testing.T) {
+	upstream, cleanup := testfiles.TempDir(t)
+	defer cleanup()
+	if err := createRepo(upstream, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	cloneDir, cleanupClone := testfiles.TempDir(t)
+	defer cleanupClone()
+	ctx := context.Background()
+	dir, err := clone(ctx, cloneDir, upstream, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config(ctx, dir, "flux", "flux@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	working := &Checkout{
+		dir:          dir,
+		upstream:     Remote{URL: upstream},
+		realNotesRef: "refs/notes/flux",
+		config:       Config{Branch: "master", NotesRef: "flux"},
+	}
+	before, err := refRevision(ctx, upstream, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := working.BranchExists(ctx, "flux-update/abc"); err != nil || ok {
+		t.Fatalf("expected the branch not to exist yet, got %v (%v)", ok, err)
+	}
+	if err := updateFile(filepath.Join(dir, "a"), testfiles.FilesUpdated); err != nil {
+		t.Fatal(err)
+	}
+	if patch, err := working.Diff(ctx); err != nil || !strings.Contains(string(patch), "+++ b/a/") {
+		t.Errorf("expected a patch of the changes in a/, got %q (%v)", patch, err)
+	}
+	if err := working.CommitAndPushBranch(ctx, "flux-update/abc", CommitAction{Message: "Update images"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := working.HeadRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed, err := refRevision(ctx, upstream, "flux-update/abc"); err != nil || pushed != head {
+		t.Errorf("expected the commit to be pushed to the branch, got %q (%v)", pushed, err)
+	}
+	if after, err := refRevision(ctx, upstream, "master"); err != nil || after != before {
+		t.Errorf("expected master not to be changed, got %q (%v)", after, err)
+	}
+	if ok, err := working.BranchExists(ctx, "master"); err != nil || !ok {
+		t.Errorf("expected master to exist, got %v (%v)", ok, err)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -15,6 +18,24 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 	"github.com/weaveworks/flux/api/v6"
 	fluxerr "github.com/weaveworks/flux/errors"
 	"github.com/weaveworks/flux/event"
@@ -27,6 +48,11 @@ var (
 	errNotImplemented = errors.New("not implemented")
 )
 
+// Credentials authenticate a request, usually by setting a header.
+type Credentials interface {
+	Set(*http.Request)
+}
+
 type Token string
 
 func (t Token) Set(req *http.Request) {
@@ -35,16 +61,26 @@ func (t Token) Set(req *http.Request) {
 	}
 }
 
+// BearerToken is a token (e.g., an OpenID Connect ID token) sent as
+// a bearer token in the Authorization header.
+type BearerToken string
+
+func (t BearerToken) Set(req *http.Request) {
+	if string(t) != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t))
+	}
+}
+
 type Client struct {
 	client   *http.Client
-	token    Token
+	token    Credentials
 	router   *mux.Router
 	endpoint string
 }
 
 var _ api.Server = &Client{}
 
-func New(c *http.Client, router *mux.Router, endpoint string, t Token) *Client {
+func New(c *http.Client, router *mux.Router, endpoint string, t Credentials) *Client {
 	return &Client{
 		client:   c,
 		token:    t,
@@ -81,6 +117,42 @@ func (c *Client) ListImagesWithOptions(ctx context.Context, opts v10.ListImagesO
 	return res, err
 }
 
+func (c *Client) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) ([]v12.WorkloadDeployment, error) {
+	var res []v12.WorkloadDeployment
+	err := c.Get(ctx, &res, transport.ListDeployments, "namespace", opts.Namespace)
+	return res, err
+}
+
+func (c *Client) ListEvents(ctx context.Context, opts v13.ListEventsOptions) ([]event.Event, error) {
+	var res []event.Event
+	err := c.Get(ctx, &res, transport.ListEvents, "after", strconv.FormatInt(int64(opts.After), 10), "limit", strconv.Itoa(opts.Limit))
+	return res, err
+}
+
+func (c *Client) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (v14.WorkloadCheck, error) {
+	var res v14.WorkloadCheck
+	err := c.Get(ctx, &res, transport.CheckWorkload, "id", opts.Workload.String())
+	return res, err
+}
+
+func (c *Client) ImageReport(ctx context.Context, opts v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	var res []v15.ImageUsage
+	err := c.Get(ctx, &res, transport.ImageReport, "namespace", opts.Namespace)
+	return res, err
+}
+
+func (c *Client) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	var res []v16.WorkloadDelivery
+	err := c.Get(ctx, &res, transport.DeliveryReport, "namespace", opts.Namespace, "period", opts.Period.String())
+	return res, err
+}
+
+func (c *Client) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	var res []v17.ImageComparison
+	err := c.Get(ctx, &res, transport.CompareImages, "namespace", opts.Namespace)
+	return res, err
+}
+
 func (c *Client) JobStatus(ctx context.Context, jobID job.ID) (job.Status, error) {
 	var res job.Status
 	err := c.Get(ctx, &res, transport.JobStatus, "id", string(jobID))
@@ -109,6 +181,149 @@ func (c *Client) Export(ctx context.Context) ([]byte, error) {
 	return res, err
 }
 
+func (c *Client) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) ([]byte, error) {
+	var res []byte
+	err := c.Get(ctx, &res, transport.ExportCluster, "namespaces", strings.Join(opts.Namespaces, ","), "kinds", strings.Join(opts.Kinds, ","))
+	return res, err
+}
+
+func (c *Client) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (v19.DeployedState, error) {
+	var at string
+	if !opts.At.IsZero() {
+		at = opts.At.Format(time.RFC3339)
+	}
+	var res v19.DeployedState
+	err := c.Get(ctx, &res, transport.DeployedAt, "namespace", opts.Namespace, "at", at)
+	return res, err
+}
+
+func (c *Client) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (event.Event, error) {
+	var res event.Event
+	err := c.methodWithResp(ctx, "POST", &res, transport.AnnotateEvent, opts)
+	return res, err
+}
+
+func (c *Client) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	var res v21.RevisionDiff
+	err := c.Get(ctx, &res, transport.DiffRevisions, "from", opts.From, "to", opts.To, "namespace", opts.Namespace, "manifests", strconv.FormatBool(opts.Manifests))
+	return res, err
+}
+
+func (c *Client) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	var res v22.EventHistory
+	err := c.Get(ctx, &res, transport.EventHistory, eventHistoryQuery(opts)...)
+	return res, err
+}
+
+// ExportEvents streams the event history, in the export format given
+// (see event.ExportFormats), to out, as the daemon sends it.
+func (c *Client) ExportEvents(ctx context.Context, opts v22.EventHistoryOptions, format string, out io.Writer) error {
+	query := append(eventHistoryQuery(opts), "format", format)
+	u, err := transport.MakeURL(c.endpoint, c.router, transport.ExportEvents, query...)
+	if err != nil {
+		return errors.Wrap(err, "constructing URL")
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "constructing request %s", u)
+	}
+	req = req.WithContext(ctx)
+
+	c.token.Set(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.executeRequest(req)
+	if err != nil {
+		return errors.Wrap(err, "executing HTTP request")
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return errors.Wrap(err, "reading exported events")
+	}
+	return nil
+}
+
+func eventHistoryQuery(opts v22.EventHistoryOptions) []string {
+	query := []string{
+		"after", strconv.FormatInt(int64(opts.After), 10),
+		"before", strconv.FormatInt(int64(opts.Before), 10),
+		"limit", strconv.Itoa(opts.Limit),
+	}
+	if opts.Workload != nil {
+		query = append(query, "workload", opts.Workload.String())
+	}
+	for _, t := range opts.Filter.Types {
+		query = append(query, "type", t)
+	}
+	for _, id := range opts.Filter.Services {
+		query = append(query, "service", id.String())
+	}
+	for _, ns := range opts.Filter.Namespaces {
+		query = append(query, "namespace", ns)
+	}
+	if !opts.Filter.Since.IsZero() {
+		query = append(query, "since", opts.Filter.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Filter.Until.IsZero() {
+		query = append(query, "until", opts.Filter.Until.Format(time.RFC3339Nano))
+	}
+	if opts.Filter.MinLogLevel != "" {
+		query = append(query, "level", opts.Filter.MinLogLevel)
+	}
+	if opts.Filter.CorrelationID != "" {
+		query = append(query, "correlation", opts.Filter.CorrelationID)
+	}
+	return query
+}
+
+func (c *Client) MetricsSnapshot(ctx context.Context) (v23.MetricsSnapshot, error) {
+	var res v23.MetricsSnapshot
+	err := c.Get(ctx, &res, transport.MetricsSnapshot)
+	return res, err
+}
+
+func (c *Client) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	var res v24.PruneEventsResult
+	err := c.methodWithResp(ctx, "POST", &res, transport.PruneEvents, opts)
+	return res, err
+}
+
+func (c *Client) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	var res v25.ReplayEventsResult
+	err := c.Get(ctx, &res, transport.ReplayEvents, "cursor", opts.Cursor, "limit", strconv.Itoa(opts.Limit))
+	return res, err
+}
+
+func (c *Client) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	var res v26.ComplianceReport
+	params := []string{"namespace", opts.Namespace, "since", opts.Since.Format(time.RFC3339)}
+	if !opts.Until.IsZero() {
+		params = append(params, "until", opts.Until.Format(time.RFC3339))
+	}
+	err := c.Get(ctx, &res, transport.ComplianceReport, params...)
+	return res, err
+}
+
+func (c *Client) LogEvents(ctx context.Context, opts v27.LogEventsOptions) error {
+	return c.PostWithBody(ctx, transport.LogEvents, opts)
+}
+
+func (c *Client) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	var res v28.WorkloadHistory
+	query := []string{"workload", opts.Workload.String()}
+	if !opts.Since.IsZero() {
+		query = append(query, "since", opts.Since.Format(time.RFC3339Nano))
+	}
+	err := c.Get(ctx, &res, transport.WorkloadHistory, query...)
+	return res, err
+}
+
+func (c *Client) JobQueue(ctx context.Context) (v29.JobQueue, error) {
+	var res v29.JobQueue
+	err := c.Get(ctx, &res, transport.JobQueue)
+	return res, err
+}
+
 func (c *Client) GitRepoConfig(ctx context.Context, regenerate bool) (v6.GitConfig, error) {
 	var res v6.GitConfig
 	err := c.methodWithResp(ctx, "POST", &res, transport.GitRepoConfig, regenerate)
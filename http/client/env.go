@@ -0,0 +1,37 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	transport "github.com/weaveworks/flux/http"
+)
+
+// The environment variables by which fluxctl passes its connection to
+// the API on to plugins (`fluxctl-<name>` executables run as `fluxctl
+// <name>`). These are the same variables fluxctl itself reads, so a
+// plugin that runs fluxctl will reach the same API.
+const (
+	EnvURL     = "FLUX_URL"
+	EnvToken   = "FLUX_SERVICE_TOKEN"
+	EnvIDToken = "FLUX_ID_TOKEN"
+)
+
+// NewFromEnv gives a client for the API that fluxctl is connected to,
+// for use in fluxctl plugins.
+func NewFromEnv(c *http.Client) (*Client, error) {
+	endpoint := os.Getenv(EnvURL)
+	if endpoint == "" {
+		return nil, fmt.Errorf("%s is not set; is this being run as a fluxctl plugin?", EnvURL)
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", EnvURL, err)
+	}
+	var creds Credentials = Token(os.Getenv(EnvToken))
+	if idToken := os.Getenv(EnvIDToken); idToken != "" {
+		creds = BearerToken(idToken)
+	}
+	return New(c, transport.NewAPIRouter(), endpoint, creds), nil
+}
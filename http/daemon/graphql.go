@@ -0,0 +1,297 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
+	transport "github.com/weaveworks/flux/http"
+)
+
+// eventsSchema is what can be queried with GraphQL: pages of events,
+// as given by EventHistory, or an event by its ID. The metadata of an
+// event is a union of the kinds of metadata, selected with, e.g.,
+// `... on ReleaseEventMetadata`; those are in graphql_metadata.go.
+const eventsSchema = `
+schema {
+	query: Query
+}
+
+scalar Time
+# Any JSON value, for the parts of events that don't have a fixed
+# shape
+scalar JSON
+
+type Query {
+	# A page of events, newest last, as from /v22/event-history
+	events(
+		after: ID, before: ID, limit: Int,
+		workload: String, types: [String!], services: [String!], namespaces: [String!],
+		since: Time, until: Time, minLogLevel: String, correlationID: String
+	): EventPage!
+	# An event by its ID, or null if it isn't kept
+	event(id: ID!): Event
+}
+
+type EventPage {
+	events: [Event!]!
+	# If some events were left out because of the limit, the ID to
+	# give as before to get the events before these
+	older: ID
+}
+
+type Event {
+	id: ID!
+	type: String!
+	serviceIDs: [String!]!
+	namespace: String!
+	owners: [String!]!
+	criticality: String!
+	startedAt: Time!
+	endedAt: Time!
+	logLevel: String!
+	# The message, as shown by fluxctl history
+	message: String!
+	repeated: Int!
+	correlationID: String!
+	traceID: String!
+	spanID: String!
+	annotations: [Annotation!]!
+	metadata: EventMetadata
+}
+
+type Annotation {
+	time: Time!
+	user: String!
+	comment: String!
+	acknowledged: Boolean!
+}
+` + metadataSchema
+
+// graphqlMaxDepth limits how deeply queries can nest, so one can't
+// be made that takes an age to answer.
+const graphqlMaxDepth = 12
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlHandler answers GraphQL queries of the event history, given
+// as JSON in a POST, or in the query parameters of a GET.
+type graphqlHandler struct {
+	schema *graphql.Schema
+}
+
+func newGraphQLHandler(s api.Server) http.Handler {
+	return &graphqlHandler{
+		schema: graphql.MustParseSchema(eventsSchema, &graphqlRoot{server: s},
+			graphql.UseFieldResolvers(), graphql.MaxDepth(graphqlMaxDepth)),
+	}
+}
+
+func (h *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing GraphQL request"))
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if vars := query.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'variables'"))
+				return
+			}
+		}
+	}
+	transport.JSONResponse(w, r, h.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables))
+}
+
+type graphqlRoot struct {
+	server api.Server
+}
+
+type graphqlEventsArgs struct {
+	After, Before               *graphql.ID
+	Limit                       *int32
+	Workload                    *string
+	Types, Services, Namespaces *[]string
+	Since, Until                *graphql.Time
+	MinLogLevel, CorrelationID  *string
+}
+
+func (q *graphqlRoot) Events(ctx context.Context, args graphqlEventsArgs) (*graphqlEventPage, error) {
+	opts, err := graphqlHistoryOptions(args)
+	if err != nil {
+		return nil, err
+	}
+	page, err := q.server.EventHistory(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &graphqlEventPage{}
+	for _, e := range page.Events {
+		res.Events = append(res.Events, newGraphQLEvent(e))
+	}
+	if page.Older > 0 {
+		res.Older = graphqlID(page.Older)
+	}
+	return res, nil
+}
+
+func (q *graphqlRoot) Event(ctx context.Context, args struct{ ID graphql.ID }) (*graphqlEvent, error) {
+	id, err := parseGraphQLID("id", &args.ID)
+	if err != nil {
+		return nil, err
+	}
+	page, err := q.server.EventHistory(ctx, v22.EventHistoryOptions{After: id - 1, Before: id + 1, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range page.Events {
+		if e.ID == id {
+			return newGraphQLEvent(e), nil
+		}
+	}
+	return nil, nil
+}
+
+// graphqlHistoryOptions gives the page of events, and the filter,
+// from the arguments to the events field.
+func graphqlHistoryOptions(args graphqlEventsArgs) (v22.EventHistoryOptions, error) {
+	var opts v22.EventHistoryOptions
+	var err error
+	if opts.After, err = parseGraphQLID("after", args.After); err != nil {
+		return opts, err
+	}
+	if opts.Before, err = parseGraphQLID("before", args.Before); err != nil {
+		return opts, err
+	}
+	if args.Limit != nil {
+		opts.Limit = int(*args.Limit)
+	}
+	if args.Workload != nil && *args.Workload != "" {
+		id, err := flux.ParseResourceID(*args.Workload)
+		if err != nil {
+			return opts, errors.Wrap(err, "parsing argument \"workload\"")
+		}
+		opts.Workload = &id
+	}
+	if args.Types != nil {
+		opts.Filter.Types = *args.Types
+	}
+	if args.Namespaces != nil {
+		opts.Filter.Namespaces = *args.Namespaces
+	}
+	if args.Services != nil {
+		for _, service := range *args.Services {
+			id, err := flux.ParseResourceID(service)
+			if err != nil {
+				return opts, errors.Wrap(err, "parsing argument \"services\"")
+			}
+			opts.Filter.Services = append(opts.Filter.Services, id)
+		}
+	}
+	if args.Since != nil {
+		opts.Filter.Since = args.Since.Time
+	}
+	if args.Until != nil {
+		opts.Filter.Until = args.Until.Time
+	}
+	if args.MinLogLevel != nil {
+		opts.Filter.MinLogLevel = *args.MinLogLevel
+	}
+	if args.CorrelationID != nil {
+		opts.Filter.CorrelationID = *args.CorrelationID
+	}
+	return opts, nil
+}
+
+func parseGraphQLID(name string, id *graphql.ID) (event.EventID, error) {
+	if id == nil || *id == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(string(*id), 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("argument %q must be an event ID", name)
+	}
+	return event.EventID(n), nil
+}
+
+func graphqlID(id event.EventID) *graphql.ID {
+	s := graphql.ID(strconv.FormatInt(int64(id), 10))
+	return &s
+}
+
+type graphqlEventPage struct {
+	Events []*graphqlEvent
+	Older  *graphql.ID
+}
+
+type graphqlEvent struct {
+	ID            graphql.ID
+	Type          string
+	ServiceIDs    []string
+	Namespace     string
+	Owners        []string
+	Criticality   string
+	StartedAt     graphql.Time
+	EndedAt       graphql.Time
+	LogLevel      string
+	Message       string
+	Repeated      int32
+	CorrelationID string
+	TraceID       string
+	SpanID        string
+	Annotations   []graphqlAnnotation
+	Metadata      *graphqlMetadata
+}
+
+type graphqlAnnotation struct {
+	Time         graphql.Time
+	User         string
+	Comment      string
+	Acknowledged bool
+}
+
+func newGraphQLEvent(e event.Event) *graphqlEvent {
+	res := &graphqlEvent{
+		ID:            *graphqlID(e.ID),
+		Type:          e.Type,
+		ServiceIDs:    e.ServiceIDStrings(),
+		Namespace:     e.Namespace,
+		Owners:        e.Owners,
+		Criticality:   e.Criticality,
+		StartedAt:     graphql.Time{Time: e.StartedAt},
+		EndedAt:       graphql.Time{Time: e.EndedAt},
+		LogLevel:      e.LogLevel,
+		Message:       e.String(),
+		Repeated:      int32(e.Repeated),
+		CorrelationID: e.CorrelationID,
+		TraceID:       e.TraceID,
+		SpanID:        e.SpanID,
+		Metadata:      newGraphQLMetadata(e.Metadata),
+	}
+	for _, a := range e.Annotations {
+		res.Annotations = append(res.Annotations, graphqlAnnotation{
+			Time:         graphql.Time{Time: a.Time},
+			User:         a.User,
+			Comment:      a.Comment,
+			Acknowledged: a.Acknowledged,
+		})
+	}
+	return res
+}
@@ -0,0 +1,950 @@
+package daemon
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// metadataSchema has a type for each kind of event metadata, with
+// the fields as in the JSON API (but in camel case throughout);
+// durations are given as strings, e.g., `1m30s`.
+const metadataSchema = `
+union EventMetadata = CommitEventMetadata | SyncEventMetadata
+	| ReleaseEventMetadata | AutoReleaseEventMetadata | RollbackEventMetadata
+	| ObservedReleaseEventMetadata | RestartEventMetadata
+	| PolicyUpdateEventMetadata | LockEventMetadata
+	| AccessDeniedEventMetadata | AuditEventMetadata | StaleImageEventMetadata
+	| SyncHeldEventMetadata | SyncUnverifiedEventMetadata | SyncRewriteEventMetadata
+	| SyncConflictEventMetadata | FreezeEventMetadata
+	| DaemonStartEventMetadata | DaemonStopEventMetadata
+	| AutomationDeferredEventMetadata | AutomationSuspendedEventMetadata
+	| ImagePolledEventMetadata | ChainHeadEventMetadata | UnknownEventMetadata
+
+type Cause {
+	user: String!
+	message: String!
+}
+
+type Commit {
+	revision: String!
+	message: String!
+	time: Time
+	correlationID: String!
+}
+
+# What a release did to a workload
+type WorkloadResult {
+	id: String!
+	status: String!
+	error: String!
+	perContainer: [ContainerUpdate!]!
+}
+
+type ContainerUpdate {
+	container: String!
+	current: String!
+	target: String!
+}
+
+# A container given a new image automatically
+type AutomatedChange {
+	serviceID: String!
+	container: String!
+	image: String!
+}
+
+type SBOM {
+	image: String!
+	digest: String!
+	ref: String!
+}
+
+type ImageVerification {
+	image: String!
+	digest: String!
+	verified: Boolean!
+	signatures: Int!
+	attestations: Int!
+	output: String!
+}
+
+type ReleaseSpec {
+	serviceSpecs: [String!]!
+	imageSpec: String!
+	kind: String!
+	excludes: [String!]!
+	force: Boolean!
+}
+
+type CommitEventMetadata {
+	revision: String!
+	# The spec of the change committed, which has a different shape
+	# for each kind of change
+	spec: JSON
+	result: [WorkloadResult!]!
+	signingKey: String!
+	pullRequest: String!
+}
+
+type ResourceError {
+	id: String!
+	path: String!
+	error: String!
+}
+
+type SyncEventMetadata {
+	commits: [Commit!]!
+	# The kinds of commit synced: release, autorelease, policy, and
+	# other (those flux didn't make)
+	includes: [String!]!
+	errors: [ResourceError!]!
+	initialSync: Boolean!
+	recovered: Boolean!
+	skipped: Boolean!
+}
+
+type ReleaseEventMetadata {
+	revision: String!
+	result: [WorkloadResult!]!
+	error: String!
+	sboms: [SBOM!]!
+	verifications: [ImageVerification!]!
+	capacity: JSON
+	spec: ReleaseSpec!
+	cause: Cause!
+}
+
+type AutoReleaseEventMetadata {
+	revision: String!
+	result: [WorkloadResult!]!
+	error: String!
+	sboms: [SBOM!]!
+	verifications: [ImageVerification!]!
+	capacity: JSON
+	changes: [AutomatedChange!]!
+}
+
+type RollbackEventMetadata {
+	revision: String!
+	result: [WorkloadResult!]!
+	error: String!
+	sboms: [SBOM!]!
+	verifications: [ImageVerification!]!
+	capacity: JSON
+	releaseID: ID!
+	releaseRevision: String!
+	reason: String!
+	cause: Cause!
+}
+
+type ObservedReleaseEventMetadata {
+	changes: [AutomatedChange!]!
+	result: [WorkloadResult!]!
+}
+
+type RestartEventMetadata {
+	result: [WorkloadResult!]!
+	reason: String!
+	cause: Cause!
+}
+
+type Policy {
+	name: String!
+	value: String!
+}
+
+type PolicyChange {
+	id: String!
+	before: [Policy!]!
+	after: [Policy!]!
+}
+
+type PolicyUpdateEventMetadata {
+	revision: String!
+	changes: [PolicyChange!]!
+	cause: Cause!
+}
+
+type LockEventMetadata {
+	revision: String!
+	changes: [PolicyChange!]!
+	cause: Cause!
+	user: String!
+	reason: String!
+	untilRevision: String!
+}
+
+type AccessDeniedEventMetadata {
+	user: String!
+	verb: String!
+	method: String!
+}
+
+type AuditEventMetadata {
+	user: String!
+	method: String!
+	verbs: [String!]!
+	targets: [String!]!
+	result: String!
+	error: String!
+}
+
+type StaleImage {
+	id: String!
+	container: String!
+	current: String!
+	createdAt: Time
+	latest: String!
+	latestAt: Time
+	newer: Int!
+	owner: String!
+}
+
+type StaleImageEventMetadata {
+	images: [StaleImage!]!
+}
+
+type SyncHeldEventMetadata {
+	revision: String!
+	reason: String!
+	changed: Int!
+	deleted: Int!
+}
+
+type SyncUnverifiedEventMetadata {
+	url: String!
+	revision: String!
+	reason: String!
+	fingerprint: String!
+}
+
+type SyncRewriteEventMetadata {
+	url: String!
+	branch: String!
+	from: String!
+	to: String!
+	dropped: [Commit!]!
+}
+
+type SyncConflict {
+	id: String!
+	field: String!
+	applied: String!
+	live: String!
+	manager: String!
+}
+
+type SyncConflictEventMetadata {
+	conflicts: [SyncConflict!]!
+}
+
+type FreezeEventMetadata {
+	reason: String!
+	start: Time
+	end: Time
+	over: Boolean!
+}
+
+type DaemonStartEventMetadata {
+	version: String!
+	previousVersion: String!
+	configDigest: String!
+	previousConfigDigest: String!
+	changedConfig: [String!]!
+}
+
+type DaemonStopEventMetadata {
+	version: String!
+	configDigest: String!
+	reason: String!
+}
+
+type AutomationDeferredEventMetadata {
+	reason: String!
+	since: Time
+}
+
+type AutomationSuspendedEventMetadata {
+	failures: Int!
+	window: String!
+	reason: String!
+}
+
+type PolledRepository {
+	name: String!
+	tags: Int!
+	newTags: [String!]!
+}
+
+type ImagePolledEventMetadata {
+	repositories: [PolledRepository!]!
+	duration: String!
+}
+
+type ChainHeadEventMetadata {
+	id: ID!
+	hash: String!
+}
+
+# Metadata of a kind this daemon doesn't know, as it was logged
+type UnknownEventMetadata {
+	json: JSON
+}
+`
+
+// graphqlJSON is a value for the JSON scalar.
+type graphqlJSON struct {
+	value interface{}
+}
+
+func (graphqlJSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *graphqlJSON) UnmarshalGraphQL(input interface{}) error {
+	j.value = input
+	return nil
+}
+
+func (j graphqlJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.value)
+}
+
+func newGraphQLJSON(v interface{}) *graphqlJSON {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	return &graphqlJSON{v}
+}
+
+type graphqlCause struct {
+	Message string
+	User    string
+}
+
+type graphqlCommit struct {
+	Revision      string
+	Message       string
+	Time          *graphql.Time
+	CorrelationID string
+}
+
+type graphqlWorkloadResult struct {
+	ID           string
+	Status       string
+	Error        string
+	PerContainer []graphqlContainerUpdate
+}
+
+type graphqlContainerUpdate struct {
+	Container string
+	Current   string
+	Target    string
+}
+
+type graphqlAutomatedChange struct {
+	ServiceID string
+	Container string
+	Image     string
+}
+
+type graphqlSBOM struct {
+	Image  string
+	Digest string
+	Ref    string
+}
+
+type graphqlImageVerification struct {
+	Image        string
+	Digest       string
+	Verified     bool
+	Signatures   int32
+	Attestations int32
+	Output       string
+}
+
+type graphqlReleaseSpec struct {
+	ServiceSpecs []string
+	ImageSpec    string
+	Kind         string
+	Excludes     []string
+	Force        bool
+}
+
+type graphqlResourceError struct {
+	ID    string
+	Path  string
+	Error string
+}
+
+type graphqlPolicy struct {
+	Name  string
+	Value string
+}
+
+type graphqlPolicyChange struct {
+	ID     string
+	Before []graphqlPolicy
+	After  []graphqlPolicy
+}
+
+type graphqlStaleImage struct {
+	ID        string
+	Container string
+	Current   string
+	CreatedAt *graphql.Time
+	Latest    string
+	LatestAt  *graphql.Time
+	Newer     int32
+	Owner     string
+}
+
+type graphqlSyncConflict struct {
+	ID      string
+	Field   string
+	Applied string
+	Live    string
+	Manager string
+}
+
+type graphqlPolledRepository struct {
+	Name    string
+	Tags    int32
+	NewTags []string
+}
+
+// The fields shared by the metadata of releases, automated releases
+// and rollbacks
+type graphqlReleaseCommon struct {
+	Revision      string
+	Result        []graphqlWorkloadResult
+	Error         string
+	SBOMs         []graphqlSBOM
+	Verifications []graphqlImageVerification
+	Capacity      *graphqlJSON
+}
+
+type graphqlCommitMetadata struct {
+	Revision    string
+	Spec        *graphqlJSON
+	Result      []graphqlWorkloadResult
+	SigningKey  string
+	PullRequest string
+}
+
+type graphqlSyncMetadata struct {
+	Commits     []graphqlCommit
+	Includes    []string
+	Errors      []graphqlResourceError
+	InitialSync bool
+	Recovered   bool
+	Skipped     bool
+}
+
+type graphqlReleaseMetadata struct {
+	graphqlReleaseCommon
+	Spec  graphqlReleaseSpec
+	Cause graphqlCause
+}
+
+type graphqlAutoReleaseMetadata struct {
+	graphqlReleaseCommon
+	Changes []graphqlAutomatedChange
+}
+
+type graphqlRollbackMetadata struct {
+	graphqlReleaseCommon
+	ReleaseID       graphql.ID
+	ReleaseRevision string
+	Reason          string
+	Cause           graphqlCause
+}
+
+type graphqlObservedReleaseMetadata struct {
+	Changes []graphqlAutomatedChange
+	Result  []graphqlWorkloadResult
+}
+
+type graphqlChainHeadMetadata struct {
+	ID   graphql.ID
+	Hash string
+}
+
+type graphqlRestartMetadata struct {
+	Result []graphqlWorkloadResult
+	Reason string
+	Cause  graphqlCause
+}
+
+type graphqlPolicyUpdateMetadata struct {
+	Revision string
+	Changes  []graphqlPolicyChange
+	Cause    graphqlCause
+}
+
+type graphqlLockMetadata struct {
+	graphqlPolicyUpdateMetadata
+	User          string
+	Reason        string
+	UntilRevision string
+}
+
+type graphqlAccessDeniedMetadata struct {
+	User   string
+	Verb   string
+	Method string
+}
+
+type graphqlAuditMetadata struct {
+	User    string
+	Method  string
+	Verbs   []string
+	Targets []string
+	Result  string
+	Error   string
+}
+
+type graphqlStaleImageMetadata struct {
+	Images []graphqlStaleImage
+}
+
+type graphqlSyncHeldMetadata struct {
+	Revision string
+	Reason   string
+	Changed  int32
+	Deleted  int32
+}
+
+type graphqlSyncUnverifiedMetadata struct {
+	URL         string
+	Revision    string
+	Reason      string
+	Fingerprint string
+}
+
+type graphqlSyncRewriteMetadata struct {
+	URL     string
+	Branch  string
+	From    string
+	To      string
+	Dropped []graphqlCommit
+}
+
+type graphqlSyncConflictMetadata struct {
+	Conflicts []graphqlSyncConflict
+}
+
+type graphqlFreezeMetadata struct {
+	Reason string
+	Start  *graphql.Time
+	End    *graphql.Time
+	Over   bool
+}
+
+type graphqlDaemonStartMetadata struct {
+	Version              string
+	PreviousVersion      string
+	ConfigDigest         string
+	PreviousConfigDigest string
+	ChangedConfig        []string
+}
+
+type graphqlDaemonStopMetadata struct {
+	Version      string
+	ConfigDigest string
+	Reason       string
+}
+
+type graphqlAutomationDeferredMetadata struct {
+	Reason string
+	Since  *graphql.Time
+}
+
+type graphqlAutomationSuspendedMetadata struct {
+	Failures int32
+	Window   string
+	Reason   string
+}
+
+type graphqlImagePolledMetadata struct {
+	Repositories []graphqlPolledRepository
+	Duration     string
+}
+
+type graphqlUnknownMetadata struct {
+	JSON *graphqlJSON
+}
+
+// graphqlMetadata resolves the EventMetadata union to whichever kind
+// of metadata it has.
+type graphqlMetadata struct {
+	value interface{}
+}
+
+// newGraphQLMetadata gives the metadata of an event as it's queried,
+// or nil if the event has none.
+func newGraphQLMetadata(metadata event.EventMetadata) *graphqlMetadata {
+	if metadata == nil {
+		return nil
+	}
+	// Metadata may be kept as a value or a pointer to one
+	var m interface{} = metadata
+	if v := reflect.ValueOf(m); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		m = v.Elem().Interface()
+	}
+
+	var value interface{}
+	switch m := m.(type) {
+	case event.CommitEventMetadata:
+		var spec interface{}
+		if m.Spec != nil {
+			spec = m.Spec
+		}
+		value = &graphqlCommitMetadata{
+			Revision:    m.Revision,
+			Spec:        newGraphQLJSON(spec),
+			Result:      graphqlResult(m.Result),
+			SigningKey:  m.SigningKey,
+			PullRequest: m.PullRequest,
+		}
+	case event.SyncEventMetadata:
+		res := &graphqlSyncMetadata{
+			Commits:     graphqlCommits(m.Commits),
+			InitialSync: m.InitialSync,
+			Recovered:   m.Recovered,
+			Skipped:     m.Skipped,
+		}
+		for kind, included := range m.Includes {
+			if included {
+				res.Includes = append(res.Includes, kind)
+			}
+		}
+		sort.Strings(res.Includes)
+		for _, e := range m.Errors {
+			res.Errors = append(res.Errors, graphqlResourceError{ID: e.ID.String(), Path: e.Path, Error: e.Error})
+		}
+		value = res
+	case event.ReleaseEventMetadata:
+		spec := graphqlReleaseSpec{
+			ImageSpec: string(m.Spec.ImageSpec),
+			Kind:      string(m.Spec.Kind),
+			Excludes:  graphqlResourceIDs(m.Spec.Excludes),
+			Force:     m.Spec.Force,
+		}
+		for _, s := range m.Spec.ServiceSpecs {
+			spec.ServiceSpecs = append(spec.ServiceSpecs, s.String())
+		}
+		value = &graphqlReleaseMetadata{
+			graphqlReleaseCommon: graphqlCommon(m.ReleaseEventCommon),
+			Spec:                 spec,
+			Cause:                graphqlCause(m.Cause),
+		}
+	case event.AutoReleaseEventMetadata:
+		value = &graphqlAutoReleaseMetadata{
+			graphqlReleaseCommon: graphqlCommon(m.ReleaseEventCommon),
+			Changes:              graphqlAutomated(m.Spec),
+		}
+	case event.RollbackEventMetadata:
+		value = &graphqlRollbackMetadata{
+			graphqlReleaseCommon: graphqlCommon(m.ReleaseEventCommon),
+			ReleaseID:            *graphqlID(m.ReleaseID),
+			ReleaseRevision:      m.ReleaseRevision,
+			Reason:               m.Reason,
+			Cause:                graphqlCause(m.Cause),
+		}
+	case event.ObservedReleaseEventMetadata:
+		value = &graphqlObservedReleaseMetadata{Changes: graphqlAutomated(m.Spec), Result: graphqlResult(m.Result)}
+	case event.RestartEventMetadata:
+		value = &graphqlRestartMetadata{Result: graphqlResult(m.Result), Reason: m.Reason, Cause: graphqlCause(m.Cause)}
+	case event.PolicyUpdateEventMetadata:
+		value = graphqlPolicyUpdate(m)
+	case event.LockEventMetadata:
+		value = &graphqlLockMetadata{
+			graphqlPolicyUpdateMetadata: *graphqlPolicyUpdate(m.PolicyUpdateEventMetadata),
+			User:                        m.User,
+			Reason:                      m.Reason,
+			UntilRevision:               m.UntilRevision,
+		}
+	case event.AccessDeniedEventMetadata:
+		value = &graphqlAccessDeniedMetadata{User: m.User, Verb: m.Verb, Method: m.Method}
+	case event.AuditEventMetadata:
+		value = &graphqlAuditMetadata{User: m.User, Method: m.Method, Verbs: m.Verbs, Targets: m.Targets, Result: m.Result, Error: m.Error}
+	case event.StaleImageEventMetadata:
+		res := &graphqlStaleImageMetadata{}
+		for _, i := range m.Images {
+			res.Images = append(res.Images, graphqlStaleImage{
+				ID:        i.ID.String(),
+				Container: i.Container,
+				Current:   i.Current,
+				CreatedAt: graphqlTime(i.CreatedAt),
+				Latest:    i.Latest,
+				LatestAt:  graphqlTime(i.LatestAt),
+				Newer:     int32(i.Newer),
+				Owner:     i.Owner,
+			})
+		}
+		value = res
+	case event.SyncHeldEventMetadata:
+		value = &graphqlSyncHeldMetadata{Revision: m.Revision, Reason: m.Reason, Changed: int32(m.Changed), Deleted: int32(m.Deleted)}
+	case event.SyncUnverifiedEventMetadata:
+		value = &graphqlSyncUnverifiedMetadata{URL: m.URL, Revision: m.Revision, Reason: m.Reason, Fingerprint: m.Fingerprint}
+	case event.SyncRewriteEventMetadata:
+		value = &graphqlSyncRewriteMetadata{URL: m.URL, Branch: m.Branch, From: m.From, To: m.To, Dropped: graphqlCommits(m.Dropped)}
+	case event.SyncConflictEventMetadata:
+		res := &graphqlSyncConflictMetadata{}
+		for _, c := range m.Conflicts {
+			res.Conflicts = append(res.Conflicts, graphqlSyncConflict{ID: c.ID.String(), Field: c.Field, Applied: c.Applied, Live: c.Live, Manager: c.Manager})
+		}
+		value = res
+	case event.FreezeEventMetadata:
+		value = &graphqlFreezeMetadata{Reason: m.Reason, Start: graphqlTime(m.Start), End: graphqlTime(m.End), Over: m.Over}
+	case event.DaemonStartEventMetadata:
+		value = &graphqlDaemonStartMetadata{
+			Version:              m.Version,
+			PreviousVersion:      m.PreviousVersion,
+			ConfigDigest:         m.ConfigDigest,
+			PreviousConfigDigest: m.PreviousConfigDigest,
+			ChangedConfig:        m.ChangedConfig,
+		}
+	case event.DaemonStopEventMetadata:
+		value = &graphqlDaemonStopMetadata{Version: m.Version, ConfigDigest: m.ConfigDigest, Reason: m.Reason}
+	case event.AutomationDeferredEventMetadata:
+		value = &graphqlAutomationDeferredMetadata{Reason: m.Reason, Since: graphqlTime(m.Since)}
+	case event.AutomationSuspendedEventMetadata:
+		value = &graphqlAutomationSuspendedMetadata{Failures: int32(m.Failures), Window: m.Window.String(), Reason: m.Reason}
+	case event.ImagePolledEventMetadata:
+		res := &graphqlImagePolledMetadata{Duration: m.Duration.String()}
+		for _, r := range m.Repositories {
+			res.Repositories = append(res.Repositories, graphqlPolledRepository{Name: r.Name, Tags: int32(r.Tags), NewTags: r.NewTags})
+		}
+		value = res
+	case event.ChainHeadEventMetadata:
+		value = &graphqlChainHeadMetadata{ID: *graphqlID(m.ID), Hash: m.Hash}
+	default:
+		value = &graphqlUnknownMetadata{JSON: newGraphQLJSON(m)}
+	}
+	return &graphqlMetadata{value}
+}
+
+func (m *graphqlMetadata) ToCommitEventMetadata() (*graphqlCommitMetadata, bool) {
+	v, ok := m.value.(*graphqlCommitMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToSyncEventMetadata() (*graphqlSyncMetadata, bool) {
+	v, ok := m.value.(*graphqlSyncMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToReleaseEventMetadata() (*graphqlReleaseMetadata, bool) {
+	v, ok := m.value.(*graphqlReleaseMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToAutoReleaseEventMetadata() (*graphqlAutoReleaseMetadata, bool) {
+	v, ok := m.value.(*graphqlAutoReleaseMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToRollbackEventMetadata() (*graphqlRollbackMetadata, bool) {
+	v, ok := m.value.(*graphqlRollbackMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToObservedReleaseEventMetadata() (*graphqlObservedReleaseMetadata, bool) {
+	v, ok := m.value.(*graphqlObservedReleaseMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToRestartEventMetadata() (*graphqlRestartMetadata, bool) {
+	v, ok := m.value.(*graphqlRestartMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToPolicyUpdateEventMetadata() (*graphqlPolicyUpdateMetadata, bool) {
+	v, ok := m.value.(*graphqlPolicyUpdateMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToLockEventMetadata() (*graphqlLockMetadata, bool) {
+	v, ok := m.value.(*graphqlLockMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToAccessDeniedEventMetadata() (*graphqlAccessDeniedMetadata, bool) {
+	v, ok := m.value.(*graphqlAccessDeniedMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToAuditEventMetadata() (*graphqlAuditMetadata, bool) {
+	v, ok := m.value.(*graphqlAuditMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToStaleImageEventMetadata() (*graphqlStaleImageMetadata, bool) {
+	v, ok := m.value.(*graphqlStaleImageMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToSyncHeldEventMetadata() (*graphqlSyncHeldMetadata, bool) {
+	v, ok := m.value.(*graphqlSyncHeldMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToSyncUnverifiedEventMetadata() (*graphqlSyncUnverifiedMetadata, bool) {
+	v, ok := m.value.(*graphqlSyncUnverifiedMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToSyncRewriteEventMetadata() (*graphqlSyncRewriteMetadata, bool) {
+	v, ok := m.value.(*graphqlSyncRewriteMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToSyncConflictEventMetadata() (*graphqlSyncConflictMetadata, bool) {
+	v, ok := m.value.(*graphqlSyncConflictMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToFreezeEventMetadata() (*graphqlFreezeMetadata, bool) {
+	v, ok := m.value.(*graphqlFreezeMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToDaemonStartEventMetadata() (*graphqlDaemonStartMetadata, bool) {
+	v, ok := m.value.(*graphqlDaemonStartMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToDaemonStopEventMetadata() (*graphqlDaemonStopMetadata, bool) {
+	v, ok := m.value.(*graphqlDaemonStopMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToAutomationDeferredEventMetadata() (*graphqlAutomationDeferredMetadata, bool) {
+	v, ok := m.value.(*graphqlAutomationDeferredMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToAutomationSuspendedEventMetadata() (*graphqlAutomationSuspendedMetadata, bool) {
+	v, ok := m.value.(*graphqlAutomationSuspendedMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToImagePolledEventMetadata() (*graphqlImagePolledMetadata, bool) {
+	v, ok := m.value.(*graphqlImagePolledMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToChainHeadEventMetadata() (*graphqlChainHeadMetadata, bool) {
+	v, ok := m.value.(*graphqlChainHeadMetadata)
+	return v, ok
+}
+
+func (m *graphqlMetadata) ToUnknownEventMetadata() (*graphqlUnknownMetadata, bool) {
+	v, ok := m.value.(*graphqlUnknownMetadata)
+	return v, ok
+}
+
+func graphqlTime(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+func graphqlResourceIDs(ids []flux.ResourceID) []string {
+	var res []string
+	for _, id := range ids {
+		res = append(res, id.String())
+	}
+	return res
+}
+
+func graphqlCommits(commits []event.Commit) []graphqlCommit {
+	var res []graphqlCommit
+	for _, c := range commits {
+		res = append(res, graphqlCommit{Revision: c.Revision, Message: c.Message, Time: graphqlTime(c.Time), CorrelationID: c.CorrelationID})
+	}
+	return res
+}
+
+// graphqlResult gives the result of a release for each workload, in
+// the order of their IDs.
+func graphqlResult(result update.Result) []graphqlWorkloadResult {
+	var res []graphqlWorkloadResult
+	for _, id := range result.ServiceIDs() {
+		r := result[flux.MustParseResourceID(id)]
+		w := graphqlWorkloadResult{ID: id, Status: string(r.Status), Error: r.Error}
+		for _, c := range r.PerContainer {
+			w.PerContainer = append(w.PerContainer, graphqlContainerUpdate{Container: c.Container, Current: c.Current.String(), Target: c.Target.String()})
+		}
+		res = append(res, w)
+	}
+	return res
+}
+
+func graphqlAutomated(spec update.Automated) []graphqlAutomatedChange {
+	var res []graphqlAutomatedChange
+	for _, c := range spec.Changes {
+		res = append(res, graphqlAutomatedChange{ServiceID: c.ServiceID.String(), Container: c.Container.Name, Image: c.ImageID.String()})
+	}
+	return res
+}
+
+func graphqlCommon(c event.ReleaseEventCommon) graphqlReleaseCommon {
+	res := graphqlReleaseCommon{
+		Revision: c.Revision,
+		Result:   graphqlResult(c.Result),
+		Error:    c.Error,
+		Capacity: newGraphQLJSON(c.Capacity),
+	}
+	for _, s := range c.SBOMs {
+		res.SBOMs = append(res.SBOMs, graphqlSBOM(s))
+	}
+	for _, v := range c.Verifications {
+		res.Verifications = append(res.Verifications, graphqlImageVerification{
+			Image:        v.Image,
+			Digest:       v.Digest,
+			Verified:     v.Verified,
+			Signatures:   int32(v.Signatures),
+			Attestations: int32(v.Attestations),
+			Output:       v.Output,
+		})
+	}
+	return res
+}
+
+func graphqlPolicyUpdate(m event.PolicyUpdateEventMetadata) *graphqlPolicyUpdateMetadata {
+	res := &graphqlPolicyUpdateMetadata{Revision: m.Revision, Cause: graphqlCause(m.Cause)}
+	for _, c := range m.Changes {
+		res.Changes = append(res.Changes, graphqlPolicyChange{ID: c.ID.String(), Before: graphqlPolicies(c.Before), After: graphqlPolicies(c.After)})
+	}
+	return res
+}
+
+// graphqlPolicies gives the policies in the set, in the order of
+// their names.
+func graphqlPolicies(set policy.Set) []graphqlPolicy {
+	var res []graphqlPolicy
+	for p, v := range set {
+		res = append(res, graphqlPolicy{Name: string(p), Value: v})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
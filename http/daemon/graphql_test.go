@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+type releaseHistoryServer struct {
+	api.Server
+	asked v22.EventHistoryOptions
+}
+
+func (s *releaseHistoryServer) EventHistory(_ context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	s.asked = opts
+	return v22.EventHistory{
+		Events: []event.Event{
+			{
+				ID:         4,
+				Type:       event.EventRelease,
+				ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("default:deployment/helloworld")},
+				Metadata: &event.ReleaseEventMetadata{
+					ReleaseEventCommon: event.ReleaseEventCommon{Revision: "abc123"},
+					Cause:              update.Cause{User: "jane"},
+				},
+			},
+			{ID: 5, Type: event.EventCommit, Metadata: &event.CommitEventMetadata{Revision: "def456"}},
+		},
+		Older: 3,
+	}, nil
+}
+
+func TestGraphQL(t *testing.T) {
+	server := &releaseHistoryServer{}
+	handler := NewHandler(server, NewRouter())
+	query := `query ($types: [String!]) {
+  events(types: $types, namespaces: ["default"], limit: 2) {
+    older
+    events {
+      id serviceIDs
+      metadata {
+        __typename
+        ... on ReleaseEventMetadata { revision cause { user } }
+        ... on CommitEventMetadata { revision }
+      }
+    }
+  }
+}`
+	body, err := json.Marshal(graphqlRequest{Query: query, Variables: map[string]interface{}{"types": []string{"release", "commit"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/v30/graphql", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	expected := `{"data":{"events":{"older":"3","events":[` +
+		`{"id":"4","serviceIDs":["default:deployment/helloworld"],"metadata":{"__typename":"ReleaseEventMetadata","revision":"abc123","cause":{"user":"jane"}}},` +
+		`{"id":"5","serviceIDs":[],"metadata":{"__typename":"CommitEventMetadata","revision":"def456"}}]}}}`
+	if rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != expected {
+		t.Fatalf("expected\n%s\ngot %d\n%s", expected, rec.Code, rec.Body)
+	}
+	if server.asked.Limit != 2 || len(server.asked.Filter.Types) != 2 || server.asked.Filter.Namespaces[0] != "default" {
+		t.Errorf("expected the arguments to be given as the page and filter, got %+v", server.asked)
+	}
+
+	// A GET works too, and messages can be asked for
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v30/graphql?query="+strings.Replace("{ event(id: 5) { message } }", " ", "+", -1), nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"data":{"event":{"message":"Commit: def456, \u003cno changes\u003e"}}}` {
+		t.Errorf("unexpected response to GET: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v30/graphql?query="+strings.Replace("{ events(level: 1) { older } }", " ", "+", -1), nil))
+	if body := rec.Body.String(); !strings.Contains(body, `Unknown argument \"level\"`) {
+		t.Errorf("expected an unknown argument to be an error, got %s", body)
+	}
+
+	// The schema can be introspected
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v30/graphql?query="+strings.Replace(`{ __type(name: "SyncEventMetadata") { name } }`, " ", "+", -1), nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"data":{"__type":{"name":"SyncEventMetadata"}}}` {
+		t.Errorf("unexpected response to introspection: %s", body)
+	}
+}
@@ -3,7 +3,10 @@ package daemon
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -14,6 +17,24 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/auth"
+	"github.com/weaveworks/flux/event"
 	transport "github.com/weaveworks/flux/http"
 	"github.com/weaveworks/flux/job"
 	fluxmetrics "github.com/weaveworks/flux/metrics"
@@ -52,6 +73,26 @@ func NewHandler(s api.Server, r *mux.Router) http.Handler {
 	r.Get(transport.ListServicesWithOptions).HandlerFunc(handle.ListServicesWithOptions)
 	r.Get(transport.ListImages).HandlerFunc(handle.ListImagesWithOptions)
 	r.Get(transport.ListImagesWithOptions).HandlerFunc(handle.ListImagesWithOptions)
+	r.Get(transport.ListDeployments).HandlerFunc(handle.ListDeployments)
+	r.Get(transport.ListEvents).HandlerFunc(handle.ListEvents)
+	r.Get(transport.CheckWorkload).HandlerFunc(handle.CheckWorkload)
+	r.Get(transport.ImageReport).HandlerFunc(handle.ImageReport)
+	r.Get(transport.DeliveryReport).HandlerFunc(handle.DeliveryReport)
+	r.Get(transport.CompareImages).HandlerFunc(handle.CompareImages)
+	r.Get(transport.ExportCluster).HandlerFunc(handle.ExportCluster)
+	r.Get(transport.DeployedAt).HandlerFunc(handle.DeployedAt)
+	r.Get(transport.AnnotateEvent).HandlerFunc(handle.AnnotateEvent)
+	r.Get(transport.DiffRevisions).HandlerFunc(handle.DiffRevisions)
+	r.Get(transport.EventHistory).HandlerFunc(handle.EventHistory)
+	r.Get(transport.MetricsSnapshot).HandlerFunc(handle.MetricsSnapshot)
+	r.Get(transport.PruneEvents).HandlerFunc(handle.PruneEvents)
+	r.Get(transport.ReplayEvents).HandlerFunc(handle.ReplayEvents)
+	r.Get(transport.ComplianceReport).HandlerFunc(handle.ComplianceReport)
+	r.Get(transport.LogEvents).HandlerFunc(handle.LogEvents)
+	r.Get(transport.WorkloadHistory).HandlerFunc(handle.WorkloadHistory)
+	r.Get(transport.JobQueue).HandlerFunc(handle.JobQueue)
+	r.Get(transport.ExportEvents).HandlerFunc(handle.ExportEvents)
+	r.Get(transport.GraphQL).Handler(newGraphQLHandler(s))
 	r.Get(transport.UpdateManifests).HandlerFunc(handle.UpdateManifests)
 	r.Get(transport.JobStatus).HandlerFunc(handle.JobStatus)
 	r.Get(transport.SyncStatus).HandlerFunc(handle.SyncStatus)
@@ -75,6 +116,16 @@ type HTTPServer struct {
 	server api.Server
 }
 
+// requestUser gives the user to record as responsible for a change:
+// the authenticated identity if there is one, otherwise whoever the
+// client claims to be.
+func requestUser(r *http.Request, claimed string) string {
+	if id, ok := auth.IdentityFromContext(r.Context()); ok {
+		return id.Name()
+	}
+	return claimed
+}
+
 func (s HTTPServer) JobStatus(w http.ResponseWriter, r *http.Request) {
 	id := job.ID(mux.Vars(r)["id"])
 	status, err := s.server.JobStatus(r.Context(), id)
@@ -132,6 +183,8 @@ func (s HTTPServer) UpdateManifests(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	spec.Cause.User = requestUser(r, spec.Cause.User)
+
 	jobID, err := s.server.UpdateManifests(r.Context(), spec)
 	if err != nil {
 		transport.ErrorResponse(w, r, err)
@@ -161,6 +214,102 @@ func (s HTTPServer) ListServicesWithOptions(w http.ResponseWriter, r *http.Reque
 	transport.JSONResponse(w, r, res)
 }
 
+func (s HTTPServer) ListDeployments(w http.ResponseWriter, r *http.Request) {
+	opts := v12.ListDeploymentsOptions{
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	res, err := s.server.ListDeployments(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ListEvents(w http.ResponseWriter, r *http.Request) {
+	var opts v13.ListEventsOptions
+	if after := r.URL.Query().Get("after"); after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'after'"))
+			return
+		}
+		opts.After = event.EventID(id)
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'limit'"))
+			return
+		}
+		opts.Limit = n
+	}
+	res, err := s.server.ListEvents(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) CheckWorkload(w http.ResponseWriter, r *http.Request) {
+	id, err := flux.ParseResourceID(mux.Vars(r)["id"])
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing workload ID"))
+		return
+	}
+	res, err := s.server.CheckWorkload(r.Context(), v14.CheckWorkloadOptions{Workload: id})
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ImageReport(w http.ResponseWriter, r *http.Request) {
+	opts := v15.ImageReportOptions{
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	res, err := s.server.ImageReport(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) DeliveryReport(w http.ResponseWriter, r *http.Request) {
+	opts := v16.DeliveryReportOptions{
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	if period := r.URL.Query().Get("period"); period != "" {
+		d, err := time.ParseDuration(period)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrapf(err, "parsing period %q", period))
+			return
+		}
+		opts.Period = d
+	}
+	res, err := s.server.DeliveryReport(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) CompareImages(w http.ResponseWriter, r *http.Request) {
+	opts := v17.CompareImagesOptions{
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	res, err := s.server.CompareImages(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
 func (s HTTPServer) Export(w http.ResponseWriter, r *http.Request) {
 	status, err := s.server.Export(r.Context())
 	if err != nil {
@@ -171,6 +320,315 @@ func (s HTTPServer) Export(w http.ResponseWriter, r *http.Request) {
 	transport.JSONResponse(w, r, status)
 }
 
+func (s HTTPServer) ExportCluster(w http.ResponseWriter, r *http.Request) {
+	var opts v18.ExportClusterOptions
+	if namespaces := r.URL.Query().Get("namespaces"); namespaces != "" {
+		opts.Namespaces = strings.Split(namespaces, ",")
+	}
+	if kinds := r.URL.Query().Get("kinds"); kinds != "" {
+		opts.Kinds = strings.Split(kinds, ",")
+	}
+	res, err := s.server.ExportCluster(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) DeployedAt(w http.ResponseWriter, r *http.Request) {
+	opts := v19.DeployedAtOptions{
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrapf(err, "parsing time %q", at))
+			return
+		}
+		opts.At = t
+	}
+	res, err := s.server.DeployedAt(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) AnnotateEvent(w http.ResponseWriter, r *http.Request) {
+	var opts v20.AnnotateEventOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	opts.User = requestUser(r, opts.User)
+
+	res, err := s.server.AnnotateEvent(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := v21.DiffRevisionsOptions{
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Namespace: query.Get("namespace"),
+		Manifests: query.Get("manifests") == "true",
+	}
+	res, err := s.server.DiffRevisions(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) EventHistory(w http.ResponseWriter, r *http.Request) {
+	opts, err := eventHistoryOptions(r.URL.Query())
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.server.EventHistory(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+// eventHistoryOptions parses the query parameters for a page of the
+// event history: those for the page itself, and those for the filter.
+func eventHistoryOptions(query url.Values) (v22.EventHistoryOptions, error) {
+	var opts v22.EventHistoryOptions
+	for _, param := range []struct {
+		name string
+		id   *event.EventID
+	}{{"after", &opts.After}, {"before", &opts.Before}} {
+		if v := query.Get(param.name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return opts, errors.Wrapf(err, "parsing value for '%s'", param.name)
+			}
+			*param.id = event.EventID(id)
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return opts, errors.Wrap(err, "parsing value for 'limit'")
+		}
+		opts.Limit = n
+	}
+	if workload := query.Get("workload"); workload != "" {
+		id, err := flux.ParseResourceID(workload)
+		if err != nil {
+			return opts, errors.Wrap(err, "parsing value for 'workload'")
+		}
+		opts.Workload = &id
+	}
+	opts.Filter.Types = query["type"]
+	for _, service := range query["service"] {
+		id, err := flux.ParseResourceID(service)
+		if err != nil {
+			return opts, errors.Wrap(err, "parsing value for 'service'")
+		}
+		opts.Filter.Services = append(opts.Filter.Services, id)
+	}
+	opts.Filter.Namespaces = query["namespace"]
+	for _, param := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &opts.Filter.Since}, {"until", &opts.Filter.Until}} {
+		if v := query.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return opts, errors.Wrapf(err, "parsing value for '%s'", param.name)
+			}
+			*param.t = t
+		}
+	}
+	opts.Filter.MinLogLevel = query.Get("level")
+	opts.Filter.CorrelationID = query.Get("correlation")
+	return opts, nil
+}
+
+// ExportEvents streams the whole of the event history (or as much as
+// the filter selects) in NDJSON or CSV, most recent first. It's read
+// from the daemon a page at a time, so the history needn't fit in
+// memory, and each page is sent as soon as it's read.
+func (s HTTPServer) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts, err := eventHistoryOptions(query)
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = event.ExportNDJSON
+	}
+	out, err := event.NewExportWriter(w, format)
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	// Get the first page before answering, so that if the history
+	// can't be read at all, that can be said properly
+	ctx := r.Context()
+	page, err := s.server.EventHistory(ctx, opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", event.ExportContentType(format))
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for {
+		if err := exportPage(out, page); err != nil {
+			// It's too late to say what went wrong; the client will
+			// see the export cut short
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if page.Older == 0 {
+			return
+		}
+		opts.Before = page.Older
+		if page, err = s.server.EventHistory(ctx, opts); err != nil {
+			return
+		}
+	}
+}
+
+// exportPage writes the events in the page, most recent first.
+func exportPage(out *event.ExportWriter, page v22.EventHistory) error {
+	for i := len(page.Events) - 1; i >= 0; i-- {
+		if err := out.Write(page.Events[i]); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func (s HTTPServer) MetricsSnapshot(w http.ResponseWriter, r *http.Request) {
+	res, err := s.server.MetricsSnapshot(r.Context())
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) PruneEvents(w http.ResponseWriter, r *http.Request) {
+	var opts v24.PruneEventsOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.server.PruneEvents(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := v25.ReplayEventsOptions{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'limit'"))
+			return
+		}
+		opts.Limit = n
+	}
+	res, err := s.server.ReplayEvents(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) ComplianceReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := v26.ComplianceReportOptions{Namespace: query.Get("namespace")}
+	for param, t := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				transport.WriteError(w, r, http.StatusBadRequest, errors.Wrapf(err, "parsing value for '%s'", param))
+				return
+			}
+			*t = parsed
+		}
+	}
+	res, err := s.server.ComplianceReport(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) LogEvents(w http.ResponseWriter, r *http.Request) {
+	var opts v27.LogEventsOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.server.LogEvents(r.Context(), opts); err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s HTTPServer) WorkloadHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var opts v28.WorkloadHistoryOptions
+	id, err := flux.ParseResourceID(query.Get("workload"))
+	if err != nil {
+		transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'workload'"))
+		return
+	}
+	opts.Workload = id
+	if since := query.Get("since"); since != "" {
+		opts.Since, err = time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			transport.WriteError(w, r, http.StatusBadRequest, errors.Wrap(err, "parsing value for 'since'"))
+			return
+		}
+	}
+	res, err := s.server.WorkloadHistory(r.Context(), opts)
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
+func (s HTTPServer) JobQueue(w http.ResponseWriter, r *http.Request) {
+	res, err := s.server.JobQueue(r.Context())
+	if err != nil {
+		transport.ErrorResponse(w, r, err)
+		return
+	}
+	transport.JSONResponse(w, r, res)
+}
+
 func (s HTTPServer) GitRepoConfig(w http.ResponseWriter, r *http.Request) {
 	var regenerate bool
 	if err := json.NewDecoder(r.Body).Decode(&regenerate); err != nil {
@@ -232,7 +690,7 @@ func (s HTTPServer) UpdateImages(w http.ResponseWriter, r *http.Request) {
 		Excludes:     excludes,
 	}
 	cause := update.Cause{
-		User:    r.FormValue("user"),
+		User:    requestUser(r, r.FormValue("user")),
 		Message: r.FormValue("message"),
 	}
 	result, err := s.server.UpdateManifests(r.Context(), update.Spec{Type: update.Images, Cause: cause, Spec: spec})
@@ -251,7 +709,7 @@ func (s HTTPServer) UpdatePolicies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cause := update.Cause{
-		User:    r.FormValue("user"),
+		User:    requestUser(r, r.FormValue("user")),
 		Message: r.FormValue("message"),
 	}
 
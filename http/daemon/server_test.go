@@ -1,8 +1,16 @@
 package daemon
 
 import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/weaveworks/flux/api"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/http"
 )
 
@@ -15,3 +23,56 @@ func TestRouterImplementsServer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// historyServer serves the event history in pages of two events,
+// the most recent being 1-2, then 3-4, and so on.
+type historyServer struct {
+	api.Server
+	events int
+	asked  []v22.EventHistoryOptions
+}
+
+func (s *historyServer) EventHistory(_ context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	s.asked = append(s.asked, opts)
+	newest := event.EventID(s.events)
+	if opts.Before > 0 {
+		newest = opts.Before - 1
+	}
+	page := v22.EventHistory{Events: []event.Event{{ID: newest - 1, Type: event.EventCommit, Metadata: &event.CommitEventMetadata{}}, {ID: newest, Type: event.EventCommit, Metadata: &event.CommitEventMetadata{}}}}
+	if newest > 2 {
+		page.Older = newest - 1
+	}
+	return page, nil
+}
+
+func TestExportEvents(t *testing.T) {
+	server := &historyServer{events: 6}
+	handler := NewHandler(server, NewRouter())
+	req := httptest.NewRequest("GET", "/v30/export-events?format=ndjson&type=commit", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %d %q:\n%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	var ids []event.EventID
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		var e event.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if !reflect.DeepEqual(ids, []event.EventID{6, 5, 4, 3, 2, 1}) {
+		t.Errorf("expected every event, most recent first, got %v", ids)
+	}
+	if len(server.asked) != 3 || server.asked[2].Before != 3 || server.asked[0].Filter.Types[0] != event.EventCommit {
+		t.Errorf("expected the history to be read a page at a time, with the filter, got %+v", server.asked)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v30/export-events?format=xml", nil))
+	if rec.Code != 400 {
+		t.Errorf("expected an unknown format to be refused, got %d", rec.Code)
+	}
+}
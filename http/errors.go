@@ -30,7 +30,8 @@ var ErrorUnauthorized = &fluxerr.Error{
 This most likely means you have a missing or incorrect token. Please
 make sure you supply a service token, either by setting the
 environment variable FLUX_SERVICE_TOKEN, or using the argument --token
-with fluxctl.
+with fluxctl. If the daemon is configured to use an OpenID Connect
+provider, log in again with "fluxctl login".
 
 `,
 	Err: errors.New("request failed authentication"),
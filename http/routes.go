@@ -5,6 +5,26 @@ const (
 	ListServicesWithOptions = "ListServicesWithOptions"
 	ListImages              = "ListImages"
 	ListImagesWithOptions   = "ListImagesWithOptions"
+	ListDeployments         = "ListDeployments"
+	ListEvents              = "ListEvents"
+	CheckWorkload           = "CheckWorkload"
+	ImageReport             = "ImageReport"
+	DeliveryReport          = "DeliveryReport"
+	CompareImages           = "CompareImages"
+	ExportCluster           = "ExportCluster"
+	DeployedAt              = "DeployedAt"
+	AnnotateEvent           = "AnnotateEvent"
+	DiffRevisions           = "DiffRevisions"
+	EventHistory            = "EventHistory"
+	MetricsSnapshot         = "MetricsSnapshot"
+	PruneEvents             = "PruneEvents"
+	ReplayEvents            = "ReplayEvents"
+	ComplianceReport        = "ComplianceReport"
+	LogEvents               = "LogEvents"
+	WorkloadHistory         = "WorkloadHistory"
+	JobQueue                = "JobQueue"
+	ExportEvents            = "ExportEvents"
+	GraphQL                 = "GraphQL"
 	UpdateManifests         = "UpdateManifests"
 	JobStatus               = "JobStatus"
 	SyncStatus              = "SyncStatus"
@@ -24,5 +44,23 @@ var (
 	RegisterDaemonV9  = "RegisterDaemonV9"
 	RegisterDaemonV10 = "RegisterDaemonV10"
 	RegisterDaemonV11 = "RegisterDaemonV11"
+	RegisterDaemonV12 = "RegisterDaemonV12"
+	RegisterDaemonV13 = "RegisterDaemonV13"
+	RegisterDaemonV14 = "RegisterDaemonV14"
+	RegisterDaemonV15 = "RegisterDaemonV15"
+	RegisterDaemonV16 = "RegisterDaemonV16"
+	RegisterDaemonV17 = "RegisterDaemonV17"
+	RegisterDaemonV18 = "RegisterDaemonV18"
+	RegisterDaemonV19 = "RegisterDaemonV19"
+	RegisterDaemonV20 = "RegisterDaemonV20"
+	RegisterDaemonV21 = "RegisterDaemonV21"
+	RegisterDaemonV22 = "RegisterDaemonV22"
+	RegisterDaemonV23 = "RegisterDaemonV23"
+	RegisterDaemonV24 = "RegisterDaemonV24"
+	RegisterDaemonV25 = "RegisterDaemonV25"
+	RegisterDaemonV26 = "RegisterDaemonV26"
+	RegisterDaemonV27 = "RegisterDaemonV27"
+	RegisterDaemonV28 = "RegisterDaemonV28"
+	RegisterDaemonV29 = "RegisterDaemonV29"
 	LogEvent          = "LogEvent"
 )
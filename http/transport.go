@@ -33,12 +33,32 @@ func NewAPIRouter() *mux.Router {
 	r.NewRoute().Name(ListServicesWithOptions).Methods("GET").Path("/v11/services")
 	r.NewRoute().Name(ListImages).Methods("GET").Path("/v6/images")
 	r.NewRoute().Name(ListImagesWithOptions).Methods("GET").Path("/v10/images")
+	r.NewRoute().Name(ListDeployments).Methods("GET").Path("/v12/deployments")
+	r.NewRoute().Name(ListEvents).Methods("GET").Path("/v13/events")
+	r.NewRoute().Name(CheckWorkload).Methods("GET").Path("/v14/check-workload").Queries("id", "{id}")
+	r.NewRoute().Name(ImageReport).Methods("GET").Path("/v15/image-report")
+	r.NewRoute().Name(DeliveryReport).Methods("GET").Path("/v16/delivery-report")
+	r.NewRoute().Name(CompareImages).Methods("GET").Path("/v17/compare-images")
+	r.NewRoute().Name(ExportCluster).Methods("GET").Path("/v18/export-cluster")
+	r.NewRoute().Name(DeployedAt).Methods("GET").Path("/v19/deployed-at")
 
 	r.NewRoute().Name(UpdateManifests).Methods("POST").Path("/v9/update-manifests")
 	r.NewRoute().Name(JobStatus).Methods("GET").Path("/v6/jobs").Queries("id", "{id}")
 	r.NewRoute().Name(SyncStatus).Methods("GET").Path("/v6/sync").Queries("ref", "{ref}")
 	r.NewRoute().Name(Export).Methods("HEAD", "GET").Path("/v6/export")
 	r.NewRoute().Name(GitRepoConfig).Methods("POST").Path("/v9/git-repo-config")
+	r.NewRoute().Name(AnnotateEvent).Methods("POST").Path("/v20/annotate-event")
+	r.NewRoute().Name(DiffRevisions).Methods("GET").Path("/v21/diff-revisions")
+	r.NewRoute().Name(EventHistory).Methods("GET").Path("/v22/event-history")
+	r.NewRoute().Name(MetricsSnapshot).Methods("GET").Path("/v23/metrics-snapshot")
+	r.NewRoute().Name(PruneEvents).Methods("POST").Path("/v24/prune-events")
+	r.NewRoute().Name(ReplayEvents).Methods("GET").Path("/v25/replay-events")
+	r.NewRoute().Name(ComplianceReport).Methods("GET").Path("/v26/compliance-report")
+	r.NewRoute().Name(LogEvents).Methods("POST").Path("/v27/events")
+	r.NewRoute().Name(WorkloadHistory).Methods("GET").Path("/v28/workload-history")
+	r.NewRoute().Name(JobQueue).Methods("GET").Path("/v29/job-queue")
+	r.NewRoute().Name(ExportEvents).Methods("GET").Path("/v30/export-events")
+	r.NewRoute().Name(GraphQL).Methods("GET", "POST").Path("/v30/graphql")
 
 	// These routes persist to support requests from older fluxctls. In general we
 	// should avoid adding references to them so that they can eventually be removed.
@@ -57,6 +77,24 @@ func UpstreamRoutes(r *mux.Router) {
 	r.NewRoute().Name(RegisterDaemonV9).Methods("GET").Path("/v9/daemon")
 	r.NewRoute().Name(RegisterDaemonV10).Methods("GET").Path("/v10/daemon")
 	r.NewRoute().Name(RegisterDaemonV11).Methods("GET").Path("/v11/daemon")
+	r.NewRoute().Name(RegisterDaemonV12).Methods("GET").Path("/v12/daemon")
+	r.NewRoute().Name(RegisterDaemonV13).Methods("GET").Path("/v13/daemon")
+	r.NewRoute().Name(RegisterDaemonV14).Methods("GET").Path("/v14/daemon")
+	r.NewRoute().Name(RegisterDaemonV15).Methods("GET").Path("/v15/daemon")
+	r.NewRoute().Name(RegisterDaemonV16).Methods("GET").Path("/v16/daemon")
+	r.NewRoute().Name(RegisterDaemonV17).Methods("GET").Path("/v17/daemon")
+	r.NewRoute().Name(RegisterDaemonV18).Methods("GET").Path("/v18/daemon")
+	r.NewRoute().Name(RegisterDaemonV19).Methods("GET").Path("/v19/daemon")
+	r.NewRoute().Name(RegisterDaemonV20).Methods("GET").Path("/v20/daemon")
+	r.NewRoute().Name(RegisterDaemonV21).Methods("GET").Path("/v21/daemon")
+	r.NewRoute().Name(RegisterDaemonV22).Methods("GET").Path("/v22/daemon")
+	r.NewRoute().Name(RegisterDaemonV23).Methods("GET").Path("/v23/daemon")
+	r.NewRoute().Name(RegisterDaemonV24).Methods("GET").Path("/v24/daemon")
+	r.NewRoute().Name(RegisterDaemonV25).Methods("GET").Path("/v25/daemon")
+	r.NewRoute().Name(RegisterDaemonV26).Methods("GET").Path("/v26/daemon")
+	r.NewRoute().Name(RegisterDaemonV27).Methods("GET").Path("/v27/daemon")
+	r.NewRoute().Name(RegisterDaemonV28).Methods("GET").Path("/v28/daemon")
+	r.NewRoute().Name(RegisterDaemonV29).Methods("GET").Path("/v29/daemon")
 	r.NewRoute().Name(LogEvent).Methods("POST").Path("/v6/events")
 }
 
@@ -0,0 +1,260 @@
+// Package webhook receives the webhooks git hosts send when commits
+// are pushed, so the daemon can fetch and sync straight away rather
+// than waiting until it next polls the repo.
+//
+// Pushes from GitHub, GitLab, and Bitbucket (Cloud and Server) are
+// understood. Each request has to show it knows the secret: GitHub and
+// Bitbucket sign the body with it, and GitLab sends it in a header.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+// The most of a request body that's read, which is plenty for a push
+// of a great many commits.
+const maxBodySize = 25 << 20
+
+// Handler serves the webhook.
+type Handler struct {
+	Secret []byte
+	// Notify is given the push, as the URLs of the repo and the
+	// branches and tags pushed to, and says whether it was to a repo
+	// (and branch or tag) that's synced
+	Notify func(urls, branches, tags []string) bool
+	Logger log.Logger
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "webhooks must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.authenticate(r.Header, body); err != nil {
+		h.Logger.Log("err", err, "remote", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	push, err := parse(r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if push == nil {
+		// e.g., a ping, or an event other than a push
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ignored; not a push")
+		return
+	}
+	if !h.Notify(push.urls, push.branches, push.tags) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ignored; not a push to a repo and branch or tag that's synced")
+		return
+	}
+	h.Logger.Log("push", strings.Join(push.urls, ","), "branches", strings.Join(push.branches, ","), "tags", strings.Join(push.tags, ","))
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "fetching and syncing")
+}
+
+// authenticate checks the request was made by someone who knows the
+// secret.
+func (h *Handler) authenticate(header http.Header, body []byte) error {
+	if token := header.Get("X-Gitlab-Token"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), h.Secret) != 1 {
+			return errors.New("webhook token does not match the secret")
+		}
+		return nil
+	}
+	sig := header.Get("X-Hub-Signature-256")
+	if sig == "" {
+		sig = header.Get("X-Hub-Signature")
+	}
+	if sig == "" {
+		return errors.New("webhook has neither a signature nor a token")
+	}
+	parts := strings.SplitN(sig, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("webhook signature %q is not <algorithm>=<hex>", sig)
+	}
+	var newHash func() hash.Hash
+	switch parts[0] {
+	case "sha256":
+		newHash = sha256.New
+	case "sha1":
+		newHash = sha1.New
+	default:
+		return fmt.Errorf("unknown webhook signature algorithm %q", parts[0])
+	}
+	given, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return errors.Wrap(err, "decoding webhook signature")
+	}
+	mac := hmac.New(newHash, h.Secret)
+	mac.Write(body)
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return errors.New("webhook signature does not match")
+	}
+	return nil
+}
+
+type push struct {
+	urls     []string
+	branches []string
+	tags     []string
+}
+
+// parse makes out the push from the body of the webhook, according
+// to which git host sent it; or gives nil if it's not a push.
+func parse(header http.Header, body []byte) (*push, error) {
+	switch {
+	case header.Get("X-GitHub-Event") != "":
+		if header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+		var payload struct {
+			Ref        string `json:"ref"`
+			Repository struct {
+				CloneURL string `json:"clone_url"`
+				SSHURL   string `json:"ssh_url"`
+				GitURL   string `json:"git_url"`
+				HTMLURL  string `json:"html_url"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.Wrap(err, "parsing GitHub push")
+		}
+		repo := payload.Repository
+		return newPush([]string{repo.CloneURL, repo.SSHURL, repo.GitURL, repo.HTMLURL}, payload.Ref), nil
+
+	case header.Get("X-Gitlab-Event") != "":
+		// GitLab has a separate event for tags
+		if event := header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+			return nil, nil
+		}
+		var payload struct {
+			Ref     string `json:"ref"`
+			Project struct {
+				GitSSHURL  string `json:"git_ssh_url"`
+				GitHTTPURL string `json:"git_http_url"`
+				WebURL     string `json:"web_url"`
+			} `json:"project"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.Wrap(err, "parsing GitLab push")
+		}
+		project := payload.Project
+		return newPush([]string{project.GitSSHURL, project.GitHTTPURL, project.WebURL}, payload.Ref), nil
+
+	case header.Get("X-Event-Key") == "repo:push":
+		// Bitbucket Cloud
+		var payload struct {
+			Push struct {
+				Changes []struct {
+					New *struct {
+						Type string `json:"type"`
+						Name string `json:"name"`
+					} `json:"new"`
+				} `json:"changes"`
+			} `json:"push"`
+			Repository struct {
+				Links struct {
+					HTML struct {
+						Href string `json:"href"`
+					} `json:"html"`
+				} `json:"links"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.Wrap(err, "parsing Bitbucket push")
+		}
+		var refs []string
+		for _, change := range payload.Push.Changes {
+			// A branch or tag that's been deleted has no new
+			if change.New == nil {
+				continue
+			}
+			switch change.New.Type {
+			case "branch":
+				refs = append(refs, "refs/heads/"+change.New.Name)
+			case "tag":
+				refs = append(refs, "refs/tags/"+change.New.Name)
+			}
+		}
+		return newPush([]string{payload.Repository.Links.HTML.Href}, refs...), nil
+
+	case header.Get("X-Event-Key") == "repo:refs_changed":
+		// Bitbucket Server
+		var payload struct {
+			Changes []struct {
+				RefID string `json:"refId"`
+				Type  string `json:"type"`
+			} `json:"changes"`
+			Repository struct {
+				Links struct {
+					Clone []struct {
+						Href string `json:"href"`
+					} `json:"clone"`
+				} `json:"links"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.Wrap(err, "parsing Bitbucket Server push")
+		}
+		var refs []string
+		for _, change := range payload.Changes {
+			if change.Type != "DELETE" {
+				refs = append(refs, change.RefID)
+			}
+		}
+		var urls []string
+		for _, link := range payload.Repository.Links.Clone {
+			urls = append(urls, link.Href)
+		}
+		return newPush(urls, refs...), nil
+
+	case header.Get("X-Event-Key") != "":
+		// Another Bitbucket event, e.g., diagnostics:ping
+		return nil, nil
+	}
+	return nil, errors.New("not a webhook from GitHub, GitLab or Bitbucket")
+}
+
+// newPush makes a push of the refs given to the repo with the URLs
+// given; only the branches and tags pushed to are kept, and none of
+// the URLs that are empty.
+func newPush(urls []string, refs ...string) *push {
+	p := &push{}
+	for _, u := range urls {
+		if u != "" {
+			p.urls = append(p.urls, u)
+		}
+	}
+	for _, ref := range refs {
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			p.branches = append(p.branches, strings.TrimPrefix(ref, "refs/heads/"))
+		case strings.HasPrefix(ref, "refs/tags/"):
+			p.tags = append(p.tags, strings.TrimPrefix(ref, "refs/tags/"))
+		}
+	}
+	return p
+}
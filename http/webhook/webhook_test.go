@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+const secret = "s3cr3t"
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook(t *testing.T) {
+	var gotURLs, gotBranches, gotTags []string
+	h := &Handler{
+		Secret: []byte(secret),
+		Notify: func(urls, branches, tags []string) bool {
+			gotURLs, gotBranches, gotTags = urls, branches, tags
+			return len(branches) > 0 && branches[0] == "master" || len(tags) > 0
+		},
+		Logger: log.NewNopLogger(),
+	}
+
+	const github = `{"ref":"refs/heads/master","repository":{"clone_url":"https://github.com/example/config.git","ssh_url":"git@github.com:example/config.git"}}`
+	const bitbucket = `{"push":{"changes":[{"new":{"type":"branch","name":"master"}},{"new":null},{"new":{"type":"tag","name":"v1"}}]},"repository":{"links":{"html":{"href":"https://bitbucket.org/example/config"}}}}`
+	for _, c := range []struct {
+		name     string
+		header   map[string]string
+		body     string
+		status   int
+		urls     []string
+		branches []string
+		tags     []string
+	}{
+		{
+			name:     "GitHub push",
+			header:   map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": sign(github)},
+			body:     github,
+			status:   http.StatusAccepted,
+			urls:     []string{"https://github.com/example/config.git", "git@github.com:example/config.git"},
+			branches: []string{"master"},
+		},
+		{
+			name:   "GitHub push with the wrong signature",
+			header: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": sign(github + " ")},
+			body:   github,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "GitHub push without a signature",
+			header: map[string]string{"X-GitHub-Event": "push"},
+			body:   github,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "GitHub ping",
+			header: map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": sign(`{}`)},
+			body:   `{}`,
+			status: http.StatusOK,
+		},
+		{
+			name:     "GitLab push to another branch",
+			header:   map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": secret},
+			body:     `{"ref":"refs/heads/dev","project":{"git_ssh_url":"git@gitlab.com:example/config.git"}}`,
+			status:   http.StatusOK,
+			urls:     []string{"git@gitlab.com:example/config.git"},
+			branches: []string{"dev"},
+		},
+		{
+			name:   "GitLab tag push",
+			header: map[string]string{"X-Gitlab-Event": "Tag Push Hook", "X-Gitlab-Token": secret},
+			body:   `{"ref":"refs/tags/release-1","project":{"git_ssh_url":"git@gitlab.com:example/config.git"}}`,
+			status: http.StatusAccepted,
+			urls:   []string{"git@gitlab.com:example/config.git"},
+			tags:   []string{"release-1"},
+		},
+		{
+			name:   "GitLab push with the wrong token",
+			header: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "guess"},
+			body:   `{"ref":"refs/heads/master"}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "Bitbucket push",
+			header:   map[string]string{"X-Event-Key": "repo:push", "X-Hub-Signature": sign(bitbucket)},
+			body:     bitbucket,
+			status:   http.StatusAccepted,
+			urls:     []string{"https://bitbucket.org/example/config"},
+			branches: []string{"master"},
+			tags:     []string{"v1"},
+		},
+		{
+			name:   "not from a git host",
+			header: map[string]string{"X-Hub-Signature-256": sign(`{}`)},
+			body:   `{}`,
+			status: http.StatusBadRequest,
+		},
+	} {
+		gotURLs, gotBranches, gotTags = nil, nil, nil
+		req := httptest.NewRequest("POST", "/hooks/git", bytes.NewBufferString(c.body))
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", c.name, c.status, rec.Code, rec.Body.String())
+		}
+		if !reflect.DeepEqual(gotURLs, c.urls) || !reflect.DeepEqual(gotBranches, c.branches) || !reflect.DeepEqual(gotTags, c.tags) {
+			t.Errorf("%s: expected a push of %v and %v to %v, got %v and %v to %v", c.name, c.branches, c.tags, c.urls, gotBranches, gotTags, gotURLs)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RewriteRule maps image names onto other image names, e.g., to point
+// at an internal mirror of a public registry. A rule is either for an
+// exact image name, like
+//
+//     docker.io/library/nginx=harbor.internal/proxy/library/nginx
+//
+// or for all the images with some prefix, like
+//
+//     docker.io/*=harbor.internal/proxy/*
+//
+// Names are compared in their canonical form, except that images
+// from DockerHub are given the domain `docker.io` rather than
+// `index.docker.io`; so, e.g., `nginx` matches `docker.io/*` and
+// becomes `harbor.internal/proxy/library/nginx`.
+type RewriteRule struct {
+	From, To string
+}
+
+// ParseRewriteRule parses a rule given as `<from>=<to>`.
+func ParseRewriteRule(s string) (RewriteRule, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return RewriteRule{}, fmt.Errorf("expected image rewrite rule as <from>=<to>, got %q", s)
+	}
+	rule := RewriteRule{
+		From: normaliseRewritePattern(strings.TrimSpace(parts[0])),
+		To:   strings.TrimSpace(parts[1]),
+	}
+	fromWild, toWild := strings.HasSuffix(rule.From, "/*"), strings.HasSuffix(rule.To, "/*")
+	if fromWild != toWild {
+		return RewriteRule{}, fmt.Errorf("image rewrite rule %q must use a wildcard on both sides, or neither", s)
+	}
+	if strings.Contains(strings.TrimSuffix(rule.From, "/*"), "*") || strings.Contains(strings.TrimSuffix(rule.To, "/*"), "*") {
+		return RewriteRule{}, fmt.Errorf("image rewrite rule %q may only use a wildcard as the last path element", s)
+	}
+	if _, err := ParseRef(strings.TrimSuffix(rule.To, "/*") + "/x"); err != nil {
+		return RewriteRule{}, fmt.Errorf("image rewrite rule %q has invalid replacement: %s", s, err)
+	}
+	return rule, nil
+}
+
+func normaliseRewritePattern(p string) string {
+	if strings.HasPrefix(p, dockerHubHost+"/") {
+		return oldDockerHubHost + strings.TrimPrefix(p, dockerHubHost)
+	}
+	return p
+}
+
+// rewriteName gives the name in the form rules are matched against.
+func rewriteName(n Name) string {
+	c := n.CanonicalName()
+	if c.Domain == dockerHubHost {
+		c.Domain = oldDockerHubHost
+	}
+	return c.String()
+}
+
+// Rewrite gives the name the rule maps the name given to, and true;
+// or, if the rule doesn't apply, the name given and false.
+func (r RewriteRule) Rewrite(n Name) (Name, bool) {
+	name := rewriteName(n)
+	var rewritten string
+	if strings.HasSuffix(r.From, "/*") {
+		prefix := strings.TrimSuffix(r.From, "*")
+		if !strings.HasPrefix(name, prefix) {
+			return n, false
+		}
+		rewritten = strings.TrimSuffix(r.To, "*") + strings.TrimPrefix(name, prefix)
+	} else {
+		if name != r.From {
+			return n, false
+		}
+		rewritten = r.To
+	}
+	ref, err := ParseRef(rewritten)
+	if err != nil {
+		return n, false
+	}
+	return ref.Name, true
+}
+
+// RewriteRules is a list of rules, of which the first that applies is
+// used.
+type RewriteRules []RewriteRule
+
+// Rewrite gives the image ref the rules map the ref given to; the tag
+// is kept as it is.
+func (rs RewriteRules) Rewrite(ref Ref) Ref {
+	for _, r := range rs {
+		if n, ok := r.Rewrite(ref.Name); ok {
+			return n.ToRef(ref.Tag)
+		}
+	}
+	return ref
+}
+
+// Inverse gives the rule that maps names the other way, i.e., from
+// the names this rule gives back to the names it applies to.
+func (r RewriteRule) Inverse() RewriteRule {
+	return RewriteRule{
+		From: normaliseRewritePattern(r.To),
+		To:   r.From,
+	}
+}
@@ -0,0 +1,65 @@
+package image
+
+import (
+	"testing"
+)
+
+func TestParseRewriteRuleErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"docker.io/*",
+		"=harbor.internal/*",
+		"docker.io/*=harbor.internal/proxy",
+		"docker.io/library/nginx=harbor.internal/*",
+		"docker.io/*/nginx=harbor.internal/*/nginx",
+	} {
+		if _, err := ParseRewriteRule(s); err == nil {
+			t.Errorf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestRewriteRules(t *testing.T) {
+	var rules RewriteRules
+	for _, s := range []string{
+		"quay.io/weaveworks/flux=mirror.internal/flux",
+		"index.docker.io/*=harbor.internal/proxy/*",
+		"quay.io/*=harbor.internal/quay/*",
+	} {
+		r, err := ParseRewriteRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+
+	for _, c := range []struct {
+		in, out string
+	}{
+		{"nginx:1.15", "harbor.internal/proxy/library/nginx:1.15"},
+		{"docker.io/weaveworks/helloworld:master", "harbor.internal/proxy/weaveworks/helloworld:master"},
+		{"quay.io/weaveworks/flux:1.8.0", "mirror.internal/flux:1.8.0"},
+		{"quay.io/weaveworks/helm-operator:0.4.0", "harbor.internal/quay/weaveworks/helm-operator:0.4.0"},
+		{"gcr.io/google_containers/pause:3.1", "gcr.io/google_containers/pause:3.1"},
+		{"harbor.internal/proxy/library/nginx:1.15", "harbor.internal/proxy/library/nginx:1.15"},
+	} {
+		ref, err := ParseRef(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.Rewrite(ref).String(); got != c.out {
+			t.Errorf("rewriting %q: expected %q, got %q", c.in, c.out, got)
+		}
+	}
+}
+
+func TestRewriteRuleInverse(t *testing.T) {
+	r, err := ParseRewriteRule("staging.example.com/*=prod.example.com/apps/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := ParseRef("prod.example.com/apps/helloworld:v2")
+	if got := (RewriteRules{r.Inverse()}).Rewrite(ref).String(); got != "staging.example.com/helloworld:v2" {
+		t.Errorf("expected inverse to map back to staging image, got %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TagTimestampRule says how to read when an image was built from its
+// tag, for repos whose tags follow a naming convention; e.g.,
+//
+//     example.com/app=master-{20060102.1504}-*
+//
+// reads the timestamp from tags like `master-20240101.1234-abcdef`.
+// The image is given as for a RewriteRule, either exactly or as a
+// prefix ending in `/*`. In the tag, the timestamp is written in
+// braces as a Go time layout (see https://golang.org/pkg/time/) using
+// only numbers (e.g., `2006`, `01`, `02`, `15`, `04`, `05`) and the
+// punctuation allowed in tags; `*` matches anything.
+type TagTimestampRule struct {
+	Image string
+	Tag   string
+
+	layout string
+	re     *regexp.Regexp
+}
+
+// ParseTagTimestampRule parses a rule given as `<image>=<tag>`.
+func ParseTagTimestampRule(s string) (TagTimestampRule, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return TagTimestampRule{}, fmt.Errorf("expected tag timestamp rule as <image>=<tag>, got %q", s)
+	}
+	rule := TagTimestampRule{
+		Image: normaliseRewritePattern(strings.TrimSpace(parts[0])),
+		Tag:   strings.TrimSpace(parts[1]),
+	}
+	if strings.Contains(strings.TrimSuffix(rule.Image, "/*"), "*") {
+		return TagTimestampRule{}, fmt.Errorf("tag timestamp rule %q may only use a wildcard as the last path element of the image", s)
+	}
+
+	start, end := strings.Index(rule.Tag, "{"), strings.Index(rule.Tag, "}")
+	if start < 0 || end < start || strings.Count(rule.Tag, "{") != 1 || strings.Count(rule.Tag, "}") != 1 {
+		return TagTimestampRule{}, fmt.Errorf("tag timestamp rule %q must give the timestamp in the tag once, in braces", s)
+	}
+	rule.layout = rule.Tag[start+1 : end]
+	if !strings.Contains(rule.layout, "2006") {
+		return TagTimestampRule{}, fmt.Errorf("tag timestamp rule %q must give the year (2006) in the timestamp", s)
+	}
+	var layoutExpr string
+	for _, c := range rule.layout {
+		switch {
+		case c >= '0' && c <= '9':
+			layoutExpr += "[0-9]"
+		case c == '.' || c == '-' || c == '_':
+			layoutExpr += regexp.QuoteMeta(string(c))
+		default:
+			return TagTimestampRule{}, fmt.Errorf("tag timestamp rule %q may only use numbers and . - _ in the timestamp", s)
+		}
+	}
+	literal := func(s string) string {
+		return strings.Replace(regexp.QuoteMeta(s), `\*`, ".*", -1)
+	}
+	rule.re = regexp.MustCompile("^" + literal(rule.Tag[:start]) + "(" + layoutExpr + ")" + literal(rule.Tag[end+1:]) + "$")
+	return rule, nil
+}
+
+// Applies says whether the rule is for the image given.
+func (r TagTimestampRule) Applies(n Name) bool {
+	name := rewriteName(n)
+	if strings.HasSuffix(r.Image, "/*") {
+		return strings.HasPrefix(name, strings.TrimSuffix(r.Image, "*"))
+	}
+	return name == r.Image
+}
+
+// Timestamp gives the time read from the tag given, and true; or, if
+// the tag doesn't follow the rule, the zero time and false.
+func (r TagTimestampRule) Timestamp(tag string) (time.Time, bool) {
+	if r.re == nil {
+		return time.Time{}, false
+	}
+	m := r.re.FindStringSubmatch(tag)
+	if m == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(r.layout, m[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// TagTimestampRules is a list of rules, of which the first that
+// applies to an image is used.
+type TagTimestampRules []TagTimestampRule
+
+// Timestamp gives the time read from the ref's tag, by the first rule
+// for its image, and true; or, if there's no rule for the image or
+// the tag doesn't follow it, the zero time and false.
+func (rs TagTimestampRules) Timestamp(ref Ref) (time.Time, bool) {
+	for _, r := range rs {
+		if r.Applies(ref.Name) {
+			return r.Timestamp(ref.Tag)
+		}
+	}
+	return time.Time{}, false
+}
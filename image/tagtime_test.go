@@ -0,0 +1,59 @@
+package image
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTagTimestampRuleErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"example.com/app",
+		"=master-{20060102}",
+		"example.com/app=master-*",
+		"example.com/app=master-{0102}",
+		"example.com/app=master-{Jan 2 2006}",
+		"example.com/app=master-{2006}-{01}",
+		"example.com/*/app=master-{20060102}",
+	} {
+		if _, err := ParseTagTimestampRule(s); err == nil {
+			t.Errorf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestTagTimestampRules(t *testing.T) {
+	var rules TagTimestampRules
+	for _, s := range []string{
+		"example.com/app=master-{20060102.1504}-*",
+		"index.docker.io/myorg/*=*-{2006-01-02_150405}",
+	} {
+		r, err := ParseTagTimestampRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+
+	for _, c := range []struct {
+		ref      string
+		expected time.Time
+		ok       bool
+	}{
+		{"example.com/app:master-20240101.1234-abcdef", time.Date(2024, 1, 1, 12, 34, 0, 0, time.UTC), true},
+		{"example.com/app:branch-20240101.1234-abcdef", time.Time{}, false},
+		{"example.com/app:master-20241301.1234-abcdef", time.Time{}, false},
+		{"example.com/other:master-20240101.1234-abcdef", time.Time{}, false},
+		{"myorg/web:v1-2024-02-03_040506", time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC), true},
+		{"myorg/web:latest", time.Time{}, false},
+	} {
+		ref, err := ParseRef(c.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := rules.Timestamp(ref)
+		if ok != c.ok || !got.Equal(c.expected) {
+			t.Errorf("%s: expected %s (%v), got %s (%v)", c.ref, c.expected, c.ok, got, ok)
+		}
+	}
+}
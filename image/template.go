@@ -0,0 +1,82 @@
+package image
+
+import (
+	"strings"
+)
+
+// Some manifests are templated before they're applied, and use a
+// placeholder for the start of an image reference, e.g.,
+//
+//     image: ${REGISTRY}/weaveworks/helloworld:v1
+//
+// so the same manifest can be used with different registries. The
+// placeholder can't be resolved, but the rest of the reference can
+// still be updated, leaving the placeholder as it is.
+
+var templateMarkers = []string{"{{", "${", "$("}
+
+// TemplatedPrefix gives the part of an image reference that contains
+// a placeholder, up to the `/` following it; e.g., `${REGISTRY}/` in
+// `${REGISTRY}/weaveworks/helloworld:v1`. If there's no placeholder
+// before the image name, it returns false.
+func TemplatedPrefix(s string) (string, bool) {
+	last := -1
+	for _, m := range templateMarkers {
+		if i := strings.LastIndex(s, m); i > last {
+			last = i
+		}
+	}
+	if last < 0 {
+		return "", false
+	}
+	slash := strings.Index(s[last:], "/")
+	if slash < 0 {
+		return "", false
+	}
+	return s[:last+slash+1], true
+}
+
+func hasPlaceholder(s string) bool {
+	for _, m := range templateMarkers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceKeepingTemplate gives the image reference target as it
+// should be written in place of current: if current has a templated
+// prefix, that's kept and only the rest is taken from target, which
+// must then be an image of the same name (e.g., `weaveworks/helloworld`
+// above). Otherwise, it's just target. It returns false if target
+// can't be written in the form of current.
+func ReplaceKeepingTemplate(current, target string) (string, bool) {
+	prefix, _ := TemplatedPrefix(current)
+	rest := current[len(prefix):]
+	if hasPlaceholder(rest) {
+		// e.g., the tag is templated; there's nothing sensible to
+		// change
+		return "", false
+	}
+	if prefix == "" {
+		return target, true
+	}
+	segments := strings.Count(rest, "/") + 1
+	targetSegments := strings.Split(target, "/")
+	if len(targetSegments) < segments {
+		return "", false
+	}
+	targetRest := strings.Join(targetSegments[len(targetSegments)-segments:], "/")
+	if withoutTag(targetRest) != withoutTag(rest) {
+		return "", false
+	}
+	return prefix + targetRest, true
+}
+
+func withoutTag(s string) string {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
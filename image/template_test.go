@@ -0,0 +1,26 @@
+package image
+
+import (
+	"testing"
+)
+
+func TestReplaceKeepingTemplate(t *testing.T) {
+	for _, c := range []struct {
+		current, target, expected string
+		ok                        bool
+	}{
+		{"quay.io/weaveworks/helloworld:v1", "quay.io/weaveworks/helloworld:v2", "quay.io/weaveworks/helloworld:v2", true},
+		{"helloworld:v1", "docker.io/library/helloworld:v2", "docker.io/library/helloworld:v2", true},
+		{"${REGISTRY}/weaveworks/helloworld:v1", "quay.io/weaveworks/helloworld:v2", "${REGISTRY}/weaveworks/helloworld:v2", true},
+		{"{{ .Values.registry }}/helloworld:v1", "quay.io/weaveworks/helloworld:v2", "{{ .Values.registry }}/helloworld:v2", true},
+		{"$(REGISTRY)/helloworld", "quay.io/helloworld:v2", "$(REGISTRY)/helloworld:v2", true},
+		{"${REGISTRY}/weaveworks/helloworld:v1", "quay.io/other/helloworld:v2", "", false},
+		{"${REGISTRY}/weaveworks/helloworld:v1", "helloworld:v2", "", false},
+		{"${REGISTRY}/helloworld:${TAG}", "quay.io/helloworld:v2", "", false},
+	} {
+		got, ok := ReplaceKeepingTemplate(c.current, c.target)
+		if ok != c.ok || got != c.expected {
+			t.Errorf("replacing %q with %q: expected %q, %v; got %q, %v", c.current, c.target, c.expected, c.ok, got, ok)
+		}
+	}
+}
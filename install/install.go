@@ -0,0 +1,77 @@
+// Package install generates the manifests for running the flux daemon
+// in a cluster: its deployment, the secret for its deploy key, the
+// service account and role binding it needs where RBAC is in use,
+// and memcached for it to cache image metadata in.
+package install
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultImage is the daemon image used, if none is given.
+const DefaultImage = "quay.io/weaveworks/flux:1.7.0"
+
+// TemplateParameters are the things that vary from one installation
+// to another.
+type TemplateParameters struct {
+	GitURL    string
+	GitBranch string
+	GitPaths  []string
+	GitLabel  string
+	GitUser   string
+	GitEmail  string
+	Namespace string
+	Image     string
+}
+
+// FillInTemplates gives the manifests for an installation, by file
+// name.
+func FillInTemplates(params TemplateParameters) (map[string][]byte, error) {
+	if params.GitURL == "" {
+		return nil, errors.New("a git URL is needed")
+	}
+	if params.GitBranch == "" {
+		params.GitBranch = "master"
+	}
+	if params.Namespace == "" {
+		params.Namespace = "default"
+	}
+	if params.Image == "" {
+		params.Image = DefaultImage
+	}
+
+	funcs := template.FuncMap{"join": strings.Join}
+	manifests := map[string][]byte{}
+	for name, text := range templates {
+		tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing template for %s", name)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, params); err != nil {
+			return nil, errors.Wrapf(err, "filling in template for %s", name)
+		}
+		manifests[name] = out.Bytes()
+	}
+	return manifests, nil
+}
+
+// Concat gives the manifests as a single multi-document YAML stream,
+// in order of file name.
+func Concat(manifests map[string][]byte) []byte {
+	var names []string
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out bytes.Buffer
+	for _, name := range names {
+		out.Write(manifests[name])
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,66 @@
+package install
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/cluster/kubernetes/resource"
+)
+
+func TestFillInTemplates(t *testing.T) {
+	manifests, err := FillInTemplates(TemplateParameters{
+		GitURL:    "git@github.com:weaveworks/flux-example",
+		GitPaths:  []string{"namespaces", "workloads"},
+		GitLabel:  "flux-prod",
+		Namespace: "flux",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != len(templates) {
+		t.Errorf("expected %d manifests, got %d", len(templates), len(manifests))
+	}
+
+	objects, err := resource.ParseMultidoc(Concat(manifests), "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cluster-scoped resources are parsed as being in "default"
+	for _, id := range []string{
+		"flux:serviceaccount/flux",
+		"default:clusterrole/flux",
+		"default:clusterrolebinding/flux",
+		"flux:deployment/flux",
+		"flux:secret/flux-git-deploy",
+		"flux:deployment/memcached",
+		"flux:service/memcached",
+	} {
+		if _, ok := objects[id]; !ok {
+			t.Errorf("expected %s among the manifests", id)
+		}
+	}
+
+	deployment := manifests["flux-deployment.yaml"]
+	for _, arg := range []string{
+		"--git-url=git@github.com:weaveworks/flux-example\n",
+		"--git-branch=master\n",
+		"--git-path=namespaces,workloads\n",
+		"--git-label=flux-prod\n",
+		"--memcached-hostname=memcached.flux.svc.cluster.local\n",
+		"image: " + DefaultImage + "\n",
+	} {
+		if !bytes.Contains(deployment, []byte(arg)) {
+			t.Errorf("expected the deployment to include %q", strings.TrimSpace(arg))
+		}
+	}
+	if bytes.Contains(deployment, []byte("--git-user")) {
+		t.Error("expected no --git-user argument, since none was given")
+	}
+}
+
+func TestFillInTemplates_NeedsGitURL(t *testing.T) {
+	if _, err := FillInTemplates(TemplateParameters{}); err == nil {
+		t.Error("expected an error without a git URL")
+	}
+}
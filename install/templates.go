@@ -0,0 +1,165 @@
+package install
+
+// The templates for the manifests written by FillInTemplates, by the
+// name of the file each is written to. They follow the example
+// manifests in deploy/.
+var templates = map[string]string{
+	"flux-account.yaml": `---
+# The service account, cluster roles, and cluster role binding are
+# only needed for Kubernetes with role-based access control (RBAC).
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    name: flux
+  name: flux
+  namespace: {{ .Namespace }}
+---
+apiVersion: rbac.authorization.k8s.io/v1beta1
+kind: ClusterRole
+metadata:
+  labels:
+    name: flux
+  name: flux
+rules:
+  - apiGroups: ['*']
+    resources: ['*']
+    verbs: ['*']
+  - nonResourceURLs: ['*']
+    verbs: ['*']
+---
+apiVersion: rbac.authorization.k8s.io/v1beta1
+kind: ClusterRoleBinding
+metadata:
+  labels:
+    name: flux
+  name: flux
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: flux
+subjects:
+  - kind: ServiceAccount
+    name: flux
+    namespace: {{ .Namespace }}
+`,
+
+	"flux-deployment.yaml": `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: flux
+  namespace: {{ .Namespace }}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      name: flux
+  strategy:
+    type: Recreate
+  template:
+    metadata:
+      annotations:
+        prometheus.io.port: "3031" # tell prometheus to scrape /metrics endpoint's port.
+      labels:
+        name: flux
+    spec:
+      serviceAccount: flux
+      volumes:
+      - name: git-key
+        secret:
+          secretName: flux-git-deploy
+          defaultMode: 0400 # when mounted read-only, we won't be able to chmod
+      # This is a tmpfs used for generating SSH keys. In K8s >= 1.10,
+      # mounted secrets are read-only, so we need a separate volume we
+      # can write to.
+      - name: git-keygen
+        emptyDir:
+          medium: Memory
+      containers:
+      - name: flux
+        image: {{ .Image }}
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 3030 # informational
+        volumeMounts:
+        - name: git-key
+          mountPath: /etc/fluxd/ssh # to match location given in image's /etc/ssh/config
+          readOnly: true # this will be the case perforce in K8s >=1.10
+        - name: git-keygen
+          mountPath: /var/fluxd/keygen # to match location given in image's /etc/ssh/config
+        args:
+        - --ssh-keygen-dir=/var/fluxd/keygen
+        - --memcached-hostname=memcached.{{ .Namespace }}.svc.cluster.local
+        - --git-url={{ .GitURL }}
+        - --git-branch={{ .GitBranch }}
+{{- if .GitPaths }}
+        - --git-path={{ join .GitPaths "," }}
+{{- end }}
+{{- if .GitLabel }}
+        - --git-label={{ .GitLabel }}
+{{- end }}
+{{- if .GitUser }}
+        - --git-user={{ .GitUser }}
+{{- end }}
+{{- if .GitEmail }}
+        - --git-email={{ .GitEmail }}
+{{- end }}
+        - --listen-metrics=:3031
+`,
+
+	"flux-secret.yaml": `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: flux-git-deploy
+  namespace: {{ .Namespace }}
+type: Opaque
+`,
+
+	"memcache-dep.yaml": `---
+# memcached is for the Flux daemon to cache container image metadata.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached
+  namespace: {{ .Namespace }}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      name: memcached
+  template:
+    metadata:
+      labels:
+        name: memcached
+    spec:
+      containers:
+      - name: memcached
+        image: memcached:1.4.25
+        imagePullPolicy: IfNotPresent
+        args:
+        - -m 64    # Maximum memory to use, in megabytes. 64MB is default.
+        - -p 11211    # Default port, but being explicit is nice.
+        ports:
+        - name: clients
+          containerPort: 11211
+`,
+
+	"memcache-svc.yaml": `---
+apiVersion: v1
+kind: Service
+metadata:
+  name: memcached
+  namespace: {{ .Namespace }}
+spec:
+  # The memcache client uses DNS to get a list of memcached servers and then
+  # uses a consistent hash of the key to determine which server to pick.
+  clusterIP: None
+  ports:
+    - name: memcached
+      port: 11211
+  selector:
+    name: memcached
+`,
+}
@@ -0,0 +1,164 @@
+/*
+Package chartrepo has the means to look up and download charts from
+Helm chart repositories, i.e., HTTP servers with an `index.yaml`
+listing the versions of each chart (see
+https://github.com/helm/helm/blob/master/docs/chart_repository.md).
+
+This is used by fluxd, to find new versions of charts for automated
+FluxHelmReleases, and by the Helm operator, to fetch the charts
+FluxHelmReleases refer to.
+*/
+package chartrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// ChartVersion is an entry in a chart repository index.
+type ChartVersion struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	AppVersion string    `json:"appVersion,omitempty"`
+	Created    time.Time `json:"created,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	URLs       []string  `json:"urls"`
+}
+
+// Index is the index of a chart repository.
+type Index struct {
+	Entries map[string][]ChartVersion `json:"entries"`
+}
+
+// ParseIndex parses the YAML of a chart repository index.
+func ParseIndex(b []byte) (*Index, error) {
+	var index Index
+	if err := yaml.Unmarshal(b, &index); err != nil {
+		return nil, errors.Wrap(err, "parsing chart repository index")
+	}
+	return &index, nil
+}
+
+// Versions returns all the versions of the chart named.
+func (i *Index) Versions(name string) []ChartVersion {
+	return i.Entries[name]
+}
+
+// Get returns the given version of the chart named.
+func (i *Index) Get(name, version string) (ChartVersion, bool) {
+	for _, v := range i.Entries[name] {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return ChartVersion{}, false
+}
+
+// Client fetches things from chart repositories.
+type Client struct {
+	HTTP *http.Client
+}
+
+func (c Client) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", u, resp.Status)
+	}
+	return resp, nil
+}
+
+// Index fetches the index of the chart repository at the URL given.
+func (c Client) Index(ctx context.Context, repoURL string) (*Index, error) {
+	resp, err := c.get(ctx, strings.TrimSuffix(repoURL, "/")+"/index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading chart repository index")
+	}
+	return ParseIndex(b)
+}
+
+// ChartURL gives the absolute URL for downloading the chart version,
+// which may be given relative to the repository in the index.
+func ChartURL(repoURL string, v ChartVersion) (string, error) {
+	if len(v.URLs) == 0 {
+		return "", fmt.Errorf("no URL given for chart %s version %s", v.Name, v.Version)
+	}
+	base, err := url.Parse(strings.TrimSuffix(repoURL, "/") + "/")
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(v.URLs[0])
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Download fetches the chart version given into the directory given,
+// unless it's already there, and returns the path of the chart
+// archive. If the index gives a digest for the chart, the archive
+// must match it.
+func (c Client) Download(ctx context.Context, repoURL string, v ChartVersion, dir string) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", v.Name, v.Version))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	u, err := ChartURL(repoURL, v)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(dir, ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	tmp.Close()
+	if err != nil {
+		return "", errors.Wrapf(err, "downloading %s", u)
+	}
+	if v.Digest != "" && hex.EncodeToString(hash.Sum(nil)) != v.Digest {
+		return "", fmt.Errorf("chart downloaded from %s does not match the digest in the repository index", u)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
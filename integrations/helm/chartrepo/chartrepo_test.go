@@ -0,0 +1,97 @@
+package chartrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  podinfo:
+  - apiVersion: v1
+    appVersion: 1.2.0
+    created: 2018-08-20T11:43:08.617Z
+    digest: %s
+    name: podinfo
+    urls:
+    - podinfo-1.2.0.tgz
+    version: 1.2.0
+  - apiVersion: v1
+    appVersion: 1.1.0
+    created: 2018-07-20T11:43:08.617Z
+    name: podinfo
+    urls:
+    - https://charts.example.com/archive/podinfo-1.1.0.tgz
+    version: 1.1.0
+generated: 2018-08-20T11:43:08.618Z
+`
+
+func TestIndexAndDownload(t *testing.T) {
+	chart := []byte("not really a tarball")
+	sum := sha256.Sum256(chart)
+	index := []byte(fmt.Sprintf(testIndex, hex.EncodeToString(sum[:])))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/charts/index.yaml":
+			w.Write(index)
+		case "/charts/podinfo-1.2.0.tgz":
+			w.Write(chart)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client := Client{HTTP: ts.Client()}
+	repoURL := ts.URL + "/charts"
+	ctx := context.Background()
+
+	idx, err := client.Index(ctx, repoURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(idx.Versions("podinfo")); n != 2 {
+		t.Fatalf("expected 2 versions of podinfo, got %d", n)
+	}
+	v, ok := idx.Get("podinfo", "1.2.0")
+	if !ok {
+		t.Fatal("expected to find podinfo 1.2.0")
+	}
+	if v.AppVersion != "1.2.0" || v.Created.IsZero() {
+		t.Errorf("unexpected chart version %#v", v)
+	}
+
+	old, _ := idx.Get("podinfo", "1.1.0")
+	if u, _ := ChartURL(repoURL, old); u != "https://charts.example.com/archive/podinfo-1.1.0.tgz" {
+		t.Errorf("expected absolute URL to be kept, got %q", u)
+	}
+
+	dir, err := ioutil.TempDir("", "chartrepo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := client.Download(ctx, repoURL, v, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(path)
+	if string(got) != string(chart) {
+		t.Errorf("downloaded chart has unexpected contents %q", got)
+	}
+
+	v.Version, v.Digest = "1.2.1", "0000"
+	v.URLs = []string{"podinfo-1.2.0.tgz"}
+	if _, err := client.Download(ctx, repoURL, v, dir); err == nil {
+		t.Error("expected error for chart not matching its digest")
+	}
+}
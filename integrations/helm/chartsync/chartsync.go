@@ -206,6 +206,10 @@ func (chs *ChartChangeSync) applyChartChanges(prevRef, head string) error {
 	chartHasChanged := map[string]bool{}
 
 	for _, fhr := range resources {
+		// Charts from chart repositories are not affected by changes in git
+		if fhr.Spec.ChartSource != nil {
+			continue
+		}
 		chartPath := filepath.Join(chs.config.ChartsPath, fhr.Spec.ChartGitPath)
 		changed, ok := chartHasChanged[chartPath]
 		if !ok {
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os/exec"
 	"path/filepath"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/weaveworks/flux"
 	ifv1 "github.com/weaveworks/flux/apis/helm.integrations.flux.weave.works/v1alpha2"
 	fluxk8s "github.com/weaveworks/flux/cluster/kubernetes"
+	"github.com/weaveworks/flux/integrations/helm/chartrepo"
 )
 
 var (
@@ -28,8 +31,14 @@ const (
 	UpgradeAction Action = "UPDATE"
 )
 
+// How long to wait for a chart to be looked up, and downloaded, from
+// a chart repository
+const chartDownloadTimeout = 1 * time.Minute
+
 type Config struct {
 	ChartsPath string
+	// Where to keep charts downloaded from chart repositories
+	ChartCacheDir string
 }
 
 // Release contains clients needed to provide functionality related to helm releases
@@ -39,6 +48,7 @@ type Release struct {
 	HelmClient *k8shelm.Client
 
 	config Config
+	charts chartrepo.Client
 }
 
 type Releaser interface {
@@ -63,6 +73,7 @@ func New(logger log.Logger, helmClient *k8shelm.Client, config Config) *Release
 		logger:     logger,
 		HelmClient: helmClient,
 		config:     config,
+		charts:     chartrepo.Client{HTTP: &http.Client{Timeout: chartDownloadTimeout}},
 	}
 	return r
 }
@@ -127,6 +138,31 @@ func (r *Release) canDelete(name string) (bool, error) {
 	}
 }
 
+// chartPath gives the path of the chart to release: either a
+// directory in the git repo, or an archive downloaded from a chart
+// repository.
+func (r *Release) chartPath(repoDir string, fhr ifv1.FluxHelmRelease) (string, error) {
+	source := fhr.Spec.ChartSource
+	if source == nil {
+		if fhr.Spec.ChartGitPath == "" {
+			return "", fmt.Errorf(ErrChartGitPathMissing, fhr.GetName())
+		}
+		return filepath.Join(repoDir, r.config.ChartsPath, fhr.Spec.ChartGitPath), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chartDownloadTimeout)
+	defer cancel()
+	index, err := r.charts.Index(ctx, source.Repository)
+	if err != nil {
+		return "", fmt.Errorf("fetching index of chart repository %s: %s", source.Repository, err)
+	}
+	version, ok := index.Get(source.Name, source.Version)
+	if !ok {
+		return "", fmt.Errorf("chart %s version %s not found in repository %s", source.Name, source.Version, source.Repository)
+	}
+	return r.charts.Download(ctx, source.Repository, version, filepath.Join(r.config.ChartCacheDir, url.PathEscape(source.Repository)))
+}
+
 // Install performs a Chart release given the directory containing the
 // charts, and the FluxHelmRelease specifying the release. Depending
 // on the release type, this is either a new release, or an upgrade of
@@ -134,10 +170,10 @@ func (r *Release) canDelete(name string) (bool, error) {
 func (r *Release) Install(repoDir, releaseName string, fhr ifv1.FluxHelmRelease, action Action, opts InstallOptions) (*hapi_release.Release, error) {
 	r.logger.Log("info", fmt.Sprintf("releaseName= %s, action=%s, install options: %+v", releaseName, action, opts))
 
-	chartPath := fhr.Spec.ChartGitPath
-	if chartPath == "" {
-		r.logger.Log("error", fmt.Sprintf(ErrChartGitPathMissing, fhr.GetName()))
-		return nil, fmt.Errorf(ErrChartGitPathMissing, fhr.GetName())
+	chartDir, err := r.chartPath(repoDir, fhr)
+	if err != nil {
+		r.logger.Log("error", err.Error())
+		return nil, err
 	}
 
 	namespace := fhr.GetNamespace()
@@ -145,8 +181,6 @@ func (r *Release) Install(repoDir, releaseName string, fhr ifv1.FluxHelmRelease,
 		namespace = "default"
 	}
 
-	chartDir := filepath.Join(repoDir, r.config.ChartsPath, chartPath)
-
 	strVals, err := fhr.Spec.Values.YAML()
 	if err != nil {
 		r.logger.Log("error", fmt.Sprintf("Problem with supplied customizations for Chart release [%s]: %#v", releaseName, err))
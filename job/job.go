@@ -1,10 +1,15 @@
 package job
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 
+	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/update"
 )
 
@@ -15,6 +20,17 @@ type JobFunc func(log.Logger) error
 type Job struct {
 	ID ID
 	Do JobFunc
+	// What kind of job it is, e.g., the type of update it makes; the
+	// queue gives jobs a priority by their kind
+	Kind string
+	// The workloads the job concerns, if known. Jobs concerning the
+	// same workload are run one at a time, in the order they were
+	// queued, whatever their priority.
+	Workloads []flux.ResourceID
+
+	// These are filled in by the queue
+	Priority int
+	QueuedAt time.Time
 }
 
 type StatusString string
@@ -33,6 +49,11 @@ type Result struct {
 	Revision string        `json:"revision,omitempty"`
 	Spec     *update.Spec  `json:"spec,omitempty"`
 	Result   update.Result `json:"result,omitempty"`
+	// The fingerprint of the key the commit was signed with, if any
+	SigningKey string `json:"signingKey,omitempty"`
+	// The pull request opened for the commit, if it was pushed to a
+	// branch of its own
+	PullRequest string `json:"pullRequest,omitempty"`
 }
 
 // Status holds the possible states of a job; either,
@@ -49,21 +70,86 @@ func (s Status) Error() string {
 	return s.Err
 }
 
+// QueueOptions say how a queue orders the jobs in it, and how many
+// it hands out at once.
+type QueueOptions struct {
+	// The most jobs that may be running at once, i.e., handed out
+	// but not yet Done; zero means there's no limit, and it's up to
+	// whoever is receiving jobs
+	MaxRunning int
+	// The priority of each kind of job; jobs with a higher priority
+	// are handed out first. Kinds not given have priority zero.
+	Priorities map[string]int
+}
+
+// ParsePriorities parses the priorities of kinds of job, given as
+// <kind>=<priority>, e.g., auto=-1.
+func ParsePriorities(specs []string) (map[string]int, error) {
+	priorities := map[string]int{}
+	for _, s := range specs {
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("expected <kind>=<priority>, got %q", s)
+		}
+		priority, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("priority in %q is not a whole number", s)
+		}
+		priorities[parts[0]] = priority
+	}
+	return priorities, nil
+}
+
 // Queue is an unbounded queue of jobs; enqueuing a job will always
 // proceed, while dequeuing is done by receiving from a channel. It is
 // also possible to iterate over the current list of jobs.
+//
+// Jobs are handed out highest priority first, then in the order they
+// were queued; except that a job concerning a workload that an
+// earlier job also concerns waits until that job is Done.
 type Queue struct {
+	opts        QueueOptions
+	stop        <-chan struct{}
 	ready       chan *Job
 	incoming    chan *Job
+	done        chan ID
 	waiting     []*Job
+	running     []runningJob
 	waitingLock sync.Mutex
 	sync        chan struct{}
 }
 
+type runningJob struct {
+	job       *Job
+	startedAt time.Time
+}
+
+// QueuedJob describes a job in the queue, either running or waiting.
+type QueuedJob struct {
+	ID        ID                `json:"id"`
+	Kind      string            `json:"kind,omitempty"`
+	Workloads []flux.ResourceID `json:"workloads,omitempty"`
+	Priority  int               `json:"priority"`
+	QueuedAt  time.Time         `json:"queuedAt"`
+	// When the job was handed out to run; nil if it's still waiting
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+}
+
+// NewQueue makes a queue that hands out jobs in the order they were
+// queued, with no limit on how many.
 func NewQueue(stop <-chan struct{}, wg *sync.WaitGroup) *Queue {
+	return NewQueueWithOptions(stop, wg, QueueOptions{})
+}
+
+// NewQueueWithOptions makes a queue that orders jobs by the
+// priorities given, and hands out at most MaxRunning at once.
+func NewQueueWithOptions(stop <-chan struct{}, wg *sync.WaitGroup, opts QueueOptions) *Queue {
 	q := &Queue{
+		opts:     opts,
+		stop:     stop,
 		ready:    make(chan *Job),
 		incoming: make(chan *Job),
+		done:     make(chan ID),
 		waiting:  make([]*Job, 0),
 		sync:     make(chan struct{}),
 	}
@@ -72,6 +158,17 @@ func NewQueue(stop <-chan struct{}, wg *sync.WaitGroup) *Queue {
 	return q
 }
 
+// MaxRunning gives the most jobs the queue will hand out at once;
+// zero means no limit.
+func (q *Queue) MaxRunning() int {
+	return q.opts.MaxRunning
+}
+
+// Priorities gives the priority of each kind of job.
+func (q *Queue) Priorities() map[string]int {
+	return q.opts.Priorities
+}
+
 // This is not guaranteed to be up-to-date; i.e., it is possible to
 // receive from `q.Ready()` or enqueue an item, then see the same
 // length as before, temporarily.
@@ -85,6 +182,8 @@ func (q *Queue) Len() int {
 // loop can accept the job; but this does _not_ depend on a job being
 // dequeued and will always proceed eventually.
 func (q *Queue) Enqueue(j *Job) {
+	j.Priority = q.opts.Priorities[j.Kind]
+	j.QueuedAt = time.Now().UTC()
 	q.incoming <- j
 }
 
@@ -95,6 +194,16 @@ func (q *Queue) Ready() <-chan *Job {
 	return q.ready
 }
 
+// Done tells the queue that a job it handed out has finished, so
+// that jobs waiting on it (because they concern the same workloads,
+// or because as many jobs as can be are running) can proceed.
+func (q *Queue) Done(id ID) {
+	select {
+	case q.done <- id:
+	case <-q.stop:
+	}
+}
+
 func (q *Queue) ForEach(fn func(int, *Job) bool) {
 	q.waitingLock.Lock()
 	jobs := q.waiting
@@ -106,6 +215,37 @@ func (q *Queue) ForEach(fn func(int, *Job) bool) {
 	}
 }
 
+// Jobs describes the jobs in the queue: those running, in the order
+// they were started, then those waiting, in the order they'll be run
+// as things stand.
+func (q *Queue) Jobs() []QueuedJob {
+	q.waitingLock.Lock()
+	defer q.waitingLock.Unlock()
+	var jobs []QueuedJob
+	for _, r := range q.running {
+		started := r.startedAt
+		jobs = append(jobs, describe(r.job, &started))
+	}
+	remaining := append([]*Job(nil), q.waiting...)
+	for len(remaining) > 0 {
+		i := pick(remaining, func(*Job) bool { return false })
+		jobs = append(jobs, describe(remaining[i], nil))
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return jobs
+}
+
+func describe(j *Job, started *time.Time) QueuedJob {
+	return QueuedJob{
+		ID:        j.ID,
+		Kind:      j.Kind,
+		Workloads: j.Workloads,
+		Priority:  j.Priority,
+		QueuedAt:  j.QueuedAt,
+		StartedAt: started,
+	}
+}
+
 // Block until any previous operations have completed. Note that this
 // is only meaningful if you are using the queue from a single other
 // goroutine; i.e., it makes sense to do, say,
@@ -123,8 +263,9 @@ func (q *Queue) Sync() {
 func (q *Queue) loop(stop <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
+		next := q.nextOrNil()
 		var out chan *Job = nil
-		if len(q.waiting) > 0 {
+		if next != nil {
 			out = q.ready
 		}
 
@@ -137,21 +278,90 @@ func (q *Queue) loop(stop <-chan struct{}, wg *sync.WaitGroup) {
 			q.waitingLock.Lock()
 			q.waiting = append(q.waiting, in)
 			q.waitingLock.Unlock()
-		case out <- q.nextOrNil(): // cannot proceed if out is nil
+		case id := <-q.done:
+			q.waitingLock.Lock()
+			for i, r := range q.running {
+				if r.job.ID == id {
+					q.running = append(q.running[:i], q.running[i+1:]...)
+					break
+				}
+			}
+			q.waitingLock.Unlock()
+		case out <- next: // cannot proceed if out is nil
 			q.waitingLock.Lock()
-			q.waiting = q.waiting[1:]
+			for i, j := range q.waiting {
+				if j == next {
+					q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+					break
+				}
+			}
+			q.running = append(q.running, runningJob{job: next, startedAt: time.Now().UTC()})
 			q.waitingLock.Unlock()
 		}
 	}
 }
 
-// nextOrNil returns the head of the queue, or nil if
-// the queue is empty.
+// nextOrNil returns the job to hand out next, or nil if the queue is
+// empty, or every job in it has to wait.
 func (q *Queue) nextOrNil() *Job {
 	q.waitingLock.Lock()
 	defer q.waitingLock.Unlock()
-	if len(q.waiting) > 0 {
-		return q.waiting[0]
+	if len(q.waiting) == 0 {
+		return nil
+	}
+	if q.opts.MaxRunning > 0 && len(q.running) >= q.opts.MaxRunning {
+		return nil
+	}
+	i := pick(q.waiting, func(j *Job) bool {
+		for _, r := range q.running {
+			if overlaps(j, r.job) {
+				return true
+			}
+		}
+		return false
+	})
+	if i < 0 {
+		return nil
+	}
+	return q.waiting[i]
+}
+
+// pick gives the index of the job to run first out of those given,
+// which are in the order they were queued: the one with the highest
+// priority, of those that aren't busy and don't concern the same
+// workloads as a job before them. It gives -1 if there's no such job.
+func pick(jobs []*Job, busy func(*Job) bool) int {
+	best := -1
+	for i, j := range jobs {
+		if best >= 0 && j.Priority <= jobs[best].Priority {
+			continue
+		}
+		if busy(j) || heldBack(jobs, i) {
+			continue
+		}
+		best = i
+	}
+	return best
+}
+
+// heldBack says whether the job at index i concerns a workload that
+// a job before it also concerns.
+func heldBack(jobs []*Job, i int) bool {
+	for _, earlier := range jobs[:i] {
+		if overlaps(jobs[i], earlier) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(a, b *Job) bool {
+	for _, x := range a.Workloads {
+		for _, y := range b.Workloads {
+			if x == y {
+				return true
+			}
+		}
 	}
-	return nil
+	return false
 }
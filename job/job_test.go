@@ -3,6 +3,9 @@ package job
 import (
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
 )
 
 func TestQueue(t *testing.T) {
@@ -21,7 +24,7 @@ func TestQueue(t *testing.T) {
 	}
 
 	// When this proceeds, the value will be in the queue
-	q.Enqueue(&Job{"job 1", nil})
+	q.Enqueue(&Job{ID: "job 1"})
 	q.Sync()
 	if q.Len() != 1 {
 		t.Errorf("Queue has length %d (!= 1) after enqueuing one item (and sync)", q.Len())
@@ -44,3 +47,114 @@ func TestQueue(t *testing.T) {
 	default:
 	}
 }
+
+func receive(t *testing.T, q *Queue) *Job {
+	select {
+	case j := <-q.Ready():
+		return j
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a job")
+	}
+	return nil
+}
+
+func TestQueuePriorities(t *testing.T) {
+	shutdown := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	defer close(shutdown)
+	q := NewQueueWithOptions(shutdown, wg, QueueOptions{
+		MaxRunning: 1,
+		Priorities: map[string]int{"image": 1},
+	})
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	other := flux.MustParseResourceID("default:deployment/other")
+	fresh := flux.MustParseResourceID("default:deployment/fresh")
+
+	q.Enqueue(&Job{ID: "running", Kind: "auto"})
+	q.Sync()
+	if j := receive(t, q); j.ID != "running" {
+		t.Fatalf("expected the first job, got %s", j.ID)
+	}
+	q.Enqueue(&Job{ID: "auto hello", Kind: "auto", Workloads: []flux.ResourceID{hello}})
+	q.Enqueue(&Job{ID: "auto other", Kind: "auto", Workloads: []flux.ResourceID{other}})
+	// Releases jump ahead of automated releases, except those of the
+	// same workload queued before them
+	q.Enqueue(&Job{ID: "release hello", Kind: "image", Workloads: []flux.ResourceID{hello}})
+	q.Enqueue(&Job{ID: "release other", Kind: "image", Workloads: []flux.ResourceID{other}})
+	q.Enqueue(&Job{ID: "release fresh", Kind: "image", Workloads: []flux.ResourceID{fresh}})
+	q.Sync()
+
+	var order []ID
+	for _, j := range q.Jobs() {
+		order = append(order, j.ID)
+	}
+	expected := []ID{"running", "release fresh", "auto hello", "release hello", "auto other", "release other"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected queue %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected queue %v, got %v", expected, order)
+		}
+	}
+	if q.Jobs()[0].StartedAt == nil || q.Jobs()[1].StartedAt != nil {
+		t.Error("expected only the first job to be running")
+	}
+
+	// Nothing more is handed out until the running job is done
+	select {
+	case j := <-q.Ready():
+		t.Fatalf("expected no job while one is running, got %s", j.ID)
+	default:
+	}
+	for i := 1; i < len(expected); i++ {
+		q.Done(order[i-1])
+		if j := receive(t, q); j.ID != expected[i] {
+			t.Errorf("expected %s next, got %s", expected[i], j.ID)
+		}
+	}
+}
+
+func TestQueueSameWorkload(t *testing.T) {
+	shutdown := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	defer close(shutdown)
+	// No limit on how many run at once, but jobs for the same
+	// workload still run one at a time
+	q := NewQueue(shutdown, wg)
+	hello := flux.MustParseResourceID("default:deployment/hello")
+
+	q.Enqueue(&Job{ID: "first", Workloads: []flux.ResourceID{hello}})
+	q.Enqueue(&Job{ID: "second", Workloads: []flux.ResourceID{hello}})
+	q.Enqueue(&Job{ID: "unrelated"})
+	if j := receive(t, q); j.ID != "first" {
+		t.Fatalf("expected first job, got %s", j.ID)
+	}
+	if j := receive(t, q); j.ID != "unrelated" {
+		t.Fatalf("expected the unrelated job while the first runs, got %s", j.ID)
+	}
+	select {
+	case j := <-q.Ready():
+		t.Fatalf("expected the second job to wait, got %s", j.ID)
+	default:
+	}
+	q.Done("first")
+	if j := receive(t, q); j.ID != "second" {
+		t.Fatalf("expected second job, got %s", j.ID)
+	}
+}
+
+func TestParsePriorities(t *testing.T) {
+	priorities, err := ParsePriorities([]string{"auto=-1", "image=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(priorities) != 2 || priorities["auto"] != -1 || priorities["image"] != 2 {
+		t.Errorf("unexpected priorities: %v", priorities)
+	}
+	for _, bad := range []string{"auto", "=1", "auto=high"} {
+		if _, err := ParsePriorities([]string{bad}); err == nil {
+			t.Errorf("expected error from %q", bad)
+		}
+	}
+}
@@ -14,4 +14,19 @@ const (
 	LabelReleaseType = "release_type"
 	LabelReleaseKind = "release_kind"
 	LabelStage       = "stage"
+
+	// Labels for per-workload metrics
+	LabelWorkload = "workload"
+
+	// Labels for automation metrics
+	LabelReason = "reason"
+
+	// Labels for cache metrics
+	LabelHit = "hit"
+
+	// Labels for event metrics
+	LabelEventType = "event_type"
+
+	// Labels for git metrics
+	LabelOperation = "operation"
 )
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// How many events to hold for the bus, while earlier ones are being
+// published
+const busQueueSize = 1000
+
+// Publisher is a message broker, to which events can be published,
+// each under a subject.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Bus is an event.EventWriter that publishes each event, as JSON, to
+// a message broker, so that any number of consumers can subscribe to
+// events rather than polling for them. Each is published under the
+// subject `<Subject>.<event type>`, e.g., `flux.events.release`.
+// Events are published in the order they're logged, by Loop; they're
+// published at most once, so a consumer that isn't subscribed when
+// an event is published won't see it.
+type Bus struct {
+	Publisher Publisher
+	Subject   string
+	Logger    log.Logger
+
+	once  sync.Once
+	queue chan event.Event
+}
+
+var _ event.EventWriter = &Bus{}
+
+func (b *Bus) init() {
+	b.once.Do(func() {
+		b.queue = make(chan event.Event, busQueueSize)
+	})
+}
+
+// LogEvent queues the event to be published. If the queue is full,
+// because the broker is failing or slow, the event is dropped.
+func (b *Bus) LogEvent(e event.Event) error {
+	b.init()
+	select {
+	case b.queue <- e:
+	default:
+		b.Logger.Log("subject", b.Subject, "dropped", e.Type, "err", "too many events waiting to be published")
+	}
+	return nil
+}
+
+// Loop publishes the events queued, until told to stop.
+func (b *Bus) Loop(stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	b.init()
+	for {
+		select {
+		case <-stop:
+			return
+		case e := <-b.queue:
+			if err := b.publish(e); err != nil {
+				b.Logger.Log("subject", b.Subject, "event", e.Type, "err", err)
+			}
+		}
+	}
+}
+
+func (b *Bus) publish(e event.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.Publisher.Publish(b.Subject+"."+e.Type, data)
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// CriticalAlert is an event.EventWriter that sends an alert as soon
+// as it sees an error concerning a workload of high criticality,
+// rather than waiting for a digest.
+type CriticalAlert struct {
+	Senders []Sender
+	Logger  log.Logger
+	// Renderer formats the event for the alert; if nil, the event's
+	// own String is used.
+	Renderer *event.Renderer
+}
+
+var _ event.EventWriter = CriticalAlert{}
+
+func (a CriticalAlert) LogEvent(e event.Event) error {
+	if e.Criticality != event.CriticalityHigh || e.LogLevel != event.LogLevelError {
+		return nil
+	}
+	subject, body := criticalMessage(e, a.Renderer)
+	// Don't hold up whatever logged the event while we send
+	go func() {
+		for _, s := range a.Senders {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			var err error
+			// Templates given are for text, so take precedence
+			if es, ok := s.(EventSender); ok && a.Renderer == nil {
+				err = es.SendEvent(ctx, subject, e)
+			} else {
+				err = s.Send(ctx, subject, body)
+			}
+			if err != nil {
+				a.Logger.Log("alert", "critical workload", "err", err)
+			}
+			cancel()
+		}
+	}()
+	return nil
+}
+
+func criticalMessage(e event.Event, r *event.Renderer) (subject, body string) {
+	subject = fmt.Sprintf("Flux: %s error affecting critical workloads %s", e.Type, strings.Join(e.ServiceIDStrings(), ", "))
+	body = e.String()
+	if r != nil {
+		if msg, err := r.Render(e); err == nil {
+			body = msg
+		}
+	}
+	if len(e.Owners) > 0 {
+		body += "\n[owners: " + strings.Join(e.Owners, ", ") + "]"
+	}
+	return subject, body
+}
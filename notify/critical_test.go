@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestCriticalAlert(t *testing.T) {
+	sent := make(chanSender, 1)
+	a := CriticalAlert{Senders: []Sender{sent}, Logger: log.NewNopLogger()}
+
+	ev := event.Event{
+		ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("default:deployment/payments")},
+		Type:       event.EventSync,
+		LogLevel:   event.LogLevelError,
+		Message:    "sync failed",
+	}
+	// Not critical, so left for the digest
+	a.LogEvent(ev)
+	ev.Criticality = event.CriticalityHigh
+	a.LogEvent(ev)
+	select {
+	case msg := <-sent:
+		if !strings.Contains(msg, "default:deployment/payments") || !strings.Contains(msg, "sync failed") {
+			t.Errorf("unexpected alert %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert")
+	}
+	select {
+	case msg := <-sent:
+		t.Errorf("expected only one alert, got another: %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCriticalAlert_Renderer(t *testing.T) {
+	r, err := event.NewRenderer(map[string]string{
+		event.EventLock: `{{join .ServiceIDStrings ", "}} locked, and shouldn't be`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := event.Event{
+		ServiceIDs:  []flux.ResourceID{flux.MustParseResourceID("default:deployment/payments")},
+		Type:        event.EventLock,
+		LogLevel:    event.LogLevelError,
+		Criticality: event.CriticalityHigh,
+	}
+	if _, body := criticalMessage(ev, r); body != "default:deployment/payments locked, and shouldn't be" {
+		t.Errorf("expected the message from the template given, got %q", body)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Package notify has ways of telling people what Flux has been doing,
+other than (or as well as) sending events upstream.
+
+A Digest collects events over a period (e.g., a day or a week), and
+sends a summary of the releases, policy changes, stale images and
+errors in each namespace at the end of it, to whichever Senders it is
+given (e.g., Slack, or email). This is for those who want to know
+what's changed, but not as it happens.
+
+A StaleImageAlert, on the other hand, sends word of stale images
+straight away; and a CriticalAlert sends word straight away of errors
+affecting workloads marked as highly critical.
+*/
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+// How long to allow for sending a digest
+const sendTimeout = time.Minute
+
+// Sender sends a message somewhere people will read it.
+type Sender interface {
+	Send(ctx context.Context, subject, body string) error
+}
+
+// Digest is an event.EventWriter that summarises the events it is
+// given, and sends the summary every Period.
+type Digest struct {
+	Period  time.Duration
+	Senders []Sender
+	Logger  log.Logger
+
+	mu         sync.Mutex
+	since      time.Time
+	namespaces map[string]*namespaceDigest
+}
+
+var _ event.EventWriter = &Digest{}
+
+type namespaceDigest struct {
+	releases []string
+	policies []string
+	stale    []string
+	// error message -> how many times it was seen
+	errors map[string]int
+}
+
+func (d *Digest) namespace(ns string) *namespaceDigest {
+	if d.namespaces == nil {
+		d.namespaces = map[string]*namespaceDigest{}
+	}
+	n, ok := d.namespaces[ns]
+	if !ok {
+		n = &namespaceDigest{errors: map[string]int{}}
+		d.namespaces[ns] = n
+	}
+	return n
+}
+
+func namespaceOf(id flux.ResourceID) string {
+	ns, _, _ := id.Components()
+	return ns
+}
+
+func (d *Digest) LogEvent(e event.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.since.IsZero() {
+		d.since = time.Now().UTC()
+	}
+
+	switch m := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		d.addRelease(e, m.Result, m.Error)
+	case *event.AutoReleaseEventMetadata:
+		d.addRelease(e, m.Result, m.Error)
+	case *event.RollbackEventMetadata:
+		d.addRelease(e, m.Result, m.Error)
+	case *event.SyncEventMetadata:
+		for _, re := range m.Errors {
+			d.namespace(namespaceOf(re.ID)).errors[fmt.Sprintf("sync %s: %s", re.ID, re.Error)]++
+		}
+	case *event.StaleImageEventMetadata:
+		for _, img := range m.Images {
+			d.namespace(namespaceOf(img.ID)).stale = append(d.namespace(namespaceOf(img.ID)).stale, describeStaleImage(img))
+		}
+	case *event.CommitEventMetadata:
+		if m.Spec == nil || m.Spec.Type != update.Policy {
+			return nil
+		}
+		updates, _ := m.Spec.Spec.(policy.Updates)
+		for id, u := range updates {
+			if desc := describePolicyUpdate(u); desc != "" {
+				d.namespace(namespaceOf(id)).policies = append(d.namespace(namespaceOf(id)).policies, fmt.Sprintf("%s: %s", id, desc))
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Digest) addRelease(e event.Event, result update.Result, errMsg string) {
+	for id, res := range result {
+		for _, c := range res.PerContainer {
+			d.namespace(namespaceOf(id)).releases = append(d.namespace(namespaceOf(id)).releases,
+				fmt.Sprintf("%s %s: %s -> %s", id, c.Container, c.Current, c.Target.Tag))
+		}
+		if res.Status == update.ReleaseStatusFailed && res.Error != "" {
+			d.namespace(namespaceOf(id)).errors[fmt.Sprintf("%s %s: %s", e.Type, id, res.Error)]++
+		}
+	}
+	if errMsg != "" && len(result) == 0 {
+		for _, id := range e.ServiceIDs {
+			d.namespace(namespaceOf(id)).errors[fmt.Sprintf("%s %s: %s", e.Type, id, errMsg)]++
+		}
+	}
+}
+
+func describePolicyUpdate(u policy.Update) string {
+	var changes []string
+	for p, v := range u.Add {
+		if policy.Boolean(p) {
+			changes = append(changes, "+"+string(p))
+		} else {
+			changes = append(changes, fmt.Sprintf("%s=%s", p, v))
+		}
+	}
+	for p := range u.Remove {
+		changes = append(changes, "-"+string(p))
+	}
+	sort.Strings(changes)
+	return strings.Join(changes, ", ")
+}
+
+// Summary returns the digest of everything seen since the last
+// summary was taken, and starts a new period; or false if nothing
+// has been seen.
+func (d *Digest) Summary(now time.Time) (subject, body string, ok bool) {
+	d.mu.Lock()
+	namespaces, since := d.namespaces, d.since
+	d.namespaces, d.since = nil, now
+	d.mu.Unlock()
+	if len(namespaces) == 0 {
+		return "", "", false
+	}
+
+	var nsNames []string
+	var releases, errors int
+	for ns, n := range namespaces {
+		nsNames = append(nsNames, ns)
+		releases += len(n.releases)
+		for _, count := range n.errors {
+			errors += count
+		}
+	}
+	sort.Strings(nsNames)
+
+	subject = fmt.Sprintf("Flux digest: %d image updates, %d errors since %s", releases, errors, since.Format("2006-01-02 15:04 MST"))
+	buf := &bytes.Buffer{}
+	for _, ns := range nsNames {
+		n := namespaces[ns]
+		if len(n.releases)+len(n.policies)+len(n.stale)+len(n.errors) == 0 {
+			continue
+		}
+		if ns == "" {
+			ns = "<cluster>"
+		}
+		fmt.Fprintf(buf, "Namespace %s\n", ns)
+		section(buf, "Releases", n.releases)
+		section(buf, "Policy changes", n.policies)
+		section(buf, "Stale images", n.stale)
+		var errs []string
+		for msg, count := range n.errors {
+			if count > 1 {
+				msg = fmt.Sprintf("%s (%d times)", msg, count)
+			}
+			errs = append(errs, msg)
+		}
+		section(buf, "Errors", errs)
+		fmt.Fprintln(buf)
+	}
+	return subject, strings.TrimSpace(buf.String()), true
+}
+
+func section(buf *bytes.Buffer, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	sort.Strings(lines)
+	fmt.Fprintf(buf, "  %s:\n", title)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "  - %s\n", line)
+	}
+}
+
+// Loop sends a digest every Period, until told to stop.
+func (d *Digest) Loop(stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(d.Period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			d.send(now.UTC())
+		}
+	}
+}
+
+func (d *Digest) send(now time.Time) {
+	subject, body, ok := d.Summary(now)
+	if !ok {
+		return
+	}
+	for _, s := range d.Senders {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		if err := s.Send(ctx, subject, body); err != nil {
+			d.Logger.Log("digest", "failed", "err", err)
+		}
+		cancel()
+	}
+}
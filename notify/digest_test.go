@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestDigestSummary(t *testing.T) {
+	foo := flux.MustParseResourceID("default:deployment/foo")
+	bar := flux.MustParseResourceID("prod:deployment/bar")
+	current, _ := image.ParseRef("quay.io/foo/app:1.0")
+	target, _ := image.ParseRef("quay.io/foo/app:1.1")
+
+	d := &Digest{}
+	d.LogEvent(event.Event{
+		Type: event.EventAutoRelease,
+		Metadata: &event.AutoReleaseEventMetadata{
+			ReleaseEventCommon: event.ReleaseEventCommon{
+				Result: update.Result{
+					foo: update.ControllerResult{
+						Status: update.ReleaseStatusSuccess,
+						PerContainer: []update.ContainerUpdate{
+							{Container: "app", Current: current, Target: target},
+						},
+					},
+				},
+			},
+		},
+	})
+	lock := &update.Spec{Type: update.Policy, Spec: policy.Updates{
+		bar: policy.Update{Add: policy.Set{policy.Locked: "true"}},
+	}}
+	d.LogEvent(event.Event{Type: event.EventCommit, Metadata: &event.CommitEventMetadata{Spec: lock}})
+	for i := 0; i < 3; i++ {
+		d.LogEvent(event.Event{Type: event.EventSync, Metadata: &event.SyncEventMetadata{
+			Errors: []event.ResourceError{{ID: bar, Error: "invalid manifest"}},
+		}})
+	}
+
+	subject, body, ok := d.Summary(time.Now())
+	if !ok {
+		t.Fatal("expected a summary")
+	}
+	if !strings.Contains(subject, "1 image updates, 3 errors") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	for _, expected := range []string{
+		"Namespace default",
+		"default:deployment/foo app: quay.io/foo/app:1.0 -> 1.1",
+		"Namespace prod",
+		"prod:deployment/bar: +locked",
+		"sync prod:deployment/bar: invalid manifest (3 times)",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in digest:\n%s", expected, body)
+		}
+	}
+	if strings.Index(body, "Namespace default") > strings.Index(body, "Namespace prod") {
+		t.Errorf("expected namespaces in order:\n%s", body)
+	}
+
+	if _, _, ok := d.Summary(time.Now()); ok {
+		t.Error("expected nothing in the digest after taking a summary")
+	}
+}
+
+func TestSlackSender(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	s := SlackSender{WebhookURL: server.URL}
+	if err := s.Send(context.Background(), "Flux digest", "Namespace default"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got["text"], "*Flux digest*") || !strings.Contains(got["text"], "Namespace default") {
+		t.Errorf("unexpected message %q", got["text"])
+	}
+}
+
+func TestSlackSender_SendEvent(t *testing.T) {
+	var got struct {
+		Text   string
+		Blocks []event.SlackBlock
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	s := SlackSender{WebhookURL: server.URL}
+	ev := event.Event{
+		Type:       event.EventLock,
+		ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("default:deployment/foo")},
+	}
+	if err := s.SendEvent(context.Background(), "Flux: locked", ev); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Flux: locked" || len(got.Blocks) == 0 || got.Blocks[0].Text == nil || got.Blocks[0].Text.Text != ev.MarkdownString() {
+		t.Errorf("unexpected message %+v", got)
+	}
+}
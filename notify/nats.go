@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultNATSTimeout = 10 * time.Second
+	natsReconnectWait  = 2 * time.Second
+)
+
+// NATS is a Publisher that publishes to a NATS server, given by a URL
+// of the form `nats://[user[:password]@]host[:port]` (a user without
+// a password is sent as a token), or `tls://...` to connect with TLS;
+// several URLs, separated by commas, may be given for a cluster. It
+// connects when first asked to publish, and after that reconnects by
+// itself whenever the connection fails, keeping what's published in
+// the meantime to send once it's reconnected.
+type NATS struct {
+	URL string
+	// The name to give the connection, so it can be identified in
+	// the server's monitoring
+	Name string
+	// How long to wait for the server; by default, ten seconds
+	Timeout time.Duration
+	// Files with the certificate(s) of the CA to verify the server
+	// with, and the certificate and key to present to it, if the
+	// connection uses TLS; each may be left empty
+	CACert, Cert, Key string
+	// For logging when the connection fails and is reconnected; may
+	// be nil
+	Logger log.Logger
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+var _ Publisher = &NATS{}
+
+func (n *NATS) timeout() time.Duration {
+	if n.Timeout > 0 {
+		return n.Timeout
+	}
+	return defaultNATSTimeout
+}
+
+// Publish sends the data to the subject given, and waits for the
+// server to have it, so that it fails if the server's unreachable
+// or refuses it.
+func (n *NATS) Publish(subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("invalid NATS subject %q", subject)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return err
+		}
+	}
+	if err := n.conn.Publish(subject, data); err != nil {
+		return err
+	}
+	return n.conn.FlushTimeout(n.timeout())
+}
+
+// Close closes the connection to the server, if there is one.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
+
+// connect connects to the server, to keep reconnecting for as long
+// as the connection is wanted. It must be called with the lock held.
+func (n *NATS) connect() error {
+	logger := n.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	opts := []nats.Option{
+		nats.Name(n.Name),
+		nats.Timeout(n.timeout()),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(natsReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Log("nats", "disconnected", "err", err)
+			}
+		}),
+		nats.ReconnectHandler(func(*nats.Conn) {
+			logger.Log("nats", "reconnected")
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			logger.Log("nats", "error", "err", err)
+		}),
+	}
+	if n.CACert != "" {
+		opts = append(opts, nats.RootCAs(n.CACert))
+	}
+	if n.Cert != "" || n.Key != "" {
+		opts = append(opts, nats.ClientCert(n.Cert, n.Key))
+	}
+	conn, err := nats.Connect(n.URL, opts...)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS server: %s", err)
+	}
+	n.conn = conn
+	return nil
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+type published struct {
+	subject string
+	data    []byte
+}
+
+// natsServer accepts connections, and passes on what's published to
+// it, and the connections, so they can be cut; it pings each client
+// once it's connected, to check it answers.
+func natsServer(t *testing.T) (net.Listener, <-chan string, <-chan published, <-chan net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connects := make(chan string, 10)
+	pubs := make(chan published, 10)
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+				pinged := false
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch {
+					case len(fields) == 0:
+					case fields[0] == "CONNECT":
+						connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+					case fields[0] == "PING":
+						fmt.Fprintf(conn, "PONG\r\n")
+						if !pinged {
+							fmt.Fprintf(conn, "PING\r\n")
+							pinged = true
+						}
+					case fields[0] == "PUB" && len(fields) == 3:
+						var size int
+						fmt.Sscan(fields[2], &size)
+						data := make([]byte, size+2)
+						if _, err := io.ReadFull(r, data); err != nil {
+							return
+						}
+						pubs <- published{fields[1], data[:size]}
+					}
+				}
+			}()
+		}
+	}()
+	return listener, connects, pubs, conns
+}
+
+func TestBusNATS(t *testing.T) {
+	listener, connects, pubs, _ := natsServer(t)
+	defer listener.Close()
+	nats := &NATS{URL: "nats://flux:s3cret@" + listener.Addr().String(), Name: "fluxd", Timeout: time.Second}
+	defer nats.Close()
+	bus := &Bus{Publisher: nats, Subject: "flux.events", Logger: log.NewNopLogger()}
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go bus.Loop(stop, wg)
+	defer func() { close(stop); wg.Wait() }()
+
+	bus.LogEvent(event.Event{Type: event.EventRelease, Message: "hello", Metadata: &event.ReleaseEventMetadata{}})
+	bus.LogEvent(event.Event{Type: event.EventSync, Message: "hello", Metadata: &event.SyncEventMetadata{}})
+
+	select {
+	case connect := <-connects:
+		var options map[string]interface{}
+		if err := json.Unmarshal([]byte(connect), &options); err != nil {
+			t.Fatal(err)
+		}
+		if options["user"] != "flux" || options["pass"] != "s3cret" || options["name"] != "fluxd" {
+			t.Errorf("unexpected connect options %v", options)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a connection")
+	}
+	for _, expected := range []string{event.EventRelease, event.EventSync} {
+		select {
+		case p := <-pubs:
+			if p.subject != "flux.events."+expected {
+				t.Errorf("expected subject flux.events.%s, got %s", expected, p.subject)
+			}
+			var e event.Event
+			if err := json.Unmarshal(p.data, &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Type != expected || e.Message != "hello" {
+				t.Errorf("expected %s event, got %+v", expected, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s event", expected)
+		}
+	}
+}
+
+func TestNATSBadSubject(t *testing.T) {
+	nats := &NATS{URL: "nats://127.0.0.1:1"}
+	if err := nats.Publish("flux events", nil); err == nil {
+		t.Error("expected a subject with a space to be refused")
+	}
+}
+
+func TestNATSReconnect(t *testing.T) {
+	listener, connects, pubs, conns := natsServer(t)
+	defer listener.Close()
+	nats := &NATS{URL: "nats://" + listener.Addr().String(), Timeout: time.Second}
+	defer nats.Close()
+
+	if err := nats.Publish("flux.events.sync", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	<-connects
+	if p := <-pubs; string(p.data) != "first" {
+		t.Fatalf("expected the first message, got %q", p.data)
+	}
+
+	// When the connection's cut, it's made again, and publishing
+	// carries on
+	(<-conns).Close()
+	select {
+	case <-connects:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting to be reconnected")
+	}
+	if err := nats.Publish("flux.events.sync", []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-pubs:
+		if string(p.data) != "second" {
+			t.Errorf("expected the second message, got %q", p.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the second message")
+	}
+}
+
+func TestNATSUnreachable(t *testing.T) {
+	nats := &NATS{URL: "nats://127.0.0.1:1", Timeout: time.Second}
+	if err := nats.Publish("flux.events.sync", nil); err == nil {
+		t.Error("expected publishing to fail when the server can't be reached")
+	}
+}
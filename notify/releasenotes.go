@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/update"
+)
+
+// ReleaseNotes is an event.EventWriter that posts a ReleaseNote for
+// each release that changed something (including automated releases
+// and rollbacks) to Webhook, for a changelog or release notes system.
+// If CommitURL is given, `{revision}` in it is replaced with the
+// revision of each commit, to link to it; e.g.,
+// `https://github.com/example/config/commit/{revision}`.
+type ReleaseNotes struct {
+	Webhook   *Webhook
+	CommitURL string
+}
+
+var _ event.EventWriter = ReleaseNotes{}
+
+// ReleaseNote is what's posted about a release.
+type ReleaseNote struct {
+	// release, autorelease or rollback
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Services []ReleaseNoteService `json:"services"`
+	Commits  []ReleaseNoteCommit  `json:"commits"`
+	// Who asked for the release and why; empty for automated
+	// releases
+	Cause update.Cause `json:"cause"`
+	// For a rollback, the revision of the release rolled back, and
+	// why it was
+	RolledBack string `json:"rolledBack,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// ReleaseNoteService is what a release changed in one workload.
+type ReleaseNoteService struct {
+	ID     flux.ResourceID    `json:"id"`
+	Images []ReleaseNoteImage `json:"images"`
+}
+
+// ReleaseNoteImage is an image changed in a container.
+type ReleaseNoteImage struct {
+	Container string `json:"container"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// ReleaseNoteCommit is a commit made for a release, with a link to
+// it, if there's somewhere to link to.
+type ReleaseNoteCommit struct {
+	Revision string `json:"revision"`
+	URL      string `json:"url,omitempty"`
+}
+
+func (n ReleaseNotes) LogEvent(e event.Event) error {
+	note, ok := n.note(e)
+	if !ok {
+		return nil
+	}
+	return n.Webhook.Post(e.Type, note)
+}
+
+// note gives the release note for the event, and true; or false, if
+// the event isn't a release that changed anything.
+func (n ReleaseNotes) note(e event.Event) (ReleaseNote, bool) {
+	var common event.ReleaseEventCommon
+	note := ReleaseNote{Type: e.Type, Time: e.StartedAt.UTC()}
+	switch metadata := e.Metadata.(type) {
+	case *event.ReleaseEventMetadata:
+		common, note.Cause = metadata.ReleaseEventCommon, metadata.Cause
+	case *event.AutoReleaseEventMetadata:
+		common = metadata.ReleaseEventCommon
+	case *event.RollbackEventMetadata:
+		common, note.Cause = metadata.ReleaseEventCommon, metadata.Cause
+		note.RolledBack, note.Reason = metadata.ReleaseRevision, metadata.Reason
+	default:
+		return ReleaseNote{}, false
+	}
+	if common.Error != "" || common.Revision == "" {
+		return ReleaseNote{}, false
+	}
+
+	for id, result := range common.Result {
+		if result.Status != update.ReleaseStatusSuccess {
+			continue
+		}
+		service := ReleaseNoteService{ID: id}
+		for _, c := range result.PerContainer {
+			service.Images = append(service.Images, ReleaseNoteImage{
+				Container: c.Container,
+				From:      c.Current.String(),
+				To:        c.Target.String(),
+			})
+		}
+		if len(service.Images) > 0 {
+			note.Services = append(note.Services, service)
+		}
+	}
+	if len(note.Services) == 0 {
+		return ReleaseNote{}, false
+	}
+	sort.Slice(note.Services, func(i, j int) bool {
+		return note.Services[i].ID.String() < note.Services[j].ID.String()
+	})
+
+	commit := ReleaseNoteCommit{Revision: common.Revision}
+	if n.CommitURL != "" {
+		commit.URL = strings.Replace(n.CommitURL, "{revision}", common.Revision, -1)
+	}
+	note.Commits = []ReleaseNoteCommit{commit}
+	return note, true
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestReleaseNote(t *testing.T) {
+	hello := flux.MustParseResourceID("default:deployment/hello")
+	skipped := flux.MustParseResourceID("default:deployment/skipped")
+	current, _ := image.ParseRef("quay.io/weaveworks/helloworld:v1")
+	target, _ := image.ParseRef("quay.io/weaveworks/helloworld:v2")
+	now := time.Now().UTC()
+	release := event.Event{
+		Type:      event.EventRelease,
+		StartedAt: now,
+		Metadata: &event.ReleaseEventMetadata{
+			ReleaseEventCommon: event.ReleaseEventCommon{
+				Revision: "abc1234",
+				Result: update.Result{
+					hello: {Status: update.ReleaseStatusSuccess, PerContainer: []update.ContainerUpdate{
+						{Container: "greeter", Current: current, Target: target},
+					}},
+					skipped: {Status: update.ReleaseStatusSkipped},
+				},
+			},
+			Cause: update.Cause{User: "jane", Message: "ship it"},
+		},
+	}
+	n := ReleaseNotes{CommitURL: "https://github.com/example/config/commit/{revision}"}
+
+	note, ok := n.note(release)
+	if !ok {
+		t.Fatal("expected a release note")
+	}
+	if note.Type != event.EventRelease || !note.Time.Equal(now) || note.Cause.User != "jane" {
+		t.Errorf("unexpected release note %+v", note)
+	}
+	if len(note.Services) != 1 || note.Services[0].ID != hello || len(note.Services[0].Images) != 1 {
+		t.Fatalf("expected only the workload changed, got %+v", note.Services)
+	}
+	if im := note.Services[0].Images[0]; im.Container != "greeter" || im.From != current.String() || im.To != target.String() {
+		t.Errorf("unexpected image change %+v", im)
+	}
+	if len(note.Commits) != 1 || note.Commits[0].URL != "https://github.com/example/config/commit/abc1234" {
+		t.Errorf("expected a link to the commit, got %+v", note.Commits)
+	}
+
+	release.Metadata.(*event.ReleaseEventMetadata).Error = "push failed"
+	if _, ok := n.note(release); ok {
+		t.Error("expected no release note for a failed release")
+	}
+	if _, ok := n.note(event.Event{Type: event.EventSync, Metadata: &event.SyncEventMetadata{}}); ok {
+		t.Error("expected no release note for a sync")
+	}
+}
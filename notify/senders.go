@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// EventSender is a Sender that can send an event in its own format,
+// rather than as text.
+type EventSender interface {
+	Sender
+	SendEvent(ctx context.Context, subject string, e event.Event) error
+}
+
+// SlackSender posts messages to a Slack incoming webhook.
+type SlackSender struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (s SlackSender) Send(ctx context.Context, subject, body string) error {
+	return s.post(ctx, map[string]string{
+		"text": fmt.Sprintf("*%s*\n```\n%s\n```", subject, body),
+	})
+}
+
+// SendEvent posts the event as Slack blocks, as an EventSender. The
+// subject is used as the notification text.
+func (s SlackSender) SendEvent(ctx context.Context, subject string, e event.Event) error {
+	return s.post(ctx, struct {
+		Text   string             `json:"text"`
+		Blocks []event.SlackBlock `json:"blocks"`
+	}{subject, e.SlackBlocks()})
+}
+
+func (s SlackSender) post(ctx context.Context, message interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("posting to Slack webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailSender sends messages by email, through the SMTP server at
+// Addr (host:port). If Username is given, it authenticates with
+// PLAIN auth.
+type EmailSender struct {
+	Addr     string
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+func (s EmailSender) Send(ctx context.Context, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	msg.WriteString("\r\n")
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, msg.Bytes())
+}
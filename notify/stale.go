@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// StaleImageAlert is an event.EventWriter that sends an alert as
+// soon as it sees a stale image event, rather than waiting for a
+// digest.
+type StaleImageAlert struct {
+	Senders []Sender
+	Logger  log.Logger
+}
+
+var _ event.EventWriter = StaleImageAlert{}
+
+func (a StaleImageAlert) LogEvent(e event.Event) error {
+	m, ok := e.Metadata.(*event.StaleImageEventMetadata)
+	if !ok || len(m.Images) == 0 {
+		return nil
+	}
+	subject, body := staleImageMessage(m.Images)
+	// Don't hold up whatever logged the event while we send
+	go func() {
+		for _, s := range a.Senders {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := s.Send(ctx, subject, body); err != nil {
+				a.Logger.Log("alert", "stale images", "err", err)
+			}
+			cancel()
+		}
+	}()
+	return nil
+}
+
+func staleImageMessage(images []event.StaleImage) (subject, body string) {
+	subject = fmt.Sprintf("Flux: %d workload containers are running stale images", len(images))
+	buf := &bytes.Buffer{}
+	for _, img := range images {
+		fmt.Fprintf(buf, "%s\n", describeStaleImage(img))
+	}
+	return subject, buf.String()
+}
+
+func describeStaleImage(img event.StaleImage) string {
+	desc := fmt.Sprintf("%s %s: %s (created %s) is %d behind %s (created %s)",
+		img.ID, img.Container, img.Current, img.CreatedAt.Format("2006-01-02"),
+		img.Newer, img.Latest, img.LatestAt.Format("2006-01-02"))
+	if img.Owner != "" {
+		desc += " [owner: " + img.Owner + "]"
+	}
+	return desc
+}
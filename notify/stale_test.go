@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+type chanSender chan string
+
+func (c chanSender) Send(_ context.Context, subject, body string) error {
+	c <- subject + "\n" + body
+	return nil
+}
+
+func staleImageEvent() event.Event {
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	return event.Event{
+		Type:     event.EventStaleImage,
+		LogLevel: event.LogLevelWarn,
+		Metadata: &event.StaleImageEventMetadata{
+			Images: []event.StaleImage{{
+				ID:        flux.MustParseResourceID("default:deployment/foo"),
+				Container: "app",
+				Current:   "quay.io/foo/app:1.0",
+				CreatedAt: created,
+				Latest:    "1.3",
+				LatestAt:  created.Add(90 * 24 * time.Hour),
+				Newer:     3,
+			}},
+		},
+	}
+}
+
+func TestStaleImageAlert(t *testing.T) {
+	sent := make(chanSender, 1)
+	a := StaleImageAlert{Senders: []Sender{sent}, Logger: log.NewNopLogger()}
+
+	a.LogEvent(event.Event{Type: event.EventSync, Metadata: &event.SyncEventMetadata{}})
+	a.LogEvent(staleImageEvent())
+	select {
+	case msg := <-sent:
+		if !strings.Contains(msg, "default:deployment/foo app: quay.io/foo/app:1.0 (created 2018-01-01) is 3 behind 1.3") {
+			t.Errorf("unexpected alert %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert to be sent")
+	}
+	select {
+	case msg := <-sent:
+		t.Errorf("expected only one alert, but also got %q", msg)
+	default:
+	}
+}
+
+func TestDigestStaleImages(t *testing.T) {
+	d := &Digest{}
+	d.LogEvent(staleImageEvent())
+	_, body, ok := d.Summary(time.Now())
+	if !ok {
+		t.Fatal("expected a summary")
+	}
+	if !strings.Contains(body, "Stale images:") || !strings.Contains(body, "quay.io/foo/app:1.0") {
+		t.Errorf("expected stale image in digest:\n%s", body)
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// WebhookSubscriber is a webhook that's posted the events it asks
+// for. Subscribers are usually loaded from a file like
+//
+//     subscribers:
+//     - name: alerts
+//       url: https://alerts.example.com/flux
+//       secret: s3cret
+//       types: [sync, sync_conflict]
+//       minLogLevel: warn
+//     - name: prod-changelog
+//       url: https://changelog.example.com/events
+//       namespaces: [prod]
+//
+// Since the file may have secrets in it, it's best kept in a
+// Kubernetes secret, mounted into the daemon's container.
+type WebhookSubscriber struct {
+	Name        string   `yaml:"name"`
+	URL         string   `yaml:"url"`
+	Secret      string   `yaml:"secret"`
+	Types       []string `yaml:"types"`
+	Namespaces  []string `yaml:"namespaces"`
+	MinLogLevel string   `yaml:"minLogLevel"`
+}
+
+type webhookSubscribersConfig struct {
+	Subscribers []WebhookSubscriber `yaml:"subscribers"`
+}
+
+// Filter gives the filter selecting the events the subscriber asked
+// for.
+func (s WebhookSubscriber) Filter() event.EventFilter {
+	return event.EventFilter{
+		Types:       s.Types,
+		Namespaces:  s.Namespaces,
+		MinLogLevel: s.MinLogLevel,
+	}
+}
+
+// LoadWebhookSubscribers reads and checks a file of webhook
+// subscribers.
+func LoadWebhookSubscribers(path string) ([]WebhookSubscriber, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading webhook subscribers")
+	}
+	return ParseWebhookSubscribers(bytes)
+}
+
+func ParseWebhookSubscribers(bytes []byte) ([]WebhookSubscriber, error) {
+	var config webhookSubscribersConfig
+	if err := yaml.Unmarshal(bytes, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing webhook subscribers")
+	}
+	names := map[string]bool{}
+	for _, s := range config.Subscribers {
+		if s.Name == "" || s.URL == "" {
+			return nil, fmt.Errorf("webhook subscriber %q needs both a name and a URL", s.Name)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("more than one webhook subscriber named %q", s.Name)
+		}
+		names[s.Name] = true
+		if s.MinLogLevel != "" {
+			if err := event.ValidateLogLevel(s.MinLogLevel); err != nil {
+				return nil, errors.Wrapf(err, "webhook subscriber %q", s.Name)
+			}
+		}
+	}
+	return config.Subscribers, nil
+}
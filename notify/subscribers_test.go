@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux/event"
+)
+
+const testWebhookSubscribers = `
+subscribers:
+- name: alerts
+  url: https://alerts.example.com/flux
+  secret: s3cret
+  types: [sync, sync_conflict]
+  minLogLevel: warn
+- name: prod-changelog
+  url: https://changelog.example.com/events
+  namespaces: [prod]
+`
+
+func TestParseWebhookSubscribers(t *testing.T) {
+	subscribers, err := ParseWebhookSubscribers([]byte(testWebhookSubscribers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscribers) != 2 {
+		t.Fatalf("expected two subscribers, got %+v", subscribers)
+	}
+	alerts := subscribers[0].Filter()
+	if subscribers[0].Secret != "s3cret" || len(alerts.Types) != 2 || alerts.MinLogLevel != event.LogLevelWarn {
+		t.Errorf("unexpected subscriber %+v", subscribers[0])
+	}
+	if changelog := subscribers[1].Filter(); len(changelog.Namespaces) != 1 || changelog.Namespaces[0] != "prod" {
+		t.Errorf("unexpected subscriber %+v", subscribers[1])
+	}
+
+	for _, bad := range []string{
+		"subscribers:\n- url: https://example.com\n",
+		"subscribers:\n- name: a\n  url: https://example.com\n- name: a\n  url: https://example.org\n",
+		"subscribers:\n- name: a\n  url: https://example.com\n  minLogLevel: loud\n",
+	} {
+		if _, err := ParseWebhookSubscribers([]byte(bad)); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/weaveworks/flux/event"
+)
+
+// LoadMessageTemplates reads a YAML map of event type to template,
+// e.g.,
+//
+//     release: ':rocket: {{join .ServiceIDStrings ", "}} released'
+//
+// and returns a renderer that uses those in preference to the
+// defaults (see event.DefaultTemplates).
+func LoadMessageTemplates(path string) (*event.Renderer, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading message templates")
+	}
+	var templates map[string]string
+	if err := yaml.Unmarshal(bytes, &templates); err != nil {
+		return nil, errors.Wrap(err, "parsing message templates")
+	}
+	return event.NewRenderer(templates)
+}
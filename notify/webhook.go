@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/tracing"
+)
+
+const (
+	// How many events to hold for a webhook, while earlier ones are
+	// being sent
+	webhookQueueSize = 1000
+	// The longest to wait between tries at posting an event
+	maxWebhookBackoff = 5 * time.Minute
+	// The header giving the signature of the body, when there's a
+	// secret
+	WebhookSignatureHeader = "X-Flux-Signature"
+	// The header giving the cursor for the event posted, when the
+	// webhook is a named subscriber
+	WebhookCursorHeader = "X-Flux-Cursor"
+)
+
+// Webhook is an event.EventWriter that posts each event it's given,
+// as JSON, to URL. Events are posted in the order they're logged, by
+// Loop; if an event can't be posted, it's tried again up to Retries
+// times, waiting Backoff (by default, a second) and then twice as
+// long each time. If Secret is given, each request is signed with
+// it: the header X-Flux-Signature has `sha256=` followed by the hex
+// HMAC-SHA256 of the body.
+//
+// Only the events that Filter selects are posted. If the webhook is
+// a subscriber with a Name, each event is posted with a cursor, in
+// the header X-Flux-Cursor; a subscriber that was down can hand the
+// last cursor it received to the daemon's API, to be given the events
+// it missed.
+type Webhook struct {
+	Name    string
+	URL     string
+	Secret  string
+	Filter  event.EventFilter
+	Retries int
+	Backoff time.Duration
+	Client  *http.Client
+	Logger  log.Logger
+
+	once  sync.Once
+	queue chan webhookPost
+}
+
+var _ event.EventWriter = &Webhook{}
+
+// A payload waiting to be posted, what it's about (for the
+// X-Flux-Event header), its cursor, if it has one, and the trace it
+// was part of, if any
+type webhookPost struct {
+	kind            string
+	body            []byte
+	cursor          string
+	traceID, spanID string
+}
+
+func (w *Webhook) init() {
+	w.once.Do(func() {
+		w.queue = make(chan webhookPost, webhookQueueSize)
+	})
+}
+
+// LogEvent queues the event to be posted, if the filter selects it.
+func (w *Webhook) LogEvent(e event.Event) error {
+	if !w.Filter.Matches(e) {
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	p := webhookPost{kind: e.Type, body: body, traceID: e.TraceID, spanID: e.SpanID}
+	// Events that weren't kept (and so have no ID) can't be resumed
+	// from
+	if w.Name != "" && e.ID != 0 {
+		p.cursor = event.MakeCursor(w.Name, e.ID)
+	}
+	w.enqueue(p)
+	return nil
+}
+
+// Post queues the payload to be posted, as JSON, saying it's about
+// the kind of event given.
+func (w *Webhook) Post(kind string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	w.enqueue(webhookPost{kind: kind, body: body})
+	return nil
+}
+
+// enqueue queues the post. If the queue is full, because the webhook
+// is failing or slow, the post is dropped.
+func (w *Webhook) enqueue(p webhookPost) {
+	w.init()
+	select {
+	case w.queue <- p:
+	default:
+		w.Logger.Log("webhook", w.URL, "dropped", p.kind, "err", "too many events waiting to be posted")
+	}
+}
+
+// Loop posts the events queued, until told to stop.
+func (w *Webhook) Loop(stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	w.init()
+	for {
+		select {
+		case <-stop:
+			return
+		case p := <-w.queue:
+			if err := w.send(stop, p); err != nil {
+				w.Logger.Log("webhook", w.URL, "event", p.kind, "err", err)
+			}
+		}
+	}
+}
+
+// send posts the payload, trying again (after a wait) when that
+// fails in a way that may not last.
+func (w *Webhook) send(stop <-chan struct{}, p webhookPost) error {
+	wait := w.Backoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for try := 0; ; try++ {
+		retry, err := w.post(p)
+		if err == nil || !retry || try >= w.Retries {
+			return err
+		}
+		select {
+		case <-stop:
+			return err
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxWebhookBackoff {
+			wait = maxWebhookBackoff
+		}
+	}
+}
+
+// post makes one request, and says whether it's worth trying again
+// if it failed.
+func (w *Webhook) post(p webhookPost) (bool, error) {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(p.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flux-Event", p.kind)
+	if p.cursor != "" {
+		req.Header.Set(WebhookCursorHeader, p.cursor)
+	}
+	if w.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+WebhookSignature(w.Secret, p.body))
+	}
+	tracing.InjectIDs(req.Header, p.traceID, p.spanID)
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("posting event to webhook: %s", resp.Status)
+	}
+	return false, nil
+}
+
+// WebhookSignature gives the hex HMAC-SHA256 of the body, with the
+// secret given; a receiver can compare it with the signature in the
+// request, to check the request came from flux.
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
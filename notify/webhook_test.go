@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/event"
+)
+
+func TestWebhook(t *testing.T) {
+	type received struct {
+		e           event.Event
+		signature   string
+		valid       bool
+		traceparent string
+	}
+	got := make(chan received, 2)
+	var mu sync.Mutex
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Fail the first time, to be tried again
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var e event.Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Error(err)
+		}
+		signature := r.Header.Get(WebhookSignatureHeader)
+		got <- received{e, signature, signature == "sha256="+WebhookSignature("s3cret", body), r.Header.Get("traceparent")}
+	}))
+	defer server.Close()
+
+	w := &Webhook{URL: server.URL, Secret: "s3cret", Retries: 2, Backoff: time.Millisecond, Logger: log.NewNopLogger()}
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go w.Loop(stop, wg)
+	defer func() { close(stop); wg.Wait() }()
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	w.LogEvent(event.Event{Type: event.EventRelease, Message: "hello", Metadata: &event.ReleaseEventMetadata{}, TraceID: traceID, SpanID: spanID})
+	w.LogEvent(event.Event{Type: event.EventSync, Message: "hello", Metadata: &event.SyncEventMetadata{}})
+	for i, expected := range []string{event.EventRelease, event.EventSync} {
+		select {
+		case r := <-got:
+			if r.e.Type != expected || r.e.Message != "hello" {
+				t.Errorf("expected %s event, got %+v", expected, r.e)
+			}
+			if !r.valid {
+				t.Errorf("expected a valid signature, got %q", r.signature)
+			}
+			// The event's trace is given, if it has one
+			if traceparent := "00-" + traceID + "-" + spanID + "-01"; i == 0 && r.traceparent != traceparent {
+				t.Errorf("expected traceparent %q, got %q", traceparent, r.traceparent)
+			} else if i == 1 && r.traceparent != "" {
+				t.Errorf("expected no traceparent, got %q", r.traceparent)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %s event to be posted", expected)
+		}
+	}
+}
+
+func TestWebhookSubscriber(t *testing.T) {
+	type received struct {
+		e      event.Event
+		cursor string
+	}
+	got := make(chan received, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e event.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		got <- received{e, r.Header.Get(WebhookCursorHeader)}
+	}))
+	defer server.Close()
+
+	w := &Webhook{
+		Name:   "alerts",
+		URL:    server.URL,
+		Filter: event.EventFilter{Types: []string{event.EventSync}, Namespaces: []string{"prod"}},
+		Logger: log.NewNopLogger(),
+	}
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go w.Loop(stop, wg)
+	defer func() { close(stop); wg.Wait() }()
+
+	prod := []flux.ResourceID{flux.MustParseResourceID("prod:deployment/hello")}
+	dev := []flux.ResourceID{flux.MustParseResourceID("dev:deployment/hello")}
+	w.LogEvent(event.Event{ID: 1, Type: event.EventSync, ServiceIDs: dev, Metadata: &event.SyncEventMetadata{}})
+	w.LogEvent(event.Event{ID: 2, Type: event.EventRelease, ServiceIDs: prod, Metadata: &event.ReleaseEventMetadata{}})
+	w.LogEvent(event.Event{ID: 3, Type: event.EventSync, ServiceIDs: prod, Metadata: &event.SyncEventMetadata{}})
+	select {
+	case r := <-got:
+		if r.e.ID != 3 {
+			t.Errorf("expected only event 3 to be posted, got %+v", r.e)
+		}
+		name, id, err := event.ParseCursor(r.cursor)
+		if err != nil || name != "alerts" || id != 3 {
+			t.Errorf("expected a cursor for alerts and event 3, got %q (%s, %d, %v)", r.cursor, name, id, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an event to be posted")
+	}
+	select {
+	case r := <-got:
+		t.Errorf("expected no more events to be posted, got %+v", r.e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
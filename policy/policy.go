@@ -12,8 +12,19 @@ const (
 	Locked     = Policy("locked")
 	LockedUser = Policy("locked_user")
 	LockedMsg  = Policy("locked_msg")
-	Automated  = Policy("automated")
-	TagAll     = Policy("tag_all")
+	// LockedUntil is the revision a workload is meant to be locked
+	// until, e.g., that of a fix yet to be merged
+	LockedUntil = Policy("locked_until")
+	Automated   = Policy("automated")
+	TagAll      = Policy("tag_all")
+	// ReleaseGate is a URL to ask before releasing to a workload
+	ReleaseGate = Policy("release_gate")
+	// ChartVersion is a pattern for the chart versions an automated
+	// FluxHelmRelease may be updated to
+	ChartVersion = Policy("chart_version")
+	// Observe means automated releases to a workload are only
+	// planned and recorded, not committed
+	Observe = Policy("observe")
 )
 
 // Policy is an string, denoting the current deployment policy of a service,
@@ -22,7 +33,7 @@ type Policy string
 
 func Boolean(policy Policy) bool {
 	switch policy {
-	case Locked, Automated, Ignore:
+	case Locked, Automated, Ignore, Observe:
 		return true
 	}
 	return false
@@ -36,6 +47,30 @@ func Tag(policy Policy) bool {
 	return strings.HasPrefix(string(policy), "tag.")
 }
 
+// PinPrefix gives the policy that pins the container named to a
+// tag. Automation keeps a pinned container at the tag it's pinned to,
+// until it's unpinned.
+func PinPrefix(container string) Policy {
+	return Policy("pin." + container)
+}
+
+func Pin(policy Policy) bool {
+	return strings.HasPrefix(string(policy), "pin.")
+}
+
+// GetPin returns the tag the container named is pinned to, if it is
+// pinned.
+func GetPin(policies Set, container string) (string, bool) {
+	if policies == nil {
+		return "", false
+	}
+	tag, ok := policies.Get(PinPrefix(container))
+	if !ok || tag == "" {
+		return "", false
+	}
+	return tag, true
+}
+
 func GetTagPattern(policies Set, container string) Pattern {
 	if policies == nil {
 		return PatternAll
@@ -47,6 +82,16 @@ func GetTagPattern(policies Set, container string) Pattern {
 	return NewPattern(pattern)
 }
 
+// GetChartVersionPattern returns the pattern chart versions must
+// match to be considered for automated updates.
+func GetChartVersionPattern(policies Set) Pattern {
+	pattern, ok := policies.Get(ChartVersion)
+	if !ok {
+		return PatternAll
+	}
+	return NewPattern(pattern)
+}
+
 type Updates map[flux.ResourceID]Update
 
 type Update struct {
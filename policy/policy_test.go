@@ -88,3 +88,19 @@ func Test_GetTagPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPin(t *testing.T) {
+	policies := Set{}.Set(PinPrefix("app"), "v1.4.2").Set(TagPrefix("sidecar"), "glob:*")
+	if tag, ok := GetPin(policies, "app"); !ok || tag != "v1.4.2" {
+		t.Errorf("expected app to be pinned to v1.4.2, got %q (%v)", tag, ok)
+	}
+	if tag, ok := GetPin(policies, "sidecar"); ok {
+		t.Errorf("expected sidecar not to be pinned, got %q", tag)
+	}
+	if _, ok := GetPin(nil, "app"); ok {
+		t.Error("expected nothing to be pinned with no policies")
+	}
+	if !Pin(PinPrefix("app")) || Pin(TagPrefix("app")) {
+		t.Error("expected only the pin policy to be a pin")
+	}
+}
@@ -54,6 +54,9 @@ type Warmer struct {
 	Trace         bool
 	Priority      chan image.Name
 	Notify        func()
+	// For images whose build time can be read from their tags, so
+	// their manifests needn't be fetched
+	TagTimestamps image.TagTimestampRules
 }
 
 // NewWarmer creates cache warmer that (when Loop is invoked) will
@@ -214,8 +217,15 @@ func (w *Warmer) warm(ctx context.Context, now time.Time, logger log.Logger, id
 			return // abort and let the error be written
 		}
 
-		// See if we have the manifest already cached
+		// If the build time can be read from the tag, that's all
+		// that's needed to order the image; don't fetch the manifest
 		newID := id.ToRef(tag)
+		if createdAt, ok := w.TagTimestamps.Timestamp(newID); ok {
+			newImages[tag] = image.Info{ID: newID, CreatedAt: createdAt}
+			continue
+		}
+
+		// See if we have the manifest already cached
 		key := NewManifestKey(newID.CanonicalRef())
 		bytes, deadline, err := w.cache.GetKey(key)
 		// If err, then we don't have it yet. Update.
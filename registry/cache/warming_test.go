@@ -140,3 +140,34 @@ func setup(t *testing.T, digest *string) (*Warmer, Client) {
 	warmer := &Warmer{clientFactory: factory, cache: c, burst: 10}
 	return warmer, c
 }
+
+func TestWarmWithTagTimestamps(t *testing.T) {
+	rule, err := image.ParseTagTimestampRule("example.com/path/image=master-{20060102.1504}-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mock.Client{
+		TagsFn: func() ([]string, error) {
+			return []string{"master-20240101.1234-abcdef", "tag"}, nil
+		},
+		ManifestFn: func(tag string) (registry.ImageEntry, error) {
+			if tag != "tag" {
+				t.Errorf("remote client was asked for the manifest of %q, which has a timestamp", tag)
+			}
+			return registry.ImageEntry{Info: image.Info{ID: repo.ToRef(tag), CreatedAt: time.Now(), Digest: "abc"}}, nil
+		},
+	}
+	c := &mem{}
+	warmer := &Warmer{clientFactory: &mock.ClientFactory{Client: client}, cache: c, burst: 10, TagTimestamps: image.TagTimestampRules{rule}}
+	warmer.warm(context.TODO(), time.Now(), log.NewNopLogger(), repo, registry.NoCredentials())
+
+	registry := &Cache{Reader: c}
+	images, err := registry.GetRepositoryImages(repo)
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+	for _, im := range images {
+		if im.ID.Tag == "master-20240101.1234-abcdef" {
+			assert.Equal(t, time.Date(2024, 1, 1, 12, 34, 0, 0, time.UTC), im.CreatedAt)
+		}
+	}
+}
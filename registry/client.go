@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"reflect"
 	"time"
@@ -62,6 +63,7 @@ func (entry *ImageEntry) UnmarshalJSON(bytes []byte) error {
 type Client interface {
 	Tags(context.Context) ([]string, error)
 	Manifest(ctx context.Context, ref string) (ImageEntry, error)
+	HasTag(ctx context.Context, tag string) (bool, error)
 }
 
 // ClientFactory supplies Client implementations for a given repo,
@@ -100,6 +102,51 @@ func (a *Remote) Tags(ctx context.Context) ([]string, error) {
 	return repository.Tags(ctx).All(ctx)
 }
 
+// The media types accepted when asking whether a tag exists; these
+// include those of OCI manifests, since that's what artefacts other
+// than images (e.g., signatures and SBOMs attached by cosign) tend to
+// be pushed as.
+var tagMediaTypes = []string{
+	schema2.MediaTypeManifest,
+	manifestlist.MediaTypeManifestList,
+	schema1.MediaTypeSignedManifest,
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// HasTag asks the registry whether there's a manifest with the tag
+// given in this repository, without fetching the manifest.
+func (a *Remote) HasTag(ctx context.Context, tag string) (bool, error) {
+	u := a.base + "/v2/" + a.repo.Image + "/manifests/" + tag
+	do := func(method string) (*http.Response, error) {
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tagMediaTypes {
+			req.Header.Add("Accept", t)
+		}
+		return (&http.Client{Transport: a.transport}).Do(req.WithContext(ctx))
+	}
+	resp, err := do("HEAD")
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		// Not every registry answers HEAD requests for manifests
+		resp.Body.Close()
+		resp, err = do("GET")
+	}
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return true, nil
+	}
+	return false, fmt.Errorf("%s from %s", resp.Status, u)
+}
+
 // Manifest fetches the metadata for an image reference; currently
 // assumed to be in the same repo as that provided to `NewRemote(...)`
 func (a *Remote) Manifest(ctx context.Context, ref string) (ImageEntry, error) {
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/image"
+)
+
+func TestRemoteHasTag(t *testing.T) {
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if !strings.Contains(strings.Join(r.Header["Accept"], ","), "application/vnd.oci.image.manifest.v1+json") {
+			t.Errorf("expected OCI manifests to be accepted, got %v", r.Header["Accept"])
+		}
+		switch r.URL.Path {
+		case "/v2/weaveworks/helloworld/manifests/sha256-abc123.sbom":
+		case "/v2/weaveworks/helloworld/manifests/no-head":
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/v2/weaveworks/helloworld/manifests/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	name, _ := image.ParseRef("example.com/weaveworks/helloworld")
+	remote := &Remote{transport: http.DefaultTransport, repo: name.CanonicalName(), base: ts.URL}
+	for tag, expected := range map[string]bool{
+		"sha256-abc123.sbom": true,
+		"sha256-def456.sbom": false,
+		"no-head":            true,
+	} {
+		methods = nil
+		found, err := remote.HasTag(context.Background(), tag)
+		if err != nil {
+			t.Errorf("%s: %v", tag, err)
+		}
+		if found != expected {
+			t.Errorf("%s: expected %v, got %v", tag, expected, found)
+		}
+		if methods[0] != "HEAD" {
+			t.Errorf("%s: expected a HEAD request first, got %v", tag, methods)
+		}
+	}
+	if _, err := remote.HasTag(context.Background(), "broken"); err == nil {
+		t.Error("expected an error from a server error")
+	}
+}
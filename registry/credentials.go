@@ -139,6 +139,13 @@ func (cs Credentials) credsFor(host string) creds {
 	return creds{}
 }
 
+// Basic gives the username and password to use for the host, both
+// empty if there are none.
+func (cs Credentials) Basic(host string) (username, password string) {
+	cred := cs.credsFor(host)
+	return cred.username, cred.password
+}
+
 // Hosts returns all of the hosts available in these credentials.
 func (cs Credentials) Hosts() []string {
 	hosts := []string{}
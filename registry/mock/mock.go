@@ -12,6 +12,8 @@ import (
 type Client struct {
 	ManifestFn func(ref string) (registry.ImageEntry, error)
 	TagsFn     func() ([]string, error)
+	// If nil, HasTag looks for the tag in those TagsFn gives
+	HasTagFn func(tag string) (bool, error)
 }
 
 func (m *Client) Manifest(ctx context.Context, tag string) (registry.ImageEntry, error) {
@@ -22,6 +24,22 @@ func (m *Client) Tags(context.Context) ([]string, error) {
 	return m.TagsFn()
 }
 
+func (m *Client) HasTag(_ context.Context, tag string) (bool, error) {
+	if m.HasTagFn != nil {
+		return m.HasTagFn(tag)
+	}
+	tags, err := m.TagsFn()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range tags {
+		if t == tag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 var _ registry.Client = &Client{}
 
 type ClientFactory struct {
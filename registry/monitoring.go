@@ -84,6 +84,16 @@ func (m *instrumentedClient) Manifest(ctx context.Context, ref string) (res Imag
 	return
 }
 
+func (m *instrumentedClient) HasTag(ctx context.Context, tag string) (res bool, err error) {
+	start := time.Now()
+	res, err = m.next.HasTag(ctx, tag)
+	remoteDuration.With(
+		LabelRequestKind, RequestKindMetadata,
+		fluxmetrics.LabelSuccess, strconv.FormatBool(err == nil),
+	).Observe(time.Since(start).Seconds())
+	return
+}
+
 func (m *instrumentedClient) Tags(ctx context.Context) (res []string, err error) {
 	start := time.Now()
 	res, err = m.next.Tags(ctx)
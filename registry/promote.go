@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"github.com/weaveworks/flux/image"
+)
+
+// When images are promoted from one registry to another (e.g., from
+// staging to production) before being released, the images available
+// for a workload are those in the registry they're promoted from,
+// since the registry named in the manifests only has the images that
+// have been released already.
+
+type promotingRegistry struct {
+	next    Registry
+	sources image.RewriteRules
+}
+
+// NewPromotingRegistry gives a registry in which the images named by
+// the sources given are looked for in the repositories they are
+// promoted from; the images found there are reported under the name
+// asked for, as though they had been promoted already.
+func NewPromotingRegistry(next Registry, sources image.RewriteRules) Registry {
+	return &promotingRegistry{
+		next:    next,
+		sources: sources,
+	}
+}
+
+func (r *promotingRegistry) GetRepositoryImages(name image.Name) ([]image.Info, error) {
+	source := r.sources.Rewrite(name.ToRef("")).Name
+	infos, err := r.next.GetRepositoryImages(source)
+	if err != nil || source == name {
+		return infos, err
+	}
+	for i := range infos {
+		infos[i].ID = name.ToRef(infos[i].ID.Tag)
+	}
+	return infos, nil
+}
+
+func (r *promotingRegistry) GetImage(ref image.Ref) (image.Info, error) {
+	info, err := r.next.GetImage(r.sources.Rewrite(ref))
+	if err != nil {
+		return info, err
+	}
+	info.ID = ref
+	return info, nil
+}
+
+// ImageCredsWithSources adds the repositories that images are
+// promoted from to the images to fetch, with the credentials of the
+// images promoted, so that they are kept in the cache.
+func ImageCredsWithSources(lookup func() ImageCreds, sources image.RewriteRules) func() ImageCreds {
+	return func() ImageCreds {
+		imageCreds := lookup()
+		toAdd := ImageCreds{}
+		for name, creds := range imageCreds {
+			if source := sources.Rewrite(name.ToRef("")).Name; source != name {
+				toAdd[source] = creds
+			}
+		}
+		for name, creds := range toAdd {
+			if _, ok := imageCreds[name]; !ok {
+				imageCreds[name] = creds
+			}
+		}
+		return imageCreds
+	}
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/weaveworks/flux/image"
+)
+
+type stagingRegistry struct{}
+
+func (stagingRegistry) GetRepositoryImages(name image.Name) ([]image.Info, error) {
+	if name.String() != "staging.example.com/app" {
+		return nil, ErrNoImageData
+	}
+	return []image.Info{
+		{ID: name.ToRef("v1"), Digest: "sha256:1"},
+		{ID: name.ToRef("v2"), Digest: "sha256:2"},
+	}, nil
+}
+
+func (stagingRegistry) GetImage(ref image.Ref) (image.Info, error) {
+	return image.Info{ID: ref, Digest: "sha256:" + ref.Tag}, nil
+}
+
+func TestPromotingRegistry(t *testing.T) {
+	rule, err := image.ParseRewriteRule("staging.example.com/*=prod.example.com/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := image.RewriteRules{rule.Inverse()}
+	reg := NewPromotingRegistry(stagingRegistry{}, sources)
+
+	prod, _ := image.ParseRef("prod.example.com/app:v2")
+	infos, err := reg.GetRepositoryImages(prod.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[1].ID.String() != "prod.example.com/app:v2" {
+		t.Errorf("expected staging images, named as in prod, got %v", infos)
+	}
+	info, err := reg.GetImage(prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != prod || info.Digest != "sha256:v2" {
+		t.Errorf("expected staging image info for %s, got %+v", prod, info)
+	}
+
+	creds := ImageCredsWithSources(func() ImageCreds {
+		return ImageCreds{prod.Name: NoCredentials()}
+	}, sources)()
+	staging, _ := image.ParseRef("staging.example.com/app")
+	if _, ok := creds[staging.Name]; !ok || len(creds) != 2 {
+		t.Errorf("expected staging repository to be fetched too, got %v", creds)
+	}
+}
@@ -9,24 +9,37 @@ import (
 	"github.com/weaveworks/flux"
 	"github.com/weaveworks/flux/cluster"
 	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/registry"
 	"github.com/weaveworks/flux/resource"
 	"github.com/weaveworks/flux/update"
 )
 
 type ReleaseContext struct {
-	cluster   cluster.Cluster
-	manifests cluster.Manifests
-	repo      *git.Checkout
-	registry  registry.Registry
+	cluster       cluster.Cluster
+	manifests     cluster.Manifests
+	repo          *git.Checkout
+	registry      registry.Registry
+	imageRewrites image.RewriteRules
+	gate          Gate
+	promotion     *Promotion
+	pullCheck     *PullCheck
+	// If set, used to load the resources until any updates are
+	// written
+	loadClean func() (map[string]resource.Resource, error)
+	written   bool
 }
 
-func NewReleaseContext(c cluster.Cluster, m cluster.Manifests, reg registry.Registry, repo *git.Checkout) *ReleaseContext {
+func NewReleaseContext(c cluster.Cluster, m cluster.Manifests, reg registry.Registry, repo *git.Checkout, rewrites image.RewriteRules, gate Gate, promotion *Promotion, pullCheck *PullCheck) *ReleaseContext {
 	return &ReleaseContext{
-		cluster:   c,
-		manifests: m,
-		repo:      repo,
-		registry:  reg,
+		cluster:       c,
+		manifests:     m,
+		repo:          repo,
+		registry:      reg,
+		imageRewrites: rewrites,
+		gate:          gate,
+		promotion:     promotion,
+		pullCheck:     pullCheck,
 	}
 }
 
@@ -35,29 +48,74 @@ func (rc *ReleaseContext) Registry() registry.Registry {
 }
 
 func (rc *ReleaseContext) LoadManifests() (map[string]resource.Resource, error) {
+	if rc.loadClean != nil && !rc.written {
+		return rc.loadClean()
+	}
 	return rc.manifests.LoadManifests(rc.repo.Dir(), rc.repo.ManifestDirs())
 }
 
-func (rc *ReleaseContext) WriteUpdates(updates []*update.ControllerUpdate) error {
-	err := func() error {
-		for _, update := range updates {
-			manifestBytes, err := ioutil.ReadFile(update.ManifestPath)
+// LoadCleanManifestsWith makes the release context load the resources
+// with the function given until it writes any updates, e.g., so they
+// can come from a cache.
+func (rc *ReleaseContext) LoadCleanManifestsWith(load func() (map[string]resource.Resource, error)) {
+	rc.loadClean = load
+}
+
+// RewriteImages applies the image rewrite rules, if any, to the
+// target of each container update, and makes the results report the
+// rewritten images, so that what's reported is what's written.
+func (rc *ReleaseContext) RewriteImages(updates []*update.ControllerUpdate, results update.Result) {
+	if len(rc.imageRewrites) == 0 {
+		return
+	}
+	for _, u := range updates {
+		for i := range u.Updates {
+			u.Updates[i].Target = rc.imageRewrites.Rewrite(u.Updates[i].Target)
+		}
+		if res, ok := results[u.ResourceID]; ok {
+			res.PerContainer = u.Updates
+			results[u.ResourceID] = res
+		}
+	}
+}
+
+// WriteUpdates writes the updates given to the manifests. An update
+// that can't be made, e.g., because the manifest is laid out in a way
+// that can't be updated automatically, is recorded as failed in the
+// results, with the file in question; the updates that were written
+// are returned.
+func (rc *ReleaseContext) WriteUpdates(updates []*update.ControllerUpdate, results update.Result) ([]*update.ControllerUpdate, error) {
+	var written []*update.ControllerUpdate
+	for _, u := range updates {
+		manifestBytes, err := ioutil.ReadFile(u.ManifestPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range u.Updates {
+			manifestBytes, err = rc.manifests.UpdateImage(manifestBytes, u.ResourceID, container.Container, container.Target)
 			if err != nil {
-				return err
+				break
 			}
-			for _, container := range update.Updates {
-				manifestBytes, err = rc.manifests.UpdateImage(manifestBytes, update.ResourceID, container.Container, container.Target)
-				if err != nil {
-					return err
-				}
+		}
+		if err != nil {
+			path, relErr := filepath.Rel(rc.repo.Dir(), u.ManifestPath)
+			if relErr != nil {
+				path = u.ManifestPath
 			}
-			if err = ioutil.WriteFile(update.ManifestPath, manifestBytes, os.FileMode(0600)); err != nil {
-				return err
+			results[u.ResourceID] = update.ControllerResult{
+				Status:       update.ReleaseStatusFailed,
+				Error:        fmt.Sprintf("updating %s: %s", path, err),
+				PerContainer: u.Updates,
 			}
+			continue
+		}
+		rc.written = true
+		if err = ioutil.WriteFile(u.ManifestPath, manifestBytes, os.FileMode(0600)); err != nil {
+			return nil, err
 		}
-		return nil
-	}()
-	return err
+		written = append(written, u)
+	}
+	return written, nil
 }
 
 // ---
@@ -79,6 +137,13 @@ func (rc *ReleaseContext) SelectServices(results update.Result, prefilters, post
 	var toAskClusterAbout []flux.ResourceID
 	for _, s := range allDefined {
 		res := s.Filter(prefilters...)
+		if res.Error == "" && cluster.IsGenerated(s.Resource.Source()) {
+			// There's nowhere to write an update to
+			res = update.ControllerResult{
+				Status: update.ReleaseStatusSkipped,
+				Error:  update.GeneratedManifest,
+			}
+		}
 		if res.Error == "" {
 			// Give these a default value, in case we don't find them
 			// in the cluster.
@@ -0,0 +1,193 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/tracing"
+	"github.com/weaveworks/flux/update"
+)
+
+const (
+	GateAllow = "allow"
+	GateDeny  = "deny"
+)
+
+// GateRequest is what's sent to a release gate: the workload to be
+// released to, and what would change.
+type GateRequest struct {
+	Workload    flux.ResourceID          `json:"workload"`
+	ReleaseType update.ReleaseType       `json:"releaseType"`
+	Containers  []update.ContainerUpdate `json:"containers"`
+}
+
+// GateResponse is what a release gate may respond with. A gate can
+// also just respond with the text `allow` or `deny`; any other
+// response with a 200 OK status is taken to mean the release is
+// allowed.
+type GateResponse struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Gate decides whether a release may go ahead, by consulting the URL
+// given.
+type Gate interface {
+	Check(ctx context.Context, url string, req GateRequest) (allowed bool, reason string, err error)
+}
+
+// HTTPGate POSTs the proposed release, as JSON, to the gate URL. The
+// release is allowed only if the response has the status 200 OK and
+// does not say `deny`.
+//
+// Since gate URLs come from annotations in the git repo, anyone who
+// can push to it could otherwise have fluxd post to whatever it can
+// reach; so gate URLs, and any redirects from them, must be under one
+// of the Allowed URLs, i.e., have the same scheme and host, and a path
+// below it. With none allowed, every gate is refused.
+type HTTPGate struct {
+	Client  *http.Client
+	Allowed []*url.URL
+}
+
+// ParseGateURLs parses the URLs that release gates are allowed to be
+// under, for HTTPGate.Allowed.
+func ParseGateURLs(urls []string) ([]*url.URL, error) {
+	var allowed []*url.URL
+	for _, s := range urls {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing release gate URL %q", s)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("release gate URL %q must be an absolute http or https URL", s)
+		}
+		allowed = append(allowed, u)
+	}
+	return allowed, nil
+}
+
+func (g HTTPGate) allows(u *url.URL) bool {
+	// Servers may resolve `..` in the path themselves, so compare what
+	// it resolves to
+	p := path.Clean("/" + u.Path)
+	for _, a := range g.Allowed {
+		if u.Scheme != a.Scheme || !strings.EqualFold(u.Host, a.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(path.Clean("/"+a.Path), "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (g HTTPGate) Check(ctx context.Context, gateURL string, req GateRequest) (bool, string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return false, "", err
+	}
+	httpReq, err := http.NewRequest("POST", gateURL, bytes.NewReader(body))
+	if err != nil {
+		return false, "", errors.Wrap(err, "constructing release gate request")
+	}
+	if !g.allows(httpReq.URL) {
+		return false, "", fmt.Errorf("release gate %s is not under any of the URLs allowed for gates", gateURL)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, httpReq.Header)
+
+	client := http.DefaultClient
+	if g.Client != nil {
+		client = g.Client
+	}
+	checked := *client
+	checked.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if !g.allows(r.URL) {
+			return fmt.Errorf("redirected to %s, which is not under any of the URLs allowed for gates", r.URL)
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(r, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	resp, err := checked.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return false, "", errors.Wrap(err, "calling release gate")
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", errors.Wrap(err, "reading release gate response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Sprintf("gate responded %s", resp.Status), nil
+	}
+
+	var decision GateResponse
+	if err := json.Unmarshal(respBody, &decision); err != nil {
+		decision.Decision = strings.TrimSpace(string(respBody))
+	}
+	if strings.ToLower(decision.Decision) == GateDeny {
+		return false, decision.Reason, nil
+	}
+	return true, decision.Reason, nil
+}
+
+// GateUpdates asks the release gate of each workload to be updated,
+// if it has one, whether the release may go ahead. Those that are
+// refused are dropped from the updates returned, and marked as
+// skipped in the results.
+func (rc *ReleaseContext) GateUpdates(ctx context.Context, releaseType update.ReleaseType, updates []*update.ControllerUpdate, results update.Result) ([]*update.ControllerUpdate, error) {
+	var allowed []*update.ControllerUpdate
+	for _, u := range updates {
+		var url string
+		if u.Resource != nil {
+			url, _ = u.Resource.Policy().Get(policy.ReleaseGate)
+		}
+		if url == "" {
+			allowed = append(allowed, u)
+			continue
+		}
+		if rc.gate == nil {
+			return nil, fmt.Errorf("%s has a release gate, but no way of checking release gates is configured", u.ResourceID)
+		}
+
+		ok, reason, err := rc.gate.Check(ctx, url, GateRequest{
+			Workload:    u.ResourceID,
+			ReleaseType: releaseType,
+			Containers:  u.Updates,
+		})
+		if err != nil {
+			ok, reason = false, err.Error()
+		}
+		if ok {
+			allowed = append(allowed, u)
+			continue
+		}
+		msg := "denied by release gate"
+		if reason != "" {
+			msg = msg + ": " + reason
+		}
+		results[u.ResourceID] = update.ControllerResult{
+			Status: update.ReleaseStatusSkipped,
+			Error:  msg,
+		}
+	}
+	return allowed, nil
+}
@@ -0,0 +1,168 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/policy"
+	"github.com/weaveworks/flux/resource"
+	"github.com/weaveworks/flux/update"
+)
+
+type gatedWorkload struct {
+	resource.Workload
+	id       flux.ResourceID
+	policies policy.Set
+}
+
+func (w gatedWorkload) ResourceID() flux.ResourceID { return w.id }
+func (w gatedWorkload) Policy() policy.Set          { return w.policies }
+
+func TestHTTPGate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding gate request: %s", err)
+		}
+		switch r.URL.Path {
+		case "/allow":
+			w.Write([]byte("allow"))
+		case "/deny":
+			w.Write([]byte("deny\n"))
+		case "/json":
+			json.NewEncoder(w).Encode(GateResponse{Decision: GateDeny, Reason: "change freeze"})
+		case "/empty":
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	allowed, err := ParseGateURLs([]string{ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := HTTPGate{Client: ts.Client(), Allowed: allowed}
+	for path, expected := range map[string]bool{
+		"/allow":     true,
+		"/empty":     true,
+		"/deny":      false,
+		"/json":      false,
+		"/forbidden": false,
+	} {
+		allowed, reason, err := gate.Check(context.Background(), ts.URL+path, GateRequest{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", path, err)
+		}
+		if allowed != expected {
+			t.Errorf("%s: expected allowed=%v, got %v (reason %q)", path, expected, allowed, reason)
+		}
+	}
+}
+
+func TestGateUpdates(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(GateResponse{Decision: GateDeny, Reason: "change freeze"})
+	}))
+	defer ts.Close()
+
+	gatedID := flux.MustParseResourceID("default:deployment/gated")
+	ungatedID := flux.MustParseResourceID("default:deployment/ungated")
+	ref, _ := image.ParseRef("quay.io/weaveworks/helloworld:master-a000002")
+	containers := []update.ContainerUpdate{{Container: "greeter", Target: ref}}
+	updates := []*update.ControllerUpdate{
+		{
+			ResourceID: gatedID,
+			Resource:   gatedWorkload{id: gatedID, policies: policy.Set{policy.ReleaseGate: ts.URL}},
+			Updates:    containers,
+		},
+		{
+			ResourceID: ungatedID,
+			Resource:   gatedWorkload{id: ungatedID, policies: policy.Set{}},
+			Updates:    containers,
+		},
+	}
+	results := update.Result{
+		gatedID:   {Status: update.ReleaseStatusSuccess, PerContainer: containers},
+		ungatedID: {Status: update.ReleaseStatusSuccess, PerContainer: containers},
+	}
+
+	allowedURLs, err := ParseGateURLs([]string{ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := &ReleaseContext{gate: HTTPGate{Client: ts.Client(), Allowed: allowedURLs}}
+	allowed, err := rc.GateUpdates(context.Background(), "image", updates, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allowed) != 1 || allowed[0].ResourceID != ungatedID {
+		t.Errorf("expected only %s to be allowed, got %v", ungatedID, allowed)
+	}
+	if res := results[gatedID]; res.Status != update.ReleaseStatusSkipped || res.Error != "denied by release gate: change freeze" {
+		t.Errorf("expected %s to be skipped by the gate, got %#v", gatedID, res)
+	}
+
+	rc = &ReleaseContext{}
+	if _, err = rc.GateUpdates(context.Background(), "image", updates, results); err == nil {
+		t.Error("expected error when there's a release gate but no way to check it")
+	}
+}
+
+func TestHTTPGateAllowed(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/gates/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+			return
+		}
+		w.Write([]byte("allow"))
+	}))
+	defer ts.Close()
+
+	if _, err := ParseGateURLs([]string{"/gates"}); err == nil {
+		t.Error("expected a relative URL not to be accepted")
+	}
+	allowed, err := ParseGateURLs([]string{ts.URL + "/gates/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := HTTPGate{Client: ts.Client(), Allowed: allowed}
+	for path, expected := range map[string]bool{
+		"/gates/hello":    true,
+		"/gates":          true,
+		"/gatesx":         false,
+		"/internal":       false,
+		"/gates/../etc":   false,
+		"/gates/redirect": false,
+	} {
+		requested = nil
+		ok, _, err := gate.Check(context.Background(), ts.URL+path, GateRequest{})
+		if ok != expected || (err == nil) != expected {
+			t.Errorf("%s: expected allowed=%v, got %v (error %v)", path, expected, ok, err)
+		}
+		for _, p := range requested {
+			if p == "/internal" || p == "/gatesx" {
+				t.Errorf("%s: expected no request to %s", path, p)
+			}
+		}
+	}
+
+	// With nothing allowed, no gate is
+	if ok, _, err := (HTTPGate{Client: ts.Client()}).Check(context.Background(), ts.URL+"/gates/hello", GateRequest{}); ok || err == nil {
+		t.Error("expected the gate to be refused when no gate URLs are allowed")
+	}
+
+	// The request goes with the release's context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, _, err := gate.Check(ctx, ts.URL+"/gates/hello", GateRequest{}); ok || err == nil {
+		t.Error("expected the check to fail once the release is cancelled")
+	}
+}
@@ -0,0 +1,96 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/supplychain"
+	"github.com/weaveworks/flux/update"
+)
+
+// How long to allow for copying an image, including verifying it.
+const promoteTimeout = 5 * time.Minute
+
+// Promotion copies images to the registry named in the manifests
+// (e.g., a production registry) from the registry they are promoted
+// from (e.g., a staging registry), before they are released; so that
+// the workloads updated never pull from the registry promoted from.
+type Promotion struct {
+	// Map the names of images, as written in manifests, to the names
+	// of the images they are promoted from
+	Sources image.RewriteRules
+	Copier  supplychain.Copier
+	// If set, images must pass verification before being promoted
+	Verifier supplychain.Verifier
+}
+
+// Source gives the image the ref given is promoted from, which is
+// the ref itself if it isn't promoted.
+func (p *Promotion) Source(ref image.Ref) image.Ref {
+	if p == nil {
+		return ref
+	}
+	return p.Sources.Rewrite(ref)
+}
+
+// PromoteImages copies the target of each container update from the
+// registry it's promoted from, if it is. Workloads for which an image
+// can't be promoted are dropped from the updates returned, and marked
+// as failed in the results.
+func (rc *ReleaseContext) PromoteImages(ctx context.Context, updates []*update.ControllerUpdate, results update.Result) []*update.ControllerUpdate {
+	if rc.promotion == nil {
+		return updates
+	}
+	// The same image may be released to several workloads, but only
+	// needs copying once
+	promoted := map[image.Ref]error{}
+	var allowed []*update.ControllerUpdate
+updates:
+	for _, u := range updates {
+		for _, c := range u.Updates {
+			err, done := promoted[c.Target]
+			if !done {
+				err = rc.promote(ctx, c.Target)
+				promoted[c.Target] = err
+			}
+			if err != nil {
+				results[u.ResourceID] = update.ControllerResult{
+					Status:       update.ReleaseStatusFailed,
+					Error:        fmt.Sprintf("promoting %s: %s", c.Target, err),
+					PerContainer: u.Updates,
+				}
+				continue updates
+			}
+		}
+		allowed = append(allowed, u)
+	}
+	return allowed
+}
+
+func (rc *ReleaseContext) promote(ctx context.Context, target image.Ref) error {
+	source := rc.promotion.Source(target)
+	if source == target {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, promoteTimeout)
+	defer cancel()
+
+	// Copy by digest, so that what's verified is what's copied
+	var digest string
+	if rc.registry != nil {
+		if info, err := rc.registry.GetImage(target); err == nil {
+			digest = info.Digest
+		}
+	}
+	if rc.promotion.Verifier != nil {
+		if _, err := rc.promotion.Verifier.Verify(ctx, source, digest); err != nil {
+			return fmt.Errorf("%s failed verification: %s", source, err)
+		}
+	}
+	if err := rc.promotion.Copier.Copy(ctx, source, digest, target); err != nil {
+		return fmt.Errorf("copying from %s: %s", source, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/update"
+)
+
+type recordingCopier struct {
+	copied []string
+	fail   map[string]bool
+}
+
+func (c *recordingCopier) Copy(ctx context.Context, from image.Ref, digest string, to image.Ref) error {
+	if c.fail[from.String()] {
+		return errors.New("unauthorized")
+	}
+	c.copied = append(c.copied, from.String()+" -> "+to.String())
+	return nil
+}
+
+func TestPromoteImages(t *testing.T) {
+	rule, err := image.ParseRewriteRule("staging.example.com/*=prod.example.com/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copier := &recordingCopier{fail: map[string]bool{"staging.example.com/broken:v2": true}}
+	rc := &ReleaseContext{
+		promotion: &Promotion{
+			Sources: image.RewriteRules{rule.Inverse()},
+			Copier:  copier,
+		},
+	}
+
+	target := func(s string) []update.ContainerUpdate {
+		ref, _ := image.ParseRef(s)
+		return []update.ContainerUpdate{{Container: "app", Target: ref}}
+	}
+	frontend := flux.MustParseResourceID("default:deployment/frontend")
+	backend := flux.MustParseResourceID("default:deployment/backend")
+	broken := flux.MustParseResourceID("default:deployment/broken")
+	public := flux.MustParseResourceID("default:deployment/public")
+	updates := []*update.ControllerUpdate{
+		{ResourceID: frontend, Updates: target("prod.example.com/app:v2")},
+		{ResourceID: backend, Updates: target("prod.example.com/app:v2")},
+		{ResourceID: broken, Updates: target("prod.example.com/broken:v2")},
+		{ResourceID: public, Updates: target("nginx:1.15")},
+	}
+	results := update.Result{}
+
+	promoted := rc.PromoteImages(context.Background(), updates, results)
+	if len(promoted) != 3 {
+		t.Fatalf("expected three workloads to be released, got %d", len(promoted))
+	}
+	if len(copier.copied) != 1 || copier.copied[0] != "staging.example.com/app:v2 -> prod.example.com/app:v2" {
+		t.Errorf("expected the image to be copied once from staging to prod, got %v", copier.copied)
+	}
+	if res := results[broken]; res.Status != update.ReleaseStatusFailed {
+		t.Errorf("expected release to %s to fail, got %+v", broken, res)
+	}
+}
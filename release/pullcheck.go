@@ -0,0 +1,84 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry"
+	"github.com/weaveworks/flux/update"
+)
+
+// How long to allow for fetching the manifest of an image to be
+// released.
+const pullCheckTimeout = 30 * time.Second
+
+// PullCheck makes sure the images an automated release would write
+// can be pulled, before the release is committed: that their
+// manifests are still in the registry, can be fetched with the
+// credentials the workloads use, and are for an architecture that
+// can be run. Otherwise, the release would leave the workloads stuck
+// in ImagePullBackOff.
+type PullCheck struct {
+	Clients registry.ClientFactory
+	// The credentials the workloads in the cluster use, by image
+	Credentials func() registry.ImageCreds
+}
+
+// CheckPulls fetches the manifest of the target of each container
+// update, using the credentials for the image. Workloads with an
+// image that can't be pulled are dropped from the updates returned,
+// and marked as failed in the results.
+func (rc *ReleaseContext) CheckPulls(ctx context.Context, updates []*update.ControllerUpdate, results update.Result) []*update.ControllerUpdate {
+	if rc.pullCheck == nil || len(updates) == 0 {
+		return updates
+	}
+	creds := registry.ImageCreds{}
+	if rc.pullCheck.Credentials != nil {
+		creds = rc.pullCheck.Credentials()
+	}
+	// The same image may be released to several workloads, but only
+	// needs checking once
+	checked := map[image.Ref]error{}
+	var pullable []*update.ControllerUpdate
+updates:
+	for _, u := range updates {
+		for _, c := range u.Updates {
+			err, done := checked[c.Target]
+			if !done {
+				err = rc.checkPull(ctx, c.Target, creds[c.Target.Name])
+				checked[c.Target] = err
+			}
+			if err != nil {
+				results[u.ResourceID] = update.ControllerResult{
+					Status:       update.ReleaseStatusFailed,
+					Error:        fmt.Sprintf("image %s can't be pulled: %s", c.Target, err),
+					PerContainer: u.Updates,
+				}
+				continue updates
+			}
+		}
+		pullable = append(pullable, u)
+	}
+	return pullable
+}
+
+func (rc *ReleaseContext) checkPull(ctx context.Context, target image.Ref, creds registry.Credentials) error {
+	ctx, cancel := context.WithTimeout(ctx, pullCheckTimeout)
+	defer cancel()
+
+	client, err := rc.pullCheck.Clients.ClientFor(target.CanonicalName(), creds)
+	if err != nil {
+		return err
+	}
+	entry, err := client.Manifest(ctx, target.Tag)
+	if err != nil {
+		return err
+	}
+	if entry.ExcludedReason != "" {
+		return errors.New(entry.ExcludedReason)
+	}
+	return nil
+}
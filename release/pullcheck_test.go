@@ -0,0 +1,65 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry"
+	"github.com/weaveworks/flux/registry/mock"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestCheckPulls(t *testing.T) {
+	var fetched []string
+	client := &mock.Client{
+		ManifestFn: func(tag string) (registry.ImageEntry, error) {
+			fetched = append(fetched, tag)
+			var entry registry.ImageEntry
+			switch tag {
+			case "deleted":
+				return entry, errors.New("manifest unknown")
+			case "arm":
+				entry.ExcludedReason = "no suitable manifest (linux amd64) in manifestlist"
+			}
+			return entry, nil
+		},
+	}
+	rc := &ReleaseContext{pullCheck: &PullCheck{Clients: &mock.ClientFactory{Client: client}}}
+
+	target := func(s string) []update.ContainerUpdate {
+		ref, _ := image.ParseRef(s)
+		return []update.ContainerUpdate{{Container: "app", Target: ref}}
+	}
+	frontend := flux.MustParseResourceID("default:deployment/frontend")
+	backend := flux.MustParseResourceID("default:deployment/backend")
+	deleted := flux.MustParseResourceID("default:deployment/deleted")
+	arm := flux.MustParseResourceID("default:deployment/arm")
+	updates := []*update.ControllerUpdate{
+		{ResourceID: frontend, Updates: target("example.com/app:v2")},
+		{ResourceID: backend, Updates: target("example.com/app:v2")},
+		{ResourceID: deleted, Updates: target("example.com/app:deleted")},
+		{ResourceID: arm, Updates: target("example.com/app:arm")},
+	}
+	results := update.Result{}
+
+	pullable := rc.CheckPulls(context.Background(), updates, results)
+	if len(pullable) != 2 {
+		t.Fatalf("expected two workloads to be released, got %d", len(pullable))
+	}
+	if len(fetched) != 3 {
+		t.Errorf("expected each image to be checked once, got %v", fetched)
+	}
+	for _, id := range []flux.ResourceID{deleted, arm} {
+		if res := results[id]; res.Status != update.ReleaseStatusFailed {
+			t.Errorf("expected release to %s to fail, got %+v", id, res)
+		}
+	}
+
+	// Without a check, the updates are left alone
+	if got := (&ReleaseContext{}).CheckPulls(context.Background(), updates, update.Result{}); len(got) != len(updates) {
+		t.Errorf("expected all updates without a pull check, got %d", len(got))
+	}
+}
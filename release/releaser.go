@@ -1,6 +1,7 @@
 package release
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
 
+	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/resource"
 	"github.com/weaveworks/flux/update"
 )
@@ -19,7 +21,10 @@ type Changes interface {
 	CommitMessage(update.Result) string
 }
 
-func Release(rc *ReleaseContext, changes Changes, logger log.Logger) (results update.Result, err error) {
+// Release calculates and makes the changes given. The context is that
+// of the release as a whole, and goes with the requests made along
+// the way (e.g., to release gates), so they're cancelled with it.
+func Release(ctx context.Context, rc *ReleaseContext, changes Changes, logger log.Logger) (results update.Result, err error) {
 	defer func(start time.Time) {
 		update.ObserveRelease(
 			start,
@@ -36,8 +41,23 @@ func Release(rc *ReleaseContext, changes Changes, logger log.Logger) (results up
 	if err != nil {
 		return nil, err
 	}
+	rc.RewriteImages(updates, results)
+	// Only ask release gates about releases that will actually happen
+	if changes.ReleaseKind() == update.ReleaseKindExecute {
+		updates, err = rc.GateUpdates(ctx, changes.ReleaseType(), updates, results)
+		if err != nil {
+			return nil, err
+		}
+		// Promote images only once they are sure to be released
+		updates = rc.PromoteImages(ctx, updates, results)
+		// Automated releases have no one watching to notice an image
+		// that can't be pulled, so check before committing them
+		if _, ok := changes.(*update.Automated); ok {
+			updates = rc.CheckPulls(ctx, updates, results)
+		}
+	}
 
-	err = ApplyChanges(rc, updates, logger)
+	updates, err = ApplyChanges(rc, updates, results, logger)
 	if err != nil {
 		return nil, MakeReleaseError(errors.Wrap(err, "applying changes"))
 	}
@@ -54,17 +74,23 @@ func Release(rc *ReleaseContext, changes Changes, logger log.Logger) (results up
 	return results, nil
 }
 
-func ApplyChanges(rc *ReleaseContext, updates []*update.ControllerUpdate, logger log.Logger) error {
+// ApplyChanges writes the updates to the manifests, returning those
+// that were written; any that couldn't be are marked as failed in
+// the results.
+func ApplyChanges(rc *ReleaseContext, updates []*update.ControllerUpdate, results update.Result, logger log.Logger) ([]*update.ControllerUpdate, error) {
 	logger.Log("updates", len(updates))
 	if len(updates) == 0 {
 		logger.Log("exit", "no images to update for services given")
-		return nil
+		return nil, nil
 	}
 
 	timer := update.NewStageTimer("write_changes")
-	err := rc.WriteUpdates(updates)
+	written, err := rc.WriteUpdates(updates, results)
 	timer.ObserveDuration()
-	return err
+	if err == nil && len(written) < len(updates) {
+		logger.Log("updates", len(updates), "failed", len(updates)-len(written))
+	}
+	return written, err
 }
 
 // VerifyChanges checks that the `after` resources are exactly the
@@ -87,8 +113,20 @@ func VerifyChanges(before map[string]resource.Resource, updates []*update.Contro
 		if !ok {
 			return verificationError("resource %q mentioned in update is not a workload", update.ResourceID.String())
 		}
+		current := map[string]string{}
+		for _, c := range wl.Containers() {
+			current[c.Name] = c.Image.String()
+		}
 		for _, containerUpdate := range update.Updates {
-			if err := wl.SetContainerImage(containerUpdate.Container, containerUpdate.Target); err != nil {
+			// The manifest may have a templated prefix on the image,
+			// which is kept when updating
+			target := containerUpdate.Target
+			if written, ok := image.ReplaceKeepingTemplate(current[containerUpdate.Container], target.String()); ok && written != target.String() {
+				if ref, err := image.ParseRef(written); err == nil {
+					target = ref
+				}
+			}
+			if err := wl.SetContainerImage(containerUpdate.Container, target); err != nil {
 				return verificationError("updating container %q in resource %q failed: %s", containerUpdate.Container, update.ResourceID.String(), err.Error())
 			}
 		}
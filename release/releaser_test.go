@@ -1,6 +1,7 @@
 package release
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -751,7 +752,7 @@ func Test_UpdateMultidoc(t *testing.T) {
 		ImageSpec:    update.ImageSpecLatest,
 		Kind:         update.ReleaseKindExecute,
 	}
-	results, err := Release(ctx, spec, log.NewNopLogger())
+	results, err := Release(context.Background(), ctx, spec, log.NewNopLogger())
 	if err != nil {
 		t.Error(err)
 	}
@@ -799,7 +800,7 @@ func Test_UpdateList(t *testing.T) {
 		ImageSpec:    update.ImageSpecLatest,
 		Kind:         update.ReleaseKindExecute,
 	}
-	results, err := Release(ctx, spec, log.NewNopLogger())
+	results, err := Release(context.Background(), ctx, spec, log.NewNopLogger())
 	if err != nil {
 		t.Error(err)
 	}
@@ -1028,7 +1029,7 @@ func Test_UpdateContainers(t *testing.T) {
 				specs.SkipMismatches = ignoreMismatches
 				specs.Force = tst.Force
 
-				results, err := Release(ctx, specs, log.NewNopLogger())
+				results, err := Release(context.Background(), ctx, specs, log.NewNopLogger())
 
 				assert.Equal(t, expected.Err, err)
 				if expected.Err == nil {
@@ -1041,11 +1042,51 @@ func Test_UpdateContainers(t *testing.T) {
 }
 
 func testRelease(t *testing.T, ctx *ReleaseContext, spec update.ReleaseSpec, expected update.Result) {
-	results, err := Release(ctx, spec, log.NewNopLogger())
+	results, err := Release(context.Background(), ctx, spec, log.NewNopLogger())
 	assert.NoError(t, err)
 	assert.Equal(t, expected, results)
 }
 
+func Test_RewriteImages(t *testing.T) {
+	rule, err := image.ParseRewriteRule("quay.io/*=mirror.internal/quay/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirroredRef, _ := image.ParseRef("mirror.internal/quay/weaveworks/helloworld:master-a000002")
+
+	spec := update.ReleaseSpec{
+		ServiceSpecs: []update.ResourceSpec{hwSvcSpec},
+		ImageSpec:    update.ImageSpecFromRef(newHwRef),
+		Kind:         update.ReleaseKindExecute,
+	}
+	expect := expected{
+		Specific: update.Result{
+			hwSvcID: update.ControllerResult{
+				Status: update.ReleaseStatusSuccess,
+				PerContainer: []update.ContainerUpdate{
+					update.ContainerUpdate{
+						Container: helloContainer,
+						Current:   oldRef,
+						Target:    mirroredRef,
+					},
+				},
+			},
+		},
+		Else: ignoredNotIncluded,
+	}
+
+	checkout, clean := setup(t)
+	defer clean()
+
+	testRelease(t, &ReleaseContext{
+		cluster:       mockCluster(hwSvc, lockedSvc),
+		manifests:     mockManifests,
+		registry:      mockRegistry,
+		repo:          checkout,
+		imageRewrites: image.RewriteRules{rule},
+	}, spec, expect.Result())
+}
+
 // --- test verification
 
 // A Manifests implementation that does updates incorrectly, so they should fail verification.
@@ -1074,7 +1115,7 @@ func Test_BadRelease(t *testing.T) {
 		repo:      checkout1,
 		registry:  mockRegistry,
 	}
-	_, err := Release(ctx, spec, log.NewNopLogger())
+	_, err := Release(context.Background(), ctx, spec, log.NewNopLogger())
 	if err != nil {
 		t.Fatal("release with 'good' Manifests should succeed, but errored:", err)
 	}
@@ -1088,7 +1129,7 @@ func Test_BadRelease(t *testing.T) {
 		repo:      checkout2,
 		registry:  mockRegistry,
 	}
-	_, err = Release(ctx, spec, log.NewNopLogger())
+	_, err = Release(context.Background(), ctx, spec, log.NewNopLogger())
 	if err == nil {
 		t.Fatal("did not return an error, but was expected to fail verification")
 	}
@@ -8,8 +8,27 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/update"
 )
@@ -54,6 +73,168 @@ func (p *ErrorLoggingServer) ListServicesWithOptions(ctx context.Context, opts v
 	return p.server.ListServicesWithOptions(ctx, opts)
 }
 
+func (p *ErrorLoggingServer) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) (_ []v12.WorkloadDeployment, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ListDeployments", "error", err)
+		}
+	}()
+	return p.server.ListDeployments(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) ListEvents(ctx context.Context, opts v13.ListEventsOptions) (_ []event.Event, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ListEvents", "error", err)
+		}
+	}()
+	return p.server.ListEvents(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (_ v14.WorkloadCheck, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "CheckWorkload", "error", err)
+		}
+	}()
+	return p.server.CheckWorkload(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) ImageReport(ctx context.Context, opts v15.ImageReportOptions) (_ []v15.ImageUsage, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ImageReport", "error", err)
+		}
+	}()
+	return p.server.ImageReport(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) (_ []v16.WorkloadDelivery, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "DeliveryReport", "error", err)
+		}
+	}()
+	return p.server.DeliveryReport(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) (_ []v17.ImageComparison, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "CompareImages", "error", err)
+		}
+	}()
+	return p.server.CompareImages(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) (_ []byte, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ExportCluster", "error", err)
+		}
+	}()
+	return p.server.ExportCluster(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (_ v19.DeployedState, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "DeployedAt", "error", err)
+		}
+	}()
+	return p.server.DeployedAt(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (_ event.Event, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "AnnotateEvent", "error", err)
+		}
+	}()
+	return p.server.AnnotateEvent(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (_ v21.RevisionDiff, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "DiffRevisions", "error", err)
+		}
+	}()
+	return p.server.DiffRevisions(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (_ v22.EventHistory, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "EventHistory", "error", err)
+		}
+	}()
+	return p.server.EventHistory(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) MetricsSnapshot(ctx context.Context) (_ v23.MetricsSnapshot, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "MetricsSnapshot", "error", err)
+		}
+	}()
+	return p.server.MetricsSnapshot(ctx)
+}
+
+func (p *ErrorLoggingServer) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (_ v24.PruneEventsResult, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "PruneEvents", "error", err)
+		}
+	}()
+	return p.server.PruneEvents(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (_ v25.ReplayEventsResult, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ReplayEvents", "error", err)
+		}
+	}()
+	return p.server.ReplayEvents(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (_ v26.ComplianceReport, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "ComplianceReport", "error", err)
+		}
+	}()
+	return p.server.ComplianceReport(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) LogEvents(ctx context.Context, opts v27.LogEventsOptions) (err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "LogEvents", "error", err)
+		}
+	}()
+	return p.server.LogEvents(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (_ v28.WorkloadHistory, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "WorkloadHistory", "error", err)
+		}
+	}()
+	return p.server.WorkloadHistory(ctx, opts)
+}
+
+func (p *ErrorLoggingServer) JobQueue(ctx context.Context) (_ v29.JobQueue, err error) {
+	defer func() {
+		if err != nil {
+			p.logger.Log("method", "JobQueue", "error", err)
+		}
+	}()
+	return p.server.JobQueue(ctx)
+}
+
 func (p *ErrorLoggingServer) ListImages(ctx context.Context, spec update.ResourceSpec) (_ []v6.ImageStatus, err error) {
 	defer func() {
 		if err != nil {
@@ -11,8 +11,27 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/job"
 	fluxmetrics "github.com/weaveworks/flux/metrics"
 	"github.com/weaveworks/flux/update"
@@ -68,6 +87,186 @@ func (i *instrumentedServer) ListServicesWithOptions(ctx context.Context, opts v
 	return i.s.ListServicesWithOptions(ctx, opts)
 }
 
+func (i *instrumentedServer) ListDeployments(ctx context.Context, opts v12.ListDeploymentsOptions) (_ []v12.WorkloadDeployment, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ListDeployments",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ListDeployments(ctx, opts)
+}
+
+func (i *instrumentedServer) ListEvents(ctx context.Context, opts v13.ListEventsOptions) (_ []event.Event, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ListEvents",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ListEvents(ctx, opts)
+}
+
+func (i *instrumentedServer) CheckWorkload(ctx context.Context, opts v14.CheckWorkloadOptions) (_ v14.WorkloadCheck, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "CheckWorkload",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.CheckWorkload(ctx, opts)
+}
+
+func (i *instrumentedServer) ImageReport(ctx context.Context, opts v15.ImageReportOptions) (_ []v15.ImageUsage, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ImageReport",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ImageReport(ctx, opts)
+}
+
+func (i *instrumentedServer) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) (_ []v16.WorkloadDelivery, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "DeliveryReport",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.DeliveryReport(ctx, opts)
+}
+
+func (i *instrumentedServer) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) (_ []v17.ImageComparison, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "CompareImages",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.CompareImages(ctx, opts)
+}
+
+func (i *instrumentedServer) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) (_ []byte, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ExportCluster",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ExportCluster(ctx, opts)
+}
+
+func (i *instrumentedServer) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (_ v19.DeployedState, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "DeployedAt",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.DeployedAt(ctx, opts)
+}
+
+func (i *instrumentedServer) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (_ event.Event, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "AnnotateEvent",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.AnnotateEvent(ctx, opts)
+}
+
+func (i *instrumentedServer) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (_ v21.RevisionDiff, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "DiffRevisions",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.DiffRevisions(ctx, opts)
+}
+
+func (i *instrumentedServer) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (_ v22.EventHistory, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "EventHistory",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.EventHistory(ctx, opts)
+}
+
+func (i *instrumentedServer) MetricsSnapshot(ctx context.Context) (_ v23.MetricsSnapshot, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "MetricsSnapshot",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.MetricsSnapshot(ctx)
+}
+
+func (i *instrumentedServer) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (_ v24.PruneEventsResult, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "PruneEvents",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.PruneEvents(ctx, opts)
+}
+
+func (i *instrumentedServer) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (_ v25.ReplayEventsResult, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ReplayEvents",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ReplayEvents(ctx, opts)
+}
+
+func (i *instrumentedServer) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (_ v26.ComplianceReport, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "ComplianceReport",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.ComplianceReport(ctx, opts)
+}
+
+func (i *instrumentedServer) LogEvents(ctx context.Context, opts v27.LogEventsOptions) (err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "LogEvents",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.LogEvents(ctx, opts)
+}
+
+func (i *instrumentedServer) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (_ v28.WorkloadHistory, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "WorkloadHistory",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.WorkloadHistory(ctx, opts)
+}
+
+func (i *instrumentedServer) JobQueue(ctx context.Context) (_ v29.JobQueue, err error) {
+	defer func(begin time.Time) {
+		requestDuration.With(
+			fluxmetrics.LabelMethod, "JobQueue",
+			fluxmetrics.LabelSuccess, fmt.Sprint(err == nil),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return i.s.JobQueue(ctx)
+}
+
 func (i *instrumentedServer) ListImages(ctx context.Context, spec update.ResourceSpec) (_ []v6.ImageStatus, err error) {
 	defer func(begin time.Time) {
 		requestDuration.With(
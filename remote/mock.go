@@ -12,8 +12,27 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/guid"
 	"github.com/weaveworks/flux/image"
 	"github.com/weaveworks/flux/job"
@@ -35,6 +54,59 @@ type MockServer struct {
 	ListImagesAnswer []v6.ImageStatus
 	ListImagesError  error
 
+	ListDeploymentsAnswer []v12.WorkloadDeployment
+	ListDeploymentsError  error
+
+	ListEventsAnswer []event.Event
+	ListEventsError  error
+
+	CheckWorkloadAnswer v14.WorkloadCheck
+	CheckWorkloadError  error
+
+	ImageReportAnswer []v15.ImageUsage
+	ImageReportError  error
+
+	DeliveryReportAnswer []v16.WorkloadDelivery
+	DeliveryReportError  error
+
+	CompareImagesAnswer []v17.ImageComparison
+	CompareImagesError  error
+
+	ExportClusterAnswer []byte
+	ExportClusterError  error
+
+	DeployedAtAnswer v19.DeployedState
+	DeployedAtError  error
+
+	AnnotateEventAnswer event.Event
+	AnnotateEventError  error
+
+	DiffRevisionsAnswer v21.RevisionDiff
+	DiffRevisionsError  error
+
+	EventHistoryAnswer v22.EventHistory
+	EventHistoryError  error
+
+	MetricsSnapshotAnswer v23.MetricsSnapshot
+	MetricsSnapshotError  error
+
+	PruneEventsAnswer v24.PruneEventsResult
+	PruneEventsError  error
+
+	ReplayEventsAnswer v25.ReplayEventsResult
+	ReplayEventsError  error
+
+	ComplianceReportAnswer v26.ComplianceReport
+	ComplianceReportError  error
+
+	LogEventsError error
+
+	WorkloadHistoryAnswer v28.WorkloadHistory
+	WorkloadHistoryError  error
+
+	JobQueueAnswer v29.JobQueue
+	JobQueueError  error
+
 	UpdateManifestsArgTest func(update.Spec) error
 	UpdateManifestsAnswer  job.ID
 	UpdateManifestsError   error
@@ -79,6 +151,78 @@ func (p *MockServer) ListImagesWithOptions(context.Context, v10.ListImagesOption
 	return p.ListImagesAnswer, p.ListImagesError
 }
 
+func (p *MockServer) ListDeployments(context.Context, v12.ListDeploymentsOptions) ([]v12.WorkloadDeployment, error) {
+	return p.ListDeploymentsAnswer, p.ListDeploymentsError
+}
+
+func (p *MockServer) ListEvents(context.Context, v13.ListEventsOptions) ([]event.Event, error) {
+	return p.ListEventsAnswer, p.ListEventsError
+}
+
+func (p *MockServer) CheckWorkload(context.Context, v14.CheckWorkloadOptions) (v14.WorkloadCheck, error) {
+	return p.CheckWorkloadAnswer, p.CheckWorkloadError
+}
+
+func (p *MockServer) ImageReport(context.Context, v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	return p.ImageReportAnswer, p.ImageReportError
+}
+
+func (p *MockServer) DeliveryReport(context.Context, v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	return p.DeliveryReportAnswer, p.DeliveryReportError
+}
+
+func (p *MockServer) CompareImages(context.Context, v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	return p.CompareImagesAnswer, p.CompareImagesError
+}
+
+func (p *MockServer) ExportCluster(context.Context, v18.ExportClusterOptions) ([]byte, error) {
+	return p.ExportClusterAnswer, p.ExportClusterError
+}
+
+func (p *MockServer) DeployedAt(context.Context, v19.DeployedAtOptions) (v19.DeployedState, error) {
+	return p.DeployedAtAnswer, p.DeployedAtError
+}
+
+func (p *MockServer) AnnotateEvent(context.Context, v20.AnnotateEventOptions) (event.Event, error) {
+	return p.AnnotateEventAnswer, p.AnnotateEventError
+}
+
+func (p *MockServer) DiffRevisions(context.Context, v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	return p.DiffRevisionsAnswer, p.DiffRevisionsError
+}
+
+func (p *MockServer) EventHistory(context.Context, v22.EventHistoryOptions) (v22.EventHistory, error) {
+	return p.EventHistoryAnswer, p.EventHistoryError
+}
+
+func (p *MockServer) MetricsSnapshot(context.Context) (v23.MetricsSnapshot, error) {
+	return p.MetricsSnapshotAnswer, p.MetricsSnapshotError
+}
+
+func (p *MockServer) PruneEvents(context.Context, v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	return p.PruneEventsAnswer, p.PruneEventsError
+}
+
+func (p *MockServer) ReplayEvents(context.Context, v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	return p.ReplayEventsAnswer, p.ReplayEventsError
+}
+
+func (p *MockServer) ComplianceReport(context.Context, v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	return p.ComplianceReportAnswer, p.ComplianceReportError
+}
+
+func (p *MockServer) LogEvents(context.Context, v27.LogEventsOptions) error {
+	return p.LogEventsError
+}
+
+func (p *MockServer) WorkloadHistory(context.Context, v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	return p.WorkloadHistoryAnswer, p.WorkloadHistoryError
+}
+
+func (p *MockServer) JobQueue(context.Context) (v29.JobQueue, error) {
+	return p.JobQueueAnswer, p.JobQueueError
+}
+
 func (p *MockServer) UpdateManifests(ctx context.Context, s update.Spec) (job.ID, error) {
 	if p.UpdateManifestsArgTest != nil {
 		if err := p.UpdateManifestsArgTest(s); err != nil {
@@ -152,6 +296,93 @@ func ServerTestBattery(t *testing.T, wrap func(mock api.UpstreamServer) api.Upst
 		},
 	}
 
+	deploymentsAnswer := []v12.WorkloadDeployment{
+		{
+			ID:     flux.MustParseResourceID("foobar/hello"),
+			Status: "ready",
+			Containers: []v12.DeployedContainer{
+				{Name: "frobnicator", Image: imageID.String()},
+			},
+			Revision: "1234567",
+		},
+	}
+
+	eventsAnswer := []event.Event{
+		{
+			ID:         12,
+			ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("foobar/hello")},
+			Type:       event.EventLock,
+			LogLevel:   event.LogLevelInfo,
+		},
+	}
+
+	annotateEventAnswer := event.Event{
+		ID:         12,
+		ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("foobar/hello")},
+		Type:       event.EventLock,
+		LogLevel:   event.LogLevelInfo,
+		Annotations: []event.Annotation{
+			{Time: time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC), User: "jane", Comment: "maintenance", Acknowledged: true},
+		},
+	}
+
+	checkAnswer := v14.WorkloadCheck{
+		ID:       flux.MustParseResourceID("foobar/hello"),
+		Problems: []string{"no manifest for the workload was found in the git repo"},
+	}
+
+	imageReportAnswer := []v15.ImageUsage{
+		{
+			Image:      "quay.io/example/hello:1.0",
+			Repository: "quay.io/example/hello",
+			Tag:        "1.0",
+			Pattern:    "*",
+			Latest:     "1.2",
+			Behind:     2,
+			UsedBy: []v15.ImageUser{
+				{Workload: flux.MustParseResourceID("foobar/hello"), Container: "hello"},
+			},
+		},
+	}
+
+	lastDeployed := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	deliveryReportAnswer := []v16.WorkloadDelivery{
+		{
+			Workload:              flux.MustParseResourceID("foobar/hello"),
+			Deploys:               14,
+			DeployFrequency:       2,
+			MedianLeadTimeSeconds: 3600,
+			Rollbacks:             1,
+			ChangeFailureRate:     1.0 / 14,
+			LastDeployed:          &lastDeployed,
+		},
+	}
+
+	compareImagesAnswer := []v17.ImageComparison{
+		{
+			Workload:  flux.MustParseResourceID("foobar/hello"),
+			Container: "frobnicator",
+			Desired:   "quay.io/example/frobnicator:v2",
+			Running:   "quay.io/example/frobnicator:v1",
+			Synced:    "quay.io/example/frobnicator:v1",
+			Status:    v17.ImagePendingSync,
+		},
+	}
+
+	deployedAtAnswer := v19.DeployedState{
+		At:       time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC),
+		Revision: "3a5b4c1d9e0f2a7b68c4e1d3f5a7b9c0d2e4f6a8",
+		SyncedAt: time.Date(2026, 10, 14, 3, 5, 10, 0, time.UTC),
+		Workloads: []v19.DeployedWorkload{
+			{
+				ID: flux.MustParseResourceID("foobar/hello"),
+				Containers: []v19.DeployedContainer{
+					{Name: "frobnicator", Image: "quay.io/example/frobnicator:v1"},
+				},
+			},
+		},
+	}
+
 	syncStatusAnswer := []string{
 		"commit 1",
 		"commit 2",
@@ -167,6 +398,73 @@ func ServerTestBattery(t *testing.T, wrap func(mock api.UpstreamServer) api.Upst
 			ImageSpec: update.ImageSpecLatest,
 		},
 	}
+	diffRevisionsAnswer := v21.RevisionDiff{
+		From: "3a5b4c1d9e0f2a7b68c4e1d3f5a7b9c0d2e4f6a8",
+		To:   "9c0d2e4f6a83a5b4c1d9e0f2a7b68c4e1d3f5a7b",
+		Changes: []v21.ResourceChange{
+			{
+				ID:     flux.MustParseResourceID("foobar/hello"),
+				Status: v21.ResourceChanged,
+				Images: []v21.ImageChange{
+					{Container: "frobnicator", From: "quay.io/example/frobnicator:v1", To: "quay.io/example/frobnicator:v2"},
+				},
+			},
+		},
+	}
+	eventHistoryAnswer := v22.EventHistory{
+		Events: []event.Event{
+			{ID: 41, Type: event.EventLock, ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("foobar/hello")}, LogLevel: event.LogLevelInfo},
+		},
+		Older: 41,
+	}
+	metricsSnapshotAnswer := v23.MetricsSnapshot{
+		Time: time.Date(2026, 10, 14, 3, 12, 0, 0, time.UTC),
+		Metrics: []v23.Metric{
+			{Name: "flux_daemon_queue_length_count", Type: v23.MetricGauge, Value: 2},
+			{Name: "flux_daemon_sync_duration_seconds", Type: v23.MetricHistogram, Labels: map[string]string{"success": "true"}, Value: 42.5, Count: 3},
+		},
+	}
+	pruneEventsAnswer := v24.PruneEventsResult{
+		Policy: event.RetentionPolicy{MaxAge: 720 * time.Hour, MaxPerService: 50},
+		Pruned: 12,
+	}
+	replayEventsAnswer := v25.ReplayEventsResult{
+		Events: []event.Event{
+			{ID: 43, Type: event.EventLock, ServiceIDs: []flux.ResourceID{flux.MustParseResourceID("foobar/hello")}, LogLevel: event.LogLevelInfo},
+		},
+		Cursor: event.MakeCursor("alerts", 43),
+		More:   true,
+	}
+	complianceReportAnswer := v26.ComplianceReport{
+		Since:       time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC),
+		Until:       time.Date(2018, 9, 8, 0, 0, 0, 0, time.UTC),
+		GeneratedAt: time.Date(2018, 9, 8, 0, 0, 0, 0, time.UTC),
+		Releases: []v26.ReportedEvent{
+			{EventID: 44, Type: event.EventRelease, Time: time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC), Workloads: []flux.ResourceID{flux.MustParseResourceID("foobar/hello")}, User: "jane", Message: "Released: quay.io/example/hello:1.1 to foobar/hello"},
+		},
+		Approvals: []v26.ReportedApproval{
+			{EventID: 44, Time: time.Date(2018, 9, 3, 11, 0, 0, 0, time.UTC), User: "sam", Comment: "change 1234"},
+		},
+		Signature: "abc123",
+	}
+	lockEnded := time.Date(2018, 9, 4, 9, 0, 0, 0, time.UTC)
+	workloadHistoryAnswer := v28.WorkloadHistory{
+		Workload: flux.MustParseResourceID("foobar:deployment/hello"),
+		Entries: []v28.WorkloadHistoryEntry{
+			{Kind: v28.HistoryImage, EventID: 44, Start: time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC), Container: "greeter", From: "quay.io/example/hello:1.0", To: "quay.io/example/hello:1.1", User: "jane"},
+			{Kind: v28.HistoryLock, EventID: 45, Start: time.Date(2018, 9, 3, 11, 0, 0, 0, time.UTC), End: &lockEnded, Message: "Locked: foobar:deployment/hello"},
+		},
+	}
+	jobStarted := time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC)
+	jobQueueAnswer := v29.JobQueue{
+		MaxRunning: 1,
+		Priorities: map[string]int{"auto": -1},
+		Jobs: []job.QueuedJob{
+			{ID: "6ed8bd5e", Kind: "image", Workloads: []flux.ResourceID{flux.MustParseResourceID("foobar:deployment/hello")}, QueuedAt: time.Date(2018, 9, 3, 9, 59, 0, 0, time.UTC), StartedAt: &jobStarted},
+			{ID: "a6b1c2d3", Kind: "auto", Priority: -1, QueuedAt: time.Date(2018, 9, 3, 9, 58, 0, 0, time.UTC)},
+		},
+	}
+
 	checkUpdateSpec := func(s update.Spec) error {
 		if !reflect.DeepEqual(updateSpec, s) {
 			return errors.New("expected != actual")
@@ -177,6 +475,23 @@ func ServerTestBattery(t *testing.T, wrap func(mock api.UpstreamServer) api.Upst
 	mock := &MockServer{
 		ListServicesAnswer:     serviceAnswer,
 		ListImagesAnswer:       imagesAnswer,
+		ListDeploymentsAnswer:  deploymentsAnswer,
+		ListEventsAnswer:       eventsAnswer,
+		CheckWorkloadAnswer:    checkAnswer,
+		ImageReportAnswer:      imageReportAnswer,
+		DeliveryReportAnswer:   deliveryReportAnswer,
+		CompareImagesAnswer:    compareImagesAnswer,
+		ExportClusterAnswer:    []byte("---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: foobar\n"),
+		DeployedAtAnswer:       deployedAtAnswer,
+		AnnotateEventAnswer:    annotateEventAnswer,
+		DiffRevisionsAnswer:    diffRevisionsAnswer,
+		EventHistoryAnswer:     eventHistoryAnswer,
+		MetricsSnapshotAnswer:  metricsSnapshotAnswer,
+		PruneEventsAnswer:      pruneEventsAnswer,
+		ReplayEventsAnswer:     replayEventsAnswer,
+		ComplianceReportAnswer: complianceReportAnswer,
+		WorkloadHistoryAnswer:  workloadHistoryAnswer,
+		JobQueueAnswer:         jobQueueAnswer,
 		UpdateManifestsArgTest: checkUpdateSpec,
 		UpdateManifestsAnswer:  job.ID(guid.New()),
 		SyncStatusAnswer:       syncStatusAnswer,
@@ -220,6 +535,229 @@ func ServerTestBattery(t *testing.T, wrap func(mock api.UpstreamServer) api.Upst
 		t.Error("expected error from ListImages, got nil")
 	}
 
+	ds, err := client.ListDeployments(ctx, v12.ListDeploymentsOptions{Namespace: namespace})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(ds, mock.ListDeploymentsAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.ListDeploymentsAnswer, ds))
+	}
+	mock.ListDeploymentsError = fmt.Errorf("list deployments error")
+	if _, err = client.ListDeployments(ctx, v12.ListDeploymentsOptions{}); err == nil {
+		t.Error("expected error from ListDeployments, got nil")
+	}
+
+	evs, err := client.ListEvents(ctx, v13.ListEventsOptions{After: 11})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(evs, mock.ListEventsAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.ListEventsAnswer, evs))
+	}
+	mock.ListEventsError = fmt.Errorf("list events error")
+	if _, err = client.ListEvents(ctx, v13.ListEventsOptions{}); err == nil {
+		t.Error("expected error from ListEvents, got nil")
+	}
+
+	check, err := client.CheckWorkload(ctx, v14.CheckWorkloadOptions{Workload: checkAnswer.ID})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(check, mock.CheckWorkloadAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.CheckWorkloadAnswer, check))
+	}
+	mock.CheckWorkloadError = fmt.Errorf("check workload error")
+	if _, err = client.CheckWorkload(ctx, v14.CheckWorkloadOptions{Workload: checkAnswer.ID}); err == nil {
+		t.Error("expected error from CheckWorkload, got nil")
+	}
+
+	report, err := client.ImageReport(ctx, v15.ImageReportOptions{Namespace: "foobar"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(report, mock.ImageReportAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.ImageReportAnswer, report))
+	}
+	mock.ImageReportError = fmt.Errorf("image report error")
+	if _, err = client.ImageReport(ctx, v15.ImageReportOptions{}); err == nil {
+		t.Error("expected error from ImageReport, got nil")
+	}
+
+	delivery, err := client.DeliveryReport(ctx, v16.DeliveryReportOptions{Namespace: "foobar", Period: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(delivery, mock.DeliveryReportAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.DeliveryReportAnswer, delivery))
+	}
+	mock.DeliveryReportError = fmt.Errorf("delivery report error")
+	if _, err = client.DeliveryReport(ctx, v16.DeliveryReportOptions{}); err == nil {
+		t.Error("expected error from DeliveryReport, got nil")
+	}
+
+	compared, err := client.CompareImages(ctx, v17.CompareImagesOptions{Namespace: "foobar"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(compared, mock.CompareImagesAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.CompareImagesAnswer, compared))
+	}
+	mock.CompareImagesError = fmt.Errorf("compare images error")
+	if _, err = client.CompareImages(ctx, v17.CompareImagesOptions{}); err == nil {
+		t.Error("expected error from CompareImages, got nil")
+	}
+
+	exported, err := client.ExportCluster(ctx, v18.ExportClusterOptions{Namespaces: []string{"foobar"}, Kinds: []string{"namespace"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(exported, mock.ExportClusterAnswer) {
+		t.Error(fmt.Errorf("expected:\n%q\ngot:\n%q", mock.ExportClusterAnswer, exported))
+	}
+	mock.ExportClusterError = fmt.Errorf("export cluster error")
+	if _, err = client.ExportCluster(ctx, v18.ExportClusterOptions{}); err == nil {
+		t.Error("expected error from ExportCluster, got nil")
+	}
+
+	deployed, err := client.DeployedAt(ctx, v19.DeployedAtOptions{At: deployedAtAnswer.At, Namespace: "foobar"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(deployed, mock.DeployedAtAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.DeployedAtAnswer, deployed))
+	}
+	mock.DeployedAtError = fmt.Errorf("deployed at error")
+	if _, err = client.DeployedAt(ctx, v19.DeployedAtOptions{}); err == nil {
+		t.Error("expected error from DeployedAt, got nil")
+	}
+
+	annotated, err := client.AnnotateEvent(ctx, v20.AnnotateEventOptions{ID: 12, Comment: "maintenance", Acknowledge: true, User: "jane"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(annotated, mock.AnnotateEventAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.AnnotateEventAnswer, annotated))
+	}
+	mock.AnnotateEventError = fmt.Errorf("annotate event error")
+	if _, err = client.AnnotateEvent(ctx, v20.AnnotateEventOptions{ID: 12}); err == nil {
+		t.Error("expected error from AnnotateEvent, got nil")
+	}
+
+	diff, err := client.DiffRevisions(ctx, v21.DiffRevisionsOptions{From: "3a5b4c1", To: "9c0d2e4", Namespace: "foobar"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(diff, mock.DiffRevisionsAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.DiffRevisionsAnswer, diff))
+	}
+	mock.DiffRevisionsError = fmt.Errorf("diff revisions error")
+	if _, err = client.DiffRevisions(ctx, v21.DiffRevisionsOptions{}); err == nil {
+		t.Error("expected error from DiffRevisions, got nil")
+	}
+
+	workload := flux.MustParseResourceID("foobar/hello")
+	history, err := client.EventHistory(ctx, v22.EventHistoryOptions{
+		Workload: &workload,
+		Before:   42,
+		Limit:    1,
+		Filter: event.EventFilter{
+			Types:       []string{event.EventRelease, event.EventAutoRelease},
+			Since:       time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC),
+			MinLogLevel: event.LogLevelWarn,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(history, mock.EventHistoryAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.EventHistoryAnswer, history))
+	}
+	mock.EventHistoryError = fmt.Errorf("event history error")
+	if _, err = client.EventHistory(ctx, v22.EventHistoryOptions{}); err == nil {
+		t.Error("expected error from EventHistory, got nil")
+	}
+
+	snapshot, err := client.MetricsSnapshot(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(snapshot, mock.MetricsSnapshotAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.MetricsSnapshotAnswer, snapshot))
+	}
+	mock.MetricsSnapshotError = fmt.Errorf("metrics snapshot error")
+	if _, err = client.MetricsSnapshot(ctx); err == nil {
+		t.Error("expected error from MetricsSnapshot, got nil")
+	}
+
+	pruned, err := client.PruneEvents(ctx, v24.PruneEventsOptions{Policy: event.RetentionPolicy{MaxPerService: 50}})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(pruned, mock.PruneEventsAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.PruneEventsAnswer, pruned))
+	}
+	mock.PruneEventsError = fmt.Errorf("prune events error")
+	if _, err = client.PruneEvents(ctx, v24.PruneEventsOptions{}); err == nil {
+		t.Error("expected error from PruneEvents, got nil")
+	}
+
+	replayed, err := client.ReplayEvents(ctx, v25.ReplayEventsOptions{Cursor: event.MakeCursor("alerts", 0), Limit: 10})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(replayed, mock.ReplayEventsAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.ReplayEventsAnswer, replayed))
+	}
+	mock.ReplayEventsError = fmt.Errorf("replay events error")
+	if _, err = client.ReplayEvents(ctx, v25.ReplayEventsOptions{}); err == nil {
+		t.Error("expected error from ReplayEvents, got nil")
+	}
+
+	compliance, err := client.ComplianceReport(ctx, v26.ComplianceReportOptions{Since: time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(compliance, mock.ComplianceReportAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.ComplianceReportAnswer, compliance))
+	}
+	mock.ComplianceReportError = fmt.Errorf("compliance report error")
+	if _, err = client.ComplianceReport(ctx, v26.ComplianceReportOptions{}); err == nil {
+		t.Error("expected error from ComplianceReport, got nil")
+	}
+
+	batch := v27.LogEventsOptions{Events: []event.Event{{Type: event.EventSync, Metadata: &event.SyncEventMetadata{}}}}
+	if err := client.LogEvents(ctx, batch); err != nil {
+		t.Error(err)
+	}
+	mock.LogEventsError = fmt.Errorf("log events error")
+	if err := client.LogEvents(ctx, batch); err == nil {
+		t.Error("expected error from LogEvents, got nil")
+	}
+
+	workloadHistory, err := client.WorkloadHistory(ctx, v28.WorkloadHistoryOptions{Workload: flux.MustParseResourceID("foobar:deployment/hello")})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(workloadHistory, mock.WorkloadHistoryAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.WorkloadHistoryAnswer, workloadHistory))
+	}
+	mock.WorkloadHistoryError = fmt.Errorf("workload history error")
+	if _, err = client.WorkloadHistory(ctx, v28.WorkloadHistoryOptions{Workload: flux.MustParseResourceID("foobar:deployment/hello")}); err == nil {
+		t.Error("expected error from WorkloadHistory, got nil")
+	}
+
+	queue, err := client.JobQueue(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(queue, mock.JobQueueAnswer) {
+		t.Error(fmt.Errorf("expected:\n%#v\ngot:\n%#v", mock.JobQueueAnswer, queue))
+	}
+	mock.JobQueueError = fmt.Errorf("job queue error")
+	if _, err = client.JobQueue(ctx); err == nil {
+		t.Error("expected error from JobQueue, got nil")
+	}
+
 	jobid, err := mock.UpdateManifests(ctx, updateSpec)
 	if err != nil {
 		t.Error(err)
@@ -8,8 +8,27 @@ import (
 	"github.com/weaveworks/flux/api"
 	"github.com/weaveworks/flux/api/v10"
 	"github.com/weaveworks/flux/api/v11"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/remote"
 	"github.com/weaveworks/flux/update"
@@ -39,6 +58,78 @@ func (bc baseClient) ListServicesWithOptions(context.Context, v11.ListServicesOp
 	return nil, remote.UpgradeNeededError(errors.New("ListServicesWithOptions method not implemented"))
 }
 
+func (bc baseClient) ListDeployments(context.Context, v12.ListDeploymentsOptions) ([]v12.WorkloadDeployment, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ListDeployments method not implemented"))
+}
+
+func (bc baseClient) ListEvents(context.Context, v13.ListEventsOptions) ([]event.Event, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ListEvents method not implemented"))
+}
+
+func (bc baseClient) CheckWorkload(context.Context, v14.CheckWorkloadOptions) (v14.WorkloadCheck, error) {
+	return v14.WorkloadCheck{}, remote.UpgradeNeededError(errors.New("CheckWorkload method not implemented"))
+}
+
+func (bc baseClient) ImageReport(context.Context, v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ImageReport method not implemented"))
+}
+
+func (bc baseClient) DeliveryReport(context.Context, v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	return nil, remote.UpgradeNeededError(errors.New("DeliveryReport method not implemented"))
+}
+
+func (bc baseClient) CompareImages(context.Context, v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	return nil, remote.UpgradeNeededError(errors.New("CompareImages method not implemented"))
+}
+
+func (bc baseClient) ExportCluster(context.Context, v18.ExportClusterOptions) ([]byte, error) {
+	return nil, remote.UpgradeNeededError(errors.New("ExportCluster method not implemented"))
+}
+
+func (bc baseClient) DeployedAt(context.Context, v19.DeployedAtOptions) (v19.DeployedState, error) {
+	return v19.DeployedState{}, remote.UpgradeNeededError(errors.New("DeployedAt method not implemented"))
+}
+
+func (bc baseClient) AnnotateEvent(context.Context, v20.AnnotateEventOptions) (event.Event, error) {
+	return event.Event{}, remote.UpgradeNeededError(errors.New("AnnotateEvent method not implemented"))
+}
+
+func (bc baseClient) DiffRevisions(context.Context, v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	return v21.RevisionDiff{}, remote.UpgradeNeededError(errors.New("DiffRevisions method not implemented"))
+}
+
+func (bc baseClient) EventHistory(context.Context, v22.EventHistoryOptions) (v22.EventHistory, error) {
+	return v22.EventHistory{}, remote.UpgradeNeededError(errors.New("EventHistory method not implemented"))
+}
+
+func (bc baseClient) MetricsSnapshot(context.Context) (v23.MetricsSnapshot, error) {
+	return v23.MetricsSnapshot{}, remote.UpgradeNeededError(errors.New("MetricsSnapshot method not implemented"))
+}
+
+func (bc baseClient) PruneEvents(context.Context, v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	return v24.PruneEventsResult{}, remote.UpgradeNeededError(errors.New("PruneEvents method not implemented"))
+}
+
+func (bc baseClient) ReplayEvents(context.Context, v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	return v25.ReplayEventsResult{}, remote.UpgradeNeededError(errors.New("ReplayEvents method not implemented"))
+}
+
+func (bc baseClient) ComplianceReport(context.Context, v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	return v26.ComplianceReport{}, remote.UpgradeNeededError(errors.New("ComplianceReport method not implemented"))
+}
+
+func (bc baseClient) LogEvents(context.Context, v27.LogEventsOptions) error {
+	return remote.UpgradeNeededError(errors.New("LogEvents method not implemented"))
+}
+
+func (bc baseClient) WorkloadHistory(context.Context, v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	return v28.WorkloadHistory{}, remote.UpgradeNeededError(errors.New("WorkloadHistory method not implemented"))
+}
+
+func (bc baseClient) JobQueue(context.Context) (v29.JobQueue, error) {
+	return v29.JobQueue{}, remote.UpgradeNeededError(errors.New("JobQueue method not implemented"))
+}
+
 func (bc baseClient) ListImages(context.Context, update.ResourceSpec) ([]v6.ImageStatus, error) {
 	return nil, remote.UpgradeNeededError(errors.New("ListImages method not implemented"))
 }
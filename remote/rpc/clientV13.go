@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV13 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces ListEvents.
+type RPCClientV13 struct {
+	*RPCClientV12
+}
+
+type clientV13 interface {
+	v13.Server
+	v13.Upstream
+}
+
+var _ clientV13 = &RPCClientV13{}
+
+// NewClientV13 creates a new rpc-backed implementation of the server.
+func NewClientV13(conn io.ReadWriteCloser) *RPCClientV13 {
+	return &RPCClientV13{NewClientV12(conn)}
+}
+
+func (p *RPCClientV13) ListEvents(ctx context.Context, opts v13.ListEventsOptions) ([]event.Event, error) {
+	var resp ListEventsResponse
+	err := p.client.Call("RPCServer.ListEvents", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV15 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces ImageReport.
+type RPCClientV15 struct {
+	*RPCClientV14
+}
+
+type clientV15 interface {
+	v15.Server
+	v15.Upstream
+}
+
+var _ clientV15 = &RPCClientV15{}
+
+// NewClientV15 creates a new rpc-backed implementation of the server.
+func NewClientV15(conn io.ReadWriteCloser) *RPCClientV15 {
+	return &RPCClientV15{NewClientV14(conn)}
+}
+
+func (p *RPCClientV15) ImageReport(ctx context.Context, opts v15.ImageReportOptions) ([]v15.ImageUsage, error) {
+	var resp ImageReportResponse
+	err := p.client.Call("RPCServer.ImageReport", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
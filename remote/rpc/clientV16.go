@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV16 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces DeliveryReport.
+type RPCClientV16 struct {
+	*RPCClientV15
+}
+
+type clientV16 interface {
+	v16.Server
+	v16.Upstream
+}
+
+var _ clientV16 = &RPCClientV16{}
+
+// NewClientV16 creates a new rpc-backed implementation of the server.
+func NewClientV16(conn io.ReadWriteCloser) *RPCClientV16 {
+	return &RPCClientV16{NewClientV15(conn)}
+}
+
+func (p *RPCClientV16) DeliveryReport(ctx context.Context, opts v16.DeliveryReportOptions) ([]v16.WorkloadDelivery, error) {
+	var resp DeliveryReportResponse
+	err := p.client.Call("RPCServer.DeliveryReport", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV17 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces CompareImages.
+type RPCClientV17 struct {
+	*RPCClientV16
+}
+
+type clientV17 interface {
+	v17.Server
+	v17.Upstream
+}
+
+var _ clientV17 = &RPCClientV17{}
+
+// NewClientV17 creates a new rpc-backed implementation of the server.
+func NewClientV17(conn io.ReadWriteCloser) *RPCClientV17 {
+	return &RPCClientV17{NewClientV16(conn)}
+}
+
+func (p *RPCClientV17) CompareImages(ctx context.Context, opts v17.CompareImagesOptions) ([]v17.ImageComparison, error) {
+	var resp CompareImagesResponse
+	err := p.client.Call("RPCServer.CompareImages", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
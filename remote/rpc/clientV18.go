@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV18 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces ExportCluster.
+type RPCClientV18 struct {
+	*RPCClientV17
+}
+
+type clientV18 interface {
+	v18.Server
+	v18.Upstream
+}
+
+var _ clientV18 = &RPCClientV18{}
+
+// NewClientV18 creates a new rpc-backed implementation of the server.
+func NewClientV18(conn io.ReadWriteCloser) *RPCClientV18 {
+	return &RPCClientV18{NewClientV17(conn)}
+}
+
+func (p *RPCClientV18) ExportCluster(ctx context.Context, opts v18.ExportClusterOptions) ([]byte, error) {
+	var resp ExportClusterResponse
+	err := p.client.Call("RPCServer.ExportCluster", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV19 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces DeployedAt.
+type RPCClientV19 struct {
+	*RPCClientV18
+}
+
+type clientV19 interface {
+	v19.Server
+	v19.Upstream
+}
+
+var _ clientV19 = &RPCClientV19{}
+
+// NewClientV19 creates a new rpc-backed implementation of the server.
+func NewClientV19(conn io.ReadWriteCloser) *RPCClientV19 {
+	return &RPCClientV19{NewClientV18(conn)}
+}
+
+func (p *RPCClientV19) DeployedAt(ctx context.Context, opts v19.DeployedAtOptions) (v19.DeployedState, error) {
+	var resp DeployedAtResponse
+	err := p.client.Call("RPCServer.DeployedAt", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV20 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces AnnotateEvent.
+type RPCClientV20 struct {
+	*RPCClientV19
+}
+
+type clientV20 interface {
+	v20.Server
+	v20.Upstream
+}
+
+var _ clientV20 = &RPCClientV20{}
+
+// NewClientV20 creates a new rpc-backed implementation of the server.
+func NewClientV20(conn io.ReadWriteCloser) *RPCClientV20 {
+	return &RPCClientV20{NewClientV19(conn)}
+}
+
+func (p *RPCClientV20) AnnotateEvent(ctx context.Context, opts v20.AnnotateEventOptions) (event.Event, error) {
+	var resp AnnotateEventResponse
+	err := p.client.Call("RPCServer.AnnotateEvent", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV21 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces DiffRevisions.
+type RPCClientV21 struct {
+	*RPCClientV20
+}
+
+type clientV21 interface {
+	v21.Server
+	v21.Upstream
+}
+
+var _ clientV21 = &RPCClientV21{}
+
+// NewClientV21 creates a new rpc-backed implementation of the server.
+func NewClientV21(conn io.ReadWriteCloser) *RPCClientV21 {
+	return &RPCClientV21{NewClientV20(conn)}
+}
+
+func (p *RPCClientV21) DiffRevisions(ctx context.Context, opts v21.DiffRevisionsOptions) (v21.RevisionDiff, error) {
+	var resp DiffRevisionsResponse
+	err := p.client.Call("RPCServer.DiffRevisions", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV22 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces EventHistory.
+type RPCClientV22 struct {
+	*RPCClientV21
+}
+
+type clientV22 interface {
+	v22.Server
+	v22.Upstream
+}
+
+var _ clientV22 = &RPCClientV22{}
+
+// NewClientV22 creates a new rpc-backed implementation of the server.
+func NewClientV22(conn io.ReadWriteCloser) *RPCClientV22 {
+	return &RPCClientV22{NewClientV21(conn)}
+}
+
+func (p *RPCClientV22) EventHistory(ctx context.Context, opts v22.EventHistoryOptions) (v22.EventHistory, error) {
+	var resp EventHistoryResponse
+	err := p.client.Call("RPCServer.EventHistory", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV23 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces MetricsSnapshot.
+type RPCClientV23 struct {
+	*RPCClientV22
+}
+
+type clientV23 interface {
+	v23.Server
+	v23.Upstream
+}
+
+var _ clientV23 = &RPCClientV23{}
+
+// NewClientV23 creates a new rpc-backed implementation of the server.
+func NewClientV23(conn io.ReadWriteCloser) *RPCClientV23 {
+	return &RPCClientV23{NewClientV22(conn)}
+}
+
+func (p *RPCClientV23) MetricsSnapshot(ctx context.Context) (v23.MetricsSnapshot, error) {
+	var resp MetricsSnapshotResponse
+	err := p.client.Call("RPCServer.MetricsSnapshot", struct{}{}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV24 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces PruneEvents.
+type RPCClientV24 struct {
+	*RPCClientV23
+}
+
+type clientV24 interface {
+	v24.Server
+	v24.Upstream
+}
+
+var _ clientV24 = &RPCClientV24{}
+
+// NewClientV24 creates a new rpc-backed implementation of the server.
+func NewClientV24(conn io.ReadWriteCloser) *RPCClientV24 {
+	return &RPCClientV24{NewClientV23(conn)}
+}
+
+func (p *RPCClientV24) PruneEvents(ctx context.Context, opts v24.PruneEventsOptions) (v24.PruneEventsResult, error) {
+	var resp PruneEventsResponse
+	err := p.client.Call("RPCServer.PruneEvents", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
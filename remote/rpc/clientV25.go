@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV25 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces ReplayEvents.
+type RPCClientV25 struct {
+	*RPCClientV24
+}
+
+type clientV25 interface {
+	v25.Server
+	v25.Upstream
+}
+
+var _ clientV25 = &RPCClientV25{}
+
+// NewClientV25 creates a new rpc-backed implementation of the server.
+func NewClientV25(conn io.ReadWriteCloser) *RPCClientV25 {
+	return &RPCClientV25{NewClientV24(conn)}
+}
+
+func (p *RPCClientV25) ReplayEvents(ctx context.Context, opts v25.ReplayEventsOptions) (v25.ReplayEventsResult, error) {
+	var resp ReplayEventsResponse
+	err := p.client.Call("RPCServer.ReplayEvents", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
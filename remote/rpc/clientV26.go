@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV26 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces ComplianceReport.
+type RPCClientV26 struct {
+	*RPCClientV25
+}
+
+type clientV26 interface {
+	v26.Server
+	v26.Upstream
+}
+
+var _ clientV26 = &RPCClientV26{}
+
+// NewClientV26 creates a new rpc-backed implementation of the server.
+func NewClientV26(conn io.ReadWriteCloser) *RPCClientV26 {
+	return &RPCClientV26{NewClientV25(conn)}
+}
+
+func (p *RPCClientV26) ComplianceReport(ctx context.Context, opts v26.ComplianceReportOptions) (v26.ComplianceReport, error) {
+	var resp ComplianceReportResponse
+	err := p.client.Call("RPCServer.ComplianceReport", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV27 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces LogEvents.
+type RPCClientV27 struct {
+	*RPCClientV26
+}
+
+type clientV27 interface {
+	v27.Server
+	v27.Upstream
+}
+
+var _ clientV27 = &RPCClientV27{}
+
+// NewClientV27 creates a new rpc-backed implementation of the server.
+func NewClientV27(conn io.ReadWriteCloser) *RPCClientV27 {
+	return &RPCClientV27{NewClientV26(conn)}
+}
+
+func (p *RPCClientV27) LogEvents(ctx context.Context, opts v27.LogEventsOptions) error {
+	var resp LogEventsResponse
+	err := p.client.Call("RPCServer.LogEvents", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return err
+}
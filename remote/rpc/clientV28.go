@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV28 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces WorkloadHistory.
+type RPCClientV28 struct {
+	*RPCClientV27
+}
+
+type clientV28 interface {
+	v28.Server
+	v28.Upstream
+}
+
+var _ clientV28 = &RPCClientV28{}
+
+// NewClientV28 creates a new rpc-backed implementation of the server.
+func NewClientV28(conn io.ReadWriteCloser) *RPCClientV28 {
+	return &RPCClientV28{NewClientV27(conn)}
+}
+
+func (p *RPCClientV28) WorkloadHistory(ctx context.Context, opts v28.WorkloadHistoryOptions) (v28.WorkloadHistory, error) {
+	var resp WorkloadHistoryResponse
+	err := p.client.Call("RPCServer.WorkloadHistory", opts, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+
+	"github.com/weaveworks/flux/api/v29"
+	"github.com/weaveworks/flux/remote"
+)
+
+// RPCClientV29 is the rpc-backed implementation of a server, for
+// talking to remote daemons. This version introduces JobQueue.
+type RPCClientV29 struct {
+	*RPCClientV28
+}
+
+type clientV29 interface {
+	v29.Server
+	v29.Upstream
+}
+
+var _ clientV29 = &RPCClientV29{}
+
+// NewClientV29 creates a new rpc-backed implementation of the server.
+func NewClientV29(conn io.ReadWriteCloser) *RPCClientV29 {
+	return &RPCClientV29{NewClientV28(conn)}
+}
+
+func (p *RPCClientV29) JobQueue(ctx context.Context) (v29.JobQueue, error) {
+	var resp JobQueueResponse
+	err := p.client.Call("RPCServer.JobQueue", struct{}{}, &resp)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok && err != nil {
+			err = remote.FatalError{Err: err}
+		}
+	} else if resp.ApplicationError != nil {
+		err = resp.ApplicationError
+	}
+	return resp.Result, err
+}
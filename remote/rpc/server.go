@@ -7,6 +7,24 @@ import (
 	"net/rpc/jsonrpc"
 
 	"github.com/weaveworks/flux/api/v10"
+	"github.com/weaveworks/flux/api/v12"
+	"github.com/weaveworks/flux/api/v13"
+	"github.com/weaveworks/flux/api/v14"
+	"github.com/weaveworks/flux/api/v15"
+	"github.com/weaveworks/flux/api/v16"
+	"github.com/weaveworks/flux/api/v17"
+	"github.com/weaveworks/flux/api/v18"
+	"github.com/weaveworks/flux/api/v19"
+	"github.com/weaveworks/flux/api/v20"
+	"github.com/weaveworks/flux/api/v21"
+	"github.com/weaveworks/flux/api/v22"
+	"github.com/weaveworks/flux/api/v23"
+	"github.com/weaveworks/flux/api/v24"
+	"github.com/weaveworks/flux/api/v25"
+	"github.com/weaveworks/flux/api/v26"
+	"github.com/weaveworks/flux/api/v27"
+	"github.com/weaveworks/flux/api/v28"
+	"github.com/weaveworks/flux/api/v29"
 
 	"github.com/pkg/errors"
 
@@ -14,6 +32,7 @@ import (
 	"github.com/weaveworks/flux/api/v6"
 	"github.com/weaveworks/flux/api/v9"
 	fluxerr "github.com/weaveworks/flux/errors"
+	"github.com/weaveworks/flux/event"
 	"github.com/weaveworks/flux/job"
 	"github.com/weaveworks/flux/update"
 )
@@ -114,6 +133,310 @@ func (p *RPCServer) ListImagesWithOptions(opts v10.ListImagesOptions, resp *List
 	return err
 }
 
+type ListDeploymentsResponse struct {
+	Result           []v12.WorkloadDeployment
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ListDeployments(opts v12.ListDeploymentsOptions, resp *ListDeploymentsResponse) error {
+	v, err := p.s.ListDeployments(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ListEventsResponse struct {
+	Result           []event.Event
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ListEvents(opts v13.ListEventsOptions, resp *ListEventsResponse) error {
+	v, err := p.s.ListEvents(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type CheckWorkloadResponse struct {
+	Result           v14.WorkloadCheck
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) CheckWorkload(opts v14.CheckWorkloadOptions, resp *CheckWorkloadResponse) error {
+	v, err := p.s.CheckWorkload(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ImageReportResponse struct {
+	Result           []v15.ImageUsage
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ImageReport(opts v15.ImageReportOptions, resp *ImageReportResponse) error {
+	v, err := p.s.ImageReport(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type DeliveryReportResponse struct {
+	Result           []v16.WorkloadDelivery
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) DeliveryReport(opts v16.DeliveryReportOptions, resp *DeliveryReportResponse) error {
+	v, err := p.s.DeliveryReport(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type CompareImagesResponse struct {
+	Result           []v17.ImageComparison
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) CompareImages(opts v17.CompareImagesOptions, resp *CompareImagesResponse) error {
+	v, err := p.s.CompareImages(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ExportClusterResponse struct {
+	Result           []byte
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ExportCluster(opts v18.ExportClusterOptions, resp *ExportClusterResponse) error {
+	v, err := p.s.ExportCluster(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type DeployedAtResponse struct {
+	Result           v19.DeployedState
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) DeployedAt(opts v19.DeployedAtOptions, resp *DeployedAtResponse) error {
+	v, err := p.s.DeployedAt(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type AnnotateEventResponse struct {
+	Result           event.Event
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) AnnotateEvent(opts v20.AnnotateEventOptions, resp *AnnotateEventResponse) error {
+	v, err := p.s.AnnotateEvent(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type DiffRevisionsResponse struct {
+	Result           v21.RevisionDiff
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) DiffRevisions(opts v21.DiffRevisionsOptions, resp *DiffRevisionsResponse) error {
+	v, err := p.s.DiffRevisions(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type EventHistoryResponse struct {
+	Result           v22.EventHistory
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) EventHistory(opts v22.EventHistoryOptions, resp *EventHistoryResponse) error {
+	v, err := p.s.EventHistory(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type MetricsSnapshotResponse struct {
+	Result           v23.MetricsSnapshot
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) MetricsSnapshot(_ struct{}, resp *MetricsSnapshotResponse) error {
+	v, err := p.s.MetricsSnapshot(context.Background())
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type PruneEventsResponse struct {
+	Result           v24.PruneEventsResult
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) PruneEvents(opts v24.PruneEventsOptions, resp *PruneEventsResponse) error {
+	v, err := p.s.PruneEvents(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ReplayEventsResponse struct {
+	Result           v25.ReplayEventsResult
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ReplayEvents(opts v25.ReplayEventsOptions, resp *ReplayEventsResponse) error {
+	v, err := p.s.ReplayEvents(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type ComplianceReportResponse struct {
+	Result           v26.ComplianceReport
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) ComplianceReport(opts v26.ComplianceReportOptions, resp *ComplianceReportResponse) error {
+	v, err := p.s.ComplianceReport(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type LogEventsResponse struct {
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) LogEvents(opts v27.LogEventsOptions, resp *LogEventsResponse) error {
+	err := p.s.LogEvents(context.Background(), opts)
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type WorkloadHistoryResponse struct {
+	Result           v28.WorkloadHistory
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) WorkloadHistory(opts v28.WorkloadHistoryOptions, resp *WorkloadHistoryResponse) error {
+	v, err := p.s.WorkloadHistory(context.Background(), opts)
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
+type JobQueueResponse struct {
+	Result           v29.JobQueue
+	ApplicationError *fluxerr.Error
+}
+
+func (p *RPCServer) JobQueue(_ struct{}, resp *JobQueueResponse) error {
+	v, err := p.s.JobQueue(context.Background())
+	resp.Result = v
+	if err != nil {
+		if err, ok := errors.Cause(err).(*fluxerr.Error); ok {
+			resp.ApplicationError = err
+			return nil
+		}
+	}
+	return err
+}
+
 type UpdateManifestsResponse struct {
 	Result           job.ID
 	ApplicationError *fluxerr.Error
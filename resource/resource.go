@@ -19,6 +19,22 @@ type Container struct {
 	Image image.Ref
 }
 
+// Chart is a version of a chart in a Helm chart repository.
+type Chart struct {
+	Repository string
+	Name       string
+	Version    string
+}
+
+// ChartRelease is a resource that may release a chart from a chart
+// repository, e.g., a FluxHelmRelease.
+type ChartRelease interface {
+	Resource
+	// Chart returns the chart released, and true; or false if the
+	// chart does not come from a chart repository.
+	Chart() (Chart, bool)
+}
+
 type Workload interface {
 	Resource
 	Containers() []Container
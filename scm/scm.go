@@ -0,0 +1,190 @@
+/*
+Package scm opens pull requests (or merge requests, as GitLab has
+it) with the hosts of git repos, so changes flux makes can be pushed
+to a branch of their own and approved by someone before they're
+merged into the branch that's synced.
+*/
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/tracing"
+)
+
+// PullRequest asks for the head branch to be merged into the base
+// branch.
+type PullRequest struct {
+	Head  string
+	Base  string
+	Title string
+	Body  string
+}
+
+// Opened is a pull request that's been opened.
+type Opened struct {
+	URL string
+	// What the git host knows the pull request by within the repo,
+	// e.g., for commenting on it
+	Number int
+}
+
+// Provider opens pull requests with a git host.
+type Provider interface {
+	// Open opens the pull request.
+	Open(ctx context.Context, pr PullRequest) (Opened, error)
+	// Comment comments on a pull request that's been opened.
+	Comment(ctx context.Context, pr Opened, body string) error
+}
+
+// NewProvider gives the Provider for the kind of git host given,
+// "github" or "gitlab", for the repo at the (git) URL given. If the
+// API URL is empty, it's that of the host in the repo URL. The
+// credentials are asked for a token each time a pull request is
+// opened, so the token can be refreshed.
+func NewProvider(kind, repoURL, apiURL string, creds git.CredentialsProvider) (Provider, error) {
+	host, path := git.SplitRepoURL(repoURL)
+	if host == "" || path == "" {
+		return nil, fmt.Errorf("can't make out the host and repo from the git URL %q", repoURL)
+	}
+	if creds == nil {
+		return nil, errors.New("a token is needed to open pull requests")
+	}
+	switch kind {
+	case "github":
+		if apiURL == "" {
+			apiURL = "https://api.github.com"
+			if host != "github.com" {
+				// GitHub Enterprise
+				apiURL = "https://" + host + "/api/v3"
+			}
+		}
+		return &GitHub{APIURL: apiURL, Repo: path, Credentials: creds}, nil
+	case "gitlab":
+		if apiURL == "" {
+			apiURL = "https://" + host + "/api/v4"
+		}
+		return &GitLab{APIURL: apiURL, Project: path, Credentials: creds}, nil
+	}
+	return nil, fmt.Errorf("unknown git host %q; expected github or gitlab", kind)
+}
+
+// GitHub opens pull requests with the GitHub (or GitHub Enterprise)
+// API.
+type GitHub struct {
+	APIURL string
+	// As `<owner>/<name>`
+	Repo        string
+	Credentials git.CredentialsProvider
+	// If nil, http.DefaultClient is used
+	Client *http.Client
+}
+
+func (g *GitHub) Open(ctx context.Context, pr PullRequest) (Opened, error) {
+	var created struct {
+		HTMLURL string `json:"html_url"`
+		Number  int    `json:"number"`
+	}
+	err := post(ctx, g.Client, g.Credentials, g.APIURL+"/repos/"+g.Repo+"/pulls", map[string]string{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  pr.Head,
+		"base":  pr.Base,
+	}, &created)
+	if err != nil {
+		return Opened{}, errors.Wrap(err, "opening GitHub pull request")
+	}
+	return Opened{URL: created.HTMLURL, Number: created.Number}, nil
+}
+
+func (g *GitHub) Comment(ctx context.Context, pr Opened, body string) error {
+	// Pull requests are commented on as the issues they are
+	var created struct{}
+	err := post(ctx, g.Client, g.Credentials, fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.APIURL, g.Repo, pr.Number), map[string]string{
+		"body": body,
+	}, &created)
+	return errors.Wrap(err, "commenting on GitHub pull request")
+}
+
+// GitLab opens merge requests with the GitLab API.
+type GitLab struct {
+	APIURL string
+	// As `<group>/<name>`, with any subgroups
+	Project     string
+	Credentials git.CredentialsProvider
+	// If nil, http.DefaultClient is used
+	Client *http.Client
+}
+
+func (g *GitLab) Open(ctx context.Context, pr PullRequest) (Opened, error) {
+	var created struct {
+		WebURL string `json:"web_url"`
+		IID    int    `json:"iid"`
+	}
+	err := post(ctx, g.Client, g.Credentials, g.APIURL+"/projects/"+url.PathEscape(g.Project)+"/merge_requests", map[string]string{
+		"title":         pr.Title,
+		"description":   pr.Body,
+		"source_branch": pr.Head,
+		"target_branch": pr.Base,
+	}, &created)
+	if err != nil {
+		return Opened{}, errors.Wrap(err, "opening GitLab merge request")
+	}
+	return Opened{URL: created.WebURL, Number: created.IID}, nil
+}
+
+func (g *GitLab) Comment(ctx context.Context, pr Opened, body string) error {
+	var created struct{}
+	err := post(ctx, g.Client, g.Credentials, fmt.Sprintf("%s/projects/%s/merge_requests/%d/notes", g.APIURL, url.PathEscape(g.Project), pr.Number), map[string]string{
+		"body": body,
+	}, &created)
+	return errors.Wrap(err, "commenting on GitLab merge request")
+}
+
+// post posts the request to the API, with the token from the
+// credentials, and decodes the response into `out`.
+func post(ctx context.Context, client *http.Client, creds git.CredentialsProvider, apiURL string, in, out interface{}) error {
+	token, err := creds.Credentials(ctx, apiURL)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.Password)
+	tracing.Inject(ctx, req.Header)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
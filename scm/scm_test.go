@@ -0,0 +1,105 @@
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/weaveworks/flux/git"
+)
+
+func TestNewProvider(t *testing.T) {
+	creds := git.StaticCredentials("flux", "token")
+	for _, c := range []struct {
+		kind, url string
+		expected  Provider
+	}{
+		{"github", "git@github.com:example/config", &GitHub{APIURL: "https://api.github.com", Repo: "example/config", Credentials: creds}},
+		{"github", "https://github.example.com/example/config.git", &GitHub{APIURL: "https://github.example.com/api/v3", Repo: "example/config", Credentials: creds}},
+		{"gitlab", "ssh://git@gitlab.com/group/sub/config.git", &GitLab{APIURL: "https://gitlab.com/api/v4", Project: "group/sub/config", Credentials: creds}},
+	} {
+		p, err := NewProvider(c.kind, c.url, "", creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch expected := c.expected.(type) {
+		case *GitHub:
+			if got, ok := p.(*GitHub); !ok || got.APIURL != expected.APIURL || got.Repo != expected.Repo {
+				t.Errorf("expected %+v for %s, got %+v", expected, c.url, p)
+			}
+		case *GitLab:
+			if got, ok := p.(*GitLab); !ok || got.APIURL != expected.APIURL || got.Project != expected.Project {
+				t.Errorf("expected %+v for %s, got %+v", expected, c.url, p)
+			}
+		}
+	}
+	if _, err := NewProvider("bitbucket", "git@bitbucket.org:example/config", "", creds); err == nil {
+		t.Error("expected an error for an unknown kind of git host")
+	}
+	if _, err := NewProvider("github", "git@github.com:example/config", "", nil); err == nil {
+		t.Error("expected an error when there's no token")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	var path, auth string
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		if path == "/repos/example/taken/pulls" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"A pull request already exists"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url":"https://github.com/example/config/pull/1","number":1,"web_url":"https://gitlab.com/group/config/merge_requests/1","iid":1}`))
+	}))
+	defer server.Close()
+	creds := git.StaticCredentials("flux", "s3cr3t")
+	pr := PullRequest{Head: "flux-update/abc", Base: "master", Title: "Update images", Body: "Changes"}
+	ctx := context.Background()
+
+	github := &GitHub{APIURL: server.URL, Repo: "example/config", Credentials: creds}
+	opened, err := github.Open(ctx, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.URL != "https://github.com/example/config/pull/1" || opened.Number != 1 || path != "/repos/example/config/pulls" || auth != "Bearer s3cr3t" {
+		t.Errorf("unexpected pull request %+v, opened at %s with %q", opened, path, auth)
+	}
+	if got["head"] != pr.Head || got["base"] != pr.Base || got["title"] != pr.Title || got["body"] != pr.Body {
+		t.Errorf("unexpected pull request posted: %v", got)
+	}
+	if err := github.Comment(ctx, opened, "Preview"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/repos/example/config/issues/1/comments" || got["body"] != "Preview" {
+		t.Errorf("unexpected comment %v, posted to %s", got, path)
+	}
+
+	gitlab := &GitLab{APIURL: server.URL, Project: "group/config", Credentials: creds}
+	opened, err = gitlab.Open(ctx, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.URL != "https://gitlab.com/group/config/merge_requests/1" || opened.Number != 1 || path != "/projects/group%2Fconfig/merge_requests" {
+		t.Errorf("unexpected merge request %+v, opened at %s", opened, path)
+	}
+	if got["source_branch"] != pr.Head || got["target_branch"] != pr.Base || got["description"] != pr.Body {
+		t.Errorf("unexpected merge request posted: %v", got)
+	}
+	if err := gitlab.Comment(ctx, opened, "Preview"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/projects/group%2Fconfig/merge_requests/1/notes" || got["body"] != "Preview" {
+		t.Errorf("unexpected comment %v, posted to %s", got, path)
+	}
+
+	if _, err := (&GitHub{APIURL: server.URL, Repo: "example/taken", Credentials: creds}).Open(ctx, pr); err == nil {
+		t.Error("expected an error when the pull request is refused")
+	}
+}
@@ -0,0 +1,97 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/weaveworks/flux/image"
+)
+
+// OCI fetches manifests published as an OCI artifact, e.g., with
+// `flux push artifact` or `oras push`: an image whose layers are
+// tarballs of the manifests. It calls crane to talk to the registry,
+// so crane's credentials (e.g., from ~/.docker/config.json) are used.
+type OCI struct {
+	// The artifact, e.g., ghcr.io/example/manifests:production
+	Ref   string
+	Paths []string
+	// Exe is the path to crane; if empty, it's looked up in $PATH
+	Exe string
+}
+
+func (o *OCI) Fetch(ctx context.Context) (*Snapshot, error) {
+	ref, err := image.ParseRef(o.Ref)
+	if err != nil {
+		return nil, err
+	}
+	// Fetch everything by digest, so what's unpacked is what the
+	// revision says
+	out, err := o.crane(ctx, "digest", o.Ref)
+	if err != nil {
+		return nil, err
+	}
+	digest := strings.TrimSpace(string(out))
+	pinned := ref.Name.String() + "@" + digest
+
+	out, err = o.crane(ctx, "manifest", pinned)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, errors.New(o.Ref + " has no layers to unpack")
+	}
+
+	snapshot, err := newSnapshot(digest, o.Paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range manifest.Layers {
+		blob, err := o.crane(ctx, "blob", ref.Name.String()+"@"+layer.Digest)
+		if err != nil {
+			snapshot.Clean()
+			return nil, err
+		}
+		if err := extract(bytes.NewReader(blob), snapshot.Dir()); err != nil {
+			snapshot.Clean()
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// crane runs crane with the arguments given, and returns what it
+// outputs.
+func (o *OCI) crane(ctx context.Context, args ...string) ([]byte, error) {
+	exe := o.Exe
+	if exe == "" {
+		exe = "crane"
+	}
+	return runTool(ctx, exe, args...)
+}
+
+func runTool(ctx context.Context, exe string, args ...string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, exe, args...)
+	errOut := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		if errOut.Len() == 0 {
+			return nil, err
+		}
+		return nil, errors.New(strings.TrimSpace(errOut.String()))
+	}
+	return out.Bytes(), nil
+}
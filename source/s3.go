@@ -0,0 +1,66 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// S3 fetches manifests published as a tarball (optionally gzipped)
+// in an S3 bucket. It calls the aws CLI, so the credentials and
+// region it's configured with (e.g., from the environment) are used.
+type S3 struct {
+	Bucket string
+	Key    string
+	Paths  []string
+	// Exe is the path to aws; if empty, it's looked up in $PATH
+	Exe string
+}
+
+func (s *S3) Fetch(ctx context.Context) (*Snapshot, error) {
+	exe := s.Exe
+	if exe == "" {
+		exe = "aws"
+	}
+	out, err := runTool(ctx, exe, "s3api", "head-object", "--bucket", s.Bucket, "--key", s.Key)
+	if err != nil {
+		return nil, err
+	}
+	var head struct {
+		ETag string `json:"ETag"`
+	}
+	if err := json.Unmarshal(out, &head); err != nil {
+		return nil, err
+	}
+	etag := strings.Trim(head.ETag, `"`)
+
+	download, err := ioutil.TempDir("", "flux-s3")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(download)
+	tarball := filepath.Join(download, "manifests.tar")
+	// Only the object with the ETag looked at, in case it's replaced
+	// in the meantime
+	if _, err := runTool(ctx, exe, "s3api", "get-object", "--bucket", s.Bucket, "--key", s.Key, "--if-match", head.ETag, tarball); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshot, err := newSnapshot(etag, s.Paths)
+	if err != nil {
+		return nil, err
+	}
+	if err := extract(f, snapshot.Dir()); err != nil {
+		snapshot.Clean()
+		return nil, err
+	}
+	return snapshot, nil
+}
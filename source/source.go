@@ -0,0 +1,221 @@
+// Package source provides the places the daemon can fetch the
+// manifests it syncs from: a git repo (as ever), an OCI artifact in
+// an image registry, or a tarball in an S3 bucket.
+package source
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/policy"
+)
+
+// Store is somewhere the desired state of the cluster is published,
+// as manifests.
+type Store interface {
+	// Fetch gets the manifests at the latest revision. The snapshot
+	// returned must be cleaned up once it's no longer needed.
+	Fetch(ctx context.Context) (*Snapshot, error)
+}
+
+// Snapshot is the manifests from a store at one revision, in a
+// directory of their own.
+type Snapshot struct {
+	// Revision identifies what was fetched: a commit, for git; a
+	// digest, for an OCI artifact; an ETag, for S3
+	Revision string
+	// The checkout the snapshot is of, if it's from git; nil,
+	// otherwise
+	Checkout *git.Checkout
+
+	dir   string
+	paths []string
+}
+
+// Dir gives the directory the manifests are in.
+func (s *Snapshot) Dir() string {
+	return s.dir
+}
+
+// ManifestDirs gives the paths to the manifests, which are the paths
+// given to the store, within the directory; or if none were given,
+// the directory itself.
+func (s *Snapshot) ManifestDirs() []string {
+	if s.Checkout != nil {
+		return s.Checkout.ManifestDirs()
+	}
+	if len(s.paths) == 0 {
+		return []string{s.dir}
+	}
+	dirs := make([]string, len(s.paths))
+	for i, p := range s.paths {
+		dirs[i] = filepath.Join(s.dir, p)
+	}
+	return dirs
+}
+
+// Clean removes the snapshot's files.
+func (s *Snapshot) Clean() {
+	if s.Checkout != nil {
+		s.Checkout.Clean()
+		return
+	}
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// Git fetches manifests by cloning a git repo.
+type Git struct {
+	Repo   *git.Repo
+	Config git.Config
+	// If set, the manifests are those at the latest tag that matches,
+	// rather than at the head of the branch
+	Tags policy.Pattern
+}
+
+// Head gives the revision the manifests would be fetched at, and the
+// branch or tag that's at.
+func (g Git) Head(ctx context.Context) (rev, ref string, err error) {
+	if g.Tags == nil {
+		rev, err := g.Repo.Revision(ctx, g.Config.Branch)
+		return rev, g.Config.Branch, err
+	}
+	tags, err := g.Repo.Tags(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	tag, ok := LatestTag(tags, g.Tags, g.Config.SyncTag)
+	if !ok {
+		return "", "", fmt.Errorf("no tags in the git repo match %s", g.Tags)
+	}
+	return tag.Revision, tag.Name, nil
+}
+
+func (g Git) Fetch(ctx context.Context) (*Snapshot, error) {
+	config := g.Config
+	if g.Tags != nil {
+		_, tag, err := g.Head(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Cloning at a tag checks out its commit
+		config.Branch = tag
+	}
+	working, err := g.Repo.Clone(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := working.HeadRevision(ctx)
+	if err != nil {
+		working.Clean()
+		return nil, err
+	}
+	return &Snapshot{Revision: rev, Checkout: working, dir: working.Dir()}, nil
+}
+
+// LatestTag gives the latest of the tags that match the pattern,
+// leaving out the tag given (i.e., the one flux moves to mark what's
+// been synced): for a semver pattern, that's the highest version;
+// otherwise, it's the one made most recently.
+func LatestTag(tags []git.Tag, pattern policy.Pattern, except string) (git.Tag, bool) {
+	_, bySemver := pattern.(policy.SemverPattern)
+	var latest git.Tag
+	var latestVersion *semver.Version
+	var found bool
+	for _, tag := range tags {
+		if tag.Name == except || !pattern.Matches(tag.Name) {
+			continue
+		}
+		var version *semver.Version
+		if bySemver {
+			// It matched, so it parses
+			version, _ = semver.NewVersion(tag.Name)
+		}
+		if !found || newerTag(tag, version, latest, latestVersion) {
+			latest, latestVersion, found = tag, version, true
+		}
+	}
+	return latest, found
+}
+
+func newerTag(a git.Tag, va *semver.Version, b git.Tag, vb *semver.Version) bool {
+	switch {
+	case va != nil && vb != nil && !va.Equal(vb):
+		return va.GreaterThan(vb)
+	case !a.Time.Equal(b.Time):
+		return a.Time.After(b.Time)
+	}
+	return a.Name > b.Name
+}
+
+// GitExport fetches manifests from a git repo without making a
+// working clone, so it can be used with a read-only repo; there's no
+// checkout in the snapshots it gives.
+type GitExport struct {
+	Repo   *git.Repo
+	Branch string
+	Paths  []string
+}
+
+func (g GitExport) Fetch(ctx context.Context) (*Snapshot, error) {
+	rev, err := g.Repo.Revision(ctx, g.Branch)
+	if err != nil {
+		return nil, err
+	}
+	export, err := g.Repo.Export(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshot(rev, export.Dir(), g.Paths), nil
+}
+
+// Open gives the store at the URL given, which is either
+// oci://<image ref> for an OCI artifact, or s3://<bucket>/<key> for a
+// tarball in S3. The manifests are those under the paths given, within
+// what's fetched.
+func Open(rawurl string, paths []string) (Store, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "oci":
+		ref := strings.TrimPrefix(rawurl, "oci://")
+		if ref == "" {
+			return nil, fmt.Errorf("no image given in %q", rawurl)
+		}
+		return &OCI{Ref: ref, Paths: paths}, nil
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("expected s3://<bucket>/<key>, got %q", rawurl)
+		}
+		return &S3{Bucket: u.Host, Key: key, Paths: paths}, nil
+	}
+	return nil, fmt.Errorf("unknown manifest store %q; expected oci://<image ref> or s3://<bucket>/<key>", rawurl)
+}
+
+// NewSnapshot makes a snapshot of the manifests in the directory
+// given, at the revision given, e.g., for stores defined elsewhere.
+// The directory is removed when the snapshot is cleaned up.
+func NewSnapshot(rev, dir string, paths []string) *Snapshot {
+	return &Snapshot{Revision: rev, dir: dir, paths: paths}
+}
+
+// newSnapshot makes a snapshot in a new temporary directory.
+func newSnapshot(rev string, paths []string) (*Snapshot, error) {
+	dir, err := ioutil.TempDir("", "flux-manifests")
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshot(rev, dir, paths), nil
+}
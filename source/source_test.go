@@ -0,0 +1,213 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/flux/git"
+	"github.com/weaveworks/flux/git/gittest"
+	"github.com/weaveworks/flux/policy"
+)
+
+// tarball makes a gzipped tarball of the files given.
+func tarball(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	w := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fakeTool writes a shell script standing in for a tool, which has
+// the tarball given at $TARBALL.
+func fakeTool(t *testing.T, dir, name, script string, tarballBytes []byte) string {
+	path := filepath.Join(dir, "manifests.tgz")
+	if err := ioutil.WriteFile(path, tarballBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(exe, []byte("#!/bin/sh\nTARBALL="+path+"\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func readManifest(t *testing.T, snapshot *Snapshot) string {
+	dirs := snapshot.ManifestDirs()
+	if len(dirs) != 1 {
+		t.Fatalf("expected one manifest dir, got %v", dirs)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dirs[0], "deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+const fakeCrane = `case "$1" in
+  digest) echo sha256:abc123 ;;
+  manifest) echo '{"layers": [{"digest": "sha256:def456"}]}' ;;
+  blob) cat "$TARBALL" ;;
+  *) echo "unexpected $*" >&2; exit 1 ;;
+esac
+`
+
+func TestOCI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-crane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := fakeTool(t, dir, "crane", fakeCrane, tarball(t, map[string]string{"prod/deployment.yaml": "kind: Deployment"}))
+
+	store, err := Open("oci://ghcr.io/example/manifests:production", []string{"prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.(*OCI).Exe = exe
+	snapshot, err := store.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Clean()
+	if snapshot.Revision != "sha256:abc123" {
+		t.Errorf("expected the digest as the revision, got %q", snapshot.Revision)
+	}
+	if got := readManifest(t, snapshot); got != "kind: Deployment" {
+		t.Errorf("unexpected manifest %q", got)
+	}
+}
+
+const fakeAWS = `case "$2" in
+  head-object) echo '{"ETag": "\"etag1\"", "ContentLength": 100}' ;;
+  get-object) for last; do :; done; cp "$TARBALL" "$last" ;;
+  *) echo "unexpected $*" >&2; exit 1 ;;
+esac
+`
+
+func TestS3(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := fakeTool(t, dir, "aws", fakeAWS, tarball(t, map[string]string{"deployment.yaml": "kind: Deployment"}))
+
+	store, err := Open("s3://manifests/production.tgz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.(*S3).Exe = exe
+	snapshot, err := store.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Clean()
+	if snapshot.Revision != "etag1" {
+		t.Errorf("expected the ETag as the revision, got %q", snapshot.Revision)
+	}
+	if got := readManifest(t, snapshot); got != "kind: Deployment" {
+		t.Errorf("unexpected manifest %q", got)
+	}
+}
+
+func TestExtractRefusesEscapes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := tarball(t, map[string]string{"../escaped.yaml": "kind: Secret"})
+	if err := extract(bytes.NewReader(archive), dir); err == nil {
+		t.Error("expected a file outside the directory to be refused")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, bad := range []string{"oci://", "s3://bucket", "http://example.com/manifests.tgz"} {
+		if _, err := Open(bad, nil); err == nil {
+			t.Errorf("expected %q to be refused", bad)
+		}
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	now := time.Now()
+	tags := []git.Tag{
+		{Name: "prod-2", Revision: "b", Time: now.Add(-time.Hour)},
+		{Name: "prod-10", Revision: "a", Time: now.Add(-2 * time.Hour)},
+		{Name: "v1.2.0", Revision: "c", Time: now.Add(-time.Minute)},
+		{Name: "v1.10.0", Revision: "d", Time: now.Add(-3 * time.Hour)},
+		{Name: "v2.0.0", Revision: "e", Time: now.Add(-4 * time.Hour)},
+		{Name: "flux-sync", Revision: "f", Time: now},
+	}
+	for _, c := range []struct {
+		pattern  string
+		expected string
+	}{
+		// the most recently made, for a glob
+		{"prod-*", "prod-2"},
+		{"*", "v1.2.0"},
+		// the highest version, for semver
+		{"semver:~1", "v1.10.0"},
+		{"semver:*", "v2.0.0"},
+	} {
+		tag, ok := LatestTag(tags, policy.NewPattern(c.pattern), "flux-sync")
+		if !ok || tag.Name != c.expected {
+			t.Errorf("expected %s to be the latest tag matching %s, got %+v", c.expected, c.pattern, tag)
+		}
+	}
+	if tag, ok := LatestTag(tags, policy.NewPattern("staging-*"), "flux-sync"); ok {
+		t.Errorf("expected no tag to match, got %+v", tag)
+	}
+}
+
+func TestGitHead(t *testing.T) {
+	repo, cleanup := gittest.Repo(t)
+	defer cleanup()
+	upstream := strings.TrimPrefix(repo.Origin().URL, "file://")
+	if err := exec.Command("git", "-C", upstream, "tag", "prod-1", "master").Run(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := repo.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	master, err := repo.Revision(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := git.Config{Branch: "master", SyncTag: "flux-sync"}
+	if rev, ref, err := (Git{Repo: repo, Config: config}).Head(ctx); err != nil || rev != master || ref != "master" {
+		t.Errorf("expected the head of master, %s, got %s at %s (%v)", master, rev, ref, err)
+	}
+	if rev, ref, err := (Git{Repo: repo, Config: config, Tags: policy.NewPattern("prod-*")}).Head(ctx); err != nil || rev != master || ref != "prod-1" {
+		t.Errorf("expected prod-1, at %s, got %s at %s (%v)", master, rev, ref, err)
+	}
+	if _, _, err := (Git{Repo: repo, Config: config, Tags: policy.NewPattern("staging-*")}).Head(ctx); err == nil {
+		t.Error("expected an error when no tag matches")
+	}
+}
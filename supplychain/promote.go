@@ -0,0 +1,165 @@
+package supplychain
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry"
+)
+
+// Copier copies an image from one repository to another, e.g., to
+// promote it from a staging registry to a production registry. If a
+// digest is given, the image with that digest is copied, rather than
+// whatever the tag refers to at the time.
+type Copier interface {
+	Copy(ctx context.Context, from image.Ref, digest string, to image.Ref) error
+}
+
+// NewCopier gives the Copier that uses the tool named, which is
+// either "crane" or "skopeo". The credentials, if given, are used to
+// pull from and push to the registries; otherwise the tool uses
+// whatever it finds by itself.
+func NewCopier(tool string, creds func() registry.ImageCreds) (Copier, error) {
+	switch tool {
+	case "crane":
+		return CraneCopier{Credentials: creds}, nil
+	case "skopeo":
+		return SkopeoCopier{Credentials: creds}, nil
+	}
+	return nil, fmt.Errorf("unknown image copying tool %q; expected crane or skopeo", tool)
+}
+
+// CraneCopier copies images by calling `crane copy`.
+type CraneCopier struct {
+	// Exe is the path to crane; if empty, it's looked up in $PATH
+	Exe string
+	// Credentials gives the credentials for each image; may be nil
+	Credentials func() registry.ImageCreds
+}
+
+func (c CraneCopier) Copy(ctx context.Context, from image.Ref, digest string, to image.Ref) error {
+	exe := c.Exe
+	if exe == "" {
+		exe = "crane"
+	}
+	// crane has no flags for credentials, but reads them from the
+	// docker config in $DOCKER_CONFIG
+	dir, err := writeDockerConfig(c.Credentials, from.Name, to.Name)
+	if err != nil {
+		return err
+	}
+	var env []string
+	if dir != "" {
+		defer os.RemoveAll(dir)
+		env = []string{"DOCKER_CONFIG=" + dir}
+	}
+	return runTool(ctx, env, exe, "copy", copySource(from, digest), to.String())
+}
+
+// SkopeoCopier copies images by calling `skopeo copy`. All the
+// architectures of a multi-arch image are copied.
+type SkopeoCopier struct {
+	// Exe is the path to skopeo; if empty, it's looked up in $PATH
+	Exe string
+	// Credentials gives the credentials for each image; may be nil
+	Credentials func() registry.ImageCreds
+}
+
+func (c SkopeoCopier) Copy(ctx context.Context, from image.Ref, digest string, to image.Ref) error {
+	exe := c.Exe
+	if exe == "" {
+		exe = "skopeo"
+	}
+	// The credentials are given in a file rather than with
+	// --src-creds and --dest-creds, so they don't show up in the
+	// process list
+	dir, err := writeDockerConfig(c.Credentials, from.Name, to.Name)
+	if err != nil {
+		return err
+	}
+	args := []string{"copy", "--all"}
+	if dir != "" {
+		defer os.RemoveAll(dir)
+		args = append(args, "--authfile", filepath.Join(dir, "config.json"))
+	}
+	args = append(args, "docker://"+copySource(from, digest), "docker://"+to.String())
+	return runTool(ctx, nil, exe, args...)
+}
+
+func copySource(from image.Ref, digest string) string {
+	if digest == "" {
+		return from.String()
+	}
+	return from.Name.String() + "@" + digest
+}
+
+type dockerAuth struct {
+	Auth string `json:"auth"`
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+// writeDockerConfig writes a docker config.json, with the credentials
+// for the registry of each image named, to a new directory, and
+// gives the directory, which the caller must remove. If there are no
+// credentials to write, it gives an empty string.
+func writeDockerConfig(lookup func() registry.ImageCreds, names ...image.Name) (string, error) {
+	if lookup == nil {
+		return "", nil
+	}
+	all := lookup()
+	config := dockerConfig{Auths: map[string]dockerAuth{}}
+	for _, name := range names {
+		host := name.CanonicalName().Domain
+		user, pass := all[name].Basic(host)
+		if user == "" && pass == "" {
+			continue
+		}
+		config.Auths[host] = dockerAuth{Auth: base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))}
+	}
+	if len(config.Auths) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", "flux-promote")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), b, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func runTool(ctx context.Context, env []string, exe string, args ...string) error {
+	cmd := exec.CommandContext(ctx, exe, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	errOut := &bytes.Buffer{}
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		if errOut.Len() == 0 {
+			return err
+		}
+		return errors.New(strings.TrimSpace(errOut.String()))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package supplychain
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry"
+)
+
+// A stand-in for crane and skopeo, which records its arguments and
+// the docker config it's given.
+const fakeCopyTool = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+for arg; do
+  if [ "$prev" = "--authfile" ]; then cp "$arg" "$(dirname "$0")/config.json"; fi
+  prev="$arg"
+done
+if [ -n "$DOCKER_CONFIG" ]; then cp "$DOCKER_CONFIG/config.json" "$(dirname "$0")/config.json"; fi
+`
+
+func TestCopierCredentials(t *testing.T) {
+	from, _ := image.ParseRef("staging.example.com/app:v1")
+	to, _ := image.ParseRef("quay.io/weaveworks/app:v1")
+	stagingCreds, err := registry.ParseCredentials("test", []byte(`{"staging.example.com":{"auth":"c3RhZ2U6czNjcjN0"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quayCreds, err := registry.ParseCredentials("test", []byte(`{"quay.io":{"auth":"cXVheTpwYXNz"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := func() registry.ImageCreds {
+		return registry.ImageCreds{from.Name: stagingCreds, to.Name: quayCreds}
+	}
+
+	for _, tool := range []string{"crane", "skopeo"} {
+		dir, err := ioutil.TempDir("", "flux-copy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		exe := filepath.Join(dir, tool)
+		if err := ioutil.WriteFile(exe, []byte(fakeCopyTool), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		var copier Copier
+		switch tool {
+		case "crane":
+			copier = CraneCopier{Exe: exe, Credentials: creds}
+		case "skopeo":
+			copier = SkopeoCopier{Exe: exe, Credentials: creds}
+		}
+		if err := copier.Copy(context.Background(), from, "sha256:abc123", to); err != nil {
+			t.Fatalf("%s: %v", tool, err)
+		}
+
+		args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+		if !strings.Contains(string(args), "staging.example.com/app@sha256:abc123") {
+			t.Errorf("%s: expected the image to be copied by digest, got %q", tool, args)
+		}
+		config, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+		if err != nil {
+			t.Fatalf("%s: expected a docker config to be given: %v", tool, err)
+		}
+		for _, expected := range []string{`"staging.example.com":{"auth":"c3RhZ2U6czNjcjN0"}`, `"quay.io":{"auth":"cXVheTpwYXNz"}`} {
+			if !strings.Contains(string(config), expected) {
+				t.Errorf("%s: expected %s in docker config, got %s", tool, expected, config)
+			}
+		}
+		if strings.Contains(string(args), "s3cr3t") {
+			t.Errorf("%s: credentials given in arguments: %q", tool, args)
+		}
+	}
+}
+
+func TestCopierNoCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "skopeo")
+	if err := ioutil.WriteFile(exe, []byte(fakeCopyTool), 0755); err != nil {
+		t.Fatal(err)
+	}
+	from, _ := image.ParseRef("staging.example.com/app:v1")
+	to, _ := image.ParseRef("quay.io/weaveworks/app:v1")
+	copier := SkopeoCopier{Exe: exe, Credentials: func() registry.ImageCreds { return registry.ImageCreds{} }}
+	if err := copier.Copy(context.Background(), from, "", to); err != nil {
+		t.Fatal(err)
+	}
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if strings.Contains(string(args), "--authfile") {
+		t.Errorf("expected no authfile without credentials, got %q", args)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Package supplychain has facilities for checking and recording where
+the images Flux releases come from: software bills of materials
+(SBOMs) and signatures attached to images in their registries.
+
+The conventions used for finding things attached to an image are
+those of cosign (https://github.com/sigstore/cosign), i.e., for an
+image with manifest digest `sha256:abc...`, its signature is tagged in
+the same repository as `sha256-abc....sig`, its attestations as
+`.att`, and an attached SBOM as `.sbom`.
+*/
+package supplychain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry"
+	"github.com/weaveworks/flux/update"
+)
+
+const (
+	SignatureSuffix   = ".sig"
+	AttestationSuffix = ".att"
+	SBOMSuffix        = ".sbom"
+)
+
+const sbomCheckTimeout = 30 * time.Second
+
+// ErrNoSBOM is returned (possibly wrapped) by LocateSBOM if the image
+// has no SBOM attached.
+var ErrNoSBOM = errors.New("no SBOM attached")
+
+// AttachedTag gives the tag under which cosign stores an artefact
+// (e.g., signature) for the image with the manifest digest given.
+func AttachedTag(digest, suffix string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("malformed digest %q", digest)
+	}
+	return parts[0] + "-" + parts[1] + suffix, nil
+}
+
+// SBOMLocator figures out where the SBOM for an image can be found.
+type SBOMLocator interface {
+	LocateSBOM(image.Ref) (event.SBOM, error)
+}
+
+// CosignSBOMLocator locates SBOMs attached to images with `cosign
+// attach sbom`, using the digests in the image registry cache. It
+// asks the image's registry whether the SBOM's tag is there, with the
+// credentials for the image, so that only SBOMs that exist are
+// recorded.
+type CosignSBOMLocator struct {
+	Registry registry.Registry
+	Clients  registry.ClientFactory
+	// The credentials the workloads in the cluster use, by image
+	Credentials func() registry.ImageCreds
+}
+
+func (l CosignSBOMLocator) LocateSBOM(ref image.Ref) (event.SBOM, error) {
+	info, err := l.Registry.GetImage(ref)
+	if err != nil {
+		return event.SBOM{}, errors.Wrapf(err, "fetching image metadata for %s", ref)
+	}
+	if info.Digest == "" {
+		return event.SBOM{}, fmt.Errorf("no digest known for image %s", ref)
+	}
+	tag, err := AttachedTag(info.Digest, SBOMSuffix)
+	if err != nil {
+		return event.SBOM{}, err
+	}
+
+	creds := registry.NoCredentials()
+	if l.Credentials != nil {
+		creds = l.Credentials()[ref.Name]
+	}
+	client, err := l.Clients.ClientFor(ref.CanonicalName(), creds)
+	if err != nil {
+		return event.SBOM{}, errors.Wrapf(err, "connecting to registry for %s", ref)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sbomCheckTimeout)
+	defer cancel()
+	found, err := client.HasTag(ctx, tag)
+	if err != nil {
+		return event.SBOM{}, errors.Wrapf(err, "looking for SBOM of %s", ref)
+	}
+	if !found {
+		return event.SBOM{}, ErrNoSBOM
+	}
+	return event.SBOM{
+		Image:  ref.String(),
+		Digest: info.Digest,
+		Ref:    ref.Name.ToRef(tag).String(),
+	}, nil
+}
+
+// LocateSBOMs finds the SBOMs for all the images changed by a
+// release. Images without an SBOM are left out; those for which an
+// SBOM can't be located are reported in the error returned, but don't
+// stop the others from being located.
+func LocateSBOMs(l SBOMLocator, result update.Result) ([]event.SBOM, error) {
+	var sboms []event.SBOM
+	var failed []string
+	seen := map[string]bool{}
+	for _, res := range result {
+		if res.Status != update.ReleaseStatusSuccess {
+			continue
+		}
+		for _, c := range res.PerContainer {
+			if seen[c.Target.String()] {
+				continue
+			}
+			seen[c.Target.String()] = true
+			sbom, err := l.LocateSBOM(c.Target)
+			if errors.Cause(err) == ErrNoSBOM {
+				continue
+			}
+			if err != nil {
+				failed = append(failed, err.Error())
+				continue
+			}
+			sboms = append(sboms, sbom)
+		}
+	}
+	sort.Slice(sboms, func(i, j int) bool { return sboms[i].Image < sboms[j].Image })
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return sboms, errors.New(strings.Join(failed, "; "))
+	}
+	return sboms, nil
+}
@@ -0,0 +1,72 @@
+package supplychain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/image"
+	"github.com/weaveworks/flux/registry/mock"
+	"github.com/weaveworks/flux/update"
+)
+
+func TestAttachedTag(t *testing.T) {
+	tag, err := AttachedTag("sha256:abc123", SBOMSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "sha256-abc123.sbom" {
+		t.Errorf("unexpected tag %q", tag)
+	}
+	if _, err := AttachedTag("abc123", SBOMSuffix); err == nil {
+		t.Error("expected error for malformed digest")
+	}
+}
+
+func TestLocateSBOMs(t *testing.T) {
+	known, _ := image.ParseRef("quay.io/weaveworks/helloworld:v2")
+	noSBOM, _ := image.ParseRef("quay.io/weaveworks/helloworld:v1")
+	unknown, _ := image.ParseRef("quay.io/weaveworks/sidecar:v5")
+	var asked []string
+	locator := CosignSBOMLocator{
+		Registry: &mock.Registry{
+			Images: []image.Info{{ID: known, Digest: "sha256:abc123"}, {ID: noSBOM, Digest: "sha256:def456"}},
+		},
+		Clients: &mock.ClientFactory{Client: &mock.Client{
+			HasTagFn: func(tag string) (bool, error) {
+				asked = append(asked, tag)
+				return tag == "sha256-abc123.sbom", nil
+			},
+		}},
+	}
+
+	result := update.Result{
+		flux.MustParseResourceID("default:deployment/helloworld"): update.ControllerResult{
+			Status: update.ReleaseStatusSuccess,
+			PerContainer: []update.ContainerUpdate{
+				{Container: "helloworld", Target: known},
+				{Container: "sidecar", Target: unknown},
+			},
+		},
+		flux.MustParseResourceID("default:deployment/old"): update.ControllerResult{
+			Status: update.ReleaseStatusSuccess,
+			PerContainer: []update.ContainerUpdate{
+				{Container: "helloworld", Target: noSBOM},
+			},
+		},
+	}
+
+	sboms, err := LocateSBOMs(locator, result)
+	if err == nil || strings.Contains(err.Error(), noSBOM.String()) {
+		t.Errorf("expected error for image with no metadata only, got %v", err)
+	}
+	if len(asked) != 2 {
+		t.Errorf("expected the registry to be asked about each SBOM tag, got %v", asked)
+	}
+	if len(sboms) != 1 {
+		t.Fatalf("expected one SBOM, got %d", len(sboms))
+	}
+	if sboms[0].Ref != "quay.io/weaveworks/helloworld:sha256-abc123.sbom" || sboms[0].Digest != "sha256:abc123" {
+		t.Errorf("unexpected SBOM reference %+v", sboms[0])
+	}
+}
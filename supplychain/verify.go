@@ -0,0 +1,237 @@
+package supplychain
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+)
+
+// Verifier checks that an image is signed (and possibly attested) by
+// someone we trust. An error means the image should not be released.
+type Verifier interface {
+	Verify(ctx context.Context, ref image.Ref, digest string) (event.ImageVerification, error)
+}
+
+// CosignVerifier verifies images by calling the executable `cosign`.
+// If Key is given, signatures must be made with that key (a path to a
+// public key, or a KMS URI); otherwise "keyless" verification is
+// done, checking the identity and issuer in the signing certificate.
+type CosignVerifier struct {
+	// Exe is the path to cosign; if empty, it's looked up in $PATH
+	Exe                   string
+	Key                   string
+	CertificateIdentity   string
+	CertificateOIDCIssuer string
+	// If true, also require a SLSA provenance attestation
+	RequireProvenance bool
+}
+
+func (v CosignVerifier) Verify(ctx context.Context, ref image.Ref, digest string) (event.ImageVerification, error) {
+	verification := event.ImageVerification{
+		Image:  ref.String(),
+		Digest: digest,
+	}
+	// Always verify by digest, so what's checked is what's released.
+	target := ref.Name.String()
+	if digest != "" {
+		target = target + "@" + digest
+	} else {
+		target = ref.String()
+	}
+
+	out, err := v.cosign(ctx, "verify", target)
+	if err != nil {
+		verification.Output = err.Error()
+		return verification, err
+	}
+	verification.Signatures = countJSONLines(out)
+
+	if v.RequireProvenance {
+		out, err := v.cosign(ctx, "verify-attestation", target, "--type", "slsaprovenance")
+		if err != nil {
+			verification.Output = err.Error()
+			return verification, err
+		}
+		verification.Attestations = countJSONLines(out)
+	}
+
+	verification.Verified = true
+	return verification, nil
+}
+
+func (v CosignVerifier) cosign(ctx context.Context, subcommand, target string, extra ...string) ([]byte, error) {
+	exe := v.Exe
+	if exe == "" {
+		exe = "cosign"
+	}
+	args := []string{subcommand}
+	if v.Key != "" {
+		args = append(args, "--key", v.Key)
+	} else {
+		if v.CertificateIdentity != "" {
+			args = append(args, "--certificate-identity", v.CertificateIdentity)
+		}
+		if v.CertificateOIDCIssuer != "" {
+			args = append(args, "--certificate-oidc-issuer", v.CertificateOIDCIssuer)
+		}
+	}
+	args = append(args, extra...)
+	args = append(args, "--output", "json", target)
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if err := cmd.Run(); err != nil {
+		if errOut.Len() == 0 {
+			return nil, err
+		}
+		return nil, errors.New(strings.TrimSpace(errOut.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// countJSONLines counts the verified payloads cosign outputs; this
+// is either a single JSON array, or one JSON object per line.
+func countJSONLines(out []byte) int {
+	var payloads []json.RawMessage
+	if err := json.Unmarshal(out, &payloads); err == nil {
+		return len(payloads)
+	}
+	var n int
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// VerificationCache remembers the outcome of verifying images, so
+// that each image (by digest) is only verified once, and so the
+// outcome can be attached to events later. Outcomes are kept for the
+// digest in each repository, since signatures are stored alongside
+// the image; only so many are kept, and the least recently used are
+// forgotten first.
+type VerificationCache struct {
+	Verifier Verifier
+	// How long to remember a failure before trying again
+	RetryFailedAfter time.Duration
+	// How many outcomes to remember; by default, 1000
+	Size int
+
+	mu      sync.Mutex
+	entries *list.List               // of *cachedVerification, most recently used first
+	results map[string]*list.Element // by repository@digest
+	latest  map[string]*list.Element // by image ref, the last outcome for it
+}
+
+const defaultVerificationCacheSize = 1000
+
+type cachedVerification struct {
+	key          string
+	refs         map[string]struct{}
+	verification event.ImageVerification
+	err          error
+	at           time.Time
+}
+
+func (c *VerificationCache) Verify(ctx context.Context, ref image.Ref, digest string) (event.ImageVerification, error) {
+	key := ref.Name.String() + "@" + digest
+	c.mu.Lock()
+	if el, ok := c.results[key]; ok {
+		cached := el.Value.(*cachedVerification)
+		if cached.err == nil || time.Since(cached.at) < c.RetryFailedAfter {
+			c.entries.MoveToFront(el)
+			c.remember(ref, el)
+			c.mu.Unlock()
+			return forRef(cached.verification, ref), cached.err
+		}
+	}
+	c.mu.Unlock()
+
+	verification, err := c.Verifier.Verify(ctx, ref, digest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = list.New()
+		c.results = map[string]*list.Element{}
+		c.latest = map[string]*list.Element{}
+	}
+	el, ok := c.results[key]
+	if ok {
+		cached := el.Value.(*cachedVerification)
+		cached.verification, cached.err, cached.at = verification, err, time.Now()
+		c.entries.MoveToFront(el)
+	} else {
+		el = c.entries.PushFront(&cachedVerification{
+			key:          key,
+			refs:         map[string]struct{}{},
+			verification: verification,
+			err:          err,
+			at:           time.Now(),
+		})
+		c.results[key] = el
+	}
+	c.remember(ref, el)
+
+	size := c.Size
+	if size <= 0 {
+		size = defaultVerificationCacheSize
+	}
+	for c.entries.Len() > size {
+		c.forget(c.entries.Back())
+	}
+	return verification, err
+}
+
+// Lookup returns the outcome of the last verification of the image
+// given, if it's still remembered.
+func (c *VerificationCache) Lookup(ref image.Ref) (event.ImageVerification, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.latest[ref.String()]
+	if !ok {
+		return event.ImageVerification{}, false
+	}
+	return forRef(el.Value.(*cachedVerification).verification, ref), true
+}
+
+// remember records the entry as the latest outcome for the ref. It
+// must be called with the lock held.
+func (c *VerificationCache) remember(ref image.Ref, el *list.Element) {
+	r := ref.String()
+	if prev, ok := c.latest[r]; ok && prev != el {
+		delete(prev.Value.(*cachedVerification).refs, r)
+	}
+	c.latest[r] = el
+	el.Value.(*cachedVerification).refs[r] = struct{}{}
+}
+
+// forget removes the entry, and any refs for which it's the latest
+// outcome. It must be called with the lock held.
+func (c *VerificationCache) forget(el *list.Element) {
+	cached := c.entries.Remove(el).(*cachedVerification)
+	delete(c.results, cached.key)
+	for r := range cached.refs {
+		delete(c.latest, r)
+	}
+}
+
+// forRef gives the verification as being of the ref given, which may
+// have a different tag to the one first verified with the same
+// digest.
+func forRef(v event.ImageVerification, ref image.Ref) event.ImageVerification {
+	v.Image = ref.String()
+	return v
+}
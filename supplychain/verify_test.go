@@ -0,0 +1,130 @@
+package supplychain
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weaveworks/flux/event"
+	"github.com/weaveworks/flux/image"
+)
+
+// A stand-in for cosign, which accepts only images with "good" in
+// the name.
+const fakeCosign = `#!/bin/sh
+for last; do :; done
+case "$last" in
+  *good*) echo '[{"critical":{}}]' ;;
+  *) echo "no matching signatures" >&2; exit 1 ;;
+esac
+`
+
+func TestCosignVerifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flux-cosign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "cosign")
+	if err := ioutil.WriteFile(exe, []byte(fakeCosign), 0755); err != nil {
+		t.Fatal(err)
+	}
+	v := CosignVerifier{Exe: exe, Key: "cosign.pub", RequireProvenance: true}
+
+	good, _ := image.ParseRef("quay.io/weaveworks/good:v1")
+	verification, err := v.Verify(context.Background(), good, "sha256:abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Verified || verification.Signatures != 1 || verification.Attestations != 1 {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+
+	bad, _ := image.ParseRef("quay.io/weaveworks/bad:v1")
+	verification, err = v.Verify(context.Background(), bad, "sha256:abc123")
+	if err == nil || verification.Verified {
+		t.Errorf("expected verification to fail, got %+v", verification)
+	}
+	if verification.Output != "no matching signatures" {
+		t.Errorf("expected output to be recorded, got %q", verification.Output)
+	}
+}
+
+type countingVerifier int
+
+func (c *countingVerifier) Verify(_ context.Context, ref image.Ref, digest string) (event.ImageVerification, error) {
+	*c++
+	if ref.Tag == "bad" {
+		return event.ImageVerification{Image: ref.String()}, errors.New("unsigned")
+	}
+	return event.ImageVerification{Image: ref.String(), Digest: digest, Verified: true}, nil
+}
+
+func TestVerificationCache(t *testing.T) {
+	var count countingVerifier
+	cache := &VerificationCache{Verifier: &count}
+	ref, _ := image.ParseRef("quay.io/weaveworks/helloworld:v1")
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Verify(context.Background(), ref, "sha256:abc123"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected image to be verified once, was verified %d times", count)
+	}
+	if v, ok := cache.Lookup(ref); !ok || !v.Verified {
+		t.Errorf("expected verification to be found, got %+v", v)
+	}
+
+	bad := ref.WithNewTag("bad")
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Verify(context.Background(), bad, "sha256:def456"); err == nil {
+			t.Error("expected failed verification to be returned")
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected failed verification to be retried, count is %d", count)
+	}
+}
+
+func TestVerificationCacheByDigest(t *testing.T) {
+	var count countingVerifier
+	cache := &VerificationCache{Verifier: &count, Size: 2}
+	v1, _ := image.ParseRef("quay.io/weaveworks/helloworld:v1")
+	latest := v1.WithNewTag("latest")
+
+	// The same image under another tag isn't verified again
+	for _, ref := range []image.Ref{v1, latest} {
+		if _, err := cache.Verify(context.Background(), ref, "sha256:abc123"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected the digest to be verified once, was verified %d times", count)
+	}
+	if v, ok := cache.Lookup(latest); !ok || v.Image != latest.String() {
+		t.Errorf("expected verification for %s, got %+v", latest, v)
+	}
+
+	// Only so many are remembered, the least recently used going first
+	v2 := v1.WithNewTag("v2")
+	v3 := v1.WithNewTag("v3")
+	cache.Verify(context.Background(), v2, "sha256:def456")
+	cache.Verify(context.Background(), v1, "sha256:abc123")
+	cache.Verify(context.Background(), v3, "sha256:fed789")
+	if _, ok := cache.Lookup(v2); ok {
+		t.Error("expected the least recently used verification to be forgotten")
+	}
+	for _, ref := range []image.Ref{v1, latest, v3} {
+		if _, ok := cache.Lookup(ref); !ok {
+			t.Errorf("expected verification of %s to be remembered", ref)
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected three verifications, got %d", count)
+	}
+}
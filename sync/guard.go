@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/weaveworks/flux/resource"
+)
+
+// Guard holds back syncs that would change more of the cluster than
+// expected, e.g., after a bad merge rewrites the whole repo, until
+// they are confirmed.
+type Guard struct {
+	// If more than this many resources would be added or changed,
+	// the sync is held back; zero means no limit
+	MaxChanges int
+	// If more than this many resources have been removed from the
+	// repo, the sync is held back; zero means no limit
+	MaxDeletes int
+}
+
+// Enabled says whether the guard has any limits to check.
+func (g Guard) Enabled() bool {
+	return g.MaxChanges > 0 || g.MaxDeletes > 0
+}
+
+// Diff counts the resources that differ between two versions of the
+// repo.
+type Diff struct {
+	Changed []string
+	Deleted []string
+}
+
+// DiffResources finds the resources that have been added or changed
+// in the `after` version of the repo, and those that have been
+// removed from it, relative to the `before` version.
+func DiffResources(before, after map[string]resource.Resource) Diff {
+	var diff Diff
+	for id, res := range after {
+		if old, ok := before[id]; !ok || !bytes.Equal(old.Bytes(), res.Bytes()) {
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			diff.Deleted = append(diff.Deleted, id)
+		}
+	}
+	return diff
+}
+
+// Check returns an error, saying which limit is exceeded, if the
+// diff given is too big to sync without confirmation.
+func (g Guard) Check(diff Diff) error {
+	if g.MaxChanges > 0 && len(diff.Changed) > g.MaxChanges {
+		return fmt.Errorf("%d resources would be added or changed, more than the limit of %d", len(diff.Changed), g.MaxChanges)
+	}
+	if g.MaxDeletes > 0 && len(diff.Deleted) > g.MaxDeletes {
+		return fmt.Errorf("%d resources have been removed from the repo, more than the limit of %d", len(diff.Deleted), g.MaxDeletes)
+	}
+	return nil
+}
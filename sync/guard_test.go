@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/weaveworks/flux/resource"
+)
+
+// rscEdited is a resource that has been edited, so its bytes differ
+// from the mock's.
+type rscEdited struct {
+	rsc
+}
+
+func (re rscEdited) Bytes() []byte {
+	return []byte("edited")
+}
+
+func TestDiffResources(t *testing.T) {
+	before := map[string]resource.Resource{
+		"res1": mockResourceWithoutIgnorePolicy("deployment", "ns1", "d1"),
+		"res2": mockResourceWithoutIgnorePolicy("deployment", "ns1", "d2"),
+		"res3": mockResourceWithoutIgnorePolicy("service", "ns1", "s1"),
+	}
+	after := map[string]resource.Resource{
+		"res1": mockResourceWithoutIgnorePolicy("deployment", "ns1", "d1"),
+		"res2": rscEdited{mockResourceWithoutIgnorePolicy("deployment", "ns1", "d2")},
+		"res4": mockResourceWithoutIgnorePolicy("service", "ns1", "s2"),
+	}
+
+	diff := DiffResources(before, after)
+	sort.Strings(diff.Changed)
+	if len(diff.Changed) != 2 || diff.Changed[0] != "res2" || diff.Changed[1] != "res4" {
+		t.Errorf("expected res2 and res4 to be changed, got %v", diff.Changed)
+	}
+	if len(diff.Deleted) != 1 || diff.Deleted[0] != "res3" {
+		t.Errorf("expected res3 to be deleted, got %v", diff.Deleted)
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	diff := Diff{
+		Changed: []string{"res1", "res2", "res3"},
+		Deleted: []string{"res4", "res5"},
+	}
+	for _, c := range []struct {
+		guard Guard
+		held  bool
+	}{
+		{Guard{}, false},
+		{Guard{MaxChanges: 3}, false},
+		{Guard{MaxChanges: 2}, true},
+		{Guard{MaxDeletes: 2}, false},
+		{Guard{MaxDeletes: 1}, true},
+		{Guard{MaxChanges: 5, MaxDeletes: 1}, true},
+	} {
+		if err := c.guard.Check(diff); (err != nil) != c.held {
+			t.Errorf("%+v: expected held to be %v, got error %v", c.guard, c.held, err)
+		}
+	}
+}
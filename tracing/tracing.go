@@ -0,0 +1,188 @@
+/*
+Package tracing records spans around what the daemon does -- syncs,
+releases, commits -- with the OpenTelemetry SDK, and exports them to
+an OpenTelemetry collector (or anything else that accepts OTLP over
+HTTP, e.g., Jaeger or Tempo), so that flux's activity can be lined up
+with the traces of the applications it deploys.
+
+The trace context is propagated in the W3C `traceparent` and
+`tracestate` headers: taken from the requests made to the API, so a
+release asked for as part of a trace (e.g., by a CI pipeline) is
+recorded in it, and given in the requests fluxd makes, to release
+gates, webhooks and so on. The trace and span IDs are also put in the
+events flux logs, so an event can be looked up in the tracing backend
+and vice versa.
+*/
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/weaveworks/flux"
+
+// Propagator reads and writes the trace context in the W3C format.
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Tracer starts spans, and exports them in batches once they've
+// ended. A nil *Tracer is a valid tracer that records nothing, so
+// callers needn't check whether tracing is switched on.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer makes a tracer that reports spans under the service name
+// given, and sends them with the exporter. Any options given are
+// passed to the SDK's TracerProvider, after those made here.
+func NewTracer(service string, exporter sdktrace.SpanExporter, opts ...sdktrace.TracerProviderOption) *Tracer {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	}, opts...)
+	provider := sdktrace.NewTracerProvider(opts...)
+	return &Tracer{provider: provider, tracer: provider.Tracer(instrumentationName)}
+}
+
+// Span is an operation in progress. The methods of a nil *Span do
+// nothing, and its IDs are empty.
+type Span struct {
+	span trace.Span
+}
+
+// Start starts a span with the name given, as a child of the span in
+// the context, if there is one (including one propagated from
+// elsewhere), or as the root of a new trace otherwise. The attributes
+// are given as key, value pairs. The context returned has the new
+// span in it.
+func (t *Tracer) Start(ctx context.Context, name string, keyvals ...string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	ctx, span := t.tracer.Start(ctx, name)
+	s := &Span{span}
+	s.SetAttributes(keyvals...)
+	return ctx, s
+}
+
+// Flush exports the spans that have ended so far.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports what's left, and stops the tracer.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// SpanFromContext gives the span in the context, or nil if there
+// isn't one.
+func SpanFromContext(ctx context.Context) *Span {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return &Span{span}
+}
+
+// TraceID gives the ID of the trace the span is part of.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
+
+// SpanID gives the ID of the span itself.
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().SpanID().String()
+}
+
+// SetAttributes adds the key, value pairs given to the span.
+func (s *Span) SetAttributes(keyvals ...string) {
+	if s == nil {
+		return
+	}
+	var attrs []attribute.KeyValue
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		attrs = append(attrs, attribute.String(keyvals[i], keyvals[i+1]))
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+// End ends the span, recording the error it ended with, if not nil.
+// Only the first call to End counts.
+func (s *Span) End(err error) {
+	if s == nil || !s.span.IsRecording() {
+		return
+	}
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+}
+
+// Detach gives a context with the span of the context given, if it
+// has one, but none of its deadline or values, for work that goes on
+// after the request it came from, e.g., a job that's queued.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// Middleware takes the trace context from the headers of each
+// request, if it's there, and puts it in the request's context, so
+// spans started for the request are part of the caller's trace.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Inject puts the trace context of the span in the context given, if
+// there is one, in the headers.
+func Inject(ctx context.Context, header http.Header) {
+	Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// InjectIDs puts the trace context given by the trace and span IDs,
+// as recorded in an event, in the headers; nothing is put there if
+// either isn't a valid ID.
+func InjectIDs(header http.Header, traceID, spanID string) {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	Inject(trace.ContextWithSpanContext(context.Background(), sc), header)
+}
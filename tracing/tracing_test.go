@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "sync")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("expected no span from a nil tracer")
+	}
+	span.SetAttributes("a", "b")
+	span.End(nil)
+	if span.TraceID() != "" || span.SpanID() != "" {
+		t.Error("expected empty IDs from a nil span")
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpanParentage(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tracer := NewTracer("fluxd", exp)
+
+	ctx, parent := tracer.Start(context.Background(), "release", "release.kind", "execute")
+	_, child := tracer.Start(ctx, "commit")
+	if len(parent.TraceID()) != 32 || len(parent.SpanID()) != 16 {
+		t.Fatalf("expected W3C-sized IDs, got %q, %q", parent.TraceID(), parent.SpanID())
+	}
+	if child.TraceID() != parent.TraceID() {
+		t.Error("expected the child to be in the parent's trace")
+	}
+	if SpanFromContext(ctx).SpanID() != parent.SpanID() {
+		t.Error("expected the parent span in the context")
+	}
+	child.End(errors.New("push failed"))
+	child.End(nil)
+	parent.End(nil)
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected two spans, got %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Parent.SpanID() != p.SpanContext.SpanID() || p.Parent.IsValid() {
+		t.Errorf("unexpected parentage: %+v, %+v", c, p)
+	}
+	if c.Status.Code != codes.Error || c.Status.Description != "push failed" {
+		t.Errorf("expected the first End to count, got status %+v", c.Status)
+	}
+	if len(p.Attributes) != 1 || p.Attributes[0].Value.AsString() != "execute" {
+		t.Errorf("expected attribute, got %v", p.Attributes)
+	}
+	if name, _ := p.Resource.Set().Value("service.name"); name.AsString() != "fluxd" {
+		t.Errorf("expected the service name in the resource, got %q", name.AsString())
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tracer := NewTracer("fluxd", exp)
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	// A span started for a request is part of the caller's trace,
+	// and is what's given to requests made in turn
+	var outgoing http.Header
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(Detach(r.Context()), "release")
+		defer span.End(nil)
+		outgoing = http.Header{}
+		Inject(ctx, outgoing)
+	}))
+	req := httptest.NewRequest("POST", "/v11/update-manifests", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected a span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.SpanContext.TraceID().String() != traceID || s.Parent.SpanID().String() != spanID {
+		t.Errorf("expected the span to be a child of the caller's, got %+v", s)
+	}
+	expected := "00-" + traceID + "-" + s.SpanContext.SpanID().String() + "-01"
+	if got := outgoing.Get("traceparent"); got != expected {
+		t.Errorf("expected traceparent %q to be given on, got %q", expected, got)
+	}
+
+	// .. and one recorded in an event can be given too
+	header := http.Header{}
+	InjectIDs(header, traceID, spanID)
+	if got := header.Get("traceparent"); got != "00-"+traceID+"-"+spanID+"-01" {
+		t.Errorf("unexpected traceparent from IDs: %q", got)
+	}
+	header = http.Header{}
+	InjectIDs(header, "", "")
+	if len(header) != 0 {
+		t.Errorf("expected no headers without IDs, got %v", header)
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	var got []*tracepb.ResourceSpans
+	var path, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		for len(body) > 0 {
+			num, typ, n := protowire.ConsumeTag(body)
+			if n < 0 || num != 1 || typ != protowire.BytesType {
+				t.Errorf("unexpected field %d in request", num)
+				return
+			}
+			body = body[n:]
+			b, n := protowire.ConsumeBytes(body)
+			body = body[n:]
+			rs := &tracepb.ResourceSpans{}
+			if err := proto.Unmarshal(b, rs); err != nil {
+				t.Error(err)
+				return
+			}
+			got = append(got, rs)
+		}
+	}))
+	defer srv.Close()
+
+	tracer := NewTracer("fluxd", NewOTLP(srv.URL+"/", nil))
+	_, span := tracer.Start(context.Background(), "sync", "revision", "abc123")
+	span.End(errors.New("apply failed"))
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/traces" || contentType != "application/x-protobuf" {
+		t.Errorf("expected spans posted to /v1/traces as protobuf, got %q, %q", path, contentType)
+	}
+	if len(got) != 1 || len(got[0].ScopeSpans) != 1 || len(got[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("expected one span, got %+v", got)
+	}
+	var service string
+	for _, kv := range got[0].Resource.Attributes {
+		if kv.Key == "service.name" {
+			service = kv.Value.GetStringValue()
+		}
+	}
+	if service != "fluxd" {
+		t.Errorf("expected the service name fluxd, got %q", service)
+	}
+	s := got[0].ScopeSpans[0].Spans[0]
+	if s.Name != "sync" || len(s.TraceId) != 16 || len(s.SpanId) != 8 {
+		t.Errorf("unexpected span: %+v", s)
+	}
+	if s.Status.Code != tracepb.Status_STATUS_CODE_ERROR || s.Status.Message != "apply failed" {
+		t.Errorf("unexpected status: %+v", s.Status)
+	}
+	if len(s.Attributes) != 1 || s.Attributes[0].Key != "revision" {
+		t.Errorf("unexpected attributes: %+v", s.Attributes)
+	}
+}
+
+func TestOTLPExportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad spans", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	client := &otlpClient{endpoint: srv.URL}
+	err := client.UploadTraces(context.Background(), []*tracepb.ResourceSpans{{}})
+	if err == nil || !strings.Contains(err.Error(), "bad spans") {
+		t.Errorf("expected an error from a 400, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package update
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/weaveworks/flux"
+	"github.com/weaveworks/flux/resource"
+)
+
+// ChartUpdates is a set of changes to the versions of charts used by
+// (automated) FluxHelmReleases.
+type ChartUpdates struct {
+	Changes []ChartUpdate `json:"changes"`
+}
+
+type ChartUpdate struct {
+	ResourceID flux.ResourceID `json:"resourceID"`
+	Chart      resource.Chart  `json:"chart"`
+	// Target is the version of the chart to update to; the version
+	// in Chart is the current one
+	Target     string `json:"target"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+func (c *ChartUpdates) Add(id flux.ResourceID, chart resource.Chart, target, appVersion string) {
+	c.Changes = append(c.Changes, ChartUpdate{id, chart, target, appVersion})
+}
+
+func (c ChartUpdates) CommitMessage() string {
+	buf := &bytes.Buffer{}
+	switch len(c.Changes) {
+	case 1:
+		ch := c.Changes[0]
+		fmt.Fprintf(buf, "Auto-update chart %s to %s", ch.Chart.Name, ch.Target)
+	default:
+		fmt.Fprintf(buf, "Auto-update charts\n")
+		for _, ch := range c.Changes {
+			fmt.Fprintf(buf, "\n- %s: %s %s -> %s", ch.ResourceID, ch.Chart.Name, ch.Chart.Version, ch.Target)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,18 @@
+package update
+
+import (
+	"github.com/weaveworks/flux"
+)
+
+// RestartSpec is for rolling out new pods for workloads, without
+// changing their images or anything else about them; e.g., so that
+// they pick up a config map or secret that flux has changed. Nothing
+// is committed to git.
+type RestartSpec struct {
+	Workloads []flux.ResourceID `json:"workloads"`
+	// Why the workloads are being restarted
+	Reason string      `json:"reason,omitempty"`
+	Kind   ReleaseKind `json:"kind"`
+	// Disregard any release freeze
+	Force bool `json:"force,omitempty"`
+}
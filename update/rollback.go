@@ -0,0 +1,17 @@
+package update
+
+// RollbackSpec is for undoing a release: the images it changed are
+// set back to what they were before it, for those workloads that are
+// still running the images it released.
+type RollbackSpec struct {
+	// The ID of the event recording the release to roll back
+	ReleaseID int64 `json:"releaseID"`
+	// Why the release is being rolled back
+	Reason string      `json:"reason,omitempty"`
+	Kind   ReleaseKind `json:"kind"`
+	// Disregard locks, and any release freeze
+	Force bool `json:"force,omitempty"`
+	// The revision at which the release was committed; this is
+	// filled in by the daemon
+	ReleaseRevision string `json:"releaseRevision,omitempty"`
+}
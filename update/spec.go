@@ -13,6 +13,9 @@ const (
 	Auto       = "auto"
 	Sync       = "sync"
 	Containers = "containers"
+	Charts     = "charts"
+	Rollback   = "rollback"
+	Restart    = "restart"
 )
 
 // How did this update get triggered?
@@ -71,6 +74,24 @@ func (spec *Spec) UnmarshalJSON(in []byte) error {
 			return err
 		}
 		spec.Spec = update
+	case Charts:
+		var update ChartUpdates
+		if err := json.Unmarshal(wire.SpecBytes, &update); err != nil {
+			return err
+		}
+		spec.Spec = update
+	case Rollback:
+		var update RollbackSpec
+		if err := json.Unmarshal(wire.SpecBytes, &update); err != nil {
+			return err
+		}
+		spec.Spec = update
+	case Restart:
+		var update RestartSpec
+		if err := json.Unmarshal(wire.SpecBytes, &update); err != nil {
+			return err
+		}
+		spec.Spec = update
 	default:
 		return errors.New("unknown spec type: " + wire.Type)
 	}
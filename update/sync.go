@@ -1,4 +1,7 @@
 package update
 
 type ManualSync struct {
+	// If given, the revision, held back by the sync guardrails, that
+	// may go ahead and be synced
+	Confirm string `json:"confirm,omitempty"`
 }